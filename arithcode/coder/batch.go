@@ -0,0 +1,131 @@
+package coder
+
+import (
+	"fmt"
+	"slices"
+)
+
+// The batch methods code runs of symbols with one model, such as the bytes
+// of a string or a payload. They check the model once per run instead of
+// once per symbol and write the same data as coding the symbols one by one
+// with Encode and Decode. The model must not change during a run, so they
+// do not suit adaptive models that are updated after every symbol.
+
+// checkModel returns the total frequency of model, or an error when it is
+// out of range.
+func checkModel(model Model) (uint64, error) {
+	total := model.TotalFreq()
+	if total == 0 || total > MaxTotalFreq {
+		return 0, fmt.Errorf("%w: %d", ErrModelPrecision, total)
+	}
+	return total, nil
+}
+
+// EncodeAll writes symbols using model, like calling Encode for each symbol.
+func (e *Encoder) EncodeAll(symbols []int, model Model) error {
+	if len(symbols) == 0 {
+		return nil
+	}
+	total, err := checkModel(model)
+	if err != nil {
+		return err
+	}
+	count := model.SymbolCount()
+	for _, symbol := range symbols {
+		if err := e.encodeSymbol(symbol, count, total, model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeBytes writes the bytes of data as symbols using model, like calling
+// Encode for each byte. The caller cannot update an adaptive model between
+// the bytes as it could between calls to Encode, so model is treated as
+// static. pbmodel.AdaptiveCompress codes string and bytes fields with it,
+// relying on the byte model of the adaptive builder being the static uniform
+// model.
+func (e *Encoder) EncodeBytes(data []byte, model Model) error {
+	if len(data) == 0 {
+		return nil
+	}
+	total, err := checkModel(model)
+	if err != nil {
+		return err
+	}
+	count := model.SymbolCount()
+	for _, b := range data {
+		if err := e.encodeSymbol(int(b), count, total, model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeSymbol writes symbol of a model with count symbols and the given
+// total frequency.
+func (e *Encoder) encodeSymbol(symbol, count int, total uint64, model Model) error {
+	if symbol < 0 || symbol >= count {
+		return fmt.Errorf("%w: %d of %d", ErrSymbolRange, symbol, count)
+	}
+	symLow, symHigh := model.Freq(symbol)
+	if symLow >= symHigh || symHigh > total {
+		return fmt.Errorf("%w: symbol %d has frequency range [%d, %d)", ErrSymbolRange, symbol, symLow, symHigh)
+	}
+	return e.encodeRange(symLow, symHigh, total)
+}
+
+// DecodeN reads n symbols written by EncodeAll with the same model and
+// appends them to dst. It allocates room for all n symbols up front, so n
+// read from untrusted data must be bounded by the caller.
+func (d *Decoder) DecodeN(n int, model Model, dst []int) ([]int, error) {
+	if n <= 0 {
+		return dst, nil
+	}
+	total, err := checkModel(model)
+	if err != nil {
+		return dst, err
+	}
+	dst = slices.Grow(dst, n)
+	for range n {
+		symbol, err := d.decodeSymbol(total, model)
+		if err != nil {
+			return dst, err
+		}
+		dst = append(dst, symbol)
+	}
+	return dst, nil
+}
+
+// DecodeBytes reads len(dst) bytes written by EncodeBytes with the same
+// model into dst. The model must have at most 256 symbols.
+func (d *Decoder) DecodeBytes(dst []byte, model Model) error {
+	if len(dst) == 0 {
+		return nil
+	}
+	if count := model.SymbolCount(); count > 256 {
+		return fmt.Errorf("%w: %d symbols do not fit in a byte", ErrSymbolRange, count)
+	}
+	total, err := checkModel(model)
+	if err != nil {
+		return err
+	}
+	for i := range dst {
+		symbol, err := d.decodeSymbol(total, model)
+		if err != nil {
+			return err
+		}
+		dst[i] = byte(symbol)
+	}
+	return nil
+}
+
+// decodeSymbol reads a symbol of a model with the given total frequency.
+func (d *Decoder) decodeSymbol(total uint64, model Model) (int, error) {
+	symbol := model.Find(d.target(total))
+	symLow, symHigh := model.Freq(symbol)
+	if err := d.decodeRange(symLow, symHigh, total); err != nil {
+		return 0, err
+	}
+	return symbol, nil
+}
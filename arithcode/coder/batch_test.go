@@ -0,0 +1,192 @@
+package coder
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// byteModel is a uniform model of the 256 byte values.
+type byteModel struct{}
+
+func (byteModel) SymbolCount() int                   { return 256 }
+func (byteModel) Freq(symbol int) (low, high uint64) { return uint64(symbol), uint64(symbol) + 1 }
+func (byteModel) TotalFreq() uint64                  { return 256 }
+func (byteModel) Find(cumFreq uint64) int            { return int(cumFreq) }
+func (byteModel) Cost(symbol int) float64            { return 8 }
+
+func TestBatchMatchesSingleSymbols(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	model := &testModel{freqs: []uint64{5, 1, 30, 2, 12}}
+	symbols := make([]int, 500)
+	for i := range symbols {
+		symbols[i] = rng.Intn(len(model.freqs))
+	}
+	data := make([]byte, 500)
+	rng.Read(data)
+
+	var single bytes.Buffer
+	enc := NewEncoder(&single)
+	for _, symbol := range symbols {
+		if err := enc.Encode(symbol, model); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	for _, b := range data {
+		if err := enc.Encode(int(b), byteModel{}); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	var batch bytes.Buffer
+	enc = NewEncoder(&batch)
+	if err := enc.EncodeAll(symbols, model); err != nil {
+		t.Fatalf("EncodeAll failed: %v", err)
+	}
+	if err := enc.EncodeBytes(data, byteModel{}); err != nil {
+		t.Fatalf("EncodeBytes failed: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if !bytes.Equal(single.Bytes(), batch.Bytes()) {
+		t.Fatalf("batch encoding differs from encoding single symbols")
+	}
+
+	dec, err := NewDecoder(&batch)
+	if err != nil {
+		t.Fatalf("NewDecoder failed: %v", err)
+	}
+	prefix := []int{-1}
+	got, err := dec.DecodeN(len(symbols), model, prefix)
+	if err != nil {
+		t.Fatalf("DecodeN failed: %v", err)
+	}
+	if got[0] != -1 || len(got) != len(symbols)+1 {
+		t.Fatalf("DecodeN did not append to dst")
+	}
+	for i, want := range symbols {
+		if got[i+1] != want {
+			t.Fatalf("symbol %d: got %d, want %d", i, got[i+1], want)
+		}
+	}
+	gotData := make([]byte, len(data))
+	if err := dec.DecodeBytes(gotData, byteModel{}); err != nil {
+		t.Fatalf("DecodeBytes failed: %v", err)
+	}
+	if !bytes.Equal(gotData, data) {
+		t.Fatalf("DecodeBytes mismatch")
+	}
+}
+
+func TestBatchRejectsInvalidInput(t *testing.T) {
+	enc := NewEncoder(&bytes.Buffer{})
+	tooLarge := &testModel{freqs: []uint64{1, MaxTotalFreq}}
+	if err := enc.EncodeAll([]int{0}, tooLarge); !errors.Is(err, ErrModelPrecision) {
+		t.Errorf("EncodeAll: expected ErrModelPrecision, got %v", err)
+	}
+	model := &testModel{freqs: []uint64{1, 1}}
+	if err := enc.EncodeAll([]int{0, 2}, model); !errors.Is(err, ErrSymbolRange) {
+		t.Errorf("EncodeAll: expected ErrSymbolRange, got %v", err)
+	}
+	if err := enc.EncodeBytes([]byte{1, 2}, model); !errors.Is(err, ErrSymbolRange) {
+		t.Errorf("EncodeBytes: expected ErrSymbolRange, got %v", err)
+	}
+
+	dec, err := NewDecoder(bytes.NewReader([]byte{0x12, 0x34, 0x56, 0x78}))
+	if err != nil {
+		t.Fatalf("NewDecoder failed: %v", err)
+	}
+	wide := &testModel{freqs: make([]uint64, 300)}
+	for i := range wide.freqs {
+		wide.freqs[i] = 1
+	}
+	if err := dec.DecodeBytes(make([]byte, 1), wide); !errors.Is(err, ErrSymbolRange) {
+		t.Errorf("DecodeBytes: expected ErrSymbolRange for a model wider than a byte, got %v", err)
+	}
+}
+
+func BenchmarkEncodeBytes(b *testing.B) {
+	data := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	b.Run("Encode", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		var buf bytes.Buffer
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			enc := NewEncoder(&buf)
+			for _, v := range data {
+				if err := enc.Encode(int(v), byteModel{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if err := enc.Close(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("EncodeBytes", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		var buf bytes.Buffer
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			enc := NewEncoder(&buf)
+			if err := enc.EncodeBytes(data, byteModel{}); err != nil {
+				b.Fatal(err)
+			}
+			if err := enc.Close(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecodeBytes(b *testing.B) {
+	data := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(data)
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if err := enc.EncodeBytes(data, byteModel{}); err != nil {
+		b.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		b.Fatal(err)
+	}
+	compressed := buf.Bytes()
+
+	dst := make([]byte, len(data))
+	b.Run("Decode", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			dec, err := NewDecoder(bytes.NewReader(compressed))
+			if err != nil {
+				b.Fatal(err)
+			}
+			for j := range dst {
+				v, err := dec.Decode(byteModel{})
+				if err != nil {
+					b.Fatal(err)
+				}
+				dst[j] = byte(v)
+			}
+		}
+	})
+	b.Run("DecodeBytes", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			dec, err := NewDecoder(bytes.NewReader(compressed))
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := dec.DecodeBytes(dst, byteModel{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,469 @@
+package coder
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// testModel is a minimal model with explicit frequencies.
+type testModel struct {
+	freqs []uint64
+}
+
+func (m *testModel) SymbolCount() int { return len(m.freqs) }
+
+func (m *testModel) Freq(symbol int) (low, high uint64) {
+	for _, freq := range m.freqs[:symbol] {
+		low += freq
+	}
+	return low, low + m.freqs[symbol]
+}
+
+func (m *testModel) TotalFreq() uint64 {
+	var total uint64
+	for _, freq := range m.freqs {
+		total += freq
+	}
+	return total
+}
+
+func (m *testModel) Find(cumFreq uint64) int {
+	for symbol, freq := range m.freqs {
+		if cumFreq < freq {
+			return symbol
+		}
+		cumFreq -= freq
+	}
+	panic("cumFreq out of range")
+}
+
+func (m *testModel) Cost(symbol int) float64 { return SymbolCost(m, symbol) }
+
+func TestRoundtripAtMaxTotalFreq(t *testing.T) {
+	model := &testModel{freqs: []uint64{1, MaxTotalFreq - 2, 1}}
+	symbols := []int{0, 2, 1, 0, 0, 2, 2, 1, 0, 2}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, symbol := range symbols {
+		if err := enc.Encode(symbol, model); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	dec, err := NewDecoder(&buf)
+	if err != nil {
+		t.Fatalf("NewDecoder failed: %v", err)
+	}
+	for i, want := range symbols {
+		got, err := dec.Decode(model)
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("Symbol %d: expected %d, got %d", i, want, got)
+		}
+	}
+}
+
+func TestEncodeRejectsInvalidModels(t *testing.T) {
+	enc := NewEncoder(&bytes.Buffer{})
+
+	tooLarge := &testModel{freqs: []uint64{1, MaxTotalFreq}}
+	if err := enc.Encode(0, tooLarge); !errors.Is(err, ErrModelPrecision) {
+		t.Errorf("Expected ErrModelPrecision, got %v", err)
+	}
+
+	model := &testModel{freqs: []uint64{1, 1}}
+	for _, symbol := range []int{-1, 2} {
+		if err := enc.Encode(symbol, model); !errors.Is(err, ErrSymbolRange) {
+			t.Errorf("Symbol %d: expected ErrSymbolRange, got %v", symbol, err)
+		}
+	}
+
+	empty := &testModel{freqs: []uint64{1, 0, 1}}
+	if err := enc.Encode(1, empty); !errors.Is(err, ErrSymbolRange) {
+		t.Errorf("Empty range: expected ErrSymbolRange, got %v", err)
+	}
+}
+
+func TestDecodeRejectsInvalidModels(t *testing.T) {
+	dec, err := NewDecoder(bytes.NewReader([]byte{0x12, 0x34, 0x56, 0x78}))
+	if err != nil {
+		t.Fatalf("NewDecoder failed: %v", err)
+	}
+
+	tooLarge := &testModel{freqs: []uint64{MaxTotalFreq, 1}}
+	if _, err := dec.Decode(tooLarge); !errors.Is(err, ErrModelPrecision) {
+		t.Errorf("Expected ErrModelPrecision, got %v", err)
+	}
+}
+
+func TestUniformRoundtrip(t *testing.T) {
+	type pair struct{ value, max uint64 }
+	var pairs []pair
+	for _, max := range []uint64{0, 1, 7, 32, MaxTotalFreq - 1, MaxTotalFreq, 1 << 40, math.MaxUint64} {
+		pairs = append(pairs, pair{0, max}, pair{max, max}, pair{max / 2, max}, pair{max / 3, max})
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, p := range pairs {
+		if err := enc.EncodeUniform(p.value, p.max); err != nil {
+			t.Fatalf("EncodeUniform(%d, %d) failed: %v", p.value, p.max, err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	dec, err := NewDecoder(&buf)
+	if err != nil {
+		t.Fatalf("NewDecoder failed: %v", err)
+	}
+	for _, p := range pairs {
+		value, err := dec.DecodeUniform(p.max)
+		if err != nil {
+			t.Fatalf("DecodeUniform(%d) failed: %v", p.max, err)
+		}
+		if value != p.value {
+			t.Errorf("DecodeUniform(%d): expected %d, got %d", p.max, p.value, value)
+		}
+	}
+
+	if err := NewEncoder(&bytes.Buffer{}).EncodeUniform(8, 7); !errors.Is(err, ErrSymbolRange) {
+		t.Errorf("Expected ErrSymbolRange, got %v", err)
+	}
+}
+
+func TestUniformSize(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for i := 0; i < 8000; i++ {
+		// hop limit 0-7: exactly 3 bits each
+		if err := enc.EncodeUniform(uint64(rng.Intn(8)), 7); err != nil {
+			t.Fatalf("EncodeUniform failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if expected := 8000 * 3 / 8; buf.Len() > expected+1 {
+		t.Errorf("Expected about %d bytes, got %d", expected, buf.Len())
+	}
+}
+
+func TestBitsTracksCost(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	model := &testModel{freqs: []uint64{1, 2, 5, 100, 3}}
+
+	enc := NewEncoder(&bytes.Buffer{})
+	var cost float64
+	for i := 0; i < 1000; i++ {
+		symbol := rng.Intn(model.SymbolCount())
+		if err := enc.Encode(symbol, model); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		cost += SymbolCost(model, symbol)
+		if got := enc.Bits(); math.Abs(got-cost) > 0.01*cost+1 {
+			t.Fatalf("symbol %d: Bits %.2f, want about %.2f", i, got, cost)
+		}
+	}
+}
+
+// randomSegments returns symbol sequences of varying lengths, including an
+// empty one.
+func randomSegments(rng *rand.Rand, model *testModel) [][]int {
+	var segments [][]int
+	for _, n := range []int{5, 0, 1, 40, 3, 200, 2} {
+		segment := make([]int, n)
+		for i := range segment {
+			segment[i] = rng.Intn(model.SymbolCount())
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+func TestFlushSegments(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	model := &testModel{freqs: []uint64{900, 60, 30, 9, 1}}
+	segments := randomSegments(rng, model)
+
+	var buf bytes.Buffer
+	var offsets []int
+	enc := NewEncoder(&buf)
+	for _, segment := range segments {
+		offsets = append(offsets, buf.Len())
+		for _, symbol := range segment {
+			if err := enc.Encode(symbol, model); err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+		}
+		if err := enc.Flush(); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+	}
+	stream := buf.Bytes()
+
+	// Decode the whole stream segment by segment
+	dec, err := NewDecoder(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatalf("NewDecoder failed: %v", err)
+	}
+	for k, segment := range segments {
+		if k > 0 {
+			if err := dec.NextSegment(); err != nil {
+				t.Fatalf("segment %d: NextSegment failed: %v", k, err)
+			}
+		}
+		for i, want := range segment {
+			got, err := dec.Decode(model)
+			if err != nil {
+				t.Fatalf("segment %d: Decode failed: %v", k, err)
+			}
+			if got != want {
+				t.Fatalf("segment %d symbol %d: expected %d, got %d", k, i, want, got)
+			}
+		}
+	}
+	if err := dec.NextSegment(); err != io.EOF {
+		t.Errorf("NextSegment at end of stream: expected io.EOF, got %v", err)
+	}
+
+	// Each segment can also be decoded on its own
+	for k, segment := range segments {
+		dec, err := NewDecoder(bytes.NewReader(stream[offsets[k]:]))
+		if err != nil {
+			t.Fatalf("segment %d: NewDecoder failed: %v", k, err)
+		}
+		for i, want := range segment {
+			got, err := dec.Decode(model)
+			if err != nil {
+				t.Fatalf("segment %d: Decode failed: %v", k, err)
+			}
+			if got != want {
+				t.Fatalf("segment %d symbol %d: expected %d, got %d", k, i, want, got)
+			}
+		}
+	}
+}
+
+func TestFlushIncrementalInput(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	model := &testModel{freqs: []uint64{500, 300, 150, 50}}
+	segments := randomSegments(rng, model)
+
+	// The decoder reads from the same buffer the encoder appends to, so it
+	// reaches the end of input after every segment
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	var dec *Decoder
+	for k, segment := range segments {
+		for _, symbol := range segment {
+			if err := enc.Encode(symbol, model); err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+		}
+		if err := enc.Flush(); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+
+		if k == 0 {
+			var err error
+			if dec, err = NewDecoder(&buf); err != nil {
+				t.Fatalf("NewDecoder failed: %v", err)
+			}
+		} else if err := dec.NextSegment(); err != nil {
+			t.Fatalf("segment %d: NextSegment failed: %v", k, err)
+		}
+		for i, want := range segment {
+			got, err := dec.Decode(model)
+			if err != nil {
+				t.Fatalf("segment %d: Decode failed: %v", k, err)
+			}
+			if got != want {
+				t.Fatalf("segment %d symbol %d: expected %d, got %d", k, i, want, got)
+			}
+		}
+	}
+}
+
+func TestHasTrailingData(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	model := &testModel{freqs: []uint64{600, 250, 100, 50}}
+
+	for n := 0; n < 50; n++ {
+		symbols := make([]int, n)
+		for i := range symbols {
+			symbols[i] = rng.Intn(model.SymbolCount())
+		}
+
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		for _, symbol := range symbols {
+			if err := enc.Encode(symbol, model); err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		stream := buf.Bytes()
+
+		for _, extra := range [][]byte{nil, {0}, {0xFF}, {1, 2, 3, 4, 5, 6, 7, 8}} {
+			dec, err := NewDecoder(bytes.NewReader(append(bytes.Clone(stream), extra...)))
+			if err != nil {
+				t.Fatalf("NewDecoder failed: %v", err)
+			}
+			for i := range symbols {
+				if _, err := dec.Decode(model); err != nil {
+					t.Fatalf("%d symbols: Decode at position %d failed: %v", n, i, err)
+				}
+			}
+			if err := dec.Finish(); err != nil {
+				t.Fatalf("%d symbols: Finish failed: %v", n, err)
+			}
+			if got := dec.Len(); got != len(stream) {
+				t.Errorf("%d symbols, %d extra bytes: Len() = %d, want %d", n, len(extra), got, len(stream))
+			}
+			trailing, err := dec.HasTrailingData()
+			if err != nil {
+				t.Fatalf("%d symbols: HasTrailingData failed: %v", n, err)
+			}
+			if want := len(extra) > 0; trailing != want {
+				t.Errorf("%d symbols, %d extra bytes: HasTrailingData() = %v, want %v", n, len(extra), trailing, want)
+			}
+		}
+	}
+}
+
+func TestTruncationDetected(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	model := &testModel{freqs: []uint64{700, 200, 80, 20}}
+	symbols := make([]int, 300)
+	for i := range symbols {
+		symbols[i] = rng.Intn(model.SymbolCount())
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, symbol := range symbols {
+		if err := enc.Encode(symbol, model); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	stream := buf.Bytes()
+
+	decodeAll := func(data []byte) error {
+		dec, err := NewDecoder(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		for range symbols {
+			if _, err := dec.Decode(model); err != nil {
+				return err
+			}
+		}
+		return dec.Finish()
+	}
+
+	if err := decodeAll(stream); err != nil {
+		t.Fatalf("complete stream: %v", err)
+	}
+	for n := 1; n < len(stream); n++ {
+		if err := decodeAll(stream[:n]); !errors.Is(err, ErrTruncated) {
+			t.Fatalf("stream truncated to %d of %d bytes: expected ErrTruncated, got %v", n, len(stream), err)
+		}
+	}
+}
+
+func TestEOSDetectsCorruption(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	model := &testModel{freqs: []uint64{600, 250, 100, 50}}
+	symbols := make([]int, 100)
+	for i := range symbols {
+		symbols[i] = rng.Intn(model.SymbolCount())
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoderEOS(&buf)
+	for _, symbol := range symbols {
+		if err := enc.Encode(symbol, model); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	stream := buf.Bytes()
+
+	// decodeAll reports whether data decoded without error into different
+	// symbols
+	decodeAll := func(data []byte) (wrong bool, err error) {
+		dec, err := NewDecoderEOS(bytes.NewReader(data))
+		if err != nil {
+			return false, err
+		}
+		for _, want := range symbols {
+			got, err := dec.Decode(model)
+			if err != nil {
+				return false, err
+			}
+			wrong = wrong || got != want
+		}
+		return wrong, dec.Finish()
+	}
+
+	if wrong, err := decodeAll(stream); wrong || err != nil {
+		t.Fatalf("complete stream: wrong symbols %v, error %v", wrong, err)
+	}
+
+	// Flip a single bit in every position of the stream; flips in the
+	// padding after the termination bits do not change the symbols
+	undetected := 0
+	for bit := 0; bit < len(stream)*8; bit++ {
+		corrupt := bytes.Clone(stream)
+		corrupt[bit/8] ^= 0x80 >> (bit % 8)
+		if wrong, err := decodeAll(corrupt); wrong && err == nil {
+			undetected++
+		}
+	}
+	t.Logf("undetected corruptions: %d of %d", undetected, len(stream)*8)
+	if undetected > len(stream)*8/50 {
+		t.Errorf("too many undetected corruptions: %d of %d", undetected, len(stream)*8)
+	}
+}
+
+func TestCheckPlatform(t *testing.T) {
+	if err := CheckPlatform(); err != nil {
+		t.Fatalf("CheckPlatform failed: %v", err)
+	}
+
+	// An encoder on a failing platform refuses to write anything
+	var buf bytes.Buffer
+	enc := newEncoder(&buf)
+	enc.err = ErrPlatform
+	if err := enc.EncodeUniform(3, 7); !errors.Is(err, ErrPlatform) {
+		t.Errorf("Encode: expected ErrPlatform, got %v", err)
+	}
+	if err := enc.Close(); !errors.Is(err, ErrPlatform) {
+		t.Errorf("Close: expected ErrPlatform, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("encoder wrote %d bytes", buf.Len())
+	}
+}
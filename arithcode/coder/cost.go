@@ -0,0 +1,30 @@
+package coder
+
+import "math"
+
+// SymbolCost returns -log2(p(symbol)) for the given model.
+// Models use it to implement Model.Cost.
+func SymbolCost(model Model, symbol int) float64 {
+	low, high := model.Freq(symbol)
+	return math.Log2(float64(model.TotalFreq()) / float64(high-low))
+}
+
+// EstimateBits returns the expected number of bits needed to encode
+// symbols with model, without running the encoder.
+//
+// The estimate is the sum of the symbol costs; the actual encoded size
+// additionally includes up to two bits of termination and the padding
+// to a whole byte added by Encoder.Close.
+func EstimateBits(symbols []int, model Model) float64 {
+	var bits float64
+	for _, symbol := range symbols {
+		bits += model.Cost(symbol)
+	}
+	return bits
+}
+
+// EstimateBytes converts a bit estimate into the number of whole bytes
+// a finished encoding is expected to take.
+func EstimateBytes(bits float64) int {
+	return int(math.Ceil(bits / 8))
+}
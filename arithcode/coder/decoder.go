@@ -0,0 +1,333 @@
+package coder
+
+import (
+	"fmt"
+	"io"
+)
+
+// Decoder decompresses data using arithmetic coding.
+type Decoder struct {
+	input *bitReader
+	low   uint64 // Lower bound of the current interval
+	high  uint64 // Upper bound of the current interval
+	value uint64 // Current value being decoded
+
+	// Segment bookkeeping for NextSegment and truncation checks
+	bitsRead  int // Bits read in the current segment, including missing bits
+	missing   int // Trailing bits read past the end of input
+	intervals int // Number of interval shifts in the current segment
+	pending   int // Shifts for which the encoder had not yet written a bit
+
+	eos       bool // Segments end with an end-of-stream marker
+	precision Precision
+}
+
+// NewDecoder creates a new arithmetic decoder that reads from r.
+// It returns ErrPlatform when the platform check fails, see CheckPlatform.
+func NewDecoder(r io.Reader) (*Decoder, error) {
+	if err := CheckPlatform(); err != nil {
+		return nil, err
+	}
+	return newDecoder(r)
+}
+
+// newDecoder creates a decoder without checking the platform.
+func newDecoder(r io.Reader) (*Decoder, error) {
+	d := &Decoder{
+		input:     newBitReader(r),
+		low:       0,
+		high:      stateMax,
+		precision: DefaultPrecision,
+	}
+	if err := d.fill(0); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// NewDecoderEOS creates a decoder for streams written by an encoder created
+// with NewEncoderEOS. Finish and NextSegment verify the end-of-stream marker.
+func NewDecoderEOS(r io.Reader) (*Decoder, error) {
+	d, err := NewDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+	d.eos = true
+	return d, nil
+}
+
+// SetPrecision selects the precision the stream was encoded with, see
+// Precision. It must be called before anything is decoded.
+func (d *Decoder) SetPrecision(p Precision) {
+	d.precision = p
+}
+
+// Reset discards the state of the decoder and starts decoding a new stream
+// from r, so that a decoder can be reused without allocating. Whether
+// segments end with an end-of-stream marker and the precision are kept.
+func (d *Decoder) Reset(r io.Reader) error {
+	*d.input = bitReader{input: r}
+	d.low = 0
+	d.high = stateMax
+	d.value = 0
+	return d.fill(0)
+}
+
+// fill reads the initial value of a segment, of which the lowest kept bits
+// have already been read.
+func (d *Decoder) fill(kept int) error {
+	d.value &= 1<<kept - 1
+	d.bitsRead = kept
+	d.missing = 0
+	d.intervals = 0
+	d.pending = 0
+
+	for i := kept; i < stateBits; i++ {
+		bit, err := d.input.ReadBit()
+		if err != nil {
+			if err == io.EOF && i > 0 {
+				// Partial read is acceptable for short messages
+				d.missing = stateBits - i
+				d.bitsRead = stateBits
+				d.value <<= (stateBits - i)
+				break
+			}
+			return err
+		}
+		d.value = (d.value << 1) | uint64(bit)
+		d.bitsRead++
+	}
+	return nil
+}
+
+// Finish verifies the end of the current segment, after all of its symbols
+// have been decoded. It returns ErrTruncated when the input ended before the
+// bits that terminate the segment, and for decoders created with
+// NewDecoderEOS, ErrCorrupt when the end-of-stream marker does not match.
+func (d *Decoder) Finish() error {
+	if d.eos {
+		marker, err := d.DecodeUniform(eosMax)
+		if err != nil {
+			return err
+		}
+		if marker != eosMarker {
+			return ErrCorrupt
+		}
+	}
+
+	// The encoder terminates a segment with two bits after the last interval
+	// shift
+	if d.bitsRead-d.missing < d.intervals+2 {
+		return ErrTruncated
+	}
+	return nil
+}
+
+// Len returns the length of the current segment in bytes. It must be called
+// after Finish. The decoder reads ahead of the symbols it decodes, so the
+// input may have been read beyond the segment.
+func (d *Decoder) Len() int {
+	return (d.intervals + 2 + 7) / 8
+}
+
+// HasTrailingData reports whether the input continues after the end of the
+// current segment. It must be called after Finish and may consume input, so
+// the decoder cannot continue with NextSegment afterwards.
+func (d *Decoder) HasTrailingData() (bool, error) {
+	segmentBits := 8 * d.Len()
+	if d.bitsRead-d.missing > segmentBits {
+		return true, nil
+	}
+	if d.missing > 0 {
+		// The input already ended
+		return false, nil
+	}
+	return d.input.more()
+}
+
+// NextSegment ends the current segment and starts decoding the next segment
+// of a stream written with Encoder.Flush. All symbols of the current segment
+// must have been decoded; the end of the segment is verified as by Finish.
+// It returns io.EOF when the stream has no further segments.
+//
+// The decoder reads ahead of the symbols it decodes. When the input reported
+// io.EOF at the end of the segment, for example because the next message has
+// not arrived yet, reading continues from the input once it has more data.
+func (d *Decoder) NextSegment() error {
+	if err := d.Finish(); err != nil {
+		return err
+	}
+
+	// The segment ends two bits after the last interval shift, padded to a
+	// whole byte; the bits read beyond that belong to the next segment.
+	segmentBits := 8 * d.Len()
+	kept := d.bitsRead - segmentBits - d.missing
+	if kept > 0 {
+		// Drop the zero bits that stood in for missing input
+		d.value >>= d.missing
+	} else {
+		kept = 0
+	}
+
+	d.low = 0
+	d.high = stateMax
+	return d.fill(kept)
+}
+
+// Decode reads and returns the next symbol using the given model.
+func (d *Decoder) Decode(model Model) (int, error) {
+	total, err := checkModel(model)
+	if err != nil {
+		return 0, err
+	}
+	return d.decodeSymbol(total, model)
+}
+
+// DecodeTarget returns the cumulative frequency, out of total, that the next
+// symbol's range contains. The caller looks up the symbol whose range
+// contains it and consumes the symbol with DecodeRange. Together they decode
+// a symbol written by Encoder.EncodeRange, without the calls through the
+// Model interface.
+func (d *Decoder) DecodeTarget(total uint64) (uint64, error) {
+	if total == 0 || total > MaxTotalFreq {
+		return 0, fmt.Errorf("%w: %d", ErrModelPrecision, total)
+	}
+	return d.target(total), nil
+}
+
+// DecodeRange consumes the symbol with cumulative frequency range
+// [low, high) out of total, which must contain the value returned by
+// DecodeTarget.
+func (d *Decoder) DecodeRange(low, high, total uint64) error {
+	if low >= high || high > total {
+		return fmt.Errorf("%w: frequency range [%d, %d) of %d", ErrSymbolRange, low, high, total)
+	}
+	return d.decodeRange(low, high, total)
+}
+
+// DecodeUniform reads a value written by Encoder.EncodeUniform with the same max.
+func (d *Decoder) DecodeUniform(max uint64) (uint64, error) {
+	var value uint64
+	shift := 0
+	for max >= MaxTotalFreq {
+		chunk := d.target(uniformChunkMask + 1)
+		if err := d.decodeRange(chunk, chunk+1, uniformChunkMask+1); err != nil {
+			return 0, err
+		}
+		value |= chunk << shift
+		shift += uniformChunkBits
+		max >>= uniformChunkBits
+	}
+
+	last := d.target(max + 1)
+	if last > max {
+		return 0, fmt.Errorf("%w: %d exceeds maximum %d", ErrSymbolRange, last, max)
+	}
+	if err := d.decodeRange(last, last+1, max+1); err != nil {
+		return 0, err
+	}
+	return value | last<<shift, nil
+}
+
+// target returns the cumulative frequency, out of total, that the current
+// value points to.
+func (d *Decoder) target(total uint64) uint64 {
+	return d.precision.target(d.low, d.high, d.value, total)
+}
+
+// decodeRange narrows the interval to the decoded symbol's cumulative
+// frequency range [symLow, symHigh) out of total.
+func (d *Decoder) decodeRange(symLow, symHigh, total uint64) error {
+	// Update the interval
+	d.low, d.high = d.precision.narrowInterval(d.low, d.high, symLow, symHigh, total)
+
+	// Normalize the interval
+	for {
+		if d.high < half {
+			// The encoder wrote a bit and its pending bits
+			d.pending = 0
+		} else if d.low >= half {
+			d.low -= half
+			d.high -= half
+			d.value -= half
+			d.pending = 0
+		} else if d.low >= quarter && d.high < 3*quarter {
+			d.low -= quarter
+			d.high -= quarter
+			d.value -= quarter
+			d.pending++
+		} else {
+			break
+		}
+
+		// Scale up the interval
+		d.low = (d.low << 1) & stateMax
+		d.high = ((d.high << 1) & stateMax) | 1
+
+		// Read next bit into value
+		bit, err := d.input.ReadBit()
+		if err != nil {
+			if err == io.EOF {
+				bit = 0 // Treat EOF as 0 bits
+				d.missing++
+			} else {
+				return err
+			}
+		} else {
+			d.missing = 0
+		}
+		d.value = ((d.value << 1) & stateMax) | uint64(bit)
+		d.bitsRead++
+		d.intervals++
+	}
+
+	// The segment ends two bits after its last interval shift, see Finish.
+	// Once the input has ended, an input too short for the shifts so far
+	// stays too short however many symbols follow, so decoding stops here
+	// instead of at Finish.
+	if d.missing > 0 && d.bitsRead-d.missing < d.intervals+2 {
+		return ErrTruncated
+	}
+	return nil
+}
+
+// bitReader reads individual bits from an io.Reader.
+type bitReader struct {
+	input       io.Reader
+	accumulator byte
+	numBits     int
+	buf         [1]byte // Avoids allocating for every read byte
+}
+
+func newBitReader(r io.Reader) *bitReader {
+	return &bitReader{input: r}
+}
+
+// more discards the unread bits of the current byte and reports whether the
+// input has more bytes.
+func (br *bitReader) more() (bool, error) {
+	br.numBits = 0
+	_, err := br.ReadBit()
+	if err == io.EOF {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (br *bitReader) ReadBit() (byte, error) {
+	if br.numBits == 0 {
+		n, err := br.input.Read(br.buf[:])
+		if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			return 0, io.EOF
+		}
+		br.accumulator = br.buf[0]
+		br.numBits = 8
+	}
+
+	br.numBits--
+	bit := (br.accumulator >> br.numBits) & 1
+	return bit, nil
+}
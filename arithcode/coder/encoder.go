@@ -0,0 +1,307 @@
+package coder
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	// stateBits defines the precision of the arithmetic coding state.
+	// We use 32 bits to balance precision and performance.
+	stateBits = 32
+	// stateMax is the maximum value of the state (2^32 - 1).
+	stateMax uint64 = (1 << stateBits) - 1
+	// half is the midpoint of the state range.
+	half uint64 = 1 << (stateBits - 1)
+	// quarter is one quarter of the state range.
+	quarter uint64 = 1 << (stateBits - 2)
+)
+
+// The end-of-stream marker written by encoders created with NewEncoderEOS.
+// It is coded uniformly in [0, eosMax] and costs 8 bits.
+const (
+	eosMarker = 0xA5
+	eosMax    = 0xFF
+)
+
+// uniformChunkBits is the chunk size for coding uniform values wider than
+// the coder precision.
+const (
+	uniformChunkBits = 16
+	uniformChunkMask = 1<<uniformChunkBits - 1
+)
+
+// Encoder compresses data using arithmetic coding.
+type Encoder struct {
+	output      *bitWriter
+	low         uint64 // Lower bound of the current interval
+	high        uint64 // Upper bound of the current interval
+	pendingBits int    // Number of pending underflow bits
+	eos         bool   // Write an end-of-stream marker before terminating
+	precision   Precision
+	err         error // Result of CheckPlatform, reported instead of coding
+}
+
+// NewEncoder creates a new arithmetic encoder that writes to w.
+// When the platform check fails, every write returns ErrPlatform and nothing
+// is written to w, see CheckPlatform.
+func NewEncoder(w io.Writer) *Encoder {
+	e := newEncoder(w)
+	e.err = CheckPlatform()
+	return e
+}
+
+// newEncoder creates an encoder without checking the platform.
+func newEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		output:    newBitWriter(w),
+		low:       0,
+		high:      stateMax,
+		precision: DefaultPrecision,
+	}
+}
+
+// NewEncoderEOS creates an encoder that ends every segment with an
+// end-of-stream marker. The stream must be decoded with a decoder created by
+// NewDecoderEOS.
+func NewEncoderEOS(w io.Writer) *Encoder {
+	e := NewEncoder(w)
+	e.eos = true
+	return e
+}
+
+// SetPrecision selects the precision of the encoder, see Precision. It must
+// be called before anything is encoded.
+func (e *Encoder) SetPrecision(p Precision) {
+	e.precision = p
+}
+
+// Reset discards the state of the encoder and makes it write to w, so that
+// an encoder can be reused without allocating. Whether segments end with an
+// end-of-stream marker and the precision are kept.
+func (e *Encoder) Reset(w io.Writer) {
+	*e.output = bitWriter{output: w}
+	e.low = 0
+	e.high = stateMax
+	e.pendingBits = 0
+}
+
+// Encode writes a symbol using the given model.
+func (e *Encoder) Encode(symbol int, model Model) error {
+	total, err := checkModel(model)
+	if err != nil {
+		return err
+	}
+	return e.encodeSymbol(symbol, model.SymbolCount(), total, model)
+}
+
+// EncodeRange writes a symbol given by its cumulative frequency range
+// [low, high) out of total, as Encode does with the range from a model. It
+// lets concrete model types code without the calls through the Model
+// interface.
+func (e *Encoder) EncodeRange(low, high, total uint64) error {
+	if total == 0 || total > MaxTotalFreq {
+		return fmt.Errorf("%w: %d", ErrModelPrecision, total)
+	}
+	if low >= high || high > total {
+		return fmt.Errorf("%w: frequency range [%d, %d) of %d", ErrSymbolRange, low, high, total)
+	}
+	return e.encodeRange(low, high, total)
+}
+
+// EncodeUniform writes value in range [0, max], where every value is
+// equally likely. It costs log2(max+1) bits without needing a model.
+//
+// Ranges wider than MaxTotalFreq are coded in 16-bit chunks, lowest first.
+func (e *Encoder) EncodeUniform(value, max uint64) error {
+	if value > max {
+		return fmt.Errorf("%w: %d exceeds maximum %d", ErrSymbolRange, value, max)
+	}
+
+	for max >= MaxTotalFreq {
+		if err := e.encodeRange(value&uniformChunkMask, value&uniformChunkMask+1, uniformChunkMask+1); err != nil {
+			return err
+		}
+		value >>= uniformChunkBits
+		max >>= uniformChunkBits
+	}
+	return e.encodeRange(value, value+1, max+1)
+}
+
+// encodeRange narrows the interval to the cumulative frequency range
+// [symLow, symHigh) out of total.
+func (e *Encoder) encodeRange(symLow, symHigh, total uint64) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	// Calculate the new interval
+	e.low, e.high = e.precision.narrowInterval(e.low, e.high, symLow, symHigh, total)
+
+	// Normalize the interval
+	for {
+		if e.high < half {
+			// High is in lower half, output 0
+			if err := e.output.WriteBit(0); err != nil {
+				return err
+			}
+			// Output pending 1s
+			for e.pendingBits > 0 {
+				if err := e.output.WriteBit(1); err != nil {
+					return err
+				}
+				e.pendingBits--
+			}
+		} else if e.low >= half {
+			// Low is in upper half, output 1
+			if err := e.output.WriteBit(1); err != nil {
+				return err
+			}
+			// Output pending 0s
+			for e.pendingBits > 0 {
+				if err := e.output.WriteBit(0); err != nil {
+					return err
+				}
+				e.pendingBits--
+			}
+			e.low -= half
+			e.high -= half
+		} else if e.low >= quarter && e.high < 3*quarter {
+			// Underflow: interval straddles the middle
+			e.pendingBits++
+			e.low -= quarter
+			e.high -= quarter
+		} else {
+			break
+		}
+
+		// Scale up the interval
+		e.low = (e.low << 1) & stateMax
+		e.high = ((e.high << 1) & stateMax) | 1
+	}
+
+	return nil
+}
+
+// Bits returns the information coded so far in bits: the bits output, the
+// pending underflow bits and the bits needed to narrow the state range to
+// the current interval. The difference of Bits before and after coding
+// symbols is their cost, without the termination that Close adds.
+func (e *Encoder) Bits() float64 {
+	width := float64(e.high-e.low) + 1
+	return float64(e.output.written) + float64(e.pendingBits) + stateBits - math.Log2(width)
+}
+
+// Close finalizes the encoding and flushes any remaining bits.
+func (e *Encoder) Close() error {
+	return e.terminate()
+}
+
+// Flush terminates the current segment and pads it to a whole byte, so that
+// everything encoded so far can be decoded. The encoder then starts a new
+// segment in the same stream. Decoder.NextSegment moves the decoder to the
+// next segment.
+//
+// Models are not part of the coder state: adaptive models keep their state
+// across segments unless the caller resets them.
+func (e *Encoder) Flush() error {
+	if err := e.terminate(); err != nil {
+		return err
+	}
+	e.low = 0
+	e.high = stateMax
+	e.pendingBits = 0
+	return nil
+}
+
+// terminate outputs enough bits to disambiguate the final interval and pads
+// the output to a whole byte.
+//
+// Together with the bits output for renormalization this writes two bits
+// more than the number of interval shifts, which Decoder.NextSegment relies
+// on to find the end of the segment.
+func (e *Encoder) terminate() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.eos {
+		if err := e.EncodeUniform(eosMarker, eosMax); err != nil {
+			return err
+		}
+	}
+
+	e.pendingBits++
+
+	if e.low < quarter {
+		if err := e.output.WriteBit(0); err != nil {
+			return err
+		}
+		for e.pendingBits > 0 {
+			if err := e.output.WriteBit(1); err != nil {
+				return err
+			}
+			e.pendingBits--
+		}
+	} else {
+		if err := e.output.WriteBit(1); err != nil {
+			return err
+		}
+		for e.pendingBits > 0 {
+			if err := e.output.WriteBit(0); err != nil {
+				return err
+			}
+			e.pendingBits--
+		}
+	}
+
+	return e.output.Flush()
+}
+
+// bitWriter writes individual bits to an io.Writer.
+type bitWriter struct {
+	output      io.Writer
+	accumulator byte
+	numBits     int
+	written     uint64  // Bits written, including those not yet flushed
+	buf         [1]byte // Avoids allocating for every written byte
+}
+
+func newBitWriter(w io.Writer) *bitWriter {
+	return &bitWriter{output: w}
+}
+
+func (bw *bitWriter) WriteBit(bit byte) error {
+	bw.accumulator = (bw.accumulator << 1) | (bit & 1)
+	bw.numBits++
+	bw.written++
+
+	if bw.numBits == 8 {
+		if err := bw.writeByte(bw.accumulator); err != nil {
+			return err
+		}
+		bw.accumulator = 0
+		bw.numBits = 0
+	}
+
+	return nil
+}
+
+func (bw *bitWriter) Flush() error {
+	if bw.numBits > 0 {
+		// Pad with zeros to complete the byte
+		bw.accumulator <<= (8 - bw.numBits)
+		if err := bw.writeByte(bw.accumulator); err != nil {
+			return err
+		}
+		bw.accumulator = 0
+		bw.numBits = 0
+	}
+	return nil
+}
+
+func (bw *bitWriter) writeByte(b byte) error {
+	bw.buf[0] = b
+	_, err := bw.output.Write(bw.buf[:])
+	return err
+}
@@ -0,0 +1,106 @@
+package coder
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fuzzModel builds a valid model from fuzz input. The first byte selects the
+// number of symbols and the following bytes their frequencies; the remaining
+// input is returned as the source for symbols.
+func fuzzModel(data []byte) (*testModel, []byte) {
+	numSymbols := 2
+	if len(data) > 0 {
+		numSymbols = 1 + int(data[0])
+		data = data[1:]
+	}
+
+	freqs := make([]uint64, numSymbols)
+	for i := range freqs {
+		freqs[i] = 1
+		if len(data) >= 2 {
+			// Skewed frequencies stress the precision of the coder; the
+			// total stays below MaxTotalFreq
+			freqs[i] += uint64(data[0]) << (data[1] % 12)
+			data = data[2:]
+		}
+	}
+	return &testModel{freqs: freqs}, data
+}
+
+func FuzzRoundtrip(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{3, 100, 0, 50, 0, 10, 0, 1, 0, 0, 1, 2, 3, 3, 2, 1, 0})
+	f.Add([]byte{1, 255, 15, 1, 0, 0, 0, 0, 0, 0, 0, 1, 0})
+	f.Add([]byte{255, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		model, rest := fuzzModel(data)
+		symbols := make([]int, len(rest))
+		for i, b := range rest {
+			symbols[i] = int(b) % model.SymbolCount()
+		}
+
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		for _, symbol := range symbols {
+			if err := enc.Encode(symbol, model); err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		dec, err := NewDecoder(&buf)
+		if err != nil {
+			t.Fatalf("NewDecoder failed: %v", err)
+		}
+		for i, want := range symbols {
+			got, err := dec.Decode(model)
+			if err != nil {
+				t.Fatalf("Decode at position %d failed: %v", i, err)
+			}
+			if got != want {
+				t.Fatalf("position %d: expected %d, got %d", i, want, got)
+			}
+		}
+		if err := dec.Finish(); err != nil {
+			t.Fatalf("Finish failed: %v", err)
+		}
+	})
+}
+
+func FuzzDecodeMutated(f *testing.F) {
+	f.Add([]byte{3, 100, 0, 50, 0, 10, 0, 1, 0}, []byte{0x12, 0x34, 0x56, 0x78}, false)
+	f.Add([]byte{}, []byte{}, true)
+	f.Add([]byte{255}, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, true)
+
+	// Decoding arbitrary bytes must either produce symbols or fail with an
+	// error, but never panic or loop forever.
+	f.Fuzz(func(t *testing.T, modelData, stream []byte, eos bool) {
+		model, _ := fuzzModel(modelData)
+
+		newDecoder := NewDecoder
+		if eos {
+			newDecoder = NewDecoderEOS
+		}
+		dec, err := newDecoder(bytes.NewReader(stream))
+		if err != nil {
+			return
+		}
+
+		// Every symbol consumes at least a fraction of a bit, so a bounded
+		// number of symbols suffices to read past the end of the stream
+		for i := 0; i < 64*len(stream)+64; i++ {
+			symbol, err := dec.Decode(model)
+			if err != nil {
+				return
+			}
+			if symbol < 0 || symbol >= model.SymbolCount() {
+				t.Fatalf("decoded symbol %d out of range [0, %d)", symbol, model.SymbolCount())
+			}
+		}
+		_ = dec.Finish()
+	})
+}
@@ -0,0 +1,82 @@
+// Package coder implements the arithmetic coding engine: Encoder, Decoder
+// and the bit-level IO beneath them.
+//
+// Arithmetic coding is an entropy encoding technique that represents
+// messages as fractional values, achieving compression rates close to
+// the theoretical Shannon limit.
+//
+// The coder keeps a 32-bit interval. After each symbol the interval is
+// renormalized so that its width always exceeds a quarter of the state
+// range. A symbol can therefore be coded exactly when its model's total
+// frequency is at most MaxTotalFreq; larger totals would give rare symbols
+// an empty interval and silently corrupt the stream. Encode and Decode
+// reject such models with ErrModelPrecision. How the interval is split
+// between symbols is selected with Precision, for platforms without fast
+// 64-bit arithmetic.
+//
+// A stream can hold several independently decodable segments: Encoder.Flush
+// terminates a segment and Decoder.NextSegment continues with the next one.
+//
+// The decoder detects input that ends before the encoded symbols do and
+// reports ErrTruncated. Encoders created with NewEncoderEOS additionally end
+// every segment with an end-of-stream marker, which a decoder created with
+// NewDecoderEOS verifies, so that most corrupted streams fail with ErrCorrupt
+// instead of silently decoding garbage.
+package coder
+
+import "errors"
+
+// MaxTotalFreq is the largest total frequency a model may have.
+const MaxTotalFreq = quarter
+
+// ErrModelPrecision is returned when a model's total frequency exceeds
+// MaxTotalFreq.
+var ErrModelPrecision = errors.New("coder: model total frequency exceeds coder precision")
+
+// ErrTruncated is returned when the input ends before all bits needed by the
+// decoded symbols were read.
+var ErrTruncated = errors.New("coder: input truncated")
+
+// ErrCorrupt is returned by an end-of-stream checking decoder when the
+// end-of-stream marker does not decode as written.
+var ErrCorrupt = errors.New("coder: end-of-stream marker mismatch")
+
+// ErrSymbolRange is returned when a symbol is outside the model's alphabet
+// or has an empty frequency range.
+var ErrSymbolRange = errors.New("coder: symbol out of range")
+
+// Model defines the interface for probability models used in arithmetic coding.
+// A model provides the probability distribution for symbols in the data stream.
+type Model interface {
+	// SymbolCount returns the total number of possible symbols in this model.
+	SymbolCount() int
+
+	// Freq returns the cumulative frequency range [low, high) for the given symbol.
+	// The range is relative to the total frequency returned by TotalFreq().
+	// Returns (low, high) where 0 <= low < high <= TotalFreq(),
+	// for symbol in range [0, SymbolCount()).
+	Freq(symbol int) (low, high uint64)
+
+	// TotalFreq returns the sum of all symbol frequencies.
+	// It must be in range [1, MaxTotalFreq].
+	TotalFreq() uint64
+
+	// Find returns the symbol corresponding to the given cumulative frequency.
+	// The cumFreq must be in range [0, TotalFreq()).
+	Find(cumFreq uint64) int
+
+	// Cost returns the information content of the symbol in bits,
+	// i.e. the number of bits the encoder spends on it.
+	Cost(symbol int) float64
+}
+
+// SymbolEncoder codes symbols with a Model. It is implemented by Encoder and
+// by other coding backends that derive their codes from the same models.
+type SymbolEncoder interface {
+	Encode(symbol int, model Model) error
+}
+
+// SymbolDecoder decodes symbols written by the matching SymbolEncoder.
+type SymbolDecoder interface {
+	Decode(model Model) (int, error)
+}
@@ -0,0 +1,132 @@
+package coder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// ErrPlatform is returned when the coder does not reproduce its reference
+// output on the running platform, for example because of a miscompile or an
+// unexpected byte order. Data coded on such a platform would not decode
+// elsewhere.
+var ErrPlatform = errors.New("coder: platform check failed")
+
+// CheckPlatform verifies that the bit output of the coder and the fixed-width
+// conversions used for fixed32, fixed64, float and double fields produce
+// their reference output on the running platform. The check runs once, on
+// first use by NewEncoder or NewDecoder; later calls return the same result.
+//
+// A failure wraps ErrPlatform. It is a configuration error: the encoders and
+// decoders refuse to code rather than produce data that other platforms
+// cannot read.
+func CheckPlatform() error {
+	return platformCheck()
+}
+
+var platformCheck = sync.OnceValue(checkPlatform)
+
+// Reference vector of the platform check: ranges coded with EncodeRange,
+// followed by a uniform value wider than the coder precision.
+var (
+	platformRanges = [][3]uint64{
+		{0, 40, 100}, {75, 90, 100}, {99, 100, 100}, {0, 1, 3},
+		{1, 2, 3}, {1000, 40000, MaxTotalFreq}, {5, 6, 7},
+	}
+	platformUniform    uint64 = 0x0123456789ABCDEF
+	platformUniformMax uint64 = math.MaxUint64
+)
+
+// platformOutputs are the reference outputs of the coder for the vector, by
+// precision.
+var platformOutputs = [...]string{
+	Wide:   "5c0601f1aafe5ab18de4c11880",
+	Narrow: "5c0601b1ce3dbcabb5cb08dc",
+}
+
+// checkPlatform runs the platform check.
+func checkPlatform() error {
+	if err := checkFixedWidth(); err != nil {
+		return fmt.Errorf("%w: %w", ErrPlatform, err)
+	}
+	for precision := range platformOutputs {
+		if err := checkBitIO(Precision(precision)); err != nil {
+			return fmt.Errorf("%w: %v precision: %w", ErrPlatform, Precision(precision), err)
+		}
+	}
+	return nil
+}
+
+// checkFixedWidth verifies the little-endian and IEEE 754 conversions.
+func checkFixedWidth() error {
+	var buf [8]byte
+
+	binary.LittleEndian.PutUint32(buf[:4], 0x01020304)
+	if !bytes.Equal(buf[:4], []byte{4, 3, 2, 1}) || binary.LittleEndian.Uint32(buf[:4]) != 0x01020304 {
+		return fmt.Errorf("fixed32 conversion produced %x", buf[:4])
+	}
+	binary.LittleEndian.PutUint64(buf[:], 0x0102030405060708)
+	if !bytes.Equal(buf[:], []byte{8, 7, 6, 5, 4, 3, 2, 1}) || binary.LittleEndian.Uint64(buf[:]) != 0x0102030405060708 {
+		return fmt.Errorf("fixed64 conversion produced %x", buf[:])
+	}
+
+	if bits := math.Float32bits(-1.5); bits != 0xBFC00000 || math.Float32frombits(bits) != -1.5 {
+		return fmt.Errorf("float conversion produced %#x", bits)
+	}
+	if bits := math.Float64bits(0.1); bits != 0x3FB999999999999A || math.Float64frombits(bits) != 0.1 {
+		return fmt.Errorf("double conversion produced %#x", bits)
+	}
+	return nil
+}
+
+// checkBitIO codes the reference vector with precision and compares the
+// output.
+func checkBitIO(precision Precision) error {
+	var buf bytes.Buffer
+	enc := newEncoder(&buf)
+	enc.SetPrecision(precision)
+	for _, r := range platformRanges {
+		if err := enc.EncodeRange(r[0], r[1], r[2]); err != nil {
+			return err
+		}
+	}
+	if err := enc.EncodeUniform(platformUniform, platformUniformMax); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if got, want := hex.EncodeToString(buf.Bytes()), platformOutputs[precision]; got != want {
+		return fmt.Errorf("encoder produced %s, want %s", got, want)
+	}
+
+	dec, err := newDecoder(&buf)
+	if err != nil {
+		return err
+	}
+	dec.SetPrecision(precision)
+	for i, r := range platformRanges {
+		target, err := dec.DecodeTarget(r[2])
+		if err != nil {
+			return err
+		}
+		if target < r[0] || target >= r[1] {
+			return fmt.Errorf("decoder produced %d for range %d", target, i)
+		}
+		if err := dec.DecodeRange(r[0], r[1], r[2]); err != nil {
+			return err
+		}
+	}
+	value, err := dec.DecodeUniform(platformUniformMax)
+	if err != nil {
+		return err
+	}
+	if value != platformUniform {
+		return fmt.Errorf("decoder produced %#x, want %#x", value, platformUniform)
+	}
+	return dec.Finish()
+}
@@ -0,0 +1,63 @@
+package coder
+
+// Precision selects the arithmetic that narrows the interval of the coder to
+// the range of a symbol. Both precisions keep a 32-bit interval and accept
+// the same models, but they split the interval differently: data must be
+// decoded with the precision it was encoded with.
+type Precision uint8
+
+const (
+	// Wide splits the interval with 64-bit products, so that every symbol
+	// gets exactly its share of the interval.
+	Wide Precision = iota
+	// Narrow only needs 32-bit multiplications and divisions, for
+	// microcontrollers without fast 64-bit arithmetic. The interval is
+	// split in units of interval/total, and the remainder goes to the last
+	// symbol of the model. The loss is negligible for models with totals up
+	// to 2^16, the other symbols get at least 1-total/2^30 of their share;
+	// models with totals close to MaxTotalFreq code noticeably worse.
+	Narrow
+)
+
+func (p Precision) String() string {
+	switch p {
+	case Wide:
+		return "wide"
+	case Narrow:
+		return "narrow"
+	default:
+		return "unknown"
+	}
+}
+
+// narrowInterval returns the part of the interval [low, high] that belongs
+// to the cumulative frequency range [symLow, symHigh) out of total.
+func (p Precision) narrowInterval(low, high, symLow, symHigh, total uint64) (uint64, uint64) {
+	if p == Narrow {
+		// The interval is wider than a quarter of the state range, so
+		// unit is at least 1 for totals up to MaxTotalFreq, and the
+		// products stay within the interval
+		l, h := uint32(low), uint32(high)
+		unit := (h - l) / uint32(total)
+		if symHigh < total {
+			h = l + unit*uint32(symHigh) - 1
+		}
+		l += unit * uint32(symLow)
+		return uint64(l), uint64(h)
+	}
+
+	rangeSize := high - low + 1
+	return low + (rangeSize*symLow)/total, low + (rangeSize*symHigh)/total - 1
+}
+
+// target returns the cumulative frequency, out of total, that value points
+// to within the interval [low, high].
+func (p Precision) target(low, high, value, total uint64) uint64 {
+	if p == Narrow {
+		unit := (uint32(high) - uint32(low)) / uint32(total)
+		return min(uint64((uint32(value)-uint32(low))/unit), total-1)
+	}
+
+	rangeSize := high - low + 1
+	return ((value-low+1)*total - 1) / rangeSize
+}
@@ -0,0 +1,7 @@
+//go:build coder_narrow
+
+package coder
+
+// DefaultPrecision is the precision of new encoders and decoders. Building
+// without the coder_narrow tag makes it Wide.
+const DefaultPrecision = Narrow
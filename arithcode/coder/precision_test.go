@@ -0,0 +1,147 @@
+package coder
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// The conformance profile of the precisions: every precision roundtrips
+// symbols of models with any total up to MaxTotalFreq, including the most
+// skewed ones, uniform values of any width, segments and end-of-stream
+// markers, when the decoder uses the precision of the encoder.
+func TestPrecisionConformance(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	testModels := []*testModel{
+		{freqs: []uint64{1, 1}},
+		{freqs: []uint64{900, 60, 30, 9, 1}},
+		{freqs: []uint64{1, 1<<16 - 2, 1}},
+		{freqs: []uint64{1, MaxTotalFreq - 2, 1}},
+		{freqs: []uint64{MaxTotalFreq/3 + 1, MaxTotalFreq / 3, MaxTotalFreq / 3}},
+	}
+	for range 3 {
+		freqs := make([]uint64, 1+rng.Intn(300))
+		for i := range freqs {
+			freqs[i] = 1 + uint64(rng.Intn(1<<rng.Intn(20)))
+		}
+		testModels = append(testModels, &testModel{freqs: freqs})
+	}
+
+	type step struct {
+		model   *testModel // nil for a uniform value
+		symbol  int
+		uniform uint64
+		max     uint64
+	}
+	var segments [][]step
+	for range 4 {
+		var segment []step
+		for range 300 {
+			if rng.Intn(10) == 0 {
+				max := rng.Uint64() >> rng.Intn(64)
+				segment = append(segment, step{uniform: rng.Uint64() % (max/2 + 1), max: max})
+				continue
+			}
+			model := testModels[rng.Intn(len(testModels))]
+			segment = append(segment, step{model: model, symbol: rng.Intn(model.SymbolCount())})
+		}
+		segments = append(segments, segment)
+	}
+	// Runs of the least likely symbols stress the narrowing of the interval
+	for _, model := range testModels[2:4] {
+		var segment []step
+		for range 100 {
+			segment = append(segment, step{model: model, symbol: 0}, step{model: model, symbol: 2})
+		}
+		segments = append(segments, segment)
+	}
+
+	for _, precision := range []Precision{Wide, Narrow} {
+		for _, eos := range []bool{false, true} {
+			var buf bytes.Buffer
+			enc := NewEncoder(&buf)
+			enc.eos = eos
+			enc.SetPrecision(precision)
+			for _, segment := range segments {
+				for _, s := range segment {
+					var err error
+					if s.model == nil {
+						err = enc.EncodeUniform(s.uniform, s.max)
+					} else {
+						err = enc.Encode(s.symbol, s.model)
+					}
+					if err != nil {
+						t.Fatalf("%v: encode failed: %v", precision, err)
+					}
+				}
+				if err := enc.Flush(); err != nil {
+					t.Fatalf("%v: Flush failed: %v", precision, err)
+				}
+			}
+
+			dec, err := NewDecoder(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dec.eos = eos
+			dec.SetPrecision(precision)
+			for k, segment := range segments {
+				if k > 0 {
+					if err := dec.NextSegment(); err != nil {
+						t.Fatalf("%v segment %d: NextSegment failed: %v", precision, k, err)
+					}
+				}
+				for i, s := range segment {
+					if s.model == nil {
+						got, err := dec.DecodeUniform(s.max)
+						if err != nil || got != s.uniform {
+							t.Fatalf("%v segment %d step %d: got %d, %v, want %d", precision, k, i, got, err, s.uniform)
+						}
+						continue
+					}
+					got, err := dec.Decode(s.model)
+					if err != nil || got != s.symbol {
+						t.Fatalf("%v segment %d step %d: got %d, %v, want %d", precision, k, i, got, err, s.symbol)
+					}
+				}
+			}
+			if err := dec.Finish(); err != nil {
+				t.Errorf("%v: Finish failed: %v", precision, err)
+			}
+		}
+	}
+}
+
+func TestNarrowPrecisionSize(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	model := &testModel{freqs: []uint64{30000, 20000, 10000, 5000, 500, 35, 1}}
+
+	// Symbols with the distribution of the model
+	symbols := make([]int, 20000)
+	for i := range symbols {
+		symbols[i] = model.Find(uint64(rng.Int63n(int64(model.TotalFreq()))))
+	}
+
+	var sizes [2]int
+	for i, precision := range []Precision{Wide, Narrow} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.SetPrecision(precision)
+		for _, symbol := range symbols {
+			if err := enc.Encode(symbol, model); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		sizes[i] = buf.Len()
+	}
+
+	// Models with 16-bit totals lose almost nothing
+	t.Logf("wide %d bytes, narrow %d bytes", sizes[0], sizes[1])
+	if math.Abs(float64(sizes[1]-sizes[0])) > float64(sizes[0])/1000+1 {
+		t.Errorf("narrow precision %d bytes, wide %d bytes", sizes[1], sizes[0])
+	}
+}
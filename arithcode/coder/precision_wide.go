@@ -0,0 +1,7 @@
+//go:build !coder_narrow
+
+package coder
+
+// DefaultPrecision is the precision of new encoders and decoders. Building
+// with the coder_narrow tag makes it Narrow.
+const DefaultPrecision = Wide
@@ -0,0 +1,382 @@
+// Package huffman implements a static Huffman coding backend that codes
+// symbols with the same models as the arithmetic coder.
+//
+// A canonical Huffman code is derived from each model's frequencies the first
+// time the model is used. Huffman codes spend a whole number of bits on every
+// symbol, so they compress a few percent worse than arithmetic coding, and
+// noticeably worse for very skewed models such as booleans. In exchange
+// decoding is a table walk over the code lengths, which is fast and simple
+// to port, for example to firmware.
+//
+// Codes are cached per model, so models must not change while in use:
+// adaptive models cannot be coded with this package.
+package huffman
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// MaxCodeLength is the length limit of a code word in bits. Frequencies are
+// flattened until the Huffman code fits the limit.
+const MaxCodeLength = 24
+
+// Code is a canonical Huffman code for a model.
+//
+// Code words of the same length are consecutive integers in symbol order,
+// and shorter code words precede longer ones. The code is thus fully
+// described by the code length of every symbol.
+type Code struct {
+	lengths []uint8  // Code length of every symbol
+	words   []uint32 // Code word of every symbol
+	single  int      // The only symbol of a code without code words, otherwise -1
+
+	// Decoding tables, indexed by code length
+	count  [MaxCodeLength + 1]int    // Number of code words of the length
+	first  [MaxCodeLength + 1]uint32 // First code word of the length
+	offset [MaxCodeLength + 1]int    // Index of the first symbol of the length in sorted
+	sorted []int                     // Symbols ordered by code length and symbol
+}
+
+// NewCode creates a canonical Huffman code from the frequencies of model.
+func NewCode(model coder.Model) *Code {
+	freqs := make([]uint64, model.SymbolCount())
+	for i := range freqs {
+		low, high := model.Freq(i)
+		freqs[i] = high - low
+	}
+	return NewCodeFromFrequencies(freqs)
+}
+
+// NewCodeFromFrequencies creates a canonical Huffman code from symbol
+// frequencies. Symbols with a zero frequency get no code word.
+func NewCodeFromFrequencies(freqs []uint64) *Code {
+	lengths := codeLengths(freqs)
+	for maxLength(lengths) > MaxCodeLength {
+		flattened := make([]uint64, len(freqs))
+		for i, freq := range freqs {
+			if freq > 0 {
+				flattened[i] = freq/2 + 1
+			}
+		}
+		freqs = flattened
+		lengths = codeLengths(freqs)
+	}
+
+	code := newCanonicalCode(lengths)
+	if len(code.sorted) == 0 {
+		for symbol, freq := range freqs {
+			if freq > 0 {
+				code.single = symbol
+				break
+			}
+		}
+	}
+	return code
+}
+
+// Length returns the code length of symbol in bits.
+func (c *Code) Length(symbol int) int {
+	return int(c.lengths[symbol])
+}
+
+// SymbolCount returns the number of symbols of the code.
+func (c *Code) SymbolCount() int {
+	return len(c.lengths)
+}
+
+// newCanonicalCode assigns canonical code words for the given code lengths.
+func newCanonicalCode(lengths []uint8) *Code {
+	c := &Code{
+		lengths: lengths,
+		words:   make([]uint32, len(lengths)),
+		single:  -1,
+	}
+	for _, length := range lengths {
+		if length > 0 {
+			c.count[length]++
+		}
+	}
+
+	var word uint32
+	index := 0
+	for length := 1; length <= MaxCodeLength; length++ {
+		word = (word + uint32(c.count[length-1])) << 1
+		c.first[length] = word
+		c.offset[length] = index
+		index += c.count[length]
+	}
+	c.sorted = make([]int, index)
+	next := c.offset
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		c.words[symbol] = c.first[length] + uint32(next[length]-c.offset[length])
+		c.sorted[next[length]] = symbol
+		next[length]++
+	}
+	return c
+}
+
+// codeLengths computes Huffman code lengths for the frequencies. A single
+// symbol with a non-zero frequency gets a length of zero: it is implied and
+// costs no bits.
+func codeLengths(freqs []uint64) []uint8 {
+	lengths := make([]uint8, len(freqs))
+
+	// Nodes 0..n-1 are the symbols, the rest are created while merging
+	parent := make([]int, len(freqs), 2*len(freqs))
+	var queue nodeQueue
+	for symbol, freq := range freqs {
+		parent[symbol] = -1
+		if freq > 0 {
+			queue = append(queue, node{freq: freq, index: symbol})
+		}
+	}
+	heap.Init(&queue)
+
+	for queue.Len() > 1 {
+		a := heap.Pop(&queue).(node)
+		b := heap.Pop(&queue).(node)
+		merged := len(parent)
+		parent = append(parent, -1)
+		parent[a.index] = merged
+		parent[b.index] = merged
+		heap.Push(&queue, node{freq: a.freq + b.freq, index: merged})
+	}
+
+	for symbol, freq := range freqs {
+		if freq == 0 {
+			continue
+		}
+		depth := 0
+		for p := parent[symbol]; p >= 0; p = parent[p] {
+			depth++
+		}
+		lengths[symbol] = uint8(min(depth, 255))
+	}
+	return lengths
+}
+
+// maxLength returns the longest code length.
+func maxLength(lengths []uint8) int {
+	longest := 0
+	for _, length := range lengths {
+		longest = max(longest, int(length))
+	}
+	return longest
+}
+
+// node is a symbol or a merged subtree while building the Huffman tree.
+type node struct {
+	freq  uint64
+	index int
+}
+
+// nodeQueue is a min-heap of nodes. Ties are broken by index, so that the
+// code does not depend on the heap implementation.
+type nodeQueue []node
+
+func (q nodeQueue) Len() int { return len(q) }
+func (q nodeQueue) Less(i, k int) bool {
+	if q[i].freq != q[k].freq {
+		return q[i].freq < q[k].freq
+	}
+	return q[i].index < q[k].index
+}
+func (q nodeQueue) Swap(i, k int) { q[i], q[k] = q[k], q[i] }
+func (q *nodeQueue) Push(x any)   { *q = append(*q, x.(node)) }
+func (q *nodeQueue) Pop() (x any) {
+	old := *q
+	x = old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
+
+// Encoder writes symbols as Huffman code words.
+type Encoder struct {
+	output *bitWriter
+	codes  map[coder.Model]*Code
+}
+
+// NewEncoder creates a Huffman encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		output: &bitWriter{output: w},
+		codes:  make(map[coder.Model]*Code),
+	}
+}
+
+// Encode writes symbol using the Huffman code of model.
+func (e *Encoder) Encode(symbol int, model coder.Model) error {
+	code := e.code(model)
+	if symbol < 0 || symbol >= code.SymbolCount() {
+		return fmt.Errorf("%w: %d not in [0, %d)", coder.ErrSymbolRange, symbol, code.SymbolCount())
+	}
+	if code.single >= 0 {
+		if symbol != code.single {
+			return fmt.Errorf("%w: symbol %d has zero frequency", coder.ErrSymbolRange, symbol)
+		}
+		return nil
+	}
+	length := code.lengths[symbol]
+	if length == 0 {
+		return fmt.Errorf("%w: symbol %d has zero frequency", coder.ErrSymbolRange, symbol)
+	}
+	word := code.words[symbol]
+	for i := int(length) - 1; i >= 0; i-- {
+		if err := e.output.WriteBit(byte(word >> i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close pads the last byte with zero bits and writes it.
+func (e *Encoder) Close() error {
+	return e.output.Flush()
+}
+
+// code returns the cached code of model.
+func (e *Encoder) code(model coder.Model) *Code {
+	code, ok := e.codes[model]
+	if !ok {
+		code = NewCode(model)
+		e.codes[model] = code
+	}
+	return code
+}
+
+// Decoder reads symbols written by an Encoder.
+type Decoder struct {
+	input *bitReader
+	codes map[coder.Model]*Code
+}
+
+// NewDecoder creates a Huffman decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		input: &bitReader{input: r},
+		codes: make(map[coder.Model]*Code),
+	}
+}
+
+// Decode reads the next symbol using the Huffman code of model. It returns
+// coder.ErrTruncated when the input ends within a code word.
+func (d *Decoder) Decode(model coder.Model) (int, error) {
+	code, ok := d.codes[model]
+	if !ok {
+		code = NewCode(model)
+		d.codes[model] = code
+	}
+	if code.single >= 0 {
+		return code.single, nil
+	}
+
+	var word uint32
+	for length := 1; length <= MaxCodeLength; length++ {
+		bit, err := d.input.ReadBit()
+		if err == io.EOF {
+			return 0, coder.ErrTruncated
+		} else if err != nil {
+			return 0, err
+		}
+		word = word<<1 | uint32(bit)
+		if index := int(word - code.first[length]); word >= code.first[length] && index < code.count[length] {
+			return code.sorted[code.offset[length]+index], nil
+		}
+	}
+	// Huffman codes are complete, so every bit sequence decodes
+	return 0, coder.ErrCorrupt
+}
+
+// Finish verifies the end of the stream, after all symbols have been
+// decoded: the padding of the last byte must be zero bits.
+func (d *Decoder) Finish() error {
+	for d.input.numBits > 0 {
+		bit, _ := d.input.ReadBit()
+		if bit != 0 {
+			return coder.ErrCorrupt
+		}
+	}
+	return nil
+}
+
+// Len returns the length of the stream in bytes. It must be called after
+// Finish and before HasTrailingData.
+func (d *Decoder) Len() int {
+	return d.input.read
+}
+
+// HasTrailingData reports whether the input continues after the last byte
+// of the stream. It must be called after Finish.
+func (d *Decoder) HasTrailingData() (bool, error) {
+	_, err := d.input.ReadBit()
+	if err == io.EOF {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// bitWriter writes individual bits, most significant bit first.
+type bitWriter struct {
+	output      io.Writer
+	accumulator byte
+	numBits     int
+}
+
+func (bw *bitWriter) WriteBit(bit byte) error {
+	bw.accumulator = bw.accumulator<<1 | bit&1
+	bw.numBits++
+	if bw.numBits == 8 {
+		if _, err := bw.output.Write([]byte{bw.accumulator}); err != nil {
+			return err
+		}
+		bw.accumulator = 0
+		bw.numBits = 0
+	}
+	return nil
+}
+
+func (bw *bitWriter) Flush() error {
+	if bw.numBits == 0 {
+		return nil
+	}
+	bw.accumulator <<= 8 - bw.numBits
+	if _, err := bw.output.Write([]byte{bw.accumulator}); err != nil {
+		return err
+	}
+	bw.accumulator = 0
+	bw.numBits = 0
+	return nil
+}
+
+// bitReader reads individual bits, most significant bit first.
+type bitReader struct {
+	input       io.Reader
+	accumulator byte
+	numBits     int
+	read        int // Bytes read from input
+	buf         [1]byte
+}
+
+func (br *bitReader) ReadBit() (byte, error) {
+	if br.numBits == 0 {
+		n, err := br.input.Read(br.buf[:])
+		if n == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			return 0, err
+		}
+		br.accumulator = br.buf[0]
+		br.numBits = 8
+		br.read++
+	}
+	br.numBits--
+	return (br.accumulator >> br.numBits) & 1, nil
+}
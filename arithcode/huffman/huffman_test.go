@@ -0,0 +1,156 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+func TestCodeLengths(t *testing.T) {
+	code := NewCodeFromFrequencies([]uint64{50, 25, 12, 13})
+	want := []int{1, 2, 3, 3}
+	for symbol, length := range want {
+		if got := code.Length(symbol); got != length {
+			t.Errorf("symbol %d: expected length %d, got %d", symbol, length, got)
+		}
+	}
+}
+
+func TestCodeLengthLimit(t *testing.T) {
+	// Fibonacci frequencies give the deepest possible Huffman tree
+	freqs := []uint64{1, 1}
+	for len(freqs) < 40 {
+		freqs = append(freqs, freqs[len(freqs)-1]+freqs[len(freqs)-2])
+	}
+
+	code := NewCodeFromFrequencies(freqs)
+	for symbol := range freqs {
+		if length := code.Length(symbol); length == 0 || length > MaxCodeLength {
+			t.Errorf("symbol %d: length %d not in [1, %d]", symbol, length, MaxCodeLength)
+		}
+	}
+	roundtrip(t, models.NewFrequencyTable(freqs), []int{0, 1, 39, 38, 20, 0})
+}
+
+func TestRoundtrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, model := range []coder.Model{
+		models.NewUniformModel(2),
+		models.NewUniformModel(5),
+		models.NewUniformModel(256),
+		models.NewFrequencyTable([]uint64{950, 50}),
+		models.SharedEnglishModelEOS(),
+	} {
+		symbols := make([]int, 1000)
+		for i := range symbols {
+			symbols[i] = rng.Intn(model.SymbolCount())
+		}
+		roundtrip(t, model, symbols)
+	}
+}
+
+func TestSingleSymbol(t *testing.T) {
+	model := models.NewUniformModel(1)
+	if size := roundtrip(t, model, []int{0, 0, 0}); size != 0 {
+		t.Errorf("implied symbols should take no space, got %d bytes", size)
+	}
+}
+
+func TestEfficiency(t *testing.T) {
+	model := models.SharedEnglishModelEOS()
+	code := NewCode(model)
+
+	// The expected code length is within a bit of the entropy
+	var entropy, expected float64
+	total := float64(model.TotalFreq())
+	for symbol := 0; symbol < model.SymbolCount(); symbol++ {
+		low, high := model.Freq(symbol)
+		p := float64(high-low) / total
+		entropy -= p * math.Log2(p)
+		expected += p * float64(code.Length(symbol))
+	}
+	t.Logf("entropy %.3f bits, Huffman %.3f bits per symbol", entropy, expected)
+	if expected < entropy || expected > entropy+1 {
+		t.Errorf("expected length %.3f not within a bit of entropy %.3f", expected, entropy)
+	}
+}
+
+func TestTruncatedAndTrailing(t *testing.T) {
+	model := models.NewUniformModel(256)
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, symbol := range []int{1, 2, 3} {
+		if err := enc.Encode(symbol, model); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	dec := NewDecoder(bytes.NewReader(buf.Bytes()[:2]))
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		_, err = dec.Decode(model)
+	}
+	if !errors.Is(err, coder.ErrTruncated) {
+		t.Errorf("expected ErrTruncated, got %v", err)
+	}
+
+	dec = NewDecoder(bytes.NewReader(append(buf.Bytes(), 0)))
+	for i := 0; i < 3; i++ {
+		if _, err := dec.Decode(model); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+	}
+	if err := dec.Finish(); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	if got := dec.Len(); got != buf.Len() {
+		t.Errorf("Len() = %d, want %d", got, buf.Len())
+	}
+	if trailing, err := dec.HasTrailingData(); err != nil || !trailing {
+		t.Errorf("expected trailing data, got %v, %v", trailing, err)
+	}
+}
+
+// roundtrip codes symbols with model and returns the encoded size.
+func roundtrip(t *testing.T, model coder.Model, symbols []int) int {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, symbol := range symbols {
+		if err := enc.Encode(symbol, model); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	size := buf.Len()
+
+	dec := NewDecoder(&buf)
+	for i, want := range symbols {
+		got, err := dec.Decode(model)
+		if err != nil {
+			t.Fatalf("Decode at position %d failed: %v", i, err)
+		}
+		if got != want {
+			t.Fatalf("position %d: expected %d, got %d", i, want, got)
+		}
+	}
+	if err := dec.Finish(); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	if trailing, err := dec.HasTrailingData(); err != nil || trailing {
+		t.Fatalf("unexpected trailing data: %v, %v", trailing, err)
+	}
+	return size
+}
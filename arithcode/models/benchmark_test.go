@@ -0,0 +1,219 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// BenchmarkEncodeString benchmarks string encoding with various sizes.
+func BenchmarkEncodeString(b *testing.B) {
+	text := "The quick brown fox jumps over the lazy dog. This is a test of compression performance. "
+
+	benchmarks := []struct {
+		name string
+		text string
+	}{
+		{"Small_100B", repeatText(text, 100)},
+		{"Medium_1KB", repeatText(text, 1024)},
+		{"Large_10KB", repeatText(text, 10*1024)},
+		{"VeryLarge_100KB", repeatText(text, 100*1024)},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(int64(len(bm.text)))
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				var buf bytes.Buffer
+				if err := EncodeString(bm.text, &buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkDecodeString benchmarks string decoding with various sizes.
+func BenchmarkDecodeString(b *testing.B) {
+	text := "The quick brown fox jumps over the lazy dog. This is a test of compression performance. "
+
+	benchmarks := []struct {
+		name string
+		text string
+	}{
+		{"Small_100B", repeatText(text, 100)},
+		{"Medium_1KB", repeatText(text, 1024)},
+		{"Large_10KB", repeatText(text, 10*1024)},
+		{"VeryLarge_100KB", repeatText(text, 100*1024)},
+	}
+
+	for _, bm := range benchmarks {
+		// Pre-compress the data
+		var compressed bytes.Buffer
+		if err := EncodeString(bm.text, &compressed); err != nil {
+			b.Fatal(err)
+		}
+		compressedData := compressed.Bytes()
+
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(int64(len(bm.text)))
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				_, err := DecodeString(bytes.NewReader(compressedData))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkFrequencyTable benchmarks symbol lookup in static tables, which
+// use the cumulative array, and in adaptive tables, which use the Fenwick
+// tree.
+func BenchmarkFrequencyTable(b *testing.B) {
+	for _, size := range []int{2, 64, 256, 4096} {
+		freqs := make([]uint64, size)
+		for i := range freqs {
+			freqs[i] = uint64(1 + (i*7919)%100)
+		}
+
+		static := NewFrequencyTable(freqs)
+		adaptive := NewFrequencyTable(freqs)
+		adaptive.Add(0, 1)
+
+		for _, bm := range []struct {
+			name  string
+			table *FrequencyTable
+		}{
+			{"Static", static},
+			{"Adaptive", adaptive},
+		} {
+			b.Run(fmt.Sprintf("%s_%d", bm.name, size), func(b *testing.B) {
+				total := bm.table.TotalFreq()
+				var sink uint64
+				for i := 0; i < b.N; i++ {
+					symbol := bm.table.Find(uint64(i*7919) % total)
+					low, high := bm.table.Freq(symbol)
+					sink += high - low
+				}
+				_ = sink
+			})
+		}
+	}
+}
+
+// BenchmarkFrequencyTableCoding compares coding through the Model interface
+// with the direct FrequencyTable.Encode and Decode methods.
+func BenchmarkFrequencyTableCoding(b *testing.B) {
+	const numSymbols = 4096
+
+	freqs := make([]uint64, 256)
+	for i := range freqs {
+		freqs[i] = uint64(1 + (i*7919)%100)
+	}
+	symbols := make([]int, numSymbols)
+	for i := range symbols {
+		symbols[i] = (i * i * 31) % len(freqs)
+	}
+
+	static := NewFrequencyTable(freqs)
+	adaptive := NewFrequencyTable(freqs)
+	adaptive.Add(0, 1)
+
+	for _, table := range []struct {
+		name  string
+		table *FrequencyTable
+	}{
+		{"Static", static},
+		{"Adaptive", adaptive},
+	} {
+		ft := table.table
+		var model coder.Model = ft
+
+		var encoded bytes.Buffer
+		enc := coder.NewEncoder(&encoded)
+		for _, symbol := range symbols {
+			if err := enc.Encode(symbol, model); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(table.name+"/Encode/Interface", func(b *testing.B) {
+			var buf bytes.Buffer
+			enc := coder.NewEncoder(&buf)
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				enc.Reset(&buf)
+				for _, symbol := range symbols {
+					if err := enc.Encode(symbol, model); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*numSymbols), "ns/symbol")
+		})
+		b.Run(table.name+"/Encode/Direct", func(b *testing.B) {
+			var buf bytes.Buffer
+			enc := coder.NewEncoder(&buf)
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				enc.Reset(&buf)
+				for _, symbol := range symbols {
+					if err := ft.Encode(enc, symbol); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*numSymbols), "ns/symbol")
+		})
+		b.Run(table.name+"/Decode/Interface", func(b *testing.B) {
+			input := bytes.NewReader(encoded.Bytes())
+			dec, err := coder.NewDecoder(input)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for i := 0; i < b.N; i++ {
+				input.Reset(encoded.Bytes())
+				if err := dec.Reset(input); err != nil {
+					b.Fatal(err)
+				}
+				for range symbols {
+					if _, err := dec.Decode(model); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*numSymbols), "ns/symbol")
+		})
+		b.Run(table.name+"/Decode/Direct", func(b *testing.B) {
+			input := bytes.NewReader(encoded.Bytes())
+			dec, err := coder.NewDecoder(input)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for i := 0; i < b.N; i++ {
+				input.Reset(encoded.Bytes())
+				if err := dec.Reset(input); err != nil {
+					b.Fatal(err)
+				}
+				for range symbols {
+					if _, err := ft.Decode(dec); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*numSymbols), "ns/symbol")
+		})
+	}
+}
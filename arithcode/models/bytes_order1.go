@@ -0,0 +1,206 @@
+package models
+
+import (
+	"errors"
+	"io"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+const (
+	// bytesStartContext is the context before the first byte.
+	bytesStartContext = 256
+
+	// bytesIncrement is added to a byte's count in its context each time
+	// it is coded.
+	bytesIncrement = 32
+
+	// bytesMaxTotal is the count total at which a context is rescaled.
+	bytesMaxTotal = 1 << 16
+)
+
+// MaxLength is the longest string or payload supported by the coders with a
+// length prefix, the range of their 4-byte length varint. Decoders also use
+// it to limit the lengths they read, so that corrupted input cannot allocate
+// without bound.
+const MaxLength = 1<<28 - 1
+
+// BytesOrder1Model is an adaptive order-1 model for binary payloads.
+//
+// The next byte is predicted from the previous one. Each context starts from
+// a prior shaped after typical protobuf and binary payloads: zero bytes and
+// repeated bytes are common, small values dominate, bytes with the high bit
+// set are usually varint continuations followed by smaller bytes, and
+// protobuf field tags (wire types 0, 2 and 5 of low field numbers) are
+// frequent. Counts adapt while coding, so repeated structures in a payload
+// become cheap.
+//
+// The encoder and decoder must code the same payloads in the same order with
+// identically initialized models.
+type BytesOrder1Model struct {
+	contexts [257]*FrequencyTable // Created on first use
+	prior    [256]uint64          // Scratch space for Reset
+}
+
+// NewBytesOrder1Model creates an order-1 bytes model.
+func NewBytesOrder1Model() *BytesOrder1Model {
+	return &BytesOrder1Model{}
+}
+
+// GetModel returns the model for the byte following prev.
+// Use -1 for the start of a payload.
+func (m *BytesOrder1Model) GetModel(prev int) coder.Model {
+	return m.context(prev)
+}
+
+func (m *BytesOrder1Model) context(prev int) *FrequencyTable {
+	if prev < 0 {
+		prev = bytesStartContext
+	}
+	if model := m.contexts[prev]; model != nil {
+		return model
+	}
+
+	model := newAdaptiveFrequencyTable(bytesPrior(make([]uint64, 256), prev), bytesMaxTotal)
+	m.contexts[prev] = model
+	return model
+}
+
+// Reset restores the initial state of the model. The contexts created so far
+// are kept and reinitialized in place, so that a model reused for many
+// payloads does not allocate.
+func (m *BytesOrder1Model) Reset() {
+	for prev, model := range m.contexts {
+		if model != nil {
+			model.resetAdaptive(bytesPrior(m.prior[:], prev))
+		}
+	}
+}
+
+// bytesPrior fills freqs with the initial frequencies of the 256 bytes
+// following prev and returns it.
+func bytesPrior(freqs []uint64, prev int) []uint64 {
+	for b := range freqs {
+		freq := uint64(4)
+
+		switch {
+		case b == 0x00:
+			freq = 96
+		case b == 0xFF:
+			freq = 24
+		case b < 0x20:
+			freq = 24
+		case b < 0x80:
+			freq = 12
+		}
+
+		// Protobuf tags of low field numbers: varint, length and fixed32
+		if b < 0x80 && b >= 0x08 {
+			switch b & 7 {
+			case 0, 2, 5:
+				freq += 16
+			}
+		}
+
+		switch {
+		case b == prev:
+			// Runs of zeros, padding and repeated values
+			freq += 48
+		case prev >= 0x80 && prev < 0x100 && b < 0x80:
+			// Varint continuation bytes are followed by smaller bytes
+			freq += 8
+		}
+
+		freqs[b] = freq
+	}
+	return freqs
+}
+
+// Encode writes data into enc and updates the model.
+// The length is not coded; the caller must transmit it.
+func (m *BytesOrder1Model) Encode(enc *coder.Encoder, data []byte) error {
+	prev := -1
+	for _, b := range data {
+		model := m.context(prev)
+		if err := model.Encode(enc, int(b)); err != nil {
+			return err
+		}
+		model.Add(int(b), bytesIncrement)
+		prev = int(b)
+	}
+	return nil
+}
+
+// Decode reads n bytes written by Encode and updates the model.
+func (m *BytesOrder1Model) Decode(dec *coder.Decoder, n int) ([]byte, error) {
+	if n < 0 || n > MaxLength {
+		return nil, errors.New("bytes: invalid length")
+	}
+
+	data := make([]byte, n)
+	prev := -1
+	for i := range data {
+		model := m.context(prev)
+		b, err := model.Decode(dec)
+		if err != nil {
+			return nil, err
+		}
+		model.Add(b, bytesIncrement)
+		data[i] = byte(b)
+		prev = b
+	}
+	return data, nil
+}
+
+// EncodeBytesOrder1 encodes a binary payload, prefixed with its length,
+// using a fresh order-1 bytes model.
+func EncodeBytesOrder1(data []byte, w io.Writer) error {
+	enc := coder.NewEncoder(w)
+	byteModel := NewUniformModel(256)
+
+	// Encode length as varint
+	tempLen := len(data)
+	for i := 0; i < 4; i++ {
+		b := byte(tempLen & 0x7F)
+		tempLen >>= 7
+		if tempLen > 0 {
+			b |= 0x80
+		}
+		if err := enc.Encode(int(b), byteModel); err != nil {
+			return err
+		}
+		if tempLen == 0 {
+			break
+		}
+	}
+
+	if err := NewBytesOrder1Model().Encode(enc, data); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// DecodeBytesOrder1 decodes a payload written by EncodeBytesOrder1.
+func DecodeBytesOrder1(r io.Reader) ([]byte, error) {
+	dec, err := coder.NewDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+	byteModel := NewUniformModel(256)
+
+	// Decode length
+	var length int
+	for i := 0; i < 4; i++ {
+		symbol, err := dec.Decode(byteModel)
+		if err != nil {
+			return nil, err
+		}
+		b := byte(symbol)
+		length |= int(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	return NewBytesOrder1Model().Decode(dec, length)
+}
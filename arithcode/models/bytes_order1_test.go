@@ -0,0 +1,86 @@
+package models
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// sampleBinaryPayload resembles a batch of serialized protobuf records.
+func sampleBinaryPayload() []byte {
+	var data []byte
+	for i := 0; i < 20; i++ {
+		data = append(data, 0x08)
+		data = binary.AppendUvarint(data, uint64(1000+i*7))
+		data = append(data, 0x15)
+		data = binary.LittleEndian.AppendUint32(data, uint32(375317890+i*13))
+		data = append(data, 0x1a, 0x04, 0x00, 0x00, 0x00, byte(i))
+	}
+	return data
+}
+
+func TestBytesOrder1Roundtrip(t *testing.T) {
+	tests := [][]byte{
+		nil,
+		{0},
+		{0xFF, 0xFF, 0xFF},
+		[]byte("plain text bytes"),
+		sampleBinaryPayload(),
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := EncodeBytesOrder1(test, &buf); err != nil {
+			t.Fatalf("EncodeBytesOrder1 failed: %v", err)
+		}
+
+		result, err := DecodeBytesOrder1(&buf)
+		if err != nil {
+			t.Fatalf("DecodeBytesOrder1 failed: %v", err)
+		}
+		if !bytes.Equal(result, test) {
+			t.Errorf("Decoded bytes don't match:\noriginal: %x\nresult:   %x", test, result)
+		}
+	}
+}
+
+func TestBytesOrder1Compression(t *testing.T) {
+	data := sampleBinaryPayload()
+
+	var buf bytes.Buffer
+	if err := EncodeBytesOrder1(data, &buf); err != nil {
+		t.Fatalf("EncodeBytesOrder1 failed: %v", err)
+	}
+
+	t.Logf("Original: %d bytes, order-1: %d bytes", len(data), buf.Len())
+	if buf.Len() >= len(data)*3/4 {
+		t.Errorf("Expected at least 25%% reduction, got %d of %d bytes", buf.Len(), len(data))
+	}
+}
+
+func TestBytesOrder1Reset(t *testing.T) {
+	payload := sampleBinaryPayload()
+	encode := func(m *BytesOrder1Model) []byte {
+		var buf bytes.Buffer
+		enc := coder.NewEncoder(&buf)
+		if err := m.Encode(enc, payload); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	model := NewBytesOrder1Model()
+	first := encode(model)
+	if adapted := encode(model); bytes.Equal(adapted, first) {
+		t.Fatal("model did not adapt to the first payload")
+	}
+	model.Reset()
+	if reset := encode(model); !bytes.Equal(reset, first) {
+		t.Error("model after Reset codes differently from a new model")
+	}
+}
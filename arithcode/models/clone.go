@@ -0,0 +1,126 @@
+package models
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// Cloner is implemented by the coder.Models whose state changes while
+// coding; the other adaptive models have Clone methods. A clone starts from
+// the state of the model and then adapts independently of it.
+type Cloner interface {
+	// CloneModel returns an independent copy of the model.
+	CloneModel() coder.Model
+}
+
+// Clone returns an independent copy of m when m is a Cloner. Static models
+// are returned as is, since they can be shared.
+func Clone(m coder.Model) coder.Model {
+	if c, ok := m.(Cloner); ok {
+		return c.CloneModel()
+	}
+	return m
+}
+
+// Clone returns an independent copy of the table.
+func (ft *FrequencyTable) Clone() *FrequencyTable {
+	c := *ft
+	c.tree = slices.Clone(ft.tree)
+	c.cum = slices.Clone(ft.cum)
+	return &c
+}
+
+// CloneModel implements Cloner.
+func (ft *FrequencyTable) CloneModel() coder.Model { return ft.Clone() }
+
+// Clone returns an independent copy of the model. The static model is
+// shared.
+func (m *EscapeModel) Clone() *EscapeModel {
+	c := *m
+	c.adaptive = m.adaptive.Clone()
+	return &c
+}
+
+// CloneModel implements Cloner.
+func (m *EscapeModel) CloneModel() coder.Model { return m.Clone() }
+
+// Clone returns an independent copy of the model.
+func (m *PPMModel) Clone() *PPMModel {
+	c := &PPMModel{
+		order:           m.order,
+		contexts:        make(map[string]*ppmContext, len(m.contexts)),
+		maxContextTotal: m.maxContextTotal,
+	}
+	for key, ctx := range m.contexts {
+		c.contexts[key] = &ppmContext{
+			symbols: slices.Clone(ctx.symbols),
+			counts:  slices.Clone(ctx.counts),
+			total:   ctx.total,
+		}
+	}
+	return c
+}
+
+// ResetFrom sets m to a copy of src, reusing the memory of m. A model that
+// started as a copy of src and adapted since is restored without
+// allocating, which suits pooled models that start from a trained state.
+func (m *PPMModel) ResetFrom(src *PPMModel) {
+	m.order = src.order
+	m.maxContextTotal = src.maxContextTotal
+	if m.contexts == nil {
+		m.contexts = make(map[string]*ppmContext, len(src.contexts))
+	}
+	for key := range m.contexts {
+		if _, ok := src.contexts[key]; !ok {
+			delete(m.contexts, key)
+		}
+	}
+	for key, ctx := range src.contexts {
+		c, ok := m.contexts[key]
+		if !ok {
+			c = &ppmContext{}
+			m.contexts[key] = c
+		}
+		c.symbols = append(c.symbols[:0], ctx.symbols...)
+		c.counts = append(c.counts[:0], ctx.counts...)
+		c.total = ctx.total
+	}
+}
+
+// Clone returns an independent copy of the model.
+func (m *BytesOrder1Model) Clone() *BytesOrder1Model {
+	c := &BytesOrder1Model{}
+	for prev, ctx := range m.contexts {
+		if ctx != nil {
+			c.contexts[prev] = ctx.Clone()
+		}
+	}
+	return c
+}
+
+// Clone returns an independent copy of the model that codes text with text,
+// which is usually a clone of the text model of m.
+func (m *EmojiTextModel) Clone(text *PPMModel) *EmojiTextModel {
+	c := &EmojiTextModel{
+		text:     text,
+		emoji:    m.emoji.Clone(),
+		variants: m.variants.Clone(),
+	}
+	for i, segment := range m.segments {
+		c.segments[i] = segment.Clone()
+	}
+	return c
+}
+
+// Clone returns an independent copy of the model.
+func (m *UnicodeModel) Clone() *UnicodeModel {
+	c := *m
+	c.pageModel = m.pageModel.Clone()
+	c.offsets = maps.Clone(m.offsets)
+	for page, offsets := range c.offsets {
+		c.offsets[page] = offsets.Clone()
+	}
+	return &c
+}
@@ -1,4 +1,4 @@
-package arithcode
+package models
 
 import (
 	"bytes"
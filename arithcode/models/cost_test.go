@@ -0,0 +1,59 @@
+package models
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+func TestModelCost(t *testing.T) {
+	uniform := NewUniformModel(256)
+	if cost := uniform.Cost(17); math.Abs(cost-8) > 1e-9 {
+		t.Errorf("Uniform(256) cost: expected 8 bits, got %f", cost)
+	}
+
+	table := NewFrequencyTable([]uint64{1, 1, 2})
+	expected := []float64{2, 2, 1}
+	for symbol, want := range expected {
+		if cost := table.Cost(symbol); math.Abs(cost-want) > 1e-9 {
+			t.Errorf("Symbol %d: expected %f bits, got %f", symbol, want, cost)
+		}
+	}
+}
+
+func TestEstimateBitsMatchesEncoder(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+
+	freqs := make([]uint64, 40)
+	for i := range freqs {
+		freqs[i] = 1 + uint64(rng.Intn(200))
+	}
+	model := NewFrequencyTable(freqs)
+
+	symbols := make([]int, 5000)
+	for i := range symbols {
+		symbols[i] = model.Find(uint64(rng.Int63n(int64(model.TotalFreq()))))
+	}
+
+	var buf bytes.Buffer
+	enc := coder.NewEncoder(&buf)
+	for _, symbol := range symbols {
+		if err := enc.Encode(symbol, model); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	estimated := coder.EstimateBytes(coder.EstimateBits(symbols, model))
+	actual := buf.Len()
+	t.Logf("Estimated: %d bytes, Actual: %d bytes", estimated, actual)
+
+	if diff := actual - estimated; diff < -1 || diff > 2 {
+		t.Errorf("Estimate %d bytes too far from actual %d bytes", estimated, actual)
+	}
+}
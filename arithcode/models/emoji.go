@@ -0,0 +1,216 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// commonEmoji lists emoji frequently used in short messages, by decreasing
+// popularity. The order is part of the format and must not change.
+var commonEmoji = []rune{
+	'👍', '❤', '😂', '👋', '🙏', '😊', '😀', '🔥', '👌', '✅',
+	'😁', '😅', '🤣', '😍', '😎', '😉', '🎉', '💪', '👏', '🙂',
+	'😢', '😭', '🤔', '😮', '😡', '👀', '💯', '⚡', '📡', '📍',
+	'🏠', '⛺', '🚗', '⛰', '🌲', '☀', '🌧', '❄', '🔋', '📶',
+	'🆗', '❗', '❓', '👎', '🤙', '🍺', '☕', '🐕', '⭐', '🌙',
+	'🛰', '🚶', '🚴', '🏕', '🎯', '🗺', '📻', '🔴', '🟢', '🟡',
+	'⚠', '💩', '🤘', '😴', '🥶', '🥵', '😃', '😆', '🥳', '😬',
+	'🙄', '😱', '🤷', '🙌', '✌', '🤞', '💙', '💚', '💛', '🧡',
+	'💜', '🖤', '💔', '✨', '🌟', '🌈', '☁', '⛅', '🌊', '🏔',
+	'🚙', '🚲', '✈', '🚁', '⛵', '📱', '💻', '🔧', '🔌', '🆘',
+}
+
+// emojiIndex maps an emoji codepoint to its index in commonEmoji.
+var emojiIndex = func() map[rune]int {
+	index := make(map[rune]int, len(commonEmoji))
+	for i, r := range commonEmoji {
+		index[r] = i
+	}
+	return index
+}()
+
+// Emoji presentation variants that may follow an emoji codepoint.
+var emojiVariants = []rune{
+	0,       // Bare codepoint
+	0xFE0F,  // Variation selector 16, emoji presentation
+	0x1F3FB, // Skin tone modifiers, light to dark
+	0x1F3FC,
+	0x1F3FD,
+	0x1F3FE,
+	0x1F3FF,
+}
+
+// EmojiCount returns the number of emoji with a compact index.
+func EmojiCount() int { return len(commonEmoji) }
+
+// EmojiIndex returns the compact index of an emoji codepoint.
+func EmojiIndex(r rune) (int, bool) {
+	index, ok := emojiIndex[r]
+	return index, ok
+}
+
+// EmojiRune returns the emoji codepoint for a compact index.
+func EmojiRune(index int) rune { return commonEmoji[index] }
+
+// Segment kinds of an emoji text string.
+const (
+	emojiSegmentText = iota
+	emojiSegmentEmoji
+	emojiSegmentEnd
+
+	emojiSegmentStart = emojiSegmentEnd // Context before the first segment
+)
+
+// EmojiTextModel codes text containing emoji.
+//
+// A string is split into runs of text and common emoji. Text runs are coded
+// with the wrapped PPM model, while each emoji is coded as its compact index
+// followed by its presentation variant (none, emoji presentation selector
+// or skin tone). Emoji outside the common set are coded as text.
+//
+// The model adapts while coding, so the encoder and decoder must code the
+// same strings in the same order with identically initialized models.
+type EmojiTextModel struct {
+	text *PPMModel
+
+	segments [3]*FrequencyTable // Next segment kind, by previous kind
+	emoji    *FrequencyTable
+	variants *FrequencyTable
+}
+
+// NewEmojiTextModel creates an emoji text model that codes text runs with text.
+func NewEmojiTextModel(text *PPMModel) *EmojiTextModel {
+	m := &EmojiTextModel{text: text}
+
+	m.segments[emojiSegmentText] = newAdaptiveTable([]uint64{1, 16, 64})
+	m.segments[emojiSegmentEmoji] = newAdaptiveTable([]uint64{32, 16, 32})
+	m.segments[emojiSegmentStart] = newAdaptiveTable([]uint64{64, 8, 1})
+
+	freqs := make([]uint64, len(commonEmoji))
+	for i := range freqs {
+		// Zipf's law: frequency is inversely proportional to rank
+		freqs[i] = 1 + 256/uint64(i+1)
+	}
+	m.emoji = newAdaptiveTable(freqs)
+	m.variants = newAdaptiveTable([]uint64{64, 32, 2, 2, 2, 2, 2})
+
+	return m
+}
+
+// EncodeString encodes s into enc and updates the model.
+func (m *EmojiTextModel) EncodeString(enc *coder.Encoder, s string) error {
+	prev := emojiSegmentStart
+	for len(s) > 0 {
+		end := nextEmoji(s)
+		if end > 0 {
+			if err := m.encodeSegment(enc, prev, emojiSegmentText); err != nil {
+				return err
+			}
+			if err := m.text.EncodeString(enc, s[:end]); err != nil {
+				return err
+			}
+			s = s[end:]
+			prev = emojiSegmentText
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+
+		variant := 0
+		if next, nextSize := utf8.DecodeRuneInString(s); len(s) > 0 {
+			for i, v := range emojiVariants[1:] {
+				if next == v {
+					variant = i + 1
+					s = s[nextSize:]
+					break
+				}
+			}
+		}
+
+		if err := m.encodeSegment(enc, prev, emojiSegmentEmoji); err != nil {
+			return err
+		}
+		index := emojiIndex[r]
+		if err := enc.Encode(index, m.emoji); err != nil {
+			return err
+		}
+		adaptTable(m.emoji, index)
+		if err := enc.Encode(variant, m.variants); err != nil {
+			return err
+		}
+		adaptTable(m.variants, variant)
+		prev = emojiSegmentEmoji
+	}
+
+	return m.encodeSegment(enc, prev, emojiSegmentEnd)
+}
+
+// DecodeString decodes a string written by EncodeString and updates the model.
+func (m *EmojiTextModel) DecodeString(dec *coder.Decoder) (string, error) {
+	var result strings.Builder
+	prev := emojiSegmentStart
+	for {
+		kind, err := dec.Decode(m.segments[prev])
+		if err != nil {
+			return "", err
+		}
+		adaptTable(m.segments[prev], kind)
+
+		switch kind {
+		case emojiSegmentEnd:
+			return result.String(), nil
+
+		case emojiSegmentText:
+			text, err := m.text.DecodeString(dec)
+			if err != nil {
+				return "", err
+			}
+			result.WriteString(text)
+
+		case emojiSegmentEmoji:
+			index, err := dec.Decode(m.emoji)
+			if err != nil {
+				return "", err
+			}
+			adaptTable(m.emoji, index)
+			variant, err := dec.Decode(m.variants)
+			if err != nil {
+				return "", err
+			}
+			adaptTable(m.variants, variant)
+
+			result.WriteRune(commonEmoji[index])
+			if variant > 0 {
+				result.WriteRune(emojiVariants[variant])
+			}
+		}
+
+		if result.Len() > ppmMaxStringLength {
+			return "", errors.New("emoji: string exceeds maximum length")
+		}
+		prev = kind
+	}
+}
+
+// encodeSegment codes the kind of the next segment.
+func (m *EmojiTextModel) encodeSegment(enc *coder.Encoder, prev, kind int) error {
+	if err := enc.Encode(kind, m.segments[prev]); err != nil {
+		return err
+	}
+	adaptTable(m.segments[prev], kind)
+	return nil
+}
+
+// nextEmoji returns the length of the text before the first common emoji in s.
+func nextEmoji(s string) int {
+	for i, r := range s {
+		if _, ok := emojiIndex[r]; ok {
+			return i
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+func TestEmojiIndex(t *testing.T) {
+	for i := 0; i < EmojiCount(); i++ {
+		index, ok := EmojiIndex(EmojiRune(i))
+		if !ok || index != i {
+			t.Errorf("Emoji %d: EmojiIndex returned %d, %v", i, index, ok)
+		}
+	}
+	if _, ok := EmojiIndex('a'); ok {
+		t.Error("'a' must not be an emoji")
+	}
+}
+
+func TestEmojiTextRoundtrip(t *testing.T) {
+	tests := []string{
+		"",
+		"👍",
+		"❤️",
+		"👍🏽👍🏿",
+		"On my way 🚗 see you soon 😊",
+		"🔥🔥🔥",
+		"Rare emoji 🦩 stays text",
+		"Text with invalid \xff byte 👍",
+	}
+
+	var buf bytes.Buffer
+	enc := coder.NewEncoder(&buf)
+	model := NewEmojiTextModel(NewEnglishPPMModel())
+	for _, test := range tests {
+		if err := model.EncodeString(enc, test); err != nil {
+			t.Fatalf("EncodeString(%q) failed: %v", test, err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	dec, err := coder.NewDecoder(&buf)
+	if err != nil {
+		t.Fatalf("NewDecoder failed: %v", err)
+	}
+	model = NewEmojiTextModel(NewEnglishPPMModel())
+	for _, test := range tests {
+		result, err := model.DecodeString(dec)
+		if err != nil {
+			t.Fatalf("DecodeString(%q) failed: %v", test, err)
+		}
+		if result != test {
+			t.Errorf("Decoded string doesn't match:\noriginal: %q\nresult:   %q", test, result)
+		}
+	}
+}
+
+func TestEmojiTextVsPPM(t *testing.T) {
+	tests := []string{
+		"👍",
+		"❤️",
+		"Made it to the top 🏔️ amazing view 😍",
+		"Thanks 🙏🙏",
+	}
+
+	for _, test := range tests {
+		var bufPPM, bufEmoji bytes.Buffer
+
+		enc := coder.NewEncoder(&bufPPM)
+		if err := NewEnglishPPMModel().EncodeString(enc, test); err != nil {
+			t.Fatalf("PPM encode failed: %v", err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		enc = coder.NewEncoder(&bufEmoji)
+		if err := NewEmojiTextModel(NewEnglishPPMModel()).EncodeString(enc, test); err != nil {
+			t.Fatalf("Emoji encode failed: %v", err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		t.Logf("%q: raw %d bytes, ppm %d bytes, emoji %d bytes", test, len(test), bufPPM.Len(), bufEmoji.Len())
+		if bufEmoji.Len() >= bufPPM.Len() {
+			t.Errorf("Emoji model (%d bytes) should beat PPM (%d bytes)", bufEmoji.Len(), bufPPM.Len())
+		}
+	}
+}
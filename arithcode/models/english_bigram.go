@@ -0,0 +1,198 @@
+package models
+
+import "io"
+
+// NewEnglishBigramModel creates an order-2 model with hand-written tables
+// for common English bigrams instead of the generated corpus tables of
+// NewEnglishOrder2Model. These were the tables of the order-2 model before
+// they were generated, and formats defined with them, such as Meshtastic
+// V10, keep coding their strings with them.
+func NewEnglishBigramModel() *EnglishOrder2Model {
+	model := newEnglishOrder2Model()
+	model.buildBigramModels()
+	return model
+}
+
+// EncodeStringBigram encodes a string like EncodeStringOrder2 with the model
+// of NewEnglishBigramModel.
+func EncodeStringBigram(s string, w io.Writer) error {
+	return encodeStringOrder2(sharedEnglishBigramModel(), s, w)
+}
+
+// DecodeStringBigram decodes a string written by EncodeStringBigram.
+func DecodeStringBigram(r io.Reader) (string, error) {
+	return DecodeStringBigramLimit(r, MaxLength)
+}
+
+// DecodeStringBigramLimit decodes a string written by EncodeStringBigram and
+// fails with ErrStringTooLong when it is longer than limit bytes of UTF-8.
+func DecodeStringBigramLimit(r io.Reader, limit int) (string, error) {
+	return decodeStringOrder2(sharedEnglishBigramModel(), r, limit)
+}
+
+// buildBigramModels creates frequency tables for common bigram contexts.
+func (em *EnglishOrder2Model) buildBigramModels() {
+	numSymbols := len(em.symbolToChar)
+
+	// Helper to create a frequency table with biases
+	createBiasedModel := func(biases map[rune]uint64) *FrequencyTable {
+		freqs := make([]uint64, numSymbols)
+		// Start with small base frequency
+		for i := range freqs {
+			freqs[i] = 5
+		}
+		// Apply biases
+		for ch, freq := range biases {
+			if idx, ok := em.charToSymbol[ch]; ok {
+				freqs[idx] = freq
+			}
+		}
+		return NewFrequencyTable(freqs)
+	}
+
+	// Common bigram patterns in English
+	// "th" → e, a, i, o, er
+	em.contextModels["th"] = createBiasedModel(map[rune]uint64{
+		'e': 900, 'a': 400, 'i': 350, 'o': 300, 'r': 250, ' ': 200, 'y': 150,
+	})
+
+	// "he" → r, n, space, d, y
+	em.contextModels["he"] = createBiasedModel(map[rune]uint64{
+		'r': 700, ' ': 500, 'n': 400, 'd': 300, 'y': 250, 's': 200, 'a': 150,
+	})
+
+	// "in" → g, space, t, e, d
+	em.contextModels["in"] = createBiasedModel(map[rune]uint64{
+		'g': 800, ' ': 600, 't': 400, 'e': 300, 'd': 250, 's': 200, 'k': 150,
+	})
+
+	// "er" → space, s, e, i, a
+	em.contextModels["er"] = createBiasedModel(map[rune]uint64{
+		' ': 700, 's': 500, 'e': 300, 'i': 250, 'a': 200, 'y': 180, 't': 150,
+	})
+
+	// "an" → d, t, space, c, y
+	em.contextModels["an"] = createBiasedModel(map[rune]uint64{
+		'd': 600, 't': 500, ' ': 450, 'c': 300, 'y': 250, 'g': 200, 's': 150,
+	})
+
+	// "re" → space, d, s, a, n
+	em.contextModels["re"] = createBiasedModel(map[rune]uint64{
+		' ': 600, 'd': 500, 's': 450, 'a': 350, 'n': 300, 't': 250, 'e': 200,
+	})
+
+	// "nd" → space, e, a, i
+	em.contextModels["nd"] = createBiasedModel(map[rune]uint64{
+		' ': 800, 'e': 400, 'a': 200, 'i': 150, 's': 100,
+	})
+
+	// "on" → space, g, e, t, a
+	em.contextModels["on"] = createBiasedModel(map[rune]uint64{
+		' ': 600, 'g': 500, 'e': 350, 't': 300, 'a': 200, 's': 180, 'd': 150,
+	})
+
+	// "nt" → space, e, i, s, a
+	em.contextModels["nt"] = createBiasedModel(map[rune]uint64{
+		' ': 700, 'e': 400, 'i': 300, 's': 250, 'a': 200, 'o': 150, 'r': 120,
+	})
+
+	// "ha" → t, v, n, s, d
+	em.contextModels["ha"] = createBiasedModel(map[rune]uint64{
+		't': 700, 'v': 500, 'n': 350, 's': 300, 'd': 250, 'r': 200, 'l': 150,
+	})
+
+	// "en" → t, d, space, c, s
+	em.contextModels["en"] = createBiasedModel(map[rune]uint64{
+		't': 600, 'd': 400, ' ': 350, 'c': 300, 's': 250, 'e': 200, 'a': 150,
+	})
+
+	// "ed" → space, (punctuation)
+	em.contextModels["ed"] = createBiasedModel(map[rune]uint64{
+		' ': 900, '.': 150, ',': 100, '!': 50, '?': 40,
+	})
+
+	// "to" → space, r, n, o, w
+	em.contextModels["to"] = createBiasedModel(map[rune]uint64{
+		' ': 700, 'r': 400, 'n': 300, 'o': 250, 'w': 200, 'p': 150, 'm': 120,
+	})
+
+	// "it" → space, y, h, e, i
+	em.contextModels["it"] = createBiasedModel(map[rune]uint64{
+		' ': 600, 'y': 400, 'h': 350, 'e': 300, 'i': 250, 's': 200, 't': 150,
+	})
+
+	// "st" → space, a, e, i, r
+	em.contextModels["st"] = createBiasedModel(map[rune]uint64{
+		' ': 500, 'a': 400, 'e': 350, 'i': 300, 'r': 250, 'o': 200, 'u': 150,
+	})
+
+	// "io" → n, ns
+	em.contextModels["io"] = createBiasedModel(map[rune]uint64{
+		'n': 900, 'u': 150, 's': 100,
+	})
+
+	// "le" → space, d, s, r, a
+	em.contextModels["le"] = createBiasedModel(map[rune]uint64{
+		' ': 600, 'd': 350, 's': 300, 'r': 250, 'a': 200, 't': 180, 'n': 150,
+	})
+
+	// "ar" → e, d, y, t, s
+	em.contextModels["ar"] = createBiasedModel(map[rune]uint64{
+		'e': 500, 'd': 400, 'y': 350, 't': 300, 's': 250, 'i': 200, 'k': 150,
+	})
+
+	// "te" → space, d, r, s, n
+	em.contextModels["te"] = createBiasedModel(map[rune]uint64{
+		' ': 500, 'd': 450, 'r': 400, 's': 350, 'n': 300, 'm': 250, 'l': 200,
+	})
+
+	// "co" → n, m, u, l, r
+	em.contextModels["co"] = createBiasedModel(map[rune]uint64{
+		'n': 600, 'm': 500, 'u': 400, 'l': 300, 'r': 250, 'v': 200, 'p': 150,
+	})
+
+	// "or" → space, e, t, d, y
+	em.contextModels["or"] = createBiasedModel(map[rune]uint64{
+		' ': 600, 'e': 400, 't': 350, 'd': 300, 'y': 250, 's': 200, 'i': 150,
+	})
+
+	// "at" → space, e, i, ion, h
+	em.contextModels["at"] = createBiasedModel(map[rune]uint64{
+		' ': 500, 'e': 450, 'i': 400, 'h': 300, 't': 250, 'u': 200, 'o': 150,
+	})
+
+	// "ou" → t, r, n, s, l
+	em.contextModels["ou"] = createBiasedModel(map[rune]uint64{
+		't': 600, 'r': 500, 'n': 400, 's': 350, 'l': 300, 'p': 200, 'g': 150,
+	})
+
+	// Space + common starting letters
+	em.contextModels[" t"] = createBiasedModel(map[rune]uint64{
+		'h': 900, 'o': 400, 'i': 300, 'a': 250, 'e': 200, 'r': 150, 'w': 120,
+	})
+
+	em.contextModels[" a"] = createBiasedModel(map[rune]uint64{
+		'n': 600, ' ': 400, 't': 350, 'l': 300, 's': 250, 'r': 200, 'b': 150,
+	})
+
+	em.contextModels[" i"] = createBiasedModel(map[rune]uint64{
+		'n': 700, 's': 500, 't': 400, 'f': 200, ' ': 150,
+	})
+
+	em.contextModels[" w"] = createBiasedModel(map[rune]uint64{
+		'h': 600, 'a': 400, 'i': 350, 'e': 300, 'o': 250, 'r': 200,
+	})
+
+	em.contextModels[" h"] = createBiasedModel(map[rune]uint64{
+		'e': 700, 'a': 500, 'i': 350, 'o': 300, 'u': 200, 'y': 150,
+	})
+
+	// Common endings
+	em.contextModels["ly"] = createBiasedModel(map[rune]uint64{
+		' ': 900, '.': 100, ',': 80,
+	})
+
+	em.contextModels["ng"] = createBiasedModel(map[rune]uint64{
+		' ': 700, 's': 300, 'e': 200, '.': 100,
+	})
+}
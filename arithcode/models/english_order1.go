@@ -1,6 +1,10 @@
-package arithcode
+package models
 
-import "io"
+import (
+	"io"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
 
 // EnglishOrder1Model is an order-1 model for English text compression.
 // It uses the previous character as context to predict the next character,
@@ -180,7 +184,7 @@ func (em *EnglishOrder1Model) buildContextModels() {
 }
 
 // GetModel returns the appropriate model for the given context.
-func (em *EnglishOrder1Model) GetModel(prevSymbol int) Model {
+func (em *EnglishOrder1Model) GetModel(prevSymbol int) coder.Model {
 	if prevSymbol < 0 || prevSymbol >= len(em.symbolToChar) {
 		return em.defaultModel
 	}
@@ -194,8 +198,8 @@ func (em *EnglishOrder1Model) GetModel(prevSymbol int) Model {
 
 // EncodeStringOrder1 encodes a string using the order-1 English model.
 func EncodeStringOrder1(s string, w io.Writer) error {
-	enc := NewEncoder(w)
-	model := NewEnglishOrder1Model()
+	enc := coder.NewEncoder(w)
+	model := SharedEnglishOrder1Model()
 	byteModel := NewUniformModel(256)
 
 	runes := []rune(s)
@@ -257,12 +261,19 @@ func EncodeStringOrder1(s string, w io.Writer) error {
 
 // DecodeStringOrder1 decodes a string using the order-1 English model.
 func DecodeStringOrder1(r io.Reader) (string, error) {
-	dec, err := NewDecoder(r)
+	return DecodeStringOrder1Limit(r, MaxLength)
+}
+
+// DecodeStringOrder1Limit decodes a string written by EncodeStringOrder1 and
+// fails with ErrStringTooLong when it is longer than limit bytes of UTF-8.
+// The length is checked before the characters are decoded.
+func DecodeStringOrder1Limit(r io.Reader, limit int) (string, error) {
+	dec, err := coder.NewDecoder(r)
 	if err != nil {
 		return "", err
 	}
 
-	model := NewEnglishOrder1Model()
+	model := SharedEnglishOrder1Model()
 	byteModel := NewUniformModel(256)
 
 	// Decode length
@@ -282,8 +293,13 @@ func DecodeStringOrder1(r io.Reader) (string, error) {
 	// Track previous symbol for context
 	prevSymbol := -1
 
+	// Every character takes at least a byte
+	if length > limit {
+		return "", ErrStringTooLong
+	}
+
 	// Decode characters
-	result := make([]rune, 0, length)
+	result := make([]rune, 0, min(length, 1024))
 	for len(result) < length {
 		contextModel := model.GetModel(prevSymbol)
 		symbol, err := dec.Decode(contextModel)
@@ -319,5 +335,9 @@ func DecodeStringOrder1(r io.Reader) (string, error) {
 		}
 	}
 
-	return string(result), nil
+	str := string(result)
+	if len(str) > limit {
+		return "", ErrStringTooLong
+	}
+	return str, nil
 }
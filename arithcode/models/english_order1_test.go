@@ -1,8 +1,12 @@
-package arithcode
+package models
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
 )
 
 func TestEnglishOrder1Model(t *testing.T) {
@@ -102,3 +106,48 @@ func BenchmarkEnglishOrder1(b *testing.B) {
 		}
 	}
 }
+
+func TestDecodeStringOrderLimit(t *testing.T) {
+	orders := []struct {
+		name   string
+		encode func(string, io.Writer) error
+		decode func(io.Reader, int) (string, error)
+	}{
+		{"order-0", EncodeString, DecodeStringLimit},
+		{"order-1", EncodeStringOrder1, DecodeStringOrder1Limit},
+		{"order-2", EncodeStringOrder2, DecodeStringOrder2Limit},
+		{"bigram", EncodeStringBigram, DecodeStringBigramLimit},
+	}
+
+	// A length far beyond the limit and the input
+	var hostile bytes.Buffer
+	enc := coder.NewEncoder(&hostile)
+	for _, b := range []int{0xff, 0xff, 0xff, 0x7f} {
+		if err := enc.Encode(b, NewUniformModel(256)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, order := range orders {
+		var buf bytes.Buffer
+		if err := order.encode("Grüße, world", &buf); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := order.decode(bytes.NewReader(buf.Bytes()), 14); err != nil || got != "Grüße, world" {
+			t.Errorf("%s: got %q, %v within the limit", order.name, got, err)
+		}
+		if _, err := order.decode(bytes.NewReader(buf.Bytes()), 13); !errors.Is(err, ErrStringTooLong) {
+			t.Errorf("%s: got %v, want ErrStringTooLong", order.name, err)
+		}
+
+		if _, err := order.decode(bytes.NewReader(hostile.Bytes()), 100); !errors.Is(err, ErrStringTooLong) {
+			t.Errorf("%s: hostile length: got %v, want ErrStringTooLong", order.name, err)
+		}
+		if _, err := order.decode(bytes.NewReader(hostile.Bytes()), MaxLength); !errors.Is(err, coder.ErrTruncated) {
+			t.Errorf("%s: hostile length: got %v, want ErrTruncated", order.name, err)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package models
+
+import (
+	"io"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+//go:generate go run ../../cmd/genorder2 -out english_order2_tables.go -trigrams 256
+
+// EnglishOrder2Model is an order-2 model for English text compression.
+// It uses the previous 2 characters as context to predict the next character,
+// achieving better compression than order-0 or order-1 models.
+//
+// The context tables are generated from a text corpus, see cmd/genorder2.
+// When the tables include trigram contexts, the previous 3 characters are used
+// where available.
+type EnglishOrder2Model struct {
+	charToSymbol map[rune]int
+	symbolToChar []rune
+
+	// Context-dependent frequency tables
+	// contextModels[ctx] gives the frequency table for the next character
+	// where ctx is a hash of the previous 2 characters
+	contextModels map[string]*FrequencyTable
+
+	// Default models for when we don't have enough context
+	order1Model  *EnglishOrder1Model
+	defaultModel *FrequencyTable
+
+	// Special symbols
+	otherSymbol int
+}
+
+// NewEnglishOrder2Model creates an order-2 model for English text.
+func NewEnglishOrder2Model() *EnglishOrder2Model {
+	model := newEnglishOrder2Model()
+
+	// Build context-specific models from the generated corpus tables
+	model.buildContextModels()
+
+	return model
+}
+
+// newEnglishOrder2Model creates an order-2 model without context tables.
+func newEnglishOrder2Model() *EnglishOrder2Model {
+	// Common English characters
+	chars := []rune{
+		' ', 'e', 't', 'a', 'o', 'i', 'n', 's', 'h', 'r',
+		'd', 'l', 'c', 'u', 'm', 'w', 'f', 'g', 'y', 'p',
+		'b', 'v', 'k', 'j', 'x', 'q', 'z',
+		'E', 'T', 'A', 'O', 'I', 'N', 'S', 'H', 'R', 'D',
+		'L', 'C', 'U', 'M', 'W', 'F', 'G', 'Y', 'P', 'B',
+		'V', 'K', 'J', 'X', 'Q', 'Z',
+		'.', ',', '!', '?', ';', ':', '-', '\'', '"', '(',
+		')', '[', ']', '{', '}', '\n', '\t', '\r',
+		'0', '1', '2', '3', '4', '5', '6', '7', '8', '9',
+		'@', '#', '$', '%', '&', '*', '+', '=', '/', '\\',
+		'_', '|', '<', '>', '~', '`',
+	}
+
+	// Add special "other" symbol
+	const otherCharPlaceholder = rune(0xE000)
+	chars = append(chars, otherCharPlaceholder)
+
+	charToSymbol := make(map[rune]int, len(chars))
+	for i, ch := range chars {
+		charToSymbol[ch] = i
+	}
+
+	model := &EnglishOrder2Model{
+		charToSymbol:  charToSymbol,
+		symbolToChar:  chars,
+		contextModels: make(map[string]*FrequencyTable),
+		order1Model:   NewEnglishOrder1Model(),
+		otherSymbol:   len(chars) - 1,
+	}
+
+	// Build default model (order-0 frequencies)
+	model.defaultModel = model.createDefaultModel()
+
+	return model
+}
+
+// createDefaultModel creates the default order-0 frequency table.
+func (em *EnglishOrder2Model) createDefaultModel() *FrequencyTable {
+	numSymbols := len(em.symbolToChar)
+	freqs := make([]uint64, numSymbols)
+
+	// Default frequencies
+	baseFreqs := []uint64{
+		1300, 1270, 906, 817, 751, 697, 675, 633, 609, 599,
+		425, 403, 278, 276, 241, 236, 223, 202, 197, 193,
+		149, 98, 77, 15, 15, 10, 7,
+		50, 50, 45, 40, 40, 35, 35, 30, 30, 25,
+		25, 20, 20, 15, 15, 15, 15, 10, 10, 10,
+		8, 5, 5, 3, 2, 2,
+		100, 80, 20, 15, 10, 8, 50, 30, 40, 15,
+		15, 10, 10, 5, 5, 80, 20, 5,
+		50, 50, 50, 50, 50, 50, 50, 50, 50, 50,
+		20, 5, 5, 5, 10, 5, 10, 10, 15, 15,
+		30, 5, 8, 8, 3, 3,
+	}
+
+	copy(freqs, baseFreqs)
+	freqs[em.otherSymbol] = 100
+
+	return NewFrequencyTable(freqs)
+}
+
+// buildContextModels creates frequency tables for the bigram and trigram
+// contexts of the generated order2ContextCounts table.
+func (em *EnglishOrder2Model) buildContextModels() {
+	// Bigram contexts are built first, they are the priors of trigram contexts
+	for _, order := range []int{2, 3} {
+		for ctx, counts := range order2ContextCounts {
+			runes := []rune(ctx)
+			if len(runes) != order || !em.knownChars(runes) {
+				continue
+			}
+
+			// Use the shorter context as the prior, so that characters that were
+			// not seen in the corpus after this context remain cheap enough
+			prior := em.fallbackModel(em.charToSymbol[runes[order-1]])
+			if model, ok := em.contextModels[string(runes[1:])]; ok && order == 3 {
+				prior = model
+			}
+			em.contextModels[ctx] = em.createContextModel(prior, counts)
+		}
+	}
+}
+
+// knownChars reports whether all runes are in the model alphabet.
+func (em *EnglishOrder2Model) knownChars(runes []rune) bool {
+	for _, ch := range runes {
+		if _, ok := em.charToSymbol[ch]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Weights for combining the corpus counts of a context with its prior.
+const (
+	order2PriorTotal  = 256
+	order2CountWeight = 32
+)
+
+// createContextModel creates a frequency table from the corpus counts of a
+// context, mixed with the prior distribution.
+func (em *EnglishOrder2Model) createContextModel(prior *FrequencyTable, counts map[rune]uint32) *FrequencyTable {
+	priorFreqs := prior.Frequencies()
+	priorTotal := prior.TotalFreq()
+
+	freqs := make([]uint64, len(em.symbolToChar))
+	for i, f := range priorFreqs {
+		freqs[i] = f*order2PriorTotal/priorTotal + 1
+	}
+	for ch, count := range counts {
+		symbol, ok := em.charToSymbol[ch]
+		if !ok {
+			symbol = em.otherSymbol
+		}
+		freqs[symbol] += uint64(count) * order2CountWeight
+	}
+	return NewFrequencyTable(freqs)
+}
+
+// fallbackModel returns the order-1 table for the previous symbol.
+func (em *EnglishOrder2Model) fallbackModel(prev1 int) *FrequencyTable {
+	if model, ok := em.order1Model.contextModels[prev1]; ok {
+		return model
+	}
+	return em.defaultModel
+}
+
+// GetModel returns the appropriate model for the given context.
+func (em *EnglishOrder2Model) GetModel(prev1, prev2 int) coder.Model {
+	return em.GetContextModel(prev1, prev2, -1)
+}
+
+// GetContextModel returns the model for the previous three symbols, using the
+// longest context that has a table.
+func (em *EnglishOrder2Model) GetContextModel(prev1, prev2, prev3 int) coder.Model {
+	valid := func(symbol int) bool {
+		return symbol >= 0 && symbol < len(em.symbolToChar)
+	}
+
+	// Try order-3 context (previous 3 chars)
+	if valid(prev1) && valid(prev2) && valid(prev3) {
+		ctx := string([]rune{em.symbolToChar[prev3], em.symbolToChar[prev2], em.symbolToChar[prev1]})
+		if model, ok := em.contextModels[ctx]; ok {
+			return model
+		}
+	}
+
+	// Try order-2 context (previous 2 chars)
+	if valid(prev1) && valid(prev2) {
+		ctx := string([]rune{em.symbolToChar[prev2], em.symbolToChar[prev1]})
+		if model, ok := em.contextModels[ctx]; ok {
+			return model
+		}
+	}
+
+	// Fall back to order-1 context
+	if valid(prev1) {
+		return em.order1Model.GetModel(prev1)
+	}
+
+	// Fall back to order-0
+	return em.defaultModel
+}
+
+// EncodeStringOrder2 encodes a string using the order-2 English model.
+func EncodeStringOrder2(s string, w io.Writer) error {
+	return encodeStringOrder2(SharedEnglishOrder2Model(), s, w)
+}
+
+// encodeStringOrder2 encodes a string with an order-2 model.
+func encodeStringOrder2(model *EnglishOrder2Model, s string, w io.Writer) error {
+	enc := coder.NewEncoder(w)
+	byteModel := NewUniformModel(256)
+
+	runes := []rune(s)
+	length := len(runes)
+
+	// Encode length as varint
+	tempLen := length
+	for i := 0; i < 4; i++ {
+		b := byte(tempLen & 0x7F)
+		tempLen >>= 7
+		if tempLen > 0 {
+			b |= 0x80
+		}
+		if err := enc.Encode(int(b), byteModel); err != nil {
+			return err
+		}
+		if tempLen == 0 {
+			break
+		}
+	}
+
+	// Track previous 3 symbols for context
+	prevSymbol1 := -1 // most recent
+	prevSymbol2 := -1 // second most recent
+	prevSymbol3 := -1
+
+	// Encode each character
+	for _, ch := range runes {
+		symbol, ok := model.charToSymbol[ch]
+		if !ok {
+			// Character not in table, encode as "other"
+			contextModel := model.GetContextModel(prevSymbol1, prevSymbol2, prevSymbol3)
+			if err := enc.Encode(model.otherSymbol, contextModel); err != nil {
+				return err
+			}
+
+			// Encode the actual rune as UTF-8 bytes
+			utf8Bytes := []byte(string(ch))
+			if err := enc.Encode(len(utf8Bytes), NewUniformModel(5)); err != nil {
+				return err
+			}
+			for _, b := range utf8Bytes {
+				if err := enc.Encode(int(b), byteModel); err != nil {
+					return err
+				}
+			}
+
+			prevSymbol3 = prevSymbol2
+			prevSymbol2 = prevSymbol1
+			prevSymbol1 = model.otherSymbol
+		} else {
+			// Use context-specific model
+			contextModel := model.GetContextModel(prevSymbol1, prevSymbol2, prevSymbol3)
+			if err := enc.Encode(symbol, contextModel); err != nil {
+				return err
+			}
+			prevSymbol3 = prevSymbol2
+			prevSymbol2 = prevSymbol1
+			prevSymbol1 = symbol
+		}
+	}
+
+	return enc.Close()
+}
+
+// DecodeStringOrder2 decodes a string using the order-2 English model.
+func DecodeStringOrder2(r io.Reader) (string, error) {
+	return DecodeStringOrder2Limit(r, MaxLength)
+}
+
+// DecodeStringOrder2Limit decodes a string written by EncodeStringOrder2 and
+// fails with ErrStringTooLong when it is longer than limit bytes of UTF-8.
+// The length is checked before the characters are decoded.
+func DecodeStringOrder2Limit(r io.Reader, limit int) (string, error) {
+	return decodeStringOrder2(SharedEnglishOrder2Model(), r, limit)
+}
+
+// decodeStringOrder2 decodes a string written by encodeStringOrder2 with
+// the same model.
+func decodeStringOrder2(model *EnglishOrder2Model, r io.Reader, limit int) (string, error) {
+	dec, err := coder.NewDecoder(r)
+	if err != nil {
+		return "", err
+	}
+
+	byteModel := NewUniformModel(256)
+
+	// Decode length
+	var length int
+	for i := 0; i < 4; i++ {
+		symbol, err := dec.Decode(byteModel)
+		if err != nil {
+			return "", err
+		}
+		b := byte(symbol)
+		length |= int(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	// Track previous 3 symbols for context
+	prevSymbol1 := -1
+	prevSymbol2 := -1
+	prevSymbol3 := -1
+
+	// Every character takes at least a byte
+	if length > limit {
+		return "", ErrStringTooLong
+	}
+
+	// Decode characters
+	result := make([]rune, 0, min(length, 1024))
+	for len(result) < length {
+		contextModel := model.GetContextModel(prevSymbol1, prevSymbol2, prevSymbol3)
+		symbol, err := dec.Decode(contextModel)
+		if err != nil {
+			return "", err
+		}
+
+		if symbol == model.otherSymbol {
+			// Decode UTF-8 bytes for unknown character
+			numBytes, err := dec.Decode(NewUniformModel(5))
+			if err != nil {
+				return "", err
+			}
+
+			utf8Bytes := make([]byte, numBytes)
+			for i := 0; i < numBytes; i++ {
+				b, err := dec.Decode(byteModel)
+				if err != nil {
+					return "", err
+				}
+				utf8Bytes[i] = byte(b)
+			}
+
+			runes := []rune(string(utf8Bytes))
+			if len(runes) > 0 {
+				result = append(result, runes[0])
+			}
+
+			prevSymbol3 = prevSymbol2
+			prevSymbol2 = prevSymbol1
+			prevSymbol1 = model.otherSymbol
+		} else {
+			result = append(result, model.symbolToChar[symbol])
+			prevSymbol3 = prevSymbol2
+			prevSymbol2 = prevSymbol1
+			prevSymbol1 = symbol
+		}
+	}
+
+	str := string(result)
+	if len(str) > limit {
+		return "", ErrStringTooLong
+	}
+	return str, nil
+}
@@ -0,0 +1,615 @@
+// Code generated by genorder2 from corpus.txt; DO NOT EDIT.
+
+package models
+
+// order2ContextCounts maps bigram and trigram contexts to the number of
+// times each character followed the context in the training corpus.
+var order2ContextCounts = map[string]map[rune]uint32{
+	" D":  {'i': 1, 'o': 1},
+	" E":  {'n': 1, 'v': 1},
+	" I":  {' ': 12, 't': 1},
+	" L":  {'e': 1, 'o': 1},
+	" S":  {'a': 1, 'u': 1},
+	" T":  {'h': 11, 'a': 1},
+	" W":  {'e': 2},
+	" a":  {'n': 64, ' ': 35, 'r': 24, 't': 20, 'l': 11, 'b': 7, 'f': 7, 'g': 3, 'm': 3, 'i': 2, 'c': 1, 'd': 1, 's': 1, 'u': 1},
+	" b":  {'e': 28, 'u': 12, 'a': 7, 'r': 6, 'i': 5, 'o': 5, 'y': 5, 'l': 1},
+	" c":  {'o': 26, 'a': 22, 'h': 16, 'l': 13, 'e': 1, 'i': 1},
+	" d":  {'e': 10, 'o': 8, 'a': 7, 'r': 3, 'i': 2, 'u': 1},
+	" e":  {'v': 15, 'a': 7, 'n': 3, 'i': 2, 'l': 2, 'x': 2, 'd': 1, 'm': 1},
+	" f":  {'o': 26, 'i': 13, 'r': 8, 'a': 4, 'e': 1, 'u': 1},
+	" g":  {'o': 8, 'r': 5, 'e': 4, 'a': 2, 'l': 1, 'u': 1},
+	" h":  {'a': 20, 'o': 12, 'e': 11, 'i': 7, 'u': 2},
+	" i":  {'s': 39, 'n': 26, 't': 26, 'f': 6, 'd': 1, 'm': 1},
+	" j":  {'o': 4, 'u': 3},
+	" k":  {'n': 9, 'e': 2},
+	" l":  {'o': 14, 'a': 11, 'i': 10, 'e': 7, 'u': 2},
+	" m":  {'e': 26, 'o': 16, 'a': 9, 'i': 9, 'u': 4, 'y': 2},
+	" n":  {'e': 31, 'o': 23, 'a': 3, 'i': 3, 'u': 1},
+	" o":  {'n': 32, 'f': 23, 'p': 6, 'r': 6, 't': 5, 'l': 4, 'u': 3, 'v': 3, 'w': 1},
+	" p":  {'a': 13, 'r': 9, 'l': 7, 'e': 5, 'o': 4, 'h': 1, 'i': 1},
+	" q":  {'u': 4},
+	" r":  {'e': 23, 'a': 7, 'o': 6, 'i': 5, 'u': 2},
+	" s":  {'e': 29, 't': 26, 'h': 21, 'o': 20, 'i': 15, 'm': 6, 'u': 5, 'a': 4, 'p': 3, 'n': 2, 'k': 1, 'l': 1, 'q': 1, 'w': 1},
+	" t":  {'h': 250, 'o': 56, 'i': 11, 'r': 8, 'e': 6, 'a': 4, 'u': 4, 'w': 3},
+	" u":  {'p': 7, 's': 7, 'n': 5},
+	" v":  {'i': 4, 'a': 3, 'e': 1},
+	" w":  {'a': 34, 'i': 28, 'e': 26, 'h': 20, 'o': 15, 'r': 4},
+	" y":  {'o': 39, 'e': 3},
+	", ":  {'a': 12, 'b': 10, 's': 8, 't': 8, 'I': 4, 'i': 4, 'k': 3, 'e': 2, 'g': 2, 'j': 2, 'w': 2, 'c': 1, 'f': 1, 'l': 1, 'm': 1, 'o': 1, 'p': 1, 'r': 1, 'u': 1},
+	". ":  {'T': 11, 'L': 2, 'B': 1, 'D': 1, 'E': 1, 'I': 1, 'W': 1},
+	"? ":  {'I': 3, 'D': 1, 'W': 1},
+	"A ":  {'j': 1, 's': 1},
+	"Be":  {'c': 1, 'l': 1},
+	"Ca":  {'n': 2},
+	"Do":  {' ': 3},
+	"Ev":  {'e': 2},
+	"Fo":  {'r': 1, 'u': 1},
+	"Go":  {'o': 2},
+	"He":  {' ': 1, 'a': 1, 'l': 1},
+	"Ho":  {'p': 1, 'w': 1},
+	"I ":  {'w': 5, 't': 3, 'a': 2, 'h': 2, 'b': 1, 'c': 1, 'd': 1, 'm': 1, 'r': 1},
+	"If":  {' ': 2},
+	"In":  {' ': 2},
+	"It":  {' ': 6},
+	"Le":  {'t': 3},
+	"No":  {' ': 2, 'd': 1},
+	"On":  {' ': 2},
+	"Pl":  {'e': 2},
+	"Re":  {'c': 1, 'm': 1},
+	"Se":  {'c': 1, 'n': 1},
+	"Sh":  {'a': 1, 'e': 1},
+	"St":  {'a': 1, 'i': 1, 'o': 1},
+	"Ta":  {'k': 1, 'l': 1},
+	"Th":  {'e': 31, 'a': 7, 'i': 2, 'u': 1},
+	"We":  {' ': 6},
+	"Wh":  {'e': 4, 'a': 1, 'i': 1},
+	"Ye":  {'s': 2},
+	"a ":  {'s': 11, 'm': 6, 'w': 6, 't': 3, 'b': 2, 'l': 2, 'a': 1, 'c': 1, 'd': 1, 'f': 1, 'g': 1, 'h': 1, 'n': 1, 'p': 1, 'r': 1},
+	"ab":  {'o': 7, 'l': 2},
+	"ac":  {'h': 6, 'k': 4, 't': 4, 'e': 1, 'r': 1},
+	"ad":  {' ': 10, 'i': 6, 'e': 2, '.': 1, 'm': 1, 'y': 1},
+	"af":  {'t': 7, 'e': 1, 'f': 1},
+	"ag":  {'e': 18, 'a': 2},
+	"ai":  {'n': 10, 't': 6, 'l': 4, 'r': 2, 'd': 1},
+	"ak":  {'e': 8, ' ': 2, '.': 1, 's': 1},
+	"al":  {'l': 20, ' ': 5, 'k': 5, 'm': 3, 'o': 2, 'r': 1, 'u': 1, 'w': 1},
+	"am":  {'e': 7, ' ': 4, 'a': 1, 'i': 1, 'p': 1},
+	"an":  {'d': 59, ' ': 20, 'k': 7, 'n': 5, 't': 5, 'y': 4, 'g': 3, '.': 1, 'c': 1, 'e': 1, 's': 1},
+	"ap":  {'e': 2, 'p': 2},
+	"ar":  {'e': 26, ' ': 17, 't': 8, 'd': 7, 'k': 4, 'r': 4, 'a': 2, 'g': 2, 'l': 2, 'o': 2, 's': 2, 'y': 2, 'b': 1, 'c': 1, 'm': 1, 'n': 1, 'v': 1},
+	"as":  {' ': 17, 't': 8, 'e': 5, 's': 4, 'u': 2, 'y': 2, 'k': 1},
+	"at":  {' ': 45, 'e': 12, 'i': 9, 'h': 8, 't': 5, '.': 2, 'c': 2, 'o': 2, 'u': 2, ',': 1},
+	"au":  {'s': 3, 'd': 1, 'l': 1},
+	"av":  {'e': 11},
+	"ay":  {' ': 15, '.': 4, 's': 3, '?': 2, '!': 1, ',': 1},
+	"az":  {'i': 1, 'y': 1},
+	"ba":  {'t': 3, 'c': 2, 'k': 1, 's': 1},
+	"be":  {' ': 9, 'f': 6, 'c': 3, 'e': 3, 'n': 2, 'r': 2, 't': 2, 'g': 1, 'h': 1, 'l': 1, 's': 1},
+	"bi":  {'r': 3, 'g': 1, 'l': 1, 'n': 1, 't': 1},
+	"bl":  {'e': 5, 'o': 1},
+	"bo":  {'u': 7, 'o': 3, 'a': 1, 'r': 1, 'v': 1},
+	"br":  {'i': 4, 'a': 2, 'e': 1, 'o': 1},
+	"bu":  {'t': 9, 'i': 2, 'd': 1},
+	"by":  {' ': 4, '.': 1, 't': 1},
+	"ca":  {'n': 8, 'm': 4, 'r': 4, 't': 3, 'u': 3, 'l': 2, 'b': 1, 'k': 1, 's': 1},
+	"ce":  {' ': 3, 's': 2, 'i': 1, 'l': 1, 'p': 1},
+	"ch":  {' ': 12, 'a': 10, 'e': 7, 'i': 2, '.': 1, 'u': 1},
+	"ci":  {'l': 2, 'd': 1, 'n': 1, 't': 1},
+	"ck":  {' ': 6, 'l': 3, 'e': 2, '.': 1, 'a': 1, 'p': 1},
+	"cl":  {'o': 7, 'e': 4, 'i': 2, 'u': 1},
+	"co":  {'m': 11, 'n': 8, 'u': 5, 'l': 3, 'v': 2, 'f': 1, 'o': 1, 'r': 1},
+	"cr":  {'i': 1, 'o': 1},
+	"ct":  {'i': 4, ' ': 3, 'e': 2, ',': 1, 's': 1},
+	"d ":  {'t': 43, 'a': 15, 'm': 10, 'i': 9, 's': 9, 'n': 8, 'h': 7, 'c': 6, 'w': 6, 'b': 5, 'd': 5, 'o': 5, 'p': 3, 'u': 3, 'y': 3, 'l': 2, 'r': 2, 'I': 1, 'e': 1, 'f': 1, 'k': 1, 'q': 1},
+	"d,":  {' ': 6},
+	"d.":  {' ': 2},
+	"da":  {'y': 13, 'n': 2, 'r': 2, 't': 2, ' ': 1},
+	"de":  {' ': 9, 's': 8, 'r': 7, 'l': 3, '.': 2, 'a': 1, 'c': 1, 'd': 1, 'f': 1, 'v': 1},
+	"dg":  {'e': 5},
+	"di":  {'n': 8, 'o': 5, 'a': 1, 'c': 1, 'r': 1, 's': 1, 't': 1},
+	"do":  {'w': 5, '.': 1, 'e': 1, 'g': 1, 'o': 1},
+	"dr":  {'e': 2, 'o': 2, 'a': 1},
+	"ds":  {' ': 7, ',': 1, '.': 1},
+	"e ":  {'s': 47, 't': 45, 'a': 32, 'w': 31, 'c': 27, 'n': 24, 'o': 22, 'm': 21, 'b': 19, 'l': 19, 'r': 18, 'i': 17, 'h': 15, 'e': 14, 'f': 14, 'p': 14, 'y': 14, 'd': 11, 'g': 6, 'v': 6, 'k': 2, 'u': 2, 'j': 1, 'q': 1},
+	"e,":  {' ': 14},
+	"e.":  {' ': 3},
+	"e?":  {' ': 2},
+	"ea":  {'r': 21, 's': 10, 'd': 8, 't': 8, 'c': 5, 'k': 4, ' ': 2, 'm': 2, '.': 1, 'n': 1, 'v': 1},
+	"ec":  {'o': 5, 't': 5, 'a': 3, 'k': 3, 'e': 1, 'i': 1},
+	"ed":  {' ': 31, ',': 4, '.': 4, 'g': 1, 'i': 1},
+	"ee":  {' ': 10, 't': 6, 'd': 5, 'n': 5, 'k': 4, 's': 2, 'p': 1},
+	"ef":  {'o': 6, 'a': 1, 't': 1},
+	"eg":  {'i': 2},
+	"ei":  {'r': 3, 'g': 2, 'v': 1},
+	"ek":  {'s': 2, ',': 1, '.': 1},
+	"el":  {'l': 6, ' ': 5, 'i': 3, 'a': 2, 'd': 2, 's': 2, '.': 1, 'c': 1, 'e': 1, 'o': 1, 'p': 1, 't': 1, 'v': 1, 'y': 1},
+	"em":  {' ': 4, 'e': 3, '.': 1, 'b': 1, 'i': 1, 'p': 1, 's': 1},
+	"en":  {' ': 30, 'd': 12, 't': 8, 'c': 5, 'e': 4, 'i': 3, '.': 2, 'n': 2, 's': 2, 'g': 1},
+	"eo":  {'p': 4, 'n': 1},
+	"ep":  {' ': 3, '.': 2, 'e': 2, 'o': 2, 't': 2, 'l': 1},
+	"er":  {' ': 44, 'e': 29, 'y': 18, 's': 10, 'a': 5, '.': 4, ',': 2, 'f': 2, 'i': 2, 'n': 2, '-': 1, '?': 1, 'd': 1, 'g': 1, 'm': 1, 'o': 1},
+	"es":  {' ': 31, 's': 19, 't': 10, '.': 6, ',': 3, 'e': 3, 'h': 3, 'c': 2, 'k': 2, 'i': 1, 'u': 1},
+	"et":  {' ': 11, 't': 5, 'w': 5, 'h': 4, 'i': 3, ',': 2, 'u': 2, 'e': 1, 's': 1},
+	"ev":  {'e': 20, 'i': 1},
+	"ew":  {' ': 10, 's': 2, 'h': 1},
+	"ex":  {'t': 5, 'c': 1, 'p': 1},
+	"ey":  {' ': 7, '.': 2},
+	"f ":  {'t': 15, 'y': 6, 'a': 2, 's': 2, 'e': 1, 'o': 1, 'r': 1, 'w': 1},
+	"fa":  {'i': 2, 'm': 1, 's': 1, 'u': 1},
+	"fe":  {',': 1, '.': 1, 'c': 1, 'e': 1, 'w': 1},
+	"ff":  {'l': 2, ' ': 1, 'e': 1, 'i': 1, 's': 1},
+	"fi":  {'r': 4, 'n': 3, 'e': 2, 'v': 2, 'c': 1, 'f': 1, 's': 1},
+	"fl":  {'i': 2},
+	"fo":  {'r': 29, 'l': 1, 'o': 1, 'x': 1},
+	"fr":  {'o': 6, 'e': 1, 'i': 1},
+	"ft":  {'e': 9, ',': 1, 's': 1},
+	"fu":  {'l': 2},
+	"g ":  {'t': 13, 'i': 6, 'a': 5, 'w': 4, 'o': 3, 'e': 2, 'f': 2, 'g': 2, 'h': 2, 'p': 2, 's': 2, 'y': 2, 'I': 1, 'c': 1, 'r': 1, 'u': 1},
+	"g,":  {' ': 2},
+	"ga":  {'i': 2, 't': 2},
+	"ge":  {' ': 12, 's': 7, 't': 5, '.': 4, 'n': 3, 'd': 1, 'r': 1},
+	"gh":  {'t': 11, ' ': 2, '.': 1, 'e': 1},
+	"gi":  {'n': 2, 's': 1},
+	"gl":  {'a': 1, 'e': 1, 'i': 1},
+	"gn":  {'a': 3, ' ': 2},
+	"go":  {'o': 4, 'i': 3, 'e': 1},
+	"gr":  {'o': 3, 'a': 1, 'e': 1},
+	"gs":  {' ': 4},
+	"gu":  {'a': 2},
+	"h ":  {'t': 13, 'a': 9, 'n': 3, 'o': 3, 'b': 1, 'c': 1, 'e': 1, 'f': 1, 'g': 1, 'm': 1, 'y': 1},
+	"h.":  {' ': 2},
+	"ha":  {'t': 26, 'n': 16, 'v': 9, 'r': 8, 's': 4, 'd': 3, 'l': 2, 'p': 1},
+	"he":  {' ': 209, 'r': 36, 'n': 15, 'a': 7, 'y': 5, 's': 4, 'c': 3, 'i': 3, 'l': 3, 'm': 3, 'd': 2, 'e': 1},
+	"hi":  {'n': 15, 'l': 10, 's': 5, 'p': 2, 'r': 2, 'f': 1, 'g': 1, 'k': 1},
+	"ho":  {'u': 9, 'r': 6, 'w': 5, ' ': 3, 'l': 2, 'p': 2, 'b': 1, 'm': 1, 'n': 1, 's': 1},
+	"hr":  {'o': 3, 'e': 2},
+	"ht":  {' ': 6, '.': 3, 's': 2},
+	"hu":  {'r': 2, 'm': 1, 't': 1},
+	"hy":  {' ': 1, ',': 1},
+	"ia":  {'b': 1, 'n': 1},
+	"ib":  {'e': 1, 'r': 1},
+	"ic":  {'k': 5, ',': 1, '.': 1, 'e': 1, 's': 1, 't': 1},
+	"id":  {'e': 5, 'g': 3, ' ': 2, 'a': 1, 'i': 1},
+	"ie":  {'l': 2, 'n': 2, 's': 2, 'w': 2, 'v': 1},
+	"if":  {' ': 6, 't': 2, 'e': 1, 'f': 1},
+	"ig":  {'h': 12, 'n': 5, ' ': 1},
+	"ik":  {'e': 3},
+	"il":  {'l': 24, ' ': 6, 'e': 5, 'd': 3, 's': 1, 't': 1, 'u': 1, 'w': 1, 'y': 1},
+	"im":  {'e': 11, 'p': 5, 'b': 1},
+	"in":  {'g': 58, ' ': 30, 'e': 9, 't': 6, 'k': 5, 'a': 4, 'i': 4, 'u': 4, 'd': 3, 'c': 2, 's': 2, ',': 1, 'l': 1},
+	"io":  {'n': 14, ' ': 5},
+	"ip":  {'s': 2, ' ': 1, '.': 1},
+	"ir":  {' ': 4, 'd': 4, 's': 4, 'e': 2, ',': 1, 'm': 1, 't': 1},
+	"is":  {' ': 42, 't': 4, 'h': 3, 's': 3, '.': 1, 'a': 1, 'e': 1, 'l': 1},
+	"it":  {' ': 26, 'h': 12, 'i': 5, '.': 3, 'e': 3, 'y': 3, ',': 2, 't': 2, 'c': 1, 's': 1},
+	"iv":  {'e': 9, 'i': 1},
+	"jo":  {'i': 3, 'u': 1},
+	"ju":  {'s': 2, 'm': 1},
+	"k ":  {'t': 6, 'a': 3, 'i': 3, 'y': 3, 'b': 2, 'c': 2, 'o': 2, 'w': 2, 'I': 1, 'f': 1, 'h': 1, 'l': 1},
+	"k,":  {' ': 2},
+	"ke":  {' ': 5, 'd': 5, ',': 2, 'r': 2, '.': 1, 'l': 1, 'p': 1, 't': 1, 'y': 1},
+	"ki":  {'n': 4},
+	"kl":  {'y': 3},
+	"kn":  {'o': 9},
+	"ks":  {' ': 5, '.': 4, ',': 2},
+	"l ":  {'s': 6, 't': 6, 'a': 5, 'h': 5, 'b': 4, 'n': 4, 'c': 3, 'd': 3, 'f': 2, 'i': 2, 'o': 2, 'p': 2, 'g': 1, 'k': 1, 'l': 1, 'm': 1, 'u': 1, 'w': 1},
+	"l,":  {' ': 2},
+	"la":  {'n': 5, 's': 4, 'y': 3, 'g': 2, 'k': 2, 'r': 2, 't': 2, 'c': 1, 'd': 1, 'i': 1, 'z': 1},
+	"ld":  {' ': 19, 'r': 2, '.': 1, 's': 1},
+	"le":  {' ': 12, 'a': 10, 's': 5, 'm': 3, '.': 2, 'y': 2, ',': 1, 'b': 1, 'c': 1, 'e': 1, 'f': 1, 'r': 1, 't': 1},
+	"li":  {'n': 6, 'f': 2, 'g': 2, 'k': 2, 's': 2, 'v': 2, 'a': 1, 'b': 1, 'd': 1, 'e': 1, 'm': 1},
+	"lk":  {' ': 2, '.': 1, 'e': 1, 'i': 1},
+	"ll":  {' ': 32, 's': 5, 'e': 4, '.': 3, 'o': 3, 'y': 3, ',': 2, 'a': 2, 'i': 1},
+	"lm":  {'o': 2, 'e': 1},
+	"lo":  {'n': 7, 's': 6, 'w': 6, 'c': 3, 'u': 3, 'o': 2, ',': 1, 't': 1},
+	"ls":  {' ': 4, '.': 2, 'e': 2},
+	"lt":  {' ': 2, 'e': 1, 's': 1},
+	"lu":  {'n': 2, 'd': 1, 'e': 1, 'r': 1},
+	"lw":  {'a': 2},
+	"ly":  {' ': 9, '.': 4, ',': 1},
+	"m ":  {'t': 5, 'a': 2, 'i': 2, 'o': 2, 'c': 1, 'e': 1, 'h': 1, 'w': 1},
+	"m,":  {' ': 2},
+	"ma":  {'l': 6, 'i': 2, 'n': 2, 'd': 1, 'k': 1, 'r': 1, 't': 1, 'y': 1, 'z': 1},
+	"mb":  {'e': 2, '.': 1, 'i': 1},
+	"me":  {' ': 21, 's': 19, 'e': 4, 'n': 4, 'r': 3, 't': 3, ',': 2, 'a': 2, '.': 1, '?': 1, 'd': 1, 'm': 1, 'o': 1, 'w': 1},
+	"mi":  {'n': 6, 's': 4, 'l': 3, 't': 2, 'd': 1},
+	"mm":  {'e': 2, 'i': 1, 'o': 1, 'u': 1},
+	"mo":  {'r': 11, 's': 3, 'n': 2, 'u': 2, 'b': 1, 'd': 1, 'm': 1},
+	"mp":  {'l': 4, 'r': 2, ' ': 1, 'e': 1, 's': 1, 'u': 1},
+	"mu":  {'c': 2, 's': 2, 'n': 1},
+	"my":  {' ': 2},
+	"n ":  {'t': 51, 'a': 11, 'w': 8, 'o': 7, 'i': 6, 'r': 6, 's': 6, 'f': 3, 'm': 3, 'p': 3, 'u': 3, 'y': 3, 'S': 2, 'c': 2, 'e': 2, 'h': 2, 'l': 2, 'E': 1, 'F': 1, 'T': 1, 'b': 1, 'd': 1, 'j': 1},
+	"n,":  {' ': 3},
+	"na":  {'l': 4, 'm': 3, 't': 2, ' ': 1, '?': 1, 'r': 1},
+	"nc":  {'h': 4, 'e': 3, 'i': 3, 'l': 1, 'y': 1},
+	"nd":  {' ': 70, 'e': 5, 'i': 4, 's': 3, '.': 2, 'a': 2, ',': 1, 'o': 1},
+	"ne":  {' ': 15, 'w': 9, 't': 6, 'a': 5, 'e': 5, 'l': 5, 'x': 5, 'd': 4, '.': 3, 's': 2, ',': 1, 'c': 1, 'r': 1, 'v': 1, 'y': 1},
+	"ng":  {' ': 48, '.': 10, 's': 4, ',': 2, 'e': 2, 'l': 2, '?': 1, 'i': 1, 'u': 1},
+	"ni":  {'n': 12, 'g': 5, 's': 1, 't': 1},
+	"nk":  {' ': 6, 's': 5, 'i': 1},
+	"nl":  {'y': 3, 'i': 2, 'a': 1},
+	"nn":  {'e': 6, 'a': 2},
+	"no":  {'w': 16, 'd': 8, 't': 5, 'o': 3, ' ': 2, 'i': 1, 'r': 1},
+	"ns":  {' ': 6, ',': 1, 'e': 1, 'o': 1, 't': 1},
+	"nt":  {' ': 9, 'e': 6, 'i': 4, 'a': 3, 'o': 2, ',': 1, '.': 1, 'h': 1, 's': 1},
+	"nu":  {'t': 3, 'e': 1, 'm': 1},
+	"ny":  {'o': 2, 't': 2, ' ': 1},
+	"o ":  {'t': 17, 'a': 8, 'c': 6, 'm': 5, 'r': 4, 'w': 4, 'f': 3, 'h': 3, 'i': 3, 'l': 3, 'I': 2, 'n': 2, 'o': 2, 's': 2, 'y': 2, 'b': 1, 'd': 1, 'p': 1, 'q': 1, 'u': 1},
+	"oa":  {'d': 3, 'r': 1},
+	"ob":  {'l': 3, 'b': 1, 'i': 1},
+	"oc":  {'a': 2, 'k': 2},
+	"od":  {'e': 10, ' ': 6, 'a': 3, '.': 2},
+	"oe":  {'s': 2},
+	"of":  {' ': 20, 'f': 4, 't': 1},
+	"og":  {'.': 1, 'e': 1},
+	"oi":  {'n': 8, 's': 1},
+	"ok":  {'s': 2, '.': 1, 'i': 1},
+	"ol":  {'d': 8, 'l': 3, ' ': 1, 'a': 1},
+	"om":  {'e': 13, ' ': 5, 'i': 2, 'm': 2, 'o': 2, 'p': 2, 'b': 1},
+	"on":  {' ': 31, 'e': 12, 'g': 8, '.': 6, 'd': 5, 'l': 5, 't': 5, 'i': 3, 's': 3, 'n': 1},
+	"oo":  {'d': 8, 'k': 4, 'n': 3, 's': 2, 'f': 1, 'l': 1, 'r': 1},
+	"op":  {'e': 7, ' ': 4, 'l': 4, 'p': 2, ',': 1, 's': 1, 'y': 1},
+	"or":  {' ': 27, 'e': 14, 'k': 12, 't': 9, 'd': 4, 'm': 4, 'n': 4, 'r': 3, 'w': 2, 'i': 1, 'p': 1, 's': 1},
+	"os":  {'e': 10, 't': 4, 'i': 2, 's': 1},
+	"ot":  {'h': 7, ' ': 3, 'e': 2},
+	"ou":  {' ': 28, 'r': 14, 't': 11, 'l': 10, 'n': 8, 's': 4, 'd': 3, 'g': 3, 'p': 3, ',': 1, '?': 1},
+	"ov":  {'e': 7},
+	"ow":  {' ': 23, 'n': 9, 'e': 5, '.': 4, ',': 1, '?': 1, 'i': 1, 's': 1},
+	"p ":  {'a': 2, 'f': 2, 'i': 2, 's': 2, 't': 2, 'b': 1, 'c': 1, 'o': 1, 'w': 1},
+	"p,":  {' ': 2},
+	"pa":  {'r': 4, 't': 4, 's': 3, 'c': 2, 'n': 1, 'p': 1},
+	"pd":  {'a': 2},
+	"pe":  {'n': 6, 'r': 5, 'a': 4, 'o': 4, 'd': 3, ' ': 1},
+	"pl":  {'e': 10, 'a': 5, 'y': 1},
+	"po":  {'i': 2, 'r': 2, 'w': 2, 's': 1},
+	"pp":  {'e': 3, 'y': 1},
+	"pr":  {'o': 6, 'e': 5},
+	"ps":  {' ': 4},
+	"pt":  {' ': 2},
+	"pu":  {'s': 1, 't': 1},
+	"py":  {' ': 2},
+	"qu":  {'i': 4, 'a': 1},
+	"r ":  {'t': 34, 'a': 9, 'p': 8, 's': 8, 'f': 6, 'n': 6, 'o': 6, 'i': 5, 'w': 4, 'b': 3, 'm': 3, 'r': 3, 'g': 2, 'h': 2, 'l': 2, 'c': 1, 'd': 1, 'j': 1, 'u': 1, 'y': 1},
+	"r,":  {' ': 3},
+	"ra":  {'i': 6, 't': 5, 'd': 4, 'c': 2, 'f': 1, 'l': 1, 'r': 1, 's': 1, 'w': 1},
+	"rc":  {'h': 2},
+	"rd":  {' ': 7, 's': 4, 'a': 2, 'i': 2, ',': 1, 'e': 1},
+	"re":  {' ': 61, 'a': 12, 's': 10, ',': 5, 'n': 5, 'p': 5, 'e': 4, 'd': 3, 't': 3, '.': 2, 'l': 2, '?': 1, 'c': 1, 'g': 1, 'm': 1},
+	"rf":  {'e': 1, 'u': 1},
+	"rg":  {'e': 3},
+	"ri":  {'v': 5, 'd': 4, 'n': 4, 'e': 3, 't': 3, 'g': 2, 'p': 2, 'b': 1, 'm': 1},
+	"rk":  {' ': 9, 'e': 3, ',': 1, '.': 1, 'i': 1, 's': 1},
+	"rl":  {'y': 2},
+	"rm":  {' ': 2, '.': 2, ',': 1, 'e': 1, 'w': 1},
+	"rn":  {'i': 5, ' ': 3, 'o': 2, ',': 1, '.': 1, 'e': 1, 's': 1},
+	"ro":  {'u': 10, 'm': 5, 'w': 4, 'a': 3, 'b': 3, 'n': 2, 'p': 2, 's': 2, 'c': 1, 'j': 1, 'o': 1, 't': 1, 'v': 1},
+	"rr":  {'i': 3, 'o': 3, 'y': 1},
+	"rs":  {' ': 6, 't': 6, ',': 3, '.': 2, 'd': 1},
+	"rt":  {' ': 8, 's': 4, 'h': 3, 'e': 2, 'i': 1},
+	"ru":  {'l': 1, 's': 1},
+	"rw":  {'a': 2},
+	"ry":  {' ': 17, 'o': 3, 't': 2, 'd': 1},
+	"s ":  {'a': 31, 't': 26, 'w': 18, 'b': 11, 'o': 11, 's': 9, 'c': 7, 'f': 7, 'l': 7, 'i': 6, 'e': 4, 'm': 4, 'n': 4, 'g': 3, 'r': 2, 'd': 1, 'h': 1, 'q': 1, 'u': 1, 'v': 1},
+	"s,":  {' ': 10},
+	"s.":  {' ': 4},
+	"sa":  {'g': 13, 'f': 1, 'i': 1, 'm': 1, 'n': 1, 's': 1, 'v': 1},
+	"sc":  {'r': 1, 'u': 1},
+	"se":  {' ': 18, 'd': 8, 'n': 8, 'e': 7, 'a': 4, 't': 4, 'c': 3, 's': 3, 'v': 3, '.': 2, 'l': 2, 'm': 1, 'u': 1},
+	"sh":  {'o': 13, 'e': 4, 'i': 4, ' ': 3, 'a': 2, ',': 1, '.': 1},
+	"si":  {'g': 5, 'm': 4, 't': 4, 'n': 3, 'd': 2, 'c': 1, 'o': 1, 'x': 1},
+	"sk":  {' ': 2, '.': 1, 'y': 1},
+	"sl":  {'a': 1, 'o': 1},
+	"sm":  {'a': 6},
+	"sn":  {'o': 2},
+	"so":  {' ': 10, 'm': 6, 'u': 3, 'l': 1, 'r': 1},
+	"sp":  {'a': 2, 'e': 2},
+	"ss":  {'a': 13, ' ': 6, 'e': 5, 'u': 2, 'i': 1},
+	"st":  {' ': 20, 'a': 15, 'e': 9, 'o': 7, 'i': 5, '.': 2, ',': 1, 'r': 1, 's': 1},
+	"su":  {'r': 4, 'a': 3, 'm': 3, 'n': 2, 'l': 1},
+	"sy":  {' ': 2},
+	"t ":  {'t': 31, 'i': 21, 'w': 18, 'a': 16, 's': 14, 'c': 9, 'o': 9, 'f': 7, 'm': 6, 'n': 6, 'y': 6, 'e': 4, 'd': 3, 'h': 3, 'u': 3, 'b': 2, 'l': 2, 'r': 2, 'k': 1, 'p': 1, 'v': 1},
+	"t,":  {' ': 9},
+	"t.":  {' ': 3},
+	"ta":  {'r': 8, 't': 4, 'l': 3, 'c': 2, 'k': 2, 'i': 1, 'n': 1, 'p': 1, 'y': 1},
+	"tc":  {'h': 3},
+	"te":  {'r': 30, 'n': 9, ' ': 7, 's': 6, 'p': 4, 'd': 3, '.': 2, 'a': 2, ',': 1, ':': 1, 'e': 1, 'l': 1, 'm': 1},
+	"th":  {'e': 220, 'a': 25, ' ': 18, 'i': 18, 'r': 5, 'o': 2, '.': 1, 'd': 1},
+	"ti":  {'n': 13, 'o': 13, 'm': 10, 'l': 6, 'c': 1, 'e': 1, 'r': 1, 's': 1},
+	"to":  {' ': 43, 'r': 6, 'w': 5, 'p': 4, 'n': 3, 'd': 2, 'm': 2, 'g': 1, 'l': 1, 'o': 1},
+	"tr":  {'a': 4, 'e': 2, 'i': 2, 'y': 1},
+	"ts":  {' ': 11, '.': 2},
+	"tt":  {'e': 9, 'i': 3},
+	"tu":  {'r': 8},
+	"tw":  {'o': 7, 'e': 2},
+	"ty":  {' ': 2, '.': 1},
+	"u ":  {'c': 6, 'a': 5, 'w': 3, 'h': 2, 'n': 2, 's': 2, 't': 2, 'g': 1, 'i': 1, 'k': 1, 'l': 1, 'm': 1, 'o': 1},
+	"ua":  {'l': 3, 'r': 2, 'g': 1},
+	"uc":  {'h': 2},
+	"ud":  {'i': 2, ' ': 1, 'e': 1, 'g': 1, 's': 1},
+	"ue":  {' ': 1, '.': 1, 's': 1},
+	"ug":  {'h': 3},
+	"ui":  {'c': 4, 'l': 2},
+	"ul":  {'d': 10, 't': 2, ' ': 1, 'e': 1, 'l': 1},
+	"um":  {'m': 3, ',': 1, 'b': 1, 'i': 1, 'p': 1},
+	"un":  {'d': 8, 'c': 4, 't': 4, ',': 1, 'i': 1, 's': 1},
+	"up":  {' ': 5, '.': 2, 'd': 2, ',': 1},
+	"ur":  {' ': 14, 'n': 7, 'e': 6, 'i': 2, 'c': 1, 'd': 1, 's': 1},
+	"us":  {'e': 8, ' ': 3, 'i': 2, 't': 2, 'u': 2, '.': 1, 'a': 1, 'h': 1},
+	"ut":  {' ': 19, 'e': 5, 'h': 1},
+	"va":  {'l': 3},
+	"ve":  {'r': 25, ' ': 14, 'n': 6, 'd': 4, 's': 2, ',': 1, '?': 1},
+	"vi":  {'e': 2, 'l': 2, 'c': 1, 'n': 1},
+	"w ":  {'t': 6, 'i': 5, 'w': 4, 'a': 2, 'b': 2, 'o': 2, 's': 2, 'u': 2, 'e': 1, 'f': 1, 'h': 1, 'k': 1, 'l': 1, 'm': 1, 'p': 1, 'r': 1},
+	"wa":  {'s': 12, 'y': 8, 'i': 6, 'r': 4, 't': 4, 'n': 3, 'l': 2},
+	"we":  {'r': 10, ' ': 8, 'a': 6, 'e': 5, 'l': 3, 's': 1},
+	"wh":  {'e': 12, 'o': 3, 'a': 2, 'i': 2, 'y': 2},
+	"wi":  {'t': 13, 'l': 12, 'n': 5},
+	"wn":  {' ': 6, '.': 3},
+	"wo":  {'r': 16, ' ': 3, 'u': 2, 'n': 1},
+	"wr":  {'i': 2, 'o': 2},
+	"ws":  {' ': 3, 'p': 1},
+	"xt":  {' ': 5},
+	"y ":  {'t': 13, 'a': 8, 'i': 7, 'w': 7, 'b': 4, 'h': 4, 's': 4, 'c': 3, 'd': 3, 'o': 3, 'p': 3, 'g': 2, 'm': 2, 'f': 1, 'k': 1, 'n': 1, 'y': 1},
+	"y,":  {' ': 3},
+	"ye":  {'a': 2, 's': 1},
+	"yo":  {'u': 39, 'n': 5},
+	"ys":  {' ': 2, '.': 1},
+	"yt":  {'h': 4, 'e': 1},
+	" I ": {'w': 4, 't': 2, 'a': 1, 'b': 1, 'c': 1, 'd': 1, 'm': 1, 'r': 1},
+	" Th": {'e': 8, 'a': 2, 'u': 1},
+	" a ": {'s': 9, 'm': 6, 'w': 6, 'b': 2, 'l': 2, 't': 2, 'c': 1, 'd': 1, 'f': 1, 'g': 1, 'h': 1, 'n': 1, 'p': 1, 'r': 1},
+	" al": {'l': 5, 'm': 2, 'o': 2, 'r': 1, 'w': 1},
+	" an": {'d': 54, ' ': 5, 'y': 3, 't': 2},
+	" ar": {'e': 19, 'r': 3, 'o': 2},
+	" at": {' ': 20},
+	" be": {' ': 8, 'f': 6, 'c': 3, 'e': 3, 'n': 2, 't': 2, 'g': 1, 'h': 1, 'l': 1, 's': 1},
+	" bu": {'t': 9, 'i': 2, 'd': 1},
+	" ca": {'n': 8, 'm': 4, 'r': 4, 'l': 2, 'b': 1, 'k': 1, 's': 1, 't': 1},
+	" ch": {'a': 10, 'e': 4, 'i': 1, 'u': 1},
+	" cl": {'o': 7, 'e': 4, 'i': 2},
+	" co": {'m': 9, 'u': 5, 'n': 4, 'l': 3, 'v': 2, 'f': 1, 'o': 1, 'r': 1},
+	" de": {'s': 5, 'l': 2, 'c': 1, 'f': 1, 'v': 1},
+	" do": {'w': 4, '.': 1, 'e': 1, 'g': 1, 'o': 1},
+	" ev": {'e': 15},
+	" fi": {'r': 4, 'n': 3, 'e': 2, 'v': 2, 'f': 1, 's': 1},
+	" fo": {'r': 23, 'l': 1, 'o': 1, 'x': 1},
+	" fr": {'o': 6, 'e': 1, 'i': 1},
+	" go": {'o': 4, 'i': 3, 'e': 1},
+	" ha": {'v': 8, 's': 4, 'r': 3, 'd': 2, 'l': 1, 'n': 1, 'p': 1},
+	" he": {'a': 7, 'r': 3, 'l': 1},
+	" ho": {'w': 4, 'u': 3, 'l': 2, 'b': 1, 'm': 1, 'p': 1},
+	" in": {' ': 22, 't': 2, 'c': 1, 's': 1},
+	" is": {' ': 37, '.': 1, 'l': 1},
+	" it": {' ': 22, '.': 3, ',': 1},
+	" kn": {'o': 9},
+	" la": {'s': 4, 'k': 2, 't': 2, 'n': 1, 'r': 1, 'z': 1},
+	" li": {'g': 2, 'k': 2, 'n': 2, 'b': 1, 'f': 1, 's': 1, 'v': 1},
+	" lo": {'n': 5, 'c': 2, 'o': 2, 'u': 2, 'w': 2, 's': 1},
+	" ma": {'i': 2, 'n': 2, 'd': 1, 'k': 1, 'r': 1, 't': 1, 'y': 1},
+	" me": {'s': 15, ' ': 4, 'e': 4, 'a': 2, '?': 1},
+	" mi": {'s': 4, 'n': 3, 'l': 2},
+	" mo": {'r': 9, 'u': 2, 'b': 1, 'd': 1, 'm': 1, 'n': 1, 's': 1},
+	" ne": {'w': 8, 't': 6, 'a': 5, 'e': 5, 'x': 5, 's': 1, 'v': 1},
+	" no": {'d': 8, 't': 5, 'w': 5, ' ': 2, 'i': 1, 'o': 1, 'r': 1},
+	" of": {' ': 19, 'f': 3, 't': 1},
+	" on": {' ': 22, 'e': 5, 'l': 5},
+	" pa": {'t': 4, 'r': 3, 's': 3, 'c': 2, 'n': 1},
+	" pr": {'o': 5, 'e': 4},
+	" re": {'a': 8, 'p': 5, 's': 3, 'l': 2, 't': 2, 'g': 1, 'm': 1, 'n': 1},
+	" se": {'n': 8, 'e': 7, 'a': 4, 'c': 3, 'v': 3, 'l': 2, 't': 2},
+	" sh": {'o': 13, 'i': 4, 'a': 2, 'e': 2},
+	" si": {'g': 4, 'm': 4, 'd': 2, 'n': 2, 't': 2, 'x': 1},
+	" so": {' ': 10, 'm': 6, 'u': 3, 'l': 1},
+	" st": {'a': 12, 'o': 6, 'e': 5, 'i': 2, 'r': 1},
+	" th": {'e': 208, 'a': 25, 'i': 10, 'r': 5, 'o': 2},
+	" ti": {'m': 10, 'r': 1},
+	" to": {' ': 41, 'w': 5, 'n': 3, 'd': 2, 'm': 2, 'g': 1, 'l': 1, 'p': 1},
+	" tr": {'a': 4, 'i': 2, 'e': 1, 'y': 1},
+	" wa": {'s': 12, 'i': 6, 'y': 6, 't': 4, 'n': 3, 'l': 2, 'r': 1},
+	" we": {' ': 8, 'a': 6, 'r': 5, 'e': 4, 'l': 2, 's': 1},
+	" wh": {'e': 11, 'o': 3, 'a': 2, 'i': 2, 'y': 2},
+	" wi": {'l': 12, 't': 12, 'n': 4},
+	" wo": {'r': 12, 'u': 2, 'n': 1},
+	" yo": {'u': 39},
+	", a": {'n': 11, 'l': 1},
+	", b": {'u': 7, 'e': 2, 'r': 1},
+	", s": {'o': 4, 'e': 2, 'i': 1, 't': 1},
+	", t": {'h': 8},
+	". T": {'h': 10, 'a': 1},
+	"The": {' ': 26, 'r': 4, 's': 1},
+	"a s": {'h': 3, 'i': 3, 'm': 3, 'p': 1, 't': 1},
+	"ad ": {'t': 5, 'i': 2, 'n': 1, 'p': 1, 'w': 1},
+	"age": {' ': 8, 's': 7, '.': 2, 'n': 1},
+	"ain": {' ': 7, ',': 1, 'i': 1, 'l': 1},
+	"ake": {' ': 4, ',': 2, '.': 1, 'r': 1},
+	"all": {' ': 10, 'e': 3, 'y': 3, '.': 2, ',': 1, 'o': 1},
+	"an ": {'r': 4, 'a': 2, 'e': 2, 'h': 2, 'o': 2, 's': 2, 'w': 2, 'b': 1, 'p': 1, 't': 1, 'y': 1},
+	"and": {' ': 57, '.': 1, 'i': 1},
+	"ar ": {'t': 7, 'a': 2, 'm': 2, 'p': 2, 'b': 1, 'f': 1, 'i': 1, 'y': 1},
+	"are": {' ': 22, 'n': 2, ',': 1, '.': 1},
+	"art": {' ': 4, 's': 2, 'e': 1, 'i': 1},
+	"as ": {'b': 2, 't': 2, 'w': 2, 'a': 1, 'c': 1, 'd': 1, 'f': 1, 'g': 1, 'i': 1, 'l': 1, 'n': 1, 'o': 1, 'r': 1, 's': 1},
+	"ast": {' ': 5, 'e': 2, '.': 1},
+	"at ": {'t': 13, 's': 5, 'w': 5, 'a': 4, 'y': 4, 'e': 3, 'i': 3, 'n': 2, 'c': 1, 'f': 1, 'h': 1, 'm': 1, 'p': 1, 'r': 1},
+	"ate": {'r': 5, ' ': 4, ',': 1, ':': 1, 's': 1},
+	"ath": {'e': 5, ' ': 3},
+	"ati": {'o': 7, 'e': 1, 's': 1},
+	"ave": {' ': 9, 'd': 1, 's': 1},
+	"ay ": {'a': 3, 'i': 3, 't': 3, 'w': 3, 'b': 1, 'd': 1, 's': 1},
+	"be ": {'t': 2, 'b': 1, 'c': 1, 'f': 1, 'i': 1, 'o': 1, 's': 1, 'y': 1},
+	"but": {' ': 9},
+	"can": {' ': 8},
+	"ch ": {'a': 4, 't': 4, 'c': 1, 'f': 1, 'n': 1, 'o': 1},
+	"cha": {'n': 5, 'r': 4, 't': 1},
+	"com": {'e': 4, 'i': 2, 'm': 2, 'p': 2, 'b': 1},
+	"con": {'d': 4, 't': 3, 'n': 1},
+	"d a": {'n': 8, ' ': 3, 'f': 2, 'l': 1, 'r': 1},
+	"d i": {'n': 4, 's': 2, 't': 2, 'd': 1},
+	"d m": {'o': 4, 'e': 2, 'i': 2, 'a': 1, 'y': 1},
+	"d n": {'o': 5, 'e': 2, 'i': 1},
+	"d s": {'h': 3, 'o': 3, 'e': 2, 't': 1},
+	"d t": {'h': 30, 'o': 8, 'i': 2, 'a': 1, 'e': 1, 'u': 1},
+	"day": {' ': 8, '.': 2, '?': 2, '!': 1},
+	"de ": {'o': 3, 'i': 2, 'w': 2, 'f': 1, 's': 1},
+	"des": {' ': 3, 'k': 2, 'c': 1, 'i': 1, 't': 1},
+	"din": {'g': 7, 'a': 1},
+	"e a": {'n': 9, 'r': 9, ' ': 3, 'f': 2, 'g': 2, 'i': 2, 't': 2, 'b': 1, 'l': 1, 'u': 1},
+	"e b": {'e': 6, 'o': 4, 'a': 3, 'i': 2, 'u': 2, 'r': 1, 'y': 1},
+	"e c": {'o': 10, 'a': 7, 'h': 6, 'l': 3, 'i': 1},
+	"e d": {'e': 4, 'o': 3, 'a': 1, 'i': 1, 'r': 1, 'u': 1},
+	"e e": {'v': 6, 'a': 4, 'n': 2, 'd': 1, 'l': 1},
+	"e f": {'o': 8, 'i': 4, 'r': 2},
+	"e h": {'a': 5, 'i': 5, 'o': 3, 'u': 2},
+	"e i": {'s': 9, 't': 6, 'n': 2},
+	"e l": {'a': 7, 'i': 6, 'o': 4, 'e': 1, 'u': 1},
+	"e m": {'e': 8, 'a': 6, 'o': 6, 'u': 1},
+	"e n": {'e': 16, 'o': 5, 'a': 1, 'i': 1, 'u': 1},
+	"e o": {'f': 7, 'n': 6, 'l': 3, 'p': 2, 'r': 2, 't': 2},
+	"e p": {'r': 5, 'a': 3, 'e': 3, 'o': 2, 'l': 1},
+	"e r": {'e': 5, 'i': 5, 'a': 4, 'o': 3, 'u': 1},
+	"e s": {'t': 11, 'e': 8, 'h': 8, 'i': 5, 'o': 4, 'u': 4, 'a': 3, 'n': 2, 'k': 1, 'q': 1},
+	"e t": {'h': 23, 'o': 9, 'r': 6, 'i': 3, 'e': 2, 'a': 1, 'w': 1},
+	"e w": {'a': 8, 'e': 7, 'i': 7, 'o': 4, 'h': 3, 'r': 2},
+	"e y": {'o': 13, 'e': 1},
+	"e, ": {'b': 4, 't': 3, 'a': 2, 'i': 1, 'j': 1, 'r': 1, 's': 1, 'w': 1},
+	"ead": {' ': 5, 'i': 2, 'y': 1},
+	"ear": {' ': 15, 'l': 2, 'c': 1, 'd': 1, 'n': 1, 's': 1},
+	"eas": {'e': 3, 't': 2, 'u': 2, 'y': 2, ' ': 1},
+	"eat": {'h': 4, 'e': 2, ',': 1, '.': 1},
+	"ed ": {'t': 11, 'a': 7, 'i': 3, 'm': 3, 'b': 1, 'd': 1, 'p': 1, 'q': 1, 'r': 1, 's': 1, 'u': 1},
+	"ee ": {'t': 3, 'y': 3, 'a': 2, 'p': 1, 'w': 1},
+	"en ": {'t': 8, 'i': 5, 'a': 2, 'm': 2, 's': 2, 'u': 2, 'w': 2, 'y': 2, 'c': 1, 'd': 1, 'j': 1, 'o': 1, 'p': 1},
+	"end": {' ': 6, 'i': 3, 's': 2, 'a': 1},
+	"ent": {' ': 5, ',': 1, 'e': 1, 's': 1},
+	"er ": {'t': 19, 'f': 4, 'i': 3, 'o': 3, 's': 3, 'a': 2, 'n': 2, 'p': 2, 'r': 2, 'w': 2, 'c': 1, 'h': 1},
+	"ere": {' ': 25, ',': 2, '?': 1, 'd': 1},
+	"ers": {' ': 5, ',': 2, 't': 2, '.': 1},
+	"ery": {' ': 12, 'o': 3, 't': 2, 'd': 1},
+	"es ": {'a': 8, 't': 7, 'b': 4, 'w': 3, 'i': 2, 'o': 2, 'c': 1, 'f': 1, 'h': 1, 'l': 1, 's': 1},
+	"ess": {'a': 13, ' ': 3, 'u': 2, 'i': 1},
+	"est": {' ': 5, 'i': 2, '.': 1, 'e': 1, 's': 1},
+	"et ": {'f': 2, 'm': 2, 's': 2, 'a': 1, 'h': 1, 'i': 1, 'l': 1, 'u': 1},
+	"eve": {'r': 13, 'n': 6, ' ': 1},
+	"ew ": {'t': 2, 'f': 1, 'i': 1, 'k': 1, 'm': 1, 'o': 1, 'p': 1, 'r': 1, 's': 1},
+	"f t": {'h': 15},
+	"for": {' ': 19, 'e': 7, 'w': 2, 'm': 1},
+	"fte": {'r': 7, 'e': 1, 'n': 1},
+	"g t": {'h': 11, 'i': 1, 'o': 1},
+	"ge ": {'a': 2, 'b': 2, 'c': 2, 'w': 2, 'h': 1, 'o': 1, 't': 1, 'y': 1},
+	"ght": {' ': 6, '.': 3, 's': 2},
+	"h a": {' ': 3, 'f': 2, 'n': 2, 'b': 1, 'l': 1},
+	"h t": {'h': 9, 'o': 3, 'w': 1},
+	"han": {'k': 7, 'n': 4, ' ': 3, 'd': 1, 'g': 1},
+	"hat": {' ': 25, '.': 1},
+	"hav": {'e': 9},
+	"he ": {'s': 33, 'c': 17, 'n': 16, 'm': 15, 'l': 14, 'r': 14, 't': 13, 'w': 13, 'p': 11, 'e': 10, 'h': 9, 'b': 8, 'o': 8, 'a': 6, 'f': 6, 'v': 6, 'd': 3, 'g': 3, 'u': 2, 'i': 1, 'q': 1},
+	"hen": {' ': 15},
+	"her": {'e': 23, ' ': 9, 's': 2, '.': 1, 'm': 1},
+	"hil": {'l': 5, 'e': 3, 'd': 2},
+	"hin": {'g': 10, 'k': 4, 'i': 1},
+	"hou": {'l': 5, 'r': 2, 's': 2},
+	"igh": {'t': 11, 'e': 1},
+	"ill": {' ': 19, 'a': 2, 's': 2, '.': 1},
+	"ime": {' ': 9, ',': 1, 'n': 1},
+	"in ": {'t': 18, 'a': 4, 's': 2, 'w': 2, 'E': 1, 'o': 1, 'p': 1, 'r': 1},
+	"ine": {' ': 6, ',': 1, 'd': 1, 's': 1},
+	"ing": {' ': 40, '.': 10, 's': 4, ',': 2, '?': 1, 'l': 1},
+	"ion": {' ': 8, '.': 3, 's': 3},
+	"is ": {'a': 9, 'c': 5, 'w': 5, 't': 4, 'l': 3, 's': 3, 'b': 2, 'f': 2, 'm': 2, 'n': 2, 'o': 2, 'e': 1, 'g': 1, 'v': 1},
+	"it ": {'i': 5, 'w': 4, 'c': 3, 's': 3, 'f': 2, 't': 2, 'u': 2, 'a': 1, 'b': 1, 'h': 1, 'o': 1, 'r': 1},
+	"ith": {' ': 12},
+	"ive": {'d': 3, 'r': 3, ' ': 1, ',': 1, '?': 1},
+	"kno": {'w': 9},
+	"ld ": {'b': 3, 'm': 3, 's': 2, 'a': 1, 'c': 1, 'd': 1, 'h': 1, 'k': 1, 'l': 1, 'n': 1, 'p': 1, 'r': 1, 'u': 1, 'w': 1},
+	"le ": {'w': 2, 'a': 1, 'b': 1, 'c': 1, 'd': 1, 'j': 1, 'l': 1, 'n': 1, 'o': 1, 's': 1, 't': 1},
+	"lea": {'r': 5, 's': 4, 'v': 1},
+	"ll ": {'s': 5, 'b': 4, 'h': 3, 't': 3, 'a': 2, 'c': 2, 'd': 2, 'n': 2, 'o': 2, 'f': 1, 'g': 1, 'k': 1, 'l': 1, 'm': 1, 'p': 1, 'u': 1},
+	"ly ": {'t': 3, 'i': 2, 'b': 1, 'c': 1, 'm': 1, 'w': 1},
+	"me ": {'t': 3, 'h': 2, 'k': 2, 'l': 2, 'o': 2, 's': 2, 'a': 1, 'b': 1, 'd': 1, 'f': 1, 'i': 1, 'm': 1, 'w': 1, 'y': 1},
+	"mes": {'s': 13, ' ': 3, 'h': 2, '.': 1},
+	"mor": {'e': 5, 'n': 4, 'r': 2},
+	"n a": {' ': 4, 'n': 4, 'b': 1, 'r': 1, 's': 1},
+	"n t": {'h': 43, 'o': 7, 'r': 1},
+	"nd ": {'t': 24, 'a': 5, 'n': 5, 'c': 4, 'h': 4, 'i': 4, 'o': 4, 's': 4, 'd': 3, 'm': 3, 'w': 3, 'y': 2, 'I': 1, 'b': 1, 'e': 1, 'f': 1, 'u': 1},
+	"ne ": {'a': 2, 'f': 2, 'o': 2, 's': 2, 'c': 1, 'd': 1, 'e': 1, 'i': 1, 'm': 1, 'n': 1, 't': 1},
+	"new": {' ': 7, 's': 2},
+	"ng ": {'t': 13, 'i': 6, 'a': 5, 'w': 4, 'o': 3, 'e': 2, 'f': 2, 'g': 2, 'h': 2, 'p': 2, 's': 2, 'y': 2, 'I': 1, 'c': 1, 'u': 1},
+	"nin": {'g': 11, 'e': 1},
+	"now": {' ': 13, '.': 2, 'n': 1},
+	"nt ": {'t': 4, 'o': 2, 'd': 1, 'f': 1, 'i': 1},
+	"o t": {'h': 15, 'e': 1, 'o': 1},
+	"ode": {' ': 4, 's': 3, '.': 2, 'l': 1},
+	"of ": {'t': 13, 'a': 2, 'e': 1, 'o': 1, 'r': 1, 's': 1, 'w': 1},
+	"ome": {' ': 4, 't': 3, 's': 2, ',': 1, 'n': 1, 'o': 1, 'w': 1},
+	"on ": {'t': 18, 'a': 3, 'S': 2, 'o': 2, 'F': 1, 'T': 1, 'f': 1, 'l': 1, 'u': 1, 'w': 1},
+	"one": {' ': 9, '.': 3},
+	"or ": {'t': 7, 'a': 5, 's': 4, 'n': 2, 'w': 2, 'g': 1, 'h': 1, 'i': 1, 'j': 1, 'l': 1, 'm': 1, 'o': 1},
+	"ore": {' ': 11, 's': 2, '.': 1},
+	"ork": {' ': 8, 'e': 2, 'i': 1, 's': 1},
+	"ort": {' ': 4, 'h': 2, 's': 2, 'e': 1},
+	"ose": {' ': 6, 'd': 2, 's': 2},
+	"ou ": {'c': 6, 'a': 5, 'w': 3, 'h': 2, 'n': 2, 's': 2, 't': 2, 'g': 1, 'i': 1, 'k': 1, 'l': 1, 'm': 1, 'o': 1},
+	"oul": {'d': 10},
+	"our": {' ': 13, 'n': 1},
+	"out": {' ': 9, 'e': 1, 'h': 1},
+	"ow ": {'i': 4, 't': 4, 'w': 4, 'a': 2, 'b': 2, 'u': 2, 'e': 1, 'h': 1, 'l': 1, 'o': 1, 's': 1},
+	"own": {' ': 6, '.': 3},
+	"ple": {' ': 7, 'a': 2, ',': 1},
+	"r a": {'n': 4, ' ': 3, 'l': 1, 't': 1},
+	"r t": {'h': 25, 'o': 4, 'i': 2, 'r': 1, 'u': 1, 'w': 1},
+	"re ": {'a': 9, 'i': 9, 't': 9, 's': 6, 'w': 5, 'y': 4, 'd': 3, 'e': 2, 'f': 2, 'g': 2, 'h': 2, 'n': 2, 'b': 1, 'c': 1, 'm': 1, 'o': 1, 'p': 1, 'r': 1},
+	"rea": {'d': 6, 'c': 4, 't': 2},
+	"res": {'s': 3, ' ': 2, 'c': 1, 'e': 1, 'h': 1, 't': 1, 'u': 1},
+	"rk ": {'a': 2, 'c': 2, 'w': 2, 'i': 1, 'o': 1, 't': 1},
+	"rou": {'g': 3, 'n': 3, 'p': 2, 's': 1, 't': 1},
+	"ry ": {'a': 3, 'o': 2, 'p': 2, 't': 2, 'b': 1, 'c': 1, 'f': 1, 'i': 1, 'm': 1, 'n': 1, 's': 1, 'y': 1},
+	"s a": {'t': 8, ' ': 7, 'n': 7, 'r': 5, 'l': 2, 'b': 1, 'm': 1},
+	"s b": {'e': 6, 'a': 2, 'y': 2, 'u': 1},
+	"s o": {'f': 4, 'n': 3, 'u': 2, 'p': 1, 'v': 1},
+	"s s": {'e': 2, 'h': 2, 'i': 1, 'l': 1, 'o': 1, 'p': 1, 't': 1},
+	"s t": {'h': 22, 'o': 2, 'a': 1, 'i': 1},
+	"s w": {'e': 7, 'i': 5, 'h': 2, 'o': 2, 'a': 1, 'r': 1},
+	"s, ": {'a': 4, 'I': 1, 'b': 1, 'c': 1, 'i': 1, 'm': 1, 's': 1},
+	"sag": {'e': 13},
+	"se ": {'t': 5, 'a': 3, 'o': 3, 'b': 2, 'r': 2, 'c': 1, 'd': 1, 'g': 1},
+	"sho": {'r': 6, 'u': 5, 'p': 1, 'w': 1},
+	"so ": {'t': 3, 'I': 2, 'm': 2, 'a': 1, 'l': 1, 'q': 1},
+	"ssa": {'g': 13},
+	"st ": {'a': 5, 'w': 4, 'c': 2, 'n': 2, 's': 2, 'i': 1, 'k': 1, 'o': 1, 't': 1, 'v': 1},
+	"sta": {'r': 7, 't': 4, 'k': 1, 'l': 1, 'n': 1, 'y': 1},
+	"ste": {'p': 4, 'r': 4, 'a': 1},
+	"t a": {'n': 6, ' ': 4, 'l': 3, 't': 2, 'r': 1},
+	"t c": {'o': 4, 'a': 2, 'h': 2, 'l': 1},
+	"t i": {'s': 11, 't': 6, 'n': 3, 'f': 1},
+	"t o": {'n': 6, 'f': 1, 'r': 1, 'v': 1},
+	"t s": {'e': 5, 't': 3, 'i': 2, 'o': 2, 'h': 1, 'p': 1},
+	"t t": {'h': 19, 'o': 8, 'e': 2, 'i': 1, 'u': 1},
+	"t w": {'a': 6, 'e': 3, 'h': 3, 'i': 3, 'o': 3},
+	"t, ": {'I': 3, 'b': 1, 'g': 1, 'i': 1, 'k': 1, 'o': 1, 't': 1},
+	"ten": {' ': 5, 'n': 2, 'c': 1, 'd': 1},
+	"ter": {' ': 16, 's': 3, 'y': 3, '.': 2, 'n': 2, ',': 1, '?': 1, 'd': 1, 'i': 1},
+	"th ": {'t': 6, 'a': 5, 'o': 2, 'e': 1, 'g': 1, 'm': 1, 'n': 1, 'y': 1},
+	"tha": {'t': 20, 'n': 5},
+	"the": {' ': 182, 'r': 21, 'n': 5, 'y': 5, 'i': 3, 'm': 3, 's': 1},
+	"thi": {'n': 13, 's': 4, 'r': 1},
+	"tim": {'e': 10},
+	"tin": {'g': 11, 'a': 1, 'u': 1},
+	"tio": {'n': 13},
+	"to ": {'t': 12, 'a': 6, 'c': 5, 'h': 3, 'r': 3, 'i': 2, 'm': 2, 's': 2, 'w': 2, 'b': 1, 'd': 1, 'f': 1, 'l': 1, 'o': 1, 'y': 1},
+	"ts ": {'a': 3, 't': 3, 'e': 1, 'f': 1, 'o': 1, 's': 1, 'w': 1},
+	"tte": {'r': 7, 'n': 2},
+	"uld": {' ': 10},
+	"ur ": {'o': 2, 'p': 2, 'b': 1, 'd': 1, 'f': 1, 'g': 1, 'l': 1, 'n': 1, 'r': 1, 's': 1, 't': 1, 'u': 1},
+	"ut ": {'t': 9, 'i': 3, 'a': 1, 'c': 1, 'e': 1, 'f': 1, 'o': 1, 'w': 1, 'y': 1},
+	"ve ": {'b': 3, 'l': 2, 't': 2, 'a': 1, 'f': 1, 'i': 1, 'n': 1, 'p': 1, 's': 1, 'y': 1},
+	"ver": {'y': 14, ' ': 8, 'a': 1, 'e': 1, 's': 1},
+	"was": {' ': 12},
+	"wer": {'e': 5, ' ': 3, ',': 1, 's': 1},
+	"whe": {'n': 8, 'r': 4},
+	"wil": {'l': 12},
+	"wit": {'h': 12, 'c': 1},
+	"wor": {'k': 12, 'd': 2, 'm': 1, 't': 1},
+	"y t": {'o': 7, 'h': 6},
+	"you": {' ': 27, 'r': 10, ',': 1, '?': 1},
+}
@@ -1,4 +1,4 @@
-package arithcode
+package models
 
 import (
 	"bytes"
@@ -41,6 +41,28 @@ func TestEnglishOrder2Model(t *testing.T) {
 	}
 }
 
+func TestEnglishBigramModel(t *testing.T) {
+	for _, test := range []string{"Hello World", "The weather is nice, meet at the station", "Grüße 😀"} {
+		var buf bytes.Buffer
+		if err := EncodeStringBigram(test, &buf); err != nil {
+			t.Fatalf("EncodeStringBigram failed: %v", err)
+		}
+		result, err := DecodeStringBigram(&buf)
+		if err != nil {
+			t.Fatalf("DecodeStringBigram failed: %v", err)
+		}
+		if result != test {
+			t.Errorf("got %q, want %q", result, test)
+		}
+	}
+
+	// The bigram tables are not the generated ones
+	bigram, generated := NewEnglishBigramModel(), SharedEnglishOrder2Model()
+	if len(bigram.contextModels) >= len(generated.contextModels) {
+		t.Errorf("bigram model has %d contexts, generated %d", len(bigram.contextModels), len(generated.contextModels))
+	}
+}
+
 func TestEnglishOrderComparison(t *testing.T) {
 	testStrings := []string{
 		"The quick brown fox jumps over the lazy dog. The dog was very lazy indeed.",
@@ -94,3 +116,23 @@ func BenchmarkEnglishOrder2(b *testing.B) {
 		}
 	}
 }
+
+func TestEnglishOrder2ContextCoverage(t *testing.T) {
+	model := NewEnglishOrder2Model()
+	text := "Does anyone have a spare battery for the repeater on the hill? I will meet you at the station after work."
+
+	runes := []rune(text)
+	var covered, total int
+	for i := 2; i < len(runes); i++ {
+		ctx := string(runes[i-2 : i])
+		if _, ok := model.contextModels[ctx]; ok {
+			covered++
+		}
+		total++
+	}
+
+	t.Logf("bigram contexts covered: %d/%d", covered, total)
+	if covered*10 < total*9 {
+		t.Errorf("only %d/%d bigram contexts have a table", covered, total)
+	}
+}
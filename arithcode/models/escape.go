@@ -0,0 +1,144 @@
+package models
+
+import (
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+const (
+	// DefaultEscapeLimit is the number of mispredictions after which an
+	// EscapeModel switches to its adaptive table.
+	DefaultEscapeLimit = 8
+
+	// escapeMargin is how many bits more the static table must spend on a
+	// symbol than the adaptive table for the symbol to count as mispredicted.
+	escapeMargin = 1.0
+
+	// escapeIncrement is added to a symbol's adaptive count each time it is
+	// coded.
+	escapeIncrement = 32
+
+	// escapeMaxTotal is the default count total at which the adaptive table
+	// is rescaled.
+	escapeMaxTotal = 1 << 16
+)
+
+// EscapeModel codes symbols with a static model until the static model has
+// mispredicted a number of symbols, and with an adaptive table for the rest
+// of its lifetime.
+//
+// The adaptive table starts from a flattened copy of the static distribution
+// and learns from every coded symbol, also while the static model is in use.
+// A symbol counts as mispredicted when the static model spends noticeably more
+// bits on it than the adaptive table would have. The switch therefore follows
+// from the coded symbols alone and needs no extra signaling, as long as the
+// encoder and decoder call Update after every symbol.
+type EscapeModel struct {
+	static   coder.Model
+	adaptive *FrequencyTable
+
+	limit  int
+	misses int
+}
+
+// NewEscapeModel creates a model that escapes from static to an adaptive table
+// after limit mispredictions.
+func NewEscapeModel(static coder.Model, limit int) *EscapeModel {
+	n := static.SymbolCount()
+	total := static.TotalFreq()
+
+	freqs := make([]uint64, n)
+	for i := range freqs {
+		low, high := static.Freq(i)
+		freqs[i] = 1 + (high-low)*uint64(n)/total
+	}
+	adaptive := newAdaptiveFrequencyTable(freqs, escapeMaxTotal)
+
+	return &EscapeModel{
+		static:   static,
+		adaptive: adaptive,
+		limit:    limit,
+	}
+}
+
+// WithDecay sets the count total at which the adaptive table halves its
+// counts and returns m. A lower limit lets the model follow drifting symbol
+// distributions faster. Limits below twice the symbol count are raised to
+// it; the limit must not exceed coder.MaxTotalFreq.
+//
+// The encoder and decoder must use the same limit.
+func (m *EscapeModel) WithDecay(limit uint64) *EscapeModel {
+	m.adaptive.SetMaxTotal(max(limit, 2*uint64(m.adaptive.SymbolCount())))
+	return m
+}
+
+// Escaped reports whether the model has switched to the adaptive table.
+func (m *EscapeModel) Escaped() bool {
+	return m.misses >= m.limit
+}
+
+// Update records a coded symbol. It must be called after every symbol coded
+// with the model, by both the encoder and the decoder.
+func (m *EscapeModel) Update(symbol int) {
+	if !m.Escaped() && m.static.Cost(symbol) > m.adaptive.Cost(symbol)+escapeMargin {
+		m.misses++
+	}
+	m.adaptive.Add(symbol, escapeIncrement)
+}
+
+// current returns the model used for the next symbol.
+func (m *EscapeModel) current() coder.Model {
+	if m.Escaped() {
+		return m.adaptive
+	}
+	return m.static
+}
+
+// SymbolCount returns the number of symbols.
+func (m *EscapeModel) SymbolCount() int {
+	return m.static.SymbolCount()
+}
+
+// Freq returns the cumulative frequency range for the symbol.
+func (m *EscapeModel) Freq(symbol int) (low, high uint64) {
+	return m.current().Freq(symbol)
+}
+
+// TotalFreq returns the total frequency of the current model.
+func (m *EscapeModel) TotalFreq() uint64 {
+	return m.current().TotalFreq()
+}
+
+// Find returns the symbol for the cumulative frequency.
+func (m *EscapeModel) Find(cumFreq uint64) int {
+	return m.current().Find(cumFreq)
+}
+
+// Cost returns the number of bits needed to encode the symbol.
+func (m *EscapeModel) Cost(symbol int) float64 {
+	return m.current().Cost(symbol)
+}
+
+// EncodeEscaped encodes the symbol and, when model is an EscapeModel, records
+// it with Update.
+func EncodeEscaped(enc *coder.Encoder, symbol int, model coder.Model) error {
+	if err := enc.Encode(symbol, model); err != nil {
+		return err
+	}
+	if em, ok := model.(*EscapeModel); ok {
+		em.Update(symbol)
+	}
+	return nil
+}
+
+// DecodeEscaped decodes a symbol and, when model is an EscapeModel, records
+// it with Update.
+func DecodeEscaped(dec *coder.Decoder, model coder.Model) (int, error) {
+	symbol, err := dec.Decode(model)
+	if err != nil {
+		return 0, err
+	}
+	if em, ok := model.(*EscapeModel); ok {
+		em.Update(symbol)
+	}
+	return symbol, nil
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// escapeSymbols codes symbols with a fresh escape model around static and
+// returns the encoded size and whether the model escaped.
+func escapeSymbols(t *testing.T, static func() coder.Model, symbols []int) (int, bool) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc := coder.NewEncoder(&buf)
+	encModel := NewEscapeModel(static(), DefaultEscapeLimit)
+	for _, symbol := range symbols {
+		if err := EncodeEscaped(enc, symbol, encModel); err != nil {
+			t.Fatalf("EncodeEscaped failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	size := buf.Len()
+
+	dec, err := coder.NewDecoder(&buf)
+	if err != nil {
+		t.Fatalf("NewDecoder failed: %v", err)
+	}
+	decModel := NewEscapeModel(static(), DefaultEscapeLimit)
+	for i, want := range symbols {
+		got, err := DecodeEscaped(dec, decModel)
+		if err != nil {
+			t.Fatalf("DecodeEscaped failed: %v", err)
+		}
+		if got != want {
+			t.Fatalf("symbol %d: got %d, want %d", i, got, want)
+		}
+	}
+	if encModel.Escaped() != decModel.Escaped() {
+		t.Fatalf("encoder escaped %v, decoder escaped %v", encModel.Escaped(), decModel.Escaped())
+	}
+
+	return size, encModel.Escaped()
+}
+
+func TestEscapeModelMatchingDistribution(t *testing.T) {
+	static := func() coder.Model { return NewFrequencyTable([]uint64{900, 100}) }
+
+	var symbols []int
+	for i := 0; i < 200; i++ {
+		symbols = append(symbols, boolToSymbol(i%10 == 0))
+	}
+
+	_, escaped := escapeSymbols(t, static, symbols)
+	if escaped {
+		t.Errorf("escaped although the static model predicts the symbols well")
+	}
+}
+
+func TestEscapeModelMismatchedDistribution(t *testing.T) {
+	static := func() coder.Model { return NewFrequencyTable([]uint64{950, 50}) }
+
+	// The deployment mostly uses the value the static table considers rare
+	var symbols []int
+	for i := 0; i < 200; i++ {
+		symbols = append(symbols, boolToSymbol(i%20 != 0))
+	}
+
+	size, escaped := escapeSymbols(t, static, symbols)
+	if !escaped {
+		t.Fatalf("did not escape from a mispredicting static model")
+	}
+
+	staticBits := coder.EstimateBits(symbols, static())
+	t.Logf("static: %.0f bytes, escape: %d bytes", staticBits/8, size)
+	if float64(size) >= staticBits/8/2 {
+		t.Errorf("escape model (%d bytes) should be much smaller than static (%.0f bytes)", size, staticBits/8)
+	}
+}
+
+func boolToSymbol(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestEscapeModelDecay(t *testing.T) {
+	// drift returns the cost of the symbol the traffic drifted to
+	drift := func(decay uint64) float64 {
+		model := NewEscapeModel(NewFrequencyTable([]uint64{1, 1, 1, 1}), 0)
+		if decay > 0 {
+			model.WithDecay(decay)
+		}
+		for i := 0; i < 5000; i++ {
+			model.Update(0)
+		}
+		for i := 0; i < 50; i++ {
+			model.Update(3)
+		}
+		return model.Cost(3)
+	}
+
+	slow, fast := drift(0), drift(256)
+	t.Logf("cost after drift: default %.2f bits, decay 256 %.2f bits", slow, fast)
+	if fast >= slow {
+		t.Errorf("decay should adapt faster: %.2f bits >= %.2f bits", fast, slow)
+	}
+	if fast > 1 {
+		t.Errorf("drifted symbol should cost at most 1 bit with decay, got %.2f", fast)
+	}
+}
@@ -0,0 +1,97 @@
+package models
+
+import "github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+
+// Smoothing selects how FitModel estimates the probability of symbols from
+// their counts. Every smoothing gives symbols with a zero count a non-zero
+// frequency: a symbol that did not occur in the training data can still
+// occur in the data being coded, and a zero frequency would make it
+// impossible to encode.
+type Smoothing int
+
+const (
+	// Laplace adds one to every count. It suits histograms where most
+	// symbols occur, such as flags and small enums.
+	Laplace Smoothing = iota
+	// KneserNey subtracts a discount from the count of every observed
+	// symbol and spreads the discounted mass over all symbols, in proportion
+	// to the number of distinct symbols observed. It keeps much less
+	// probability for unseen symbols than Laplace when few of many symbols
+	// occur, such as the bytes of numeric fields.
+	KneserNey
+)
+
+// kneserNeyDiscount is the discount used when the counts do not allow
+// estimating one.
+const kneserNeyDiscount = 0.75
+
+// FitModel creates a frequency table from a histogram of symbol counts.
+// An empty histogram, or one without counts, gives a uniform table.
+func FitModel(hist []uint64, smoothing Smoothing) *FrequencyTable {
+	if smoothing == KneserNey {
+		if probs := kneserNeyProbabilities(hist); probs != nil {
+			return trainedTable(probs)
+		}
+	}
+
+	freqs := make([]uint64, len(hist))
+	for i, count := range hist {
+		freqs[i] = min(count, ^uint64(0)-1) + 1
+	}
+	return NewFrequencyTable(freqs)
+}
+
+// kneserNeyProbabilities returns the interpolated absolute discounting
+// estimate of the symbol probabilities, or nil for a histogram without
+// counts.
+func kneserNeyProbabilities(hist []uint64) []float64 {
+	var total float64
+	var distinct, once, twice int
+	for _, count := range hist {
+		total += float64(count)
+		switch {
+		case count == 0:
+			continue
+		case count == 1:
+			once++
+		case count == 2:
+			twice++
+		}
+		distinct++
+	}
+	if total == 0 {
+		return nil
+	}
+
+	// Estimate the discount from the symbols seen once and twice
+	discount := kneserNeyDiscount
+	if once > 0 && twice > 0 {
+		discount = float64(once) / float64(once+2*twice)
+	}
+
+	// The mass freed by discounting is spread uniformly over all symbols
+	uniform := discount * float64(distinct) / total / float64(len(hist))
+	probs := make([]float64, len(hist))
+	for i, count := range hist {
+		probs[i] = max(float64(count)-discount, 0)/total + uniform
+	}
+	return probs
+}
+
+// BlendModels returns a table whose probabilities are the weighted mean of
+// the probabilities of a and b: weight of a and 1-weight of b. The models
+// must have the same number of symbols and weight must be in [0, 1].
+func BlendModels(a, b coder.Model, weight float64) *FrequencyTable {
+	if a.SymbolCount() != b.SymbolCount() {
+		panic("models: blending models with different symbol counts")
+	}
+
+	totalA, totalB := float64(a.TotalFreq()), float64(b.TotalFreq())
+	probs := make([]float64, a.SymbolCount())
+	for symbol := range probs {
+		lowA, highA := a.Freq(symbol)
+		lowB, highB := b.Freq(symbol)
+		probs[symbol] = weight*float64(highA-lowA)/totalA + (1-weight)*float64(highB-lowB)/totalB
+	}
+	return trainedTable(probs)
+}
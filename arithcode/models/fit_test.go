@@ -0,0 +1,93 @@
+package models
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+func TestFitModel(t *testing.T) {
+	// A sparse histogram: few of many symbols occur
+	hist := make([]uint64, 256)
+	hist[0], hist[1], hist[2], hist[7] = 1000, 300, 2, 1
+
+	for _, smoothing := range []Smoothing{Laplace, KneserNey} {
+		model := FitModel(hist, smoothing)
+		if err := ValidateFrequencies(model.Frequencies()); err != nil {
+			t.Fatalf("smoothing %d: %v", smoothing, err)
+		}
+
+		// Every symbol, seen or not, must roundtrip
+		symbols := []int{0, 1, 255, 2, 7, 100, 0}
+		var buf bytes.Buffer
+		enc := coder.NewEncoder(&buf)
+		for _, symbol := range symbols {
+			if err := enc.Encode(symbol, model); err != nil {
+				t.Fatalf("smoothing %d: Encode failed: %v", smoothing, err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		dec, err := coder.NewDecoder(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range symbols {
+			got, err := dec.Decode(model)
+			if err != nil {
+				t.Fatalf("smoothing %d: Decode failed: %v", smoothing, err)
+			}
+			if got != want {
+				t.Fatalf("smoothing %d: symbol %d: got %d, want %d", smoothing, i, got, want)
+			}
+		}
+	}
+
+	// Kneser-Ney keeps less probability for the unseen symbols
+	laplace, kneserNey := FitModel(hist, Laplace), FitModel(hist, KneserNey)
+	if laplace.Cost(0) <= kneserNey.Cost(0) {
+		t.Errorf("most frequent symbol costs %.3f bits with Laplace, %.3f with Kneser-Ney", laplace.Cost(0), kneserNey.Cost(0))
+	}
+}
+
+func TestFitModelLaplace(t *testing.T) {
+	got := FitModel([]uint64{0, 5, 1}, Laplace).Frequencies()
+	if want := []uint64{1, 6, 2}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFitModelEmpty(t *testing.T) {
+	for _, smoothing := range []Smoothing{Laplace, KneserNey} {
+		freqs := FitModel(make([]uint64, 4), smoothing).Frequencies()
+		if !slices.Equal(freqs, []uint64{1, 1, 1, 1}) {
+			t.Errorf("smoothing %d: got %v, want a uniform table", smoothing, freqs)
+		}
+	}
+}
+
+func TestBlendModels(t *testing.T) {
+	trained := NewFrequencyTable([]uint64{97, 1, 1, 1})
+	static := NewUniformModel(4)
+
+	previous := static.Cost(0)
+	for _, weight := range []float64{0, 0.5, 0.9, 1} {
+		blended := BlendModels(trained, static, weight)
+		if err := ValidateFrequencies(blended.Frequencies()); err != nil {
+			t.Fatalf("weight %v: %v", weight, err)
+		}
+		// More weight on the trained model makes its common symbol cheaper
+		cost := blended.Cost(0)
+		if cost > previous+0.01 {
+			t.Errorf("weight %v: symbol 0 costs %.3f bits, %.3f with less weight", weight, cost, previous)
+		}
+		previous = cost
+	}
+
+	if got, want := BlendModels(trained, static, 0).Cost(3), static.Cost(3); got > want+0.01 {
+		t.Errorf("weight 0: symbol 3 costs %.3f bits, want %.3f", got, want)
+	}
+}
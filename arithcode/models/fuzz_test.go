@@ -0,0 +1,136 @@
+package models
+
+import (
+	"bytes"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+func FuzzFrequencyTableRoundtrip(f *testing.F) {
+	f.Add([]byte{10, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, []byte{0, 9, 5, 3, 7, 1}, false)
+	f.Add([]byte{255, 255, 1}, []byte{0, 0, 0, 0, 1, 2}, true)
+	f.Add([]byte{}, []byte{}, true)
+
+	// Frequencies are given as big-endian 16-bit values, the symbols as
+	// 16-bit values modulo the alphabet size. With adapt set the table is
+	// updated after every symbol, exercising the Fenwick tree and rescaling.
+	f.Fuzz(func(t *testing.T, freqData, symbolData []byte, adapt bool) {
+		freqs := make([]uint64, 1+len(freqData)/2)
+		for i := range freqs {
+			freqs[i] = 1
+			if 2*i+1 < len(freqData) {
+				freqs[i] += uint64(freqData[2*i])<<8 | uint64(freqData[2*i+1])
+			}
+		}
+
+		symbols := make([]int, len(symbolData)/2)
+		for i := range symbols {
+			symbols[i] = (int(symbolData[2*i])<<8 | int(symbolData[2*i+1])) % len(freqs)
+		}
+
+		var buf bytes.Buffer
+		enc := coder.NewEncoder(&buf)
+		model := NewFrequencyTable(freqs)
+		if adapt {
+			model.SetMaxTotal(max(1<<16, 2*uint64(len(freqs))))
+		}
+		for _, symbol := range symbols {
+			if err := enc.Encode(symbol, model); err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			if adapt {
+				model.Add(symbol, 32)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		dec, err := coder.NewDecoder(&buf)
+		if err != nil {
+			t.Fatalf("coder.NewDecoder failed: %v", err)
+		}
+		model = NewFrequencyTable(freqs)
+		if adapt {
+			model.SetMaxTotal(max(1<<16, 2*uint64(len(freqs))))
+		}
+		for i, want := range symbols {
+			got, err := dec.Decode(model)
+			if err != nil {
+				t.Fatalf("Decode at position %d failed: %v", i, err)
+			}
+			if got != want {
+				t.Fatalf("position %d: expected %d, got %d", i, want, got)
+			}
+			if adapt {
+				model.Add(got, 32)
+			}
+		}
+	})
+}
+
+func FuzzEnglishString(f *testing.F) {
+	f.Add("")
+	f.Add("hello world")
+	f.Add("Meet at the trail head at 10:30, bring water!")
+	f.Add("Unicode: café, naïve, 日本語 👍")
+	f.Add("\x00\x7f ￿")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		// Invalid UTF-8 is replaced with U+FFFD when splitting into runes
+		if !utf8.ValidString(s) {
+			t.Skip("invalid UTF-8")
+		}
+
+		var buf bytes.Buffer
+		if err := EncodeString(s, &buf); err != nil {
+			t.Fatalf("EncodeString failed: %v", err)
+		}
+		got, err := DecodeString(&buf)
+		if err != nil {
+			t.Fatalf("DecodeString failed: %v", err)
+		}
+		if got != s {
+			t.Fatalf("roundtrip mismatch: expected %q, got %q", s, got)
+		}
+
+		// The EOS variant must handle the same strings
+		buf.Reset()
+		model := SharedEnglishModelEOS()
+		enc := coder.NewEncoder(&buf)
+		if err := EncodeStringEOS(enc, model, s); err != nil {
+			t.Fatalf("EncodeStringEOS failed: %v", err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		dec, err := coder.NewDecoder(&buf)
+		if err != nil {
+			t.Fatalf("coder.NewDecoder failed: %v", err)
+		}
+		got, err = DecodeStringEOS(dec, model)
+		if err != nil {
+			t.Fatalf("DecodeStringEOS failed: %v", err)
+		}
+		if got != s {
+			t.Fatalf("EOS roundtrip mismatch: expected %q, got %q", s, got)
+		}
+	})
+}
+
+func FuzzDecodeString(f *testing.F) {
+	var buf bytes.Buffer
+	if err := EncodeString("hello world", &buf); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes())
+	f.Add([]byte{})
+	f.Add([]byte{0xFF, 0xFF, 0xFF, 0xFF})
+
+	// Arbitrary input must be rejected with an error, not a panic.
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = DecodeString(bytes.NewReader(data))
+	})
+}
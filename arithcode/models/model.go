@@ -0,0 +1,426 @@
+// Package models implements probability models for the arithmetic coder
+// in package coder: fixed and adaptive frequency tables, and string models
+// for English and trained text.
+//
+// All models satisfy the invariants documented on coder.Model. Constructors
+// panic on tables that violate them; use NewFrequencyTableChecked for tables
+// built from untrusted input.
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"slices"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// UniformModel implements a model where all symbols have equal probability.
+type UniformModel struct {
+	numSymbols int
+}
+
+// NewUniformModel creates a uniform probability model with the given number of symbols.
+func NewUniformModel(numSymbols int) *UniformModel {
+	if numSymbols <= 0 {
+		panic("numSymbols must be positive")
+	}
+	if uint64(numSymbols) > coder.MaxTotalFreq {
+		panic("numSymbols exceeds coder precision")
+	}
+	return &UniformModel{numSymbols: numSymbols}
+}
+
+// SharedUniformModel returns a uniform model with the given number of
+// symbols. Models of up to 256 symbols come from a fixed table shared by all
+// callers, so that they cost no allocation; uniform models have no state.
+func SharedUniformModel(numSymbols int) *UniformModel {
+	if numSymbols > 0 && numSymbols <= len(sharedUniformModels) {
+		return &sharedUniformModels[numSymbols-1]
+	}
+	return NewUniformModel(numSymbols)
+}
+
+// sharedUniformModels holds the uniform model of i+1 symbols at index i.
+var sharedUniformModels = func() (table [256]UniformModel) {
+	for i := range table {
+		table[i].numSymbols = i + 1
+	}
+	return table
+}()
+
+func (m *UniformModel) SymbolCount() int {
+	return m.numSymbols
+}
+
+func (m *UniformModel) Freq(symbol int) (low, high uint64) {
+	if symbol < 0 || symbol >= m.numSymbols {
+		panic("symbol out of range")
+	}
+	return uint64(symbol), uint64(symbol + 1)
+}
+
+func (m *UniformModel) TotalFreq() uint64 {
+	return uint64(m.numSymbols)
+}
+
+func (m *UniformModel) Find(cumFreq uint64) int {
+	if cumFreq >= uint64(m.numSymbols) {
+		panic("cumFreq out of range")
+	}
+	return int(cumFreq)
+}
+
+func (m *UniformModel) Cost(symbol int) float64 {
+	return coder.SymbolCost(m, symbol)
+}
+
+// FrequencyTable implements a model with custom symbol frequencies.
+//
+// The alphabet is not limited to bytes: a table may have up to
+// coder.MaxTotalFreq/2 symbols, so field numbers, enum indices and word
+// dictionaries can be coded directly as single symbols.
+//
+// Cumulative frequencies are kept in a Fenwick (binary indexed) tree, so both
+// cumulative range queries and frequency updates take O(log n) time. This makes
+// the table usable as the backing store for adaptive models. Until the first
+// Add, a table also keeps a precomputed cumulative array, so that static
+// tables look up ranges directly and find symbols with a binary search.
+//
+// The total frequency never exceeds the table's limit (coder.MaxTotalFreq by
+// default, see SetMaxTotal): larger tables are scaled down on construction
+// and counts are halved when Add would grow past it.
+type FrequencyTable struct {
+	tree     []uint64 // Fenwick tree, 1-indexed: tree[i] covers symbols (i-lowbit(i), i]
+	cum      []uint64 // Cumulative frequencies, cum[i] is the low bound of symbol i; nil after Add
+	total    uint64   // Total of all frequencies
+	maxTotal uint64   // Total at which frequencies are halved
+	step     int      // Largest power of two <= SymbolCount(), used by Find
+}
+
+// ValidateFrequencies checks that frequencies form a valid model:
+// the table is non-empty, every frequency is positive and the total
+// does not exceed coder.MaxTotalFreq.
+func ValidateFrequencies(frequencies []uint64) error {
+	if len(frequencies) == 0 {
+		return errors.New("frequencies must not be empty")
+	}
+
+	var total uint64
+	for i, freq := range frequencies {
+		if freq == 0 {
+			return fmt.Errorf("frequency of symbol %d must be positive", i)
+		}
+		if freq > coder.MaxTotalFreq-total {
+			return fmt.Errorf("total frequency exceeds coder precision %d", uint64(coder.MaxTotalFreq))
+		}
+		total += freq
+	}
+	return nil
+}
+
+// NewFrequencyTableChecked creates a model from the given symbol frequencies,
+// returning an error when they violate the coder invariants.
+func NewFrequencyTableChecked(frequencies []uint64) (*FrequencyTable, error) {
+	if err := ValidateFrequencies(frequencies); err != nil {
+		return nil, err
+	}
+	return newStaticFrequencyTable(frequencies), nil
+}
+
+// NewFrequencyTable creates a model from the given symbol frequencies.
+// The frequencies slice defines the frequency (probability weight) of each symbol.
+//
+// Frequencies whose total exceeds coder.MaxTotalFreq are scaled down
+// proportionally, keeping every symbol codable. It panics when the table is
+// empty or contains a zero frequency.
+func NewFrequencyTable(frequencies []uint64) *FrequencyTable {
+	if len(frequencies) == 0 {
+		panic("frequencies must not be empty")
+	}
+	if uint64(len(frequencies)) > coder.MaxTotalFreq/2 {
+		panic("too many symbols for coder precision")
+	}
+	for _, freq := range frequencies {
+		if freq == 0 {
+			panic("frequency must be positive")
+		}
+	}
+	return newStaticFrequencyTable(normalizeFrequencies(frequencies, coder.MaxTotalFreq))
+}
+
+// newAdaptiveFrequencyTable creates a table that is updated with Add and
+// halves its frequencies at maxTotal. It skips the cumulative array, which
+// the first Add would discard.
+func newAdaptiveFrequencyTable(frequencies []uint64, maxTotal uint64) *FrequencyTable {
+	ft := newFrequencyTable(normalizeFrequencies(frequencies, coder.MaxTotalFreq))
+	ft.SetMaxTotal(maxTotal)
+	return ft
+}
+
+// normalizeFrequencies scales frequencies down so that their total is at most
+// maxTotal, keeping each frequency at least 1. Frequencies that already fit
+// are returned unchanged.
+func normalizeFrequencies(frequencies []uint64, maxTotal uint64) []uint64 {
+	total, overflow := sumFrequencies(frequencies)
+	if !overflow && total <= maxTotal {
+		return frequencies
+	}
+
+	scaled := slices.Clone(frequencies)
+	for overflow {
+		// The total doesn't fit in 64 bits; halve until it does
+		for i, freq := range scaled {
+			scaled[i] = (freq + 1) / 2
+		}
+		total, overflow = sumFrequencies(scaled)
+	}
+
+	// Distribute budget proportionally; the +1 keeps symbols non-zero, so
+	// the result totals at most budget + n = maxTotal.
+	budget := maxTotal - uint64(len(scaled))
+	for i, freq := range scaled {
+		hi, lo := bits.Mul64(freq, budget)
+		q, _ := bits.Div64(hi, lo, total)
+		scaled[i] = q + 1
+	}
+	return scaled
+}
+
+// sumFrequencies returns the total of frequencies and whether it overflowed.
+func sumFrequencies(frequencies []uint64) (total uint64, overflow bool) {
+	for _, freq := range frequencies {
+		var carry uint64
+		total, carry = bits.Add64(total, freq, 0)
+		if carry != 0 {
+			return 0, true
+		}
+	}
+	return total, false
+}
+
+// newStaticFrequencyTable creates a table with a cumulative array.
+func newStaticFrequencyTable(frequencies []uint64) *FrequencyTable {
+	ft := newFrequencyTable(frequencies)
+	ft.cum = cumulativeFrequencies(frequencies)
+	return ft
+}
+
+// cumulativeFrequencies returns the low bound of every symbol, followed by
+// the total.
+func cumulativeFrequencies(frequencies []uint64) []uint64 {
+	cum := make([]uint64, len(frequencies)+1)
+	for i, freq := range frequencies {
+		cum[i+1] = cum[i] + freq
+	}
+	return cum
+}
+
+func newFrequencyTable(frequencies []uint64) *FrequencyTable {
+	n := len(frequencies)
+	tree := make([]uint64, n+1)
+	total := buildTree(tree, frequencies)
+
+	step := 1
+	for step*2 <= n {
+		step *= 2
+	}
+
+	return &FrequencyTable{
+		tree:     tree,
+		total:    total,
+		maxTotal: coder.MaxTotalFreq,
+		step:     step,
+	}
+}
+
+// buildTree fills the zeroed Fenwick tree with frequencies and returns their
+// total.
+func buildTree(tree, frequencies []uint64) uint64 {
+	n := len(frequencies)
+	var total uint64
+	for i, freq := range frequencies {
+		total += freq
+		tree[i+1] += freq
+
+		// Propagate the partial sum to the parent node
+		if parent := (i + 1) + ((i + 1) & -(i + 1)); parent <= n {
+			tree[parent] += tree[i+1]
+		}
+	}
+	return total
+}
+
+// resetAdaptive restores an adaptive table to frequencies without
+// allocating. The frequencies must have the table's symbol count and a total
+// within coder.MaxTotalFreq.
+func (ft *FrequencyTable) resetAdaptive(frequencies []uint64) {
+	clear(ft.tree)
+	ft.total = buildTree(ft.tree, frequencies)
+	ft.cum = nil
+}
+
+func (ft *FrequencyTable) SymbolCount() int {
+	return len(ft.tree) - 1
+}
+
+// prefix returns the sum of frequencies of symbols [0, n).
+func (ft *FrequencyTable) prefix(n int) uint64 {
+	var sum uint64
+	for i := n; i > 0; i -= i & -i {
+		sum += ft.tree[i]
+	}
+	return sum
+}
+
+func (ft *FrequencyTable) Freq(symbol int) (low, high uint64) {
+	if symbol < 0 || symbol >= ft.SymbolCount() {
+		panic("symbol out of range")
+	}
+	if ft.cum != nil {
+		return ft.cum[symbol], ft.cum[symbol+1]
+	}
+
+	low = ft.prefix(symbol)
+
+	// The frequency of a single symbol is the tree node minus the
+	// sums of the children that the node covers.
+	freq := ft.tree[symbol+1]
+	parent := (symbol + 1) - ((symbol + 1) & -(symbol + 1))
+	for i := symbol; i > parent; i -= i & -i {
+		freq -= ft.tree[i]
+	}
+
+	return low, low + freq
+}
+
+func (ft *FrequencyTable) TotalFreq() uint64 {
+	return ft.total
+}
+
+func (ft *FrequencyTable) Find(cumFreq uint64) int {
+	if cumFreq >= ft.total {
+		panic("cumFreq out of range")
+	}
+
+	if ft.cum != nil {
+		// Binary search for the last symbol whose low bound is <= cumFreq
+		lo, hi := 0, ft.SymbolCount()-1
+		for lo < hi {
+			mid := int(uint(lo+hi+1) >> 1)
+			if ft.cum[mid] <= cumFreq {
+				lo = mid
+			} else {
+				hi = mid - 1
+			}
+		}
+		return lo
+	}
+
+	// Descend the Fenwick tree to find the last symbol whose
+	// cumulative low bound is <= cumFreq.
+	n := ft.SymbolCount()
+	pos := 0
+	for step := ft.step; step > 0; step >>= 1 {
+		if next := pos + step; next <= n && ft.tree[next] <= cumFreq {
+			pos = next
+			cumFreq -= ft.tree[next]
+		}
+	}
+	return pos
+}
+
+func (ft *FrequencyTable) Cost(symbol int) float64 {
+	return coder.SymbolCost(ft, symbol)
+}
+
+// Encode writes symbol to enc. It is equivalent to enc.Encode(symbol, ft),
+// but calls the table directly instead of through the Model interface,
+// which is noticeably faster in loops that code many symbols.
+func (ft *FrequencyTable) Encode(enc *coder.Encoder, symbol int) error {
+	if symbol < 0 || symbol >= ft.SymbolCount() {
+		return fmt.Errorf("%w: %d of %d", coder.ErrSymbolRange, symbol, ft.SymbolCount())
+	}
+	low, high := ft.Freq(symbol)
+	return enc.EncodeRange(low, high, ft.total)
+}
+
+// Decode reads a symbol written by Encode or by coder.Encoder.Encode with
+// the table. It is equivalent to dec.Decode(ft).
+func (ft *FrequencyTable) Decode(dec *coder.Decoder) (int, error) {
+	target, err := dec.DecodeTarget(ft.total)
+	if err != nil {
+		return 0, err
+	}
+	symbol := ft.Find(target)
+	low, high := ft.Freq(symbol)
+	if err := dec.DecodeRange(low, high, ft.total); err != nil {
+		return 0, err
+	}
+	return symbol, nil
+}
+
+// Add increases the frequency of symbol by delta in O(log n) time.
+//
+// When the total grows past the table's limit, all frequencies are halved
+// (keeping them non-zero), so adaptive statistics stay within coder
+// precision and favor recent symbols.
+func (ft *FrequencyTable) Add(symbol int, delta uint64) {
+	if symbol < 0 || symbol >= ft.SymbolCount() {
+		panic("symbol out of range")
+	}
+	if delta > ft.maxTotal {
+		delta = ft.maxTotal
+	}
+
+	n := ft.SymbolCount()
+	for i := symbol + 1; i <= n; i += i & -i {
+		ft.tree[i] += delta
+	}
+	ft.total += delta
+
+	for ft.total > ft.maxTotal {
+		ft.Rescale()
+	}
+	// Adaptive tables change too often to keep the cumulative array
+	ft.cum = nil
+}
+
+// SetMaxTotal sets the total at which Add halves the frequencies.
+// Adaptive models use a lower limit to adapt faster. The limit must be
+// within [2*SymbolCount(), coder.MaxTotalFreq].
+func (ft *FrequencyTable) SetMaxTotal(maxTotal uint64) {
+	if maxTotal < 2*uint64(ft.SymbolCount()) || maxTotal > coder.MaxTotalFreq {
+		panic("maxTotal out of range")
+	}
+	ft.maxTotal = maxTotal
+	for ft.total > ft.maxTotal {
+		ft.Rescale()
+	}
+}
+
+// Frequencies returns the frequency of each symbol.
+func (ft *FrequencyTable) Frequencies() []uint64 {
+	freqs := make([]uint64, ft.SymbolCount())
+	for i := range freqs {
+		low, high := ft.Freq(i)
+		freqs[i] = high - low
+	}
+	return freqs
+}
+
+// Rescale halves all frequencies, rounding up so that none becomes zero.
+func (ft *FrequencyTable) Rescale() {
+	freqs := ft.Frequencies()
+	for i, freq := range freqs {
+		freqs[i] = (freq + 1) / 2
+	}
+
+	maxTotal, static := ft.maxTotal, ft.cum != nil
+	*ft = *newFrequencyTable(freqs)
+	ft.maxTotal = maxTotal
+	if static {
+		ft.cum = cumulativeFrequencies(freqs)
+	}
+}
@@ -0,0 +1,661 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+func TestUniformModel(t *testing.T) {
+	model := NewUniformModel(256)
+
+	if model.SymbolCount() != 256 {
+		t.Errorf("Expected 256 symbols, got %d", model.SymbolCount())
+	}
+
+	if model.TotalFreq() != 256 {
+		t.Errorf("Expected total freq 256, got %d", model.TotalFreq())
+	}
+
+	for i := 0; i < 256; i++ {
+		low, high := model.Freq(i)
+		if low != uint64(i) || high != uint64(i+1) {
+			t.Errorf("Symbol %d: expected [%d, %d), got [%d, %d)", i, i, i+1, low, high)
+		}
+
+		found := model.Find(uint64(i))
+		if found != i {
+			t.Errorf("Find(%d) = %d, expected %d", i, found, i)
+		}
+	}
+}
+
+func TestFrequencyTable(t *testing.T) {
+	freqs := []uint64{10, 20, 30, 40}
+	model := NewFrequencyTable(freqs)
+
+	if model.SymbolCount() != 4 {
+		t.Errorf("Expected 4 symbols, got %d", model.SymbolCount())
+	}
+
+	if model.TotalFreq() != 100 {
+		t.Errorf("Expected total freq 100, got %d", model.TotalFreq())
+	}
+
+	expected := [][2]uint64{
+		{0, 10},
+		{10, 30},
+		{30, 60},
+		{60, 100},
+	}
+
+	for i := 0; i < 4; i++ {
+		low, high := model.Freq(i)
+		if low != expected[i][0] || high != expected[i][1] {
+			t.Errorf("Symbol %d: expected [%d, %d), got [%d, %d)",
+				i, expected[i][0], expected[i][1], low, high)
+		}
+	}
+
+	// Test Find
+	tests := []struct {
+		cumFreq  uint64
+		expected int
+	}{
+		{0, 0},
+		{5, 0},
+		{9, 0},
+		{10, 1},
+		{25, 1},
+		{29, 1},
+		{30, 2},
+		{50, 2},
+		{59, 2},
+		{60, 3},
+		{99, 3},
+	}
+
+	for _, tt := range tests {
+		symbol := model.Find(tt.cumFreq)
+		if symbol != tt.expected {
+			t.Errorf("Find(%d) = %d, expected %d", tt.cumFreq, symbol, tt.expected)
+		}
+	}
+}
+
+func TestFrequencyTableAdd(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+
+	freqs := make([]uint64, 37)
+	for i := range freqs {
+		freqs[i] = 1 + uint64(rng.Intn(20))
+	}
+	model := NewFrequencyTable(freqs)
+
+	for step := 0; step < 200; step++ {
+		symbol := rng.Intn(len(freqs))
+		delta := uint64(1 + rng.Intn(10))
+		freqs[symbol] += delta
+		model.Add(symbol, delta)
+
+		var cum uint64
+		for i, freq := range freqs {
+			low, high := model.Freq(i)
+			if low != cum || high != cum+freq {
+				t.Fatalf("Step %d, symbol %d: expected [%d, %d), got [%d, %d)", step, i, cum, cum+freq, low, high)
+			}
+			if found := model.Find(low); found != i {
+				t.Fatalf("Step %d: Find(%d) = %d, expected %d", step, low, found, i)
+			}
+			if found := model.Find(high - 1); found != i {
+				t.Fatalf("Step %d: Find(%d) = %d, expected %d", step, high-1, found, i)
+			}
+			cum += freq
+		}
+		if model.TotalFreq() != cum {
+			t.Fatalf("Step %d: expected total %d, got %d", step, cum, model.TotalFreq())
+		}
+	}
+}
+
+func TestNewFrequencyTableChecked(t *testing.T) {
+	invalid := map[string][]uint64{
+		"empty":    {},
+		"zero":     {1, 0, 1},
+		"overflow": {coder.MaxTotalFreq, 1},
+		"wrap":     {^uint64(0), 2},
+	}
+	for name, freqs := range invalid {
+		if _, err := NewFrequencyTableChecked(freqs); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	model, err := NewFrequencyTableChecked([]uint64{1, coder.MaxTotalFreq - 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if model.TotalFreq() != coder.MaxTotalFreq {
+		t.Errorf("Expected total %d, got %d", uint64(coder.MaxTotalFreq), model.TotalFreq())
+	}
+}
+
+func TestFrequencyTableNormalize(t *testing.T) {
+	tests := map[string][]uint64{
+		"large":    {1, 1 << 40, 1},
+		"overflow": {^uint64(0), ^uint64(0), 1, 7},
+		"many":     slices.Repeat([]uint64{coder.MaxTotalFreq / 4}, 1000),
+	}
+
+	for name, freqs := range tests {
+		model := NewFrequencyTable(freqs)
+		if model.TotalFreq() > coder.MaxTotalFreq {
+			t.Errorf("%s: total %d exceeds coder precision", name, model.TotalFreq())
+		}
+		for symbol, freq := range model.Frequencies() {
+			if freq == 0 {
+				t.Errorf("%s: symbol %d has zero frequency", name, symbol)
+			}
+		}
+		testRoundtripAllSymbols(t, name, model)
+	}
+
+	// Tables within precision are not modified
+	model := NewFrequencyTable([]uint64{3, 5, 7})
+	if freqs := model.Frequencies(); !slices.Equal(freqs, []uint64{3, 5, 7}) {
+		t.Errorf("Expected unchanged frequencies, got %v", freqs)
+	}
+}
+
+func TestFrequencyTableAddRescale(t *testing.T) {
+	model := NewFrequencyTable([]uint64{1, 1, 1, 1})
+	for i := 0; i < 100; i++ {
+		model.Add(i%3, coder.MaxTotalFreq/3)
+		if model.TotalFreq() > coder.MaxTotalFreq {
+			t.Fatalf("Add %d: total %d exceeds coder precision", i, model.TotalFreq())
+		}
+	}
+	if model.Frequencies()[3] == 0 {
+		t.Error("Unused symbol lost its frequency")
+	}
+	testRoundtripAllSymbols(t, "after rescale", model)
+
+	adaptive := NewFrequencyTable([]uint64{1, 1})
+	adaptive.SetMaxTotal(64)
+	for i := 0; i < 100; i++ {
+		adaptive.Add(0, 8)
+		if adaptive.TotalFreq() > 64 {
+			t.Fatalf("Add %d: total %d exceeds limit", i, adaptive.TotalFreq())
+		}
+	}
+	if freqs := adaptive.Frequencies(); freqs[1] != 1 {
+		t.Errorf("Expected symbol 1 to keep frequency 1, got %v", freqs)
+	}
+}
+
+// testRoundtripAllSymbols encodes every symbol of model and checks that
+// decoding returns them.
+func testRoundtripAllSymbols(t *testing.T, name string, model coder.Model) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc := coder.NewEncoder(&buf)
+	for symbol := 0; symbol < model.SymbolCount(); symbol++ {
+		if err := enc.Encode(symbol, model); err != nil {
+			t.Fatalf("%s: Encode failed: %v", name, err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("%s: Close failed: %v", name, err)
+	}
+
+	dec, err := coder.NewDecoder(&buf)
+	if err != nil {
+		t.Fatalf("%s: NewDecoder failed: %v", name, err)
+	}
+	for want := 0; want < model.SymbolCount(); want++ {
+		got, err := dec.Decode(model)
+		if err != nil {
+			t.Fatalf("%s: Decode failed: %v", name, err)
+		}
+		if got != want {
+			t.Fatalf("%s: expected symbol %d, got %d", name, want, got)
+		}
+	}
+}
+
+func TestRoundtripUniform(t *testing.T) {
+	model := NewUniformModel(256)
+	data := []int{0, 1, 2, 255, 128, 64, 32, 16, 8, 4, 2, 1, 0}
+
+	// Encode
+	var buf bytes.Buffer
+	enc := coder.NewEncoder(&buf)
+	for _, symbol := range data {
+		if err := enc.Encode(symbol, model); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Decode
+	dec, err := coder.NewDecoder(&buf)
+	if err != nil {
+		t.Fatalf("coder.NewDecoder failed: %v", err)
+	}
+
+	for i, expected := range data {
+		symbol, err := dec.Decode(model)
+		if err != nil {
+			t.Fatalf("Decode at position %d failed: %v", i, err)
+		}
+		if symbol != expected {
+			t.Errorf("Position %d: expected %d, got %d", i, expected, symbol)
+		}
+	}
+}
+
+func TestRoundtripFrequencyTable(t *testing.T) {
+	freqs := []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	model := NewFrequencyTable(freqs)
+	data := []int{0, 9, 5, 3, 7, 1, 8, 2, 4, 6, 0, 0, 9, 9}
+
+	// Encode
+	var buf bytes.Buffer
+	enc := coder.NewEncoder(&buf)
+	for _, symbol := range data {
+		if err := enc.Encode(symbol, model); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Decode
+	dec, err := coder.NewDecoder(&buf)
+	if err != nil {
+		t.Fatalf("coder.NewDecoder failed: %v", err)
+	}
+
+	for i, expected := range data {
+		symbol, err := dec.Decode(model)
+		if err != nil {
+			t.Fatalf("Decode at position %d failed: %v", i, err)
+		}
+		if symbol != expected {
+			t.Errorf("Position %d: expected %d, got %d", i, expected, symbol)
+		}
+	}
+}
+
+func TestRoundtripLargeAlphabet(t *testing.T) {
+	rng := rand.New(rand.NewSource(518))
+
+	for _, numSymbols := range []int{257, 1000, 70000} {
+		freqs := make([]uint64, numSymbols)
+		for i := range freqs {
+			freqs[i] = 1 + uint64(rng.Intn(1000))
+		}
+		model := NewFrequencyTable(freqs)
+		if model.SymbolCount() != numSymbols {
+			t.Fatalf("%d symbols: SymbolCount() = %d", numSymbols, model.SymbolCount())
+		}
+
+		data := make([]int, 2000)
+		for i := range data {
+			data[i] = rng.Intn(numSymbols)
+		}
+		// Include both ends of the alphabet
+		data[0], data[1] = 0, numSymbols-1
+
+		// Encode, adapting the table as symbols are seen
+		var buf bytes.Buffer
+		enc := coder.NewEncoder(&buf)
+		for _, symbol := range data {
+			if err := enc.Encode(symbol, model); err != nil {
+				t.Fatalf("%d symbols: Encode failed: %v", numSymbols, err)
+			}
+			model.Add(symbol, 32)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("%d symbols: Close failed: %v", numSymbols, err)
+		}
+
+		// Decode
+		model = NewFrequencyTable(freqs)
+		dec, err := coder.NewDecoder(&buf)
+		if err != nil {
+			t.Fatalf("%d symbols: coder.NewDecoder failed: %v", numSymbols, err)
+		}
+		for i, expected := range data {
+			symbol, err := dec.Decode(model)
+			if err != nil {
+				t.Fatalf("%d symbols, position %d: Decode failed: %v", numSymbols, i, err)
+			}
+			if symbol != expected {
+				t.Fatalf("%d symbols, position %d: expected %d, got %d", numSymbols, i, expected, symbol)
+			}
+			model.Add(symbol, 32)
+		}
+	}
+}
+
+func TestRoundtripRandomData(t *testing.T) {
+	rng := rand.New(rand.NewSource(12345))
+
+	for trial := 0; trial < 100; trial++ {
+		// Generate random model
+		numSymbols := 2 + rng.Intn(50)
+		freqs := make([]uint64, numSymbols)
+		for i := range freqs {
+			freqs[i] = 1 + uint64(rng.Intn(100))
+		}
+		model := NewFrequencyTable(freqs)
+
+		// Generate random data
+		dataLen := 10 + rng.Intn(100)
+		data := make([]int, dataLen)
+		for i := range data {
+			data[i] = rng.Intn(numSymbols)
+		}
+
+		// Encode
+		var buf bytes.Buffer
+		enc := coder.NewEncoder(&buf)
+		for _, symbol := range data {
+			if err := enc.Encode(symbol, model); err != nil {
+				t.Fatalf("Trial %d: Encode failed: %v", trial, err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("Trial %d: Close failed: %v", trial, err)
+		}
+
+		// Decode
+		dec, err := coder.NewDecoder(&buf)
+		if err != nil {
+			t.Fatalf("Trial %d: coder.NewDecoder failed: %v", trial, err)
+		}
+
+		for i, expected := range data {
+			symbol, err := dec.Decode(model)
+			if err != nil {
+				t.Fatalf("Trial %d, position %d: Decode failed: %v", trial, i, err)
+			}
+			if symbol != expected {
+				t.Errorf("Trial %d, position %d: expected %d, got %d", trial, i, expected, symbol)
+			}
+		}
+	}
+}
+
+func TestEnglishStringRoundtrip(t *testing.T) {
+	testCases := []string{
+		"",
+		"a",
+		"Hello, World!",
+		"The quick brown fox jumps over the lazy dog.",
+		"This is a longer test string with multiple sentences. It includes punctuation, numbers like 123, and symbols like @#$%.",
+		"UPPERCASE and lowercase and MiXeD cAsE",
+		"Tabs\tand\nnewlines\r\nare supported",
+		"Unicode: café, naïve, 日本語", // Test non-English characters
+	}
+
+	for i, original := range testCases {
+		var buf bytes.Buffer
+		if err := EncodeString(original, &buf); err != nil {
+			t.Fatalf("Test %d: EncodeString failed: %v", i, err)
+		}
+
+		decoded, err := DecodeString(&buf)
+		if err != nil {
+			t.Fatalf("Test %d: DecodeString failed: %v", i, err)
+		}
+
+		if decoded != original {
+			t.Errorf("Test %d: strings don't match.\nOriginal: %q\nDecoded:  %q", i, original, decoded)
+		}
+	}
+}
+
+func TestEnglishStringEOSRoundtrip(t *testing.T) {
+	testCases := []string{
+		"",
+		"a",
+		"Hello, World!",
+		"The quick brown fox jumps over the lazy dog.",
+		"Unicode: café, naïve, 日本語",
+		"Placeholders \ue000 and \ue001 are escaped",
+	}
+
+	model := NewEnglishModelEOS()
+
+	var buf bytes.Buffer
+	enc := coder.NewEncoder(&buf)
+	for i, original := range testCases {
+		if err := EncodeStringEOS(enc, model, original); err != nil {
+			t.Fatalf("Test %d: EncodeStringEOS failed: %v", i, err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	dec, err := coder.NewDecoder(&buf)
+	if err != nil {
+		t.Fatalf("coder.NewDecoder failed: %v", err)
+	}
+	for i, original := range testCases {
+		decoded, err := DecodeStringEOS(dec, model)
+		if err != nil {
+			t.Fatalf("Test %d: DecodeStringEOS failed: %v", i, err)
+		}
+		if decoded != original {
+			t.Errorf("Test %d: strings don't match.\nOriginal: %q\nDecoded:  %q", i, original, decoded)
+		}
+	}
+
+	if err := EncodeStringEOS(coder.NewEncoder(&buf), NewEnglishModel(), "x"); err == nil {
+		t.Error("Expected error when model has no end-of-string symbol")
+	}
+}
+
+func TestEnglishBytesEOSMatchesString(t *testing.T) {
+	model := NewEnglishModelEOS()
+	for _, text := range []string{"", "Hello, World!", "Unicode: café, 日本語", "invalid \xff\xfe utf-8"} {
+		var fromString, fromBytes bytes.Buffer
+		enc := coder.NewEncoder(&fromString)
+		if err := EncodeStringEOS(enc, model, text); err != nil {
+			t.Fatalf("EncodeStringEOS(%q) failed: %v", text, err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		enc = coder.NewEncoder(&fromBytes)
+		if err := EncodeBytesEOS(enc, model, []byte(text)); err != nil {
+			t.Fatalf("EncodeBytesEOS(%q) failed: %v", text, err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(fromString.Bytes(), fromBytes.Bytes()) {
+			t.Errorf("EncodeBytesEOS(%q) differs from EncodeStringEOS", text)
+		}
+	}
+}
+
+func TestEnglishStringCompression(t *testing.T) {
+	text := "The quick brown fox jumps over the lazy dog. " +
+		"This is a test of the English text model compression. " +
+		"Common English words should compress well."
+
+	var buf bytes.Buffer
+	if err := EncodeString(text, &buf); err != nil {
+		t.Fatalf("EncodeString failed: %v", err)
+	}
+
+	compressedSize := buf.Len()
+	originalSize := len(text)
+
+	t.Logf("Original size: %d bytes, Compressed size: %d bytes, Ratio: %.2f%%",
+		originalSize, compressedSize, 100.0*float64(compressedSize)/float64(originalSize))
+
+	// We expect some compression for English text
+	// This is a weak test since the string is short, but it should still compress somewhat
+	if compressedSize >= originalSize {
+		t.Logf("Warning: Compressed size (%d) >= original size (%d)", compressedSize, originalSize)
+	}
+}
+
+func TestEmptyData(t *testing.T) {
+	model := NewUniformModel(256)
+	data := []int{}
+
+	var buf bytes.Buffer
+	enc := coder.NewEncoder(&buf)
+	for _, symbol := range data {
+		if err := enc.Encode(symbol, model); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Should produce some output even for empty data (the final state)
+	if buf.Len() == 0 {
+		t.Error("Expected non-zero output for empty data")
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	model := NewUniformModel(256)
+	data := make([]int, 1000)
+	rng := rand.New(rand.NewSource(42))
+	for i := range data {
+		data[i] = rng.Intn(256)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		enc := coder.NewEncoder(&buf)
+		for _, symbol := range data {
+			enc.Encode(symbol, model)
+		}
+		enc.Close()
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	model := NewUniformModel(256)
+	data := make([]int, 1000)
+	rng := rand.New(rand.NewSource(42))
+	for i := range data {
+		data[i] = rng.Intn(256)
+	}
+
+	var buf bytes.Buffer
+	enc := coder.NewEncoder(&buf)
+	for _, symbol := range data {
+		enc.Encode(symbol, model)
+	}
+	enc.Close()
+
+	compressed := buf.Bytes()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dec, _ := coder.NewDecoder(bytes.NewReader(compressed))
+		for j := 0; j < len(data); j++ {
+			dec.Decode(model)
+		}
+	}
+}
+
+// TestCompressionRatioEnglishText tests compression effectiveness on English text of various sizes.
+
+func TestFrequencyTableStaticLookup(t *testing.T) {
+	freqs := []uint64{5, 1, 1, 30, 2, 7, 1, 3, 9}
+
+	// Static tables use the cumulative array, adaptive ones the Fenwick tree
+	static := NewFrequencyTable(freqs)
+	adaptive := NewFrequencyTable(freqs)
+	adaptive.Add(4, 0)
+
+	for symbol := range freqs {
+		sl, sh := static.Freq(symbol)
+		al, ah := adaptive.Freq(symbol)
+		if sl != al || sh != ah {
+			t.Errorf("symbol %d: static range [%d, %d), adaptive [%d, %d)", symbol, sl, sh, al, ah)
+		}
+	}
+	for cumFreq := uint64(0); cumFreq < static.TotalFreq(); cumFreq++ {
+		if s, a := static.Find(cumFreq), adaptive.Find(cumFreq); s != a {
+			t.Errorf("cumFreq %d: static found %d, adaptive %d", cumFreq, s, a)
+		}
+	}
+
+	// Adding to a static table switches it to the Fenwick tree
+	static.Add(3, 10)
+	low, high := static.Freq(3)
+	if high-low != 40 {
+		t.Errorf("expected frequency 40 after Add, got %d", high-low)
+	}
+	if symbol := static.Find(low + 39); symbol != 3 {
+		t.Errorf("expected symbol 3, got %d", symbol)
+	}
+}
+
+func TestFrequencyTableDirectCoding(t *testing.T) {
+	ft := NewFrequencyTable([]uint64{10, 1, 30, 5, 54})
+	symbols := []int{0, 2, 4, 4, 1, 3, 2, 0, 4}
+
+	// Symbols coded directly and through the Model interface are compatible
+	var buf bytes.Buffer
+	enc := coder.NewEncoder(&buf)
+	for i, symbol := range symbols {
+		var err error
+		if i%2 == 0 {
+			err = ft.Encode(enc, symbol)
+		} else {
+			err = enc.Encode(symbol, ft)
+		}
+		if err != nil {
+			t.Fatalf("encoding symbol %d failed: %v", i, err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dec, err := coder.NewDecoder(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range symbols {
+		var got int
+		if i%3 == 0 {
+			got, err = dec.Decode(ft)
+		} else {
+			got, err = ft.Decode(dec)
+		}
+		if err != nil {
+			t.Fatalf("decoding symbol %d failed: %v", i, err)
+		}
+		if got != want {
+			t.Errorf("symbol %d: got %d, want %d", i, got, want)
+		}
+	}
+
+	if err := ft.Encode(coder.NewEncoder(&buf), 5); !errors.Is(err, coder.ErrSymbolRange) {
+		t.Errorf("Encode of symbol out of range: got %v, want ErrSymbolRange", err)
+	}
+}
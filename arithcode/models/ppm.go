@@ -0,0 +1,419 @@
+package models
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// ppmEOS is the end-of-string symbol; symbols 0-255 are UTF-8 bytes.
+const ppmEOS = 256
+
+// ppmAlphabetSize is the number of symbols in the order -1 fallback model.
+const ppmAlphabetSize = 257
+
+// ppmMaxStringLength limits decoded strings, so that corrupted input
+// without an end-of-string symbol cannot grow the result without bound.
+const ppmMaxStringLength = 1 << 20
+
+// ppmMaxContextTotal is the default count total at which a context is
+// rescaled, keeping statistics adaptive and well within coder precision.
+const ppmMaxContextTotal = 1 << 14
+
+// PPMModel is an adaptive order-N text model using Prediction by Partial
+// Matching (PPM, method C).
+//
+// Each context of the previous k bytes (k = N..0) keeps counts of the bytes
+// that followed it. A symbol is coded in the longest context where it has
+// been seen before; every context that does not contain it codes an escape
+// symbol and falls back to the next shorter context. Symbols already seen in
+// a longer context are excluded from the shorter ones, since the escape
+// proves they were not the next symbol. When no context predicts the symbol,
+// it is coded with a uniform order -1 model.
+//
+// Text is coded as UTF-8 bytes, so any string can be coded without an
+// "other" escape. Strings are terminated with an end-of-string symbol.
+//
+// The model adapts while coding, so the encoder and decoder must code the
+// same strings in the same order with identically initialized models.
+type PPMModel struct {
+	order    int
+	contexts map[string]*ppmContext
+
+	// Count total at which a context is rescaled, see WithDecay
+	maxContextTotal uint64
+
+	// Scratch state reused for every coded symbol
+	excluded [ppmAlphabetSize]bool
+	scratch  ppmCodingModel
+}
+
+// ppmContext holds the symbol counts following one context.
+// Symbols are kept in first-seen order so coding is deterministic.
+type ppmContext struct {
+	symbols []int
+	counts  []uint64
+	total   uint64
+}
+
+// NewPPMModel creates an empty PPM model using contexts of up to order bytes.
+func NewPPMModel(order int) *PPMModel {
+	if order < 0 {
+		panic("order must not be negative")
+	}
+	return &PPMModel{
+		order:           order,
+		contexts:        make(map[string]*ppmContext),
+		maxContextTotal: ppmMaxContextTotal,
+	}
+}
+
+// WithDecay sets the count total at which a context halves its counts and
+// returns m. A lower limit forgets old statistics sooner, so the model
+// follows changes in the coded text faster at the cost of less precise
+// statistics for stable text. The limit must be within
+// [2, coder.MaxTotalFreq/2].
+//
+// The encoder and decoder must use the same limit.
+func (m *PPMModel) WithDecay(limit uint64) *PPMModel {
+	if limit < 2 || limit > coder.MaxTotalFreq/2 {
+		panic("decay limit out of range")
+	}
+	m.maxContextTotal = limit
+	return m
+}
+
+// NewEnglishPPMModel creates an order-3 PPM model primed with English text,
+// so that short strings compress well from the first byte.
+func NewEnglishPPMModel() *PPMModel {
+	model := NewPPMModel(3)
+	for _, line := range strings.Split(englishPPMTraining, "\n") {
+		model.Train(line)
+	}
+	return model
+}
+
+// Train updates the model statistics with s as if it had been coded.
+func (m *PPMModel) Train(s string) {
+	history := make([]byte, 0, m.order)
+	for i := 0; i < len(s); i++ {
+		m.update(history, int(s[i]))
+		history = m.pushHistory(history, s[i])
+	}
+	m.update(history, ppmEOS)
+}
+
+// EncodeString encodes s into enc and updates the model.
+func (m *PPMModel) EncodeString(enc *coder.Encoder, s string) error {
+	history := make([]byte, 0, m.order)
+	for i := 0; i < len(s); i++ {
+		if err := m.encodeSymbol(enc, history, int(s[i])); err != nil {
+			return err
+		}
+		history = m.pushHistory(history, s[i])
+	}
+	return m.encodeSymbol(enc, history, ppmEOS)
+}
+
+// DecodeString decodes a string written by EncodeString and updates the model.
+func (m *PPMModel) DecodeString(dec *coder.Decoder) (string, error) {
+	history := make([]byte, 0, m.order)
+	var result []byte
+	for {
+		symbol, err := m.decodeSymbol(dec, history)
+		if err != nil {
+			return "", err
+		}
+		if symbol == ppmEOS {
+			return string(result), nil
+		}
+		if len(result) >= ppmMaxStringLength {
+			return "", errors.New("ppm: string exceeds maximum length")
+		}
+		result = append(result, byte(symbol))
+		history = m.pushHistory(history, byte(symbol))
+	}
+}
+
+// encodeSymbol codes a single symbol, escaping down from the longest context.
+func (m *PPMModel) encodeSymbol(enc *coder.Encoder, history []byte, symbol int) error {
+	m.excluded = [ppmAlphabetSize]bool{}
+
+	for k := len(history); k >= 0; k-- {
+		ctx := m.contexts[string(history[len(history)-k:])]
+		model := m.contextModel(ctx)
+		if model == nil {
+			continue
+		}
+
+		if index := model.indexOf(symbol); index >= 0 {
+			if err := enc.Encode(index, model); err != nil {
+				return err
+			}
+			m.update(history, symbol)
+			return nil
+		}
+
+		if err := enc.Encode(model.escapeSymbol(), model); err != nil {
+			return err
+		}
+		m.exclude(ctx)
+	}
+
+	model := m.fallbackModel()
+	if err := enc.Encode(model.indexOf(symbol), model); err != nil {
+		return err
+	}
+	m.update(history, symbol)
+	return nil
+}
+
+// decodeSymbol mirrors encodeSymbol.
+func (m *PPMModel) decodeSymbol(dec *coder.Decoder, history []byte) (int, error) {
+	m.excluded = [ppmAlphabetSize]bool{}
+
+	for k := len(history); k >= 0; k-- {
+		ctx := m.contexts[string(history[len(history)-k:])]
+		model := m.contextModel(ctx)
+		if model == nil {
+			continue
+		}
+
+		index, err := dec.Decode(model)
+		if err != nil {
+			return 0, err
+		}
+		if index != model.escapeSymbol() {
+			symbol := model.symbols[index]
+			m.update(history, symbol)
+			return symbol, nil
+		}
+		m.exclude(ctx)
+	}
+
+	model := m.fallbackModel()
+	index, err := dec.Decode(model)
+	if err != nil {
+		return 0, err
+	}
+	symbol := model.symbols[index]
+	m.update(history, symbol)
+	return symbol, nil
+}
+
+// contextModel builds the coding model for ctx with the current exclusions.
+// Returns nil when the context does not predict any non-excluded symbol.
+func (m *PPMModel) contextModel(ctx *ppmContext) *ppmCodingModel {
+	if ctx == nil {
+		return nil
+	}
+
+	model := &m.scratch
+	model.reset()
+	for i, symbol := range ctx.symbols {
+		if !m.excluded[symbol] {
+			model.add(symbol, ctx.counts[i])
+		}
+	}
+	if len(model.symbols) == 0 {
+		return nil
+	}
+
+	// PPM method C: the escape frequency equals the number of distinct
+	// symbols seen in the context.
+	model.add(-1, uint64(len(model.symbols)))
+	return model
+}
+
+// fallbackModel builds the uniform order -1 model over non-excluded symbols.
+func (m *PPMModel) fallbackModel() *ppmCodingModel {
+	model := &m.scratch
+	model.reset()
+	for symbol := 0; symbol < ppmAlphabetSize; symbol++ {
+		if !m.excluded[symbol] {
+			model.add(symbol, 1)
+		}
+	}
+	return model
+}
+
+// exclude marks all symbols of ctx as excluded for the current symbol.
+func (m *PPMModel) exclude(ctx *ppmContext) {
+	for _, symbol := range ctx.symbols {
+		m.excluded[symbol] = true
+	}
+}
+
+// update increments the count of symbol in every context of history.
+func (m *PPMModel) update(history []byte, symbol int) {
+	for k := 0; k <= len(history); k++ {
+		key := string(history[len(history)-k:])
+		ctx, ok := m.contexts[key]
+		if !ok {
+			ctx = &ppmContext{}
+			m.contexts[key] = ctx
+		}
+		ctx.increment(symbol, m.maxContextTotal)
+	}
+}
+
+// pushHistory appends b to history, keeping at most order bytes.
+func (m *PPMModel) pushHistory(history []byte, b byte) []byte {
+	if m.order == 0 {
+		return history
+	}
+	if len(history) == m.order {
+		copy(history, history[1:])
+		history = history[:len(history)-1]
+	}
+	return append(history, b)
+}
+
+// increment adds one occurrence of symbol, rescaling when the total reaches
+// maxTotal.
+func (ctx *ppmContext) increment(symbol int, maxTotal uint64) {
+	found := false
+	for i, s := range ctx.symbols {
+		if s == symbol {
+			ctx.counts[i]++
+			found = true
+			break
+		}
+	}
+	if !found {
+		ctx.symbols = append(ctx.symbols, symbol)
+		ctx.counts = append(ctx.counts, 1)
+	}
+	ctx.total++
+
+	if ctx.total >= maxTotal {
+		ctx.total = 0
+		for i := range ctx.counts {
+			ctx.counts[i] = (ctx.counts[i] + 1) / 2
+			ctx.total += ctx.counts[i]
+		}
+	}
+}
+
+// ppmCodingModel is the model for coding one symbol in one context.
+// The last entry is the escape symbol (-1) unless it is the order -1 model.
+type ppmCodingModel struct {
+	symbols  []int
+	cumFreqs []uint64
+}
+
+func (pm *ppmCodingModel) reset() {
+	pm.symbols = pm.symbols[:0]
+	if len(pm.cumFreqs) == 0 {
+		pm.cumFreqs = append(pm.cumFreqs, 0)
+	}
+	pm.cumFreqs = pm.cumFreqs[:1]
+}
+
+func (pm *ppmCodingModel) add(symbol int, freq uint64) {
+	pm.symbols = append(pm.symbols, symbol)
+	pm.cumFreqs = append(pm.cumFreqs, pm.cumFreqs[len(pm.cumFreqs)-1]+freq)
+}
+
+// indexOf returns the coding index of symbol, or -1 if it is not predicted.
+func (pm *ppmCodingModel) indexOf(symbol int) int {
+	for i, s := range pm.symbols {
+		if s == symbol {
+			return i
+		}
+	}
+	return -1
+}
+
+// escapeSymbol returns the coding index of the escape symbol.
+func (pm *ppmCodingModel) escapeSymbol() int {
+	return len(pm.symbols) - 1
+}
+
+func (pm *ppmCodingModel) SymbolCount() int {
+	return len(pm.symbols)
+}
+
+func (pm *ppmCodingModel) Freq(symbol int) (low, high uint64) {
+	return pm.cumFreqs[symbol], pm.cumFreqs[symbol+1]
+}
+
+func (pm *ppmCodingModel) TotalFreq() uint64 {
+	return pm.cumFreqs[len(pm.cumFreqs)-1]
+}
+
+func (pm *ppmCodingModel) Find(cumFreq uint64) int {
+	left, right := 0, len(pm.symbols)
+	for left < right-1 {
+		mid := (left + right) / 2
+		if pm.cumFreqs[mid] <= cumFreq {
+			left = mid
+		} else {
+			right = mid
+		}
+	}
+	return left
+}
+
+func (pm *ppmCodingModel) Cost(symbol int) float64 {
+	return coder.SymbolCost(pm, symbol)
+}
+
+// EncodeStringPPM encodes a string using an English-primed order-3 PPM model.
+func EncodeStringPPM(s string, w io.Writer) error {
+	enc := coder.NewEncoder(w)
+	if err := NewEnglishPPMModel().EncodeString(enc, s); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// DecodeStringPPM decodes a string written by EncodeStringPPM.
+func DecodeStringPPM(r io.Reader) (string, error) {
+	dec, err := coder.NewDecoder(r)
+	if err != nil {
+		return "", err
+	}
+	return NewEnglishPPMModel().DecodeString(dec)
+}
+
+// englishPPMTraining primes NewEnglishPPMModel. Each line is trained as a
+// separate string, so it should resemble short messages and descriptions.
+const englishPPMTraining = `Hello, how are you?
+Hi there! Is anyone out there?
+The quick brown fox jumps over the lazy dog.
+I am on my way, I will be there in about ten minutes.
+Where are you? Can you hear me?
+Yes, I can hear you loud and clear.
+Thanks for the message, see you at the meeting point.
+We are at the camp near the trail head.
+Good morning everyone, the weather is nice today.
+Good night, talk to you tomorrow.
+Is the node working? Testing the network range from the hill.
+This is a test message being sent over the mesh network.
+Copy that. Heading back to base now.
+Let me know when you get this message.
+I think we should leave early in the morning before it gets too hot.
+The battery is low, I need to charge it soon.
+Meet at the parking lot at the north end of the park.
+What is your location? I can see you on the map.
+There is no signal here, trying again from the top of the ridge.
+Received your message, thank you for the update.
+Can someone check the weather for this afternoon?
+It is going to rain later, bring a jacket.
+Have a great day and stay safe out there!
+Main camping area near the trail head
+Base Camp
+Emergency! Need help at the river crossing.
+All good here, nothing to report.
+OK
+Ok thanks
+Yes
+No
+On my way
+Roger that
+Check in at the station every hour.
+The repeater on the mountain is online again.
+Node is running on solar power with a new antenna.`
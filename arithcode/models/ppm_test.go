@@ -0,0 +1,147 @@
+package models
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+func TestPPMRoundtrip(t *testing.T) {
+	tests := []string{
+		"",
+		"a",
+		"Hello World",
+		"The quick brown fox jumps over the lazy dog",
+		"Location: 37.5318,-122.3898",
+		"Unicode: café, naïve, 日本語",
+		"Binary-ish: \x00\x01\xff",
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeStringPPM(test, &buf); err != nil {
+				t.Fatalf("EncodeStringPPM failed: %v", err)
+			}
+
+			result, err := DecodeStringPPM(&buf)
+			if err != nil {
+				t.Fatalf("DecodeStringPPM failed: %v", err)
+			}
+			if result != test {
+				t.Errorf("Decoded string doesn't match:\noriginal: %q\nresult:   %q", test, result)
+			}
+		})
+	}
+}
+
+func TestPPMAdaptsAcrossStrings(t *testing.T) {
+	texts := []string{
+		"Temperature 21.5C humidity 40%",
+		"Temperature 21.7C humidity 41%",
+		"Temperature 22.0C humidity 41%",
+	}
+
+	var buf bytes.Buffer
+	enc := coder.NewEncoder(&buf)
+	model := NewPPMModel(3)
+	for _, text := range texts {
+		if err := model.EncodeString(enc, text); err != nil {
+			t.Fatalf("EncodeString failed: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	dec, err := coder.NewDecoder(&buf)
+	if err != nil {
+		t.Fatalf("coder.NewDecoder failed: %v", err)
+	}
+	model = NewPPMModel(3)
+	for _, text := range texts {
+		result, err := model.DecodeString(dec)
+		if err != nil {
+			t.Fatalf("DecodeString failed: %v", err)
+		}
+		if result != text {
+			t.Errorf("Decoded string doesn't match:\noriginal: %q\nresult:   %q", text, result)
+		}
+	}
+}
+
+func TestPPMVsOrder2(t *testing.T) {
+	testStrings := []string{
+		"Hello! How are you today? I hope you are doing well. Have a great day!",
+		"This is a test message being sent over the Meshtastic network",
+		"We are heading to the trail head, meet us at the parking lot in ten minutes.",
+	}
+
+	var totalOrder2, totalPPM int
+	for _, test := range testStrings {
+		var bufOrder2, bufPPM bytes.Buffer
+		if err := EncodeStringOrder2(test, &bufOrder2); err != nil {
+			t.Fatalf("Order-2 encode failed: %v", err)
+		}
+		if err := EncodeStringPPM(test, &bufPPM); err != nil {
+			t.Fatalf("PPM encode failed: %v", err)
+		}
+		totalOrder2 += bufOrder2.Len()
+		totalPPM += bufPPM.Len()
+
+		t.Logf("%q: order-2 %d bytes, PPM %d bytes", test, bufOrder2.Len(), bufPPM.Len())
+	}
+
+	if totalPPM >= totalOrder2 {
+		t.Errorf("PPM (%d bytes) should beat order-2 (%d bytes)", totalPPM, totalOrder2)
+	}
+}
+
+func TestPPMDecay(t *testing.T) {
+	// The traffic drifts from one kind of message to another
+	var texts []string
+	for i := 0; i < 200; i++ {
+		texts = append(texts, "battery 87% voltage 4.1V")
+	}
+	for i := 0; i < 20; i++ {
+		texts = append(texts, "see you at the trailhead")
+	}
+
+	encodeAll := func(decay uint64) []byte {
+		var buf bytes.Buffer
+		enc := coder.NewEncoder(&buf)
+		model := NewPPMModel(2).WithDecay(decay)
+		for _, text := range texts {
+			if err := model.EncodeString(enc, text); err != nil {
+				t.Fatalf("EncodeString failed: %v", err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	slow := encodeAll(ppmMaxContextTotal)
+	fast := encodeAll(64)
+	t.Logf("default: %d bytes, decay 64: %d bytes", len(slow), len(fast))
+	if len(fast) >= len(slow) {
+		t.Errorf("decay should adapt faster to drift: %d bytes >= %d bytes", len(fast), len(slow))
+	}
+
+	dec, err := coder.NewDecoder(bytes.NewReader(fast))
+	if err != nil {
+		t.Fatalf("coder.NewDecoder failed: %v", err)
+	}
+	model := NewPPMModel(2).WithDecay(64)
+	for i, text := range texts {
+		result, err := model.DecodeString(dec)
+		if err != nil {
+			t.Fatalf("DecodeString %d failed: %v", i, err)
+		}
+		if result != text {
+			t.Fatalf("string %d: expected %q, got %q", i, text, result)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package models
+
+import "math"
+
+// Shape describes a distribution over the symbols of a model by the weight
+// of each symbol. Shapes are combined with Weighted and Mixture and turned
+// into a frequency table with NewShapedModel, so that models can be declared
+// instead of filling frequency tables by hand:
+//
+//	// Battery levels: mostly charged, rarely above 100
+//	NewShapedModel(256, Mixture(
+//		Weighted(20, Uniform(0, 100)),
+//		Weighted(80, Uniform(50, 100)),
+//	))
+//
+// The built-in shapes have a peak weight of 1, so the weight given to a shape
+// is the frequency of its most likely symbol. Any function of the symbol can
+// be used as a shape; weights must not be negative.
+type Shape func(symbol int) float64
+
+// Uniform returns a shape with weight 1 for the symbols lo to hi inclusive
+// and 0 elsewhere.
+func Uniform(lo, hi int) Shape {
+	return func(symbol int) float64 {
+		if symbol < lo || symbol > hi {
+			return 0
+		}
+		return 1
+	}
+}
+
+// Geometric returns a shape where the weight of each symbol is 1-p times the
+// weight of the previous one, starting with 1 at symbol 0. It models counts
+// that are small most of the time, p is the probability of symbol 0.
+func Geometric(p float64) Shape {
+	return func(symbol int) float64 {
+		if symbol < 0 {
+			return 0
+		}
+		return math.Pow(1-p, float64(symbol))
+	}
+}
+
+// Gaussian returns a bell-shaped shape centered on mean, with the standard
+// deviation sd.
+func Gaussian(mean, sd float64) Shape {
+	return func(symbol int) float64 {
+		z := (float64(symbol) - mean) / sd
+		return math.Exp(-z * z / 2)
+	}
+}
+
+// Weighted returns shape with its weights multiplied by weight.
+func Weighted(weight float64, shape Shape) Shape {
+	return func(symbol int) float64 {
+		return weight * shape(symbol)
+	}
+}
+
+// Mixture returns the sum of shapes.
+func Mixture(shapes ...Shape) Shape {
+	return func(symbol int) float64 {
+		var sum float64
+		for _, shape := range shapes {
+			sum += shape(symbol)
+		}
+		return sum
+	}
+}
+
+// ShapeFrequencies returns the frequencies of the symbols 0 to n-1 of shape.
+// Weights are rounded to integers; symbols with a weight below 1 get
+// frequency 1, so that every symbol stays codable.
+func ShapeFrequencies(n int, shape Shape) []uint64 {
+	freqs := make([]uint64, n)
+	for symbol := range freqs {
+		weight := shape(symbol)
+		if !(weight >= 1) {
+			// Also catches NaN
+			freqs[symbol] = 1
+			continue
+		}
+		freqs[symbol] = uint64(min(math.Round(weight), math.MaxUint32))
+	}
+	return freqs
+}
+
+// NewShapedModel creates a frequency table for the symbols 0 to n-1 of
+// shape, see ShapeFrequencies.
+func NewShapedModel(n int, shape Shape) *FrequencyTable {
+	return NewFrequencyTable(ShapeFrequencies(n, shape))
+}
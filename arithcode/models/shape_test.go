@@ -0,0 +1,49 @@
+package models
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestShapeFrequencies(t *testing.T) {
+	tests := []struct {
+		name  string
+		shape Shape
+		want  []uint64
+	}{
+		{"uniform", Uniform(2, 4), []uint64{1, 1, 1, 1, 1, 1}},
+		{"weighted", Weighted(40, Uniform(2, 4)), []uint64{1, 1, 40, 40, 40, 1}},
+		{"mixture", Mixture(
+			Weighted(200, Uniform(0, 0)),
+			Weighted(30, Uniform(1, 4)),
+			Weighted(20, Uniform(3, 4)),
+		), []uint64{200, 30, 30, 50, 50, 1}},
+		{"geometric", Weighted(100, Geometric(0.5)), []uint64{100, 50, 25, 13, 6, 3}},
+		{"gaussian", Weighted(100, Gaussian(2, 1)), []uint64{14, 61, 100, 61, 14, 1}},
+		{"invalid", func(int) float64 { return math.NaN() }, []uint64{1, 1, 1, 1, 1, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ShapeFrequencies(6, test.shape)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewShapedModel(t *testing.T) {
+	// Shapes with huge weights are scaled to the coder precision
+	model := NewShapedModel(256, Weighted(1e12, Geometric(0.01)))
+	if err := ValidateFrequencies(model.Frequencies()); err != nil {
+		t.Fatal(err)
+	}
+	freqs := model.Frequencies()
+	for i := 1; i < len(freqs); i++ {
+		if freqs[i] > freqs[i-1] {
+			t.Fatalf("frequency of symbol %d exceeds the previous one: %v", i, freqs)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "sync"
+
+// Shared instances of the static models.
+//
+// Building the English models allocates hundreds of frequency tables, so
+// callers that code many strings should reuse a single instance. These
+// models are never modified while coding, which makes a shared instance safe
+// for concurrent use. Adaptive models such as WordModel and PPMModel cannot
+// be shared.
+var (
+	sharedEnglishModel       = sync.OnceValue(NewEnglishModel)
+	sharedEnglishModelEOS    = sync.OnceValue(NewEnglishModelEOS)
+	sharedEnglishOrder1Model = sync.OnceValue(NewEnglishOrder1Model)
+	sharedEnglishOrder2Model = sync.OnceValue(NewEnglishOrder2Model)
+	sharedEnglishBigramModel = sync.OnceValue(NewEnglishBigramModel)
+)
+
+// SharedEnglishModel returns a shared model created by NewEnglishModel.
+func SharedEnglishModel() *EnglishModel { return sharedEnglishModel() }
+
+// SharedEnglishModelEOS returns a shared model created by NewEnglishModelEOS.
+func SharedEnglishModelEOS() *EnglishModel { return sharedEnglishModelEOS() }
+
+// SharedEnglishOrder1Model returns a shared model created by NewEnglishOrder1Model.
+func SharedEnglishOrder1Model() *EnglishOrder1Model { return sharedEnglishOrder1Model() }
+
+// SharedEnglishOrder2Model returns a shared model created by NewEnglishOrder2Model.
+func SharedEnglishOrder2Model() *EnglishOrder2Model { return sharedEnglishOrder2Model() }
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"encoding/binary"
+	"hash"
+	"slices"
+)
+
+// Adaptive models start from fixed tables and update on a fixed schedule
+// after every coded symbol; none of them use random numbers or depend on map
+// iteration order. An encoder and a decoder that coded the same symbols
+// therefore hold identical models, and a desync shows up as the first symbol
+// after which their state differs. State hashes find that symbol without
+// comparing whole models.
+
+// StateHasher is implemented by models whose state changes while coding.
+type StateHasher interface {
+	// HashState writes the state that affects coding to h.
+	HashState(h hash.Hash)
+}
+
+// HashState writes the state of m to h when m is a StateHasher. Static
+// models write nothing.
+func HashState(h hash.Hash, m any) {
+	if s, ok := m.(StateHasher); ok {
+		s.HashState(h)
+	}
+}
+
+// hashUints writes values to h.
+func hashUints(h hash.Hash, values ...uint64) {
+	var buf [8]byte
+	for _, v := range values {
+		binary.LittleEndian.PutUint64(buf[:], v)
+		h.Write(buf[:])
+	}
+}
+
+// HashState writes the frequencies of the table and its limit to h.
+func (ft *FrequencyTable) HashState(h hash.Hash) {
+	hashUints(h, uint64(ft.SymbolCount()), ft.total, ft.maxTotal)
+	for symbol := 0; symbol < ft.SymbolCount(); symbol++ {
+		low, high := ft.Freq(symbol)
+		hashUints(h, high-low)
+	}
+}
+
+// HashState writes the mispredictions and the adaptive table to h.
+func (m *EscapeModel) HashState(h hash.Hash) {
+	hashUints(h, uint64(m.misses), uint64(m.limit))
+	m.adaptive.HashState(h)
+}
+
+// HashState writes the contexts in the order of their keys to h.
+func (m *PPMModel) HashState(h hash.Hash) {
+	keys := make([]string, 0, len(m.contexts))
+	for key := range m.contexts {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	hashUints(h, uint64(m.order), m.maxContextTotal, uint64(len(keys)))
+	for _, key := range keys {
+		ctx := m.contexts[key]
+		hashUints(h, uint64(len(key)))
+		h.Write([]byte(key))
+		hashUints(h, ctx.total, uint64(len(ctx.symbols)))
+		for i, symbol := range ctx.symbols {
+			hashUints(h, uint64(symbol), ctx.counts[i])
+		}
+	}
+}
+
+// HashState writes the contexts created so far to h.
+func (m *BytesOrder1Model) HashState(h hash.Hash) {
+	for prev, ctx := range m.contexts {
+		if ctx != nil {
+			hashUints(h, uint64(prev))
+			ctx.HashState(h)
+		}
+	}
+}
+
+// HashState writes the text model and the emoji tables to h.
+func (m *EmojiTextModel) HashState(h hash.Hash) {
+	m.text.HashState(h)
+	for _, segment := range m.segments {
+		segment.HashState(h)
+	}
+	m.emoji.HashState(h)
+	m.variants.HashState(h)
+}
+
+// HashState writes the recent pages and the page and offset tables to h.
+func (m *UnicodeModel) HashState(h hash.Hash) {
+	for _, page := range m.recent {
+		hashUints(h, uint64(page))
+	}
+	m.pageModel.HashState(h)
+
+	pages := make([]int, 0, len(m.offsets))
+	for page := range m.offsets {
+		pages = append(pages, page)
+	}
+	slices.Sort(pages)
+	for _, page := range pages {
+		hashUints(h, uint64(page))
+		m.offsets[page].HashState(h)
+	}
+}
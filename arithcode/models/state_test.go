@@ -0,0 +1,142 @@
+package models
+
+import (
+	"hash/fnv"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+func TestHashState(t *testing.T) {
+	hashOf := func(m any) uint64 {
+		h := fnv.New64a()
+		HashState(h, m)
+		return h.Sum64()
+	}
+
+	newModels := func() []any {
+		ppm := NewPPMModel(2)
+		ppm.Train("hello world")
+		bytes := NewBytesOrder1Model()
+		bytes.context(-1).Add(7, 32)
+		escape := NewEscapeModel(NewFrequencyTable([]uint64{10, 1}), 2)
+		escape.Update(1)
+		unicode := NewUnicodeModel()
+		return []any{ppm, bytes, escape, NewEmojiTextModel(ppm), unicode}
+	}
+	a, b := newModels(), newModels()
+	for i := range a {
+		if hashOf(a[i]) != hashOf(b[i]) {
+			t.Errorf("model %d: identically built models hash differently", i)
+		}
+	}
+
+	// One more symbol changes every hash
+	a[0].(*PPMModel).Train("!")
+	a[1].(*BytesOrder1Model).context(-1).Add(7, 32)
+	a[2].(*EscapeModel).Update(1)
+	a[4].(*UnicodeModel).pageModel.Add(3, 32)
+	for i := range a {
+		if i == 3 {
+			continue // Shares the PPM model of a[0]
+		}
+		if hashOf(a[i]) == hashOf(b[i]) {
+			t.Errorf("model %d: hash did not change after an update", i)
+		}
+	}
+
+	// Static models have no state
+	var static coder.Model = SharedUniformModel(2)
+	if hashOf(static) != hashOf(nil) {
+		t.Errorf("static model has state")
+	}
+}
+
+func TestClone(t *testing.T) {
+	hashOf := func(m any) uint64 {
+		h := fnv.New64a()
+		HashState(h, m)
+		return h.Sum64()
+	}
+
+	ppm := NewPPMModel(2)
+	ppm.Train("hello world")
+	bytes := NewBytesOrder1Model()
+	bytes.context(-1).Add(7, 32)
+	escape := NewEscapeModel(NewFrequencyTable([]uint64{10, 1}), 2)
+	escape.Update(1)
+	unicode := NewUnicodeModel()
+	unicode.offsetModel(3).Add(5, 32)
+	emoji := NewEmojiTextModel(ppm)
+	emoji.emoji.Add(1, 32)
+
+	ppmClone := ppm.Clone()
+	original := []any{ppm, bytes, escape, unicode, emoji}
+	clones := []any{ppmClone, bytes.Clone(), Clone(escape), unicode.Clone(), emoji.Clone(ppmClone)}
+	for i := range original {
+		if hashOf(original[i]) != hashOf(clones[i]) {
+			t.Errorf("model %d: clone hashes differently", i)
+		}
+	}
+
+	// Updating a clone leaves the original unchanged
+	before := make([]uint64, len(original))
+	for i := range original {
+		before[i] = hashOf(original[i])
+	}
+	ppmClone.Train("!")
+	clones[1].(*BytesOrder1Model).context(-1).Add(7, 32)
+	clones[2].(*EscapeModel).Update(1)
+	clones[3].(*UnicodeModel).offsetModel(3).Add(5, 32)
+	clones[4].(*EmojiTextModel).segments[0].Add(1, 32)
+	for i := range original {
+		if hashOf(original[i]) != before[i] {
+			t.Errorf("model %d: updating the clone changed the original", i)
+		}
+		if hashOf(clones[i]) == before[i] {
+			t.Errorf("model %d: clone did not change after an update", i)
+		}
+	}
+
+	// Static models are shared
+	var static coder.Model = SharedUniformModel(2)
+	if Clone(static) != static {
+		t.Errorf("static model was copied")
+	}
+}
+
+func TestPPMResetFrom(t *testing.T) {
+	hashOf := func(m any) uint64 {
+		h := fnv.New64a()
+		HashState(h, m)
+		return h.Sum64()
+	}
+
+	trained := NewPPMModel(2)
+	trained.Train("hello world")
+	want := hashOf(trained)
+
+	m := trained.Clone()
+	m.Train("goodbye moon")
+	m.ResetFrom(trained)
+	if got := hashOf(m); got != want {
+		t.Errorf("reset model hashes differently from the source")
+	}
+	if allocs := testing.AllocsPerRun(10, func() {
+		m.ResetFrom(trained)
+	}); allocs > 0 {
+		t.Errorf("ResetFrom allocated %v times, want none", allocs)
+	}
+
+	// The source is unchanged by updates of the reset model
+	m.Train("!")
+	if hashOf(trained) != want {
+		t.Errorf("updating the reset model changed the source")
+	}
+
+	empty := NewPPMModel(3)
+	empty.ResetFrom(trained)
+	if hashOf(empty) != want {
+		t.Errorf("reset of an empty model hashes differently from the source")
+	}
+}
@@ -0,0 +1,342 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+const (
+	// trainedMaxAlphabet limits the number of distinct characters in a
+	// trained model, rarer characters are coded as "other".
+	trainedMaxAlphabet = 255
+
+	// trainedMinContextCount is the number of observations needed before a
+	// context gets its own table.
+	trainedMinContextCount = 2
+
+	// trainedScale is the total frequency (excluding the +1 floor) of each
+	// trained table.
+	trainedScale = 1 << 16
+
+	// trainedStart is the context symbol before the first character.
+	trainedStart = -1
+)
+
+// TrainedStringModel is an order-2 character model built from example text.
+//
+// It can be used in place of the built-in English models for text in other
+// languages or with a different vocabulary. Contexts that were seen rarely
+// during training fall back to order-1 and order-0 predictions.
+type TrainedStringModel struct {
+	charToSymbol map[rune]int
+	symbolToChar []rune
+
+	order0 *FrequencyTable
+	order1 map[int]*FrequencyTable
+	order2 map[[2]int]*FrequencyTable
+
+	// Special symbols
+	otherSymbol int // For characters not in the alphabet
+}
+
+// TrainStringModel builds a string model from the corpus in r.
+//
+// The corpus is read as UTF-8 text with one example string per line; the
+// context is reset at the start of each line, the same way it is at the start
+// of each encoded string.
+func TrainStringModel(r io.Reader) (*TrainedStringModel, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading corpus: %w", err)
+	}
+
+	var lines [][]rune
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line != "" {
+			lines = append(lines, []rune(line))
+		}
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("corpus is empty")
+	}
+
+	model := &TrainedStringModel{
+		charToSymbol: make(map[rune]int),
+		order1:       make(map[int]*FrequencyTable),
+		order2:       make(map[[2]int]*FrequencyTable),
+	}
+	model.buildAlphabet(lines)
+	model.buildContextModels(lines)
+
+	return model, nil
+}
+
+// buildAlphabet assigns symbols to the most frequent characters.
+func (m *TrainedStringModel) buildAlphabet(lines [][]rune) {
+	counts := make(map[rune]int)
+	for _, line := range lines {
+		for _, ch := range line {
+			counts[ch]++
+		}
+	}
+
+	chars := make([]rune, 0, len(counts))
+	for ch := range counts {
+		chars = append(chars, ch)
+	}
+	sort.Slice(chars, func(i, k int) bool {
+		if counts[chars[i]] != counts[chars[k]] {
+			return counts[chars[i]] > counts[chars[k]]
+		}
+		return chars[i] < chars[k]
+	})
+	if len(chars) > trainedMaxAlphabet {
+		chars = chars[:trainedMaxAlphabet]
+	}
+
+	for i, ch := range chars {
+		m.charToSymbol[ch] = i
+	}
+	m.symbolToChar = chars
+	m.otherSymbol = len(chars)
+}
+
+// buildContextModels counts symbol occurrences per context and converts the
+// counts to frequency tables. Each context's prediction is blended with the
+// next lower order, weighted by the number of distinct symbols seen in the
+// context (as in PPM method C).
+func (m *TrainedStringModel) buildContextModels(lines [][]rune) {
+	numSymbols := len(m.symbolToChar) + 1
+
+	counts0 := make([]int, numSymbols)
+	counts1 := make(map[int][]int)
+	counts2 := make(map[[2]int][]int)
+
+	for _, line := range lines {
+		prev1, prev2 := trainedStart, trainedStart
+		for _, ch := range line {
+			symbol := m.symbolOf(ch)
+
+			counts0[symbol]++
+			if counts1[prev1] == nil {
+				counts1[prev1] = make([]int, numSymbols)
+			}
+			counts1[prev1][symbol]++
+			ctx := [2]int{prev2, prev1}
+			if counts2[ctx] == nil {
+				counts2[ctx] = make([]int, numSymbols)
+			}
+			counts2[ctx][symbol]++
+
+			prev2, prev1 = prev1, symbol
+		}
+	}
+
+	// Order-0 uses add-one smoothing, so every symbol is codable
+	probs0 := make([]float64, numSymbols)
+	total0 := 0
+	for _, c := range counts0 {
+		total0 += c
+	}
+	for s, c := range counts0 {
+		probs0[s] = float64(c+1) / float64(total0+numSymbols)
+	}
+	m.order0 = trainedTable(probs0)
+
+	probs1 := make(map[int][]float64)
+	for ctx, counts := range counts1 {
+		if probs := blendCounts(counts, probs0); probs != nil {
+			probs1[ctx] = probs
+			m.order1[ctx] = trainedTable(probs)
+		}
+	}
+
+	for ctx, counts := range counts2 {
+		lower, ok := probs1[ctx[1]]
+		if !ok {
+			lower = probs0
+		}
+		if probs := blendCounts(counts, lower); probs != nil {
+			m.order2[ctx] = trainedTable(probs)
+		}
+	}
+}
+
+// blendCounts blends context counts with the lower order probabilities.
+// Returns nil when the context was not seen often enough.
+func blendCounts(counts []int, lower []float64) []float64 {
+	total, distinct := 0, 0
+	for _, c := range counts {
+		total += c
+		if c > 0 {
+			distinct++
+		}
+	}
+	if total < trainedMinContextCount {
+		return nil
+	}
+
+	probs := make([]float64, len(counts))
+	for s, c := range counts {
+		probs[s] = (float64(c) + float64(distinct)*lower[s]) / float64(total+distinct)
+	}
+	return probs
+}
+
+// trainedTable converts probabilities to a frequency table.
+func trainedTable(probs []float64) *FrequencyTable {
+	freqs := make([]uint64, len(probs))
+	for s, p := range probs {
+		freqs[s] = 1 + uint64(p*trainedScale)
+	}
+	return NewFrequencyTable(freqs)
+}
+
+// symbolOf returns the symbol for ch, or the "other" symbol.
+func (m *TrainedStringModel) symbolOf(ch rune) int {
+	if symbol, ok := m.charToSymbol[ch]; ok {
+		return symbol
+	}
+	return m.otherSymbol
+}
+
+// GetModel returns the appropriate model for the given context,
+// where prev1 is the most recent symbol. Use -1 for missing context.
+func (m *TrainedStringModel) GetModel(prev1, prev2 int) coder.Model {
+	if model, ok := m.order2[[2]int{prev2, prev1}]; ok {
+		return model
+	}
+	if model, ok := m.order1[prev1]; ok {
+		return model
+	}
+	return m.order0
+}
+
+// Encode writes s into enc, prefixed with its length in characters.
+func (m *TrainedStringModel) Encode(enc *coder.Encoder, s string) error {
+	byteModel := NewUniformModel(256)
+
+	runes := []rune(s)
+
+	// Encode length as varint
+	tempLen := len(runes)
+	for i := 0; i < 4; i++ {
+		b := byte(tempLen & 0x7F)
+		tempLen >>= 7
+		if tempLen > 0 {
+			b |= 0x80
+		}
+		if err := enc.Encode(int(b), byteModel); err != nil {
+			return err
+		}
+		if tempLen == 0 {
+			break
+		}
+	}
+
+	prev1, prev2 := trainedStart, trainedStart
+	for _, ch := range runes {
+		symbol := m.symbolOf(ch)
+		if err := enc.Encode(symbol, m.GetModel(prev1, prev2)); err != nil {
+			return err
+		}
+
+		if symbol == m.otherSymbol {
+			// Encode the actual rune as UTF-8 bytes
+			utf8Bytes := []byte(string(ch))
+			if err := enc.Encode(len(utf8Bytes), NewUniformModel(5)); err != nil {
+				return err
+			}
+			for _, b := range utf8Bytes {
+				if err := enc.Encode(int(b), byteModel); err != nil {
+					return err
+				}
+			}
+		}
+
+		prev2, prev1 = prev1, symbol
+	}
+
+	return nil
+}
+
+// Decode reads a string written by Encode.
+func (m *TrainedStringModel) Decode(dec *coder.Decoder) (string, error) {
+	byteModel := NewUniformModel(256)
+
+	// Decode length
+	var length int
+	for i := 0; i < 4; i++ {
+		symbol, err := dec.Decode(byteModel)
+		if err != nil {
+			return "", err
+		}
+		b := byte(symbol)
+		length |= int(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	prev1, prev2 := trainedStart, trainedStart
+	result := make([]rune, 0, min(length, 1024))
+	for len(result) < length {
+		symbol, err := dec.Decode(m.GetModel(prev1, prev2))
+		if err != nil {
+			return "", err
+		}
+
+		if symbol == m.otherSymbol {
+			// Decode UTF-8 bytes for unknown character
+			numBytes, err := dec.Decode(NewUniformModel(5))
+			if err != nil {
+				return "", err
+			}
+
+			utf8Bytes := make([]byte, numBytes)
+			for i := 0; i < numBytes; i++ {
+				b, err := dec.Decode(byteModel)
+				if err != nil {
+					return "", err
+				}
+				utf8Bytes[i] = byte(b)
+			}
+
+			runes := []rune(string(utf8Bytes))
+			if len(runes) > 0 {
+				result = append(result, runes[0])
+			}
+		} else {
+			result = append(result, m.symbolToChar[symbol])
+		}
+
+		prev2, prev1 = prev1, symbol
+	}
+
+	return string(result), nil
+}
+
+// EncodeString encodes a string using the trained model.
+// It is a drop-in replacement for EncodeStringOrder1 and EncodeStringOrder2.
+func (m *TrainedStringModel) EncodeString(s string, w io.Writer) error {
+	enc := coder.NewEncoder(w)
+	if err := m.Encode(enc, s); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// DecodeString decodes a string written by EncodeString.
+func (m *TrainedStringModel) DecodeString(r io.Reader) (string, error) {
+	dec, err := coder.NewDecoder(r)
+	if err != nil {
+		return "", err
+	}
+	return m.Decode(dec)
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const germanCorpus = `Hallo, wie geht es dir?
+Ich bin gleich da, warte bitte am Parkplatz.
+Wir treffen uns um acht Uhr an der Hütte.
+Der Akku ist fast leer, ich melde mich später.
+Guten Morgen zusammen! Das Wetter ist heute schön.
+Kann mich jemand hören? Ich teste die Reichweite vom Berg.
+Danke für die Nachricht, bis später.
+Wo seid ihr gerade? Wir sind schon auf dem Rückweg.
+Die Straße ist gesperrt, wir nehmen den Weg über die Brücke.
+Alles gut hier, der Empfang ist sehr gut.
+Ich bin jetzt zu Hause, gute Nacht!
+Bitte bringt genug Wasser mit, es wird heiß.
+Wir warten noch auf die anderen, dann geht es los.
+Hat jemand die Karte gesehen? Ich finde sie nicht.
+Der Knoten auf dem Dach läuft wieder.
+`
+
+func TestTrainedStringModelRoundtrip(t *testing.T) {
+	model, err := TrainStringModel(strings.NewReader(germanCorpus))
+	if err != nil {
+		t.Fatalf("TrainStringModel failed: %v", err)
+	}
+
+	tests := []string{
+		"",
+		"Hallo",
+		"Wir sind gleich an der Hütte.",
+		"Unbekannte Zeichen: ß€ 日本 😀",
+		"Multi\nline\ttext",
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := model.EncodeString(test, &buf); err != nil {
+			t.Fatalf("EncodeString(%q) failed: %v", test, err)
+		}
+
+		result, err := model.DecodeString(&buf)
+		if err != nil {
+			t.Fatalf("DecodeString(%q) failed: %v", test, err)
+		}
+		if result != test {
+			t.Errorf("Decoded string doesn't match:\noriginal: %q\nresult:   %q", test, result)
+		}
+	}
+}
+
+func TestTrainedStringModelVsEnglish(t *testing.T) {
+	model, err := TrainStringModel(strings.NewReader(germanCorpus))
+	if err != nil {
+		t.Fatalf("TrainStringModel failed: %v", err)
+	}
+
+	testStrings := []string{
+		"Guten Abend, wir sind jetzt auf dem Weg zur Hütte.",
+		"Der Empfang am Berg ist heute sehr gut, danke!",
+		"Ich melde mich, wenn wir am Parkplatz sind.",
+	}
+
+	var totalOrder2, totalTrained int
+	for _, test := range testStrings {
+		var bufOrder2, bufTrained bytes.Buffer
+		if err := EncodeStringOrder2(test, &bufOrder2); err != nil {
+			t.Fatalf("Order-2 encode failed: %v", err)
+		}
+		if err := model.EncodeString(test, &bufTrained); err != nil {
+			t.Fatalf("Trained encode failed: %v", err)
+		}
+		totalOrder2 += bufOrder2.Len()
+		totalTrained += bufTrained.Len()
+
+		t.Logf("%q: order-2 %d bytes, trained %d bytes", test, bufOrder2.Len(), bufTrained.Len())
+	}
+
+	if totalTrained >= totalOrder2 {
+		t.Errorf("Trained model (%d bytes) should beat English order-2 (%d bytes) on German text", totalTrained, totalOrder2)
+	}
+}
+
+func TestTrainStringModelEmpty(t *testing.T) {
+	if _, err := TrainStringModel(strings.NewReader("\n\n")); err == nil {
+		t.Error("Expected error for empty corpus")
+	}
+}
@@ -0,0 +1,262 @@
+package models
+
+import (
+	"errors"
+	"io"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+const (
+	// unicodePageBits splits a codepoint into a page and an offset.
+	// A 128 codepoint page fits most alphabets (Cyrillic, Greek, Hebrew, ...)
+	// into one or two pages.
+	unicodePageBits  = 7
+	unicodePageSize  = 1 << unicodePageBits
+	unicodePageCount = (unicode.MaxRune + 1) >> unicodePageBits
+
+	// unicodeRecentPages is the number of recently used pages that can be
+	// selected with a short code.
+	unicodeRecentPages = 16
+
+	// Page selection symbols following the recent page positions.
+	unicodeNewPage = unicodeRecentPages     // Followed by the page index
+	unicodeRawByte = unicodeRecentPages + 1 // Followed by a byte of invalid UTF-8
+	unicodeEOS     = unicodeRecentPages + 2 // End of string
+
+	// unicodeIncrement is added to a symbol's count each time it is coded.
+	unicodeIncrement = 24
+
+	// unicodeMaxTotal is the total at which a table is rescaled.
+	unicodeMaxTotal = 1 << 16
+
+	// unicodeMaxStringLength limits decoded strings, so that corrupted input
+	// without an end-of-string symbol cannot grow the result without bound.
+	unicodeMaxStringLength = 1 << 20
+)
+
+// UnicodeModel is an adaptive model for text in any script.
+//
+// Each codepoint is split into a 128 codepoint page and an offset within the
+// page. The page is coded as a position in a move-to-front list of recently
+// used pages, so text that stays within one script pays almost nothing for
+// it, and the offset is coded with an adaptive table per page. Bytes that are
+// not valid UTF-8 are coded verbatim, so every string roundtrips exactly.
+//
+// The model adapts while coding, so the encoder and decoder must code the
+// same strings in the same order with identically initialized models.
+type UnicodeModel struct {
+	recent    [unicodeRecentPages]int
+	pageModel *FrequencyTable
+	offsets   map[int]*FrequencyTable
+
+	pageIndexModel *UniformModel
+	byteModel      *UniformModel
+}
+
+// NewUnicodeModel creates a Unicode model. The first pages (ASCII, Latin-1
+// and the following Latin, Greek and Cyrillic pages) are initially recent.
+func NewUnicodeModel() *UnicodeModel {
+	m := &UnicodeModel{
+		offsets:        make(map[int]*FrequencyTable),
+		pageIndexModel: NewUniformModel(unicodePageCount),
+		byteModel:      NewUniformModel(256),
+	}
+	for i := range m.recent {
+		m.recent[i] = i
+	}
+
+	freqs := make([]uint64, unicodeEOS+1)
+	for i := 0; i < unicodeRecentPages; i++ {
+		freqs[i] = 1 + 256>>i
+	}
+	freqs[0] = 2048
+	freqs[unicodeNewPage] = 16
+	freqs[unicodeRawByte] = 1
+	freqs[unicodeEOS] = 64
+	m.pageModel = newAdaptiveTable(freqs)
+
+	return m
+}
+
+// EncodeString encodes s into enc and updates the model.
+func (m *UnicodeModel) EncodeString(enc *coder.Encoder, s string) error {
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if r == utf8.RuneError && size == 1 {
+			if err := m.encodeSymbol(enc, unicodeRawByte); err != nil {
+				return err
+			}
+			if err := enc.Encode(int(s[0]), m.byteModel); err != nil {
+				return err
+			}
+			s = s[size:]
+			continue
+		}
+		s = s[size:]
+
+		page, offset := int(r>>unicodePageBits), int(r&(unicodePageSize-1))
+		position := m.recentPosition(page)
+		if position < 0 {
+			if err := m.encodeSymbol(enc, unicodeNewPage); err != nil {
+				return err
+			}
+			if err := enc.Encode(page, m.pageIndexModel); err != nil {
+				return err
+			}
+			position = len(m.recent) - 1
+		} else if err := m.encodeSymbol(enc, position); err != nil {
+			return err
+		}
+		m.moveToFront(position, page)
+
+		offsets := m.offsetModel(page)
+		if err := enc.Encode(offset, offsets); err != nil {
+			return err
+		}
+		adaptTable(offsets, offset)
+	}
+
+	return m.encodeSymbol(enc, unicodeEOS)
+}
+
+// DecodeString decodes a string written by EncodeString and updates the model.
+func (m *UnicodeModel) DecodeString(dec *coder.Decoder) (string, error) {
+	var result []byte
+	for {
+		symbol, err := dec.Decode(m.pageModel)
+		if err != nil {
+			return "", err
+		}
+		adaptTable(m.pageModel, symbol)
+
+		if symbol == unicodeEOS {
+			return string(result), nil
+		}
+		if len(result) >= unicodeMaxStringLength {
+			return "", errors.New("unicode: string exceeds maximum length")
+		}
+
+		if symbol == unicodeRawByte {
+			b, err := dec.Decode(m.byteModel)
+			if err != nil {
+				return "", err
+			}
+			result = append(result, byte(b))
+			continue
+		}
+
+		var page int
+		position := symbol
+		if symbol == unicodeNewPage {
+			page, err = dec.Decode(m.pageIndexModel)
+			if err != nil {
+				return "", err
+			}
+			position = len(m.recent) - 1
+		} else {
+			page = m.recent[position]
+		}
+		m.moveToFront(position, page)
+
+		offsets := m.offsetModel(page)
+		offset, err := dec.Decode(offsets)
+		if err != nil {
+			return "", err
+		}
+		adaptTable(offsets, offset)
+
+		r := rune(page<<unicodePageBits | offset)
+		if !utf8.ValidRune(r) {
+			return "", errors.New("unicode: invalid codepoint")
+		}
+		result = utf8.AppendRune(result, r)
+	}
+}
+
+// encodeSymbol codes a page selection symbol and adapts the page model.
+func (m *UnicodeModel) encodeSymbol(enc *coder.Encoder, symbol int) error {
+	if err := enc.Encode(symbol, m.pageModel); err != nil {
+		return err
+	}
+	adaptTable(m.pageModel, symbol)
+	return nil
+}
+
+// recentPosition returns the position of page in the recent list, or -1.
+func (m *UnicodeModel) recentPosition(page int) int {
+	for i, p := range m.recent {
+		if p == page {
+			return i
+		}
+	}
+	return -1
+}
+
+// moveToFront moves page to the front of the recent list, dropping the
+// entry at position.
+func (m *UnicodeModel) moveToFront(position, page int) {
+	copy(m.recent[1:position+1], m.recent[:position])
+	m.recent[0] = page
+}
+
+// offsetModel returns the offset table for page, creating it on first use.
+func (m *UnicodeModel) offsetModel(page int) *FrequencyTable {
+	if model, ok := m.offsets[page]; ok {
+		return model
+	}
+
+	freqs := make([]uint64, unicodePageSize)
+	for i := range freqs {
+		freqs[i] = 1
+	}
+	if page == 0 {
+		// Bias ASCII towards printable text
+		for ch := ' '; ch <= '~'; ch++ {
+			freqs[ch] = 4
+		}
+		for ch := 'a'; ch <= 'z'; ch++ {
+			freqs[ch] = 16
+		}
+		for _, ch := range "etaoinsrhl" {
+			freqs[ch] = 32
+		}
+		freqs[' '] = 64
+		freqs['\n'] = 4
+	}
+
+	model := newAdaptiveTable(freqs)
+	m.offsets[page] = model
+	return model
+}
+
+// adaptTable increments the count of symbol. Counts are halved when the
+// total exceeds unicodeMaxTotal.
+func adaptTable(ft *FrequencyTable, symbol int) {
+	ft.Add(symbol, unicodeIncrement)
+}
+
+// newAdaptiveTable creates a table for adaptTable.
+func newAdaptiveTable(freqs []uint64) *FrequencyTable {
+	return newAdaptiveFrequencyTable(freqs, unicodeMaxTotal)
+}
+
+// EncodeStringUnicode encodes a string using a fresh Unicode model.
+func EncodeStringUnicode(s string, w io.Writer) error {
+	enc := coder.NewEncoder(w)
+	if err := NewUnicodeModel().EncodeString(enc, s); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// DecodeStringUnicode decodes a string written by EncodeStringUnicode.
+func DecodeStringUnicode(r io.Reader) (string, error) {
+	dec, err := coder.NewDecoder(r)
+	if err != nil {
+		return "", err
+	}
+	return NewUnicodeModel().DecodeString(dec)
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUnicodeRoundtrip(t *testing.T) {
+	tests := []string{
+		"",
+		"Hello, World!",
+		"Grüße aus München, schöne Brücke",
+		"Привет, как дела? Всё хорошо.",
+		"こんにちは、元気ですか？",
+		"我们在山顶等你",
+		"Emoji 👍🏽 and flags 🇪🇪",
+		"Invalid \xff\xfe bytes and �",
+		"\U0010FFFF edge",
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := EncodeStringUnicode(test, &buf); err != nil {
+			t.Fatalf("EncodeStringUnicode(%q) failed: %v", test, err)
+		}
+
+		result, err := DecodeStringUnicode(&buf)
+		if err != nil {
+			t.Fatalf("DecodeStringUnicode(%q) failed: %v", test, err)
+		}
+		if result != test {
+			t.Errorf("Decoded string doesn't match:\noriginal: %q\nresult:   %q", test, result)
+		}
+	}
+}
+
+func TestUnicodeVsOrder2(t *testing.T) {
+	tests := []string{
+		"Привет, как дела? Мы уже на вершине, ждём вас.",
+		"こんにちは、元気ですか？今どこにいますか？",
+		"Ελάτε στην καλύβα, έχουμε φαγητό και νερό.",
+		"Çok güzel bir gün, dağın tepesindeyiz.",
+	}
+
+	for _, test := range tests {
+		var bufOrder2, bufUnicode bytes.Buffer
+		if err := EncodeStringOrder2(test, &bufOrder2); err != nil {
+			t.Fatalf("Order-2 encode failed: %v", err)
+		}
+		if err := EncodeStringUnicode(test, &bufUnicode); err != nil {
+			t.Fatalf("Unicode encode failed: %v", err)
+		}
+
+		t.Logf("%q: raw %d bytes, order-2 %d bytes, unicode %d bytes", test, len(test), bufOrder2.Len(), bufUnicode.Len())
+		if bufUnicode.Len() >= bufOrder2.Len() {
+			t.Errorf("Unicode model (%d bytes) should beat order-2 (%d bytes)", bufUnicode.Len(), bufOrder2.Len())
+		}
+		if bufUnicode.Len() >= len(test) {
+			t.Errorf("Unicode model (%d bytes) should not expand the text (%d bytes)", bufUnicode.Len(), len(test))
+		}
+	}
+}
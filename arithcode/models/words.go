@@ -0,0 +1,291 @@
+package models
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// Letter case variants of dictionary words.
+const (
+	wordCaseLower = iota // "hello"
+	wordCaseTitle        // "Hello"
+	wordCaseUpper        // "HELLO"
+)
+
+// wordTokenBoost is added to a token's frequency each time it is coded,
+// so words repeated within a text become cheap.
+const wordTokenBoost = 64
+
+// WordModel is a word-level model for English text.
+//
+// Text is split into tokens: runs of letters (words) and single other
+// characters. Dictionary words are coded as a single symbol followed by their
+// letter case, common punctuation, digits and whitespace are coded as single
+// symbols, and anything else is coded with an escape symbol followed by the
+// token spelled out with the English character model.
+//
+// Token frequencies adapt while coding, so the encoder and decoder must code
+// the same strings in the same order with identically initialized models.
+type WordModel struct {
+	tokens      []string       // Token for each symbol; words followed by characters
+	tokenSymbol map[string]int // Inverse of tokens
+	numWords    int            // Number of dictionary words at the start of tokens
+	table       *FrequencyTable
+
+	escapeSymbol int // Token spelled out with the character model
+	eosSymbol    int // End of string
+
+	caseModel *FrequencyTable
+	charModel *EnglishModel
+}
+
+// NewWordModel creates a word model with the built-in English dictionary.
+func NewWordModel() *WordModel {
+	words := strings.Fields(englishWords)
+	chars := []string{
+		" ", ".", ",", "!", "?", "'", "\"", ":", ";", "-", "(", ")", "\n",
+		"0", "1", "2", "3", "4", "5", "6", "7", "8", "9",
+		"/", "@", "#", "%", "&", "+", "=", "*", "_", "\t",
+	}
+	charFreqs := []uint64{
+		8000, 900, 700, 400, 400, 100, 100, 150, 50, 200, 50, 50, 100,
+		300, 300, 200, 200, 150, 150, 150, 150, 150, 150,
+		80, 40, 40, 40, 40, 30, 30, 30, 30, 20,
+	}
+
+	wm := &WordModel{
+		tokenSymbol: make(map[string]int, len(words)+len(chars)),
+		caseModel:   NewFrequencyTable([]uint64{90, 25, 5}),
+		charModel:   SharedEnglishModelEOS(),
+	}
+
+	var freqs []uint64
+	for _, word := range words {
+		if _, exists := wm.tokenSymbol[word]; exists {
+			continue
+		}
+		rank := len(wm.tokens)
+		wm.tokenSymbol[word] = rank
+		wm.tokens = append(wm.tokens, word)
+		// Zipf's law: frequency is inversely proportional to rank
+		freqs = append(freqs, 1+20000/uint64(rank+1))
+	}
+	wm.numWords = len(wm.tokens)
+	for i, ch := range chars {
+		wm.tokenSymbol[ch] = len(wm.tokens)
+		wm.tokens = append(wm.tokens, ch)
+		freqs = append(freqs, charFreqs[i])
+	}
+
+	wm.escapeSymbol = len(freqs)
+	freqs = append(freqs, 400)
+	wm.eosSymbol = len(freqs)
+	freqs = append(freqs, 600)
+
+	wm.table = NewFrequencyTable(freqs)
+	return wm
+}
+
+func (wm *WordModel) SymbolCount() int {
+	return wm.table.SymbolCount()
+}
+
+func (wm *WordModel) Freq(symbol int) (low, high uint64) {
+	return wm.table.Freq(symbol)
+}
+
+func (wm *WordModel) TotalFreq() uint64 {
+	return wm.table.TotalFreq()
+}
+
+func (wm *WordModel) Find(cumFreq uint64) int {
+	return wm.table.Find(cumFreq)
+}
+
+func (wm *WordModel) Cost(symbol int) float64 {
+	return wm.table.Cost(symbol)
+}
+
+// EncodeString encodes s into enc, terminated by an end-of-string symbol.
+func (wm *WordModel) EncodeString(enc *coder.Encoder, s string) error {
+	for len(s) > 0 {
+		token := nextWordToken(s)
+		s = s[len(token):]
+
+		if symbol, letterCase, ok := wm.lookup(token); ok {
+			if err := wm.encodeToken(enc, symbol); err != nil {
+				return err
+			}
+			if symbol < wm.numWords {
+				if err := enc.Encode(letterCase, wm.caseModel); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := wm.encodeToken(enc, wm.escapeSymbol); err != nil {
+			return err
+		}
+		if err := EncodeStringEOS(enc, wm.charModel, token); err != nil {
+			return err
+		}
+	}
+
+	return enc.Encode(wm.eosSymbol, wm.table)
+}
+
+// DecodeString decodes a string written by EncodeString.
+func (wm *WordModel) DecodeString(dec *coder.Decoder) (string, error) {
+	var result strings.Builder
+	for {
+		symbol, err := dec.Decode(wm.table)
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case symbol == wm.eosSymbol:
+			return result.String(), nil
+
+		case symbol == wm.escapeSymbol:
+			token, err := DecodeStringEOS(dec, wm.charModel)
+			if err != nil {
+				return "", err
+			}
+			if token == "" {
+				return "", errors.New("word model: empty escaped token")
+			}
+			result.WriteString(token)
+
+		case symbol < wm.numWords:
+			letterCase, err := dec.Decode(wm.caseModel)
+			if err != nil {
+				return "", err
+			}
+			result.WriteString(applyWordCase(wm.tokens[symbol], letterCase))
+
+		default:
+			result.WriteString(wm.tokens[symbol])
+		}
+
+		wm.table.Add(symbol, wordTokenBoost)
+	}
+}
+
+// encodeToken codes a token symbol and adapts its frequency.
+func (wm *WordModel) encodeToken(enc *coder.Encoder, symbol int) error {
+	if err := enc.Encode(symbol, wm.table); err != nil {
+		return err
+	}
+	wm.table.Add(symbol, wordTokenBoost)
+	return nil
+}
+
+// lookup finds the symbol and letter case for a token.
+func (wm *WordModel) lookup(token string) (symbol, letterCase int, ok bool) {
+	if symbol, ok := wm.tokenSymbol[token]; ok && symbol >= wm.numWords {
+		return symbol, 0, true
+	}
+
+	lower := strings.ToLower(token)
+	symbol, ok = wm.tokenSymbol[lower]
+	if !ok || symbol >= wm.numWords {
+		return 0, 0, false
+	}
+
+	for letterCase := wordCaseLower; letterCase <= wordCaseUpper; letterCase++ {
+		if applyWordCase(lower, letterCase) == token {
+			return symbol, letterCase, true
+		}
+	}
+	return 0, 0, false
+}
+
+// applyWordCase converts a lowercase dictionary word to the given case.
+func applyWordCase(word string, letterCase int) string {
+	switch letterCase {
+	case wordCaseTitle:
+		r, size := utf8.DecodeRuneInString(word)
+		return string(unicode.ToUpper(r)) + word[size:]
+	case wordCaseUpper:
+		return strings.ToUpper(word)
+	default:
+		return word
+	}
+}
+
+// nextWordToken returns the first token of s: a run of letters (allowing
+// inner apostrophes, as in "don't") or a single other character.
+func nextWordToken(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if !unicode.IsLetter(r) {
+		return s[:size]
+	}
+
+	end := size
+	for end < len(s) {
+		r, size := utf8.DecodeRuneInString(s[end:])
+		if unicode.IsLetter(r) {
+			end += size
+			continue
+		}
+		if r == '\'' && end+size < len(s) {
+			next, _ := utf8.DecodeRuneInString(s[end+size:])
+			if unicode.IsLetter(next) {
+				end += size
+				continue
+			}
+		}
+		break
+	}
+	return s[:end]
+}
+
+// EncodeStringWords encodes a string using the word model.
+func EncodeStringWords(s string, w io.Writer) error {
+	enc := coder.NewEncoder(w)
+	if err := NewWordModel().EncodeString(enc, s); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// DecodeStringWords decodes a string written by EncodeStringWords.
+func DecodeStringWords(r io.Reader) (string, error) {
+	dec, err := coder.NewDecoder(r)
+	if err != nil {
+		return "", err
+	}
+	return NewWordModel().DecodeString(dec)
+}
+
+// englishWords lists common English words by decreasing frequency,
+// followed by words common in short mesh network messages.
+const englishWords = `
+the be to of and a in that have i it for not on with he as you do at
+this but his by from they we say her she or an will my one all would there
+their what so up out if about who get which go me when make can like time no
+just him know take people into year your good some could them see other than
+then now look only come its over think also back after use two how our work
+first well way even new want because any these give day most us is are was
+were been has had did said going am don't i'm it's that's can't won't didn't
+here where why very much more many thing still should need let got let's
+right down off too again never always before through while around home
+today tomorrow tonight morning night evening afternoon week hour hours
+minutes minute soon later late early ok okay yes yeah hello hi hey thanks
+thank please sorry sure great nice fine help stop wait ready check test
+message messages send sent receive received reply signal node nodes mesh
+network radio channel battery power solar antenna range location position
+map gps weather rain wind cold hot snow trail camp base road bridge river
+lake park hill mountain north south east west near far left
+team group meet meeting call back way road car walk walking heading coming
+leaving arrived here there safe everyone anyone someone something nothing
+all good bad best better fire water food need needs lost found copy roger
+over out clear loud update status online offline working test testing
+`
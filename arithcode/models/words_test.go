@@ -0,0 +1,64 @@
+package models
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWordModelRoundtrip(t *testing.T) {
+	tests := []string{
+		"",
+		"hello",
+		"Hello World",
+		"THE QUICK brown Fox",
+		"I don't think it's going to rain today.",
+		"Meet at the trail head at 10:30, bring water!",
+		"Unicode: café, naïve, 日本語",
+		"McDonald's xyzzy   spaces\tand\nnewlines",
+		"trailing apostrophe' and 'quoted'",
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeStringWords(test, &buf); err != nil {
+				t.Fatalf("EncodeStringWords failed: %v", err)
+			}
+
+			result, err := DecodeStringWords(&buf)
+			if err != nil {
+				t.Fatalf("DecodeStringWords failed: %v", err)
+			}
+			if result != test {
+				t.Errorf("Decoded string doesn't match:\noriginal: %q\nresult:   %q", test, result)
+			}
+		})
+	}
+}
+
+func TestWordModelVsOrder1(t *testing.T) {
+	testStrings := []string{
+		"Hello! How are you today? I hope you are doing well. Have a great day!",
+		"We are heading back to the camp now, meet us at the parking lot near the north road.",
+		"The battery on the solar node is low, I will check the antenna and the power in the morning.",
+	}
+
+	var totalOrder1, totalWords int
+	for _, test := range testStrings {
+		var bufOrder1, bufWords bytes.Buffer
+		if err := EncodeStringOrder1(test, &bufOrder1); err != nil {
+			t.Fatalf("Order-1 encode failed: %v", err)
+		}
+		if err := EncodeStringWords(test, &bufWords); err != nil {
+			t.Fatalf("Word encode failed: %v", err)
+		}
+		totalOrder1 += bufOrder1.Len()
+		totalWords += bufWords.Len()
+
+		t.Logf("%q: order-1 %d bytes, words %d bytes", test, bufOrder1.Len(), bufWords.Len())
+	}
+
+	if totalWords >= totalOrder1 {
+		t.Errorf("Word model (%d bytes) should beat order-1 (%d bytes)", totalWords, totalOrder1)
+	}
+}
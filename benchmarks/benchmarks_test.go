@@ -0,0 +1,100 @@
+package benchmarks
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestCodecsRoundtrip(t *testing.T) {
+	training, corpus := FixtureCorpus()
+	codecs, err := Codecs(training)
+	if err != nil {
+		t.Fatalf("Codecs failed: %v", err)
+	}
+	results, err := Run("meshfixtures", corpus, codecs, 0)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(results.Codecs) != len(codecs) {
+		t.Fatalf("got %d results for %d codecs", len(results.Codecs), len(codecs))
+	}
+
+	byName := map[string]Result{}
+	for _, result := range results.Codecs {
+		byName[result.Codec] = result
+		t.Logf("%-20s %6d bytes  ratio %.3f", result.Codec, result.CompressedBytes, result.Ratio)
+	}
+	if got := byName["protobuf"]; got.CompressedBytes != results.Corpus.Bytes || got.Ratio != 1 {
+		t.Errorf("protobuf: %d bytes with ratio %v, want the corpus size %d", got.CompressedBytes, got.Ratio, results.Corpus.Bytes)
+	}
+	if byName["zstd-dict"].DictionaryBytes == 0 {
+		t.Errorf("zstd-dict has no dictionary size")
+	}
+	if dict, plain := byName["zstd-dict"].CompressedBytes, byName["zstd"].CompressedBytes; dict >= plain {
+		t.Errorf("zstd-dict %d bytes, not smaller than zstd %d bytes", dict, plain)
+	}
+
+	var buf bytes.Buffer
+	if _, err := results.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	read, err := ReadResults(&buf)
+	if err != nil {
+		t.Fatalf("ReadResults failed: %v", err)
+	}
+	if read.Corpus != results.Corpus || len(read.Codecs) != len(results.Codecs) {
+		t.Errorf("ReadResults = %+v, want %+v", read, results)
+	}
+}
+
+func benchmarkCodecs(b *testing.B, run func(b *testing.B, codec Codec, corpus []proto.Message)) {
+	training, corpus := FixtureCorpus()
+	codecs, err := Codecs(training)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, codec := range codecs {
+		b.Run(codec.Name, func(b *testing.B) {
+			var size, compressed int
+			for _, msg := range corpus {
+				data, err := codec.Compress(msg)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size += proto.Size(msg)
+				compressed += len(data)
+			}
+			b.ReportAllocs()
+			run(b, codec, corpus)
+			b.ReportMetric(float64(compressed)/float64(len(corpus)), "B/msg")
+			b.ReportMetric(float64(compressed)/float64(size), "ratio")
+		})
+	}
+}
+
+func BenchmarkCompress(b *testing.B) {
+	benchmarkCodecs(b, func(b *testing.B, codec Codec, corpus []proto.Message) {
+		for i := 0; b.Loop(); i++ {
+			if _, err := codec.Compress(corpus[i%len(corpus)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecompress(b *testing.B) {
+	benchmarkCodecs(b, func(b *testing.B, codec Codec, corpus []proto.Message) {
+		compressed := make([][]byte, len(corpus))
+		for i, msg := range corpus {
+			compressed[i], _ = codec.Compress(msg)
+		}
+		for i := 0; b.Loop(); i++ {
+			msg := corpus[i%len(corpus)].ProtoReflect().New().Interface()
+			if err := codec.Decompress(compressed[i%len(corpus)], msg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
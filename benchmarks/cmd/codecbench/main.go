@@ -0,0 +1,39 @@
+// Command codecbench compares the Meshtastic codecs with general purpose
+// compressors on the fixture messages and writes the results as JSON, see
+// benchmarks.Results.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/egonelbre/exp-protobuf-compression/benchmarks"
+)
+
+func main() {
+	duration := flag.Duration("duration", time.Second, "minimum time to measure each speed")
+	flag.Parse()
+
+	if err := run(os.Stdout, *duration); err != nil {
+		fmt.Fprintln(os.Stderr, "codecbench:", err)
+		os.Exit(1)
+	}
+}
+
+// run benchmarks the codecs on the fixtures and writes the results to w.
+func run(w io.Writer, duration time.Duration) error {
+	training, corpus := benchmarks.FixtureCorpus()
+	codecs, err := benchmarks.Codecs(training)
+	if err != nil {
+		return err
+	}
+	results, err := benchmarks.Run("meshfixtures", corpus, codecs, duration)
+	if err != nil {
+		return err
+	}
+	_, err = results.WriteTo(w)
+	return err
+}
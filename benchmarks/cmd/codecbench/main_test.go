@@ -0,0 +1,22 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/benchmarks"
+)
+
+func TestRun(t *testing.T) {
+	var buf bytes.Buffer
+	if err := run(&buf, 0); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	results, err := benchmarks.ReadResults(&buf)
+	if err != nil {
+		t.Fatalf("ReadResults failed: %v", err)
+	}
+	if len(results.Codecs) == 0 || results.Corpus.Messages == 0 {
+		t.Errorf("empty results: %+v", results)
+	}
+}
@@ -0,0 +1,276 @@
+// Package benchmarks compares the Meshtastic codecs with general purpose
+// compressors on the same messages.
+//
+// Every message is compressed on its own, as it would be sent over the mesh,
+// so the general purpose compressors pay their framing overhead on every
+// message. The comparison that matters for small messages is zstd with a
+// dictionary trained on similar messages, see TrainZstdDict.
+package benchmarks
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+)
+
+// Codec compresses single messages.
+type Codec struct {
+	Name string
+	// Dictionary is the size of the data that both ends need besides the
+	// codec, such as a trained zstd dictionary.
+	Dictionary int
+	Compress   func(msg proto.Message) ([]byte, error)
+	Decompress func(data []byte, msg proto.Message) error
+}
+
+// Codecs returns the standard codecs followed by every Meshtastic version.
+// The zstd dictionary is trained on training, which should not include the
+// benchmarked messages.
+func Codecs(training []proto.Message) ([]Codec, error) {
+	zstdDict, err := TrainZstdDict(training)
+	if err != nil {
+		return nil, err
+	}
+	withDict, err := ZstdDict(zstdDict)
+	if err != nil {
+		return nil, err
+	}
+
+	codecs := []Codec{Protobuf(), Gzip(), Deflate(), Snappy(), Zstd(), withDict}
+	for _, version := range meshtasticmodel.Versions {
+		codecs = append(codecs, Version(version))
+	}
+	return codecs, nil
+}
+
+// Protobuf returns the uncompressed protobuf wire format.
+func Protobuf() Codec {
+	return Codec{
+		Name:       "protobuf",
+		Compress:   func(msg proto.Message) ([]byte, error) { return proto.Marshal(msg) },
+		Decompress: func(data []byte, msg proto.Message) error { return proto.Unmarshal(data, msg) },
+	}
+}
+
+// Version returns a Meshtastic codec version. It leaves out the format
+// header, so that the sizes are the ones of the coding.
+func Version(version meshtasticmodel.Version) Codec {
+	return Codec{
+		Name: version.Name,
+		Compress: func(msg proto.Message) ([]byte, error) {
+			var buf bytes.Buffer
+			if err := version.Compress(msg, &buf, meshtasticmodel.WithoutHeader()); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		},
+		Decompress: func(data []byte, msg proto.Message) error {
+			return version.Decompress(bytes.NewReader(data), msg, meshtasticmodel.WithoutHeader())
+		},
+	}
+}
+
+// Gzip returns gzip at the best compression level.
+func Gzip() Codec {
+	writers := sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.BestCompression)
+		return w
+	}}
+	var readers sync.Pool
+	return Codec{
+		Name: "gzip",
+		Compress: func(msg proto.Message) ([]byte, error) {
+			w := writers.Get().(*gzip.Writer)
+			defer writers.Put(w)
+			return compressStream(msg, w, w.Reset)
+		},
+		Decompress: func(data []byte, msg proto.Message) error {
+			r, _ := readers.Get().(*gzip.Reader)
+			if r == nil {
+				var err error
+				if r, err = gzip.NewReader(bytes.NewReader(data)); err != nil {
+					return err
+				}
+			} else if err := r.Reset(bytes.NewReader(data)); err != nil {
+				return err
+			}
+			defer readers.Put(r)
+			return decompressStream(r, msg)
+		},
+	}
+}
+
+// Deflate returns raw deflate at the best compression level, which is gzip
+// without its header and checksum.
+func Deflate() Codec {
+	writers := sync.Pool{New: func() any {
+		w, _ := flate.NewWriter(nil, flate.BestCompression)
+		return w
+	}}
+	var readers sync.Pool
+	return Codec{
+		Name: "deflate",
+		Compress: func(msg proto.Message) ([]byte, error) {
+			w := writers.Get().(*flate.Writer)
+			defer writers.Put(w)
+			return compressStream(msg, w, w.Reset)
+		},
+		Decompress: func(data []byte, msg proto.Message) error {
+			r, _ := readers.Get().(io.ReadCloser)
+			if r == nil {
+				r = flate.NewReader(bytes.NewReader(data))
+			} else if err := r.(flate.Resetter).Reset(bytes.NewReader(data), nil); err != nil {
+				return err
+			}
+			defer readers.Put(r)
+			return decompressStream(r, msg)
+		},
+	}
+}
+
+// compressStream compresses the wire format of msg with w, which reset
+// points at a new output.
+func compressStream(msg proto.Message, w io.WriteCloser, reset func(io.Writer)) ([]byte, error) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	reset(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressStream decodes msg from the wire format read from r.
+func decompressStream(r io.Reader, msg proto.Message) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+// Snappy returns Snappy block compression at the best level.
+func Snappy() Codec {
+	return Codec{
+		Name: "snappy",
+		Compress: func(msg proto.Message) ([]byte, error) {
+			data, err := proto.Marshal(msg)
+			if err != nil {
+				return nil, err
+			}
+			return s2.EncodeSnappyBest(nil, data), nil
+		},
+		Decompress: func(data []byte, msg proto.Message) error {
+			raw, err := s2.Decode(nil, data)
+			if err != nil {
+				return err
+			}
+			return proto.Unmarshal(raw, msg)
+		},
+	}
+}
+
+// Zstd returns zstd at the best compression level.
+func Zstd() Codec {
+	codec, err := newZstd("zstd", nil)
+	if err != nil {
+		panic(err)
+	}
+	return codec
+}
+
+// ZstdDict returns zstd at the best compression level with a dictionary
+// made by TrainZstdDict. At that level the encoder loads the dictionary for
+// every message, which makes compression orders of magnitude slower than at
+// the lower levels, where the dictionary barely helps.
+func ZstdDict(zstdDict []byte) (Codec, error) {
+	return newZstd("zstd-dict", zstdDict)
+}
+
+// newZstd returns a zstd codec with an optional dictionary. The frames have
+// no checksum, since the other codecs do not have one either.
+func newZstd(name string, zstdDict []byte) (Codec, error) {
+	encoderOptions := []zstd.EOption{
+		zstd.WithEncoderLevel(zstd.SpeedBestCompression),
+		zstd.WithEncoderCRC(false),
+		zstd.WithEncoderConcurrency(1),
+	}
+	decoderOptions := []zstd.DOption{zstd.WithDecoderConcurrency(1)}
+	if zstdDict != nil {
+		encoderOptions = append(encoderOptions, zstd.WithEncoderDict(zstdDict))
+		decoderOptions = append(decoderOptions, zstd.WithDecoderDicts(zstdDict))
+	}
+
+	encoder, err := zstd.NewWriter(nil, encoderOptions...)
+	if err != nil {
+		return Codec{}, fmt.Errorf("%s: %w", name, err)
+	}
+	decoder, err := zstd.NewReader(nil, decoderOptions...)
+	if err != nil {
+		return Codec{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return Codec{
+		Name:       name,
+		Dictionary: len(zstdDict),
+		Compress: func(msg proto.Message) ([]byte, error) {
+			data, err := proto.Marshal(msg)
+			if err != nil {
+				return nil, err
+			}
+			return encoder.EncodeAll(data, nil), nil
+		},
+		Decompress: func(data []byte, msg proto.Message) error {
+			raw, err := decoder.DecodeAll(data, nil)
+			if err != nil {
+				return err
+			}
+			return proto.Unmarshal(raw, msg)
+		},
+	}, nil
+}
+
+// maxZstdDict is the size limit of trained dictionaries; zstd recommends
+// dictionaries of about a hundred times the size of the messages.
+const maxZstdDict = 16 << 10
+
+// zstdDictID identifies the trained dictionaries in zstd frames; a fixed ID
+// keeps the benchmark results reproducible.
+const zstdDictID = 0x6d657368
+
+// TrainZstdDict trains a zstd dictionary on the wire format of samples.
+func TrainZstdDict(samples []proto.Message) ([]byte, error) {
+	input := make([][]byte, len(samples))
+	for i, msg := range samples {
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("sample %d: %w", i, err)
+		}
+		input[i] = data
+	}
+	zstdDict, err := dict.BuildZstdDict(input, dict.Options{
+		MaxDictSize: maxZstdDict,
+		HashBytes:   6,
+		ZstdDictID:  zstdDictID,
+		ZstdLevel:   zstd.SpeedBestCompression,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("training zstd dictionary: %w", err)
+	}
+	return zstdDict, nil
+}
@@ -0,0 +1,22 @@
+package benchmarks
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+)
+
+// FixtureCorpus splits the messages of the fixture scenarios into a
+// training set for the zstd dictionary and a corpus to benchmark, by
+// alternating scenarios, so that zstd-dict is not measured on the messages
+// it was trained on.
+func FixtureCorpus() (training, corpus []proto.Message) {
+	for i, s := range meshfixtures.Scenarios() {
+		if i%2 == 0 {
+			training = append(training, meshfixtures.Messages(s)...)
+		} else {
+			corpus = append(corpus, meshfixtures.Messages(s)...)
+		}
+	}
+	return training, corpus
+}
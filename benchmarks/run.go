@@ -0,0 +1,171 @@
+package benchmarks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+)
+
+// Schema is the version of the format of Results. It changes when fields
+// are removed or change meaning, not when fields are added.
+const Schema = 1
+
+// Results are the results of compressing a corpus with a set of codecs.
+// Sizes only depend on the corpus, times also depend on the machine.
+type Results struct {
+	Schema   int                        `json:"schema"`
+	Platform string                     `json:"platform"` // GOOS/GOARCH and the Go version
+	Corpus   meshtasticmodel.CorpusInfo `json:"corpus"`
+	Codecs   []Result                   `json:"codecs"`
+}
+
+// Result are the results of a codec on the corpus. The times and
+// allocations are averages per message.
+type Result struct {
+	Codec                 string  `json:"codec"`
+	DictionaryBytes       int     `json:"dictionary_bytes,omitempty"` // See Codec.Dictionary
+	CompressedBytes       int64   `json:"compressed_bytes"`
+	Ratio                 float64 `json:"ratio"` // Compressed size relative to the wire format
+	CompressNsPerOp       float64 `json:"compress_ns_per_op"`
+	CompressAllocsPerOp   float64 `json:"compress_allocs_per_op"`
+	DecompressNsPerOp     float64 `json:"decompress_ns_per_op"`
+	DecompressAllocsPerOp float64 `json:"decompress_allocs_per_op"`
+}
+
+// Run compresses and decompresses the corpus with every codec, repeating
+// each for at least minDuration to measure its speed. It fails when a codec
+// does not roundtrip the corpus.
+func Run(name string, corpus []proto.Message, codecs []Codec, minDuration time.Duration) (*Results, error) {
+	hash, err := meshtasticmodel.CorpusHash(corpus)
+	if err != nil {
+		return nil, err
+	}
+	results := &Results{
+		Schema:   Schema,
+		Platform: fmt.Sprintf("%s/%s, %s", runtime.GOOS, runtime.GOARCH, runtime.Version()),
+		Corpus:   meshtasticmodel.CorpusInfo{Name: name, Hash: hash, Messages: len(corpus)},
+	}
+	for _, msg := range corpus {
+		results.Corpus.Bytes += int64(proto.Size(msg))
+	}
+
+	for _, codec := range codecs {
+		result, err := runCodec(codec, corpus, results.Corpus.Bytes, minDuration)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", codec.Name, err)
+		}
+		results.Codecs = append(results.Codecs, result)
+	}
+	return results, nil
+}
+
+// runCodec measures a codec on the corpus of size bytes.
+func runCodec(codec Codec, corpus []proto.Message, size int64, minDuration time.Duration) (Result, error) {
+	result := Result{Codec: codec.Name, DictionaryBytes: codec.Dictionary}
+
+	compressed := make([][]byte, len(corpus))
+	for i, msg := range corpus {
+		data, err := codec.Compress(msg)
+		if err != nil {
+			return result, fmt.Errorf("message %d: %w", i, err)
+		}
+		compressed[i] = data
+		result.CompressedBytes += int64(len(data))
+
+		decoded := msg.ProtoReflect().New().Interface()
+		if err := codec.Decompress(data, decoded); err != nil {
+			return result, fmt.Errorf("message %d: %w", i, err)
+		}
+		if !proto.Equal(msg, decoded) {
+			return result, fmt.Errorf("message %d: roundtrip mismatch", i)
+		}
+	}
+	if size > 0 {
+		result.Ratio = float64(result.CompressedBytes) / float64(size)
+	}
+
+	compress := func() error {
+		for _, msg := range corpus {
+			if _, err := codec.Compress(msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	decompressed := make([]proto.Message, len(corpus))
+	for i, msg := range corpus {
+		decompressed[i] = msg.ProtoReflect().New().Interface()
+	}
+	decompress := func() error {
+		for i, data := range compressed {
+			proto.Reset(decompressed[i])
+			if err := codec.Decompress(data, decompressed[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var err error
+	if result.CompressNsPerOp, result.CompressAllocsPerOp, err = measure(compress, len(corpus), minDuration); err != nil {
+		return result, err
+	}
+	if result.DecompressNsPerOp, result.DecompressAllocsPerOp, err = measure(decompress, len(corpus), minDuration); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// measure runs f, which processes n messages, until minDuration has passed
+// and returns the nanoseconds and allocations per message.
+func measure(f func() error, n int, minDuration time.Duration) (nsPerOp, allocsPerOp float64, err error) {
+	if n == 0 {
+		return 0, 0, nil
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	var runs int
+	for {
+		if err := f(); err != nil {
+			return 0, 0, err
+		}
+		runs++
+		if time.Since(start) >= minDuration {
+			break
+		}
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	ops := float64(runs * n)
+	return float64(elapsed.Nanoseconds()) / ops, float64(after.Mallocs-before.Mallocs) / ops, nil
+}
+
+// WriteTo writes the results as indented JSON.
+func (r *Results) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// ReadResults reads results written by Results.WriteTo.
+func ReadResults(r io.Reader) (*Results, error) {
+	var results Results
+	if err := json.NewDecoder(r).Decode(&results); err != nil {
+		return nil, fmt.Errorf("reading benchmark results: %w", err)
+	}
+	if results.Schema != Schema {
+		return nil, fmt.Errorf("unsupported benchmark results schema %d", results.Schema)
+	}
+	return &results, nil
+}
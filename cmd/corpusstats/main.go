@@ -0,0 +1,133 @@
+// Command corpusstats collects per-field statistics from a live stream of
+// protobuf messages and periodically writes them to a file for model training.
+//
+// Messages are read from stdin as varint length-delimited protobuf messages,
+// for example:
+//
+//	mqtt-dump | corpusstats -type meshtastic.MeshPacket -out stats.json
+//
+// When the output file already exists, its statistics are loaded first so that
+// collection continues where the previous run stopped.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/egonelbre/exp-protobuf-compression/corpusstats"
+	_ "github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func main() {
+	typeName := flag.String("type", "meshtastic.MeshPacket", "full name of the message type")
+	out := flag.String("out", "stats.json", "output statistics file")
+	interval := flag.Duration("interval", time.Minute, "interval between statistics writes")
+	flag.Parse()
+
+	if err := run(*typeName, *out, *interval); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(typeName, out string, interval time.Duration) error {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(typeName))
+	if err != nil {
+		return fmt.Errorf("message type %q: %w", typeName, err)
+	}
+
+	collector := corpusstats.NewCollector()
+	if err := loadStats(collector, out); err != nil {
+		return err
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- readMessages(os.Stdin, mt, collector)
+	}()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := writeStats(collector, out); err != nil {
+				log.Printf("write stats: %v", err)
+			}
+		case <-interrupt:
+			return writeStats(collector, out)
+		case err := <-readErr:
+			if werr := writeStats(collector, out); werr != nil {
+				return werr
+			}
+			return err
+		}
+	}
+}
+
+// readMessages observes length-delimited messages from r until EOF.
+func readMessages(r io.Reader, mt protoreflect.MessageType, collector *corpusstats.Collector) error {
+	br := bufio.NewReader(r)
+	for {
+		msg := mt.New().Interface()
+		if err := protodelim.UnmarshalFrom(br, msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		collector.Observe(msg)
+	}
+}
+
+// loadStats loads existing statistics from path, if the file exists.
+func loadStats(collector *corpusstats.Collector, path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stats, err := corpusstats.ReadStats(f)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", path, err)
+	}
+	collector.Load(stats)
+	return nil
+}
+
+// writeStats atomically replaces path with the current statistics.
+func writeStats(collector *corpusstats.Collector, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := collector.Snapshot().WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
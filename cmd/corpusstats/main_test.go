@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	"github.com/egonelbre/exp-protobuf-compression/corpusstats"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestReadAndWriteStats(t *testing.T) {
+	var stream bytes.Buffer
+	for i := 0; i < 5; i++ {
+		packet := &meshtastic.MeshPacket{From: 0x12345678, Id: uint32(i + 1), HopLimit: 3}
+		if _, err := protodelim.MarshalTo(&stream, packet); err != nil {
+			t.Fatalf("MarshalTo failed: %v", err)
+		}
+	}
+
+	collector := corpusstats.NewCollector()
+	mt := (&meshtastic.MeshPacket{}).ProtoReflect().Type()
+	if err := readMessages(&stream, mt, collector); err != nil {
+		t.Fatalf("readMessages failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "stats.json")
+	if err := writeStats(collector, path); err != nil {
+		t.Fatalf("writeStats failed: %v", err)
+	}
+
+	// Loading continues the collection where the previous run stopped
+	loaded := corpusstats.NewCollector()
+	if err := loadStats(loaded, path); err != nil {
+		t.Fatalf("loadStats failed: %v", err)
+	}
+	if got := loaded.Snapshot().Messages; got != 5 {
+		t.Errorf("expected 5 messages, got %d", got)
+	}
+
+	if err := loadStats(corpusstats.NewCollector(), filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("loadStats of a missing file failed: %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+// Command genorder2 generates the context tables of the order-2 English model
+// from a text corpus.
+//
+// It counts which characters follow each two character (bigram) context and,
+// optionally, each three character (trigram) context of the corpus and writes
+// the counts as a Go source file:
+//
+//	genorder2 -out english_order2_tables.go -trigrams 256
+//
+// Without -corpus the embedded corpus.txt is used. The -bigrams and -trigrams
+// flags limit the number of contexts to the most frequent ones and -symbols
+// limits the number of successors kept per context, which controls the size of
+// the generated tables.
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"unicode/utf8"
+)
+
+//go:embed corpus.txt
+var defaultCorpus []byte
+
+func main() {
+	corpus := flag.String("corpus", "", "corpus file (default embedded corpus.txt)")
+	out := flag.String("out", "english_order2_tables.go", "output Go file")
+	pkg := flag.String("package", "models", "package name of the output file")
+	bigrams := flag.Int("bigrams", 0, "maximum number of bigram contexts, 0 for all")
+	trigrams := flag.Int("trigrams", 0, "maximum number of trigram contexts, 0 disables trigrams")
+	symbols := flag.Int("symbols", 0, "maximum number of successors per context, 0 for all")
+	minCount := flag.Int("min", 2, "minimum number of occurrences of a context")
+	flag.Parse()
+
+	if err := run(*corpus, *out, *pkg, *bigrams, *trigrams, *symbols, *minCount); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(corpusPath, out, pkg string, bigrams, trigrams, symbols, minCount int) error {
+	text := defaultCorpus
+	source := "corpus.txt"
+	if corpusPath != "" {
+		data, err := os.ReadFile(corpusPath)
+		if err != nil {
+			return fmt.Errorf("read corpus: %w", err)
+		}
+		text = data
+		source = corpusPath
+	}
+	if !utf8.Valid(text) {
+		return fmt.Errorf("corpus %s is not valid UTF-8", source)
+	}
+
+	var contexts []context
+	contexts = append(contexts, countContexts(text, 2, bigrams, symbols, minCount)...)
+	if trigrams > 0 {
+		contexts = append(contexts, countContexts(text, 3, trigrams, symbols, minCount)...)
+	}
+
+	src, err := generate(pkg, source, contexts)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
+
+// context holds the successor counts of a single context.
+type context struct {
+	key        string
+	total      int
+	successors []successor
+}
+
+// successor is a character following a context and how often it occurred.
+type successor struct {
+	char  rune
+	count int
+}
+
+// countContexts counts the successors of all contexts of the given length.
+// Each line of the corpus is counted separately, matching how messages are
+// coded one at a time.
+func countContexts(text []byte, order, limit, symbols, minCount int) []context {
+	counts := make(map[string]map[rune]int)
+	for _, line := range bytes.Split(text, []byte("\n")) {
+		runes := []rune(string(line))
+		for i := order; i < len(runes); i++ {
+			key := string(runes[i-order : i])
+			successors, ok := counts[key]
+			if !ok {
+				successors = make(map[rune]int)
+				counts[key] = successors
+			}
+			successors[runes[i]]++
+		}
+	}
+
+	var contexts []context
+	for key, successors := range counts {
+		ctx := context{key: key}
+		for char, count := range successors {
+			ctx.total += count
+			ctx.successors = append(ctx.successors, successor{char: char, count: count})
+		}
+		if ctx.total < minCount {
+			continue
+		}
+		sort.Slice(ctx.successors, func(i, k int) bool {
+			a, b := ctx.successors[i], ctx.successors[k]
+			if a.count != b.count {
+				return a.count > b.count
+			}
+			return a.char < b.char
+		})
+		if symbols > 0 && len(ctx.successors) > symbols {
+			ctx.successors = ctx.successors[:symbols]
+		}
+		contexts = append(contexts, ctx)
+	}
+
+	// Keep the most frequent contexts
+	sort.Slice(contexts, func(i, k int) bool {
+		if contexts[i].total != contexts[k].total {
+			return contexts[i].total > contexts[k].total
+		}
+		return contexts[i].key < contexts[k].key
+	})
+	if limit > 0 && len(contexts) > limit {
+		contexts = contexts[:limit]
+	}
+
+	sort.Slice(contexts, func(i, k int) bool {
+		return contexts[i].key < contexts[k].key
+	})
+	return contexts
+}
+
+// generate writes the contexts as a formatted Go source file.
+func generate(pkg, source string, contexts []context) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by genorder2 from %s; DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	fmt.Fprintf(&buf, "// order2ContextCounts maps bigram and trigram contexts to the number of\n")
+	fmt.Fprintf(&buf, "// times each character followed the context in the training corpus.\n")
+	fmt.Fprintf(&buf, "var order2ContextCounts = map[string]map[rune]uint32{\n")
+	for _, ctx := range contexts {
+		fmt.Fprintf(&buf, "\t%q: {", ctx.key)
+		for i, s := range ctx.successors {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			fmt.Fprintf(&buf, "%q: %d", s.char, s.count)
+		}
+		buf.WriteString("},\n")
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return src, nil
+}
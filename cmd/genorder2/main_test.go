@@ -0,0 +1,51 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCountContexts(t *testing.T) {
+	contexts := countContexts([]byte("the then\nthe"), 2, 0, 0, 2)
+
+	var th *context
+	for i := range contexts {
+		if contexts[i].key == "th" {
+			th = &contexts[i]
+		}
+	}
+	if th == nil {
+		t.Fatalf("missing context \"th\" in %v", contexts)
+	}
+	if th.total != 3 || len(th.successors) != 1 || th.successors[0] != (successor{'e', 3}) {
+		t.Errorf("context \"th\": unexpected counts %+v", *th)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	corpus := filepath.Join(dir, "corpus.txt")
+	if err := os.WriteFile(corpus, []byte("hello there\nthe other one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "tables.go")
+	if err := run(corpus, out, "models", 0, 8, 4, 1); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	src, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), out, src, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v", err)
+	}
+	if !strings.Contains(string(src), `"he":`) {
+		t.Errorf("generated code misses context \"he\":\n%s", src)
+	}
+}
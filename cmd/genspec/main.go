@@ -0,0 +1,59 @@
+// Command genspec generates the wire format specification of the
+// Meshtastic codec from the implementation:
+//
+//	genspec -out docs/WIRE_FORMAT.md
+//
+// The specification describes how V14 codes the messages reachable from the
+// -roots messages, see meshtasticmodel.WriteSpec.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	_ "github.com/egonelbre/exp-protobuf-compression/meshtastic"
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+)
+
+// defaultRoots are the messages sent over the mesh and read from devices.
+const defaultRoots = "meshtastic.MeshPacket,meshtastic.Position,meshtastic.User,meshtastic.NodeInfo,meshtastic.Telemetry,meshtastic.Waypoint"
+
+func main() {
+	out := flag.String("out", "docs/WIRE_FORMAT.md", "output Markdown file")
+	roots := flag.String("roots", defaultRoots, "comma-separated full names of the messages to describe")
+	flag.Parse()
+
+	spec, err := generate(*roots)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, spec, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate returns the specification of the messages reachable from the
+// comma-separated roots.
+func generate(roots string) ([]byte, error) {
+	var descriptors []protoreflect.MessageDescriptor
+	for _, name := range strings.Split(roots, ",") {
+		mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(strings.TrimSpace(name)))
+		if err != nil {
+			return nil, fmt.Errorf("message %q: %w", name, err)
+		}
+		descriptors = append(descriptors, mt.Descriptor())
+	}
+
+	var buf bytes.Buffer
+	if err := meshtasticmodel.WriteSpec(&buf, descriptors...); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
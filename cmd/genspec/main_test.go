@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestSpecUpToDate fails when the checked in specification differs from
+// the implementation.
+func TestSpecUpToDate(t *testing.T) {
+	want, err := generate(defaultRoots)
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	got, err := os.ReadFile("../../docs/WIRE_FORMAT.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("docs/WIRE_FORMAT.md is out of date, run: go run ./cmd/genspec")
+	}
+}
+
+func TestUnknownRoot(t *testing.T) {
+	if _, err := generate("meshtastic.NoSuchMessage"); err == nil {
+		t.Error("generate succeeded for an unknown message")
+	}
+}
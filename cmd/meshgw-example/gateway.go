@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+)
+
+// The first byte of a datagram tells what it carries.
+const (
+	datagramFrame  = 0 // A session frame
+	datagramResync = 1 // A resync request for the encoder of the receiver
+)
+
+// maxDatagram is the size of the receive buffer, the largest UDP payload.
+const maxDatagram = 65535
+
+// gateway bridges the serial port of a node and a peer gateway.
+type gateway struct {
+	serial *serialReader
+	out    io.Writer // Serial port of the node
+	conn   net.PacketConn
+	peer   net.Addr
+
+	mu    sync.Mutex // Guards enc, which both directions use
+	enc   *meshtasticmodel.SessionEncoder
+	epoch *epochFile
+	dec   *meshtasticmodel.SessionDecoder
+
+	// Counters, published by main with expvar
+	metrics *expvar.Map
+}
+
+// newGateway creates a gateway between the node on serial and the gateway
+// at peer. The session continues from the epoch in the state file, if any.
+func newGateway(serial io.ReadWriter, conn net.PacketConn, peer net.Addr, state string, opts meshtasticmodel.SessionOptions) (*gateway, error) {
+	epoch, err := loadEpochFile(state)
+	if err != nil {
+		return nil, err
+	}
+	opts.EpochBase = epoch.epoch
+	return &gateway{
+		serial:  newSerialReader(serial),
+		out:     serial,
+		conn:    conn,
+		peer:    peer,
+		enc:     meshtasticmodel.NewSessionEncoderWithOptions(opts),
+		epoch:   epoch,
+		dec:     meshtasticmodel.NewSessionDecoderWithOptions(opts),
+		metrics: new(expvar.Map).Init(),
+	}, nil
+}
+
+// run forwards packets in both directions until ctx is done or either
+// direction fails.
+func (g *gateway) run(ctx context.Context) error {
+	errs := make(chan error, 2)
+	go func() { errs <- g.uplink() }()
+	go func() { errs <- g.downlink() }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// uplink sends the packets received by the node to the peer.
+func (g *gateway) uplink() error {
+	for {
+		payload, err := g.serial.next()
+		if err != nil {
+			return fmt.Errorf("serial: %w", err)
+		}
+		var from meshtastic.FromRadio
+		if err := proto.Unmarshal(payload, &from); err != nil {
+			g.metrics.Add("serial_invalid", 1)
+			continue
+		}
+		// Only packets go over the link, not the node's config or logs
+		packet := from.GetPacket()
+		if packet == nil {
+			continue
+		}
+
+		frame, err := g.encode(packet)
+		if err != nil {
+			g.metrics.Add("encode_errors", 1)
+			log.Printf("encode: %v", err)
+			continue
+		}
+		if _, err := g.conn.WriteTo(append([]byte{datagramFrame}, frame...), g.peer); err != nil {
+			return fmt.Errorf("udp: %w", err)
+		}
+		g.metrics.Add("uplink_packets", 1)
+		g.metrics.Add("uplink_bytes", int64(proto.Size(packet)))
+		g.metrics.Add("uplink_compressed_bytes", int64(len(frame)))
+	}
+}
+
+// encode compresses packet into a frame and keeps the epoch of the session
+// in the state file.
+func (g *gateway) encode(packet *meshtastic.MeshPacket) ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	frame, err := g.enc.Encode(packet)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.epoch.save(g.enc.Epoch()); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// downlink hands the packets sent by the peer to the node and serves the
+// resync requests of the peer.
+func (g *gateway) downlink() error {
+	buf := make([]byte, maxDatagram)
+	for {
+		n, from, err := g.conn.ReadFrom(buf)
+		if err != nil {
+			return fmt.Errorf("udp: %w", err)
+		}
+		if n == 0 || from.String() != g.peer.String() {
+			continue
+		}
+
+		switch datagram := buf[1:n]; buf[0] {
+		case datagramFrame:
+			if err := g.receive(datagram); err != nil {
+				return err
+			}
+		case datagramResync:
+			g.mu.Lock()
+			err := g.enc.HandleResyncRequest(datagram)
+			g.mu.Unlock()
+			if err != nil {
+				log.Printf("resync request: %v", err)
+				continue
+			}
+			g.metrics.Add("resyncs_served", 1)
+		}
+	}
+}
+
+// receive decodes a frame and writes its packet to the node. Frames that
+// cannot be decoded are dropped and a keyframe is requested from the peer.
+func (g *gateway) receive(frame []byte) error {
+	packet := &meshtastic.MeshPacket{}
+	if err := g.dec.Decode(frame, packet); err != nil {
+		g.metrics.Add("dropped_frames", 1)
+		if errors.Is(err, meshtasticmodel.ErrOldFrame) || !g.dec.NeedsResync() {
+			return nil
+		}
+		req := append([]byte{datagramResync}, g.dec.ResyncRequest()...)
+		if _, err := g.conn.WriteTo(req, g.peer); err != nil {
+			return fmt.Errorf("udp: %w", err)
+		}
+		g.metrics.Add("resyncs_requested", 1)
+		return nil
+	}
+
+	payload, err := proto.Marshal(&meshtastic.ToRadio{
+		PayloadVariant: &meshtastic.ToRadio_Packet{Packet: packet},
+	})
+	if err != nil || len(payload) > maxSerialPayload {
+		g.metrics.Add("dropped_packets", 1)
+		return nil
+	}
+	g.metrics.Add("downlink_packets", 1)
+	g.metrics.Add("downlink_compressed_bytes", int64(len(frame)))
+	if err := writeSerialFrame(g.out, payload); err != nil {
+		return fmt.Errorf("serial: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Command meshgw-example is an example gateway that carries the packets of a
+// Meshtastic node over an IP link with session compression.
+//
+// Usage:
+//
+//	meshgw-example -serial /dev/ttyUSB0 -listen :4403 -peer host:4403 [-state file] [-metrics :8080]
+//
+// Packets that the node receives over its serial port are compressed with a
+// SessionEncoder and sent as UDP datagrams to the peer gateway, which
+// decompresses them and hands them to its own node. The same happens in the
+// other direction, so two gateways bridge two meshes. The serial port must
+// already be configured, for example with stty, and the node must have its
+// serial module in protobuf mode.
+//
+// Datagrams can be lost or reordered, so the gateways use the resync
+// mechanism of the sessions: a gateway that cannot decode a frame asks its
+// peer for a keyframe. The epoch of the encoder is kept in the state file,
+// so that a restarted gateway continues the session where the peer expects
+// it, and the counters of the gateway are published with expvar on the
+// metrics address under /debug/vars.
+//
+// The example sends its frames over UDP to stay within the standard library;
+// an MQTT uplink would publish the same datagrams as messages.
+package main
+
+import (
+	"context"
+	"expvar"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+)
+
+func main() {
+	var config gatewayConfig
+	flag.StringVar(&config.serial, "serial", "", "serial device of the node")
+	flag.StringVar(&config.listen, "listen", ":4403", "UDP address to receive frames on")
+	flag.StringVar(&config.peer, "peer", "", "UDP address of the peer gateway")
+	flag.StringVar(&config.state, "state", "", "file keeping the session epoch across restarts")
+	flag.IntVar(&config.opts.KeyframeInterval, "keyframe-interval", 0, "frames between keyframes, see SessionOptions")
+	flag.BoolVar(&config.opts.AdminCorrelation, "admin-correlation", false, "code admin responses against their requests")
+	metrics := flag.String("metrics", "", "HTTP address to publish the counters on, none when empty")
+	flag.Parse()
+
+	if config.serial == "" || config.peer == "" {
+		fmt.Fprintln(os.Stderr, "usage: meshgw-example -serial device -listen addr -peer addr [-state file] [-metrics addr]")
+		os.Exit(2)
+	}
+
+	if err := run(config, *metrics); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// gatewayConfig configures a gateway.
+type gatewayConfig struct {
+	serial string
+	listen string
+	peer   string
+	state  string
+	opts   meshtasticmodel.SessionOptions
+}
+
+// run opens the serial port and the UDP socket of config and runs the
+// gateway until it is interrupted.
+func run(config gatewayConfig, metrics string) error {
+	serial, err := os.OpenFile(config.serial, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer serial.Close()
+
+	conn, err := net.ListenPacket("udp", config.listen)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	peer, err := net.ResolveUDPAddr("udp", config.peer)
+	if err != nil {
+		return err
+	}
+
+	g, err := newGateway(serial, conn, peer, config.state, config.opts)
+	if err != nil {
+		return err
+	}
+	if metrics != "" {
+		expvar.Publish("meshgw", g.metrics)
+		go func() { log.Println(http.ListenAndServe(metrics, nil)) }()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return g.run(ctx)
+}
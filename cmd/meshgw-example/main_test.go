@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+)
+
+// testNode is the serial port of a node, driven by a test.
+type testNode struct {
+	io.Reader // Frames sent by the node
+	io.Writer // Frames received by the node
+
+	send     *io.PipeWriter
+	received *serialReader
+}
+
+// newTestNode creates the serial port of a node.
+func newTestNode() *testNode {
+	fromNode, send := io.Pipe()
+	received, toNode := io.Pipe()
+	return &testNode{
+		Reader:   fromNode,
+		Writer:   toNode,
+		send:     send,
+		received: newSerialReader(received),
+	}
+}
+
+// sendPacket makes the node send packet to its gateway.
+func (n *testNode) sendPacket(t *testing.T, packet *meshtastic.MeshPacket) {
+	t.Helper()
+	payload, err := proto.Marshal(&meshtastic.FromRadio{
+		PayloadVariant: &meshtastic.FromRadio_Packet{Packet: packet},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSerialFrame(n.send, payload); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// receivePacket returns the next packet the gateway gave to the node.
+func (n *testNode) receivePacket(t *testing.T) *meshtastic.MeshPacket {
+	t.Helper()
+	payload, err := n.received.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var to meshtastic.ToRadio
+	if err := proto.Unmarshal(payload, &to); err != nil {
+		t.Fatal(err)
+	}
+	return to.GetPacket()
+}
+
+// startGateway runs a gateway for node on addr until the test ends.
+func startGateway(t *testing.T, node *testNode, addr string, peer net.Addr, state string) (*gateway, net.PacketConn) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	g, err := newGateway(node, conn, peer, state, meshtasticmodel.SessionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = g.run(ctx) }()
+	return g, conn
+}
+
+func TestGateway(t *testing.T) {
+	connA, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	connB, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrA, addrB := connA.LocalAddr(), connB.LocalAddr()
+	connA.Close()
+	connB.Close()
+
+	state := filepath.Join(t.TempDir(), "epoch")
+	nodeA, nodeB := newTestNode(), newTestNode()
+	gatewayA, conn := startGateway(t, nodeA, addrA.String(), addrB, state)
+	gatewayB, _ := startGateway(t, nodeB, addrB.String(), addrA, "")
+
+	packets := []*meshtastic.MeshPacket{
+		meshfixtures.PositionPacket(meshfixtures.Default),
+		meshfixtures.TelemetryPacket(meshfixtures.Default),
+		meshfixtures.TextPacket(meshfixtures.Default, meshfixtures.TextMessages[0]),
+	}
+	forward := func(from, to *testNode) {
+		t.Helper()
+		for i, packet := range packets {
+			from.sendPacket(t, packet)
+			if got := to.receivePacket(t); !proto.Equal(packet, got) {
+				t.Fatalf("packet %d: got %v, want %v", i, got, packet)
+			}
+		}
+	}
+
+	// The log output of the node is skipped
+	if _, err := nodeA.send.Write([]byte("INFO | booting\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	forward(nodeA, nodeB)
+	forward(nodeB, nodeA)
+
+	// A restarted gateway continues the session of the peer
+	conn.Close()
+	epoch := gatewayA.enc.Epoch()
+	nodeA = newTestNode()
+	gatewayA, _ = startGateway(t, nodeA, addrA.String(), addrB, state)
+	if gatewayA.enc.Epoch() != epoch {
+		t.Fatalf("restarted gateway at epoch %d, want %d", gatewayA.enc.Epoch(), epoch)
+	}
+	forward(nodeA, nodeB)
+
+	if got := gatewayB.metrics.Get("downlink_packets").String(); got != "6" {
+		t.Errorf("gateway B received %s packets, want 6", got)
+	}
+	if got := gatewayB.metrics.Get("dropped_frames"); got != nil {
+		t.Errorf("gateway B dropped %s frames", got)
+	}
+}
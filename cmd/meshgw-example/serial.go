@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Nodes frame the protobufs on their serial port with two start bytes and a
+// big-endian length. Their debug log is written to the same port as plain
+// text between the frames.
+const (
+	serialStart1 = 0x94
+	serialStart2 = 0xc3
+
+	// maxSerialPayload is the largest payload nodes send or accept.
+	maxSerialPayload = 512
+)
+
+// serialReader reads the frames of a node's serial port.
+type serialReader struct {
+	r *bufio.Reader
+}
+
+// newSerialReader creates a reader of the frames in r.
+func newSerialReader(r io.Reader) *serialReader {
+	return &serialReader{r: bufio.NewReader(r)}
+}
+
+// next returns the payload of the next frame, skipping log output and
+// frames with invalid lengths.
+func (s *serialReader) next() ([]byte, error) {
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != serialStart1 {
+			continue
+		}
+		if b, err = s.r.ReadByte(); err != nil {
+			return nil, err
+		}
+		if b != serialStart2 {
+			_ = s.r.UnreadByte() // It may start the next frame
+			continue
+		}
+
+		var header [2]byte
+		if _, err := io.ReadFull(s.r, header[:]); err != nil {
+			return nil, err
+		}
+		n := int(header[0])<<8 | int(header[1])
+		if n > maxSerialPayload {
+			continue
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(s.r, payload); err != nil {
+			return nil, err
+		}
+		return payload, nil
+	}
+}
+
+// writeSerialFrame writes payload as a frame to a node's serial port.
+func writeSerialFrame(w io.Writer, payload []byte) error {
+	if len(payload) > maxSerialPayload {
+		return fmt.Errorf("serial payload of %d bytes exceeds %d bytes", len(payload), maxSerialPayload)
+	}
+	frame := append([]byte{serialStart1, serialStart2, byte(len(payload) >> 8), byte(len(payload))}, payload...)
+	_, err := w.Write(frame)
+	return err
+}
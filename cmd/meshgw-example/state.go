@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// epochFile keeps the epoch of a session encoder across restarts. Peers
+// reject keyframes of epochs they have seen, so a restarted gateway must
+// continue from the epoch it had reached; the models themselves are not
+// kept, the first frame after a restart is a keyframe.
+type epochFile struct {
+	path  string // Empty keeps nothing
+	epoch uint64 // Last saved epoch
+}
+
+// loadEpochFile reads the epoch saved in path, zero when there is none.
+func loadEpochFile(path string) (*epochFile, error) {
+	f := &epochFile{path: path}
+	if path == "" {
+		return f, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if f.epoch, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err != nil {
+		return nil, fmt.Errorf("state file %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// save keeps epoch when it changed. The file is replaced atomically, so a
+// crash leaves either the old or the new epoch.
+func (f *epochFile) save(epoch uint64) error {
+	if f.path == "" || epoch == f.epoch {
+		return nil
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(epoch, 10)+"\n"), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return err
+	}
+	f.epoch = epoch
+	return nil
+}
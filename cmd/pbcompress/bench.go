@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+)
+
+// benchConfig configures the bench command.
+type benchConfig struct {
+	corpus   string        // Length-delimited messages, empty for the fixtures
+	typeName string        // Message type of the corpus
+	versions string        // Comma-separated version names, empty for all
+	duration time.Duration // Minimum time to measure each speed
+}
+
+// runBench benchmarks the versions on the corpus and writes the results to
+// w as JSON, see meshtasticmodel.BenchmarkResults.
+func runBench(w io.Writer, config benchConfig) error {
+	name, corpus := "meshfixtures", meshfixtures.All()
+	if config.corpus != "" {
+		var err error
+		name, corpus, err = readCorpus(config.corpus, config.typeName)
+		if err != nil {
+			return err
+		}
+	}
+
+	versions := meshtasticmodel.Versions
+	if config.versions != "" {
+		versions = nil
+		for _, versionName := range strings.Split(config.versions, ",") {
+			version, ok := meshtasticmodel.FindVersion(strings.TrimSpace(versionName))
+			if !ok {
+				return fmt.Errorf("bench: unknown version %q", versionName)
+			}
+			versions = append(versions, version)
+		}
+	}
+
+	results, err := meshtasticmodel.RunBenchmark(name, corpus, versions, config.duration)
+	if err != nil {
+		return fmt.Errorf("bench: %w", err)
+	}
+	_, err = results.WriteTo(w)
+	return err
+}
+
+// readCorpus reads varint length-delimited messages of the named type from
+// path. The corpus is named after the file.
+func readCorpus(path, typeName string) (string, []proto.Message, error) {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(typeName))
+	if err != nil {
+		return "", nil, fmt.Errorf("message type %q: %w", typeName, err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	var corpus []proto.Message
+	br := bufio.NewReader(f)
+	for {
+		msg := mt.New().Interface()
+		if err := protodelim.UnmarshalFrom(br, msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return "", nil, fmt.Errorf("%s: message %d: %w", path, len(corpus), err)
+		}
+		corpus = append(corpus, msg)
+	}
+	return path, corpus, nil
+}
@@ -0,0 +1,66 @@
+// Command pbcompress is a utility for deployments of the compressors.
+//
+// Usage:
+//
+//	pbcompress selftest [-v]
+//	pbcompress bench [-corpus file -type name] [-versions V1,V14] [-duration 1s]
+//
+// The selftest command checks every codec against built-in vectors on the
+// machine it runs on. It catches miscompiles and architecture assumptions,
+// such as byte order or word size, before a gateway goes live. It exits with
+// a non-zero status when a check fails.
+//
+// The bench command compresses a corpus with every version and writes the
+// sizes and speeds as JSON to stdout, in a format that can be shared and
+// compared; results with the same corpus hash used the same messages. The
+// corpus is a file of varint length-delimited messages, or the fixtures.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	_ "github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "selftest":
+		flags := flag.NewFlagSet("selftest", flag.ExitOnError)
+		verbose := flags.Bool("v", false, "list every check")
+		_ = flags.Parse(os.Args[2:])
+
+		if err := runSelfTest(os.Stdout, *verbose); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "bench":
+		var config benchConfig
+		flags := flag.NewFlagSet("bench", flag.ExitOnError)
+		flags.StringVar(&config.corpus, "corpus", "", "file of length-delimited messages, the fixtures when empty")
+		flags.StringVar(&config.typeName, "type", "meshtastic.MeshPacket", "full name of the message type of the corpus")
+		flags.StringVar(&config.versions, "versions", "", "comma-separated versions to benchmark, all when empty")
+		flags.DurationVar(&config.duration, "duration", time.Second, "minimum time to measure each speed")
+		_ = flags.Parse(os.Args[2:])
+
+		if err := runBench(os.Stdout, config); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: pbcompress selftest [-v]")
+	fmt.Fprintln(os.Stderr, "       pbcompress bench [-corpus file -type name] [-versions V1,V14] [-duration 1s]")
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+)
+
+func TestSelfTest(t *testing.T) {
+	var out bytes.Buffer
+	if err := runSelfTest(&out, true); err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+}
+
+func TestBench(t *testing.T) {
+	// A corpus file of length-delimited packets
+	path := filepath.Join(t.TempDir(), "corpus.bin")
+	var corpus bytes.Buffer
+	for _, packet := range []proto.Message{
+		meshfixtures.PositionPacket(meshfixtures.Default),
+		meshfixtures.TextPacket(meshfixtures.Default, meshfixtures.TextMessages[0]),
+	} {
+		if _, err := protodelim.MarshalTo(&corpus, packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, corpus.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	config := benchConfig{corpus: path, typeName: "meshtastic.MeshPacket", versions: "pbmodel, V14"}
+	if err := runBench(&out, config); err != nil {
+		t.Fatal(err)
+	}
+	results, err := meshtasticmodel.ReadBenchmarkResults(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.Corpus.Messages != 2 || len(results.Codecs) != 2 || results.Codecs[1].Codec != "V14" {
+		t.Errorf("unexpected results %+v", results)
+	}
+
+	if err := runBench(io.Discard, benchConfig{versions: "V99"}); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/huffman"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
+)
+
+// selfTest is a single check of the self-test.
+type selfTest struct {
+	name string
+	run  func() error
+}
+
+// runSelfTest runs all checks and reports them to w. It returns an error
+// when any check failed.
+func runSelfTest(w io.Writer, verbose bool) error {
+	tests := selfTests()
+
+	failed := 0
+	for _, test := range tests {
+		err := test.run()
+		switch {
+		case err != nil:
+			failed++
+			fmt.Fprintf(w, "FAIL %s: %v\n", test.name, err)
+		case verbose:
+			fmt.Fprintf(w, "ok   %s\n", test.name)
+		}
+	}
+
+	platform := fmt.Sprintf("%s/%s, %s", runtime.GOOS, runtime.GOARCH, runtime.Version())
+	if failed > 0 {
+		return fmt.Errorf("selftest: %d of %d checks failed on %s", failed, len(tests), platform)
+	}
+	fmt.Fprintf(w, "selftest: %d checks passed on %s\n", len(tests), platform)
+	return nil
+}
+
+// selfTests returns the checks of the self-test.
+//
+// The entropy coders are checked against golden output, so that the check
+// does not depend on the encoder and the decoder agreeing with each other.
+// The compressors are checked with roundtrips of the fixture messages.
+func selfTests() []selfTest {
+	tests := []selfTest{
+		{"arithcode/platform", coder.CheckPlatform},
+		{"arithcode/golden", func() error { return checkArithmeticGolden(coder.Wide) }},
+		{"arithcode/golden-narrow", func() error { return checkArithmeticGolden(coder.Narrow) }},
+		{"huffman/golden", checkHuffmanGolden},
+		{"pbmodel/varint", checkVarints},
+	}
+
+	pbmodelOptions := []struct {
+		name string
+		opts []pbmodel.Option
+	}{
+		{"default", nil},
+		{"huffman", []pbmodel.Option{pbmodel.WithBackend(pbmodel.Huffman)}},
+		{"order-1", []pbmodel.Option{pbmodel.WithStringOrder(1)}},
+		{"order-2", []pbmodel.Option{pbmodel.WithStringOrder(2)}},
+		{"varint-models", []pbmodel.Option{pbmodel.WithVarintByteModels()}},
+	}
+	for _, options := range pbmodelOptions {
+		opts := options.opts
+		tests = append(tests, selfTest{"pbmodel/" + options.name, func() error {
+			return checkRoundtrips(
+				func(msg proto.Message, w io.Writer) error { return pbmodel.Compress(msg, w, opts...) },
+				func(r io.Reader, msg proto.Message) error { return pbmodel.Decompress(r, msg, opts...) },
+			)
+		}})
+	}
+	tests = append(tests,
+		selfTest{"pbmodel/adaptive", func() error {
+			return checkRoundtrips(
+				func(msg proto.Message, w io.Writer) error { return pbmodel.AdaptiveCompress(msg, w) },
+				func(r io.Reader, msg proto.Message) error { return pbmodel.AdaptiveDecompress(r, msg) },
+			)
+		}},
+		selfTest{"pbmodel/codec", checkCodec},
+	)
+
+	for _, version := range meshtasticmodel.Versions {
+		tests = append(tests, selfTest{"meshtasticmodel/" + version.Name, func() error {
+			return checkRoundtrips(
+				func(msg proto.Message, w io.Writer) error { return version.Compress(msg, w) },
+				func(r io.Reader, msg proto.Message) error { return version.Decompress(r, msg) },
+			)
+		}})
+	}
+	tests = append(tests,
+		selfTest{"meshtasticmodel/session", checkSession},
+		selfTest{"meshtasticmodel/channels", checkChannels},
+	)
+	return tests
+}
+
+// goldenSymbols are coded by the golden checks of the entropy coders.
+var goldenSymbols = []int{0, 3, 3, 1, 7, 0, 0, 2, 6, 5, 4, 3, 0, 1, 1, 7}
+
+// goldenFrequencies is the model of goldenSymbols.
+var goldenFrequencies = []uint64{40, 20, 10, 15, 5, 3, 2, 5}
+
+// Golden outputs for goldenSymbols; the arithmetic ones, by precision, also
+// code a uniform value.
+var goldenArithmetic = [...]string{
+	coder.Wide:   "53cb3e700507ae46173e",
+	coder.Narrow: "53cb3e35f5fed4262e58",
+}
+
+const goldenHuffman = "5b38cffb6a4e"
+
+// checkArithmeticGolden checks the arithmetic coder with precision against
+// golden output.
+func checkArithmeticGolden(precision coder.Precision) error {
+	model := models.NewFrequencyTable(goldenFrequencies)
+
+	var buf bytes.Buffer
+	enc := coder.NewEncoder(&buf)
+	enc.SetPrecision(precision)
+	for _, symbol := range goldenSymbols {
+		if err := enc.Encode(symbol, model); err != nil {
+			return err
+		}
+	}
+	if err := enc.EncodeUniform(123456789, math.MaxUint32); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if got, want := hex.EncodeToString(buf.Bytes()), goldenArithmetic[precision]; got != want {
+		return fmt.Errorf("encoded %s, want %s", got, want)
+	}
+
+	dec, err := coder.NewDecoder(&buf)
+	if err != nil {
+		return err
+	}
+	dec.SetPrecision(precision)
+	for i, want := range goldenSymbols {
+		symbol, err := dec.Decode(model)
+		if err != nil {
+			return err
+		}
+		if symbol != want {
+			return fmt.Errorf("symbol %d: decoded %d, want %d", i, symbol, want)
+		}
+	}
+	value, err := dec.DecodeUniform(math.MaxUint32)
+	if err != nil {
+		return err
+	}
+	if value != 123456789 {
+		return fmt.Errorf("uniform value: decoded %d, want 123456789", value)
+	}
+	return dec.Finish()
+}
+
+// checkHuffmanGolden checks the Huffman coder against golden output.
+func checkHuffmanGolden() error {
+	model := models.NewFrequencyTable(goldenFrequencies)
+
+	var buf bytes.Buffer
+	enc := huffman.NewEncoder(&buf)
+	for _, symbol := range goldenSymbols {
+		if err := enc.Encode(symbol, model); err != nil {
+			return err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(buf.Bytes()); got != goldenHuffman {
+		return fmt.Errorf("encoded %s, want %s", got, goldenHuffman)
+	}
+
+	dec := huffman.NewDecoder(&buf)
+	for i, want := range goldenSymbols {
+		symbol, err := dec.Decode(model)
+		if err != nil {
+			return err
+		}
+		if symbol != want {
+			return fmt.Errorf("symbol %d: decoded %d, want %d", i, symbol, want)
+		}
+	}
+	return dec.Finish()
+}
+
+// checkVarints checks the varint and zigzag conversions at the edges of the
+// integer types.
+func checkVarints() error {
+	for _, value := range []uint64{0, 1, 127, 128, math.MaxUint32, math.MaxUint64} {
+		if got := pbmodel.DecodeVarint(pbmodel.EncodeVarint(value)); got != value {
+			return fmt.Errorf("varint %d: decoded %d", value, got)
+		}
+	}
+	if got := pbmodel.EncodeVarint(math.MaxUint64); len(got) != 10 || got[9] != 0x01 {
+		return fmt.Errorf("varint %d: encoded %x", uint64(math.MaxUint64), got)
+	}
+	for _, value := range []int64{0, -1, 1, math.MinInt32, math.MaxInt32, math.MinInt64, math.MaxInt64} {
+		if got := pbmodel.ZigzagDecode(pbmodel.ZigzagEncode(value)); got != value {
+			return fmt.Errorf("zigzag %d: decoded %d", value, got)
+		}
+	}
+	if got := pbmodel.ZigzagEncode(math.MinInt64); got != math.MaxUint64 {
+		return fmt.Errorf("zigzag %d: encoded %d", int64(math.MinInt64), got)
+	}
+	return nil
+}
+
+// checkRoundtrips compresses and decompresses the fixture messages.
+func checkRoundtrips(compress func(proto.Message, io.Writer) error, decompress func(io.Reader, proto.Message) error) error {
+	for _, msg := range meshfixtures.All() {
+		name := msg.ProtoReflect().Descriptor().Name()
+
+		var buf bytes.Buffer
+		if err := compress(msg, &buf); err != nil {
+			return fmt.Errorf("%s: compress: %w", name, err)
+		}
+		result := msg.ProtoReflect().New().Interface()
+		if err := decompress(&buf, result); err != nil {
+			return fmt.Errorf("%s: decompress: %w", name, err)
+		}
+		if !proto.Equal(msg, result) {
+			return fmt.Errorf("%s: roundtrip mismatch", name)
+		}
+	}
+	return nil
+}
+
+// checkCodec checks a reused pbmodel.Codec against pbmodel.Compress.
+func checkCodec() error {
+	codec := pbmodel.NewCodec()
+	for _, msg := range meshfixtures.All() {
+		name := msg.ProtoReflect().Descriptor().Name()
+
+		var want, got bytes.Buffer
+		if err := pbmodel.Compress(msg, &want); err != nil {
+			return fmt.Errorf("%s: compress: %w", name, err)
+		}
+		if err := codec.Compress(msg, &got); err != nil {
+			return fmt.Errorf("%s: codec compress: %w", name, err)
+		}
+		if !bytes.Equal(want.Bytes(), got.Bytes()) {
+			return fmt.Errorf("%s: codec output differs from Compress", name)
+		}
+
+		result := msg.ProtoReflect().New().Interface()
+		if err := codec.Decompress(&got, result); err != nil {
+			return fmt.Errorf("%s: codec decompress: %w", name, err)
+		}
+		if !proto.Equal(msg, result) {
+			return fmt.Errorf("%s: roundtrip mismatch", name)
+		}
+	}
+	return nil
+}
+
+// checkSession codes the fixture messages as one session.
+func checkSession() error {
+	encoder := meshtasticmodel.NewSessionEncoder()
+	decoder := meshtasticmodel.NewSessionDecoder()
+	for i, msg := range meshfixtures.All() {
+		frame, err := encoder.Encode(msg)
+		if err != nil {
+			return fmt.Errorf("frame %d: encode: %w", i, err)
+		}
+		result := msg.ProtoReflect().New().Interface()
+		if err := decoder.Decode(frame, result); err != nil {
+			return fmt.Errorf("frame %d: decode: %w", i, err)
+		}
+		if !proto.Equal(msg, result) {
+			return fmt.Errorf("frame %d: roundtrip mismatch", i)
+		}
+	}
+	return nil
+}
+
+// checkChannels codes a typical channel table.
+func checkChannels() error {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i*37 + 11)
+	}
+	channels := []*meshtastic.Channel{
+		{Index: 0, Role: meshtastic.Channel_PRIMARY, Settings: &meshtastic.ChannelSettings{Psk: []byte{1}}},
+		{Index: 1, Role: meshtastic.Channel_SECONDARY, Settings: &meshtastic.ChannelSettings{Name: "private", Psk: key}},
+		{Index: 2, Role: meshtastic.Channel_DISABLED},
+	}
+
+	var buf bytes.Buffer
+	if err := meshtasticmodel.CompressChannels(channels, &buf); err != nil {
+		return fmt.Errorf("compress: %w", err)
+	}
+	result, err := meshtasticmodel.DecompressChannels(&buf)
+	if err != nil {
+		return fmt.Errorf("decompress: %w", err)
+	}
+	if len(result) != len(channels) {
+		return fmt.Errorf("decoded %d channels, want %d", len(result), len(channels))
+	}
+	for i := range channels {
+		if !proto.Equal(channels[i], result[i]) {
+			return fmt.Errorf("channel %d: roundtrip mismatch", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,528 @@
+package main
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/pbz"
+)
+
+const (
+	fmtPackage          = protogen.GoImportPath("fmt")
+	ioPackage           = protogen.GoImportPath("io")
+	mathPackage         = protogen.GoImportPath("math")
+	pbmodelPackage      = protogen.GoImportPath("github.com/egonelbre/exp-protobuf-compression/pbmodel")
+	protoreflectPackage = protogen.GoImportPath("google.golang.org/protobuf/reflect/protoreflect")
+)
+
+// generate writes the compressors of the files to generate.
+func generate(gen *protogen.Plugin) {
+	for _, file := range gen.Files {
+		if file.Generate {
+			generateFile(gen, file)
+		}
+	}
+}
+
+// fileGenerator writes the compressors of the messages of a file.
+type fileGenerator struct {
+	gen  *protogen.Plugin
+	file *protogen.File
+	g    *protogen.GeneratedFile
+}
+
+// generateFile writes the compressors of the messages of file, and the
+// coders of its enums.
+func generateFile(gen *protogen.Plugin, file *protogen.File) {
+	messages, enums := collect(file.Messages, file.Enums)
+	if len(messages) == 0 {
+		return
+	}
+
+	fg := &fileGenerator{
+		gen:  gen,
+		file: file,
+		g:    gen.NewGeneratedFile(file.GeneratedFilenamePrefix+"_pbzip.pb.go", file.GoImportPath),
+	}
+	fg.g.P("// Code generated by protoc-gen-pbzip. DO NOT EDIT.")
+	fg.g.P("// source: ", file.Desc.Path())
+	fg.g.P()
+	fg.g.P("package ", file.GoPackageName)
+
+	for _, m := range messages {
+		fg.message(m)
+	}
+	for _, e := range enums {
+		fg.enum(e)
+	}
+}
+
+// collect returns the messages and enums declared in messages and enums,
+// including nested ones. Map entries are coded by the fields that use them.
+func collect(messages []*protogen.Message, enums []*protogen.Enum) ([]*protogen.Message, []*protogen.Enum) {
+	var allMessages []*protogen.Message
+	allEnums := append([]*protogen.Enum(nil), enums...)
+	for _, m := range messages {
+		if m.Desc.IsMapEntry() {
+			continue
+		}
+		allMessages = append(allMessages, m)
+		nestedMessages, nestedEnums := collect(m.Messages, m.Enums)
+		allMessages = append(allMessages, nestedMessages...)
+		allEnums = append(allEnums, nestedEnums...)
+	}
+	return allMessages, allEnums
+}
+
+// generated reports whether desc is declared in a file generated into the
+// Go package of the file, so that its unexported coders can be called.
+func (fg *fileGenerator) generated(importPath protogen.GoImportPath, desc protoreflect.Descriptor) bool {
+	if importPath != fg.file.GoImportPath {
+		return false
+	}
+	file, ok := fg.gen.FilesByPath[desc.ParentFile().Path()]
+	return ok && file.Generate
+}
+
+// unrolled reports whether the fields of m are coded by generated code.
+// Groups and fields with pbz hints are left to the reflection-based coder.
+func unrolled(m *protogen.Message) bool {
+	for _, field := range m.Fields {
+		if field.Desc.Kind() == protoreflect.GroupKind || hasHints(field) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasHints reports whether field has pbz options, see pbmodel hints.
+func hasHints(field *protogen.Field) bool {
+	opts, ok := field.Desc.Options().(*descriptorpb.FieldOptions)
+	if !ok || opts == nil {
+		return false
+	}
+	return proto.HasExtension(opts, pbz.E_Kind) || proto.HasExtension(opts, pbz.E_Range)
+}
+
+// message writes the compressor and the decompressor of m.
+func (fg *fileGenerator) message(m *protogen.Message) {
+	g := fg.g
+	name := m.GoIdent.GoName
+	encoder := g.QualifiedGoIdent(pbmodelPackage.Ident("GenEncoder"))
+	decoder := g.QualifiedGoIdent(pbmodelPackage.Ident("GenDecoder"))
+
+	g.P()
+	g.P("// Compress", name, " compresses x to w in the format of pbmodel.Compress.")
+	g.P("func Compress", name, "(x *", name, ", w ", ioPackage.Ident("Writer"), ") error {")
+	g.P("return ", pbmodelPackage.Ident("CompressGenerated"), "(w, func(e *", encoder, ") error {")
+	g.P("return pbzipEncode", name, "(e, x)")
+	g.P("})")
+	g.P("}")
+	g.P()
+	g.P("// Decompress", name, " decompresses data written by Compress", name, " or")
+	g.P("// pbmodel.Compress from r into x.")
+	g.P("func Decompress", name, "(r ", ioPackage.Ident("Reader"), ", x *", name, ") error {")
+	g.P("return ", pbmodelPackage.Ident("DecompressGenerated"), "(r, func(d *", decoder, ") error {")
+	g.P("return pbzipDecode", name, "(d, x)")
+	g.P("})")
+	g.P("}")
+
+	g.P()
+	g.P("func pbzipEncode", name, "(e *", encoder, ", x *", name, ") error {")
+	if !unrolled(m) {
+		g.P("return e.Message(x.ProtoReflect())")
+		g.P("}")
+	} else {
+		g.P("if err := e.Enter(); err != nil {")
+		g.P("return err")
+		g.P("}")
+		g.P("defer e.Leave()")
+		for _, field := range m.Fields {
+			fg.encodeField(field)
+		}
+		g.P("return nil")
+		g.P("}")
+	}
+
+	g.P()
+	g.P("func pbzipDecode", name, "(d *", decoder, ", x *", name, ") error {")
+	if !unrolled(m) {
+		g.P("return d.Message(x.ProtoReflect())")
+		g.P("}")
+		return
+	}
+	g.P("if err := d.Enter(); err != nil {")
+	g.P("return err")
+	g.P("}")
+	g.P("defer d.Leave()")
+	for _, field := range m.Fields {
+		fg.decodeField(field)
+	}
+	g.P("return nil")
+	g.P("}")
+}
+
+// encodeField writes the code that encodes the presence and the value of
+// field, like pbmodel's compressField.
+func (fg *fileGenerator) encodeField(field *protogen.Field) {
+	g := fg.g
+	name := field.Desc.Name()
+	errorf := g.QualifiedGoIdent(fmtPackage.Ident("Errorf"))
+
+	cond, value := fg.present(field)
+	g.P()
+	g.P("if ", cond, " {")
+	g.P("if err := e.Presence(true); err != nil {")
+	g.P("return ", errorf, `("field `, name, ` presence: %w", err)`)
+	g.P("}")
+	if value != "v" {
+		g.P("v := ", value)
+	}
+
+	switch {
+	case field.Desc.IsList():
+		g.P("if err := e.Length(len(v)); err != nil {")
+		g.P("return ", errorf, `("field `, name, `: list length: %w", err)`)
+		g.P("}")
+		g.P("for i, v := range v {")
+		g.P("if err := ", fg.encodeValue(field, "v"), "; err != nil {")
+		g.P("return ", errorf, `("field `, name, `: list element %d: %w", i, err)`)
+		g.P("}")
+		g.P("}")
+
+	case field.Desc.IsMap():
+		key, value := field.Message.Fields[0], field.Message.Fields[1]
+		g.P("if err := e.Length(len(v)); err != nil {")
+		g.P("return ", errorf, `("field `, name, `: map length: %w", err)`)
+		g.P("}")
+		if key.Desc.Kind() == protoreflect.BoolKind {
+			// Sorted bool keys are false before true
+			g.P("for _, k := range [...]bool{false, true} {")
+			g.P("if _, ok := v[k]; !ok {")
+			g.P("continue")
+			g.P("}")
+		} else {
+			g.P("for _, k := range ", pbmodelPackage.Ident("SortedKeys"), "(v) {")
+		}
+		g.P("if err := ", fg.encodeValue(key, "k"), "; err != nil {")
+		g.P("return ", errorf, `("field `, name, `: map key: %w", err)`)
+		g.P("}")
+		g.P("if err := ", fg.encodeValue(value, "v[k]"), "; err != nil {")
+		g.P("return ", errorf, `("field `, name, `: map value: %w", err)`)
+		g.P("}")
+		g.P("}")
+
+	default:
+		g.P("if err := ", fg.encodeValue(field, "v"), "; err != nil {")
+		g.P("return ", errorf, `("field `, name, `: %w", err)`)
+		g.P("}")
+	}
+
+	g.P("} else if err := e.Presence(false); err != nil {")
+	g.P("return ", errorf, `("field `, name, ` presence: %w", err)`)
+	g.P("}")
+}
+
+// present returns the condition under which field is present in x, the way
+// protoreflect.Message.Has reports it, and the value of the field when it
+// is present. The condition may declare v for the value.
+func (fg *fileGenerator) present(field *protogen.Field) (cond, value string) {
+	g := fg.g
+	desc := field.Desc
+	getter := "v := x.Get" + field.GoName + "(); "
+	switch {
+	case desc.IsList() || desc.IsMap():
+		return getter + "len(v) > 0", "v"
+	case field.Oneof != nil && !field.Oneof.Desc.IsSynthetic():
+		return "o, ok := x.Get" + field.Oneof.GoName + "().(*" + g.QualifiedGoIdent(field.GoIdent) + "); ok", "o." + field.GoName
+	case desc.Kind() == protoreflect.MessageKind:
+		return getter + "v != nil", "v"
+	case desc.HasPresence() && desc.Kind() == protoreflect.BytesKind:
+		return getter + "v != nil", "v"
+	case desc.HasPresence():
+		return "x != nil && x." + field.GoName + " != nil", "*x." + field.GoName
+	}
+
+	switch desc.Kind() {
+	case protoreflect.BoolKind:
+		return getter + "v", "v"
+	case protoreflect.StringKind:
+		return getter + `v != ""`, "v"
+	case protoreflect.BytesKind:
+		return getter + "len(v) > 0", "v"
+	case protoreflect.FloatKind:
+		// Negative zero is present
+		return getter + g.QualifiedGoIdent(mathPackage.Ident("Float32bits")) + "(v) != 0", "v"
+	case protoreflect.DoubleKind:
+		return getter + g.QualifiedGoIdent(mathPackage.Ident("Float64bits")) + "(v) != 0", "v"
+	default:
+		return getter + "v != 0", "v"
+	}
+}
+
+// encodeValue returns the call that encodes value, a value of field.
+func (fg *fileGenerator) encodeValue(field *protogen.Field, value string) string {
+	switch field.Desc.Kind() {
+	case protoreflect.MessageKind:
+		if fg.generated(field.Message.GoIdent.GoImportPath, field.Message.Desc) {
+			return "pbzipEncode" + field.Message.GoIdent.GoName + "(e, " + value + ")"
+		}
+		return "e.Message(" + value + ".ProtoReflect())"
+	case protoreflect.EnumKind:
+		if fg.generated(field.Enum.GoIdent.GoImportPath, field.Enum.Desc) {
+			return "pbzipEncode" + field.Enum.GoIdent.GoName + "(e, " + value + ")"
+		}
+		return "e.EnumOf(" + value + ")"
+	default:
+		return "e." + kindMethod(field.Desc.Kind()) + "(" + value + ")"
+	}
+}
+
+// decodeField writes the code that decodes a field written by the code of
+// encodeField into x.
+func (fg *fileGenerator) decodeField(field *protogen.Field) {
+	g := fg.g
+	name := field.Desc.Name()
+	errorf := g.QualifiedGoIdent(fmtPackage.Ident("Errorf"))
+
+	g.P()
+	g.P("if present, err := d.Presence(); err != nil {")
+	g.P("return ", errorf, `("field `, name, ` presence: %w", err)`)
+	g.P("} else if present {")
+
+	desc := field.Desc
+	oneof := field.Oneof != nil && !field.Oneof.Desc.IsSynthetic()
+	switch {
+	case desc.IsList():
+		g.P("length, err := d.ListLength()")
+		g.P("if err != nil {")
+		g.P("return ", errorf, `("field `, name, `: list length: %w", err)`)
+		g.P("}")
+		g.P("for i := 0; i < length; i++ {")
+		fg.decodeValue(field, "v", `"field `+string(name)+`: list element %d: %w", i`)
+		g.P("x.", field.GoName, " = append(x.", field.GoName, ", v)")
+		g.P("}")
+
+	case desc.IsMap():
+		key, value := field.Message.Fields[0], field.Message.Fields[1]
+		g.P("length, err := d.MapLength()")
+		g.P("if err != nil {")
+		g.P("return ", errorf, `("field `, name, `: map length: %w", err)`)
+		g.P("}")
+		g.P("if x.", field.GoName, " == nil {")
+		g.P("x.", field.GoName, " = make(map[", fg.goType(key), "]", fg.goType(value), ")")
+		g.P("}")
+		g.P("for i := 0; i < length; i++ {")
+		fg.decodeValue(key, "k", `"field `+string(name)+`: map key %d: %w", i`)
+		fg.decodeValue(value, "v", `"field `+string(name)+`: map value %d: %w", i`)
+		g.P("x.", field.GoName, "[k] = v")
+		g.P("}")
+
+	case desc.Kind() == protoreflect.MessageKind && oneof:
+		// Like protoreflect.Message.Mutable, decode into the set message
+		wrapper := g.QualifiedGoIdent(field.GoIdent)
+		g.P("o, ok := x.", field.Oneof.GoName, ".(*", wrapper, ")")
+		g.P("if !ok {")
+		g.P("o = &", wrapper, "{}")
+		g.P("x.", field.Oneof.GoName, " = o")
+		g.P("}")
+		g.P("if o.", field.GoName, " == nil {")
+		g.P("o.", field.GoName, " = new(", field.Message.GoIdent, ")")
+		g.P("}")
+		g.P("if err := ", fg.decodeMessage(field, "o."+field.GoName), "; err != nil {")
+		g.P("return ", errorf, `("field `, name, `: %w", err)`)
+		g.P("}")
+
+	case desc.Kind() == protoreflect.MessageKind:
+		g.P("if x.", field.GoName, " == nil {")
+		g.P("x.", field.GoName, " = new(", field.Message.GoIdent, ")")
+		g.P("}")
+		g.P("if err := ", fg.decodeMessage(field, "x."+field.GoName), "; err != nil {")
+		g.P("return ", errorf, `("field `, name, `: %w", err)`)
+		g.P("}")
+
+	default:
+		fg.decodeValue(field, "v", `"field `+string(name)+`: %w"`)
+		switch {
+		case oneof:
+			g.P("x.", field.Oneof.GoName, " = &", field.GoIdent, "{", field.GoName, ": v}")
+		case desc.HasPresence() && desc.Kind() == protoreflect.BytesKind:
+			// Present bytes are not nil
+			g.P("if v == nil {")
+			g.P("v = []byte{}")
+			g.P("}")
+			g.P("x.", field.GoName, " = v")
+		case desc.HasPresence():
+			g.P("x.", field.GoName, " = &v")
+		default:
+			g.P("x.", field.GoName, " = v")
+		}
+	}
+	g.P("}")
+}
+
+// decodeValue writes the code that declares v, a value of field, decoded
+// from the code of encodeValue. Errors are wrapped with the fmt.Errorf
+// format and arguments of wrap.
+func (fg *fileGenerator) decodeValue(field *protogen.Field, v, wrap string) {
+	g := fg.g
+	errorf := g.QualifiedGoIdent(fmtPackage.Ident("Errorf"))
+
+	switch kind := field.Desc.Kind(); {
+	case kind == protoreflect.MessageKind:
+		g.P(v, " := new(", field.Message.GoIdent, ")")
+		g.P("if err := ", fg.decodeMessage(field, v), "; err != nil {")
+		g.P("return ", errorf, "(", wrap, ", err)")
+		g.P("}")
+		return
+
+	case kind == protoreflect.EnumKind && !fg.generated(field.Enum.GoIdent.GoImportPath, field.Enum.Desc):
+		g.P(v, "Number, err := d.EnumOf(", field.Enum.GoIdent, "(0).Descriptor())")
+		g.P("if err != nil {")
+		g.P("return ", errorf, "(", wrap, ", err)")
+		g.P("}")
+		g.P(v, " := ", field.Enum.GoIdent, "(", v, "Number)")
+		return
+
+	case kind == protoreflect.EnumKind:
+		g.P(v, ", err := pbzipDecode", field.Enum.GoIdent.GoName, "(d)")
+
+	default:
+		g.P(v, ", err := d.", kindMethod(kind), "()")
+	}
+	g.P("if err != nil {")
+	g.P("return ", errorf, "(", wrap, ", err)")
+	g.P("}")
+}
+
+// decodeMessage returns the call that decodes into msg, a message of field.
+func (fg *fileGenerator) decodeMessage(field *protogen.Field, msg string) string {
+	if fg.generated(field.Message.GoIdent.GoImportPath, field.Message.Desc) {
+		return "pbzipDecode" + field.Message.GoIdent.GoName + "(d, " + msg + ")"
+	}
+	return "d.Message(" + msg + ".ProtoReflect())"
+}
+
+// goType returns the Go type of the values of field.
+func (fg *fileGenerator) goType(field *protogen.Field) string {
+	switch kind := field.Desc.Kind(); kind {
+	case protoreflect.MessageKind:
+		return "*" + fg.g.QualifiedGoIdent(field.Message.GoIdent)
+	case protoreflect.EnumKind:
+		return fg.g.QualifiedGoIdent(field.Enum.GoIdent)
+	case protoreflect.BoolKind:
+		return "bool"
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return "int32"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return "int64"
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return "uint32"
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "uint64"
+	case protoreflect.FloatKind:
+		return "float32"
+	case protoreflect.DoubleKind:
+		return "float64"
+	case protoreflect.StringKind:
+		return "string"
+	case protoreflect.BytesKind:
+		return "[]byte"
+	default:
+		panic(fmt.Sprintf("unsupported field kind: %v", kind))
+	}
+}
+
+// kindMethod returns the name of the GenEncoder and GenDecoder methods that
+// code scalar values of kind.
+func kindMethod(kind protoreflect.Kind) string {
+	switch kind {
+	case protoreflect.BoolKind:
+		return "Bool"
+	case protoreflect.Int32Kind:
+		return "Int32"
+	case protoreflect.Int64Kind:
+		return "Int64"
+	case protoreflect.Uint32Kind:
+		return "Uint32"
+	case protoreflect.Uint64Kind:
+		return "Uint64"
+	case protoreflect.Sint32Kind:
+		return "Sint32"
+	case protoreflect.Sint64Kind:
+		return "Sint64"
+	case protoreflect.Fixed32Kind:
+		return "Fixed32"
+	case protoreflect.Fixed64Kind:
+		return "Fixed64"
+	case protoreflect.Sfixed32Kind:
+		return "Sfixed32"
+	case protoreflect.Sfixed64Kind:
+		return "Sfixed64"
+	case protoreflect.FloatKind:
+		return "Float"
+	case protoreflect.DoubleKind:
+		return "Double"
+	case protoreflect.StringKind:
+		return "String"
+	case protoreflect.BytesKind:
+		return "Bytes"
+	default:
+		panic(fmt.Sprintf("unsupported field kind: %v", kind))
+	}
+}
+
+// enum writes the coders of the values of e, which code the index of the
+// value in the enum like pbmodel does, and undeclared values by number after
+// the escape.
+func (fg *fileGenerator) enum(e *protogen.Enum) {
+	g := fg.g
+	name := e.GoIdent.GoName
+	model := "pbzip" + name + "Model"
+	values := "pbzip" + name + "Values"
+
+	g.P()
+	g.P("// ", model, " is the model of the value indexes of ", name, " and the escape.")
+	g.P("var ", model, " = ", pbmodelPackage.Ident("EnumModel"), "(", len(e.Values), ")")
+	g.P()
+	g.P("// ", values, " are the values of ", name, " by index.")
+	g.P("var ", values, " = [...]", name, "{")
+	for _, value := range e.Values {
+		g.P(value.GoIdent, ",")
+	}
+	g.P("}")
+
+	g.P()
+	g.P("func pbzipEncode", name, "(e *", pbmodelPackage.Ident("GenEncoder"), ", v ", name, ") error {")
+	g.P("switch v {")
+	seen := map[protoreflect.EnumNumber]bool{}
+	for i, value := range e.Values {
+		// Aliases are coded as the first value with their number
+		if seen[value.Desc.Number()] {
+			continue
+		}
+		seen[value.Desc.Number()] = true
+		g.P("case ", value.GoIdent, ":")
+		g.P("return e.Enum(", i, ", ", model, ")")
+	}
+	g.P("}")
+	g.P("return e.UnknownEnum(", protoreflectPackage.Ident("EnumNumber"), "(v), ", model, ")")
+	g.P("}")
+
+	g.P()
+	g.P("func pbzipDecode", name, "(d *", pbmodelPackage.Ident("GenDecoder"), ") (", name, ", error) {")
+	g.P("index, err := d.Enum(", model, ")")
+	g.P("if err != nil {")
+	g.P("return 0, err")
+	g.P("}")
+	g.P("if index == len(", values, ") {")
+	g.P("number, err := d.UnknownEnum()")
+	g.P("return ", name, "(number), err")
+	g.P("}")
+	g.P("return ", values, "[index], nil")
+	g.P("}")
+}
@@ -0,0 +1,33 @@
+// Command protoc-gen-pbzip is a protoc plugin that generates compressors for
+// the messages of protobuf files:
+//
+//	protoc --go_out=. --pbzip_out=. foo.proto
+//
+// For every message Foo of foo.proto it writes, to foo_pbzip.pb.go next to
+// the output of protoc-gen-go:
+//
+//	func CompressFoo(x *Foo, w io.Writer) error
+//	func DecompressFoo(r io.Reader, x *Foo) error
+//
+// The compressors write the format of pbmodel.Compress without options, so
+// generated and reflection-based code can read each other's output. Like
+// protoc-gen-go does for marshaling, the generated code replaces the walk
+// over the message descriptors with unrolled code for the fields: presence
+// checks and values are read from the Go structs and enum indexes and
+// models are computed once. Messages of other Go packages, and messages with
+// features that only the reflection-based coder handles, such as pbz field
+// hints or groups, are coded through protoreflect.
+package main
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func main() {
+	protogen.Options{}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+		generate(gen)
+		return nil
+	})
+}
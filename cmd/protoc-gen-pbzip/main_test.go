@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gengo "google.golang.org/protobuf/cmd/protoc-gen-go/internal_gengo"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	pbziptest "github.com/egonelbre/exp-protobuf-compression/cmd/protoc-gen-pbzip/testdata"
+	"github.com/egonelbre/exp-protobuf-compression/internal/randproto"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
+)
+
+var update = flag.Bool("update", false, "rewrite the generated code in testdata")
+
+// TestGeneratedUpToDate fails when the checked in test messages and their
+// compressors differ from the output of protoc-gen-go and the generator.
+func TestGeneratedUpToDate(t *testing.T) {
+	files := testFiles()
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{files[len(files)-1].GetName()},
+		Parameter:      proto.String("paths=source_relative"),
+		ProtoFile:      files,
+	}
+	gen, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen.SupportedFeatures = gengo.SupportedFeatures
+	for _, file := range gen.Files {
+		if file.Generate {
+			gengo.GenerateFile(gen, file)
+		}
+	}
+	generate(gen)
+	resp := gen.Response()
+	if resp.Error != nil {
+		t.Fatalf("generate failed: %s", resp.GetError())
+	}
+
+	for _, file := range resp.File {
+		path := filepath.Join("testdata", file.GetName())
+		want := []byte(file.GetContent())
+		if *update {
+			if err := os.WriteFile(path, want, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date, run: go test ./protoc-gen-pbzip -update", path)
+		}
+	}
+}
+
+// generatedCoder is the generated compressor of a test message.
+type generatedCoder struct {
+	msg        proto.Message
+	compress   func(proto.Message, io.Writer) error
+	decompress func(io.Reader, proto.Message) error
+}
+
+// coderOf returns the generated coder of messages of type T.
+func coderOf[T proto.Message](compress func(T, io.Writer) error, decompress func(io.Reader, T) error) generatedCoder {
+	var zero T
+	return generatedCoder{
+		msg:        zero.ProtoReflect().Type().Zero().Interface(),
+		compress:   func(msg proto.Message, w io.Writer) error { return compress(msg.(T), w) },
+		decompress: func(r io.Reader, msg proto.Message) error { return decompress(r, msg.(T)) },
+	}
+}
+
+func generatedCoders() []generatedCoder {
+	return []generatedCoder{
+		coderOf(pbziptest.CompressSimpleMessage, pbziptest.DecompressSimpleMessage),
+		coderOf(pbziptest.CompressNumericMessage, pbziptest.DecompressNumericMessage),
+		coderOf(pbziptest.CompressRepeatedMessage, pbziptest.DecompressRepeatedMessage),
+		coderOf(pbziptest.CompressNestedMessage, pbziptest.DecompressNestedMessage),
+		coderOf(pbziptest.CompressDeepNesting, pbziptest.DecompressDeepNesting),
+		coderOf(pbziptest.CompressMessageWithEnum, pbziptest.DecompressMessageWithEnum),
+		coderOf(pbziptest.CompressMessageWithOneof, pbziptest.DecompressMessageWithOneof),
+		coderOf(pbziptest.CompressMessageWithBytes, pbziptest.DecompressMessageWithBytes),
+		coderOf(pbziptest.CompressMessageWithMap, pbziptest.DecompressMessageWithMap),
+		coderOf(pbziptest.CompressUserProfile, pbziptest.DecompressUserProfile),
+		coderOf(pbziptest.CompressEmptyMessage, pbziptest.DecompressEmptyMessage),
+		coderOf(pbziptest.CompressFeatures, pbziptest.DecompressFeatures),
+		coderOf(pbziptest.CompressHinted, pbziptest.DecompressHinted),
+	}
+}
+
+// TestGeneratedMatchesCompress checks that the generated compressors write
+// the bytes of pbmodel.Compress and read them back, for random messages.
+func TestGeneratedMatchesCompress(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, coder := range generatedCoders() {
+		name := coder.msg.ProtoReflect().Descriptor().Name()
+		for i := range 200 {
+			data := make([]byte, rng.Intn(512))
+			rng.Read(data)
+			msg := coder.msg.ProtoReflect().New()
+			randproto.Fill(msg, randproto.NewSource(data), 3)
+
+			var want, got bytes.Buffer
+			wantErr := pbmodel.Compress(msg.Interface(), &want)
+			gotErr := coder.compress(msg.Interface(), &got)
+			if (wantErr != nil) != (gotErr != nil) {
+				t.Fatalf("%s %d: Compress error %v, generated error %v", name, i, wantErr, gotErr)
+			}
+			if wantErr != nil {
+				continue
+			}
+			if !bytes.Equal(want.Bytes(), got.Bytes()) {
+				t.Fatalf("%s %d: generated output differs from Compress for %v", name, i, msg)
+			}
+
+			decoded := msg.New().Interface()
+			if err := coder.decompress(bytes.NewReader(got.Bytes()), decoded); err != nil {
+				t.Fatalf("%s %d: generated Decompress failed: %v", name, i, err)
+			}
+			if !proto.Equal(msg.Interface(), decoded) {
+				t.Fatalf("%s %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", name, i, msg, decoded)
+			}
+		}
+	}
+}
+
+func TestGeneratedTruncated(t *testing.T) {
+	msg := &pbziptest.UserProfile{
+		UserId:   42,
+		Username: "alice",
+		Bio:      "Writes compressors for protocol buffers.",
+		Tags:     []string{"go", "protobuf"},
+	}
+	var buf bytes.Buffer
+	if err := pbziptest.CompressUserProfile(msg, &buf); err != nil {
+		t.Fatal(err)
+	}
+	for n := range buf.Len() {
+		err := pbziptest.DecompressUserProfile(bytes.NewReader(buf.Bytes()[:n]), &pbziptest.UserProfile{})
+		if !errors.Is(err, pbmodel.ErrTruncated) {
+			t.Errorf("Decompress of %d of %d bytes: got %v, want ErrTruncated", n, buf.Len(), err)
+		}
+	}
+}
+
+func TestGeneratedDecompressAllocations(t *testing.T) {
+	msg := &pbziptest.NumericMessage{
+		Int32Field:   -42,
+		Uint64Field:  1 << 50,
+		Sint32Field:  -300,
+		Fixed32Field: 0xdeadbeef,
+		DoubleField:  59.437,
+	}
+	var buf bytes.Buffer
+	if err := pbziptest.CompressNumericMessage(msg, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	r := bytes.NewReader(nil)
+	decoded := &pbziptest.NumericMessage{}
+	decompress := func() {
+		r.Reset(buf.Bytes())
+		decoded.Reset()
+		if err := pbziptest.DecompressNumericMessage(r, decoded); err != nil {
+			t.Fatal(err)
+		}
+	}
+	decompress()
+	if allocs := testing.AllocsPerRun(100, decompress); allocs != 0 {
+		t.Errorf("generated Decompress allocates %v times per numeric message, want 0", allocs)
+	}
+}
+
+func benchmarkProfile() *pbziptest.UserProfile {
+	return &pbziptest.UserProfile{
+		UserId:        42,
+		Username:      "alice",
+		Email:         "alice@example.com",
+		FullName:      "Alice Example",
+		Bio:           "Writes compressors for protocol buffers.",
+		Tags:          []string{"go", "protobuf", "compression"},
+		AccountStatus: pbziptest.Status_ACTIVE,
+		Address:       &pbziptest.UserProfile_Address{City: "Tallinn", Country: "Estonia"},
+		CreatedAt:     1700000000,
+		Metadata:      map[string]string{"theme": "dark", "lang": "et"},
+	}
+}
+
+func BenchmarkCompressReflection(b *testing.B) {
+	msg := benchmarkProfile()
+	var buf bytes.Buffer
+	for b.Loop() {
+		buf.Reset()
+		if err := pbmodel.Compress(msg, &buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCompressGenerated(b *testing.B) {
+	msg := benchmarkProfile()
+	var buf bytes.Buffer
+	for b.Loop() {
+		buf.Reset()
+		if err := pbziptest.CompressUserProfile(msg, &buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
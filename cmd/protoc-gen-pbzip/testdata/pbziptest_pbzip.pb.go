@@ -0,0 +1,2423 @@
+// Code generated by protoc-gen-pbzip. DO NOT EDIT.
+// source: pbziptest.proto
+
+package pbziptest
+
+import (
+	fmt "fmt"
+	pbmodel "github.com/egonelbre/exp-protobuf-compression/pbmodel"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	io "io"
+	math "math"
+)
+
+// CompressSimpleMessage compresses x to w in the format of pbmodel.Compress.
+func CompressSimpleMessage(x *SimpleMessage, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeSimpleMessage(e, x)
+	})
+}
+
+// DecompressSimpleMessage decompresses data written by CompressSimpleMessage or
+// pbmodel.Compress from r into x.
+func DecompressSimpleMessage(r io.Reader, x *SimpleMessage) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeSimpleMessage(d, x)
+	})
+}
+
+func pbzipEncodeSimpleMessage(e *pbmodel.GenEncoder, x *SimpleMessage) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetId(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field id presence: %w", err)
+		}
+		if err := e.Int32(v); err != nil {
+			return fmt.Errorf("field id: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field id presence: %w", err)
+	}
+
+	if v := x.GetName(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field name presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field name: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field name presence: %w", err)
+	}
+
+	if v := x.GetActive(); v {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field active presence: %w", err)
+		}
+		if err := e.Bool(v); err != nil {
+			return fmt.Errorf("field active: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field active presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeSimpleMessage(d *pbmodel.GenDecoder, x *SimpleMessage) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field id presence: %w", err)
+	} else if present {
+		v, err := d.Int32()
+		if err != nil {
+			return fmt.Errorf("field id: %w", err)
+		}
+		x.Id = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field name presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field name: %w", err)
+		}
+		x.Name = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field active presence: %w", err)
+	} else if present {
+		v, err := d.Bool()
+		if err != nil {
+			return fmt.Errorf("field active: %w", err)
+		}
+		x.Active = v
+	}
+	return nil
+}
+
+// CompressNumericMessage compresses x to w in the format of pbmodel.Compress.
+func CompressNumericMessage(x *NumericMessage, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeNumericMessage(e, x)
+	})
+}
+
+// DecompressNumericMessage decompresses data written by CompressNumericMessage or
+// pbmodel.Compress from r into x.
+func DecompressNumericMessage(r io.Reader, x *NumericMessage) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeNumericMessage(d, x)
+	})
+}
+
+func pbzipEncodeNumericMessage(e *pbmodel.GenEncoder, x *NumericMessage) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetInt32Field(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field int32_field presence: %w", err)
+		}
+		if err := e.Int32(v); err != nil {
+			return fmt.Errorf("field int32_field: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field int32_field presence: %w", err)
+	}
+
+	if v := x.GetInt64Field(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field int64_field presence: %w", err)
+		}
+		if err := e.Int64(v); err != nil {
+			return fmt.Errorf("field int64_field: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field int64_field presence: %w", err)
+	}
+
+	if v := x.GetUint32Field(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field uint32_field presence: %w", err)
+		}
+		if err := e.Uint32(v); err != nil {
+			return fmt.Errorf("field uint32_field: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field uint32_field presence: %w", err)
+	}
+
+	if v := x.GetUint64Field(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field uint64_field presence: %w", err)
+		}
+		if err := e.Uint64(v); err != nil {
+			return fmt.Errorf("field uint64_field: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field uint64_field presence: %w", err)
+	}
+
+	if v := x.GetSint32Field(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field sint32_field presence: %w", err)
+		}
+		if err := e.Sint32(v); err != nil {
+			return fmt.Errorf("field sint32_field: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field sint32_field presence: %w", err)
+	}
+
+	if v := x.GetSint64Field(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field sint64_field presence: %w", err)
+		}
+		if err := e.Sint64(v); err != nil {
+			return fmt.Errorf("field sint64_field: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field sint64_field presence: %w", err)
+	}
+
+	if v := x.GetFixed32Field(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field fixed32_field presence: %w", err)
+		}
+		if err := e.Fixed32(v); err != nil {
+			return fmt.Errorf("field fixed32_field: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field fixed32_field presence: %w", err)
+	}
+
+	if v := x.GetFixed64Field(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field fixed64_field presence: %w", err)
+		}
+		if err := e.Fixed64(v); err != nil {
+			return fmt.Errorf("field fixed64_field: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field fixed64_field presence: %w", err)
+	}
+
+	if v := x.GetSfixed32Field(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field sfixed32_field presence: %w", err)
+		}
+		if err := e.Sfixed32(v); err != nil {
+			return fmt.Errorf("field sfixed32_field: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field sfixed32_field presence: %w", err)
+	}
+
+	if v := x.GetSfixed64Field(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field sfixed64_field presence: %w", err)
+		}
+		if err := e.Sfixed64(v); err != nil {
+			return fmt.Errorf("field sfixed64_field: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field sfixed64_field presence: %w", err)
+	}
+
+	if v := x.GetFloatField(); math.Float32bits(v) != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field float_field presence: %w", err)
+		}
+		if err := e.Float(v); err != nil {
+			return fmt.Errorf("field float_field: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field float_field presence: %w", err)
+	}
+
+	if v := x.GetDoubleField(); math.Float64bits(v) != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field double_field presence: %w", err)
+		}
+		if err := e.Double(v); err != nil {
+			return fmt.Errorf("field double_field: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field double_field presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeNumericMessage(d *pbmodel.GenDecoder, x *NumericMessage) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field int32_field presence: %w", err)
+	} else if present {
+		v, err := d.Int32()
+		if err != nil {
+			return fmt.Errorf("field int32_field: %w", err)
+		}
+		x.Int32Field = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field int64_field presence: %w", err)
+	} else if present {
+		v, err := d.Int64()
+		if err != nil {
+			return fmt.Errorf("field int64_field: %w", err)
+		}
+		x.Int64Field = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field uint32_field presence: %w", err)
+	} else if present {
+		v, err := d.Uint32()
+		if err != nil {
+			return fmt.Errorf("field uint32_field: %w", err)
+		}
+		x.Uint32Field = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field uint64_field presence: %w", err)
+	} else if present {
+		v, err := d.Uint64()
+		if err != nil {
+			return fmt.Errorf("field uint64_field: %w", err)
+		}
+		x.Uint64Field = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field sint32_field presence: %w", err)
+	} else if present {
+		v, err := d.Sint32()
+		if err != nil {
+			return fmt.Errorf("field sint32_field: %w", err)
+		}
+		x.Sint32Field = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field sint64_field presence: %w", err)
+	} else if present {
+		v, err := d.Sint64()
+		if err != nil {
+			return fmt.Errorf("field sint64_field: %w", err)
+		}
+		x.Sint64Field = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field fixed32_field presence: %w", err)
+	} else if present {
+		v, err := d.Fixed32()
+		if err != nil {
+			return fmt.Errorf("field fixed32_field: %w", err)
+		}
+		x.Fixed32Field = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field fixed64_field presence: %w", err)
+	} else if present {
+		v, err := d.Fixed64()
+		if err != nil {
+			return fmt.Errorf("field fixed64_field: %w", err)
+		}
+		x.Fixed64Field = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field sfixed32_field presence: %w", err)
+	} else if present {
+		v, err := d.Sfixed32()
+		if err != nil {
+			return fmt.Errorf("field sfixed32_field: %w", err)
+		}
+		x.Sfixed32Field = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field sfixed64_field presence: %w", err)
+	} else if present {
+		v, err := d.Sfixed64()
+		if err != nil {
+			return fmt.Errorf("field sfixed64_field: %w", err)
+		}
+		x.Sfixed64Field = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field float_field presence: %w", err)
+	} else if present {
+		v, err := d.Float()
+		if err != nil {
+			return fmt.Errorf("field float_field: %w", err)
+		}
+		x.FloatField = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field double_field presence: %w", err)
+	} else if present {
+		v, err := d.Double()
+		if err != nil {
+			return fmt.Errorf("field double_field: %w", err)
+		}
+		x.DoubleField = v
+	}
+	return nil
+}
+
+// CompressRepeatedMessage compresses x to w in the format of pbmodel.Compress.
+func CompressRepeatedMessage(x *RepeatedMessage, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeRepeatedMessage(e, x)
+	})
+}
+
+// DecompressRepeatedMessage decompresses data written by CompressRepeatedMessage or
+// pbmodel.Compress from r into x.
+func DecompressRepeatedMessage(r io.Reader, x *RepeatedMessage) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeRepeatedMessage(d, x)
+	})
+}
+
+func pbzipEncodeRepeatedMessage(e *pbmodel.GenEncoder, x *RepeatedMessage) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetNumbers(); len(v) > 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field numbers presence: %w", err)
+		}
+		if err := e.Length(len(v)); err != nil {
+			return fmt.Errorf("field numbers: list length: %w", err)
+		}
+		for i, v := range v {
+			if err := e.Int32(v); err != nil {
+				return fmt.Errorf("field numbers: list element %d: %w", i, err)
+			}
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field numbers presence: %w", err)
+	}
+
+	if v := x.GetWords(); len(v) > 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field words presence: %w", err)
+		}
+		if err := e.Length(len(v)); err != nil {
+			return fmt.Errorf("field words: list length: %w", err)
+		}
+		for i, v := range v {
+			if err := e.String(v); err != nil {
+				return fmt.Errorf("field words: list element %d: %w", i, err)
+			}
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field words presence: %w", err)
+	}
+
+	if v := x.GetFlags(); len(v) > 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field flags presence: %w", err)
+		}
+		if err := e.Length(len(v)); err != nil {
+			return fmt.Errorf("field flags: list length: %w", err)
+		}
+		for i, v := range v {
+			if err := e.Bool(v); err != nil {
+				return fmt.Errorf("field flags: list element %d: %w", i, err)
+			}
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field flags presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeRepeatedMessage(d *pbmodel.GenDecoder, x *RepeatedMessage) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field numbers presence: %w", err)
+	} else if present {
+		length, err := d.ListLength()
+		if err != nil {
+			return fmt.Errorf("field numbers: list length: %w", err)
+		}
+		for i := 0; i < length; i++ {
+			v, err := d.Int32()
+			if err != nil {
+				return fmt.Errorf("field numbers: list element %d: %w", i, err)
+			}
+			x.Numbers = append(x.Numbers, v)
+		}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field words presence: %w", err)
+	} else if present {
+		length, err := d.ListLength()
+		if err != nil {
+			return fmt.Errorf("field words: list length: %w", err)
+		}
+		for i := 0; i < length; i++ {
+			v, err := d.String()
+			if err != nil {
+				return fmt.Errorf("field words: list element %d: %w", i, err)
+			}
+			x.Words = append(x.Words, v)
+		}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field flags presence: %w", err)
+	} else if present {
+		length, err := d.ListLength()
+		if err != nil {
+			return fmt.Errorf("field flags: list length: %w", err)
+		}
+		for i := 0; i < length; i++ {
+			v, err := d.Bool()
+			if err != nil {
+				return fmt.Errorf("field flags: list element %d: %w", i, err)
+			}
+			x.Flags = append(x.Flags, v)
+		}
+	}
+	return nil
+}
+
+// CompressNestedMessage compresses x to w in the format of pbmodel.Compress.
+func CompressNestedMessage(x *NestedMessage, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeNestedMessage(e, x)
+	})
+}
+
+// DecompressNestedMessage decompresses data written by CompressNestedMessage or
+// pbmodel.Compress from r into x.
+func DecompressNestedMessage(r io.Reader, x *NestedMessage) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeNestedMessage(d, x)
+	})
+}
+
+func pbzipEncodeNestedMessage(e *pbmodel.GenEncoder, x *NestedMessage) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetInner(); v != nil {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field inner presence: %w", err)
+		}
+		if err := pbzipEncodeNestedMessage_Inner(e, v); err != nil {
+			return fmt.Errorf("field inner: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field inner presence: %w", err)
+	}
+
+	if v := x.GetInnerList(); len(v) > 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field inner_list presence: %w", err)
+		}
+		if err := e.Length(len(v)); err != nil {
+			return fmt.Errorf("field inner_list: list length: %w", err)
+		}
+		for i, v := range v {
+			if err := pbzipEncodeNestedMessage_Inner(e, v); err != nil {
+				return fmt.Errorf("field inner_list: list element %d: %w", i, err)
+			}
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field inner_list presence: %w", err)
+	}
+
+	if v := x.GetOuterField(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field outer_field presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field outer_field: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field outer_field presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeNestedMessage(d *pbmodel.GenDecoder, x *NestedMessage) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field inner presence: %w", err)
+	} else if present {
+		if x.Inner == nil {
+			x.Inner = new(NestedMessage_Inner)
+		}
+		if err := pbzipDecodeNestedMessage_Inner(d, x.Inner); err != nil {
+			return fmt.Errorf("field inner: %w", err)
+		}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field inner_list presence: %w", err)
+	} else if present {
+		length, err := d.ListLength()
+		if err != nil {
+			return fmt.Errorf("field inner_list: list length: %w", err)
+		}
+		for i := 0; i < length; i++ {
+			v := new(NestedMessage_Inner)
+			if err := pbzipDecodeNestedMessage_Inner(d, v); err != nil {
+				return fmt.Errorf("field inner_list: list element %d: %w", i, err)
+			}
+			x.InnerList = append(x.InnerList, v)
+		}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field outer_field presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field outer_field: %w", err)
+		}
+		x.OuterField = v
+	}
+	return nil
+}
+
+// CompressNestedMessage_Inner compresses x to w in the format of pbmodel.Compress.
+func CompressNestedMessage_Inner(x *NestedMessage_Inner, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeNestedMessage_Inner(e, x)
+	})
+}
+
+// DecompressNestedMessage_Inner decompresses data written by CompressNestedMessage_Inner or
+// pbmodel.Compress from r into x.
+func DecompressNestedMessage_Inner(r io.Reader, x *NestedMessage_Inner) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeNestedMessage_Inner(d, x)
+	})
+}
+
+func pbzipEncodeNestedMessage_Inner(e *pbmodel.GenEncoder, x *NestedMessage_Inner) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetValue(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field value presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field value: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field value presence: %w", err)
+	}
+
+	if v := x.GetCount(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field count presence: %w", err)
+		}
+		if err := e.Int32(v); err != nil {
+			return fmt.Errorf("field count: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field count presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeNestedMessage_Inner(d *pbmodel.GenDecoder, x *NestedMessage_Inner) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field value presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field value: %w", err)
+		}
+		x.Value = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field count presence: %w", err)
+	} else if present {
+		v, err := d.Int32()
+		if err != nil {
+			return fmt.Errorf("field count: %w", err)
+		}
+		x.Count = v
+	}
+	return nil
+}
+
+// CompressDeepNesting compresses x to w in the format of pbmodel.Compress.
+func CompressDeepNesting(x *DeepNesting, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeDeepNesting(e, x)
+	})
+}
+
+// DecompressDeepNesting decompresses data written by CompressDeepNesting or
+// pbmodel.Compress from r into x.
+func DecompressDeepNesting(r io.Reader, x *DeepNesting) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeDeepNesting(d, x)
+	})
+}
+
+func pbzipEncodeDeepNesting(e *pbmodel.GenEncoder, x *DeepNesting) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetLevel1(); v != nil {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field level1 presence: %w", err)
+		}
+		if err := pbzipEncodeDeepNesting_Level1(e, v); err != nil {
+			return fmt.Errorf("field level1: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field level1 presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeDeepNesting(d *pbmodel.GenDecoder, x *DeepNesting) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field level1 presence: %w", err)
+	} else if present {
+		if x.Level1 == nil {
+			x.Level1 = new(DeepNesting_Level1)
+		}
+		if err := pbzipDecodeDeepNesting_Level1(d, x.Level1); err != nil {
+			return fmt.Errorf("field level1: %w", err)
+		}
+	}
+	return nil
+}
+
+// CompressDeepNesting_Level1 compresses x to w in the format of pbmodel.Compress.
+func CompressDeepNesting_Level1(x *DeepNesting_Level1, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeDeepNesting_Level1(e, x)
+	})
+}
+
+// DecompressDeepNesting_Level1 decompresses data written by CompressDeepNesting_Level1 or
+// pbmodel.Compress from r into x.
+func DecompressDeepNesting_Level1(r io.Reader, x *DeepNesting_Level1) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeDeepNesting_Level1(d, x)
+	})
+}
+
+func pbzipEncodeDeepNesting_Level1(e *pbmodel.GenEncoder, x *DeepNesting_Level1) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetLevel2(); v != nil {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field level2 presence: %w", err)
+		}
+		if err := pbzipEncodeDeepNesting_Level1_Level2(e, v); err != nil {
+			return fmt.Errorf("field level2: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field level2 presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeDeepNesting_Level1(d *pbmodel.GenDecoder, x *DeepNesting_Level1) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field level2 presence: %w", err)
+	} else if present {
+		if x.Level2 == nil {
+			x.Level2 = new(DeepNesting_Level1_Level2)
+		}
+		if err := pbzipDecodeDeepNesting_Level1_Level2(d, x.Level2); err != nil {
+			return fmt.Errorf("field level2: %w", err)
+		}
+	}
+	return nil
+}
+
+// CompressDeepNesting_Level1_Level2 compresses x to w in the format of pbmodel.Compress.
+func CompressDeepNesting_Level1_Level2(x *DeepNesting_Level1_Level2, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeDeepNesting_Level1_Level2(e, x)
+	})
+}
+
+// DecompressDeepNesting_Level1_Level2 decompresses data written by CompressDeepNesting_Level1_Level2 or
+// pbmodel.Compress from r into x.
+func DecompressDeepNesting_Level1_Level2(r io.Reader, x *DeepNesting_Level1_Level2) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeDeepNesting_Level1_Level2(d, x)
+	})
+}
+
+func pbzipEncodeDeepNesting_Level1_Level2(e *pbmodel.GenEncoder, x *DeepNesting_Level1_Level2) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetLevel3(); v != nil {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field level3 presence: %w", err)
+		}
+		if err := pbzipEncodeDeepNesting_Level1_Level2_Level3(e, v); err != nil {
+			return fmt.Errorf("field level3: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field level3 presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeDeepNesting_Level1_Level2(d *pbmodel.GenDecoder, x *DeepNesting_Level1_Level2) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field level3 presence: %w", err)
+	} else if present {
+		if x.Level3 == nil {
+			x.Level3 = new(DeepNesting_Level1_Level2_Level3)
+		}
+		if err := pbzipDecodeDeepNesting_Level1_Level2_Level3(d, x.Level3); err != nil {
+			return fmt.Errorf("field level3: %w", err)
+		}
+	}
+	return nil
+}
+
+// CompressDeepNesting_Level1_Level2_Level3 compresses x to w in the format of pbmodel.Compress.
+func CompressDeepNesting_Level1_Level2_Level3(x *DeepNesting_Level1_Level2_Level3, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeDeepNesting_Level1_Level2_Level3(e, x)
+	})
+}
+
+// DecompressDeepNesting_Level1_Level2_Level3 decompresses data written by CompressDeepNesting_Level1_Level2_Level3 or
+// pbmodel.Compress from r into x.
+func DecompressDeepNesting_Level1_Level2_Level3(r io.Reader, x *DeepNesting_Level1_Level2_Level3) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeDeepNesting_Level1_Level2_Level3(d, x)
+	})
+}
+
+func pbzipEncodeDeepNesting_Level1_Level2_Level3(e *pbmodel.GenEncoder, x *DeepNesting_Level1_Level2_Level3) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetLevel4(); v != nil {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field level4 presence: %w", err)
+		}
+		if err := pbzipEncodeDeepNesting_Level1_Level2_Level3_Level4(e, v); err != nil {
+			return fmt.Errorf("field level4: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field level4 presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeDeepNesting_Level1_Level2_Level3(d *pbmodel.GenDecoder, x *DeepNesting_Level1_Level2_Level3) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field level4 presence: %w", err)
+	} else if present {
+		if x.Level4 == nil {
+			x.Level4 = new(DeepNesting_Level1_Level2_Level3_Level4)
+		}
+		if err := pbzipDecodeDeepNesting_Level1_Level2_Level3_Level4(d, x.Level4); err != nil {
+			return fmt.Errorf("field level4: %w", err)
+		}
+	}
+	return nil
+}
+
+// CompressDeepNesting_Level1_Level2_Level3_Level4 compresses x to w in the format of pbmodel.Compress.
+func CompressDeepNesting_Level1_Level2_Level3_Level4(x *DeepNesting_Level1_Level2_Level3_Level4, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeDeepNesting_Level1_Level2_Level3_Level4(e, x)
+	})
+}
+
+// DecompressDeepNesting_Level1_Level2_Level3_Level4 decompresses data written by CompressDeepNesting_Level1_Level2_Level3_Level4 or
+// pbmodel.Compress from r into x.
+func DecompressDeepNesting_Level1_Level2_Level3_Level4(r io.Reader, x *DeepNesting_Level1_Level2_Level3_Level4) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeDeepNesting_Level1_Level2_Level3_Level4(d, x)
+	})
+}
+
+func pbzipEncodeDeepNesting_Level1_Level2_Level3_Level4(e *pbmodel.GenEncoder, x *DeepNesting_Level1_Level2_Level3_Level4) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetLevel5(); v != nil {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field level5 presence: %w", err)
+		}
+		if err := pbzipEncodeDeepNesting_Level1_Level2_Level3_Level4_Level5(e, v); err != nil {
+			return fmt.Errorf("field level5: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field level5 presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeDeepNesting_Level1_Level2_Level3_Level4(d *pbmodel.GenDecoder, x *DeepNesting_Level1_Level2_Level3_Level4) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field level5 presence: %w", err)
+	} else if present {
+		if x.Level5 == nil {
+			x.Level5 = new(DeepNesting_Level1_Level2_Level3_Level4_Level5)
+		}
+		if err := pbzipDecodeDeepNesting_Level1_Level2_Level3_Level4_Level5(d, x.Level5); err != nil {
+			return fmt.Errorf("field level5: %w", err)
+		}
+	}
+	return nil
+}
+
+// CompressDeepNesting_Level1_Level2_Level3_Level4_Level5 compresses x to w in the format of pbmodel.Compress.
+func CompressDeepNesting_Level1_Level2_Level3_Level4_Level5(x *DeepNesting_Level1_Level2_Level3_Level4_Level5, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeDeepNesting_Level1_Level2_Level3_Level4_Level5(e, x)
+	})
+}
+
+// DecompressDeepNesting_Level1_Level2_Level3_Level4_Level5 decompresses data written by CompressDeepNesting_Level1_Level2_Level3_Level4_Level5 or
+// pbmodel.Compress from r into x.
+func DecompressDeepNesting_Level1_Level2_Level3_Level4_Level5(r io.Reader, x *DeepNesting_Level1_Level2_Level3_Level4_Level5) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeDeepNesting_Level1_Level2_Level3_Level4_Level5(d, x)
+	})
+}
+
+func pbzipEncodeDeepNesting_Level1_Level2_Level3_Level4_Level5(e *pbmodel.GenEncoder, x *DeepNesting_Level1_Level2_Level3_Level4_Level5) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetDeepValue(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field deep_value presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field deep_value: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field deep_value presence: %w", err)
+	}
+
+	if v := x.GetDeepNumber(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field deep_number presence: %w", err)
+		}
+		if err := e.Int32(v); err != nil {
+			return fmt.Errorf("field deep_number: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field deep_number presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeDeepNesting_Level1_Level2_Level3_Level4_Level5(d *pbmodel.GenDecoder, x *DeepNesting_Level1_Level2_Level3_Level4_Level5) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field deep_value presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field deep_value: %w", err)
+		}
+		x.DeepValue = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field deep_number presence: %w", err)
+	} else if present {
+		v, err := d.Int32()
+		if err != nil {
+			return fmt.Errorf("field deep_number: %w", err)
+		}
+		x.DeepNumber = v
+	}
+	return nil
+}
+
+// CompressMessageWithEnum compresses x to w in the format of pbmodel.Compress.
+func CompressMessageWithEnum(x *MessageWithEnum, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeMessageWithEnum(e, x)
+	})
+}
+
+// DecompressMessageWithEnum decompresses data written by CompressMessageWithEnum or
+// pbmodel.Compress from r into x.
+func DecompressMessageWithEnum(r io.Reader, x *MessageWithEnum) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeMessageWithEnum(d, x)
+	})
+}
+
+func pbzipEncodeMessageWithEnum(e *pbmodel.GenEncoder, x *MessageWithEnum) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetStatus(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field status presence: %w", err)
+		}
+		if err := pbzipEncodeStatus(e, v); err != nil {
+			return fmt.Errorf("field status: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field status presence: %w", err)
+	}
+
+	if v := x.GetDescription(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field description presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field description: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field description presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeMessageWithEnum(d *pbmodel.GenDecoder, x *MessageWithEnum) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field status presence: %w", err)
+	} else if present {
+		v, err := pbzipDecodeStatus(d)
+		if err != nil {
+			return fmt.Errorf("field status: %w", err)
+		}
+		x.Status = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field description presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field description: %w", err)
+		}
+		x.Description = v
+	}
+	return nil
+}
+
+// CompressMessageWithOneof compresses x to w in the format of pbmodel.Compress.
+func CompressMessageWithOneof(x *MessageWithOneof, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeMessageWithOneof(e, x)
+	})
+}
+
+// DecompressMessageWithOneof decompresses data written by CompressMessageWithOneof or
+// pbmodel.Compress from r into x.
+func DecompressMessageWithOneof(r io.Reader, x *MessageWithOneof) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeMessageWithOneof(d, x)
+	})
+}
+
+func pbzipEncodeMessageWithOneof(e *pbmodel.GenEncoder, x *MessageWithOneof) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if o, ok := x.GetValue().(*MessageWithOneof_StringValue); ok {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field string_value presence: %w", err)
+		}
+		v := o.StringValue
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field string_value: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field string_value presence: %w", err)
+	}
+
+	if o, ok := x.GetValue().(*MessageWithOneof_IntValue); ok {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field int_value presence: %w", err)
+		}
+		v := o.IntValue
+		if err := e.Int32(v); err != nil {
+			return fmt.Errorf("field int_value: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field int_value presence: %w", err)
+	}
+
+	if o, ok := x.GetValue().(*MessageWithOneof_BoolValue); ok {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field bool_value presence: %w", err)
+		}
+		v := o.BoolValue
+		if err := e.Bool(v); err != nil {
+			return fmt.Errorf("field bool_value: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field bool_value presence: %w", err)
+	}
+
+	if v := x.GetCommonField(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field common_field presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field common_field: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field common_field presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeMessageWithOneof(d *pbmodel.GenDecoder, x *MessageWithOneof) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field string_value presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field string_value: %w", err)
+		}
+		x.Value = &MessageWithOneof_StringValue{StringValue: v}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field int_value presence: %w", err)
+	} else if present {
+		v, err := d.Int32()
+		if err != nil {
+			return fmt.Errorf("field int_value: %w", err)
+		}
+		x.Value = &MessageWithOneof_IntValue{IntValue: v}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field bool_value presence: %w", err)
+	} else if present {
+		v, err := d.Bool()
+		if err != nil {
+			return fmt.Errorf("field bool_value: %w", err)
+		}
+		x.Value = &MessageWithOneof_BoolValue{BoolValue: v}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field common_field presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field common_field: %w", err)
+		}
+		x.CommonField = v
+	}
+	return nil
+}
+
+// CompressMessageWithBytes compresses x to w in the format of pbmodel.Compress.
+func CompressMessageWithBytes(x *MessageWithBytes, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeMessageWithBytes(e, x)
+	})
+}
+
+// DecompressMessageWithBytes decompresses data written by CompressMessageWithBytes or
+// pbmodel.Compress from r into x.
+func DecompressMessageWithBytes(r io.Reader, x *MessageWithBytes) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeMessageWithBytes(d, x)
+	})
+}
+
+func pbzipEncodeMessageWithBytes(e *pbmodel.GenEncoder, x *MessageWithBytes) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetData(); len(v) > 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field data presence: %w", err)
+		}
+		if err := e.Bytes(v); err != nil {
+			return fmt.Errorf("field data: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field data presence: %w", err)
+	}
+
+	if v := x.GetLabel(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field label presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field label: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field label presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeMessageWithBytes(d *pbmodel.GenDecoder, x *MessageWithBytes) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field data presence: %w", err)
+	} else if present {
+		v, err := d.Bytes()
+		if err != nil {
+			return fmt.Errorf("field data: %w", err)
+		}
+		x.Data = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field label presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field label: %w", err)
+		}
+		x.Label = v
+	}
+	return nil
+}
+
+// CompressMessageWithMap compresses x to w in the format of pbmodel.Compress.
+func CompressMessageWithMap(x *MessageWithMap, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeMessageWithMap(e, x)
+	})
+}
+
+// DecompressMessageWithMap decompresses data written by CompressMessageWithMap or
+// pbmodel.Compress from r into x.
+func DecompressMessageWithMap(r io.Reader, x *MessageWithMap) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeMessageWithMap(d, x)
+	})
+}
+
+func pbzipEncodeMessageWithMap(e *pbmodel.GenEncoder, x *MessageWithMap) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetCounts(); len(v) > 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field counts presence: %w", err)
+		}
+		if err := e.Length(len(v)); err != nil {
+			return fmt.Errorf("field counts: map length: %w", err)
+		}
+		for _, k := range pbmodel.SortedKeys(v) {
+			if err := e.String(k); err != nil {
+				return fmt.Errorf("field counts: map key: %w", err)
+			}
+			if err := e.Int32(v[k]); err != nil {
+				return fmt.Errorf("field counts: map value: %w", err)
+			}
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field counts presence: %w", err)
+	}
+
+	if v := x.GetLookup(); len(v) > 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field lookup presence: %w", err)
+		}
+		if err := e.Length(len(v)); err != nil {
+			return fmt.Errorf("field lookup: map length: %w", err)
+		}
+		for _, k := range pbmodel.SortedKeys(v) {
+			if err := e.Int32(k); err != nil {
+				return fmt.Errorf("field lookup: map key: %w", err)
+			}
+			if err := e.String(v[k]); err != nil {
+				return fmt.Errorf("field lookup: map value: %w", err)
+			}
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field lookup presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeMessageWithMap(d *pbmodel.GenDecoder, x *MessageWithMap) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field counts presence: %w", err)
+	} else if present {
+		length, err := d.MapLength()
+		if err != nil {
+			return fmt.Errorf("field counts: map length: %w", err)
+		}
+		if x.Counts == nil {
+			x.Counts = make(map[string]int32)
+		}
+		for i := 0; i < length; i++ {
+			k, err := d.String()
+			if err != nil {
+				return fmt.Errorf("field counts: map key %d: %w", i, err)
+			}
+			v, err := d.Int32()
+			if err != nil {
+				return fmt.Errorf("field counts: map value %d: %w", i, err)
+			}
+			x.Counts[k] = v
+		}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field lookup presence: %w", err)
+	} else if present {
+		length, err := d.MapLength()
+		if err != nil {
+			return fmt.Errorf("field lookup: map length: %w", err)
+		}
+		if x.Lookup == nil {
+			x.Lookup = make(map[int32]string)
+		}
+		for i := 0; i < length; i++ {
+			k, err := d.Int32()
+			if err != nil {
+				return fmt.Errorf("field lookup: map key %d: %w", i, err)
+			}
+			v, err := d.String()
+			if err != nil {
+				return fmt.Errorf("field lookup: map value %d: %w", i, err)
+			}
+			x.Lookup[k] = v
+		}
+	}
+	return nil
+}
+
+// CompressUserProfile compresses x to w in the format of pbmodel.Compress.
+func CompressUserProfile(x *UserProfile, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeUserProfile(e, x)
+	})
+}
+
+// DecompressUserProfile decompresses data written by CompressUserProfile or
+// pbmodel.Compress from r into x.
+func DecompressUserProfile(r io.Reader, x *UserProfile) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeUserProfile(d, x)
+	})
+}
+
+func pbzipEncodeUserProfile(e *pbmodel.GenEncoder, x *UserProfile) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetUserId(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field user_id presence: %w", err)
+		}
+		if err := e.Int64(v); err != nil {
+			return fmt.Errorf("field user_id: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field user_id presence: %w", err)
+	}
+
+	if v := x.GetUsername(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field username presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field username: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field username presence: %w", err)
+	}
+
+	if v := x.GetEmail(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field email presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field email: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field email presence: %w", err)
+	}
+
+	if v := x.GetFullName(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field full_name presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field full_name: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field full_name presence: %w", err)
+	}
+
+	if v := x.GetBio(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field bio presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field bio: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field bio presence: %w", err)
+	}
+
+	if v := x.GetTags(); len(v) > 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field tags presence: %w", err)
+		}
+		if err := e.Length(len(v)); err != nil {
+			return fmt.Errorf("field tags: list length: %w", err)
+		}
+		for i, v := range v {
+			if err := e.String(v); err != nil {
+				return fmt.Errorf("field tags: list element %d: %w", i, err)
+			}
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field tags presence: %w", err)
+	}
+
+	if v := x.GetAccountStatus(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field account_status presence: %w", err)
+		}
+		if err := pbzipEncodeStatus(e, v); err != nil {
+			return fmt.Errorf("field account_status: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field account_status presence: %w", err)
+	}
+
+	if v := x.GetAddress(); v != nil {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field address presence: %w", err)
+		}
+		if err := pbzipEncodeUserProfile_Address(e, v); err != nil {
+			return fmt.Errorf("field address: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field address presence: %w", err)
+	}
+
+	if v := x.GetCreatedAt(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field created_at presence: %w", err)
+		}
+		if err := e.Int64(v); err != nil {
+			return fmt.Errorf("field created_at: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field created_at presence: %w", err)
+	}
+
+	if v := x.GetUpdatedAt(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field updated_at presence: %w", err)
+		}
+		if err := e.Int64(v); err != nil {
+			return fmt.Errorf("field updated_at: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field updated_at presence: %w", err)
+	}
+
+	if v := x.GetMetadata(); len(v) > 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field metadata presence: %w", err)
+		}
+		if err := e.Length(len(v)); err != nil {
+			return fmt.Errorf("field metadata: map length: %w", err)
+		}
+		for _, k := range pbmodel.SortedKeys(v) {
+			if err := e.String(k); err != nil {
+				return fmt.Errorf("field metadata: map key: %w", err)
+			}
+			if err := e.String(v[k]); err != nil {
+				return fmt.Errorf("field metadata: map value: %w", err)
+			}
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field metadata presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeUserProfile(d *pbmodel.GenDecoder, x *UserProfile) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field user_id presence: %w", err)
+	} else if present {
+		v, err := d.Int64()
+		if err != nil {
+			return fmt.Errorf("field user_id: %w", err)
+		}
+		x.UserId = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field username presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field username: %w", err)
+		}
+		x.Username = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field email presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field email: %w", err)
+		}
+		x.Email = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field full_name presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field full_name: %w", err)
+		}
+		x.FullName = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field bio presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field bio: %w", err)
+		}
+		x.Bio = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field tags presence: %w", err)
+	} else if present {
+		length, err := d.ListLength()
+		if err != nil {
+			return fmt.Errorf("field tags: list length: %w", err)
+		}
+		for i := 0; i < length; i++ {
+			v, err := d.String()
+			if err != nil {
+				return fmt.Errorf("field tags: list element %d: %w", i, err)
+			}
+			x.Tags = append(x.Tags, v)
+		}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field account_status presence: %w", err)
+	} else if present {
+		v, err := pbzipDecodeStatus(d)
+		if err != nil {
+			return fmt.Errorf("field account_status: %w", err)
+		}
+		x.AccountStatus = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field address presence: %w", err)
+	} else if present {
+		if x.Address == nil {
+			x.Address = new(UserProfile_Address)
+		}
+		if err := pbzipDecodeUserProfile_Address(d, x.Address); err != nil {
+			return fmt.Errorf("field address: %w", err)
+		}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field created_at presence: %w", err)
+	} else if present {
+		v, err := d.Int64()
+		if err != nil {
+			return fmt.Errorf("field created_at: %w", err)
+		}
+		x.CreatedAt = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field updated_at presence: %w", err)
+	} else if present {
+		v, err := d.Int64()
+		if err != nil {
+			return fmt.Errorf("field updated_at: %w", err)
+		}
+		x.UpdatedAt = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field metadata presence: %w", err)
+	} else if present {
+		length, err := d.MapLength()
+		if err != nil {
+			return fmt.Errorf("field metadata: map length: %w", err)
+		}
+		if x.Metadata == nil {
+			x.Metadata = make(map[string]string)
+		}
+		for i := 0; i < length; i++ {
+			k, err := d.String()
+			if err != nil {
+				return fmt.Errorf("field metadata: map key %d: %w", i, err)
+			}
+			v, err := d.String()
+			if err != nil {
+				return fmt.Errorf("field metadata: map value %d: %w", i, err)
+			}
+			x.Metadata[k] = v
+		}
+	}
+	return nil
+}
+
+// CompressUserProfile_Address compresses x to w in the format of pbmodel.Compress.
+func CompressUserProfile_Address(x *UserProfile_Address, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeUserProfile_Address(e, x)
+	})
+}
+
+// DecompressUserProfile_Address decompresses data written by CompressUserProfile_Address or
+// pbmodel.Compress from r into x.
+func DecompressUserProfile_Address(r io.Reader, x *UserProfile_Address) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeUserProfile_Address(d, x)
+	})
+}
+
+func pbzipEncodeUserProfile_Address(e *pbmodel.GenEncoder, x *UserProfile_Address) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if v := x.GetStreet(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field street presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field street: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field street presence: %w", err)
+	}
+
+	if v := x.GetCity(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field city presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field city: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field city presence: %w", err)
+	}
+
+	if v := x.GetState(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field state presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field state: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field state presence: %w", err)
+	}
+
+	if v := x.GetZip(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field zip presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field zip: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field zip presence: %w", err)
+	}
+
+	if v := x.GetCountry(); v != "" {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field country presence: %w", err)
+		}
+		if err := e.String(v); err != nil {
+			return fmt.Errorf("field country: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field country presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeUserProfile_Address(d *pbmodel.GenDecoder, x *UserProfile_Address) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field street presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field street: %w", err)
+		}
+		x.Street = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field city presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field city: %w", err)
+		}
+		x.City = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field state presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field state: %w", err)
+		}
+		x.State = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field zip presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field zip: %w", err)
+		}
+		x.Zip = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field country presence: %w", err)
+	} else if present {
+		v, err := d.String()
+		if err != nil {
+			return fmt.Errorf("field country: %w", err)
+		}
+		x.Country = v
+	}
+	return nil
+}
+
+// CompressEmptyMessage compresses x to w in the format of pbmodel.Compress.
+func CompressEmptyMessage(x *EmptyMessage, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeEmptyMessage(e, x)
+	})
+}
+
+// DecompressEmptyMessage decompresses data written by CompressEmptyMessage or
+// pbmodel.Compress from r into x.
+func DecompressEmptyMessage(r io.Reader, x *EmptyMessage) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeEmptyMessage(d, x)
+	})
+}
+
+func pbzipEncodeEmptyMessage(e *pbmodel.GenEncoder, x *EmptyMessage) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+	return nil
+}
+
+func pbzipDecodeEmptyMessage(d *pbmodel.GenDecoder, x *EmptyMessage) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+	return nil
+}
+
+// CompressFeatures compresses x to w in the format of pbmodel.Compress.
+func CompressFeatures(x *Features, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeFeatures(e, x)
+	})
+}
+
+// DecompressFeatures decompresses data written by CompressFeatures or
+// pbmodel.Compress from r into x.
+func DecompressFeatures(r io.Reader, x *Features) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeFeatures(d, x)
+	})
+}
+
+func pbzipEncodeFeatures(e *pbmodel.GenEncoder, x *Features) error {
+	if err := e.Enter(); err != nil {
+		return err
+	}
+	defer e.Leave()
+
+	if x != nil && x.MaybeCount != nil {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field maybe_count presence: %w", err)
+		}
+		v := *x.MaybeCount
+		if err := e.Int32(v); err != nil {
+			return fmt.Errorf("field maybe_count: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field maybe_count presence: %w", err)
+	}
+
+	if v := x.GetMaybeData(); v != nil {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field maybe_data presence: %w", err)
+		}
+		if err := e.Bytes(v); err != nil {
+			return fmt.Errorf("field maybe_data: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field maybe_data presence: %w", err)
+	}
+
+	if x != nil && x.MaybeLevel != nil {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field maybe_level presence: %w", err)
+		}
+		v := *x.MaybeLevel
+		if err := pbzipEncodeFeatures_Level(e, v); err != nil {
+			return fmt.Errorf("field maybe_level: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field maybe_level presence: %w", err)
+	}
+
+	if v := x.GetLevel(); v != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field level presence: %w", err)
+		}
+		if err := pbzipEncodeFeatures_Level(e, v); err != nil {
+			return fmt.Errorf("field level: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field level presence: %w", err)
+	}
+
+	if v := x.GetLevels(); len(v) > 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field levels presence: %w", err)
+		}
+		if err := e.Length(len(v)); err != nil {
+			return fmt.Errorf("field levels: list length: %w", err)
+		}
+		for i, v := range v {
+			if err := pbzipEncodeFeatures_Level(e, v); err != nil {
+				return fmt.Errorf("field levels: list element %d: %w", i, err)
+			}
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field levels presence: %w", err)
+	}
+
+	if v := x.GetFlags(); len(v) > 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field flags presence: %w", err)
+		}
+		if err := e.Length(len(v)); err != nil {
+			return fmt.Errorf("field flags: map length: %w", err)
+		}
+		for _, k := range [...]bool{false, true} {
+			if _, ok := v[k]; !ok {
+				continue
+			}
+			if err := e.Bool(k); err != nil {
+				return fmt.Errorf("field flags: map key: %w", err)
+			}
+			if err := e.String(v[k]); err != nil {
+				return fmt.Errorf("field flags: map value: %w", err)
+			}
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field flags presence: %w", err)
+	}
+
+	if v := x.GetChildren(); len(v) > 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field children presence: %w", err)
+		}
+		if err := e.Length(len(v)); err != nil {
+			return fmt.Errorf("field children: map length: %w", err)
+		}
+		for _, k := range pbmodel.SortedKeys(v) {
+			if err := e.String(k); err != nil {
+				return fmt.Errorf("field children: map key: %w", err)
+			}
+			if err := pbzipEncodeSimpleMessage(e, v[k]); err != nil {
+				return fmt.Errorf("field children: map value: %w", err)
+			}
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field children presence: %w", err)
+	}
+
+	if o, ok := x.GetChoice().(*Features_Child); ok {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field child presence: %w", err)
+		}
+		v := o.Child
+		if err := pbzipEncodeSimpleMessage(e, v); err != nil {
+			return fmt.Errorf("field child: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field child presence: %w", err)
+	}
+
+	if o, ok := x.GetChoice().(*Features_ChoiceLevel); ok {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field choice_level presence: %w", err)
+		}
+		v := o.ChoiceLevel
+		if err := pbzipEncodeFeatures_Level(e, v); err != nil {
+			return fmt.Errorf("field choice_level: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field choice_level presence: %w", err)
+	}
+
+	if o, ok := x.GetChoice().(*Features_Ratio); ok {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field ratio presence: %w", err)
+		}
+		v := o.Ratio
+		if err := e.Double(v); err != nil {
+			return fmt.Errorf("field ratio: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field ratio presence: %w", err)
+	}
+
+	if v := x.GetCreated(); v != nil {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field created presence: %w", err)
+		}
+		if err := e.Message(v.ProtoReflect()); err != nil {
+			return fmt.Errorf("field created: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field created presence: %w", err)
+	}
+
+	if v := x.GetHistory(); len(v) > 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field history presence: %w", err)
+		}
+		if err := e.Length(len(v)); err != nil {
+			return fmt.Errorf("field history: list length: %w", err)
+		}
+		for i, v := range v {
+			if err := e.Message(v.ProtoReflect()); err != nil {
+				return fmt.Errorf("field history: list element %d: %w", i, err)
+			}
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field history presence: %w", err)
+	}
+
+	if v := x.GetScore(); math.Float32bits(v) != 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field score presence: %w", err)
+		}
+		if err := e.Float(v); err != nil {
+			return fmt.Errorf("field score: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field score presence: %w", err)
+	}
+
+	if v := x.GetHinted(); v != nil {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field hinted presence: %w", err)
+		}
+		if err := pbzipEncodeHinted(e, v); err != nil {
+			return fmt.Errorf("field hinted: %w", err)
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field hinted presence: %w", err)
+	}
+
+	if v := x.GetStatuses(); len(v) > 0 {
+		if err := e.Presence(true); err != nil {
+			return fmt.Errorf("field statuses presence: %w", err)
+		}
+		if err := e.Length(len(v)); err != nil {
+			return fmt.Errorf("field statuses: map length: %w", err)
+		}
+		for _, k := range pbmodel.SortedKeys(v) {
+			if err := e.Int64(k); err != nil {
+				return fmt.Errorf("field statuses: map key: %w", err)
+			}
+			if err := pbzipEncodeStatus(e, v[k]); err != nil {
+				return fmt.Errorf("field statuses: map value: %w", err)
+			}
+		}
+	} else if err := e.Presence(false); err != nil {
+		return fmt.Errorf("field statuses presence: %w", err)
+	}
+	return nil
+}
+
+func pbzipDecodeFeatures(d *pbmodel.GenDecoder, x *Features) error {
+	if err := d.Enter(); err != nil {
+		return err
+	}
+	defer d.Leave()
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field maybe_count presence: %w", err)
+	} else if present {
+		v, err := d.Int32()
+		if err != nil {
+			return fmt.Errorf("field maybe_count: %w", err)
+		}
+		x.MaybeCount = &v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field maybe_data presence: %w", err)
+	} else if present {
+		v, err := d.Bytes()
+		if err != nil {
+			return fmt.Errorf("field maybe_data: %w", err)
+		}
+		if v == nil {
+			v = []byte{}
+		}
+		x.MaybeData = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field maybe_level presence: %w", err)
+	} else if present {
+		v, err := pbzipDecodeFeatures_Level(d)
+		if err != nil {
+			return fmt.Errorf("field maybe_level: %w", err)
+		}
+		x.MaybeLevel = &v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field level presence: %w", err)
+	} else if present {
+		v, err := pbzipDecodeFeatures_Level(d)
+		if err != nil {
+			return fmt.Errorf("field level: %w", err)
+		}
+		x.Level = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field levels presence: %w", err)
+	} else if present {
+		length, err := d.ListLength()
+		if err != nil {
+			return fmt.Errorf("field levels: list length: %w", err)
+		}
+		for i := 0; i < length; i++ {
+			v, err := pbzipDecodeFeatures_Level(d)
+			if err != nil {
+				return fmt.Errorf("field levels: list element %d: %w", i, err)
+			}
+			x.Levels = append(x.Levels, v)
+		}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field flags presence: %w", err)
+	} else if present {
+		length, err := d.MapLength()
+		if err != nil {
+			return fmt.Errorf("field flags: map length: %w", err)
+		}
+		if x.Flags == nil {
+			x.Flags = make(map[bool]string)
+		}
+		for i := 0; i < length; i++ {
+			k, err := d.Bool()
+			if err != nil {
+				return fmt.Errorf("field flags: map key %d: %w", i, err)
+			}
+			v, err := d.String()
+			if err != nil {
+				return fmt.Errorf("field flags: map value %d: %w", i, err)
+			}
+			x.Flags[k] = v
+		}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field children presence: %w", err)
+	} else if present {
+		length, err := d.MapLength()
+		if err != nil {
+			return fmt.Errorf("field children: map length: %w", err)
+		}
+		if x.Children == nil {
+			x.Children = make(map[string]*SimpleMessage)
+		}
+		for i := 0; i < length; i++ {
+			k, err := d.String()
+			if err != nil {
+				return fmt.Errorf("field children: map key %d: %w", i, err)
+			}
+			v := new(SimpleMessage)
+			if err := pbzipDecodeSimpleMessage(d, v); err != nil {
+				return fmt.Errorf("field children: map value %d: %w", i, err)
+			}
+			x.Children[k] = v
+		}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field child presence: %w", err)
+	} else if present {
+		o, ok := x.Choice.(*Features_Child)
+		if !ok {
+			o = &Features_Child{}
+			x.Choice = o
+		}
+		if o.Child == nil {
+			o.Child = new(SimpleMessage)
+		}
+		if err := pbzipDecodeSimpleMessage(d, o.Child); err != nil {
+			return fmt.Errorf("field child: %w", err)
+		}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field choice_level presence: %w", err)
+	} else if present {
+		v, err := pbzipDecodeFeatures_Level(d)
+		if err != nil {
+			return fmt.Errorf("field choice_level: %w", err)
+		}
+		x.Choice = &Features_ChoiceLevel{ChoiceLevel: v}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field ratio presence: %w", err)
+	} else if present {
+		v, err := d.Double()
+		if err != nil {
+			return fmt.Errorf("field ratio: %w", err)
+		}
+		x.Choice = &Features_Ratio{Ratio: v}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field created presence: %w", err)
+	} else if present {
+		if x.Created == nil {
+			x.Created = new(timestamppb.Timestamp)
+		}
+		if err := d.Message(x.Created.ProtoReflect()); err != nil {
+			return fmt.Errorf("field created: %w", err)
+		}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field history presence: %w", err)
+	} else if present {
+		length, err := d.ListLength()
+		if err != nil {
+			return fmt.Errorf("field history: list length: %w", err)
+		}
+		for i := 0; i < length; i++ {
+			v := new(timestamppb.Timestamp)
+			if err := d.Message(v.ProtoReflect()); err != nil {
+				return fmt.Errorf("field history: list element %d: %w", i, err)
+			}
+			x.History = append(x.History, v)
+		}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field score presence: %w", err)
+	} else if present {
+		v, err := d.Float()
+		if err != nil {
+			return fmt.Errorf("field score: %w", err)
+		}
+		x.Score = v
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field hinted presence: %w", err)
+	} else if present {
+		if x.Hinted == nil {
+			x.Hinted = new(Hinted)
+		}
+		if err := pbzipDecodeHinted(d, x.Hinted); err != nil {
+			return fmt.Errorf("field hinted: %w", err)
+		}
+	}
+
+	if present, err := d.Presence(); err != nil {
+		return fmt.Errorf("field statuses presence: %w", err)
+	} else if present {
+		length, err := d.MapLength()
+		if err != nil {
+			return fmt.Errorf("field statuses: map length: %w", err)
+		}
+		if x.Statuses == nil {
+			x.Statuses = make(map[int64]Status)
+		}
+		for i := 0; i < length; i++ {
+			k, err := d.Int64()
+			if err != nil {
+				return fmt.Errorf("field statuses: map key %d: %w", i, err)
+			}
+			v, err := pbzipDecodeStatus(d)
+			if err != nil {
+				return fmt.Errorf("field statuses: map value %d: %w", i, err)
+			}
+			x.Statuses[k] = v
+		}
+	}
+	return nil
+}
+
+// CompressHinted compresses x to w in the format of pbmodel.Compress.
+func CompressHinted(x *Hinted, w io.Writer) error {
+	return pbmodel.CompressGenerated(w, func(e *pbmodel.GenEncoder) error {
+		return pbzipEncodeHinted(e, x)
+	})
+}
+
+// DecompressHinted decompresses data written by CompressHinted or
+// pbmodel.Compress from r into x.
+func DecompressHinted(r io.Reader, x *Hinted) error {
+	return pbmodel.DecompressGenerated(r, func(d *pbmodel.GenDecoder) error {
+		return pbzipDecodeHinted(d, x)
+	})
+}
+
+func pbzipEncodeHinted(e *pbmodel.GenEncoder, x *Hinted) error {
+	return e.Message(x.ProtoReflect())
+}
+
+func pbzipDecodeHinted(d *pbmodel.GenDecoder, x *Hinted) error {
+	return d.Message(x.ProtoReflect())
+}
+
+// pbzipStatusModel is the model of the value indexes of Status and the escape.
+var pbzipStatusModel = pbmodel.EnumModel(5)
+
+// pbzipStatusValues are the values of Status by index.
+var pbzipStatusValues = [...]Status{
+	Status_UNKNOWN,
+	Status_PENDING,
+	Status_ACTIVE,
+	Status_COMPLETED,
+	Status_FAILED,
+}
+
+func pbzipEncodeStatus(e *pbmodel.GenEncoder, v Status) error {
+	switch v {
+	case Status_UNKNOWN:
+		return e.Enum(0, pbzipStatusModel)
+	case Status_PENDING:
+		return e.Enum(1, pbzipStatusModel)
+	case Status_ACTIVE:
+		return e.Enum(2, pbzipStatusModel)
+	case Status_COMPLETED:
+		return e.Enum(3, pbzipStatusModel)
+	case Status_FAILED:
+		return e.Enum(4, pbzipStatusModel)
+	}
+	return e.UnknownEnum(protoreflect.EnumNumber(v), pbzipStatusModel)
+}
+
+func pbzipDecodeStatus(d *pbmodel.GenDecoder) (Status, error) {
+	index, err := d.Enum(pbzipStatusModel)
+	if err != nil {
+		return 0, err
+	}
+	if index == len(pbzipStatusValues) {
+		number, err := d.UnknownEnum()
+		return Status(number), err
+	}
+	return pbzipStatusValues[index], nil
+}
+
+// pbzipFeatures_LevelModel is the model of the value indexes of Features_Level and the escape.
+var pbzipFeatures_LevelModel = pbmodel.EnumModel(4)
+
+// pbzipFeatures_LevelValues are the values of Features_Level by index.
+var pbzipFeatures_LevelValues = [...]Features_Level{
+	Features_LOW,
+	Features_HIGH,
+	Features_TOP,
+	Features_MAX,
+}
+
+func pbzipEncodeFeatures_Level(e *pbmodel.GenEncoder, v Features_Level) error {
+	switch v {
+	case Features_LOW:
+		return e.Enum(0, pbzipFeatures_LevelModel)
+	case Features_HIGH:
+		return e.Enum(1, pbzipFeatures_LevelModel)
+	case Features_MAX:
+		return e.Enum(3, pbzipFeatures_LevelModel)
+	}
+	return e.UnknownEnum(protoreflect.EnumNumber(v), pbzipFeatures_LevelModel)
+}
+
+func pbzipDecodeFeatures_Level(d *pbmodel.GenDecoder) (Features_Level, error) {
+	index, err := d.Enum(pbzipFeatures_LevelModel)
+	if err != nil {
+		return 0, err
+	}
+	if index == len(pbzipFeatures_LevelValues) {
+		number, err := d.UnknownEnum()
+		return Features_Level(number), err
+	}
+	return pbzipFeatures_LevelValues[index], nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/pbz"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+// The generated code cannot live next to the messages of pbmodel/testdata,
+// which the pbmodel tests import, so the tests generate the messages of
+// pbmodel/testdata/test.proto anew into package pbziptest, together with
+// messages with the features test.proto lacks:
+//
+//	message Features {
+//	  enum Level {
+//	    option allow_alias = true;
+//	    LOW = 0;
+//	    HIGH = 1;
+//	    TOP = 1;
+//	    MAX = 2;
+//	  }
+//
+//	  optional int32 maybe_count = 1;
+//	  optional bytes maybe_data = 2;
+//	  optional Level maybe_level = 3;
+//	  Level level = 4;
+//	  repeated Level levels = 5;
+//	  map<bool, string> flags = 6;
+//	  map<string, SimpleMessage> children = 7;
+//	  oneof choice {
+//	    SimpleMessage child = 8;
+//	    Level choice_level = 9;
+//	    double ratio = 10;
+//	  }
+//	  google.protobuf.Timestamp created = 11;
+//	  repeated google.protobuf.Timestamp history = 12;
+//	  float score = 13;
+//	  Hinted hinted = 14;
+//	  map<int64, Status> statuses = 15;
+//	}
+//
+//	message Hinted {
+//	  uint32 battery = 1 [(pbz.kind) = PERCENT];
+//	}
+
+// testPackage is the Go package of the generated test messages.
+const testPackage = "github.com/egonelbre/exp-protobuf-compression/cmd/protoc-gen-pbzip/testdata;pbziptest"
+
+// testFiles returns the descriptors of the test messages, after the files
+// they depend on.
+func testFiles() []*descriptorpb.FileDescriptorProto {
+	file := protodesc.ToFileDescriptorProto(testdata.File_test_proto)
+	file.Name = proto.String("pbziptest.proto")
+	file.Package = proto.String("pbziptest")
+	file.Options.GoPackage = proto.String(testPackage)
+	file.Dependency = []string{timestamppb.File_google_protobuf_timestamp_proto.Path(), pbz.File_pbz_proto.Path()}
+	for _, m := range file.MessageType {
+		renameTypes(m)
+	}
+	file.MessageType = append(file.MessageType, featuresMessage(), hintedMessage())
+
+	return []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+		protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto),
+		protodesc.ToFileDescriptorProto(pbz.File_pbz_proto),
+		file,
+	}
+}
+
+// renameTypes moves the types referenced by the fields of m to package
+// pbziptest.
+func renameTypes(m *descriptorpb.DescriptorProto) {
+	for _, field := range m.Field {
+		if field.TypeName != nil {
+			field.TypeName = proto.String(strings.Replace(field.GetTypeName(), ".testdata.", ".pbziptest.", 1))
+		}
+	}
+	for _, nested := range m.NestedType {
+		renameTypes(nested)
+	}
+}
+
+func featuresMessage() *descriptorpb.DescriptorProto {
+	const (
+		level     = ".pbziptest.Features.Level"
+		simple    = ".pbziptest.SimpleMessage"
+		timestamp = ".google.protobuf.Timestamp"
+	)
+	optional := func(f *descriptorpb.FieldDescriptorProto, oneof int32) *descriptorpb.FieldDescriptorProto {
+		f.Proto3Optional = proto.Bool(true)
+		f.OneofIndex = proto.Int32(oneof)
+		return f
+	}
+	inOneof := func(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		f.OneofIndex = proto.Int32(0)
+		return f
+	}
+
+	return &descriptorpb.DescriptorProto{
+		Name: proto.String("Features"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			optional(field("maybe_count", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""), 1),
+			optional(field("maybe_data", 2, descriptorpb.FieldDescriptorProto_TYPE_BYTES, ""), 2),
+			optional(field("maybe_level", 3, descriptorpb.FieldDescriptorProto_TYPE_ENUM, level), 3),
+			field("level", 4, descriptorpb.FieldDescriptorProto_TYPE_ENUM, level),
+			repeated(field("levels", 5, descriptorpb.FieldDescriptorProto_TYPE_ENUM, level)),
+			repeated(field("flags", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".pbziptest.Features.FlagsEntry")),
+			repeated(field("children", 7, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".pbziptest.Features.ChildrenEntry")),
+			inOneof(field("child", 8, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, simple)),
+			inOneof(field("choice_level", 9, descriptorpb.FieldDescriptorProto_TYPE_ENUM, level)),
+			inOneof(field("ratio", 10, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, "")),
+			field("created", 11, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, timestamp),
+			repeated(field("history", 12, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, timestamp)),
+			field("score", 13, descriptorpb.FieldDescriptorProto_TYPE_FLOAT, ""),
+			field("hinted", 14, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".pbziptest.Hinted"),
+			repeated(field("statuses", 15, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".pbziptest.Features.StatusesEntry")),
+		},
+		NestedType: []*descriptorpb.DescriptorProto{
+			mapEntry("FlagsEntry",
+				field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_BOOL, ""),
+				field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")),
+			mapEntry("ChildrenEntry",
+				field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, simple)),
+			mapEntry("StatusesEntry",
+				field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+				field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".pbziptest.Status")),
+		},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Level"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("LOW"), Number: proto.Int32(0)},
+				{Name: proto.String("HIGH"), Number: proto.Int32(1)},
+				{Name: proto.String("TOP"), Number: proto.Int32(1)},
+				{Name: proto.String("MAX"), Number: proto.Int32(2)},
+			},
+			Options: &descriptorpb.EnumOptions{AllowAlias: proto.Bool(true)},
+		}},
+		OneofDecl: []*descriptorpb.OneofDescriptorProto{
+			{Name: proto.String("choice")},
+			{Name: proto.String("_maybe_count")},
+			{Name: proto.String("_maybe_data")},
+			{Name: proto.String("_maybe_level")},
+		},
+	}
+}
+
+func hintedMessage() *descriptorpb.DescriptorProto {
+	battery := field("battery", 1, descriptorpb.FieldDescriptorProto_TYPE_UINT32, "")
+	battery.Options = &descriptorpb.FieldOptions{}
+	proto.SetExtension(battery.Options, pbz.E_Kind, pbz.Kind_PERCENT)
+	return &descriptorpb.DescriptorProto{
+		Name:  proto.String("Hinted"),
+		Field: []*descriptorpb.FieldDescriptorProto{battery},
+	}
+}
+
+// field returns a singular field; typeName names message and enum types.
+func field(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:   typ.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+func repeated(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+	f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	return f
+}
+
+func mapEntry(name string, key, value *descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	return &descriptorpb.DescriptorProto{
+		Name:    proto.String(name),
+		Field:   []*descriptorpb.FieldDescriptorProto{key, value},
+		Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+	}
+}
@@ -0,0 +1,280 @@
+// Package corpusstats collects per-field statistics from a stream of protobuf
+// messages. The statistics are compact enough to keep for long-running
+// collection on live traffic and contain the histograms needed to train
+// field-specific compression models.
+package corpusstats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
+)
+
+// DefaultSketchCapacity is the number of distinct values tracked per field.
+const DefaultSketchCapacity = 64
+
+// FieldStats holds the statistics collected for a single field path.
+type FieldStats struct {
+	Kind    string `json:"kind"`
+	Present uint64 `json:"present"`
+	Absent  uint64 `json:"absent"`
+
+	// Values tracks the most frequent values of scalar fields.
+	// Signed integers are zigzag encoded, floats are stored as IEEE bits.
+	Values *Sketch `json:"values,omitempty"`
+
+	// Magnitudes counts values by their bit length (0 for zero, 1..64),
+	// which gives the varint size distribution of numeric fields.
+	Magnitudes []uint64 `json:"magnitudes,omitempty"`
+
+	// Lengths tracks the lengths of strings, bytes, lists and maps.
+	Lengths *Sketch `json:"lengths,omitempty"`
+
+	// Bytes counts byte frequencies of string and bytes fields.
+	Bytes []uint64 `json:"bytes,omitempty"`
+}
+
+// Stats is a snapshot of collected statistics.
+type Stats struct {
+	Messages uint64                 `json:"messages"`
+	Fields   map[string]*FieldStats `json:"fields"`
+}
+
+// ReadStats reads statistics written by Stats.WriteTo.
+func ReadStats(r io.Reader) (*Stats, error) {
+	var stats Stats
+	if err := json.NewDecoder(r).Decode(&stats); err != nil {
+		return nil, err
+	}
+	if stats.Fields == nil {
+		stats.Fields = make(map[string]*FieldStats)
+	}
+	return &stats, nil
+}
+
+// WriteTo writes the statistics as JSON.
+func (s *Stats) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	data = append(data, '\n')
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// Collector accumulates field statistics from observed messages.
+// It is safe for concurrent use.
+type Collector struct {
+	mu             sync.Mutex
+	sketchCapacity int
+	stats          Stats
+}
+
+// NewCollector creates an empty collector.
+func NewCollector() *Collector {
+	return &Collector{
+		sketchCapacity: DefaultSketchCapacity,
+		stats: Stats{
+			Fields: make(map[string]*FieldStats),
+		},
+	}
+}
+
+// Load merges previously written statistics into the collector,
+// allowing collection to continue across restarts.
+func (c *Collector) Load(stats *Stats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Messages += stats.Messages
+	for path, other := range stats.Fields {
+		fs, ok := c.stats.Fields[path]
+		if !ok {
+			fs = &FieldStats{Kind: other.Kind}
+			c.stats.Fields[path] = fs
+		}
+		fs.Present += other.Present
+		fs.Absent += other.Absent
+		if other.Values != nil {
+			c.sketch(&fs.Values).Merge(other.Values)
+		}
+		if other.Lengths != nil {
+			c.sketch(&fs.Lengths).Merge(other.Lengths)
+		}
+		fs.Magnitudes = addCounts(fs.Magnitudes, other.Magnitudes)
+		fs.Bytes = addCounts(fs.Bytes, other.Bytes)
+	}
+}
+
+// Observe records the field values of msg.
+func (c *Collector) Observe(msg proto.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Messages++
+	c.observeMessage("", msg.ProtoReflect())
+}
+
+// Snapshot returns a deep copy of the statistics collected so far.
+func (c *Collector) Snapshot() *Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Round-trip through JSON to get an independent copy.
+	data, err := json.Marshal(&c.stats)
+	if err != nil {
+		panic(fmt.Sprintf("corpusstats: marshal snapshot: %v", err))
+	}
+	var snapshot Stats
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		panic(fmt.Sprintf("corpusstats: unmarshal snapshot: %v", err))
+	}
+	return &snapshot
+}
+
+// observeMessage records all fields of msg under the given path prefix.
+func (c *Collector) observeMessage(fieldPath string, msg protoreflect.Message) {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		currentPath := pbmodel.BuildFieldPath(fieldPath, string(fd.Name()))
+		fs := c.field(currentPath, fd)
+
+		if !msg.Has(fd) {
+			fs.Absent++
+			continue
+		}
+		fs.Present++
+
+		value := msg.Get(fd)
+		switch {
+		case fd.IsList():
+			list := value.List()
+			c.sketch(&fs.Lengths).Add(uint64(list.Len()))
+			elementPath := currentPath + "[]"
+			for j := 0; j < list.Len(); j++ {
+				c.observeValue(elementPath, fd, list.Get(j))
+			}
+
+		case fd.IsMap():
+			m := value.Map()
+			c.sketch(&fs.Lengths).Add(uint64(m.Len()))
+			keyPath := currentPath + "._key"
+			valuePath := currentPath + "._value"
+			m.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				c.observeValue(keyPath, fd.MapKey(), k.Value())
+				c.observeValue(valuePath, fd.MapValue(), v)
+				return true
+			})
+
+		default:
+			c.recordValue(fs, fd, value)
+			if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+				c.observeMessage(currentPath, value.Message())
+			}
+		}
+	}
+}
+
+// observeValue records a single list element or map entry.
+func (c *Collector) observeValue(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value) {
+	fs := c.field(fieldPath, fd)
+	fs.Present++
+	c.recordValue(fs, fd, value)
+	if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		c.observeMessage(fieldPath, value.Message())
+	}
+}
+
+// recordValue updates the histograms of fs with a scalar value.
+func (c *Collector) recordValue(fs *FieldStats, fd protoreflect.FieldDescriptor, value protoreflect.Value) {
+	var v uint64
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if value.Bool() {
+			v = 1
+		}
+	case protoreflect.EnumKind:
+		v = uint64(value.Enum())
+	case protoreflect.Int32Kind, protoreflect.Int64Kind,
+		protoreflect.Sint32Kind, protoreflect.Sint64Kind,
+		protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind:
+		v = pbmodel.ZigzagEncode(value.Int())
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind,
+		protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+		v = value.Uint()
+	case protoreflect.FloatKind:
+		v = uint64(math.Float32bits(float32(value.Float())))
+	case protoreflect.DoubleKind:
+		v = math.Float64bits(value.Float())
+	case protoreflect.StringKind:
+		c.recordBytes(fs, []byte(value.String()))
+		return
+	case protoreflect.BytesKind:
+		c.recordBytes(fs, value.Bytes())
+		return
+	default:
+		// Messages are recorded through their own fields.
+		return
+	}
+
+	c.sketch(&fs.Values).Add(v)
+	if fs.Magnitudes == nil {
+		fs.Magnitudes = make([]uint64, 65)
+	}
+	fs.Magnitudes[bits.Len64(v)]++
+}
+
+// recordBytes updates the length and byte histograms of fs.
+func (c *Collector) recordBytes(fs *FieldStats, data []byte) {
+	c.sketch(&fs.Lengths).Add(uint64(len(data)))
+	if fs.Bytes == nil {
+		fs.Bytes = make([]uint64, 256)
+	}
+	for _, b := range data {
+		fs.Bytes[b]++
+	}
+}
+
+// field returns the statistics entry for a field path, creating it if needed.
+func (c *Collector) field(fieldPath string, fd protoreflect.FieldDescriptor) *FieldStats {
+	fs, ok := c.stats.Fields[fieldPath]
+	if !ok {
+		fs = &FieldStats{Kind: fd.Kind().String()}
+		c.stats.Fields[fieldPath] = fs
+	}
+	return fs
+}
+
+// sketch returns *s, allocating a new sketch if it is nil.
+func (c *Collector) sketch(s **Sketch) *Sketch {
+	if *s == nil {
+		*s = NewSketch(c.sketchCapacity)
+	}
+	return *s
+}
+
+// addCounts adds src to dst element-wise, growing dst as needed.
+func addCounts(dst, src []uint64) []uint64 {
+	if len(src) == 0 {
+		return dst
+	}
+	if len(dst) < len(src) {
+		grown := make([]uint64, len(src))
+		copy(grown, dst)
+		dst = grown
+	}
+	for i, c := range src {
+		dst[i] += c
+	}
+	return dst
+}
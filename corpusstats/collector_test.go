@@ -0,0 +1,98 @@
+package corpusstats
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestCollectorObserve(t *testing.T) {
+	collector := NewCollector()
+	for i := 0; i < 10; i++ {
+		collector.Observe(&testdata.SimpleMessage{
+			Id:     int32(i % 3),
+			Name:   "node",
+			Active: i%2 == 0,
+		})
+	}
+	collector.Observe(&testdata.RepeatedMessage{
+		Numbers: []int32{1, 2, 3},
+	})
+
+	stats := collector.Snapshot()
+	if stats.Messages != 11 {
+		t.Errorf("Expected 11 messages, got %d", stats.Messages)
+	}
+
+	id := stats.Fields["id"]
+	if id == nil {
+		t.Fatal("Missing stats for field id")
+	}
+	// proto3 scalars with zero value are not present
+	if id.Present != 6 || id.Absent != 4 {
+		t.Errorf("id: expected 6 present and 4 absent, got %d and %d", id.Present, id.Absent)
+	}
+	if id.Values.Counts[pbmodel.ZigzagEncode(1)] != 3 || id.Values.Counts[pbmodel.ZigzagEncode(2)] != 3 {
+		t.Errorf("id: unexpected value counts %v", id.Values.Counts)
+	}
+
+	name := stats.Fields["name"]
+	if name.Bytes['n'] != 10 || name.Lengths.Counts[4] != 10 {
+		t.Errorf("name: unexpected histograms")
+	}
+
+	numbers := stats.Fields["numbers"]
+	if numbers.Lengths.Counts[3] != 1 {
+		t.Errorf("numbers: expected one list of length 3")
+	}
+	if elements := stats.Fields["numbers[]"]; elements == nil || elements.Present != 3 {
+		t.Errorf("numbers[]: expected 3 elements")
+	}
+}
+
+func TestStatsRoundtrip(t *testing.T) {
+	collector := NewCollector()
+	collector.Observe(&testdata.SimpleMessage{Id: 42, Name: "hello"})
+
+	var buf bytes.Buffer
+	if _, err := collector.Snapshot().WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	stats, err := ReadStats(&buf)
+	if err != nil {
+		t.Fatalf("ReadStats failed: %v", err)
+	}
+
+	resumed := NewCollector()
+	resumed.Load(stats)
+	resumed.Observe(&testdata.SimpleMessage{Id: 42})
+
+	final := resumed.Snapshot()
+	if final.Messages != 2 {
+		t.Errorf("Expected 2 messages, got %d", final.Messages)
+	}
+	if got := final.Fields["id"].Values.Counts[pbmodel.ZigzagEncode(42)]; got != 2 {
+		t.Errorf("Expected id=42 twice, got %d", got)
+	}
+}
+
+func TestSketchEviction(t *testing.T) {
+	s := NewSketch(2)
+	for i := 0; i < 10; i++ {
+		s.Add(1)
+	}
+	s.Add(2)
+	s.Add(3)
+
+	if len(s.Counts) != 2 {
+		t.Fatalf("Expected 2 tracked values, got %d", len(s.Counts))
+	}
+	if s.Counts[1] != 10 {
+		t.Errorf("Heavy hitter count: expected 10, got %d", s.Counts[1])
+	}
+	if s.Total != 12 {
+		t.Errorf("Expected total 12, got %d", s.Total)
+	}
+}
@@ -0,0 +1,87 @@
+package corpusstats
+
+import "sort"
+
+// Sketch tracks approximate counts of the most frequent values using the
+// space-saving algorithm. It keeps at most Capacity distinct values; when a
+// new value arrives and the sketch is full, the least frequent entry is
+// evicted and its count inherited by the new value.
+//
+// The count of any tracked value is overestimated by at most the count of
+// the evicted entry, and every value with frequency above N/Capacity is
+// guaranteed to be tracked.
+type Sketch struct {
+	Capacity int               `json:"capacity"`
+	Total    uint64            `json:"total"`
+	Counts   map[uint64]uint64 `json:"counts"`
+}
+
+// NewSketch creates a sketch that tracks at most capacity distinct values.
+func NewSketch(capacity int) *Sketch {
+	if capacity <= 0 {
+		panic("capacity must be positive")
+	}
+	return &Sketch{
+		Capacity: capacity,
+		Counts:   make(map[uint64]uint64, capacity),
+	}
+}
+
+// Add records one occurrence of value.
+func (s *Sketch) Add(value uint64) {
+	s.AddN(value, 1)
+}
+
+// AddN records n occurrences of value.
+func (s *Sketch) AddN(value uint64, n uint64) {
+	s.Total += n
+
+	if _, ok := s.Counts[value]; ok || len(s.Counts) < s.Capacity {
+		s.Counts[value] += n
+		return
+	}
+
+	// Evict the least frequent entry, ties broken by smallest value so
+	// that the result does not depend on map iteration order.
+	var minValue, minCount uint64
+	first := true
+	for v, c := range s.Counts {
+		if first || c < minCount || (c == minCount && v < minValue) {
+			minValue, minCount = v, c
+			first = false
+		}
+	}
+	delete(s.Counts, minValue)
+	s.Counts[value] = minCount + n
+}
+
+// Merge adds the counts of other into s.
+func (s *Sketch) Merge(other *Sketch) {
+	for _, v := range other.Values() {
+		s.AddN(v, other.Counts[v])
+	}
+	// AddN accounted for the tracked counts; include the untracked remainder.
+	var tracked uint64
+	for _, c := range other.Counts {
+		tracked += c
+	}
+	if other.Total > tracked {
+		s.Total += other.Total - tracked
+	}
+}
+
+// Values returns the tracked values ordered by decreasing count.
+func (s *Sketch) Values() []uint64 {
+	values := make([]uint64, 0, len(s.Counts))
+	for v := range s.Counts {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		ci, cj := s.Counts[values[i]], s.Counts[values[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		return values[i] < values[j]
+	})
+	return values
+}
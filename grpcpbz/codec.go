@@ -0,0 +1,88 @@
+// Package grpcpbz implements a gRPC codec that compresses messages with
+// the arithmetic coding compressors of meshtasticmodel.
+//
+// Importing the package registers the codec under the name "pbz", with the
+// generic pbmodel baseline, so that clients can select it per call:
+//
+//	conn.Invoke(ctx, method, req, resp, grpc.CallContentSubtype(grpcpbz.Name))
+//
+// and servers answer with it when a request arrives with the content type
+// "application/grpc+pbz". Every message starts with the format header of
+// meshtasticmodel, so a mismatch between the variants of the peers fails
+// instead of decoding into garbage.
+package grpcpbz
+
+import (
+	"bytes"
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+)
+
+// Name is the name of the codec, the content subtype of gRPC requests that
+// use it.
+const Name = "pbz"
+
+func init() {
+	encoding.RegisterCodec(NewCodec(mustVersion(meshtasticmodel.WirePbmodel)))
+}
+
+// Codec is a gRPC encoding.Codec that compresses messages with a version of
+// meshtasticmodel, after a format header.
+type Codec struct {
+	version meshtasticmodel.Version
+}
+
+// NewCodec returns a codec that compresses messages with version. Use it
+// with grpc.ForceCodec or grpc.ForceServerCodec to select a variant other
+// than the registered baseline, for example one specialized for Meshtastic
+// packets.
+func NewCodec(version meshtasticmodel.Version) *Codec {
+	return &Codec{version: version}
+}
+
+// Version returns the version the codec compresses with.
+func (c *Codec) Version() meshtasticmodel.Version { return c.version }
+
+// Marshal compresses v, which must be a proto.Message.
+func (c *Codec) Marshal(v any) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("grpcpbz: failed to marshal, message is %T, want proto.Message", v)
+	}
+	var buf bytes.Buffer
+	if err := c.version.Compress(msg, &buf); err != nil {
+		return nil, fmt.Errorf("grpcpbz: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decompresses data into v, which must be a proto.Message. Data
+// compressed with another version than the one of the codec fails with a
+// *meshtasticmodel.VersionMismatchError.
+func (c *Codec) Unmarshal(data []byte, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("grpcpbz: failed to unmarshal, message is %T, want proto.Message", v)
+	}
+	proto.Reset(msg)
+	if err := c.version.Decompress(bytes.NewReader(data), msg); err != nil {
+		return fmt.Errorf("grpcpbz: %w", err)
+	}
+	return nil
+}
+
+// Name returns Name.
+func (c *Codec) Name() string { return Name }
+
+// mustVersion returns the version with id.
+func mustVersion(id meshtasticmodel.WireID) meshtasticmodel.Version {
+	v, ok := meshtasticmodel.VersionByID(id)
+	if !ok {
+		panic(fmt.Sprintf("grpcpbz: unknown version %#x", uint8(id)))
+	}
+	return v
+}
@@ -0,0 +1,160 @@
+package grpcpbz
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func testProfile() *testdata.UserProfile {
+	return &testdata.UserProfile{
+		UserId:        42,
+		Username:      "alice",
+		Email:         "alice@example.com",
+		FullName:      "Alice Example",
+		Bio:           "Writes compressors for protocol buffers.",
+		Tags:          []string{"go", "protobuf", "compression"},
+		AccountStatus: testdata.Status_ACTIVE,
+		Address:       &testdata.UserProfile_Address{City: "Tallinn", Country: "Estonia"},
+		CreatedAt:     1700000000,
+		Metadata:      map[string]string{"theme": "dark", "lang": "et"},
+	}
+}
+
+func TestCodecRoundtrip(t *testing.T) {
+	for _, version := range []meshtasticmodel.Version{
+		mustVersion(meshtasticmodel.WirePbmodel),
+		mustVersion(meshtasticmodel.WirePbmodelVarintOrder2),
+	} {
+		codec := NewCodec(version)
+		msg := testProfile()
+		data, err := codec.Marshal(msg)
+		if err != nil {
+			t.Fatalf("%s: Marshal failed: %v", version.Name, err)
+		}
+		// Unmarshal replaces the contents of the message.
+		decoded := &testdata.UserProfile{Bio: "stale", Tags: []string{"stale"}}
+		if err := codec.Unmarshal(data, decoded); err != nil {
+			t.Fatalf("%s: Unmarshal failed: %v", version.Name, err)
+		}
+		if !proto.Equal(msg, decoded) {
+			t.Errorf("%s: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", version.Name, msg, decoded)
+		}
+	}
+}
+
+func TestCodecVersionMismatch(t *testing.T) {
+	data, err := NewCodec(mustVersion(meshtasticmodel.WirePbmodelOrder1)).Marshal(testProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewCodec(mustVersion(meshtasticmodel.WirePbmodel)).Unmarshal(data, &testdata.UserProfile{})
+	var mismatch *meshtasticmodel.VersionMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("got %v, want *VersionMismatchError", err)
+	}
+}
+
+func TestCodecNotProto(t *testing.T) {
+	codec := NewCodec(mustVersion(meshtasticmodel.WirePbmodel))
+	if _, err := codec.Marshal("text"); err == nil {
+		t.Error("Marshal of a string succeeded")
+	}
+	if err := codec.Unmarshal(nil, new(string)); err == nil {
+		t.Error("Unmarshal into a string succeeded")
+	}
+}
+
+// echoServer echoes profiles back and records the content type of the
+// request.
+type echoServer struct {
+	contentType string
+}
+
+const echoMethod = "/grpcpbz.test.Echo/Echo"
+
+var echoService = grpc.ServiceDesc{
+	ServiceName: "grpcpbz.test.Echo",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Echo",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+			in := &testdata.UserProfile{}
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("content-type")) > 0 {
+				srv.(*echoServer).contentType = md.Get("content-type")[0]
+			}
+			return in, nil
+		},
+	}},
+}
+
+// dialEcho starts an echo server on an in-memory listener and connects to
+// it.
+func dialEcho(t *testing.T, serverOpts ...grpc.ServerOption) (*echoServer, *grpc.ClientConn) {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(serverOpts...)
+	echo := &echoServer{}
+	server.RegisterService(&echoService, echo)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return echo, conn
+}
+
+func TestGRPCContentSubtype(t *testing.T) {
+	echo, conn := dialEcho(t)
+
+	msg, reply := testProfile(), &testdata.UserProfile{}
+	if err := conn.Invoke(t.Context(), echoMethod, msg, reply, grpc.CallContentSubtype(Name)); err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+	if !proto.Equal(msg, reply) {
+		t.Errorf("echo mismatch.\nSent: %v\nReceived: %v", msg, reply)
+	}
+	if want := "application/grpc+" + Name; echo.contentType != want {
+		t.Errorf("server got content type %q, want %q", echo.contentType, want)
+	}
+}
+
+func TestGRPCForceCodec(t *testing.T) {
+	codec := NewCodec(mustVersion(meshtasticmodel.WirePbmodelOrder2))
+	_, conn := dialEcho(t, grpc.ForceServerCodec(codec))
+
+	msg, reply := testProfile(), &testdata.UserProfile{}
+	if err := conn.Invoke(t.Context(), echoMethod, msg, reply, grpc.ForceCodec(codec)); err != nil {
+		t.Fatalf("Invoke failed: %v", err)
+	}
+	if !proto.Equal(msg, reply) {
+		t.Errorf("echo mismatch.\nSent: %v\nReceived: %v", msg, reply)
+	}
+
+	// The registered baseline cannot read the messages of the server.
+	err := conn.Invoke(t.Context(), echoMethod, msg, reply, grpc.CallContentSubtype(Name))
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Invoke with the baseline: got %v, want code Internal", err)
+	}
+}
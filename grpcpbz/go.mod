@@ -0,0 +1,25 @@
+module github.com/egonelbre/exp-protobuf-compression/grpcpbz
+
+go 1.25.0
+
+require (
+	github.com/egonelbre/exp-protobuf-compression v0.0.0
+	github.com/egonelbre/exp-protobuf-compression/meshtasticmodel v0.0.0
+	google.golang.org/grpc v1.82.1
+	google.golang.org/protobuf v1.36.11
+)
+
+require (
+	github.com/egonelbre/exp-protobuf-compression/meshtastic v0.0.0 // indirect
+	golang.org/x/net v0.53.0 // indirect
+	golang.org/x/sys v0.43.0 // indirect
+	golang.org/x/text v0.36.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260414002931-afd174a4e478 // indirect
+)
+
+replace (
+	github.com/egonelbre/exp-protobuf-compression => ..
+	github.com/egonelbre/exp-protobuf-compression/meshfixtures => ../meshfixtures
+	github.com/egonelbre/exp-protobuf-compression/meshtastic => ../meshtastic
+	github.com/egonelbre/exp-protobuf-compression/meshtasticmodel => ../meshtasticmodel
+)
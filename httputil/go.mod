@@ -0,0 +1,18 @@
+module github.com/egonelbre/exp-protobuf-compression/httputil
+
+go 1.25
+
+require (
+	github.com/egonelbre/exp-protobuf-compression v0.0.0
+	github.com/egonelbre/exp-protobuf-compression/meshtasticmodel v0.0.0
+	google.golang.org/protobuf v1.36.11
+)
+
+require github.com/egonelbre/exp-protobuf-compression/meshtastic v0.0.0 // indirect
+
+replace (
+	github.com/egonelbre/exp-protobuf-compression => ..
+	github.com/egonelbre/exp-protobuf-compression/meshfixtures => ../meshfixtures
+	github.com/egonelbre/exp-protobuf-compression/meshtastic => ../meshtastic
+	github.com/egonelbre/exp-protobuf-compression/meshtasticmodel => ../meshtasticmodel
+)
@@ -0,0 +1,90 @@
+package httputil
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+)
+
+// Handler returns a handler that decodes request bodies with the content
+// coding Encoding before calling next, and encodes the responses of next
+// for clients that accept it. Requests with a body that cannot be decoded
+// are rejected with 400 Bad Request, and encoded requests without a request
+// type with 415 Unsupported Media Type.
+//
+// Only successful responses are encoded; responses that the handler encodes
+// itself, or that are not messages of the response type, pass unchanged.
+func Handler(next http.Handler, version meshtasticmodel.Version, types MessageTypes) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestType, responseType := types(r)
+
+		if isEncoded(r.Header) {
+			if requestType == nil {
+				http.Error(w, "unsupported content encoding "+Encoding, http.StatusUnsupportedMediaType)
+				return
+			}
+			data, err := io.ReadAll(r.Body)
+			if err == nil {
+				data, err = decode(version, requestType, data)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			r = r.Clone(r.Context())
+			r.Body = io.NopCloser(bytes.NewReader(data))
+			r.ContentLength = int64(len(data))
+			r.Header.Del("Content-Encoding")
+			r.Header.Set("Content-Length", strconv.Itoa(len(data)))
+		}
+
+		if responseType == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsEncoding(r.Header) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &responseRecorder{header: w.Header(), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		body := rec.body.Bytes()
+		if rec.status == http.StatusOK && rec.header.Get("Content-Encoding") == "" {
+			if encoded, err := encode(version, responseType, body); err == nil {
+				body = encoded
+				rec.header.Set("Content-Encoding", Encoding)
+			}
+		}
+		rec.header.Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(rec.status)
+		_, _ = w.Write(body)
+	})
+}
+
+// responseRecorder buffers a response, so that it can be encoded after
+// the handler has written it.
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header { return rec.header }
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status, rec.wroteHeader = status, true
+	}
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(p)
+}
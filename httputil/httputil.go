@@ -0,0 +1,87 @@
+// Package httputil compresses protobuf bodies of HTTP requests and
+// responses with the arithmetic coding compressors of meshtasticmodel, as
+// the content coding "pbz".
+//
+// Unlike gzip, the coding depends on the schema of the body, so both the
+// server middleware, Handler, and the client transport, Transport, are
+// given MessageTypes to find the message types of the bodies. Handlers and
+// callers keep working with the protobuf wire format: requests are decoded
+// before they reach the handler, and responses are decoded before they
+// reach the caller.
+//
+// Every body starts with the format header of meshtasticmodel, so a
+// mismatch between the versions of the peers fails instead of decoding into
+// garbage.
+package httputil
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+)
+
+// Encoding is the name of the content coding in the Content-Encoding and
+// Accept-Encoding headers.
+const Encoding = "pbz"
+
+// MessageTypes returns the message types of the request and the response
+// bodies of r. A nil type leaves that body as it is.
+type MessageTypes func(r *http.Request) (request, response protoreflect.MessageType)
+
+// encode converts the protobuf wire format data of a message of typ to
+// the content coding of version.
+func encode(version meshtasticmodel.Version, typ protoreflect.MessageType, data []byte) ([]byte, error) {
+	msg := typ.New().Interface()
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("httputil: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := version.Compress(msg, &buf); err != nil {
+		return nil, fmt.Errorf("httputil: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decode converts data in the content coding of version to the protobuf
+// wire format of a message of typ.
+func decode(version meshtasticmodel.Version, typ protoreflect.MessageType, data []byte) ([]byte, error) {
+	msg := typ.New().Interface()
+	if err := version.Decompress(bytes.NewReader(data), msg); err != nil {
+		return nil, fmt.Errorf("httputil: %w", err)
+	}
+	out, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("httputil: %w", err)
+	}
+	return out, nil
+}
+
+// isEncoded reports whether the Content-Encoding of header is Encoding.
+func isEncoded(header http.Header) bool {
+	return strings.EqualFold(strings.TrimSpace(header.Get("Content-Encoding")), Encoding)
+}
+
+// acceptsEncoding reports whether the Accept-Encoding of header lists
+// Encoding with a nonzero quality.
+func acceptsEncoding(header http.Header) bool {
+	for _, value := range header.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			if !strings.EqualFold(strings.TrimSpace(name), Encoding) {
+				continue
+			}
+			q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q=")
+			if ok && strings.Trim(q, "0.") == "" {
+				return false
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package httputil
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func testVersion(t *testing.T) meshtasticmodel.Version {
+	t.Helper()
+	v, ok := meshtasticmodel.VersionByID(meshtasticmodel.WirePbmodel)
+	if !ok {
+		t.Fatal("missing pbmodel version")
+	}
+	return v
+}
+
+func testProfile() *testdata.UserProfile {
+	return &testdata.UserProfile{
+		UserId:        42,
+		Username:      "alice",
+		Email:         "alice@example.com",
+		FullName:      "Alice Example",
+		Bio:           "Writes compressors for protocol buffers.",
+		Tags:          []string{"go", "protobuf", "compression"},
+		AccountStatus: testdata.Status_ACTIVE,
+		Address:       &testdata.UserProfile_Address{City: "Tallinn", Country: "Estonia"},
+		CreatedAt:     1700000000,
+		Metadata:      map[string]string{"theme": "dark", "lang": "et"},
+	}
+}
+
+// profileTypes codes the bodies of requests to /profile as UserProfile.
+func profileTypes(r *http.Request) (request, response protoreflect.MessageType) {
+	if r.URL.Path != "/profile" {
+		return nil, nil
+	}
+	typ := (&testdata.UserProfile{}).ProtoReflect().Type()
+	return typ, typ
+}
+
+// echoProfile echoes a UserProfile back and records the request headers.
+type echoProfile struct {
+	t      *testing.T
+	header http.Header
+}
+
+func (h *echoProfile) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.header = r.Header.Clone()
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.t.Error(err)
+	}
+	msg := &testdata.UserProfile{}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(data)
+}
+
+func post(t *testing.T, client *http.Client, url string, body []byte, header http.Header) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, data
+}
+
+func TestRoundtrip(t *testing.T) {
+	version := testVersion(t)
+	echo := &echoProfile{t: t}
+	server := httptest.NewServer(Handler(echo, version, profileTypes))
+	defer server.Close()
+
+	var wire int64
+	client := &http.Client{Transport: &Transport{
+		Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			wire = req.ContentLength
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+		Version: version,
+		Types:   profileTypes,
+	}}
+
+	msg := testProfile()
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, data := post(t, client, server.URL+"/profile", body, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, data)
+	}
+	if got := echo.header.Get("Content-Encoding"); got != "" {
+		t.Errorf("handler got Content-Encoding %q", got)
+	}
+	if wire >= int64(len(body)) {
+		t.Errorf("sent %d bytes for a %d byte body", wire, len(body))
+	}
+
+	decoded := &testdata.UserProfile{}
+	if err := proto.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("response is not a UserProfile: %v", err)
+	}
+	if !proto.Equal(msg, decoded) {
+		t.Errorf("echo mismatch.\nSent: %v\nReceived: %v", msg, decoded)
+	}
+}
+
+func TestHandlerEncodesResponse(t *testing.T) {
+	version := testVersion(t)
+	server := httptest.NewServer(Handler(&echoProfile{t: t}, version, profileTypes))
+	defer server.Close()
+
+	msg := testProfile()
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clients that do not accept the encoding get the body unchanged.
+	resp, data := post(t, http.DefaultClient, server.URL+"/profile", body, nil)
+	if resp.Header.Get("Content-Encoding") != "" || !bytes.Equal(data, body) {
+		t.Errorf("got Content-Encoding %q and %d bytes, want the %d byte body unchanged",
+			resp.Header.Get("Content-Encoding"), len(data), len(body))
+	}
+
+	resp, data = post(t, http.DefaultClient, server.URL+"/profile", body, http.Header{
+		"Accept-Encoding": {"gzip, " + Encoding},
+	})
+	if got := resp.Header.Get("Content-Encoding"); got != Encoding {
+		t.Fatalf("got Content-Encoding %q, want %q", got, Encoding)
+	}
+	decoded := &testdata.UserProfile{}
+	if err := version.Decompress(bytes.NewReader(data), decoded); err != nil {
+		t.Fatalf("Decompress failed: %v", err)
+	}
+	if !proto.Equal(msg, decoded) {
+		t.Errorf("echo mismatch.\nSent: %v\nReceived: %v", msg, decoded)
+	}
+
+	resp, _ = post(t, http.DefaultClient, server.URL+"/profile", body, http.Header{
+		"Accept-Encoding": {Encoding + ";q=0"},
+	})
+	if got := resp.Header.Get("Content-Encoding"); got != "" {
+		t.Errorf("refused encoding: got Content-Encoding %q", got)
+	}
+}
+
+func TestHandlerRejects(t *testing.T) {
+	server := httptest.NewServer(Handler(&echoProfile{t: t}, testVersion(t), profileTypes))
+	defer server.Close()
+
+	encoded := http.Header{"Content-Encoding": {Encoding}}
+	resp, _ := post(t, http.DefaultClient, server.URL+"/profile", []byte("not pbz"), encoded)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("corrupt body: got status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	resp, _ = post(t, http.DefaultClient, server.URL+"/other", []byte{}, encoded)
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("unknown type: got status %d, want %d", resp.StatusCode, http.StatusUnsupportedMediaType)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return fn(req) }
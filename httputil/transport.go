@@ -0,0 +1,76 @@
+package httputil
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtasticmodel"
+)
+
+// Transport is an http.RoundTripper that encodes request bodies with the
+// content coding Encoding, asks servers to encode the responses, and
+// decodes the encoded responses, so that callers send and receive the
+// protobuf wire format.
+type Transport struct {
+	// Base sends the requests; nil uses http.DefaultTransport.
+	Base http.RoundTripper
+	// Version compresses and decompresses the bodies.
+	Version meshtasticmodel.Version
+	// Types finds the message types of the bodies.
+	Types MessageTypes
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestType, responseType := t.Types(req)
+	// A RoundTripper must not modify the request of the caller.
+	req = req.Clone(req.Context())
+
+	if requestType != nil && req.Body != nil && req.Body != http.NoBody && req.Header.Get("Content-Encoding") == "" {
+		data, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		data, err = encode(t.Version, requestType, data)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+		req.ContentLength = int64(len(data))
+		req.Header.Set("Content-Encoding", Encoding)
+	}
+	if responseType != nil && req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", Encoding)
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil || responseType == nil || !isEncoded(resp.Header) {
+		return resp, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err == nil {
+		data, err = decode(t.Version, responseType, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
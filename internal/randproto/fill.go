@@ -0,0 +1,152 @@
+package randproto
+
+import (
+	"math"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// NewMessage returns a message of type md with random values from src,
+// nesting messages at most depth levels deep.
+func NewMessage(md protoreflect.MessageDescriptor, src *Source, depth int) protoreflect.Message {
+	msg := dynamicpb.NewMessage(md)
+	Fill(msg, src, depth)
+	return msg
+}
+
+// Fill sets random fields of msg to random values from src, nesting
+// messages at most depth levels deep. Required fields are always set.
+func Fill(msg protoreflect.Message, src *Source, depth int) {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Cardinality() != protoreflect.Required && !src.Bool() {
+			continue
+		}
+
+		switch {
+		case fd.IsList():
+			list := msg.Mutable(fd).List()
+			for range src.Intn(5) {
+				if fd.Message() != nil {
+					if depth > 0 {
+						Fill(list.AppendMutable().Message(), src, depth-1)
+					}
+					continue
+				}
+				list.Append(Value(fd, src))
+			}
+		case fd.IsMap():
+			m := msg.Mutable(fd).Map()
+			for range src.Intn(4) {
+				key := Value(fd.MapKey(), src).MapKey()
+				if fd.MapValue().Message() != nil {
+					if depth > 0 {
+						Fill(m.Mutable(key).Message(), src, depth-1)
+					}
+					continue
+				}
+				m.Set(key, Value(fd.MapValue(), src))
+			}
+		case fd.Message() != nil:
+			if depth > 0 {
+				Fill(msg.Mutable(fd).Message(), src, depth-1)
+			}
+		default:
+			msg.Set(fd, Value(fd, src))
+		}
+	}
+}
+
+// Value returns a random value of the scalar or enum field fd.
+func Value(fd protoreflect.FieldDescriptor, src *Source) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(src.Bool())
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		// Open enums may hold numbers that are not declared
+		if !fd.Enum().IsClosed() && src.Intn(4) == 0 {
+			return protoreflect.ValueOfEnum(protoreflect.EnumNumber(int32(src.Uint64())))
+		}
+		return protoreflect.ValueOfEnum(values.Get(src.Intn(values.Len())).Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(integer(src, math.MinInt32, math.MaxInt32)))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(integer(src, math.MinInt64, math.MaxInt64))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(integer(src, 0, math.MaxUint32)))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(uint64(integer(src, 0, -1)))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(float(src, math.MaxFloat32, math.SmallestNonzeroFloat32, func(bits uint64) float64 {
+			return float64(math.Float32frombits(uint32(bits)))
+		})))
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(float(src, math.MaxFloat64, math.SmallestNonzeroFloat64, math.Float64frombits))
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(text(src))
+	case protoreflect.BytesKind:
+		data := make([]byte, src.Intn(24))
+		for i := range data {
+			data[i] = src.Byte()
+		}
+		return protoreflect.ValueOfBytes(data)
+	default:
+		panic("randproto: no random values of " + fd.Kind().String())
+	}
+}
+
+// integer returns an edge case between min and max, a small number, or
+// random bits truncated by the caller. The maximum of uint64 is passed as
+// -1.
+func integer(src *Source, min, max int64) int64 {
+	switch src.Intn(4) {
+	case 0:
+		edges := []int64{0, 1, -1, min, max, min + 1, max - 1}
+		return edges[src.Intn(len(edges))]
+	case 1:
+		return int64(int8(src.Byte()))
+	default:
+		return int64(src.Uint64())
+	}
+}
+
+// float returns an edge case of a floating point type with the given
+// largest and smallest positive values, a small integer, or a value of
+// random bits.
+func float(src *Source, largest, smallest float64, fromBits func(uint64) float64) float64 {
+	switch src.Intn(4) {
+	case 0:
+		edges := []float64{0, math.Copysign(0, -1), math.NaN(), math.Inf(1), math.Inf(-1), largest, -largest, smallest, -smallest}
+		return edges[src.Intn(len(edges))]
+	case 1:
+		return float64(int8(src.Byte())) / 4
+	default:
+		return fromBits(src.Uint64())
+	}
+}
+
+// runes are characters that codecs model specially: ASCII, NUL, two and
+// three byte characters, characters outside the BMP, emoji with joiners and
+// variation selectors, and the largest valid rune.
+var runes = []rune{'a', 'e', ' ', 'Z', '0', '\n', 0, 'é', 'ß', '€', '中', '𝄞', '👍', '🏳', '‍', '️', utf8.MaxRune, utf8.RuneError}
+
+// text returns a valid UTF-8 string of random characters.
+func text(src *Source) string {
+	n := src.Intn(16)
+	var b []byte
+	for range n {
+		r := runes[src.Intn(len(runes))]
+		if src.Intn(8) == 0 {
+			r = rune(src.Uint64() % (utf8.MaxRune + 1))
+			if !utf8.ValidRune(r) {
+				r = utf8.RuneError
+			}
+		}
+		b = utf8.AppendRune(b, r)
+	}
+	return string(b)
+}
@@ -0,0 +1,105 @@
+package randproto
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// maxFieldNumber is the largest valid field number.
+const maxFieldNumber = 1<<29 - 1
+
+// RandomSchema returns a message with up to 12 fields of random types,
+// labels and numbers, which may refer to itself, to a nested message and to
+// an enum of random values.
+func RandomSchema(src *Source) (protoreflect.MessageDescriptor, error) {
+	const scope = ".randproto.random"
+	proto3 := src.Bool()
+
+	enum := &descriptorpb.EnumDescriptorProto{Name: proto.String("Choice")}
+	seen := map[int32]bool{}
+	for i := range 1 + src.Intn(4) {
+		number := int32(src.Uint64())
+		if i == 0 && proto3 {
+			number = 0 // proto3 enums start with zero
+		}
+		if seen[number] {
+			continue
+		}
+		seen[number] = true
+		enum.Value = append(enum.Value, &descriptorpb.EnumValueDescriptorProto{
+			Name:   proto.String(fmt.Sprintf("CHOICE_%d", i)),
+			Number: proto.Int32(number),
+		})
+	}
+
+	inner := &descriptorpb.DescriptorProto{Name: proto.String("Inner")}
+	for i := range 1 + src.Intn(4) {
+		typ := scalarTypes[src.Intn(len(scalarTypes))]
+		inner.Field = append(inner.Field, field(fmt.Sprintf("i%d", i+1), int32(i+1), typ, optional))
+	}
+
+	root := &descriptorpb.DescriptorProto{Name: proto.String("Random")}
+	number := int32(0)
+	for range 1 + src.Intn(12) {
+		// Mostly small gaps, sometimes a jump towards the largest numbers
+		if src.Intn(8) == 0 {
+			number += 1 + int32(src.Uint64()%(maxFieldNumber/4))
+		} else {
+			number += 1 + int32(src.Intn(20))
+		}
+		if number >= 19000 && number <= 19999 {
+			number = 20000 // reserved for the protobuf implementation
+		}
+		if number > maxFieldNumber {
+			break
+		}
+		name := fmt.Sprintf("f%d", number)
+
+		var fd *descriptorpb.FieldDescriptorProto
+		switch src.Intn(8) {
+		case 0:
+			fd = typedField(name, number, descriptorpb.FieldDescriptorProto_TYPE_ENUM, scope+".Choice", optional)
+		case 1:
+			fd = typedField(name, number, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, scope+".Inner", optional)
+		case 2:
+			fd = typedField(name, number, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, scope+".Random", optional)
+		case 3:
+			key := field("", 0, mapKeyTypes[src.Intn(len(mapKeyTypes))], nil)
+			value := field("", 0, scalarTypes[src.Intn(len(scalarTypes))], nil)
+			root.Field = append(root.Field, mapField(root, scope, name, number, key, value))
+			continue
+		default:
+			fd = field(name, number, scalarTypes[src.Intn(len(scalarTypes))], optional)
+		}
+
+		switch src.Intn(4) {
+		case 0:
+			fd.Label = repeated
+		case 1:
+			if proto3 && fd.GetType() != descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+				proto3Optional(root, fd)
+				continue
+			}
+		}
+		root.Field = append(root.Field, fd)
+	}
+
+	syntax := "proto2"
+	if proto3 {
+		syntax = "proto3"
+	}
+	file, err := newFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("randproto/random.proto"),
+		Package:     proto.String("randproto.random"),
+		Syntax:      proto.String(syntax),
+		EnumType:    []*descriptorpb.EnumDescriptorProto{enum},
+		MessageType: []*descriptorpb.DescriptorProto{root, inner},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return file.Messages().ByName("Random"), nil
+}
@@ -0,0 +1,297 @@
+package randproto
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// scalarTypes are the field types that are neither messages nor enums.
+var scalarTypes = []descriptorpb.FieldDescriptorProto_Type{
+	descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+	descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+	descriptorpb.FieldDescriptorProto_TYPE_INT32,
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED64,
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED32,
+	descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+	descriptorpb.FieldDescriptorProto_TYPE_UINT32,
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED32,
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED64,
+	descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+	descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+}
+
+// mapKeyTypes are the field types allowed as map keys.
+var mapKeyTypes = []descriptorpb.FieldDescriptorProto_Type{
+	descriptorpb.FieldDescriptorProto_TYPE_INT32,
+	descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	descriptorpb.FieldDescriptorProto_TYPE_UINT32,
+	descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+	descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+	descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED32,
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED64,
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED32,
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED64,
+	descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	descriptorpb.FieldDescriptorProto_TYPE_STRING,
+}
+
+var (
+	optional = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	required = descriptorpb.FieldDescriptorProto_LABEL_REQUIRED.Enum()
+)
+
+// field returns a field of a scalar type.
+func field(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, label *descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Type:   typ.Enum(),
+		Label:  label,
+	}
+}
+
+// typedField returns a field of a message or enum type.
+func typedField(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string, label *descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+	fd := field(name, number, typ, label)
+	fd.TypeName = proto.String(typeName)
+	return fd
+}
+
+// mapField adds the entry type of a map field to msg and returns the field.
+func mapField(msg *descriptorpb.DescriptorProto, scope, name string, number int32, key, value *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+	entry := camelCase(name) + "Entry"
+	key.Name, key.Number, key.Label = proto.String("key"), proto.Int32(1), optional
+	value.Name, value.Number, value.Label = proto.String("value"), proto.Int32(2), optional
+	msg.NestedType = append(msg.NestedType, &descriptorpb.DescriptorProto{
+		Name:    proto.String(entry),
+		Field:   []*descriptorpb.FieldDescriptorProto{key, value},
+		Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+	})
+	return typedField(name, number, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, scope+"."+msg.GetName()+"."+entry, repeated)
+}
+
+// camelCase returns the CamelCase form of a snake_case name, which names
+// the entry types of map fields.
+func camelCase(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// proto3Optional adds a proto3 optional field to msg, with its synthetic
+// oneof.
+func proto3Optional(msg *descriptorpb.DescriptorProto, fd *descriptorpb.FieldDescriptorProto) {
+	fd.Proto3Optional = proto.Bool(true)
+	fd.OneofIndex = proto.Int32(int32(len(msg.OneofDecl)))
+	msg.OneofDecl = append(msg.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String("_" + fd.GetName())})
+	msg.Field = append(msg.Field, fd)
+}
+
+// newFile builds a file without dependencies.
+func newFile(file *descriptorpb.FileDescriptorProto) (protoreflect.FileDescriptor, error) {
+	return protodesc.NewFile(file, new(protoregistry.Files))
+}
+
+// Schemas returns a fixed set of schemas with the cases that codecs get
+// wrong: every scalar kind with implicit and explicit presence, packed and
+// unpacked lists, open enums with negative and sparse values, closed enums
+// without a zero value, oneofs, maps with every key kind, recursion,
+// proto2 defaults and required fields, the largest field number, and fields
+// whose names match Meshtastic fields that codecs special-case by name but
+// whose types differ.
+func Schemas() []protoreflect.MessageDescriptor {
+	return schemas()
+}
+
+var schemas = sync.OnceValue(func() []protoreflect.MessageDescriptor {
+	var all []protoreflect.MessageDescriptor
+	for _, build := range []func() *descriptorpb.FileDescriptorProto{proto3Schemas, proto2Schemas} {
+		file, err := newFile(build())
+		if err != nil {
+			panic(err)
+		}
+		for i := 0; i < file.Messages().Len(); i++ {
+			all = append(all, file.Messages().Get(i))
+		}
+	}
+	return all
+})
+
+// proto3Schemas returns the proto3 schemas.
+func proto3Schemas() *descriptorpb.FileDescriptorProto {
+	const scope = ".randproto.tricky"
+	sparse := &descriptorpb.EnumDescriptorProto{
+		Name: proto.String("Sparse"),
+		Value: []*descriptorpb.EnumValueDescriptorProto{
+			{Name: proto.String("SPARSE_ZERO"), Number: proto.Int32(0)},
+			{Name: proto.String("SPARSE_NEGATIVE"), Number: proto.Int32(-1)},
+			{Name: proto.String("SPARSE_BIG"), Number: proto.Int32(1000)},
+			{Name: proto.String("SPARSE_MAX"), Number: proto.Int32(2147483647)},
+			{Name: proto.String("SPARSE_MIN"), Number: proto.Int32(-2147483648)},
+		},
+	}
+
+	scalars := &descriptorpb.DescriptorProto{Name: proto.String("Scalars")}
+	for i, typ := range scalarTypes {
+		scalars.Field = append(scalars.Field, field(fmt.Sprintf("implicit_%d", i+1), int32(i+1), typ, optional))
+	}
+	scalars.Field = append(scalars.Field,
+		typedField("sparse", 16, descriptorpb.FieldDescriptorProto_TYPE_ENUM, scope+".Sparse", optional),
+		field("max_number", 536870911, descriptorpb.FieldDescriptorProto_TYPE_SINT64, optional))
+
+	optionals := &descriptorpb.DescriptorProto{Name: proto.String("Optionals")}
+	for i, typ := range scalarTypes {
+		proto3Optional(optionals, field(fmt.Sprintf("explicit_%d", i+1), int32(i+1), typ, optional))
+	}
+	proto3Optional(optionals, typedField("sparse", 16, descriptorpb.FieldDescriptorProto_TYPE_ENUM, scope+".Sparse", optional))
+
+	lists := &descriptorpb.DescriptorProto{Name: proto.String("Lists")}
+	for i, typ := range scalarTypes {
+		lists.Field = append(lists.Field, field(fmt.Sprintf("list_%d", i+1), int32(i+1), typ, repeated))
+	}
+	lists.Field = append(lists.Field,
+		typedField("sparse", 16, descriptorpb.FieldDescriptorProto_TYPE_ENUM, scope+".Sparse", repeated),
+		typedField("scalars", 17, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, scope+".Scalars", repeated))
+	unpacked := field("unpacked", 18, descriptorpb.FieldDescriptorProto_TYPE_SINT32, repeated)
+	unpacked.Options = &descriptorpb.FieldOptions{Packed: proto.Bool(false)}
+	lists.Field = append(lists.Field, unpacked)
+
+	oneofs := &descriptorpb.DescriptorProto{
+		Name:      proto.String("Oneofs"),
+		OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("choice")}},
+	}
+	for _, fd := range []*descriptorpb.FieldDescriptorProto{
+		field("text", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+		field("number", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, optional),
+		typedField("scalars", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, scope+".Scalars", optional),
+		typedField("sparse", 4, descriptorpb.FieldDescriptorProto_TYPE_ENUM, scope+".Sparse", optional),
+		field("blob", 5, descriptorpb.FieldDescriptorProto_TYPE_BYTES, optional),
+		field("real", 6, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, optional),
+	} {
+		fd.OneofIndex = proto.Int32(0)
+		oneofs.Field = append(oneofs.Field, fd)
+	}
+	oneofs.Field = append(oneofs.Field, field("after", 7, descriptorpb.FieldDescriptorProto_TYPE_BOOL, optional))
+
+	maps := &descriptorpb.DescriptorProto{Name: proto.String("Maps")}
+	values := []*descriptorpb.FieldDescriptorProto{
+		field("", 0, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil),
+		typedField("", 0, descriptorpb.FieldDescriptorProto_TYPE_ENUM, scope+".Sparse", nil),
+		field("", 0, descriptorpb.FieldDescriptorProto_TYPE_BYTES, nil),
+		field("", 0, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, nil),
+		typedField("", 0, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, scope+".Scalars", nil),
+		field("", 0, descriptorpb.FieldDescriptorProto_TYPE_FLOAT, nil),
+	}
+	for i, key := range mapKeyTypes {
+		number := int32(i + 1)
+		value := proto.Clone(values[i%len(values)]).(*descriptorpb.FieldDescriptorProto)
+		maps.Field = append(maps.Field, mapField(maps, scope, fmt.Sprintf("map_%d", number), number, field("", 0, key, nil), value))
+	}
+
+	tree := &descriptorpb.DescriptorProto{
+		Name: proto.String("Tree"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			field("value", 1, descriptorpb.FieldDescriptorProto_TYPE_SINT32, optional),
+			typedField("left", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, scope+".Tree", optional),
+			typedField("right", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, scope+".Tree", optional),
+			typedField("children", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, scope+".Tree", repeated),
+			field("label", 5, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+		},
+	}
+
+	// Names that the Meshtastic codecs code specially, with other types
+	impostor := &descriptorpb.DescriptorProto{
+		Name: proto.String("Impostor"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			field("portnum", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, optional),
+			field("payload", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+			field("latitude_i", 3, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, optional),
+			field("longitude_i", 4, descriptorpb.FieldDescriptorProto_TYPE_SINT64, optional),
+			field("time", 5, descriptorpb.FieldDescriptorProto_TYPE_INT64, optional),
+			field("emoji", 6, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+			field("psk", 7, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+			field("firmware_version", 8, descriptorpb.FieldDescriptorProto_TYPE_BYTES, optional),
+			field("from", 9, descriptorpb.FieldDescriptorProto_TYPE_UINT64, optional),
+			field("uptime_seconds", 10, descriptorpb.FieldDescriptorProto_TYPE_UINT64, optional),
+			field("freemem_bytes", 11, descriptorpb.FieldDescriptorProto_TYPE_UINT32, optional),
+			field("particles_03um", 12, descriptorpb.FieldDescriptorProto_TYPE_INT64, optional),
+			field("battery_level", 13, descriptorpb.FieldDescriptorProto_TYPE_FLOAT, optional),
+			field("hw_model", 14, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+			typedField("role", 15, descriptorpb.FieldDescriptorProto_TYPE_ENUM, scope+".Sparse", optional),
+			field("snr_towards", 16, descriptorpb.FieldDescriptorProto_TYPE_FLOAT, repeated),
+			field("want_ack", 17, descriptorpb.FieldDescriptorProto_TYPE_BOOL, repeated),
+			field("id", 18, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+		},
+	}
+
+	return &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("randproto/tricky.proto"),
+		Package:     proto.String("randproto.tricky"),
+		Syntax:      proto.String("proto3"),
+		EnumType:    []*descriptorpb.EnumDescriptorProto{sparse},
+		MessageType: []*descriptorpb.DescriptorProto{scalars, optionals, lists, oneofs, maps, tree, impostor},
+	}
+}
+
+// proto2Schemas returns the proto2 schemas.
+func proto2Schemas() *descriptorpb.FileDescriptorProto {
+	const scope = ".randproto.legacy"
+	closed := &descriptorpb.EnumDescriptorProto{
+		Name: proto.String("Closed"),
+		Value: []*descriptorpb.EnumValueDescriptorProto{
+			{Name: proto.String("CLOSED_A"), Number: proto.Int32(1)},
+			{Name: proto.String("CLOSED_B"), Number: proto.Int32(5)},
+			{Name: proto.String("CLOSED_NEGATIVE"), Number: proto.Int32(-7)},
+		},
+	}
+
+	withDefault := func(fd *descriptorpb.FieldDescriptorProto, value string) *descriptorpb.FieldDescriptorProto {
+		fd.DefaultValue = proto.String(value)
+		return fd
+	}
+	packed := field("packed", 6, descriptorpb.FieldDescriptorProto_TYPE_SINT64, repeated)
+	packed.Options = &descriptorpb.FieldOptions{Packed: proto.Bool(true)}
+
+	legacy := &descriptorpb.DescriptorProto{
+		Name: proto.String("Legacy"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, required),
+			withDefault(field("name", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional), "unnamed"),
+			withDefault(typedField("kind", 3, descriptorpb.FieldDescriptorProto_TYPE_ENUM, scope+".Closed", optional), "CLOSED_B"),
+			withDefault(field("ratio", 4, descriptorpb.FieldDescriptorProto_TYPE_FLOAT, optional), "nan"),
+			field("unpacked", 5, descriptorpb.FieldDescriptorProto_TYPE_INT32, repeated),
+			packed,
+			withDefault(field("value", 7, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, optional), "-inf"),
+			withDefault(field("data", 8, descriptorpb.FieldDescriptorProto_TYPE_BYTES, optional), `\000\001`),
+			typedField("next", 9, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, scope+".Legacy", optional),
+			typedField("kinds", 10, descriptorpb.FieldDescriptorProto_TYPE_ENUM, scope+".Closed", repeated),
+			withDefault(field("count", 11, descriptorpb.FieldDescriptorProto_TYPE_UINT64, optional), "18446744073709551615"),
+			field("flag", 12, descriptorpb.FieldDescriptorProto_TYPE_BOOL, required),
+		},
+	}
+
+	return &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("randproto/legacy.proto"),
+		Package:     proto.String("randproto.legacy"),
+		Syntax:      proto.String("proto2"),
+		EnumType:    []*descriptorpb.EnumDescriptorProto{closed},
+		MessageType: []*descriptorpb.DescriptorProto{legacy},
+	}
+}
@@ -0,0 +1,52 @@
+// Package randproto generates protobuf schemas and messages for fuzzing
+// the codecs. Schemas and values are built with descriptorpb and dynamicpb
+// from the choices of a Source, so a fuzz input describes a message, and
+// the values favour the edge cases that codecs get wrong: negative zero,
+// NaN and infinities, extreme integers, enum values outside the declared
+// ones, empty and multibyte strings, and deeply nested messages.
+package randproto
+
+import "encoding/binary"
+
+// Source is a deterministic source of choices read from fuzz input. After
+// the input runs out it returns zeros, so every input describes a message.
+type Source struct {
+	data []byte
+}
+
+// NewSource returns a source reading choices from data.
+func NewSource(data []byte) *Source {
+	return &Source{data: data}
+}
+
+// Byte returns the next byte of the input.
+func (s *Source) Byte() byte {
+	if len(s.data) == 0 {
+		return 0
+	}
+	b := s.data[0]
+	s.data = s.data[1:]
+	return b
+}
+
+// Uint64 returns the next 8 bytes of the input.
+func (s *Source) Uint64() uint64 {
+	var buf [8]byte
+	for i := range buf {
+		buf[i] = s.Byte()
+	}
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+// Intn returns a number in [0, n), for n up to 256.
+func (s *Source) Intn(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return int(s.Byte()) % n
+}
+
+// Bool returns the low bit of the next byte.
+func (s *Source) Bool() bool {
+	return s.Byte()&1 != 0
+}
@@ -0,0 +1,4 @@
+// Package repocheck holds repository-wide checks that run as ordinary tests,
+// so they do not depend on a CI configuration: every package of every module
+// in the repository must build and be exercised by at least one test.
+package repocheck
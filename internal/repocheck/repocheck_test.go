@@ -0,0 +1,132 @@
+package repocheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// listedPackage holds the fields of `go list -json` used by the checks.
+type listedPackage struct {
+	ImportPath   string
+	Name         string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	Deps         []string
+	TestImports  []string
+	XTestImports []string
+	Error        *struct{ Err string }
+	DepsErrors   []struct{ Err string }
+}
+
+// modulePrefix is the import path prefix of all modules in the repository.
+const modulePrefix = "github.com/egonelbre/exp-protobuf-compression"
+
+func TestAllPackagesBuildAndAreTested(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the whole repository")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var packages []listedPackage
+	for _, dir := range moduleDirs(t, root) {
+		if out, err := goCommand(dir, "build", "./..."); err != nil {
+			t.Errorf("module %s does not build: %v\n%s", dir, err, out)
+			continue
+		}
+		packages = append(packages, listPackages(t, dir)...)
+	}
+
+	exercised := map[string]bool{}
+	for _, p := range packages {
+		if len(p.TestGoFiles) == 0 && len(p.XTestGoFiles) == 0 {
+			continue
+		}
+		exercised[p.ImportPath] = true
+		for _, imports := range [][]string{p.Deps, p.TestImports, p.XTestImports} {
+			for _, path := range imports {
+				exercised[path] = true
+			}
+		}
+	}
+
+	for _, p := range packages {
+		if p.Error != nil {
+			t.Errorf("%s: %s", p.ImportPath, p.Error.Err)
+		}
+		for _, err := range p.DepsErrors {
+			t.Errorf("%s: %s", p.ImportPath, err.Err)
+		}
+		if !exercised[p.ImportPath] {
+			t.Errorf("%s is not exercised by any test", p.ImportPath)
+		}
+	}
+}
+
+// moduleDirs returns the directories of all modules in the repository.
+func moduleDirs(t *testing.T, root string) []string {
+	t.Helper()
+
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != root && (d.Name() == "testdata" || strings.HasPrefix(d.Name(), ".")) {
+			return filepath.SkipDir
+		}
+		if d.Name() == "go.mod" {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dirs
+}
+
+// listPackages lists the packages of the module in dir.
+func listPackages(t *testing.T, dir string) []listedPackage {
+	t.Helper()
+
+	out, err := goCommand(dir, "list", "-e", "-json", "./...")
+	if err != nil {
+		t.Fatalf("go list in %s: %v\n%s", dir, err, out)
+	}
+
+	var packages []listedPackage
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var p listedPackage
+		if err := dec.Decode(&p); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("go list in %s: %v", dir, err)
+		}
+		if strings.HasPrefix(p.ImportPath, modulePrefix) {
+			packages = append(packages, p)
+		}
+	}
+	return packages
+}
+
+// goCommand runs the go command in dir and returns its output.
+func goCommand(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	return cmd.CombinedOutput()
+}
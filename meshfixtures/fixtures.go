@@ -0,0 +1,389 @@
+// Package meshfixtures builds realistic Meshtastic messages for tests,
+// benchmarks, fuzz seeds and documentation.
+//
+// Messages are derived from a Scenario, which describes the node sending
+// them. Builders are deterministic: the same scenario always produces the
+// same messages.
+package meshfixtures
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+// Scenario describes a node and its surroundings.
+type Scenario struct {
+	Name string
+
+	// Node identity
+	NodeNum   uint32
+	PeerNum   uint32 // Destination for directed packets
+	LongName  string
+	ShortName string
+	HwModel   meshtastic.HardwareModel
+	Role      meshtastic.Config_DeviceConfig_Role
+
+	// Position, in 1e-7 degrees and meters
+	LatitudeI  int32
+	LongitudeI int32
+	Altitude   int32
+	Speed      uint32 // m/s
+	Track      uint32 // 1e-5 degrees
+	SatsInView uint32
+
+	// Device state
+	Time          uint32
+	BatteryLevel  uint32
+	Voltage       float32
+	ChannelUtil   float32
+	AirUtilTx     float32
+	UptimeSeconds uint32
+
+	// Environment
+	Temperature float32
+	Humidity    float32
+	Pressure    float32
+
+	// Radio
+	HopLimit uint32
+	RxSnr    float32
+	RxRssi   int32
+}
+
+var (
+	// Default is a generic client node, used where the scenario doesn't matter.
+	Default = Scenario{
+		Name:          "default",
+		NodeNum:       123456789,
+		PeerNum:       987654321,
+		LongName:      "Test Meshtastic Node",
+		ShortName:     "TEST",
+		HwModel:       meshtastic.HardwareModel_TBEAM,
+		Role:          meshtastic.Config_DeviceConfig_CLIENT,
+		LatitudeI:     375317890,
+		LongitudeI:    -1223898570,
+		Altitude:      100,
+		SatsInView:    8,
+		Time:          1703520000,
+		BatteryLevel:  75,
+		Voltage:       4.1,
+		ChannelUtil:   15.5,
+		AirUtilTx:     5.2,
+		UptimeSeconds: 86400,
+		Temperature:   22.5,
+		Humidity:      65,
+		Pressure:      1013.25,
+		HopLimit:      3,
+		RxSnr:         7.5,
+		RxRssi:        -85,
+	}
+
+	// BaseStation is a stationary, mains powered router on a hill.
+	BaseStation = Scenario{
+		Name:          "base-station",
+		NodeNum:       0x0a1b2c3d,
+		PeerNum:       0xffffffff,
+		LongName:      "Hilltop Router",
+		ShortName:     "HILL",
+		HwModel:       meshtastic.HardwareModel_RAK4631,
+		Role:          meshtastic.Config_DeviceConfig_ROUTER,
+		LatitudeI:     474921300,
+		LongitudeI:    -1216532100,
+		Altitude:      1120,
+		SatsInView:    12,
+		Time:          1703520000,
+		BatteryLevel:  101, // Powered
+		Voltage:       5.0,
+		ChannelUtil:   24.1,
+		AirUtilTx:     9.8,
+		UptimeSeconds: 2592000,
+		Temperature:   8.25,
+		Humidity:      81,
+		Pressure:      887.5,
+		HopLimit:      3,
+		RxSnr:         11.25,
+		RxRssi:        -72,
+	}
+
+	// Hiker is a handheld node moving on foot.
+	Hiker = Scenario{
+		Name:          "hiker",
+		NodeNum:       0x5e6f7a8b,
+		PeerNum:       0x0a1b2c3d,
+		LongName:      "Trail Walker",
+		ShortName:     "TRL",
+		HwModel:       meshtastic.HardwareModel_T_ECHO,
+		Role:          meshtastic.Config_DeviceConfig_CLIENT_MUTE,
+		LatitudeI:     474712500,
+		LongitudeI:    -1216110400,
+		Altitude:      842,
+		Speed:         1,
+		Track:         27000000,
+		SatsInView:    7,
+		Time:          1703523600,
+		BatteryLevel:  58,
+		Voltage:       3.82,
+		ChannelUtil:   6.4,
+		AirUtilTx:     1.1,
+		UptimeSeconds: 14400,
+		Temperature:   4.5,
+		Humidity:      72,
+		Pressure:      921.75,
+		HopLimit:      2,
+		RxSnr:         -6.5,
+		RxRssi:        -118,
+	}
+
+	// Vehicle is a car-mounted tracker.
+	Vehicle = Scenario{
+		Name:          "vehicle",
+		NodeNum:       0x9c8d7e6f,
+		PeerNum:       0xffffffff,
+		LongName:      "Van Tracker",
+		ShortName:     "VAN",
+		HwModel:       meshtastic.HardwareModel_HELTEC_V3,
+		Role:          meshtastic.Config_DeviceConfig_TRACKER,
+		LatitudeI:     476062100,
+		LongitudeI:    -1223320700,
+		Altitude:      56,
+		Speed:         24,
+		Track:         9000000,
+		SatsInView:    10,
+		Time:          1703527200,
+		BatteryLevel:  100,
+		Voltage:       4.2,
+		ChannelUtil:   18.7,
+		AirUtilTx:     3.3,
+		UptimeSeconds: 5400,
+		Temperature:   19,
+		Humidity:      44,
+		Pressure:      1009.5,
+		HopLimit:      3,
+		RxSnr:         2.75,
+		RxRssi:        -101,
+	}
+)
+
+// Scenarios returns all predefined scenarios.
+func Scenarios() []Scenario {
+	return []Scenario{Default, BaseStation, Hiker, Vehicle}
+}
+
+// UserID returns the node ID string, e.g. "!075bcd15".
+func (s Scenario) UserID() string {
+	const hex = "0123456789abcdef"
+	id := []byte("!00000000")
+	for i := 8; i >= 1; i-- {
+		id[i] = hex[(s.NodeNum>>(uint(8-i)*4))&0xF]
+	}
+	return string(id)
+}
+
+// Position returns the basic position report of the node.
+func Position(s Scenario) *meshtastic.Position {
+	return &meshtastic.Position{
+		LatitudeI:  proto.Int32(s.LatitudeI),
+		LongitudeI: proto.Int32(s.LongitudeI),
+		Altitude:   proto.Int32(s.Altitude),
+		Time:       s.Time,
+	}
+}
+
+// FullPosition returns a position report with GPS quality fields set.
+func FullPosition(s Scenario) *meshtastic.Position {
+	msg := Position(s)
+	msg.LocationSource = meshtastic.Position_LOC_INTERNAL
+	msg.AltitudeSource = meshtastic.Position_ALT_INTERNAL
+	msg.Timestamp = s.Time
+	msg.SatsInView = s.SatsInView
+	msg.GpsAccuracy = 5
+	msg.PDOP = 120
+	msg.HDOP = 90
+	msg.VDOP = 100
+	msg.GroundSpeed = proto.Uint32(s.Speed)
+	msg.GroundTrack = proto.Uint32(s.Track)
+	msg.PrecisionBits = 32
+	return msg
+}
+
+// User returns the user profile of the node.
+func User(s Scenario) *meshtastic.User {
+	return &meshtastic.User{
+		Id:        s.UserID(),
+		LongName:  s.LongName,
+		ShortName: s.ShortName,
+		HwModel:   s.HwModel,
+	}
+}
+
+// FullUser returns the user profile with role, MAC address and public key.
+func FullUser(s Scenario) *meshtastic.User {
+	msg := User(s)
+	msg.Role = s.Role
+	msg.Macaddr = []byte{0xd4, 0xd4, byte(s.NodeNum >> 24), byte(s.NodeNum >> 16), byte(s.NodeNum >> 8), byte(s.NodeNum)}
+	msg.PublicKey = make([]byte, 32)
+	for i := range msg.PublicKey {
+		msg.PublicKey[i] = byte(s.NodeNum>>(uint(i%4)*8)) ^ byte(i*37)
+	}
+	return msg
+}
+
+// DeviceMetrics returns the device metrics of the node.
+func DeviceMetrics(s Scenario) *meshtastic.DeviceMetrics {
+	return &meshtastic.DeviceMetrics{
+		BatteryLevel:       proto.Uint32(s.BatteryLevel),
+		Voltage:            proto.Float32(s.Voltage),
+		ChannelUtilization: proto.Float32(s.ChannelUtil),
+		AirUtilTx:          proto.Float32(s.AirUtilTx),
+		UptimeSeconds:      proto.Uint32(s.UptimeSeconds),
+	}
+}
+
+// DeviceTelemetry returns a device metrics telemetry report.
+func DeviceTelemetry(s Scenario) *meshtastic.Telemetry {
+	return &meshtastic.Telemetry{
+		Time: s.Time,
+		Variant: &meshtastic.Telemetry_DeviceMetrics{
+			DeviceMetrics: DeviceMetrics(s),
+		},
+	}
+}
+
+// EnvironmentTelemetry returns an environment metrics telemetry report.
+func EnvironmentTelemetry(s Scenario) *meshtastic.Telemetry {
+	return &meshtastic.Telemetry{
+		Time: s.Time,
+		Variant: &meshtastic.Telemetry_EnvironmentMetrics{
+			EnvironmentMetrics: &meshtastic.EnvironmentMetrics{
+				Temperature:        proto.Float32(s.Temperature),
+				RelativeHumidity:   proto.Float32(s.Humidity),
+				BarometricPressure: proto.Float32(s.Pressure),
+			},
+		},
+	}
+}
+
+// NodeInfo returns the node database entry for the node.
+func NodeInfo(s Scenario) *meshtastic.NodeInfo {
+	return &meshtastic.NodeInfo{
+		Num:           s.NodeNum,
+		User:          User(s),
+		Position:      Position(s),
+		Snr:           s.RxSnr,
+		LastHeard:     s.Time + 100,
+		DeviceMetrics: DeviceMetrics(s),
+		HopsAway:      proto.Uint32(2),
+	}
+}
+
+// Packet returns a packet from the node with the given port and payload.
+func Packet(s Scenario, portnum meshtastic.PortNum, payload []byte) *meshtastic.MeshPacket {
+	return &meshtastic.MeshPacket{
+		From: s.NodeNum,
+		To:   s.PeerNum,
+		PayloadVariant: &meshtastic.MeshPacket_Decoded{
+			Decoded: &meshtastic.Data{
+				Portnum: portnum,
+				Payload: payload,
+			},
+		},
+	}
+}
+
+// TextPacket returns a text message packet from the node.
+func TextPacket(s Scenario, text string) *meshtastic.MeshPacket {
+	return Packet(s, meshtastic.PortNum_TEXT_MESSAGE_APP, []byte(text))
+}
+
+// ReactionPacket returns an emoji reaction to the packet with replyID.
+func ReactionPacket(s Scenario, replyID uint32, emoji string) *meshtastic.MeshPacket {
+	packet := TextPacket(s, emoji)
+	data := packet.GetDecoded()
+	data.ReplyId = replyID
+	data.Emoji = 1
+	return packet
+}
+
+// Waypoint returns a waypoint placed by the node.
+func Waypoint(s Scenario, name string, icon rune) *meshtastic.Waypoint {
+	return &meshtastic.Waypoint{
+		Id:          s.NodeNum ^ 0x5a5a,
+		LatitudeI:   proto.Int32(s.LatitudeI + 1200),
+		LongitudeI:  proto.Int32(s.LongitudeI - 800),
+		Expire:      s.Time + 86400,
+		Name:        name,
+		Description: "Meeting point near the trail head",
+		Icon:        uint32(icon),
+	}
+}
+
+// PositionPacket returns a packet carrying the node's position.
+func PositionPacket(s Scenario) *meshtastic.MeshPacket {
+	return Packet(s, meshtastic.PortNum_POSITION_APP, mustMarshal(FullPosition(s)))
+}
+
+// TelemetryPacket returns a packet carrying the node's device telemetry.
+func TelemetryPacket(s Scenario) *meshtastic.MeshPacket {
+	return Packet(s, meshtastic.PortNum_TELEMETRY_APP, mustMarshal(DeviceTelemetry(s)))
+}
+
+// NodeInfoPacket returns a packet carrying the node's user profile.
+func NodeInfoPacket(s Scenario) *meshtastic.MeshPacket {
+	return Packet(s, meshtastic.PortNum_NODEINFO_APP, mustMarshal(User(s)))
+}
+
+// ReceivedPacket adds receive metadata to a packet, as seen by a neighbour.
+func ReceivedPacket(s Scenario, packet *meshtastic.MeshPacket) *meshtastic.MeshPacket {
+	packet.Id = s.NodeNum ^ s.Time
+	packet.RxTime = s.Time
+	packet.RxSnr = s.RxSnr
+	packet.RxRssi = s.RxRssi
+	packet.HopLimit = s.HopLimit
+	packet.HopStart = s.HopLimit
+	return packet
+}
+
+// Text messages typical for the mesh.
+var TextMessages = []string{
+	"This is a test message being sent over the Meshtastic network",
+	"Hello from the mesh network!",
+	"On my way back to camp, should be there in about an hour.",
+	"Anyone copy? Testing range from the north ridge.",
+	"ok",
+}
+
+// Messages returns one of each message kind for the scenario.
+func Messages(s Scenario) []proto.Message {
+	return []proto.Message{
+		Position(s),
+		FullPosition(s),
+		FullUser(s),
+		DeviceTelemetry(s),
+		EnvironmentTelemetry(s),
+		NodeInfo(s),
+		Waypoint(s, "Camp", '⛺'),
+		ReceivedPacket(s, TextPacket(s, TextMessages[0])),
+		ReceivedPacket(s, ReactionPacket(s, s.PeerNum^s.Time, "👍")),
+		ReceivedPacket(s, PositionPacket(s)),
+		ReceivedPacket(s, TelemetryPacket(s)),
+		ReceivedPacket(s, NodeInfoPacket(s)),
+	}
+}
+
+// All returns the messages of all predefined scenarios.
+func All() []proto.Message {
+	var all []proto.Message
+	for _, s := range Scenarios() {
+		all = append(all, Messages(s)...)
+	}
+	return all
+}
+
+func mustMarshal(msg proto.Message) []byte {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
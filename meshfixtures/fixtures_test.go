@@ -0,0 +1,24 @@
+package meshfixtures
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestUserID(t *testing.T) {
+	if id := Default.UserID(); id != "!075bcd15" {
+		t.Errorf("Expected !075bcd15, got %s", id)
+	}
+}
+
+func TestMessagesDeterministic(t *testing.T) {
+	for _, scenario := range Scenarios() {
+		a, b := Messages(scenario), Messages(scenario)
+		for i := range a {
+			if !proto.Equal(a[i], b[i]) {
+				t.Errorf("%s: message %d differs between calls", scenario.Name, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/egonelbre/exp-protobuf-compression/meshtastic
+
+go 1.25
+
+require (
+	github.com/egonelbre/exp-protobuf-compression v0.0.0
+	google.golang.org/protobuf v1.36.11
+)
+
+replace (
+	github.com/egonelbre/exp-protobuf-compression => ..
+)
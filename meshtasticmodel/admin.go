@@ -0,0 +1,309 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
+)
+
+// Admin sessions are request/response round trips: a client asks for a
+// config section with get_config_request and the node answers with a
+// get_config_response holding exactly that section, along with the session
+// passkey that the client repeats in its following messages. With
+// SessionOptions.AdminCorrelation, sessions remember the outstanding
+// requests of the epoch, so that a response only codes which request it
+// answers and the content of the requested section, and the passkey only
+// codes whether it repeats.
+
+// maxPendingAdminRequests limits the outstanding requests remembered by a
+// session; the oldest ones are forgotten first.
+const maxPendingAdminRequests = 16
+
+// adminState is the request/response state of an admin session epoch.
+type adminState struct {
+	pending []adminRequest // Outstanding requests, oldest first
+	passkey []byte         // Last session passkey
+}
+
+// adminRequest is an outstanding admin request.
+type adminRequest struct {
+	response protoreflect.FieldDescriptor // Variant of the expected response
+	section  protoreflect.FieldNumber     // Expected oneof variant of the response, 0 if unknown
+}
+
+// adminDescriptor is the descriptor of AdminMessage.
+var adminDescriptor = (&meshtastic.AdminMessage{}).ProtoReflect().Descriptor()
+
+// adminResponses maps the variants of requests, such as get_config_request,
+// to the variants of their responses, such as get_config_response.
+var adminResponses = sync.OnceValue(func() map[protoreflect.FieldNumber]protoreflect.FieldDescriptor {
+	responses := make(map[protoreflect.FieldNumber]protoreflect.FieldDescriptor)
+	fields := adminDescriptor.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name, ok := strings.CutSuffix(string(fd.Name()), "_request")
+		if !ok {
+			continue
+		}
+		if response := fields.ByName(protoreflect.Name(name + "_response")); response != nil {
+			responses[fd.Number()] = response
+		}
+	}
+	return responses
+})
+
+// adminRequestOf returns the request of msg, if msg is one that is answered
+// with a response. Requests for a section by an enum, such as
+// get_config_request, expect the response oneof variant numbered one past
+// the enum value, which is how the config and module config variants are
+// numbered.
+func adminRequestOf(msg protoreflect.Message) (adminRequest, bool) {
+	fd := msg.WhichOneof(adminDescriptor.Oneofs().ByName("payload_variant"))
+	if fd == nil {
+		return adminRequest{}, false
+	}
+	response, ok := adminResponses()[fd.Number()]
+	if !ok {
+		return adminRequest{}, false
+	}
+
+	req := adminRequest{response: response}
+	if fd.Kind() == protoreflect.EnumKind && response.Kind() == protoreflect.MessageKind {
+		md := response.Message()
+		section := md.Fields().ByNumber(protoreflect.FieldNumber(msg.Get(fd).Enum()) + 1)
+		if md.Oneofs().Len() == 1 && section != nil && section.ContainingOneof() != nil && section.Kind() == protoreflect.MessageKind {
+			req.section = section.Number()
+		}
+	}
+	return req, true
+}
+
+// remember adds an outstanding request.
+func (s *adminState) remember(req adminRequest) {
+	if len(s.pending) == maxPendingAdminRequests {
+		s.pending = s.pending[1:]
+	}
+	s.pending = append(s.pending, req)
+}
+
+// answer removes the outstanding request with index i and returns it.
+func (s *adminState) answer(i int) adminRequest {
+	req := s.pending[i]
+	s.pending = append(s.pending[:i:i], s.pending[i+1:]...)
+	return req
+}
+
+// compressAdminMessage compresses an AdminMessage of a session with
+// correlation.
+func compressAdminMessage(msg protoreflect.Message, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	state := mcb.admin
+	passkeyFd := adminDescriptor.Fields().ByName("session_passkey")
+
+	passkey := msg.Get(passkeyFd).Bytes()
+	repeated := state.passkey != nil && bytes.Equal(passkey, state.passkey)
+	if state.passkey != nil {
+		if err := models.EncodeEscaped(enc, boolSymbol(repeated), mcb.GetBooleanModel("admin_passkey_repeated")); err != nil {
+			return fmt.Errorf("passkey: %w", err)
+		}
+	}
+	if !repeated {
+		if err := models.EncodeEscaped(enc, boolSymbol(len(passkey) > 0), mcb.GetBooleanModel("session_passkey_presence")); err != nil {
+			return fmt.Errorf("passkey: %w", err)
+		}
+		if len(passkey) > 0 {
+			if err := compressFieldValueV10("session_passkey", passkeyFd, msg.Get(passkeyFd), enc, mcb); err != nil {
+				return fmt.Errorf("passkey: %w", err)
+			}
+			state.passkey = bytes.Clone(passkey)
+		}
+	}
+
+	if len(state.pending) > 0 {
+		answered := -1
+		if variant := msg.WhichOneof(adminDescriptor.Oneofs().ByName("payload_variant")); variant != nil && onlyAdminVariant(msg, variant) {
+			for i, req := range state.pending {
+				if req.response == variant {
+					answered = i
+					break
+				}
+			}
+		}
+		if err := models.EncodeEscaped(enc, boolSymbol(answered >= 0), mcb.GetBooleanModel("admin_answers_request")); err != nil {
+			return fmt.Errorf("response: %w", err)
+		}
+		if answered >= 0 {
+			if err := encodeVarintWithModels(uint64(answered), enc, mcb); err != nil {
+				return fmt.Errorf("response: %w", err)
+			}
+			if err := compressAdminResponse(msg, state.answer(answered), enc, mcb); err != nil {
+				return fmt.Errorf("response: %w", err)
+			}
+			return nil
+		}
+	}
+
+	rest := proto.Clone(msg.Interface()).ProtoReflect()
+	rest.Clear(passkeyFd)
+	if err := compressMessageV10("", rest, enc, mcb); err != nil {
+		return err
+	}
+	if req, ok := adminRequestOf(msg); ok {
+		state.remember(req)
+	}
+	return nil
+}
+
+// decompressAdminMessage decompresses an AdminMessage written by
+// compressAdminMessage into msg.
+func decompressAdminMessage(msg protoreflect.Message, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
+	state := mcb.admin
+	passkeyFd := adminDescriptor.Fields().ByName("session_passkey")
+
+	repeated := 0
+	if state.passkey != nil {
+		var err error
+		if repeated, err = models.DecodeEscaped(dec, mcb.GetBooleanModel("admin_passkey_repeated")); err != nil {
+			return fmt.Errorf("passkey: %w", err)
+		}
+	}
+	var passkey []byte
+	if repeated == 1 {
+		passkey = bytes.Clone(state.passkey)
+	} else {
+		present, err := models.DecodeEscaped(dec, mcb.GetBooleanModel("session_passkey_presence"))
+		if err != nil {
+			return fmt.Errorf("passkey: %w", err)
+		}
+		if present == 1 {
+			value, err := decompressFieldValueV10("session_passkey", passkeyFd, dec, mcb)
+			if err != nil {
+				return fmt.Errorf("passkey: %w", err)
+			}
+			passkey = value.Bytes()
+			state.passkey = bytes.Clone(passkey)
+		}
+	}
+	setPasskey := func() {
+		if len(passkey) > 0 {
+			msg.Set(passkeyFd, protoreflect.ValueOfBytes(passkey))
+		}
+	}
+
+	if len(state.pending) > 0 {
+		answers, err := models.DecodeEscaped(dec, mcb.GetBooleanModel("admin_answers_request"))
+		if err != nil {
+			return fmt.Errorf("response: %w", err)
+		}
+		if answers == 1 {
+			i, err := decodeVarintWithModels(dec, mcb)
+			if err != nil {
+				return fmt.Errorf("response: %w", err)
+			}
+			if i >= uint64(len(state.pending)) {
+				return fmt.Errorf("response to request %d of %d: %w", i, len(state.pending), pbmodel.ErrOutOfRange)
+			}
+			if err := decompressAdminResponse(msg, state.answer(int(i)), dec, mcb); err != nil {
+				return fmt.Errorf("response: %w", err)
+			}
+			setPasskey()
+			return nil
+		}
+	}
+
+	if err := decompressMessageV10("", msg, dec, mcb); err != nil {
+		return err
+	}
+	setPasskey()
+	if req, ok := adminRequestOf(msg); ok {
+		state.remember(req)
+	}
+	return nil
+}
+
+// onlyAdminVariant reports whether variant is the only field of msg besides
+// the session passkey.
+func onlyAdminVariant(msg protoreflect.Message, variant protoreflect.FieldDescriptor) bool {
+	only := true
+	msg.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		only = fd == variant || fd.Name() == "session_passkey"
+		return only
+	})
+	return only
+}
+
+// compressAdminResponse compresses the response variant of msg answering
+// req. A response holding only the expected section codes just the section.
+func compressAdminResponse(msg protoreflect.Message, req adminRequest, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	path := pbmodel.BuildFieldPath("", string(req.response.Name()))
+	value := msg.Get(req.response)
+	if req.response.Kind() != protoreflect.MessageKind {
+		return compressFieldValueV10(path, req.response, value, enc, mcb)
+	}
+
+	response := value.Message()
+	if req.section != 0 {
+		section := response.Descriptor().Fields().ByNumber(req.section)
+		expected := response.Has(section) && onlySection(response, section)
+		if err := models.EncodeEscaped(enc, boolSymbol(expected), mcb.GetBooleanModel("admin_section_expected")); err != nil {
+			return err
+		}
+		if expected {
+			return compressMessageV10(pbmodel.BuildFieldPath(path, string(section.Name())), response.Get(section).Message(), enc, mcb)
+		}
+	}
+	return compressMessageV10(path, response, enc, mcb)
+}
+
+// decompressAdminResponse decompresses a response written by
+// compressAdminResponse into msg.
+func decompressAdminResponse(msg protoreflect.Message, req adminRequest, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
+	path := pbmodel.BuildFieldPath("", string(req.response.Name()))
+	if req.response.Kind() != protoreflect.MessageKind {
+		value, err := decompressFieldValueV10(path, req.response, dec, mcb)
+		if err != nil {
+			return err
+		}
+		msg.Set(req.response, value)
+		return nil
+	}
+
+	response := msg.Mutable(req.response).Message()
+	if req.section != 0 {
+		section := response.Descriptor().Fields().ByNumber(req.section)
+		expected, err := models.DecodeEscaped(dec, mcb.GetBooleanModel("admin_section_expected"))
+		if err != nil {
+			return err
+		}
+		if expected == 1 {
+			return decompressMessageV10(pbmodel.BuildFieldPath(path, string(section.Name())), response.Mutable(section).Message(), dec, mcb)
+		}
+	}
+	return decompressMessageV10(path, response, dec, mcb)
+}
+
+// onlySection reports whether section is the only field of response.
+func onlySection(response protoreflect.Message, section protoreflect.FieldDescriptor) bool {
+	only := true
+	response.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		only = fd == section
+		return only
+	})
+	return only
+}
+
+// boolSymbol returns the symbol of b in boolean models.
+func boolSymbol(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
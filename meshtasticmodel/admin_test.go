@@ -0,0 +1,114 @@
+package meshtasticmodel
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+// adminSession returns the admin messages of a client reading the settings
+// of a node and changing one of them.
+func adminSession() []*meshtastic.AdminMessage {
+	s := meshfixtures.Scenarios()[0]
+	passkey := []byte{0x3f, 0x91, 0x0c, 0x7e, 0xa4, 0x58, 0x21, 0xd6}
+
+	lora := &meshtastic.Config_LoRaConfig{
+		UsePreset:   true,
+		ModemPreset: meshtastic.Config_LoRaConfig_LONG_FAST,
+		Region:      meshtastic.Config_LoRaConfig_EU_868,
+		HopLimit:    3,
+		TxEnabled:   true,
+		TxPower:     27,
+	}
+	position := &meshtastic.Config_PositionConfig{
+		PositionBroadcastSecs:             900,
+		PositionBroadcastSmartEnabled:     true,
+		GpsUpdateInterval:                 120,
+		BroadcastSmartMinimumDistance:     100,
+		BroadcastSmartMinimumIntervalSecs: 30,
+	}
+
+	messages := []*meshtastic.AdminMessage{
+		{PayloadVariant: &meshtastic.AdminMessage_GetOwnerRequest{GetOwnerRequest: true}},
+		{PayloadVariant: &meshtastic.AdminMessage_GetOwnerResponse{GetOwnerResponse: meshfixtures.User(s)}, SessionPasskey: passkey},
+		// Pipelined requests, answered in order
+		{PayloadVariant: &meshtastic.AdminMessage_GetConfigRequest{GetConfigRequest: meshtastic.AdminMessage_LORA_CONFIG}, SessionPasskey: passkey},
+		{PayloadVariant: &meshtastic.AdminMessage_GetConfigRequest{GetConfigRequest: meshtastic.AdminMessage_POSITION_CONFIG}, SessionPasskey: passkey},
+		{PayloadVariant: &meshtastic.AdminMessage_GetModuleConfigRequest{GetModuleConfigRequest: meshtastic.AdminMessage_MQTT_CONFIG}, SessionPasskey: passkey},
+		{PayloadVariant: &meshtastic.AdminMessage_GetConfigResponse{GetConfigResponse: &meshtastic.Config{
+			PayloadVariant: &meshtastic.Config_Lora{Lora: lora},
+		}}, SessionPasskey: passkey},
+		{PayloadVariant: &meshtastic.AdminMessage_GetConfigResponse{GetConfigResponse: &meshtastic.Config{
+			PayloadVariant: &meshtastic.Config_Position{Position: position},
+		}}, SessionPasskey: passkey},
+		{PayloadVariant: &meshtastic.AdminMessage_GetModuleConfigResponse{GetModuleConfigResponse: &meshtastic.ModuleConfig{
+			PayloadVariant: &meshtastic.ModuleConfig_Mqtt{Mqtt: &meshtastic.ModuleConfig_MQTTConfig{Enabled: true, Address: "mqtt.meshtastic.org"}},
+		}}, SessionPasskey: passkey},
+		// A response with another section than the requested one
+		{PayloadVariant: &meshtastic.AdminMessage_GetConfigRequest{GetConfigRequest: meshtastic.AdminMessage_DEVICE_CONFIG}, SessionPasskey: passkey},
+		{PayloadVariant: &meshtastic.AdminMessage_GetConfigResponse{GetConfigResponse: &meshtastic.Config{
+			PayloadVariant: &meshtastic.Config_Power{Power: &meshtastic.Config_PowerConfig{LsSecs: 300}},
+		}}, SessionPasskey: passkey},
+		{PayloadVariant: &meshtastic.AdminMessage_GetCannedMessageModuleMessagesRequest{GetCannedMessageModuleMessagesRequest: true}, SessionPasskey: passkey},
+		{PayloadVariant: &meshtastic.AdminMessage_GetCannedMessageModuleMessagesResponse{GetCannedMessageModuleMessagesResponse: "Yes|No|On my way"}, SessionPasskey: passkey},
+		{PayloadVariant: &meshtastic.AdminMessage_SetConfig{SetConfig: &meshtastic.Config{
+			PayloadVariant: &meshtastic.Config_Lora{Lora: &meshtastic.Config_LoRaConfig{UsePreset: true, HopLimit: 5}},
+		}}, SessionPasskey: passkey},
+		// A request that is never answered and a new passkey
+		{PayloadVariant: &meshtastic.AdminMessage_GetChannelRequest{GetChannelRequest: 1}},
+		{PayloadVariant: &meshtastic.AdminMessage_RebootSeconds{RebootSeconds: 5}, SessionPasskey: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+	}
+	return messages
+}
+
+func TestAdminCorrelation(t *testing.T) {
+	sizes := map[bool]int{}
+	for _, correlate := range []bool{false, true} {
+		opts := SessionOptions{AdminCorrelation: correlate}
+		enc := NewSessionEncoderWithOptions(opts)
+		dec := NewSessionDecoderWithOptions(opts)
+		for i, msg := range adminSession() {
+			frame, err := enc.Encode(msg)
+			if err != nil {
+				t.Fatalf("correlate %v message %d: Encode failed: %v", correlate, i, err)
+			}
+			sizes[correlate] += len(frame)
+
+			result := &meshtastic.AdminMessage{}
+			if err := dec.Decode(frame, result); err != nil {
+				t.Fatalf("correlate %v message %d: Decode failed: %v", correlate, i, err)
+			}
+			if !proto.Equal(msg, result) {
+				t.Fatalf("correlate %v message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", correlate, i, msg, result)
+			}
+		}
+	}
+
+	t.Logf("session: %d bytes, with admin correlation: %d bytes", sizes[false], sizes[true])
+	if sizes[true] >= sizes[false] {
+		t.Errorf("admin correlation %d bytes, want less than %d bytes", sizes[true], sizes[false])
+	}
+}
+
+func TestAdminCorrelationKeyframes(t *testing.T) {
+	// Every frame is a keyframe, so no request is ever outstanding
+	opts := SessionOptions{KeyframeInterval: 1, AdminCorrelation: true}
+	enc := NewSessionEncoderWithOptions(opts)
+	dec := NewSessionDecoderWithOptions(opts)
+	for i, msg := range adminSession() {
+		frame, err := enc.Encode(msg)
+		if err != nil {
+			t.Fatalf("message %d: Encode failed: %v", i, err)
+		}
+		result := &meshtastic.AdminMessage{}
+		if err := dec.Decode(frame, result); err != nil {
+			t.Fatalf("message %d: Decode failed: %v", i, err)
+		}
+		if !proto.Equal(msg, result) {
+			t.Fatalf("message %d: roundtrip mismatch", i)
+		}
+	}
+}
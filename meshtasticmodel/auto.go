@@ -0,0 +1,77 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// No version is the smallest for every message: the specialized versions
+// win on the packets they model, while the baseline wins on small messages
+// with few fields. CompressAuto compresses a message with every candidate
+// and keeps the smallest output, after a byte with the WireID of the
+// version, so DecompressAuto knows which version to decode with. The tag
+// costs a byte instead of the HeaderSize bytes of a format header, but
+// offers no protection against data that is not compressed at all.
+//
+// Meshtastic packets fit a LoRa frame of a few hundred bytes, so compressing
+// them once per version is cheap enough that sizes are measured exactly
+// instead of estimated.
+
+// CompressAuto compresses msg with every version of candidates, or with
+// the Stateless versions of Versions when none are given, and writes the
+// smallest output after its tag. Versions that fail to compress msg are
+// skipped. It returns the version used.
+func CompressAuto(msg proto.Message, w io.Writer, candidates ...Version) (Version, error) {
+	if len(candidates) == 0 {
+		candidates = VersionsWith(Stateless)
+	}
+
+	var best, buf bytes.Buffer
+	var bestVersion Version
+	var errs []error
+	found := false
+	for _, v := range candidates {
+		buf.Reset()
+		if err := v.Compress(msg, &buf, WithoutHeader()); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", v.Name, err))
+			continue
+		}
+		if !found || buf.Len() < best.Len() {
+			best.Reset()
+			_, _ = best.Write(buf.Bytes())
+			bestVersion, found = v, true
+		}
+	}
+	if !found {
+		return Version{}, fmt.Errorf("meshtasticmodel: no version compressed the message: %w", errors.Join(errs...))
+	}
+
+	if _, err := w.Write([]byte{byte(bestVersion.ID)}); err != nil {
+		return Version{}, err
+	}
+	if _, err := w.Write(best.Bytes()); err != nil {
+		return Version{}, err
+	}
+	return bestVersion, nil
+}
+
+// DecompressAuto decompresses data written by CompressAuto into msg and
+// returns the version it was compressed with.
+func DecompressAuto(r io.Reader, msg proto.Message) (Version, error) {
+	var tag [1]byte
+	if _, err := io.ReadFull(r, tag[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Version{}, io.ErrUnexpectedEOF
+		}
+		return Version{}, err
+	}
+	v, ok := VersionByID(WireID(tag[0]))
+	if !ok {
+		return Version{}, fmt.Errorf("meshtasticmodel: unknown version %#x in strategy tag", tag[0])
+	}
+	return v, v.Decompress(r, msg, WithoutHeader())
+}
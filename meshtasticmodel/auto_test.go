@@ -0,0 +1,70 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestCompressAuto(t *testing.T) {
+	chosen := map[string]int{}
+	for i, msg := range meshfixtures.All() {
+		var buf bytes.Buffer
+		v, err := CompressAuto(msg, &buf)
+		if err != nil {
+			t.Fatalf("fixture %d: CompressAuto failed: %v", i, err)
+		}
+		chosen[v.Name]++
+
+		// The output is the smallest of all versions, plus the tag
+		for _, other := range VersionsWith(Stateless) {
+			var out bytes.Buffer
+			if err := other.Compress(msg, &out); err != nil {
+				continue
+			}
+			if out.Len()+1 < buf.Len() {
+				t.Errorf("fixture %d: %s chosen with %d bytes, %s has %d bytes", i, v.Name, buf.Len()-1, other.Name, out.Len())
+			}
+		}
+
+		decoded := msg.ProtoReflect().New().Interface()
+		got, err := DecompressAuto(bytes.NewReader(buf.Bytes()), decoded)
+		if err != nil {
+			t.Fatalf("fixture %d: DecompressAuto failed: %v", i, err)
+		}
+		if got.ID != v.ID {
+			t.Errorf("fixture %d: decoded with %s, compressed with %s", i, got.Name, v.Name)
+		}
+		if !proto.Equal(msg, decoded) {
+			t.Errorf("fixture %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", i, msg, decoded)
+		}
+	}
+	t.Logf("chosen versions: %v", chosen)
+}
+
+func TestCompressAutoCandidates(t *testing.T) {
+	baseline, _ := VersionByID(WirePbmodel)
+	msg := meshfixtures.Position(meshfixtures.Default)
+
+	var buf bytes.Buffer
+	v, err := CompressAuto(msg, &buf, baseline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ID != WirePbmodel || buf.Bytes()[0] != byte(WirePbmodel) {
+		t.Errorf("got %s with tag %#x, want the only candidate", v.Name, buf.Bytes()[0])
+	}
+}
+
+func TestDecompressAutoErrors(t *testing.T) {
+	if _, err := DecompressAuto(bytes.NewReader(nil), &meshtastic.Position{}); err == nil {
+		t.Error("DecompressAuto of empty data succeeded")
+	}
+	if _, err := DecompressAuto(bytes.NewReader([]byte{0xff, 0}), &meshtastic.Position{}); err == nil {
+		t.Error("DecompressAuto with an unknown tag succeeded")
+	}
+}
@@ -0,0 +1,176 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// BenchmarkSchema is the version of the format of BenchmarkResults. It
+// changes when fields are removed or change meaning, not when fields are
+// added.
+const BenchmarkSchema = 1
+
+// BenchmarkResults are the results of compressing a corpus with a set of
+// versions, in a form that can be shared and compared with results from
+// other machines. Sizes only depend on the corpus, speeds also depend on the
+// machine.
+type BenchmarkResults struct {
+	Schema   int            `json:"schema"`
+	Platform string         `json:"platform"` // GOOS/GOARCH and the Go version
+	Corpus   CorpusInfo     `json:"corpus"`
+	Codecs   []CodecResults `json:"codecs"`
+}
+
+// CorpusInfo identifies the corpus of benchmark results. Results are
+// comparable when the hashes of their corpora match.
+type CorpusInfo struct {
+	Name     string `json:"name"`
+	Hash     string `json:"hash"` // See CorpusHash
+	Messages int    `json:"messages"`
+	Bytes    int64  `json:"bytes"` // Size in the protobuf wire format
+}
+
+// CodecResults are the results of a version on the corpus.
+type CodecResults struct {
+	Codec           string  `json:"codec"` // Version.Name
+	WireID          WireID  `json:"wire_id"`
+	CompressedBytes int64   `json:"compressed_bytes"`
+	Ratio           float64 `json:"ratio"`           // Compressed size relative to the wire format
+	CompressMBps    float64 `json:"compress_mbps"`   // Megabytes of wire format per second
+	DecompressMBps  float64 `json:"decompress_mbps"` // Megabytes of wire format per second
+}
+
+// CorpusHash returns the SHA-256 of the messages, as the concatenation of
+// their deterministic length-delimited wire format.
+func CorpusHash(corpus []proto.Message) (string, error) {
+	h := sha256.New()
+	opts := protodelim.MarshalOptions{MarshalOptions: proto.MarshalOptions{Deterministic: true}}
+	for i, msg := range corpus {
+		if _, err := opts.MarshalTo(h, msg); err != nil {
+			return "", fmt.Errorf("message %d: %w", i, err)
+		}
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// RunBenchmark compresses and decompresses the corpus with every version,
+// repeating each for at least minDuration to measure its speed. It fails
+// when a version does not roundtrip the corpus.
+func RunBenchmark(name string, corpus []proto.Message, versions []Version, minDuration time.Duration) (*BenchmarkResults, error) {
+	hash, err := CorpusHash(corpus)
+	if err != nil {
+		return nil, err
+	}
+	results := &BenchmarkResults{
+		Schema:   BenchmarkSchema,
+		Platform: fmt.Sprintf("%s/%s, %s", runtime.GOOS, runtime.GOARCH, runtime.Version()),
+		Corpus:   CorpusInfo{Name: name, Hash: hash, Messages: len(corpus)},
+	}
+	for _, msg := range corpus {
+		results.Corpus.Bytes += int64(proto.Size(msg))
+	}
+
+	for _, version := range versions {
+		codec, err := benchmarkVersion(version, corpus, results.Corpus.Bytes, minDuration)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", version.Name, err)
+		}
+		results.Codecs = append(results.Codecs, codec)
+	}
+	return results, nil
+}
+
+// benchmarkVersion measures a version on the corpus of size bytes. The
+// sizes are the ones of the coding, without the format header.
+func benchmarkVersion(version Version, corpus []proto.Message, size int64, minDuration time.Duration) (CodecResults, error) {
+	codec := CodecResults{Codec: version.Name, WireID: version.ID}
+
+	compressed := make([][]byte, len(corpus))
+	compress := func() error {
+		for i, msg := range corpus {
+			var buf bytes.Buffer
+			if err := version.Compress(msg, &buf, WithoutHeader()); err != nil {
+				return fmt.Errorf("message %d: %w", i, err)
+			}
+			compressed[i] = buf.Bytes()
+		}
+		return nil
+	}
+	decompress := func() error {
+		for i, data := range compressed {
+			msg := corpus[i].ProtoReflect().New().Interface()
+			if err := version.Decompress(bytes.NewReader(data), msg, WithoutHeader()); err != nil {
+				return fmt.Errorf("message %d: %w", i, err)
+			}
+			if !proto.Equal(corpus[i], msg) {
+				return fmt.Errorf("message %d: roundtrip mismatch", i)
+			}
+		}
+		return nil
+	}
+
+	var err error
+	if codec.CompressMBps, err = measureThroughput(compress, size, minDuration); err != nil {
+		return codec, err
+	}
+	for _, data := range compressed {
+		codec.CompressedBytes += int64(len(data))
+	}
+	if size > 0 {
+		codec.Ratio = float64(codec.CompressedBytes) / float64(size)
+	}
+	if codec.DecompressMBps, err = measureThroughput(decompress, size, minDuration); err != nil {
+		return codec, err
+	}
+	return codec, nil
+}
+
+// measureThroughput runs f, which processes size bytes, until minDuration
+// has passed and returns the megabytes processed per second.
+func measureThroughput(f func() error, size int64, minDuration time.Duration) (float64, error) {
+	start := time.Now()
+	var runs int64
+	for {
+		if err := f(); err != nil {
+			return 0, err
+		}
+		runs++
+		if elapsed := time.Since(start); elapsed >= minDuration {
+			if elapsed <= 0 {
+				return 0, nil
+			}
+			return float64(size*runs) / 1e6 / elapsed.Seconds(), nil
+		}
+	}
+}
+
+// WriteTo writes the results as indented JSON.
+func (r *BenchmarkResults) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// ReadBenchmarkResults reads results written by BenchmarkResults.WriteTo.
+func ReadBenchmarkResults(r io.Reader) (*BenchmarkResults, error) {
+	var results BenchmarkResults
+	if err := json.NewDecoder(r).Decode(&results); err != nil {
+		return nil, fmt.Errorf("reading benchmark results: %w", err)
+	}
+	if results.Schema != BenchmarkSchema {
+		return nil, fmt.Errorf("unsupported benchmark results schema %d", results.Schema)
+	}
+	return &results, nil
+}
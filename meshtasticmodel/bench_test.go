@@ -0,0 +1,70 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestRunBenchmark(t *testing.T) {
+	corpus := meshfixtures.All()
+	var versions []Version
+	for _, name := range []string{"pbmodel", "V14"} {
+		version, ok := FindVersion(name)
+		if !ok {
+			t.Fatalf("unknown version %s", name)
+		}
+		versions = append(versions, version)
+	}
+
+	results, err := RunBenchmark("fixtures", corpus, versions, 0)
+	if err != nil {
+		t.Fatalf("RunBenchmark failed: %v", err)
+	}
+	if results.Corpus.Messages != len(corpus) || results.Corpus.Bytes == 0 {
+		t.Errorf("corpus %+v, want %d messages", results.Corpus, len(corpus))
+	}
+	if len(results.Codecs) != len(versions) {
+		t.Fatalf("got %d codecs, want %d", len(results.Codecs), len(versions))
+	}
+	for _, codec := range results.Codecs {
+		t.Logf("%s: %d bytes, ratio %.3f", codec.Codec, codec.CompressedBytes, codec.Ratio)
+		if codec.Ratio <= 0 || codec.Ratio >= 1 || codec.CompressMBps <= 0 || codec.DecompressMBps <= 0 {
+			t.Errorf("%s: implausible results %+v", codec.Codec, codec)
+		}
+	}
+
+	// The hash identifies the corpus
+	again, err := CorpusHash(meshfixtures.All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != results.Corpus.Hash {
+		t.Errorf("hash of the same corpus changed: %s, %s", results.Corpus.Hash, again)
+	}
+	other, err := CorpusHash(append(meshfixtures.All(), &meshtastic.Position{Time: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == results.Corpus.Hash {
+		t.Errorf("different corpora have the same hash")
+	}
+
+	var buf bytes.Buffer
+	if _, err := results.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadBenchmarkResults(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Corpus != results.Corpus || len(loaded.Codecs) != len(results.Codecs) || loaded.Codecs[1] != results.Codecs[1] {
+		t.Errorf("loaded results differ:\n%+v\n%+v", loaded, results)
+	}
+
+	if _, err := ReadBenchmarkResults(bytes.NewBufferString(`{"schema": 99}`)); err == nil {
+		t.Error("expected an error for an unsupported schema")
+	}
+}
@@ -0,0 +1,81 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+// BenchmarkDecompressMeshPacket benchmarks decompressing typical packets with
+// the static-model versions.
+func BenchmarkDecompressMeshPacket(b *testing.B) {
+	s := meshfixtures.Scenarios()[0]
+	packets := []*meshtastic.MeshPacket{
+		meshfixtures.PositionPacket(s),
+		meshfixtures.TelemetryPacket(s),
+		meshfixtures.TextPacket(s, meshfixtures.TextMessages[0]),
+	}
+
+	for _, name := range []string{"pbmodel", "V4", "V10", "V13", "V14"} {
+		version, ok := FindVersion(name)
+		if !ok {
+			b.Fatalf("unknown version %s", name)
+		}
+
+		var compressed [][]byte
+		var size int
+		for _, packet := range packets {
+			var buf bytes.Buffer
+			if err := version.Compress(packet, &buf); err != nil {
+				b.Fatal(err)
+			}
+			compressed = append(compressed, buf.Bytes())
+			size += buf.Len()
+		}
+
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, data := range compressed {
+					if err := version.Decompress(bytes.NewReader(data), &meshtastic.MeshPacket{}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCompressMeshPacket benchmarks compressing typical packets. V13
+// and V14 reuse pooled model builders, so they allocate little per packet.
+func BenchmarkCompressMeshPacket(b *testing.B) {
+	s := meshfixtures.Scenarios()[0]
+	packets := []*meshtastic.MeshPacket{
+		meshfixtures.PositionPacket(s),
+		meshfixtures.TelemetryPacket(s),
+		meshfixtures.TextPacket(s, meshfixtures.TextMessages[0]),
+	}
+
+	for _, name := range []string{"pbmodel", "V4", "V10", "V13", "V14"} {
+		version, ok := FindVersion(name)
+		if !ok {
+			b.Fatalf("unknown version %s", name)
+		}
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			var buf bytes.Buffer
+			for i := 0; i < b.N; i++ {
+				for _, packet := range packets {
+					buf.Reset()
+					if err := version.Compress(packet, &buf); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
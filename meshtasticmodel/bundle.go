@@ -0,0 +1,435 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+// A phone that connects to a node receives the node database and the queued
+// packets as a batch of FromRadio messages, and every reconnect repeats the
+// node IDs and names of the previous batch. Bundle encoders keep dictionaries
+// of the node IDs and short strings of the bundles they coded, and code a
+// value seen before as its index in the dictionary.
+//
+// A bundle that refers to the dictionary decodes only after the bundles
+// that built it. A snapshot bundle embeds the entries it refers to in its
+// header, so that it decodes without prior state, for example after a lost
+// bundle or on a new listener, at the cost of coding those entries in full.
+// Every bundle codes its messages with new V14 models; only the dictionaries
+// carry over.
+
+// ErrMissingDictionary is returned by BundleDecoder.Decode for bundles that
+// refer to dictionary entries the decoder does not have, because it missed
+// earlier bundles. Snapshot bundles still decode.
+var ErrMissingDictionary = errors.New("meshtasticmodel: bundle refers to a missing dictionary")
+
+// MaxBundleMessages is the largest number of messages of a bundle.
+const MaxBundleMessages = 1 << 12
+
+const (
+	// maxDictionaryEntries limits the entries of each dictionary, later
+	// values are coded in full.
+	maxDictionaryEntries = 1 << 12
+	// maxDictionaryStringLen is the longest string added to the dictionary,
+	// longer strings such as messages rarely repeat.
+	maxDictionaryStringLen = 64
+)
+
+// dictionaryNodeIDs are the fields that hold node IDs.
+var dictionaryNodeIDs = map[FieldKey]bool{
+	{"meshtastic.Data", 4}:       true, // dest
+	{"meshtastic.Data", 5}:       true, // source
+	{"meshtastic.MeshPacket", 1}: true, // from
+	{"meshtastic.MeshPacket", 2}: true, // to
+	{"meshtastic.MyNodeInfo", 1}: true, // my_node_num
+	{"meshtastic.NodeInfo", 1}:   true, // num
+}
+
+// dictionary holds the node IDs and strings of the coded bundles.
+type dictionary struct {
+	nodeIDs dictionaryTable
+	strings dictionaryTable
+}
+
+// dictionaryTable holds values in the order they were first coded.
+type dictionaryTable struct {
+	entries []any
+	index   map[any]int
+	base    int // Entries the bundle being coded started from
+}
+
+// add appends value unless the table has it, is full or value is a long
+// string.
+func (t *dictionaryTable) add(value any) {
+	if s, ok := value.(string); ok && len(s) > maxDictionaryStringLen {
+		return
+	}
+	if _, ok := t.index[value]; ok || len(t.entries) >= maxDictionaryEntries {
+		return
+	}
+	if t.index == nil {
+		t.index = make(map[any]int)
+	}
+	t.index[value] = len(t.entries)
+	t.entries = append(t.entries, value)
+}
+
+// branch returns a table for coding a bundle that starts from entries.
+func branch(entries []any) dictionaryTable {
+	var t dictionaryTable
+	for _, value := range entries {
+		t.add(value)
+	}
+	t.base = len(t.entries)
+	return t
+}
+
+// merge adds the values that the bundle coded with working added.
+func (t *dictionaryTable) merge(working *dictionaryTable) {
+	for _, value := range working.entries[working.base:] {
+		t.add(value)
+	}
+}
+
+// tableOf returns the table of the values of fd, nil for other fields.
+func (d *dictionary) tableOf(fd protoreflect.FieldDescriptor) *dictionaryTable {
+	switch {
+	case fd.Kind() == protoreflect.StringKind:
+		return &d.strings
+	case dictionaryNodeIDs[FieldKeyOf(fd)]:
+		return &d.nodeIDs
+	}
+	return nil
+}
+
+// encodeDictionaryValue encodes value as its index in table, or in full when
+// table does not have it yet.
+func encodeDictionaryValue(table *dictionaryTable, fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	index, known := table.index[value.Interface()]
+	if err := models.EncodeEscaped(enc, boolSymbol(known), mcb.GetBooleanModel(string(fd.Name())+"_in_dictionary")); err != nil {
+		return err
+	}
+	if known {
+		return enc.Encode(index, models.SharedUniformModel(len(table.entries)))
+	}
+	if err := compressFieldLiteralV10(fieldPath, fd, value, enc, mcb); err != nil {
+		return err
+	}
+	table.add(value.Interface())
+	return nil
+}
+
+// decodeDictionaryValue decodes a value written by encodeDictionaryValue.
+func decodeDictionaryValue(table *dictionaryTable, fieldPath string, fd protoreflect.FieldDescriptor, dec *coder.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
+	known, err := models.DecodeEscaped(dec, mcb.GetBooleanModel(string(fd.Name())+"_in_dictionary"))
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	if known == 1 {
+		if len(table.entries) == 0 {
+			return protoreflect.Value{}, errors.New("dictionary reference to an empty dictionary")
+		}
+		index, err := dec.Decode(models.SharedUniformModel(len(table.entries)))
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOf(table.entries[index]), nil
+	}
+	value, err := decompressFieldLiteralV10(fieldPath, fd, dec, mcb)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	table.add(value.Interface())
+	return value, nil
+}
+
+// BundleEncoder compresses bundles of FromRadio messages that share
+// dictionaries of node IDs and strings, see BundleDecoder.
+type BundleEncoder struct {
+	dictionary dictionary
+}
+
+// NewBundleEncoder creates a bundle encoder with empty dictionaries.
+func NewBundleEncoder() *BundleEncoder {
+	return &BundleEncoder{}
+}
+
+// Encode compresses msgs into a bundle that refers to the dictionaries of
+// the earlier bundles.
+func (b *BundleEncoder) Encode(msgs []*meshtastic.FromRadio) ([]byte, error) {
+	return b.encode(msgs, false)
+}
+
+// EncodeSnapshot compresses msgs into a bundle that embeds the dictionary
+// entries it refers to, so that it decodes without the earlier bundles.
+func (b *BundleEncoder) EncodeSnapshot(msgs []*meshtastic.FromRadio) ([]byte, error) {
+	return b.encode(msgs, true)
+}
+
+func (b *BundleEncoder) encode(msgs []*meshtastic.FromRadio, snapshot bool) ([]byte, error) {
+	if len(msgs) > MaxBundleMessages {
+		return nil, fmt.Errorf("%d messages exceed the maximum of %d", len(msgs), MaxBundleMessages)
+	}
+
+	working := &dictionary{
+		nodeIDs: branch(b.dictionary.nodeIDs.entries),
+		strings: branch(b.dictionary.strings.entries),
+	}
+	if snapshot {
+		working = b.dictionary.referencedBy(msgs)
+	}
+
+	var buf bytes.Buffer
+	enc := coder.NewEncoder(&buf)
+	mcb := newModelBuilderV14()
+	if err := b.dictionary.encodeHeader(len(msgs), working, snapshot, enc, mcb); err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+
+	mcb.dictionary = working
+	for i, msg := range msgs {
+		mcb.currentPortNum = nil
+		mcb.SetMessageType(string(msg.ProtoReflect().Descriptor().Name()))
+		if err := compressMessageV10("", msg.ProtoReflect(), enc, mcb); err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	b.dictionary.nodeIDs.merge(&working.nodeIDs)
+	b.dictionary.strings.merge(&working.strings)
+	return buf.Bytes(), nil
+}
+
+// referencedBy returns the tables of a snapshot bundle of msgs: the entries
+// of d that msgs have.
+func (d *dictionary) referencedBy(msgs []*meshtastic.FromRadio) *dictionary {
+	refs := make(map[*dictionaryTable][]int)
+	for _, msg := range msgs {
+		d.rangeValues(msg.ProtoReflect(), func(table *dictionaryTable, value protoreflect.Value) {
+			if index, ok := table.index[value.Interface()]; ok {
+				refs[table] = append(refs[table], index)
+			}
+		})
+	}
+
+	snapshot := func(table *dictionaryTable) dictionaryTable {
+		indices := refs[table]
+		slices.Sort(indices)
+		entries := make([]any, 0, len(indices))
+		for _, index := range slices.Compact(indices) {
+			entries = append(entries, table.entries[index])
+		}
+		return branch(entries)
+	}
+	return &dictionary{
+		nodeIDs: snapshot(&d.nodeIDs),
+		strings: snapshot(&d.strings),
+	}
+}
+
+// rangeValues calls fn with the values of msg and of its nested messages
+// that are coded with a table of d.
+func (d *dictionary) rangeValues(msg protoreflect.Message, fn func(*dictionaryTable, protoreflect.Value)) {
+	visit := func(fd protoreflect.FieldDescriptor, value protoreflect.Value) {
+		if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+			d.rangeValues(value.Message(), fn)
+		} else if table := d.tableOf(fd); table != nil {
+			fn(table, value)
+		}
+	}
+	msg.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch {
+		case fd.IsList():
+			list := value.List()
+			for i := range list.Len() {
+				visit(fd, list.Get(i))
+			}
+		case fd.IsMap():
+			value.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+				visit(fd.MapKey(), key.Value())
+				visit(fd.MapValue(), value)
+				return true
+			})
+		default:
+			visit(fd, value)
+		}
+		return true
+	})
+}
+
+// encodeHeader encodes the header of a bundle of count messages: the size
+// of d, which the decoder verifies, and the entries of a snapshot bundle.
+func (d *dictionary) encodeHeader(count int, working *dictionary, snapshot bool, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	for _, n := range []int{count, len(d.nodeIDs.entries), len(d.strings.entries)} {
+		if err := encodeVarintWithModels(uint64(n), enc, mcb); err != nil {
+			return err
+		}
+	}
+	if err := models.EncodeEscaped(enc, boolSymbol(snapshot), mcb.GetBooleanModel("bundle_snapshot")); err != nil {
+		return err
+	}
+	if !snapshot {
+		return nil
+	}
+
+	if err := encodeVarintWithModels(uint64(len(working.nodeIDs.entries)), enc, mcb); err != nil {
+		return err
+	}
+	for _, id := range working.nodeIDs.entries {
+		if err := encodeNodeID(id.(uint32), enc); err != nil {
+			return err
+		}
+	}
+	if err := encodeVarintWithModels(uint64(len(working.strings.entries)), enc, mcb); err != nil {
+		return err
+	}
+	for _, s := range working.strings.entries {
+		if err := mcb.encodeText(enc, s.(string)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeNodeID encodes a node ID as four uniform bytes.
+func encodeNodeID(id uint32, enc *coder.Encoder) error {
+	for shift := 24; shift >= 0; shift -= 8 {
+		if err := enc.Encode(int(id>>shift)&0xff, models.SharedUniformModel(256)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeNodeID decodes a node ID written by encodeNodeID.
+func decodeNodeID(dec *coder.Decoder) (uint32, error) {
+	var id uint32
+	for range 4 {
+		b, err := dec.Decode(models.SharedUniformModel(256))
+		if err != nil {
+			return 0, err
+		}
+		id = id<<8 | uint32(b)
+	}
+	return id, nil
+}
+
+// BundleDecoder decompresses bundles created by a BundleEncoder. It decodes
+// the bundles that refer to the dictionaries in the order they were
+// encoded, and snapshot bundles in any order.
+type BundleDecoder struct {
+	dictionary dictionary
+}
+
+// NewBundleDecoder creates a bundle decoder with empty dictionaries.
+func NewBundleDecoder() *BundleDecoder {
+	return &BundleDecoder{}
+}
+
+// Decode decompresses a bundle. It returns ErrMissingDictionary for a bundle
+// that refers to dictionary entries of bundles the decoder has not decoded.
+// A snapshot bundle decodes regardless, but extends the dictionaries only
+// when the decoder has all the earlier entries.
+func (b *BundleDecoder) Decode(bundle []byte) ([]*meshtastic.FromRadio, error) {
+	dec, err := coder.NewDecoder(bytes.NewReader(bundle))
+	if err != nil {
+		return nil, err
+	}
+	mcb := newModelBuilderV14()
+
+	var sizes [3]uint64
+	for i := range sizes {
+		if sizes[i], err = decodeVarintWithModels(dec, mcb); err != nil {
+			return nil, fmt.Errorf("header: %w", err)
+		}
+	}
+	count := sizes[0]
+	if count > MaxBundleMessages {
+		return nil, fmt.Errorf("%d messages exceed the maximum of %d", count, MaxBundleMessages)
+	}
+	complete := sizes[1] == uint64(len(b.dictionary.nodeIDs.entries)) && sizes[2] == uint64(len(b.dictionary.strings.entries))
+
+	snapshot, err := models.DecodeEscaped(dec, mcb.GetBooleanModel("bundle_snapshot"))
+	if err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	var working *dictionary
+	if snapshot == 1 {
+		if working, err = decodeSnapshot(dec, mcb); err != nil {
+			return nil, fmt.Errorf("header: %w", err)
+		}
+	} else {
+		if !complete {
+			return nil, ErrMissingDictionary
+		}
+		working = &dictionary{
+			nodeIDs: branch(b.dictionary.nodeIDs.entries),
+			strings: branch(b.dictionary.strings.entries),
+		}
+	}
+
+	mcb.dictionary = working
+	msgs := make([]*meshtastic.FromRadio, count)
+	for i := range msgs {
+		msg := &meshtastic.FromRadio{}
+		mcb.currentPortNum = nil
+		mcb.SetMessageType(string(msg.ProtoReflect().Descriptor().Name()))
+		if err := decompressMessageV10("", msg.ProtoReflect(), dec, mcb); err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		msgs[i] = msg
+	}
+
+	if complete {
+		b.dictionary.nodeIDs.merge(&working.nodeIDs)
+		b.dictionary.strings.merge(&working.strings)
+	}
+	return msgs, nil
+}
+
+// decodeSnapshot decodes the dictionary entries of a snapshot bundle.
+func decodeSnapshot(dec *coder.Decoder, mcb *ContextualModelBuilder) (*dictionary, error) {
+	count, err := decodeVarintWithModels(dec, mcb)
+	if err != nil {
+		return nil, err
+	}
+	if count > maxDictionaryEntries {
+		return nil, fmt.Errorf("%d node IDs exceed the maximum of %d", count, maxDictionaryEntries)
+	}
+	nodeIDs := make([]any, count)
+	for i := range nodeIDs {
+		if nodeIDs[i], err = decodeNodeID(dec); err != nil {
+			return nil, err
+		}
+	}
+
+	if count, err = decodeVarintWithModels(dec, mcb); err != nil {
+		return nil, err
+	}
+	if count > maxDictionaryEntries {
+		return nil, fmt.Errorf("%d strings exceed the maximum of %d", count, maxDictionaryEntries)
+	}
+	strings := make([]any, count)
+	for i := range strings {
+		s, err := mcb.decodeText(dec)
+		if err != nil {
+			return nil, err
+		}
+		if len(s) > maxDictionaryStringLen {
+			return nil, fmt.Errorf("string of %d bytes exceeds the maximum of %d", len(s), maxDictionaryStringLen)
+		}
+		strings[i] = s
+	}
+	return &dictionary{nodeIDs: branch(nodeIDs), strings: branch(strings)}, nil
+}
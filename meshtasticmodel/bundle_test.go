@@ -0,0 +1,111 @@
+package meshtasticmodel
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+// connectBundle returns what a node sends a phone on the connect after
+// minutes: its own node info, the node database and a position packet of
+// every node.
+func connectBundle(minutes uint32) []*meshtastic.FromRadio {
+	msgs := []*meshtastic.FromRadio{{
+		PayloadVariant: &meshtastic.FromRadio_MyInfo{MyInfo: &meshtastic.MyNodeInfo{
+			MyNodeNum: meshfixtures.Default.NodeNum,
+		}},
+	}}
+	for _, s := range meshfixtures.Scenarios() {
+		node := meshfixtures.NodeInfo(s)
+		node.LastHeard += 60 * minutes
+		msgs = append(msgs, &meshtastic.FromRadio{
+			PayloadVariant: &meshtastic.FromRadio_NodeInfo{NodeInfo: node},
+		})
+	}
+	for _, s := range meshfixtures.Scenarios() {
+		msgs = append(msgs, &meshtastic.FromRadio{
+			PayloadVariant: &meshtastic.FromRadio_Packet{Packet: meshfixtures.PositionPacket(s)},
+		})
+	}
+	return msgs
+}
+
+func decodeBundle(t *testing.T, dec *BundleDecoder, bundle []byte, want []*meshtastic.FromRadio) {
+	t.Helper()
+	got, err := dec.Decode(bundle)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d messages, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !proto.Equal(got[i], want[i]) {
+			t.Errorf("message %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBundle(t *testing.T) {
+	enc, dec := NewBundleEncoder(), NewBundleDecoder()
+
+	var sizes []int
+	for minutes := range uint32(3) {
+		msgs := connectBundle(minutes * 15)
+		bundle, err := enc.Encode(msgs)
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		decodeBundle(t, dec, bundle, msgs)
+		sizes = append(sizes, len(bundle))
+	}
+	t.Logf("bundle sizes: %v", sizes)
+
+	// The later bundles refer to the node IDs and names of the first
+	if sizes[1] >= sizes[0] || sizes[2] >= sizes[0] {
+		t.Errorf("later bundles (%v) should be smaller than the first (%d)", sizes[1:], sizes[0])
+	}
+}
+
+func TestBundleSnapshot(t *testing.T) {
+	enc, dec := NewBundleEncoder(), NewBundleDecoder()
+	first := connectBundle(0)
+	bundle, err := enc.Encode(first)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	decodeBundle(t, dec, bundle, first)
+
+	// A decoder without the first bundle misses the dictionary
+	msgs := connectBundle(15)
+	plain, err := enc.Encode(msgs)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if _, err := NewBundleDecoder().Decode(plain); !errors.Is(err, ErrMissingDictionary) {
+		t.Errorf("Decode without prior state: got %v, want ErrMissingDictionary", err)
+	}
+	decodeBundle(t, dec, plain, msgs)
+
+	// A snapshot bundle decodes without prior state, and keeps the decoder
+	// that has it in sync
+	msgs = connectBundle(30)
+	snapshot, err := enc.EncodeSnapshot(msgs)
+	if err != nil {
+		t.Fatalf("EncodeSnapshot failed: %v", err)
+	}
+	t.Logf("plain bundle: %d bytes, snapshot bundle: %d bytes", len(plain), len(snapshot))
+	decodeBundle(t, NewBundleDecoder(), snapshot, msgs)
+	decodeBundle(t, dec, snapshot, msgs)
+
+	msgs = connectBundle(45)
+	bundle, err = enc.Encode(msgs)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	decodeBundle(t, dec, bundle, msgs)
+}
@@ -0,0 +1,184 @@
+package meshtasticmodel
+
+import (
+	"fmt"
+	"math/bits"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// Host metrics report free memory and disk space in bytes: values of a few
+// gigabytes up to terabytes, of which only the leading digits matter. V14
+// codes a byte count as its exponent, the bit length of the count, the
+// byteCountMantissaBits bits below the leading one, and the remaining low
+// bits only when they are not all zero. Counts are kept exact, but
+// RoundByteCounts can round them to the mantissa beforehand, so that they
+// cost about two bytes instead of five.
+
+// byteCountMantissaBits is the number of bits below the leading one that
+// are always coded, a precision of about 1%.
+const byteCountMantissaBits = 7
+
+// byteCountFields are the names of the HostMetrics byte count fields.
+var byteCountFields = map[protoreflect.Name]bool{
+	"freemem_bytes":   true,
+	"diskfree1_bytes": true,
+	"diskfree2_bytes": true,
+	"diskfree3_bytes": true,
+}
+
+// sharedByteCountExponentModel is the table of byte count exponents, which
+// are mostly between 256 MiB and 4 TiB.
+var sharedByteCountExponentModel = sync.OnceValue(func() *models.FrequencyTable {
+	freqs := make([]uint64, 65)
+	for n := range freqs {
+		switch {
+		case n >= 29 && n <= 42:
+			freqs[n] = 64
+		case n >= 20 && n <= 48:
+			freqs[n] = 8
+		default:
+			freqs[n] = 1
+		}
+	}
+	return models.NewFrequencyTable(freqs)
+})
+
+// isByteCount returns whether fd is a byte count and mcb codes byte counts
+// by exponent.
+func (mcb *ContextualModelBuilder) isByteCount(fd protoreflect.FieldDescriptor) bool {
+	return mcb.byteCounts && isByteCountField(fd)
+}
+
+// isByteCountField returns whether fd is a byte count of host metrics.
+func isByteCountField(fd protoreflect.FieldDescriptor) bool {
+	return fd.Kind() == protoreflect.Uint64Kind && !fd.IsList() &&
+		fd.ContainingMessage().Name() == "HostMetrics" && byteCountFields[fd.Name()]
+}
+
+// encodeByteCount encodes a byte count as its exponent and mantissa,
+// followed by the low bits when they are not zero.
+func encodeByteCount(count uint64, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	n := bits.Len64(count)
+	if err := enc.Encode(n, sharedByteCountExponentModel()); err != nil {
+		return err
+	}
+	if n <= byteCountMantissaBits+1 {
+		return encodeLowBits(count, n, enc)
+	}
+
+	low := n - 1 - byteCountMantissaBits
+	if err := encodeLowBits(count>>low, byteCountMantissaBits+1, enc); err != nil {
+		return err
+	}
+	rest := count & (1<<low - 1)
+	if err := models.EncodeEscaped(enc, boolSymbol(rest != 0), mcb.GetBooleanModel("byte_count_low_bits")); err != nil {
+		return err
+	}
+	if rest == 0 {
+		return nil
+	}
+	return encodeBits(rest, low, enc)
+}
+
+// decodeByteCount decodes a byte count written by encodeByteCount.
+func decodeByteCount(dec *coder.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
+	n, err := dec.Decode(sharedByteCountExponentModel())
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	if n > 64 {
+		return protoreflect.Value{}, fmt.Errorf("byte count bit length %d out of range", n)
+	}
+	if n <= byteCountMantissaBits+1 {
+		count, err := decodeLowBits(n, dec)
+		return protoreflect.ValueOfUint64(count), err
+	}
+
+	low := n - 1 - byteCountMantissaBits
+	mantissa, err := decodeLowBits(byteCountMantissaBits+1, dec)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	hasLow, err := models.DecodeEscaped(dec, mcb.GetBooleanModel("byte_count_low_bits"))
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	var rest uint64
+	if hasLow == 1 {
+		if rest, err = decodeBits(low, dec); err != nil {
+			return protoreflect.Value{}, err
+		}
+	}
+	return protoreflect.ValueOfUint64(mantissa<<low | rest), nil
+}
+
+// encodeBits encodes the n low bits of value, n < 64.
+func encodeBits(value uint64, n int, enc *coder.Encoder) error {
+	// With a leading one prefixed, the bits are the ones below the leading
+	// one of a value of bit length n+1
+	return encodeLowBits(1<<n|value, n+1, enc)
+}
+
+// decodeBits decodes n bits written by encodeBits.
+func decodeBits(n int, dec *coder.Decoder) (uint64, error) {
+	value, err := decodeLowBits(n+1, dec)
+	return value &^ (1 << n), err
+}
+
+// RoundByteCounts rounds the free memory and disk space byte counts of the
+// host metrics in msg to a precision of about 1%, which V14 codes in about
+// two bytes each instead of five. It does nothing for messages without
+// host metrics.
+func RoundByteCounts(msg proto.Message) {
+	roundByteCounts(msg.ProtoReflect())
+}
+
+// roundByteCounts rounds the byte counts in msg and its submessages.
+func roundByteCounts(msg protoreflect.Message) {
+	msg.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Kind() == protoreflect.MessageKind {
+				value.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+					roundByteCounts(v.Message())
+					return true
+				})
+			}
+		case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+			if fd.IsList() {
+				list := value.List()
+				for i := 0; i < list.Len(); i++ {
+					roundByteCounts(list.Get(i).Message())
+				}
+			} else {
+				roundByteCounts(value.Message())
+			}
+		case isByteCountField(fd):
+			msg.Set(fd, protoreflect.ValueOfUint64(roundByteCount(value.Uint())))
+		}
+		return true
+	})
+}
+
+// roundByteCount rounds count to byteCountMantissaBits bits below the
+// leading one, to the nearest value.
+func roundByteCount(count uint64) uint64 {
+	n := bits.Len64(count)
+	if n <= byteCountMantissaBits+1 {
+		return count
+	}
+	low := n - 1 - byteCountMantissaBits
+	half := uint64(1) << (low - 1)
+	if count > ^uint64(0)-half {
+		// Rounding up would overflow
+		return count &^ (1<<low - 1)
+	}
+	// A carry into a longer count leaves its low bits zero as well
+	return (count + half) &^ (1<<low - 1)
+}
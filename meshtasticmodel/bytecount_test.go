@@ -0,0 +1,92 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestMeshtasticV14ByteCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics *meshtastic.HostMetrics
+		round   bool
+	}{
+		{"exact", &meshtastic.HostMetrics{
+			UptimeSeconds:  86400,
+			FreememBytes:   3_221_456_896,
+			Diskfree1Bytes: 48_318_382_080,
+			Diskfree2Bytes: proto.Uint64(1_099_511_627_776),
+			Load1:          35,
+		}, false},
+		{"rounded", &meshtastic.HostMetrics{
+			UptimeSeconds:  86400,
+			FreememBytes:   3_221_456_896,
+			Diskfree1Bytes: 48_318_382_080,
+			Diskfree2Bytes: proto.Uint64(1_099_511_627_776),
+			Load1:          35,
+		}, true},
+		{"extremes", &meshtastic.HostMetrics{
+			FreememBytes:   1,
+			Diskfree1Bytes: 255,
+			Diskfree2Bytes: proto.Uint64(0),
+			Diskfree3Bytes: proto.Uint64(math.MaxUint64),
+		}, false},
+	}
+	for _, test := range tests {
+		telemetry := &meshtastic.Telemetry{
+			Time:    1703520000,
+			Variant: &meshtastic.Telemetry_HostMetrics{HostMetrics: test.metrics},
+		}
+		if test.round {
+			RoundByteCounts(telemetry)
+		}
+
+		var bufV13, bufV14 bytes.Buffer
+		if err := CompressV13(telemetry, &bufV13); err != nil {
+			t.Fatalf("%s: V13 compress failed: %v", test.name, err)
+		}
+		if err := CompressV14(telemetry, &bufV14); err != nil {
+			t.Fatalf("%s: V14 compress failed: %v", test.name, err)
+		}
+		t.Logf("%s: V13: %d bytes, V14: %d bytes", test.name, bufV13.Len(), bufV14.Len())
+		if bufV14.Len() > bufV13.Len() {
+			t.Errorf("%s: V14 (%d bytes) should not be larger than V13 (%d bytes)", test.name, bufV14.Len(), bufV13.Len())
+		}
+		if test.round && bufV14.Len()+6 > bufV13.Len() {
+			t.Errorf("%s: V14 (%d bytes) should be at least 6 bytes smaller than V13 (%d bytes)", test.name, bufV14.Len(), bufV13.Len())
+		}
+
+		result := &meshtastic.Telemetry{}
+		if err := DecompressV14(&bufV14, result); err != nil {
+			t.Fatalf("%s: V14 decompress failed: %v", test.name, err)
+		}
+		if !proto.Equal(telemetry, result) {
+			t.Errorf("%s: V14 roundtrip verification failed\noriginal: %v\ndecoded:  %v", test.name, telemetry, result)
+		}
+	}
+}
+
+func TestRoundByteCounts(t *testing.T) {
+	tests := []struct{ count, want uint64 }{
+		{0, 0},
+		{255, 255},
+		{256, 256},
+		{257, 258},
+		{3_221_456_896, 3_221_225_472},
+		{1<<40 - 1, 1 << 40},
+		{math.MaxUint64, math.MaxUint64 &^ (1<<56 - 1)},
+	}
+	for _, test := range tests {
+		if got := roundByteCount(test.count); got != test.want {
+			t.Errorf("roundByteCount(%d) = %d, want %d", test.count, got, test.want)
+		}
+		if got := roundByteCount(test.want); got != test.want {
+			t.Errorf("roundByteCount(%d) = %d, want it unchanged", test.want, got)
+		}
+	}
+}
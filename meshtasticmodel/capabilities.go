@@ -0,0 +1,63 @@
+package meshtasticmodel
+
+import "strings"
+
+// Capabilities is a set of features supported by a compression version.
+type Capabilities uint32
+
+const (
+	// Stateless versions compress every message on its own, so messages
+	// can be decoded independently and in any order.
+	Stateless Capabilities = 1 << iota
+	// LossyCapable versions can trade precision of values for size.
+	LossyCapable
+	// RandomAccess versions can decode a single field without decoding the
+	// fields before it.
+	RandomAccess
+	// SchemaEvolutionSafe versions can decode messages compressed with an
+	// older schema after fields or enum values were added.
+	SchemaEvolutionSafe
+	// EmbeddedFriendly versions only need small static model tables and no
+	// large adaptive state, which fits microcontroller memory. The pbmodel
+	// baseline can also code from a pbmodel.Codec without allocating per
+	// message.
+	EmbeddedFriendly
+)
+
+var capabilityNames = []string{
+	"stateless",
+	"lossy-capable",
+	"random-access",
+	"schema-evolution-safe",
+	"embedded-friendly",
+}
+
+// Has reports whether c includes all capabilities in want.
+func (c Capabilities) Has(want Capabilities) bool {
+	return c&want == want
+}
+
+func (c Capabilities) String() string {
+	var names []string
+	for i, name := range capabilityNames {
+		if c&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// VersionsWith returns the versions from Versions that have all capabilities
+// in want, in the same order.
+func VersionsWith(want Capabilities) []Version {
+	var versions []Version
+	for _, v := range Versions {
+		if v.Features.Has(want) {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
@@ -0,0 +1,73 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
+)
+
+func TestCapabilitiesString(t *testing.T) {
+	tests := []struct {
+		caps Capabilities
+		want string
+	}{
+		{0, "none"},
+		{Stateless, "stateless"},
+		{Stateless | EmbeddedFriendly, "stateless|embedded-friendly"},
+		{LossyCapable | RandomAccess | SchemaEvolutionSafe, "lossy-capable|random-access|schema-evolution-safe"},
+	}
+	for _, tt := range tests {
+		if got := tt.caps.String(); got != tt.want {
+			t.Errorf("Capabilities(%d).String() = %q, want %q", uint32(tt.caps), got, tt.want)
+		}
+	}
+}
+
+func TestVersionsWith(t *testing.T) {
+	if got := VersionsWith(Stateless); len(got) != len(Versions) {
+		t.Errorf("VersionsWith(Stateless) returned %d versions, want all %d", len(got), len(Versions))
+	}
+
+	embedded := VersionsWith(Stateless | EmbeddedFriendly)
+	if len(embedded) == 0 || len(embedded) == len(Versions) {
+		t.Fatalf("VersionsWith(Stateless|EmbeddedFriendly) returned %d of %d versions", len(embedded), len(Versions))
+	}
+	for _, v := range embedded {
+		if !v.Features.Has(EmbeddedFriendly) {
+			t.Errorf("%s: features %v missing embedded-friendly", v.Name, v.Features)
+		}
+	}
+
+	if got := VersionsWith(LossyCapable); len(got) != 0 {
+		t.Errorf("VersionsWith(LossyCapable) returned %d versions, want none", len(got))
+	}
+}
+
+func TestEmbeddedCodecAllocations(t *testing.T) {
+	s := meshfixtures.Scenarios()[0]
+	packets := []*meshtastic.MeshPacket{
+		meshfixtures.PositionPacket(s),
+		meshfixtures.TelemetryPacket(s),
+		meshfixtures.TextPacket(s, meshfixtures.TextMessages[0]),
+	}
+
+	// The pbmodel baseline can run from a codec allocated once at startup
+	codec := pbmodel.NewCodec()
+	var buf bytes.Buffer
+	buf.Grow(1024)
+	compress := func() {
+		for _, packet := range packets {
+			buf.Reset()
+			if err := codec.Compress(packet, &buf); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	compress()
+	if allocs := testing.AllocsPerRun(20, compress); allocs != 0 {
+		t.Errorf("compressing %d packets allocates %v times, want 0", len(packets), allocs)
+	}
+}
@@ -0,0 +1,117 @@
+//go:build integration
+
+package meshtasticmodel
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+// The captures test runs with
+//
+//	go test -tags integration -run Captures ./...
+//
+// on packets captured from real nodes, which catches schema drift that the
+// fixtures cannot: fields and enum values added by newer firmware, or values
+// the fixtures never use. MESHTASTIC_CAPTURES names the directory of the
+// captures, testdata/captures by default. It has a directory per firmware
+// version, such as 2.5.20, with files of varint length-delimited MeshPackets
+// as written by protodelim; see testdata/captures/README.md.
+
+// captureFirmware are the firmware series that must have captures.
+var captureFirmware = []string{"2.3", "2.4", "2.5", "2.6"}
+
+func TestCaptures(t *testing.T) {
+	dir := os.Getenv("MESHTASTIC_CAPTURES")
+	if dir == "" {
+		dir = filepath.Join("testdata", "captures")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Skipf("no captures: %v", err)
+	}
+
+	var firmwares []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			firmwares = append(firmwares, entry.Name())
+		}
+	}
+	if len(firmwares) == 0 {
+		t.Skipf("no captures in %s", dir)
+	}
+	for _, series := range captureFirmware {
+		found := false
+		for _, firmware := range firmwares {
+			found = found || firmware == series || strings.HasPrefix(firmware, series+".")
+		}
+		if !found {
+			t.Errorf("no captures of firmware %s in %s", series, dir)
+		}
+	}
+
+	version := Versions[len(Versions)-1]
+	for _, firmware := range firmwares {
+		t.Run(firmware, func(t *testing.T) {
+			files, err := filepath.Glob(filepath.Join(dir, firmware, "*.bin"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			packets := 0
+			for _, file := range files {
+				packets += roundtripCapture(t, version, file)
+			}
+			if packets == 0 {
+				t.Fatalf("no packets in %s", filepath.Join(dir, firmware))
+			}
+			t.Logf("%d packets in %d files", packets, len(files))
+		})
+	}
+}
+
+// roundtripCapture checks that every packet in file roundtrips through
+// version and returns the number of packets.
+func roundtripCapture(t *testing.T, version Version, file string) int {
+	t.Helper()
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for i := 0; ; i++ {
+		packet := &meshtastic.MeshPacket{}
+		if err := protodelim.UnmarshalFrom(r, packet); err != nil {
+			if errors.Is(err, io.EOF) {
+				return i
+			}
+			t.Fatalf("%s: packet %d: %v", file, i, err)
+		}
+
+		var buf bytes.Buffer
+		if err := version.Compress(packet, &buf); err != nil {
+			t.Errorf("%s: packet %d: %s compress failed: %v", file, i, version.Name, err)
+			continue
+		}
+		result := &meshtastic.MeshPacket{}
+		if err := version.Decompress(&buf, result); err != nil {
+			t.Errorf("%s: packet %d: %s decompress failed: %v", file, i, version.Name, err)
+			continue
+		}
+		if !proto.Equal(packet, result) {
+			t.Errorf("%s: packet %d: %s roundtrip mismatch\noriginal: %v\ndecoded:  %v", file, i, version.Name, packet, result)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+// MaxChannels is the number of channels of a device's channel table.
+const MaxChannels = 8
+
+// maxPSKLength is the longest channel key, an AES-256 key, as in maxlen.go.
+const maxPSKLength = 32
+
+// CompressChannels compresses a channel table, such as the channels a device
+// sends during the config download, as one batch.
+//
+// Channels are usually listed in table order, so an index that matches the
+// channel's position costs a fraction of a bit. Roles are coded with priors
+// for a table of one PRIMARY channel followed by SECONDARY and DISABLED
+// ones. A key that was already used by an earlier channel is coded as a
+// reference to it. The remaining channel settings are coded like V14.
+func CompressChannels(channels []*meshtastic.Channel, w io.Writer) error {
+	if len(channels) > MaxChannels {
+		return fmt.Errorf("%d channels exceed the maximum of %d", len(channels), MaxChannels)
+	}
+
+	mcb := newModelBuilderV14()
+	enc := coder.NewEncoder(w)
+	if err := enc.Encode(len(channels), models.SharedUniformModel(MaxChannels+1)); err != nil {
+		return err
+	}
+
+	var keys [][]byte
+	primarySeen := false
+	for i, channel := range channels {
+		if err := compressChannel(i, channel, &keys, primarySeen, enc, mcb); err != nil {
+			return fmt.Errorf("channel %d: %w", i, err)
+		}
+		if channel.GetRole() == meshtastic.Channel_PRIMARY {
+			primarySeen = true
+		}
+	}
+
+	return enc.Close()
+}
+
+// compressChannel compresses the channel at position in the batch. Keys are
+// the distinct keys of the previous channels.
+func compressChannel(position int, channel *meshtastic.Channel, keys *[][]byte, primarySeen bool, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	implicit := 0
+	if channel.GetIndex() == int32(position) {
+		implicit = 1
+	}
+	if err := models.EncodeEscaped(enc, implicit, mcb.GetBooleanModel("channel_index_implicit")); err != nil {
+		return fmt.Errorf("field index: %w", err)
+	}
+	if implicit == 0 {
+		if err := encodeVarintWithModels(uint64(uint32(channel.GetIndex())), enc, mcb); err != nil {
+			return fmt.Errorf("field index: %w", err)
+		}
+	}
+
+	role := channel.GetRole()
+	if role < meshtastic.Channel_DISABLED || role > meshtastic.Channel_SECONDARY {
+		return fmt.Errorf("field role: unsupported value %d", role)
+	}
+	if err := enc.Encode(int(role), channelRoleModel(position, primarySeen)); err != nil {
+		return fmt.Errorf("field role: %w", err)
+	}
+
+	settings := channel.GetSettings()
+	present := 0
+	if settings != nil {
+		present = 1
+	}
+	if err := models.EncodeEscaped(enc, present, mcb.GetBooleanModel("settings_presence")); err != nil {
+		return fmt.Errorf("field settings presence: %w", err)
+	}
+	if settings == nil {
+		return nil
+	}
+
+	if err := encodeChannelKey(settings.GetPsk(), keys, enc); err != nil {
+		return fmt.Errorf("field psk: %w", err)
+	}
+	rest := proto.CloneOf(settings)
+	rest.Psk = nil
+	if err := compressMessageV10("settings", rest.ProtoReflect(), enc, mcb); err != nil {
+		return fmt.Errorf("field settings: %w", err)
+	}
+	return nil
+}
+
+// encodeChannelKey encodes a channel key, as a reference to one of the
+// distinct keys of the previous channels when possible.
+func encodeChannelKey(psk []byte, keys *[][]byte, enc *coder.Encoder) error {
+	if len(psk) > maxPSKLength {
+		return fmt.Errorf("length %d exceeds maximum %d", len(psk), maxPSKLength)
+	}
+
+	// Symbol 0 is a new key, symbol i+1 refers to keys[i]
+	reference := 0
+	for i, key := range *keys {
+		if bytes.Equal(key, psk) {
+			reference = i + 1
+			break
+		}
+	}
+	if err := enc.Encode(reference, channelKeyReferenceModel(len(*keys))); err != nil {
+		return err
+	}
+	if reference > 0 {
+		return nil
+	}
+
+	*keys = append(*keys, psk)
+	return encodePSK(psk, enc)
+}
+
+// DecompressChannels decompresses a channel table compressed with
+// CompressChannels.
+func DecompressChannels(r io.Reader) ([]*meshtastic.Channel, error) {
+	mcb := newModelBuilderV14()
+	dec, err := coder.NewDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := dec.Decode(models.SharedUniformModel(MaxChannels + 1))
+	if err != nil {
+		return nil, err
+	}
+
+	var keys [][]byte
+	channels := make([]*meshtastic.Channel, count)
+	primarySeen := false
+	for i := range channels {
+		channel, err := decompressChannel(i, &keys, primarySeen, dec, mcb)
+		if err != nil {
+			return nil, fmt.Errorf("channel %d: %w", i, err)
+		}
+		if channel.GetRole() == meshtastic.Channel_PRIMARY {
+			primarySeen = true
+		}
+		channels[i] = channel
+	}
+	return channels, nil
+}
+
+// decompressChannel decompresses a channel written by compressChannel.
+func decompressChannel(position int, keys *[][]byte, primarySeen bool, dec *coder.Decoder, mcb *ContextualModelBuilder) (*meshtastic.Channel, error) {
+	channel := &meshtastic.Channel{Index: int32(position)}
+
+	implicit, err := models.DecodeEscaped(dec, mcb.GetBooleanModel("channel_index_implicit"))
+	if err != nil {
+		return nil, fmt.Errorf("field index: %w", err)
+	}
+	if implicit == 0 {
+		index, err := decodeVarintWithModels(dec, mcb)
+		if err != nil {
+			return nil, fmt.Errorf("field index: %w", err)
+		}
+		channel.Index = int32(uint32(index))
+	}
+
+	role, err := dec.Decode(channelRoleModel(position, primarySeen))
+	if err != nil {
+		return nil, fmt.Errorf("field role: %w", err)
+	}
+	channel.Role = meshtastic.Channel_Role(role)
+
+	present, err := models.DecodeEscaped(dec, mcb.GetBooleanModel("settings_presence"))
+	if err != nil {
+		return nil, fmt.Errorf("field settings presence: %w", err)
+	}
+	if present == 0 {
+		return channel, nil
+	}
+
+	psk, err := decodeChannelKey(keys, dec)
+	if err != nil {
+		return nil, fmt.Errorf("field psk: %w", err)
+	}
+	settings := &meshtastic.ChannelSettings{}
+	if err := decompressMessageV10("settings", settings.ProtoReflect(), dec, mcb); err != nil {
+		return nil, fmt.Errorf("field settings: %w", err)
+	}
+	settings.Psk = psk
+	channel.Settings = settings
+	return channel, nil
+}
+
+// decodeChannelKey decodes a key written by encodeChannelKey.
+func decodeChannelKey(keys *[][]byte, dec *coder.Decoder) ([]byte, error) {
+	reference, err := dec.Decode(channelKeyReferenceModel(len(*keys)))
+	if err != nil {
+		return nil, err
+	}
+	if reference > 0 {
+		return bytes.Clone((*keys)[reference-1]), nil
+	}
+
+	psk, err := decodePSK(dec)
+	if err != nil {
+		return nil, err
+	}
+	*keys = append(*keys, psk)
+	return bytes.Clone(psk), nil
+}
+
+// channelRoleModel returns the role model for the channel at position.
+func channelRoleModel(position int, primarySeen bool) coder.Model {
+	switch {
+	case primarySeen:
+		return sharedSecondaryChannelRoleModel()
+	case position == 0:
+		return sharedFirstChannelRoleModel()
+	default:
+		return sharedMissingPrimaryChannelRoleModel()
+	}
+}
+
+// channelKeyReferenceModel returns the model for a key reference, given the
+// number of distinct keys of the previous channels. Channels mostly share
+// the default key or a single private key, so references are more likely
+// than new keys once a few keys are known.
+func channelKeyReferenceModel(numKeys int) coder.Model {
+	freqs := make([]uint64, numKeys+1)
+	freqs[0] = 4
+	for i := 1; i < len(freqs); i++ {
+		freqs[i] = 3
+	}
+	return models.NewFrequencyTable(freqs)
+}
+
+// Channel role frequencies are [DISABLED, PRIMARY, SECONDARY].
+var (
+	// The first channel is the primary one
+	sharedFirstChannelRoleModel = sync.OnceValue(func() coder.Model {
+		return models.NewFrequencyTable([]uint64{40, 900, 60})
+	})
+	// After the primary channel, the rest of the table is mostly disabled
+	sharedSecondaryChannelRoleModel = sync.OnceValue(func() coder.Model {
+		return models.NewFrequencyTable([]uint64{700, 10, 290})
+	})
+	// Tables whose primary channel is not first, or missing
+	sharedMissingPrimaryChannelRoleModel = sync.OnceValue(func() coder.Model {
+		return models.NewFrequencyTable([]uint64{400, 400, 200})
+	})
+)
@@ -0,0 +1,104 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestCompressChannels(t *testing.T) {
+	adminKey := make([]byte, 32)
+	for i := range adminKey {
+		adminKey[i] = byte(i*37 + 11)
+	}
+
+	channels := []*meshtastic.Channel{
+		{Index: 0, Role: meshtastic.Channel_PRIMARY, Settings: &meshtastic.ChannelSettings{
+			Psk:            []byte{1},
+			ModuleSettings: &meshtastic.ModuleSettings{PositionPrecision: 13},
+		}},
+		{Index: 1, Role: meshtastic.Channel_SECONDARY, Settings: &meshtastic.ChannelSettings{
+			Psk:  adminKey,
+			Name: "admin",
+		}},
+		{Index: 2, Role: meshtastic.Channel_SECONDARY, Settings: &meshtastic.ChannelSettings{
+			Psk:           adminKey,
+			Name:          "hiking",
+			UplinkEnabled: true,
+		}},
+	}
+	for i := len(channels); i < MaxChannels; i++ {
+		channels = append(channels, &meshtastic.Channel{Index: int32(i), Settings: &meshtastic.ChannelSettings{}})
+	}
+
+	var batch bytes.Buffer
+	if err := CompressChannels(channels, &batch); err != nil {
+		t.Fatalf("CompressChannels failed: %v", err)
+	}
+	separate := 0
+	for _, channel := range channels {
+		var buf bytes.Buffer
+		if err := CompressV14(channel, &buf); err != nil {
+			t.Fatalf("CompressV14 failed: %v", err)
+		}
+		separate += buf.Len()
+	}
+	t.Logf("batch: %d bytes, separate V14: %d bytes", batch.Len(), separate)
+	if batch.Len() >= separate {
+		t.Errorf("batch (%d bytes) should be smaller than separate messages (%d bytes)", batch.Len(), separate)
+	}
+
+	result, err := DecompressChannels(&batch)
+	if err != nil {
+		t.Fatalf("DecompressChannels failed: %v", err)
+	}
+	assertChannelsEqual(t, channels, result)
+}
+
+func TestCompressChannelsUnusual(t *testing.T) {
+	tests := map[string][]*meshtastic.Channel{
+		"empty":       nil,
+		"no settings": {{Index: 0, Role: meshtastic.Channel_PRIMARY}},
+		"out of order": {
+			{Index: 3, Role: meshtastic.Channel_SECONDARY, Settings: &meshtastic.ChannelSettings{Psk: []byte{2}}},
+			{Index: 0, Role: meshtastic.Channel_PRIMARY, Settings: &meshtastic.ChannelSettings{Psk: []byte{2}}},
+			{Index: -1, Role: meshtastic.Channel_DISABLED},
+		},
+	}
+	for name, channels := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := CompressChannels(channels, &buf); err != nil {
+				t.Fatalf("CompressChannels failed: %v", err)
+			}
+			result, err := DecompressChannels(&buf)
+			if err != nil {
+				t.Fatalf("DecompressChannels failed: %v", err)
+			}
+			assertChannelsEqual(t, channels, result)
+		})
+	}
+
+	tooMany := make([]*meshtastic.Channel, MaxChannels+1)
+	for i := range tooMany {
+		tooMany[i] = &meshtastic.Channel{Index: int32(i)}
+	}
+	if err := CompressChannels(tooMany, &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for too many channels")
+	}
+}
+
+func assertChannelsEqual(t *testing.T, want, got []*meshtastic.Channel) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d channels, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !proto.Equal(want[i], got[i]) {
+			t.Errorf("channel %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -2,11 +2,16 @@ package meshtasticmodel
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 
 	"google.golang.org/protobuf/proto"
 
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 func TestMeshtasticCompressionRatio(t *testing.T) {
@@ -16,37 +21,18 @@ func TestMeshtasticCompressionRatio(t *testing.T) {
 		maxCompressionPct float64
 	}{
 		{
-			name: "Position with full data",
-			msg: &meshtastic.Position{
-				LatitudeI:  proto.Int32(375317890),
-				LongitudeI: proto.Int32(-1223898570),
-				Altitude:   proto.Int32(100),
-				Time:       1703520000,
-			},
+			name:              "Position with full data",
+			msg:               meshfixtures.Position(meshfixtures.Default),
 			maxCompressionPct: 100, // V3 adds 1 byte strategy flag overhead, tiny message with fixed-width fields
 		},
 		{
-			name: "User profile",
-			msg: &meshtastic.User{
-				Id:        "!12345678",
-				LongName:  "Test Meshtastic Node",
-				ShortName: "TEST",
-				HwModel:   meshtastic.HardwareModel_TBEAM,
-			},
+			name:              "User profile",
+			msg:               meshfixtures.User(meshfixtures.Default),
 			maxCompressionPct: 90,
 		},
 		{
-			name: "Text message packet",
-			msg: &meshtastic.MeshPacket{
-				From: 123456789,
-				To:   987654321,
-				PayloadVariant: &meshtastic.MeshPacket_Decoded{
-					Decoded: &meshtastic.Data{
-						Portnum: meshtastic.PortNum_TEXT_MESSAGE_APP,
-						Payload: []byte("This is a test message being sent over the Meshtastic network"),
-					},
-				},
-			},
+			name:              "Text message packet",
+			msg:               meshfixtures.TextPacket(meshfixtures.Default, meshfixtures.TextMessages[0]),
 			maxCompressionPct: 95,
 		},
 	}
@@ -68,9 +54,10 @@ func TestMeshtasticCompressionRatio(t *testing.T) {
 			}
 			results := make([]result, 0, len(Versions))
 
+			// The ratios are the ones of the codings, without the header
 			for _, version := range Versions {
 				var buf bytes.Buffer
-				err := version.Compress(tt.msg, &buf)
+				err := version.Compress(tt.msg, &buf, WithoutHeader())
 				if err != nil {
 					t.Fatalf("%s Compress failed: %v", version.Name, err)
 				}
@@ -115,7 +102,7 @@ func TestMeshtasticCompressionRatio(t *testing.T) {
 				v1Result.compressedSize-bestResult.compressedSize,
 				v1Result.version.Name)
 
-			// Check last version (V10) against max compression ratio
+			// Check last version (latest) against max compression ratio
 			lastResult := results[len(results)-1]
 			lastRatio := float64(lastResult.compressedSize) / float64(originalSize) * 100
 			if lastRatio > tt.maxCompressionPct {
@@ -126,7 +113,7 @@ func TestMeshtasticCompressionRatio(t *testing.T) {
 			for _, r := range results {
 				resultMsg := tt.msg.ProtoReflect().New().Interface()
 				bufCopy := bytes.NewBuffer(r.buffer.Bytes())
-				err := r.version.Decompress(bufCopy, resultMsg)
+				err := r.version.Decompress(bufCopy, resultMsg, WithoutHeader())
 				if err != nil {
 					t.Fatalf("%s Decompress failed: %v", r.version.Name, err)
 				}
@@ -138,3 +125,90 @@ func TestMeshtasticCompressionRatio(t *testing.T) {
 		})
 	}
 }
+
+func TestMeshtasticFixturesRoundtrip(t *testing.T) {
+	for _, scenario := range meshfixtures.Scenarios() {
+		for _, msg := range meshfixtures.Messages(scenario) {
+			name := scenario.Name + "/" + string(msg.ProtoReflect().Descriptor().Name())
+			for _, version := range Versions {
+				var buf bytes.Buffer
+				if err := version.Compress(msg, &buf); err != nil {
+					t.Fatalf("%s: %s Compress failed: %v", name, version.Name, err)
+				}
+
+				result := msg.ProtoReflect().New().Interface()
+				if err := version.Decompress(&buf, result); err != nil {
+					t.Fatalf("%s: %s Decompress failed: %v", name, version.Name, err)
+				}
+				if !proto.Equal(msg, result) {
+					t.Errorf("%s: %s roundtrip verification failed", name, version.Name)
+				}
+			}
+		}
+	}
+}
+
+func TestConcurrentCompression(t *testing.T) {
+	// The static models are shared between compressors, so compressing
+	// concurrently must not interfere. Run with -race to check.
+	packets := sessionPackets()[:30]
+
+	for _, version := range Versions {
+		t.Run(version.Name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			errs := make(chan error, len(packets))
+			for _, packet := range packets {
+				wg.Go(func() {
+					var buf bytes.Buffer
+					if err := version.Compress(packet, &buf); err != nil {
+						errs <- fmt.Errorf("Compress failed: %w", err)
+						return
+					}
+					result := &meshtastic.MeshPacket{}
+					if err := version.Decompress(&buf, result); err != nil {
+						errs <- fmt.Errorf("Decompress failed: %w", err)
+						return
+					}
+					if !proto.Equal(packet, result) {
+						errs <- errors.New("roundtrip verification failed")
+					}
+				})
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRouteListTransforms(t *testing.T) {
+	// A traceroute through nodes provisioned in a batch
+	msg := &meshtastic.RouteDiscovery{
+		Route:      []uint32{0xa2e4f010, 0xa2e4f013, 0xa2e4f011, 0xa2e4f017, 0xa2e4f012, 0xa2e4f016},
+		SnrTowards: []int32{38, 36, 41, 33, 35, 40, 37},
+		RouteBack:  []uint32{0xa2e4f016, 0xa2e4f014, 0xa2e4f010},
+		SnrBack:    []int32{-20, -22, -19, -21},
+	}
+
+	var plain, transformed bytes.Buffer
+	if err := pbmodel.Compress(msg, &plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := pbmodel.Compress(msg, &transformed, pbmodel.WithListTransforms()); err != nil {
+		t.Fatal(err)
+	}
+	decoded := &meshtastic.RouteDiscovery{}
+	if err := pbmodel.Decompress(bytes.NewReader(transformed.Bytes()), decoded, pbmodel.WithListTransforms()); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(msg, decoded) {
+		t.Errorf("roundtrip mismatch.\nOriginal: %v\nDecoded: %v", msg, decoded)
+	}
+
+	t.Logf("route: %d bytes, %d bytes with list transforms", plain.Len(), transformed.Len())
+	if transformed.Len()*2 > plain.Len() {
+		t.Errorf("route with list transforms %d bytes, want at most half of %d bytes", transformed.Len(), plain.Len())
+	}
+}
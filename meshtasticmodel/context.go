@@ -1,9 +1,12 @@
 package meshtasticmodel
 
 import (
+	"sync"
+
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 )
 
 // ContextualModelBuilder creates highly specialized models based on
@@ -13,29 +16,73 @@ type ContextualModelBuilder struct {
 
 	// Context tracking
 	messageType     string // Current message type (Position, User, etc.)
-	contextModels   map[string]arithcode.Model
+	contextModels   map[string]coder.Model
 	enumPredictions map[string]protoreflect.EnumNumber
-	booleanModels   map[string]arithcode.Model // Field-specific boolean models
+	booleanModels   map[string]coder.Model // Field-specific boolean models
+	fieldModels     FieldModels            // Context-specific models by field
 
 	// Varint byte models
-	varintFirstByteModel arithcode.Model // Model for first byte of varint
-	varintContByteModel  arithcode.Model // Model for continuation bytes
+	varintFirstByteModel coder.Model // Model for first byte of varint
+	varintContByteModel  coder.Model // Model for continuation bytes
+
+	// Adaptive text model shared by all string fields (V11+), nil codes
+	// strings with the order-2 string coder
+	textModel *models.PPMModel
+	// Emoji-aware wrapper around textModel (V12+)
+	emojiTextModel *models.EmojiTextModel
+	// Lengths of the fields with a documented maximum coded uniformly up
+	// to it, and longer values rejected (V11+), false codes them as varints
+	fieldLengths bool
+	// Mispredictions after which static models escape to adaptive ones
+	// (V13+), zero keeps the static models
+	escapeLimit int
+	// Count total at which adaptive models halve their counts, zero keeps
+	// the models' defaults
+	decayLimit uint64
+	// Structured firmware versions, static tables for device enums and
+	// channel key classes (V14+), false keeps the V13 coding
+	configDownload bool
+	// Correlated fields coded as differences from a base field (V14+),
+	// false codes them like other fields
+	correlatedFields bool
+	// Particle counts coded by magnitude (V14+), false codes them like
+	// other integers
+	particleCounts bool
+	// Host byte counts coded by exponent and mantissa (V14+), false codes
+	// them like other integers
+	byteCounts bool
+	// Enum numbers the schema does not declare coded after an escape
+	// (V14+), false refuses them
+	openEnums bool
+	// Unknown fields of messages coded after their known fields (V14+),
+	// false drops them
+	unknownFields bool
+	// Outstanding admin requests and the passkey of a session, nil codes
+	// admin messages like other messages
+	admin *adminState
+	// Previous counter values of the nodes of a session, nil codes counters
+	// like other integers
+	counters *counterState
+	// Node IDs and strings of the bundle being coded, nil codes them like
+	// other values
+	dictionary *dictionary
 }
 
 // NewContextualModelBuilder creates a context-aware model builder.
 func NewContextualModelBuilder() *ContextualModelBuilder {
 	return &ContextualModelBuilder{
 		ModelBuilderV1:       NewModelBuilderV1(),
-		contextModels:        make(map[string]arithcode.Model),
+		contextModels:        make(map[string]coder.Model),
 		enumPredictions:      getCommonEnumValues(),
-		booleanModels:        make(map[string]arithcode.Model),
-		varintFirstByteModel: createVarintFirstByteModel(),
-		varintContByteModel:  createVarintContinuationByteModel(),
+		booleanModels:        make(map[string]coder.Model),
+		fieldModels:          defaultFieldModels,
+		varintFirstByteModel: sharedVarintFirstByteModel(),
+		varintContByteModel:  sharedVarintContinuationByteModel(),
 	}
 }
 
 // GetContextualFieldModel returns a model optimized for the specific field context.
-func (mcb *ContextualModelBuilder) GetContextualFieldModel(fieldPath string, fd protoreflect.FieldDescriptor) arithcode.Model {
+func (mcb *ContextualModelBuilder) GetContextualFieldModel(fieldPath string, fd protoreflect.FieldDescriptor) coder.Model {
 	// Build context key
 	contextKey := mcb.messageType + ":" + fieldPath
 
@@ -47,6 +94,7 @@ func (mcb *ContextualModelBuilder) GetContextualFieldModel(fieldPath string, fd
 	// Create context-specific model
 	model := mcb.createContextSpecificModel(fieldPath, fd)
 	if model != nil {
+		model = mcb.escapeModel(model)
 		mcb.contextModels[contextKey] = model
 		return model
 	}
@@ -55,260 +103,318 @@ func (mcb *ContextualModelBuilder) GetContextualFieldModel(fieldPath string, fd
 	return mcb.GetFieldModel(fieldPath, fd)
 }
 
-// createContextSpecificModel creates specialized models for known Meshtastic field patterns.
-func (mcb *ContextualModelBuilder) createContextSpecificModel(fieldPath string, fd protoreflect.FieldDescriptor) arithcode.Model {
+// The context-specific models are static, so a single instance of each is
+// shared by all builders.
+var (
+	sharedCoordinateModel     = sync.OnceValue(createCoordinateModel)
+	sharedAltitudeModel       = sync.OnceValue(createAltitudeModel)
+	sharedNodeIDModel         = sync.OnceValue(createNodeIDModel)
+	sharedBatteryLevelModel   = sync.OnceValue(createBatteryLevelModel)
+	sharedRSSIModel           = sync.OnceValue(createRSSIModel)
+	sharedSNRModel            = sync.OnceValue(createSNRModel)
+	sharedSNRArrayModel       = sync.OnceValue(createSNRArrayModel)
+	sharedVoltageModel        = sync.OnceValue(createVoltageModel)
+	sharedChannelVoltageModel = sync.OnceValue(createChannelVoltageModel)
+	sharedChannelCurrentModel = sync.OnceValue(createChannelCurrentModel)
+	sharedUtilizationModel    = sync.OnceValue(createUtilizationModel)
+	sharedHopCountModel       = sync.OnceValue(createHopCountModel)
+	sharedChannelNumberModel  = sync.OnceValue(createChannelNumberModel)
+	sharedSatelliteCountModel = sync.OnceValue(createSatelliteCountModel)
+	sharedGPSQualityModel     = sync.OnceValue(createGPSQualityModel)
+	sharedPrecisionModel      = sync.OnceValue(createPrecisionModel)
+	sharedDOPModel            = sync.OnceValue(createDOPModel)
+	sharedSpeedModel          = sync.OnceValue(createSpeedModel)
+	sharedDirectionModel      = sync.OnceValue(createDirectionModel)
+	sharedRequestIDModel      = sync.OnceValue(createRequestIDModel)
+	sharedPacketIDModel       = sync.OnceValue(createPacketIDModel)
+	sharedUptimeModel         = sync.OnceValue(createUptimeModel)
+	sharedTemperatureModel    = sync.OnceValue(createTemperatureModel)
+	sharedHumidityModel       = sync.OnceValue(createHumidityModel)
+	sharedPressureModel       = sync.OnceValue(createPressureModel)
+	sharedGasResistanceModel  = sync.OnceValue(createGasResistanceModel)
+	sharedIAQModel            = sync.OnceValue(createIAQModel)
+	sharedLuxModel            = sync.OnceValue(createLuxModel)
+	sharedDistanceModel       = sync.OnceValue(createDistanceModel)
+	sharedWindSpeedModel      = sync.OnceValue(createWindSpeedModel)
+	sharedRainfallModel       = sync.OnceValue(createRainfallModel)
+	sharedSoilMoistureModel   = sync.OnceValue(createSoilMoistureModel)
+	sharedParticulateModel    = sync.OnceValue(createParticulateModel)
+	sharedParticleCountModel  = sync.OnceValue(createParticleCountModel)
+	sharedCO2Model            = sync.OnceValue(createCO2Model)
+	sharedFormaldehydeModel   = sync.OnceValue(createFormaldehydeModel)
+	sharedVOCNOxModel         = sync.OnceValue(createVOCNOxModel)
+	sharedHeartRateModel      = sync.OnceValue(createHeartRateModel)
+	sharedSpO2Model           = sync.OnceValue(createSpO2Model)
+	sharedPacketCountModel    = sync.OnceValue(createPacketCountModel)
+	sharedNodeCountModel      = sync.OnceValue(createNodeCountModel)
+	sharedMemoryBytesModel    = sync.OnceValue(createMemoryBytesModel)
+	sharedLargeMemoryModel    = sync.OnceValue(createLargeMemoryModel)
+	sharedLoadAverageModel    = sync.OnceValue(createLoadAverageModel)
+	sharedTimestampModel      = sync.OnceValue(createTimestampModel)
+	sharedMillisAdjustModel   = sync.OnceValue(createMillisAdjustModel)
+	sharedPriorityModel       = sync.OnceValue(createPriorityModel)
+	sharedWaypointIDModel     = sync.OnceValue(createWaypointIDModel)
+	sharedExpireTimeModel     = sync.OnceValue(createExpireTimeModel)
+
+	sharedVarintFirstByteModel        = sync.OnceValue(createVarintFirstByteModel)
+	sharedVarintContinuationByteModel = sync.OnceValue(createVarintContinuationByteModel)
+)
+
+// contextModelByName selects specialized models for known Meshtastic field
+// names, see createContextSpecificModel.
+func (mcb *ContextualModelBuilder) contextModelByName(fieldPath string, fd protoreflect.FieldDescriptor) coder.Model {
 	fieldName := string(fd.Name())
 
 	// Coordinate models (latitude_i, longitude_i)
 	if fieldName == "latitude_i" || fieldName == "longitude_i" {
-		return createCoordinateModel()
+		return sharedCoordinateModel()
 	}
 
 	// Altitude models (typically -500 to 9000 meters)
 	if fieldName == "altitude" || fieldName == "altitude_hae" || fieldName == "altitude_geoidal_separation" {
-		return createAltitudeModel()
+		return sharedAltitudeModel()
 	}
 
 	// Node ID models (large 32-bit integers)
 	if fieldName == "from" || fieldName == "to" || fieldName == "num" || fieldName == "dest" || fieldName == "source" ||
 		fieldName == "node_num" || fieldName == "locked_to" {
-		return createNodeIDModel()
+		return sharedNodeIDModel()
 	}
 
 	// Battery level (0-100%, >100 means powered)
 	if fieldName == "battery_level" {
-		return createBatteryLevelModel()
+		return sharedBatteryLevelModel()
 	}
 
 	// Signal quality (RSSI: -120 to -30 dBm)
 	if fieldName == "rx_rssi" {
-		return createRSSIModel()
+		return sharedRSSIModel()
 	}
 
 	// Signal quality (SNR: -20 to +20 dB)
 	if fieldName == "rx_snr" || fieldName == "snr" {
-		return createSNRModel()
+		return sharedSNRModel()
 	}
 
 	// SNR arrays in routing (int32 array, scaled by 4)
 	if fieldName == "snr_towards" || fieldName == "snr_back" {
-		return createSNRArrayModel()
+		return sharedSNRArrayModel()
 	}
 
 	// Voltage (2.0 to 5.0V for battery, wider for power monitoring)
 	if fieldName == "voltage" {
-		return createVoltageModel()
+		return sharedVoltageModel()
 	}
 
 	// Multi-channel voltage measurements (ch1_voltage through ch8_voltage)
 	if fieldName == "ch1_voltage" || fieldName == "ch2_voltage" || fieldName == "ch3_voltage" || fieldName == "ch4_voltage" ||
 		fieldName == "ch5_voltage" || fieldName == "ch6_voltage" || fieldName == "ch7_voltage" || fieldName == "ch8_voltage" {
-		return createChannelVoltageModel()
+		return sharedChannelVoltageModel()
 	}
 
 	// Multi-channel current measurements (ch1_current through ch8_current)
 	if fieldName == "ch1_current" || fieldName == "ch2_current" || fieldName == "ch3_current" || fieldName == "ch4_current" ||
 		fieldName == "ch5_current" || fieldName == "ch6_current" || fieldName == "ch7_current" || fieldName == "ch8_current" {
-		return createChannelCurrentModel()
+		return sharedChannelCurrentModel()
 	}
 
 	// Channel utilization (0-1.0, percentage)
 	if fieldName == "channel_utilization" || fieldName == "air_util_tx" {
-		return createUtilizationModel()
+		return sharedUtilizationModel()
 	}
 
 	// Hop limit (typically 0-7)
 	if fieldName == "hop_limit" || fieldName == "hops_away" || fieldName == "hop_start" {
-		return createHopCountModel()
+		return sharedHopCountModel()
 	}
 
 	// Channel number/index (typically 0-7)
 	if fieldName == "channel" || fieldName == "channel_index" {
-		return createChannelNumberModel()
+		return sharedChannelNumberModel()
 	}
 
 	// Satellite count (0-20 typically)
 	if fieldName == "sats_in_view" {
-		return createSatelliteCountModel()
+		return sharedSatelliteCountModel()
 	}
 
 	// GPS quality indicators
 	if fieldName == "fix_quality" || fieldName == "fix_type" {
-		return createGPSQualityModel()
+		return sharedGPSQualityModel()
 	}
 
 	// Precision/accuracy values (typically small positive integers)
 	if fieldName == "precision_bits" || fieldName == "gps_accuracy" {
-		return createPrecisionModel()
+		return sharedPrecisionModel()
 	}
 
 	// DOP values (10-1000, representing 1.0-100.0)
 	if fieldName == "pdop" || fieldName == "hdop" || fieldName == "vdop" {
-		return createDOPModel()
+		return sharedDOPModel()
 	}
 
 	// Speed values (0-50 m/s typically)
 	if fieldName == "ground_speed" {
-		return createSpeedModel()
+		return sharedSpeedModel()
 	}
 
 	// Direction/heading values (0-35999, representing 0-359.99 degrees)
 	if fieldName == "ground_track" || fieldName == "wind_direction" {
-		return createDirectionModel()
+		return sharedDirectionModel()
 	}
 
 	// Request/Reply IDs (small sequential numbers)
 	if fieldName == "request_id" || fieldName == "reply_id" {
-		return createRequestIDModel()
+		return sharedRequestIDModel()
 	}
 
 	// Packet ID (larger numbers but sequential)
 	if fieldName == "id" && mcb.messageType == "MeshPacket" {
-		return createPacketIDModel()
+		return sharedPacketIDModel()
 	}
 
 	// Uptime in seconds (monotonically increasing)
 	if fieldName == "uptime_seconds" {
-		return createUptimeModel()
+		return sharedUptimeModel()
 	}
 
 	// Temperature (-40 to 85°C typical sensor range)
 	if fieldName == "temperature" || fieldName == "co2_temperature" || fieldName == "form_temperature" ||
 		fieldName == "soil_temperature" {
-		return createTemperatureModel()
+		return sharedTemperatureModel()
 	}
 
 	// Humidity (0-100%)
 	if fieldName == "relative_humidity" || fieldName == "co2_humidity" || fieldName == "form_humidity" {
-		return createHumidityModel()
+		return sharedHumidityModel()
 	}
 
 	// Barometric pressure (300-1100 hPa)
 	if fieldName == "barometric_pressure" {
-		return createPressureModel()
+		return sharedPressureModel()
 	}
 
 	// Gas resistance (BME680, typically 0-10000 kOhm)
 	if fieldName == "gas_resistance" {
-		return createGasResistanceModel()
+		return sharedGasResistanceModel()
 	}
 
 	// IAQ (Indoor Air Quality, 0-500)
 	if fieldName == "iaq" {
-		return createIAQModel()
+		return sharedIAQModel()
 	}
 
 	// Light measurements (lux values, wide range)
 	if fieldName == "lux" || fieldName == "white_lux" || fieldName == "ir_lux" || fieldName == "uv_lux" {
-		return createLuxModel()
+		return sharedLuxModel()
 	}
 
 	// Distance measurements (mm from radar sensor)
 	if fieldName == "distance" {
-		return createDistanceModel()
+		return sharedDistanceModel()
 	}
 
 	// Wind speed (m/s, typically 0-50)
 	if fieldName == "wind_speed" || fieldName == "wind_gust" || fieldName == "wind_lull" {
-		return createWindSpeedModel()
+		return sharedWindSpeedModel()
 	}
 
 	// Rainfall (mm, typically 0-100)
 	if fieldName == "rainfall_1h" || fieldName == "rainfall_24h" {
-		return createRainfallModel()
+		return sharedRainfallModel()
 	}
 
 	// Soil moisture (1-100%)
 	if fieldName == "soil_moisture" {
-		return createSoilMoistureModel()
+		return sharedSoilMoistureModel()
 	}
 
 	// Particulate matter (ug/m3, 0-500 typical)
 	if fieldName == "pm10_standard" || fieldName == "pm25_standard" || fieldName == "pm100_standard" ||
 		fieldName == "pm10_environmental" || fieldName == "pm25_environmental" || fieldName == "pm100_environmental" {
-		return createParticulateModel()
+		return sharedParticulateModel()
 	}
 
 	// Particle counts (#/0.1l, wide range)
 	if fieldName == "particles_03um" || fieldName == "particles_05um" || fieldName == "particles_10um" ||
 		fieldName == "particles_25um" || fieldName == "particles_50um" || fieldName == "particles_100um" {
-		return createParticleCountModel()
+		return sharedParticleCountModel()
 	}
 
 	// CO2 (ppm, 400-5000 typical)
 	if fieldName == "co2" {
-		return createCO2Model()
+		return sharedCO2Model()
 	}
 
 	// Formaldehyde (mg/m3, 0-1.0 typical)
 	if fieldName == "form_formaldehyde" {
-		return createFormaldehydeModel()
+		return sharedFormaldehydeModel()
 	}
 
 	// VOC/NOx indices (0-500)
 	if fieldName == "pm_voc_idx" || fieldName == "pm_nox_idx" {
-		return createVOCNOxModel()
+		return sharedVOCNOxModel()
 	}
 
 	// Health metrics: heart rate (40-200 bpm)
 	if fieldName == "heart_bpm" {
-		return createHeartRateModel()
+		return sharedHeartRateModel()
 	}
 
 	// Health metrics: blood oxygen (95-100%)
 	if fieldName == "spO2" {
-		return createSpO2Model()
+		return sharedSpO2Model()
 	}
 
 	// Packet statistics (monotonically increasing counters)
 	if fieldName == "num_packets_tx" || fieldName == "num_packets_rx" || fieldName == "num_packets_rx_bad" ||
 		fieldName == "num_rx_dupe" || fieldName == "num_tx_relay" || fieldName == "num_tx_relay_canceled" {
-		return createPacketCountModel()
+		return sharedPacketCountModel()
 	}
 
 	// Node counts (small values, typically 0-100)
 	if fieldName == "num_online_nodes" || fieldName == "num_total_nodes" {
-		return createNodeCountModel()
+		return sharedNodeCountModel()
 	}
 
 	// Memory metrics (bytes, varying sizes)
 	if fieldName == "heap_total_bytes" || fieldName == "heap_free_bytes" {
-		return createMemoryBytesModel()
+		return sharedMemoryBytesModel()
 	}
 
 	// Host system memory/disk (large byte counts)
 	if fieldName == "freemem_bytes" || fieldName == "diskfree1_bytes" || fieldName == "diskfree2_bytes" || fieldName == "diskfree3_bytes" {
-		return createLargeMemoryModel()
+		return sharedLargeMemoryModel()
 	}
 
 	// System load (1/100ths, typically 0-1000)
 	if fieldName == "load1" || fieldName == "load5" || fieldName == "load15" {
-		return createLoadAverageModel()
+		return sharedLoadAverageModel()
 	}
 
 	// Timestamps (epoch seconds, monotonically increasing)
 	if fieldName == "time" || fieldName == "timestamp" || fieldName == "rx_time" || fieldName == "tx_after" {
-		return createTimestampModel()
+		return sharedTimestampModel()
 	}
 
 	// Timestamp millisecond adjustments (-999 to 999)
 	if fieldName == "timestamp_millis_adjust" {
-		return createMillisAdjustModel()
+		return sharedMillisAdjustModel()
 	}
 
 	// Priority levels (discrete values 0-127)
 	if fieldName == "priority" {
-		return createPriorityModel()
+		return sharedPriorityModel()
 	}
 
 	// Waypoint/message IDs (sequential, small values)
 	if fieldName == "waypoint_id" || fieldName == "emoji" {
-		return createWaypointIDModel()
+		return sharedWaypointIDModel()
 	}
 
 	// Expire time (epoch seconds in future)
 	if fieldName == "expire" {
-		return createExpireTimeModel()
+		return sharedExpireTimeModel()
 	}
 
 	return nil
@@ -317,18 +423,14 @@ func (mcb *ContextualModelBuilder) createContextSpecificModel(fieldPath string,
 // createCoordinateModel creates a model for latitude/longitude values.
 // Coordinates are stored as int32 with 1e-7 degree precision.
 // Typical range: -1800000000 to 1800000000 (±180°)
-func createCoordinateModel() arithcode.Model {
+func createCoordinateModel() coder.Model {
 	// Favor mid-range bytes for coordinate values
-	freqs := make([]uint64, 256)
-	for i := 0; i < 256; i++ {
-		// Coordinates have fairly uniform byte distribution
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	// Coordinates have fairly uniform byte distribution
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createAltitudeModel creates a model for altitude values (-500 to 9000m typical).
-func createAltitudeModel() arithcode.Model {
+func createAltitudeModel() coder.Model {
 	freqs := make([]uint64, 256)
 	// Favor smaller varint bytes (most altitudes fit in 2 bytes when varint encoded)
 	for i := 0; i < 128; i++ {
@@ -340,99 +442,63 @@ func createAltitudeModel() arithcode.Model {
 	for i := 128; i < 256; i++ {
 		freqs[i] = 15
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createNodeIDModel creates a model for node IDs (typically large 32-bit values).
-func createNodeIDModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createNodeIDModel() coder.Model {
 	// Node IDs use full 32-bit range, relatively uniform
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createBatteryLevelModel creates a model for battery percentage (0-100).
-func createBatteryLevelModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createBatteryLevelModel() coder.Model {
 	// Battery levels 0-100, favor higher values (most devices well charged)
-	// First varint byte will be 0-100
-	for i := 0; i <= 100; i++ {
-		// Favor 50-100% range
-		if i >= 50 {
-			freqs[i] = 100
-		} else if i >= 20 {
-			freqs[i] = 50
-		} else {
-			freqs[i] = 20
-		}
-	}
-	// Continuation bytes unlikely
-	for i := 101; i < 128; i++ {
-		freqs[i] = 1
-	}
-	for i := 128; i < 256; i++ {
-		freqs[i] = 1
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	// First varint byte will be 0-100, continuation bytes unlikely
+	return models.NewShapedModel(256, models.Mixture(
+		models.Weighted(20, models.Uniform(0, 19)),
+		models.Weighted(50, models.Uniform(20, 49)),
+		models.Weighted(100, models.Uniform(50, 100)),
+	))
 }
 
 // createRSSIModel creates a model for RSSI values (-120 to -30 dBm).
 // Stored as sint32 using zigzag encoding.
-func createRSSIModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createRSSIModel() coder.Model {
 	// RSSI typically -120 to -30, zigzag encoded
 	// Zigzag: -1→1, -2→3, -30→59, -95→189
 	// After varint, first byte has continuation bit
-	for i := 0; i < 128; i++ {
+	return models.NewShapedModel(256, models.Mixture(
 		// Small negative values are common (-30 to -50)
-		freqs[i] = 50
-	}
-	for i := 128; i < 240; i++ {
+		models.Weighted(50, models.Uniform(0, 127)),
 		// Larger negative values (-51 to -95)
-		freqs[i] = 80
-	}
-	for i := 240; i < 256; i++ {
+		models.Weighted(80, models.Uniform(128, 239)),
 		// Very weak signals
-		freqs[i] = 30
-	}
-	return arithcode.NewFrequencyTable(freqs)
+		models.Weighted(30, models.Uniform(240, 255)),
+	))
 }
 
 // createSNRModel creates a model for SNR values (-20 to +20 dB).
 // Stored as float32, but we model the byte distribution.
-func createSNRModel() arithcode.Model {
+func createSNRModel() coder.Model {
 	// SNR as float uses 4 bytes, uniform distribution
-	freqs := make([]uint64, 256)
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createVoltageModel creates a model for voltage values (2.0-5.0V).
-func createVoltageModel() arithcode.Model {
+func createVoltageModel() coder.Model {
 	// Voltage as float32, 4 bytes
-	freqs := make([]uint64, 256)
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createUtilizationModel creates a model for channel utilization (0-100%).
-func createUtilizationModel() arithcode.Model {
+func createUtilizationModel() coder.Model {
 	// Utilization as float32, but typically low values (0-30%)
-	freqs := make([]uint64, 256)
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createHopCountModel creates a model for hop counts (0-7 typically).
-func createHopCountModel() arithcode.Model {
+func createHopCountModel() coder.Model {
 	freqs := make([]uint64, 256)
 	// Hops are small (0-7), heavily favor small values
 	for i := 0; i <= 7; i++ {
@@ -444,42 +510,29 @@ func createHopCountModel() arithcode.Model {
 	for i := 128; i < 256; i++ {
 		freqs[i] = 1
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createChannelNumberModel creates a model for channel numbers (0-7).
-func createChannelNumberModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createChannelNumberModel() coder.Model {
 	// Channel 0 is most common (default)
-	freqs[0] = 200
-	for i := 1; i <= 7; i++ {
-		freqs[i] = 50
-	}
-	for i := 8; i < 256; i++ {
-		freqs[i] = 1
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Mixture(
+		models.Weighted(200, models.Uniform(0, 0)),
+		models.Weighted(50, models.Uniform(1, 7)),
+	))
 }
 
 // createSatelliteCountModel creates a model for satellite counts (0-20).
-func createSatelliteCountModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createSatelliteCountModel() coder.Model {
 	// Typical GPS sees 4-12 satellites
-	for i := 0; i <= 20; i++ {
-		if i >= 4 && i <= 12 {
-			freqs[i] = 100
-		} else {
-			freqs[i] = 30
-		}
-	}
-	for i := 21; i < 256; i++ {
-		freqs[i] = 1
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Mixture(
+		models.Weighted(30, models.Uniform(0, 20)),
+		models.Weighted(70, models.Uniform(4, 12)),
+	))
 }
 
 // createPrecisionModel creates a model for precision/accuracy values.
-func createPrecisionModel() arithcode.Model {
+func createPrecisionModel() coder.Model {
 	freqs := make([]uint64, 256)
 	// Small positive integers, typically 1-20
 	for i := 0; i <= 20; i++ {
@@ -491,11 +544,11 @@ func createPrecisionModel() arithcode.Model {
 	for i := 21; i < 256; i++ {
 		freqs[i] = 5
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createDOPModel creates a model for DOP values (10-1000 representing 1.0-100.0).
-func createDOPModel() arithcode.Model {
+func createDOPModel() coder.Model {
 	freqs := make([]uint64, 256)
 	// DOP values typically 10-300 (1.0-30.0)
 	// Favor smaller values (good signal)
@@ -508,11 +561,11 @@ func createDOPModel() arithcode.Model {
 	for i := 128; i < 256; i++ {
 		freqs[i] = 20
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createSpeedModel creates a model for ground speed (0-200 km/h).
-func createSpeedModel() arithcode.Model {
+func createSpeedModel() coder.Model {
 	freqs := make([]uint64, 256)
 	// Most nodes are stationary or slow moving
 	for i := 0; i <= 50; i++ {
@@ -524,11 +577,11 @@ func createSpeedModel() arithcode.Model {
 	for i := 51; i < 256; i++ {
 		freqs[i] = 5
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createRequestIDModel creates a model for request IDs (small sequential).
-func createRequestIDModel() arithcode.Model {
+func createRequestIDModel() coder.Model {
 	freqs := make([]uint64, 256)
 	// Small sequential values
 	for i := 0; i < 128; i++ {
@@ -540,52 +593,36 @@ func createRequestIDModel() arithcode.Model {
 	for i := 128; i < 256; i++ {
 		freqs[i] = 15
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createPacketIDModel creates a model for packet IDs.
-func createPacketIDModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createPacketIDModel() coder.Model {
 	// Packet IDs are larger but somewhat sequential
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createTemperatureModel creates a model for temperature (-40 to 85°C).
 // Stored as float32.
-func createTemperatureModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createTemperatureModel() coder.Model {
 	// Temperature range, float32 representation
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createHumidityModel creates a model for relative humidity (0-100%).
-func createHumidityModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createHumidityModel() coder.Model {
 	// Humidity as float32
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createPressureModel creates a model for barometric pressure (300-1100 hPa).
-func createPressureModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createPressureModel() coder.Model {
 	// Pressure as float32
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createIAQModel creates a model for Indoor Air Quality index (0-500).
-func createIAQModel() arithcode.Model {
+func createIAQModel() coder.Model {
 	freqs := make([]uint64, 256)
 	// IAQ typically 0-300, favor good air quality (0-150)
 	for i := 0; i <= 150; i++ {
@@ -597,7 +634,7 @@ func createIAQModel() arithcode.Model {
 	for i := 151; i < 256; i++ {
 		freqs[i] = 15
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // SetMessageType sets the current message type context for better model selection.
@@ -608,30 +645,44 @@ func (mcb *ContextualModelBuilder) SetMessageType(msgType string) {
 // GetBooleanModel returns a field-specific boolean model optimized for the given field.
 // Different boolean fields have different probability distributions - some are almost
 // always false, some are often true, etc.
-func (mcb *ContextualModelBuilder) GetBooleanModel(fieldName string) arithcode.Model {
+func (mcb *ContextualModelBuilder) GetBooleanModel(fieldName string) coder.Model {
 	// Check cache
 	if model, ok := mcb.booleanModels[fieldName]; ok {
 		return model
 	}
 
 	// Create field-specific boolean model
-	model := createBooleanModel(fieldName)
+	model := mcb.escapeModel(createBooleanModel(fieldName))
 	mcb.booleanModels[fieldName] = model
 	return model
 }
 
+// escapeModel wraps a static model so that it escapes to an adaptive model
+// after escapeLimit mispredictions, which decays at decayLimit. It returns the model unchanged when
+// escaping is disabled.
+func (mcb *ContextualModelBuilder) escapeModel(model coder.Model) coder.Model {
+	if mcb.escapeLimit <= 0 {
+		return model
+	}
+	escape := models.NewEscapeModel(model, mcb.escapeLimit)
+	if mcb.decayLimit > 0 {
+		escape.WithDecay(mcb.decayLimit)
+	}
+	return escape
+}
+
 // createBooleanModel creates a probability model for a specific boolean field.
 // The frequencies are [false, true] where higher values mean higher probability.
-func createBooleanModel(fieldName string) arithcode.Model {
+func createBooleanModel(fieldName string) coder.Model {
 	switch fieldName {
 	// Fields that are almost always false (95% false, 5% true)
 	case "want_ack", "via_mqtt", "pki_encrypted", "want_response":
-		return arithcode.NewFrequencyTable([]uint64{950, 50})
+		return models.NewFrequencyTable([]uint64{950, 50})
 
 	// Fields that are very rarely true (90% false, 10% true)
 	case "is_licensed", "is_unmessagable", "is_favorite", "is_ignored",
 		"is_key_manually_verified", "retained", "rebooted", "disconnect":
-		return arithcode.NewFrequencyTable([]uint64{900, 100})
+		return models.NewFrequencyTable([]uint64{900, 100})
 
 	// Configuration flags - usually disabled (80% false, 20% true)
 	case "enabled", "encryption_enabled", "json_enabled", "tls_enabled",
@@ -648,70 +699,82 @@ func createBooleanModel(fieldName string) arithcode.Model {
 		"device_telemetry_enabled", "rotary1_enabled", "updown1_enabled",
 		"led_state", "screen_lock", "settings_lock", "alert_enabled",
 		"banner_enabled", "is_clockface_analog", "follow_gps":
-		return arithcode.NewFrequencyTable([]uint64{800, 200})
+		return models.NewFrequencyTable([]uint64{800, 200})
 
 	// Display/UI settings - often true (40% false, 60% true)
 	case "gps_enabled", "wifi_enabled", "eth_enabled", "ipv6_enabled",
 		"flip_screen", "heading_bold", "wake_on_tap_or_motion",
 		"use_12h_clock", "use_long_node_name", "position_broadcast_smart_enabled",
 		"fixed_position", "is_power_saving":
-		return arithcode.NewFrequencyTable([]uint64{400, 600})
+		return models.NewFrequencyTable([]uint64{400, 600})
 
 	// Fields with specific biases based on Meshtastic usage patterns
 	case "use_preset":
 		// Most users use presets (30% false, 70% true)
-		return arithcode.NewFrequencyTable([]uint64{300, 700})
+		return models.NewFrequencyTable([]uint64{300, 700})
 
 	case "tx_enabled":
 		// Transmit usually enabled (20% false, 80% true)
-		return arithcode.NewFrequencyTable([]uint64{200, 800})
+		return models.NewFrequencyTable([]uint64{200, 800})
 
 	case "override_duty_cycle", "sx126x_rx_boosted_gain", "pa_fan_disabled":
 		// Advanced settings rarely changed (85% false, 15% true)
-		return arithcode.NewFrequencyTable([]uint64{850, 150})
+		return models.NewFrequencyTable([]uint64{850, 150})
 
 	case "ignore_mqtt", "config_ok_to_mqtt":
 		// MQTT settings rarely used (90% false, 10% true)
-		return arithcode.NewFrequencyTable([]uint64{900, 100})
+		return models.NewFrequencyTable([]uint64{900, 100})
 
 	case "is_managed", "serial_enabled", "debug_log_api_enabled", "admin_channel_enabled":
 		// Admin/debug features rarely enabled (85% false, 15% true)
-		return arithcode.NewFrequencyTable([]uint64{850, 150})
+		return models.NewFrequencyTable([]uint64{850, 150})
 
 	case "uplink_enabled", "downlink_enabled":
 		// Channel settings (70% false, 30% true)
-		return arithcode.NewFrequencyTable([]uint64{700, 300})
+		return models.NewFrequencyTable([]uint64{700, 300})
 
 	case "is_muted":
 		// Channels rarely muted (90% false, 10% true)
-		return arithcode.NewFrequencyTable([]uint64{900, 100})
+		return models.NewFrequencyTable([]uint64{900, 100})
 
 	case "canShutdown", "hasWifi", "hasBluetooth", "hasEthernet",
 		"hasRemoteHardware", "hasPKC":
 		// Device capabilities vary widely (50% false, 50% true - conservative)
-		return arithcode.NewFrequencyTable([]uint64{500, 500})
+		return models.NewFrequencyTable([]uint64{500, 500})
+
+	case "channel_index_implicit":
+		// Channel tables are listed in index order
+		return models.NewFrequencyTable([]uint64{50, 950})
+
+	case "firmware_version_structured":
+		// Firmware versions almost always follow the release pattern
+		return models.NewFrequencyTable([]uint64{50, 950})
+
+	case "unknown_fields":
+		// Only messages of newer firmware have unknown fields (99% false, 1% true)
+		return models.NewFrequencyTable([]uint64{990, 10})
 
 	case "request_transfer", "accept_transfer":
 		// File transfers rare (95% false, 5% true)
-		return arithcode.NewFrequencyTable([]uint64{950, 50})
+		return models.NewFrequencyTable([]uint64{950, 50})
 
 	case "double_tap_as_button_press", "disable_triple_click", "led_heartbeat_disabled":
 		// Button/LED settings (70% false, 30% true)
-		return arithcode.NewFrequencyTable([]uint64{700, 300})
+		return models.NewFrequencyTable([]uint64{700, 300})
 
 	case "compass_north_top":
 		// Display orientation (60% false, 40% true)
-		return arithcode.NewFrequencyTable([]uint64{600, 400})
+		return models.NewFrequencyTable([]uint64{600, 400})
 
 	case "unknown_switch", "offline_switch", "public_key_switch",
 		"position_switch", "chat_switch", "telemetry_switch", "iaq_switch":
 		// UI switches - context dependent (65% false, 35% true)
-		return arithcode.NewFrequencyTable([]uint64{650, 350})
+		return models.NewFrequencyTable([]uint64{650, 350})
 
 	default:
 		// Conservative default: slightly biased toward false (60% false, 40% true)
 		// This is better than uniform 50/50 for most boolean fields in protocols
-		return arithcode.NewFrequencyTable([]uint64{600, 400})
+		return models.NewFrequencyTable([]uint64{600, 400})
 	}
 }
 
@@ -721,7 +784,7 @@ func createBooleanModel(fieldName string) arithcode.Model {
 // - Bits 0-6 contain the value
 // - Small values (0-127) fit in one byte with bit 7 = 0
 // - Larger values need multiple bytes with bit 7 = 1
-func createVarintFirstByteModel() arithcode.Model {
+func createVarintFirstByteModel() coder.Model {
 	freqs := make([]uint64, 256)
 
 	// Bytes 0-127: Terminal bytes (no continuation)
@@ -745,13 +808,13 @@ func createVarintFirstByteModel() arithcode.Model {
 		freqs[i] = 15
 	}
 
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createVarintContinuationByteModel creates a probability model for continuation bytes.
 // Continuation bytes appear after the first byte in multi-byte varints.
 // They follow a different pattern than the first byte.
-func createVarintContinuationByteModel() arithcode.Model {
+func createVarintContinuationByteModel() coder.Model {
 	freqs := make([]uint64, 256)
 
 	// Bytes 0-127: Last continuation byte (bit 7 = 0)
@@ -771,12 +834,12 @@ func createVarintContinuationByteModel() arithcode.Model {
 		freqs[i] = 20
 	}
 
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // GetVarintByteModel returns the appropriate model for a varint byte.
 // byteIndex: 0 for first byte, 1+ for continuation bytes
-func (mcb *ContextualModelBuilder) GetVarintByteModel(byteIndex int) arithcode.Model {
+func (mcb *ContextualModelBuilder) GetVarintByteModel(byteIndex int) coder.Model {
 	if byteIndex == 0 {
 		return mcb.varintFirstByteModel
 	}
@@ -787,144 +850,88 @@ func (mcb *ContextualModelBuilder) GetVarintByteModel(byteIndex int) arithcode.M
 
 // createSNRArrayModel creates a model for SNR arrays in routing messages.
 // Values are int32 but typically -20 to +20 dB, scaled by 4.
-func createSNRArrayModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createSNRArrayModel() coder.Model {
 	// SNR values -20 to +20 dB, scaled by 4 = -80 to +80
 	// After zigzag encoding and varint
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createChannelVoltageModel creates a model for power monitoring channel voltages.
 // Wider range than battery voltage (0-50V typical).
-func createChannelVoltageModel() arithcode.Model {
-	freqs := make([]uint64, 256)
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+func createChannelVoltageModel() coder.Model {
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createChannelCurrentModel creates a model for power monitoring channel currents.
 // Typically 0-10A, stored as float32.
-func createChannelCurrentModel() arithcode.Model {
-	freqs := make([]uint64, 256)
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+func createChannelCurrentModel() coder.Model {
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createGPSQualityModel creates a model for GPS fix quality/type indicators.
 // Small discrete values (0-9 typical).
-func createGPSQualityModel() arithcode.Model {
-	freqs := make([]uint64, 256)
-	for i := 0; i <= 9; i++ {
-		// Quality 1-3 most common (no fix, 2D, 3D)
-		if i >= 1 && i <= 3 {
-			freqs[i] = 150
-		} else {
-			freqs[i] = 30
-		}
-	}
-	for i := 10; i < 256; i++ {
-		freqs[i] = 1
-	}
-	return arithcode.NewFrequencyTable(freqs)
+func createGPSQualityModel() coder.Model {
+	// Quality 1-3 most common (no fix, 2D, 3D)
+	return models.NewShapedModel(256, models.Mixture(
+		models.Weighted(30, models.Uniform(0, 9)),
+		models.Weighted(120, models.Uniform(1, 3)),
+	))
 }
 
 // createDirectionModel creates a model for direction/heading (0-35999 for 0-359.99 degrees).
-func createDirectionModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createDirectionModel() coder.Model {
 	// Direction values spread across range, relatively uniform
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createUptimeModel creates a model for uptime_seconds (monotonically increasing).
-func createUptimeModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createUptimeModel() coder.Model {
 	// Uptime can be very large, but changes slowly
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createGasResistanceModel creates a model for gas resistance (0-10000 kOhm).
-func createGasResistanceModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createGasResistanceModel() coder.Model {
 	// Gas resistance varies widely, float32
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createLuxModel creates a model for light measurements (wide range, 0-100000+ lux).
-func createLuxModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createLuxModel() coder.Model {
 	// Lux values vary widely (indoor vs outdoor)
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createDistanceModel creates a model for distance measurements (mm, 0-10000 typical).
-func createDistanceModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createDistanceModel() coder.Model {
 	// Distance in mm, float32, moderate range
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createWindSpeedModel creates a model for wind speed (m/s, 0-50 typical).
-func createWindSpeedModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createWindSpeedModel() coder.Model {
 	// Wind speed as float32, typically low values
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createRainfallModel creates a model for rainfall (mm, 0-100 typical).
-func createRainfallModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createRainfallModel() coder.Model {
 	// Rainfall as float32, usually small values
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createSoilMoistureModel creates a model for soil moisture (1-100%).
-func createSoilMoistureModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createSoilMoistureModel() coder.Model {
 	// Soil moisture 1-100, favor mid-range (healthy soil)
-	freqs[0] = 10 // 0 is possible but rare
-	for i := 1; i <= 100; i++ {
-		if i >= 20 && i <= 80 {
-			freqs[i] = 80
-		} else {
-			freqs[i] = 30
-		}
-	}
-	for i := 101; i < 256; i++ {
-		freqs[i] = 1
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Mixture(
+		models.Weighted(10, models.Uniform(0, 0)), // 0 is possible but rare
+		models.Weighted(30, models.Uniform(1, 100)),
+		models.Weighted(50, models.Uniform(20, 80)),
+	))
 }
 
 // createParticulateModel creates a model for PM values (ug/m3, 0-500 typical).
-func createParticulateModel() arithcode.Model {
+func createParticulateModel() coder.Model {
 	freqs := make([]uint64, 256)
 	// PM values usually low (good air quality), favor 0-100
 	for i := 0; i <= 100; i++ {
@@ -936,21 +943,17 @@ func createParticulateModel() arithcode.Model {
 	for i := 101; i < 256; i++ {
 		freqs[i] = 15
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createParticleCountModel creates a model for particle counts (#/0.1l).
-func createParticleCountModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createParticleCountModel() coder.Model {
 	// Particle counts vary widely, stored as uint32
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createCO2Model creates a model for CO2 (ppm, 400-5000 typical).
-func createCO2Model() arithcode.Model {
+func createCO2Model() coder.Model {
 	freqs := make([]uint64, 256)
 	// CO2 typically 400-2000 ppm, favor lower (outdoor/ventilated)
 	// As uint32, values fit in 2 varint bytes
@@ -963,31 +966,23 @@ func createCO2Model() arithcode.Model {
 	for i := 128; i < 256; i++ {
 		freqs[i] = 20
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createFormaldehydeModel creates a model for formaldehyde (mg/m3, 0-1.0 typical).
-func createFormaldehydeModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createFormaldehydeModel() coder.Model {
 	// Formaldehyde as float32, low values
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createVOCNOxModel creates a model for VOC/NOx indices (0-500).
-func createVOCNOxModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createVOCNOxModel() coder.Model {
 	// VOC/NOx indices as float32, favor lower values (good air)
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createHeartRateModel creates a model for heart rate (40-200 bpm).
-func createHeartRateModel() arithcode.Model {
+func createHeartRateModel() coder.Model {
 	freqs := make([]uint64, 256)
 	// Heart rate 40-200, favor normal range 60-100
 	for i := 40; i <= 200; i++ {
@@ -1003,11 +998,11 @@ func createHeartRateModel() arithcode.Model {
 	for i := 201; i < 256; i++ {
 		freqs[i] = 1
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createSpO2Model creates a model for blood oxygen saturation (95-100%).
-func createSpO2Model() arithcode.Model {
+func createSpO2Model() coder.Model {
 	freqs := make([]uint64, 256)
 	// SpO2 typically 95-100%, heavily favor 98-100%
 	for i := 95; i <= 100; i++ {
@@ -1023,21 +1018,17 @@ func createSpO2Model() arithcode.Model {
 	for i := 101; i < 256; i++ {
 		freqs[i] = 1
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createPacketCountModel creates a model for packet counters (monotonically increasing).
-func createPacketCountModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createPacketCountModel() coder.Model {
 	// Packet counts increase over time, stored as uint32
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createNodeCountModel creates a model for node counts (0-100 typical).
-func createNodeCountModel() arithcode.Model {
+func createNodeCountModel() coder.Model {
 	freqs := make([]uint64, 256)
 	// Node counts typically small (0-50)
 	for i := 0; i <= 100; i++ {
@@ -1049,31 +1040,23 @@ func createNodeCountModel() arithcode.Model {
 	for i := 101; i < 256; i++ {
 		freqs[i] = 5
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createMemoryBytesModel creates a model for heap memory (bytes, KB to MB range).
-func createMemoryBytesModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createMemoryBytesModel() coder.Model {
 	// Memory sizes vary, stored as uint32
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createLargeMemoryModel creates a model for large memory/disk (GB range).
-func createLargeMemoryModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createLargeMemoryModel() coder.Model {
 	// Large memory as uint64, wide range
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createLoadAverageModel creates a model for system load (1/100ths, 0-1000 typical).
-func createLoadAverageModel() arithcode.Model {
+func createLoadAverageModel() coder.Model {
 	freqs := make([]uint64, 256)
 	// Load average usually low (0-400 = 0-4.0)
 	for i := 0; i < 128; i++ {
@@ -1085,21 +1068,17 @@ func createLoadAverageModel() arithcode.Model {
 	for i := 128; i < 256; i++ {
 		freqs[i] = 20
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createTimestampModel creates a model for timestamps (epoch seconds).
-func createTimestampModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createTimestampModel() coder.Model {
 	// Timestamps are large but change slowly, good for delta encoding
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
 
 // createMillisAdjustModel creates a model for millisecond adjustments (-999 to 999).
-func createMillisAdjustModel() arithcode.Model {
+func createMillisAdjustModel() coder.Model {
 	freqs := make([]uint64, 256)
 	// Millis adjust as int32 zigzag, small range
 	// Most values will be 0 or very small
@@ -1112,11 +1091,11 @@ func createMillisAdjustModel() arithcode.Model {
 	for i := 50; i < 256; i++ {
 		freqs[i] = 15
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createPriorityModel creates a model for priority levels (discrete 0-127).
-func createPriorityModel() arithcode.Model {
+func createPriorityModel() coder.Model {
 	freqs := make([]uint64, 256)
 	// Priority values: common ones are 10 (BACKGROUND), 64 (DEFAULT), 70 (RELIABLE), 120 (ACK)
 	priorityValues := map[int]uint64{
@@ -1132,11 +1111,11 @@ func createPriorityModel() arithcode.Model {
 	for i := 128; i < 256; i++ {
 		freqs[i] = 1
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createWaypointIDModel creates a model for waypoint/message IDs (sequential, small).
-func createWaypointIDModel() arithcode.Model {
+func createWaypointIDModel() coder.Model {
 	freqs := make([]uint64, 256)
 	// Small sequential IDs
 	for i := 0; i < 128; i++ {
@@ -1148,15 +1127,11 @@ func createWaypointIDModel() arithcode.Model {
 	for i := 128; i < 256; i++ {
 		freqs[i] = 20
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // createExpireTimeModel creates a model for expire timestamps (future epoch seconds).
-func createExpireTimeModel() arithcode.Model {
-	freqs := make([]uint64, 256)
+func createExpireTimeModel() coder.Model {
 	// Expire times are timestamps in the future
-	for i := 0; i < 256; i++ {
-		freqs[i] = 40
-	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewShapedModel(256, models.Weighted(40, models.Uniform(0, 255)))
 }
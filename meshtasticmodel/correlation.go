@@ -0,0 +1,175 @@
+package meshtasticmodel
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
+)
+
+// Some fields of a message measure nearly the same quantity: an air quality
+// sensor reports PM1.0, PM2.5 and PM10 as standard and environmental
+// concentrations, and the six values move together. V14 codes such fields
+// as a correlation group, before the other fields of the message. Each
+// field of the group is coded as the signed difference from its base, a
+// field coded before it, which takes a few bits instead of a full value.
+// Fields without a base, or whose base is not present, are coded like other
+// fields.
+
+// fieldCorrelation is a correlation group of uint32 fields of a message, in
+// coding order.
+type fieldCorrelation []correlatedField
+
+// correlatedField is a field of a correlation group and the field its value
+// is predicted from, empty for none.
+type correlatedField struct {
+	name protoreflect.Name
+	base protoreflect.Name
+}
+
+// fieldCorrelations are the correlation groups of the messages.
+//
+// The environmental concentrations equal the standard ones at low
+// concentrations, and the PM1.0 and PM10 concentrations follow PM2.5.
+var fieldCorrelations = map[protoreflect.FullName]fieldCorrelation{
+	"meshtastic.AirQualityMetrics": {
+		{name: "pm25_standard"},
+		{name: "pm10_standard", base: "pm25_standard"},
+		{name: "pm100_standard", base: "pm25_standard"},
+		{name: "pm25_environmental", base: "pm25_standard"},
+		{name: "pm10_environmental", base: "pm10_standard"},
+		{name: "pm100_environmental", base: "pm100_standard"},
+	},
+}
+
+// correlationOf returns the correlation group of md, or nil when md has none
+// or mcb does not code correlation groups.
+func (mcb *ContextualModelBuilder) correlationOf(md protoreflect.MessageDescriptor) fieldCorrelation {
+	if !mcb.correlatedFields {
+		return nil
+	}
+	return fieldCorrelations[md.FullName()]
+}
+
+// has returns whether the field belongs to the group.
+func (group fieldCorrelation) has(name protoreflect.Name) bool {
+	for _, field := range group {
+		if field.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedCorrelationResidualModel is the prior of the bit lengths of the
+// residuals of correlated fields; most differences are zero or fit in a few
+// bits.
+var sharedCorrelationResidualModel = sync.OnceValue(func() *models.FrequencyTable {
+	freqs := make([]uint64, 65)
+	for i := range freqs {
+		switch {
+		case i == 0:
+			freqs[i] = 120
+		case i <= 6:
+			freqs[i] = 40
+		case i <= 10:
+			freqs[i] = 10
+		default:
+			freqs[i] = 1
+		}
+	}
+	model := models.NewFrequencyTable(freqs)
+	model.SetMaxTotal(residualMaxTotal)
+	return model
+})
+
+// compressCorrelatedFields compresses the fields of group in msg.
+func compressCorrelatedFields(fieldPath string, msg protoreflect.Message, group fieldCorrelation, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	fields := msg.Descriptor().Fields()
+	lengths := sharedCorrelationResidualModel().Clone()
+	for _, field := range group {
+		fd := fields.ByName(field.name)
+		present := msg.Has(fd)
+		if err := encodeCorrelatedPresence(fd, present, enc, mcb); err != nil {
+			return err
+		}
+		if !present {
+			continue
+		}
+
+		if field.base == "" || !msg.Has(fields.ByName(field.base)) {
+			if err := compressFieldValueV10(pbmodel.BuildFieldPath(fieldPath, string(field.name)), fd, msg.Get(fd), enc, mcb); err != nil {
+				return fmt.Errorf("field %s: %w", field.name, err)
+			}
+			continue
+		}
+		base := int64(msg.Get(fields.ByName(field.base)).Uint())
+		if err := encodeResidual(int64(msg.Get(fd).Uint())-base, lengths, enc); err != nil {
+			return fmt.Errorf("field %s: %w", field.name, err)
+		}
+	}
+	return nil
+}
+
+// decompressCorrelatedFields decompresses the fields of group into msg.
+func decompressCorrelatedFields(fieldPath string, msg protoreflect.Message, group fieldCorrelation, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
+	fields := msg.Descriptor().Fields()
+	lengths := sharedCorrelationResidualModel().Clone()
+	for _, field := range group {
+		fd := fields.ByName(field.name)
+		present, err := decodeCorrelatedPresence(fd, dec, mcb)
+		if err != nil {
+			return err
+		}
+		if !present {
+			continue
+		}
+
+		if field.base == "" || !msg.Has(fields.ByName(field.base)) {
+			value, err := decompressFieldValueV10(pbmodel.BuildFieldPath(fieldPath, string(field.name)), fd, dec, mcb)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", field.name, err)
+			}
+			msg.Set(fd, value)
+			continue
+		}
+		residual, err := decodeResidual(lengths, dec)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.name, err)
+		}
+		value := int64(msg.Get(fields.ByName(field.base)).Uint()) + residual
+		if value < 0 || value > math.MaxUint32 {
+			return fmt.Errorf("field %s: %d: %w", field.name, value, pbmodel.ErrOutOfRange)
+		}
+		msg.Set(fd, protoreflect.ValueOfUint32(uint32(value)))
+	}
+	return nil
+}
+
+// encodeCorrelatedPresence encodes the presence of a field of a correlation
+// group with the presence model of the field.
+func encodeCorrelatedPresence(fd protoreflect.FieldDescriptor, present bool, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	bit := 0
+	if present {
+		bit = 1
+	}
+	if err := models.EncodeEscaped(enc, bit, mcb.GetBooleanModel(string(fd.Name())+"_presence")); err != nil {
+		return fmt.Errorf("field %s presence: %w", fd.Name(), err)
+	}
+	return nil
+}
+
+// decodeCorrelatedPresence decodes the presence of a field of a correlation
+// group.
+func decodeCorrelatedPresence(fd protoreflect.FieldDescriptor, dec *coder.Decoder, mcb *ContextualModelBuilder) (bool, error) {
+	bit, err := models.DecodeEscaped(dec, mcb.GetBooleanModel(string(fd.Name())+"_presence"))
+	if err != nil {
+		return false, fmt.Errorf("field %s presence: %w", fd.Name(), err)
+	}
+	return bit == 1, nil
+}
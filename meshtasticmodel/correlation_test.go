@@ -0,0 +1,80 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestMeshtasticV14AirQuality(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics *meshtastic.AirQualityMetrics
+		smaller bool
+	}{
+		{"all", &meshtastic.AirQualityMetrics{
+			Pm10Standard:       proto.Uint32(25),
+			Pm25Standard:       proto.Uint32(31),
+			Pm100Standard:      proto.Uint32(38),
+			Pm10Environmental:  proto.Uint32(24),
+			Pm25Environmental:  proto.Uint32(30),
+			Pm100Environmental: proto.Uint32(36),
+			Particles_03Um:     proto.Uint32(1500),
+			Particles_05Um:     proto.Uint32(850),
+		}, true},
+		{"clean", &meshtastic.AirQualityMetrics{
+			Pm10Standard:       proto.Uint32(6),
+			Pm25Standard:       proto.Uint32(9),
+			Pm100Standard:      proto.Uint32(11),
+			Pm10Environmental:  proto.Uint32(6),
+			Pm25Environmental:  proto.Uint32(9),
+			Pm100Environmental: proto.Uint32(11),
+		}, true},
+		{"polluted", &meshtastic.AirQualityMetrics{
+			Pm10Standard:       proto.Uint32(180),
+			Pm25Standard:       proto.Uint32(240),
+			Pm100Standard:      proto.Uint32(310),
+			Pm10Environmental:  proto.Uint32(150),
+			Pm25Environmental:  proto.Uint32(200),
+			Pm100Environmental: proto.Uint32(260),
+		}, true},
+		{"no base", &meshtastic.AirQualityMetrics{
+			Pm10Standard:  proto.Uint32(12),
+			Pm100Standard: proto.Uint32(20),
+		}, false},
+		{"partial", &meshtastic.AirQualityMetrics{
+			Pm25Standard:      proto.Uint32(0),
+			Pm10Environmental: proto.Uint32(4000000000),
+		}, false},
+		{"empty", &meshtastic.AirQualityMetrics{}, false},
+	}
+	for _, test := range tests {
+		telemetry := &meshtastic.Telemetry{
+			Time:    1703520000,
+			Variant: &meshtastic.Telemetry_AirQualityMetrics{AirQualityMetrics: test.metrics},
+		}
+
+		var bufV13, bufV14 bytes.Buffer
+		if err := CompressV13(telemetry, &bufV13); err != nil {
+			t.Fatalf("%s: V13 compress failed: %v", test.name, err)
+		}
+		if err := CompressV14(telemetry, &bufV14); err != nil {
+			t.Fatalf("%s: V14 compress failed: %v", test.name, err)
+		}
+		t.Logf("%s: V13: %d bytes, V14: %d bytes", test.name, bufV13.Len(), bufV14.Len())
+		if test.smaller && bufV14.Len() >= bufV13.Len() {
+			t.Errorf("%s: V14 (%d bytes) should be smaller than V13 (%d bytes)", test.name, bufV14.Len(), bufV13.Len())
+		}
+
+		result := &meshtastic.Telemetry{}
+		if err := DecompressV14(&bufV14, result); err != nil {
+			t.Fatalf("%s: V14 decompress failed: %v", test.name, err)
+		}
+		if !proto.Equal(telemetry, result) {
+			t.Errorf("%s: V14 roundtrip verification failed\noriginal: %v\ndecoded:  %v", test.name, telemetry, result)
+		}
+	}
+}
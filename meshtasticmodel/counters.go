@@ -0,0 +1,189 @@
+package meshtasticmodel
+
+import (
+	"cmp"
+	"fmt"
+	"hash"
+	"maps"
+	"math"
+	"math/bits"
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// Uptimes and packet counters only grow between reboots of a node, and grow
+// by about as much between reports. With SessionOptions.MonotonicCounters,
+// sessions remember the counters reported by each node in the epoch and code
+// a new report as the increase since the previous one, with an adaptive
+// model of its bit length. A smaller value means the node rebooted or reset
+// its counters; it is coded in full, like a first report.
+//
+// Counters are attributed to the node of the enclosing MeshPacket or
+// NodeInfo, and to node 0 in messages without one, such as a Telemetry
+// message sent by the node at the other end of the link.
+
+// maxCounterValues limits the counters remembered by a session; all of them
+// are forgotten when the limit is reached.
+const maxCounterValues = 1 << 10
+
+// monotonicCounters are the names of the uint32 fields that only grow
+// between resets.
+var monotonicCounters = map[protoreflect.Name]bool{
+	"uptime_seconds":        true,
+	"num_packets_tx":        true,
+	"num_packets_rx":        true,
+	"num_packets_rx_bad":    true,
+	"num_rx_dupe":           true,
+	"num_tx_relay":          true,
+	"num_tx_relay_canceled": true,
+	"num_tx_dropped":        true,
+}
+
+// counterState holds the previous counter values of the nodes of a session
+// epoch.
+type counterState struct {
+	node     uint32                // Node of the message being coded
+	previous map[counterKey]uint64 // Previous value of each counter
+}
+
+// counterKey identifies a counter of a node.
+type counterKey struct {
+	node  uint32
+	field protoreflect.FullName
+}
+
+func newCounterState() *counterState {
+	return &counterState{previous: make(map[counterKey]uint64)}
+}
+
+// clone returns an independent copy of s.
+func (s *counterState) clone() *counterState {
+	return &counterState{node: s.node, previous: maps.Clone(s.previous)}
+}
+
+// record remembers value as the last value of the counter.
+func (s *counterState) record(key counterKey, value uint64) {
+	if _, ok := s.previous[key]; !ok && len(s.previous) >= maxCounterValues {
+		clear(s.previous)
+	}
+	s.previous[key] = value
+}
+
+// hashState writes the previous counter values to h.
+func (s *counterState) hashState(h hash.Hash) {
+	keys := slices.Collect(maps.Keys(s.previous))
+	slices.SortFunc(keys, func(a, b counterKey) int {
+		return cmp.Or(cmp.Compare(a.node, b.node), cmp.Compare(a.field, b.field))
+	})
+	for _, key := range keys {
+		fmt.Fprintf(h, "%d:%s:%d\x00", key.node, key.field, s.previous[key])
+	}
+}
+
+// observeNode records the node of the message being coded when fd holds
+// it.
+func (mcb *ContextualModelBuilder) observeNode(md protoreflect.MessageDescriptor, fd protoreflect.FieldDescriptor, value protoreflect.Value) {
+	if mcb.counters == nil {
+		return
+	}
+	switch {
+	case md.Name() == "MeshPacket" && fd.Name() == "from",
+		md.Name() == "NodeInfo" && fd.Name() == "num":
+		mcb.counters.node = uint32(value.Uint())
+	}
+}
+
+// counterKeyOf returns the key of the counter of the current node in fd,
+// or false when fd is not a counter or mcb does not track counters.
+func (mcb *ContextualModelBuilder) counterKeyOf(fd protoreflect.FieldDescriptor) (counterKey, bool) {
+	if mcb.counters == nil || fd.Kind() != protoreflect.Uint32Kind || fd.IsList() || !monotonicCounters[fd.Name()] {
+		return counterKey{}, false
+	}
+	return counterKey{node: mcb.counters.node, field: fd.FullName()}, true
+}
+
+// counterIncreaseModel returns the adaptive model of the bit lengths of the
+// increases of a counter field. Increases are mostly small, so the prior
+// favors short lengths.
+func (mcb *ContextualModelBuilder) counterIncreaseModel(field protoreflect.FullName) *models.FrequencyTable {
+	name := "counter_increase:" + string(field)
+	if model, ok := mcb.contextModels[name]; ok {
+		return model.(*models.FrequencyTable)
+	}
+	freqs := make([]uint64, 33)
+	for n := range freqs {
+		freqs[n] = 1 + 16>>min(n/4, 8)
+	}
+	model := models.NewFrequencyTable(freqs)
+	model.SetMaxTotal(residualMaxTotal)
+	mcb.contextModels[name] = model
+	return model
+}
+
+// encodeCounter encodes the value of a counter as its increase since the
+// previous value of the node, or in full after a reset.
+func encodeCounter(key counterKey, value uint64, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	previous, ok := mcb.counters.previous[key]
+	mcb.counters.record(key, value)
+	if ok {
+		increased := value >= previous
+		if err := models.EncodeEscaped(enc, boolSymbol(increased), mcb.GetBooleanModel("counter_increased")); err != nil {
+			return err
+		}
+		if increased {
+			lengths := mcb.counterIncreaseModel(key.field)
+			n := bits.Len64(value - previous)
+			if err := enc.Encode(n, lengths); err != nil {
+				return err
+			}
+			lengths.Add(n, residualIncrement)
+			return encodeLowBits(value-previous, n, enc)
+		}
+	}
+	return encodeVarintWithModels(value, enc, mcb)
+}
+
+// decodeCounter decodes a counter written by encodeCounter.
+func decodeCounter(key counterKey, dec *coder.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
+	value, err := decodeCounterValue(key, dec, mcb)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	if value > math.MaxUint32 {
+		return protoreflect.Value{}, fmt.Errorf("counter %d out of range", value)
+	}
+	mcb.counters.record(key, value)
+	return protoreflect.ValueOfUint32(uint32(value)), nil
+}
+
+// decodeCounterValue decodes the value of a counter without range checks.
+func decodeCounterValue(key counterKey, dec *coder.Decoder, mcb *ContextualModelBuilder) (uint64, error) {
+	previous, ok := mcb.counters.previous[key]
+	if ok {
+		increased, err := models.DecodeEscaped(dec, mcb.GetBooleanModel("counter_increased"))
+		if err != nil {
+			return 0, err
+		}
+		if increased == 1 {
+			lengths := mcb.counterIncreaseModel(key.field)
+			n, err := dec.Decode(lengths)
+			if err != nil {
+				return 0, err
+			}
+			if n > 32 {
+				return 0, fmt.Errorf("counter increase bit length %d out of range", n)
+			}
+			lengths.Add(n, residualIncrement)
+			increase, err := decodeLowBits(n, dec)
+			if err != nil {
+				return 0, err
+			}
+			return previous + increase, nil
+		}
+	}
+	return decodeVarintWithModels(dec, mcb)
+}
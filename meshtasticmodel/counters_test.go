@@ -0,0 +1,108 @@
+package meshtasticmodel
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+// counterSession returns the reports of two nodes seen in node infos and of
+// the local node in telemetry, fifteen minutes apart. The local node
+// reboots halfway through.
+func counterSession() []proto.Message {
+	var msgs []proto.Message
+	for i := range uint32(16) {
+		for _, node := range []struct{ num, uptime uint32 }{{0x1234abcd, 86400}, {0x5678ef01, 3600}} {
+			msgs = append(msgs, &meshtastic.FromRadio{
+				PayloadVariant: &meshtastic.FromRadio_NodeInfo{NodeInfo: &meshtastic.NodeInfo{
+					Num: node.num,
+					DeviceMetrics: &meshtastic.DeviceMetrics{
+						BatteryLevel:  proto.Uint32(90),
+						UptimeSeconds: proto.Uint32(node.uptime + i*900),
+					},
+				}},
+			})
+		}
+
+		start := i
+		if i >= 8 {
+			start = i - 8
+		}
+		msgs = append(msgs, &meshtastic.Telemetry{
+			Time: 1700000000 + i*900,
+			Variant: &meshtastic.Telemetry_LocalStats{LocalStats: &meshtastic.LocalStats{
+				UptimeSeconds:   60 + start*900,
+				NumPacketsTx:    10 + start*7,
+				NumPacketsRx:    30 + start*41,
+				NumPacketsRxBad: start / 3,
+				NumOnlineNodes:  12,
+				NumTotalNodes:   40,
+			}},
+		})
+	}
+	return msgs
+}
+
+func TestMonotonicCounters(t *testing.T) {
+	sizes := map[bool]int{}
+	for _, monotonic := range []bool{false, true} {
+		opts := SessionOptions{MonotonicCounters: monotonic}
+		enc := NewSessionEncoderWithOptions(opts)
+		dec := NewSessionDecoderWithOptions(opts)
+		for i, msg := range counterSession() {
+			frame, err := enc.Encode(msg)
+			if err != nil {
+				t.Fatalf("monotonic %v message %d: Encode failed: %v", monotonic, i, err)
+			}
+			sizes[monotonic] += len(frame)
+
+			result := msg.ProtoReflect().New().Interface()
+			if err := dec.Decode(frame, result); err != nil {
+				t.Fatalf("monotonic %v message %d: Decode failed: %v", monotonic, i, err)
+			}
+			if !proto.Equal(msg, result) {
+				t.Fatalf("monotonic %v message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", monotonic, i, msg, result)
+			}
+			if enc.StateHash() != dec.StateHash() {
+				t.Fatalf("monotonic %v message %d: state hash mismatch", monotonic, i)
+			}
+		}
+	}
+
+	t.Logf("session: %d bytes, with monotonic counters: %d bytes", sizes[false], sizes[true])
+	if sizes[true] >= sizes[false] {
+		t.Errorf("monotonic counters %d bytes, want less than %d bytes", sizes[true], sizes[false])
+	}
+}
+
+func TestMonotonicCountersSnapshot(t *testing.T) {
+	opts := SessionOptions{MonotonicCounters: true}
+	enc := NewSessionEncoderWithOptions(opts)
+	msgs := counterSession()
+	for i, msg := range msgs[:6] {
+		if _, err := enc.Encode(msg); err != nil {
+			t.Fatalf("message %d: Encode failed: %v", i, err)
+		}
+	}
+
+	// Both branches continue from the counters of the snapshot
+	snap := enc.Snapshot()
+	for branch := range 2 {
+		enc, dec := snap.NewEncoder(), snap.NewDecoder()
+		for i, msg := range msgs[6:] {
+			frame, err := enc.Encode(msg)
+			if err != nil {
+				t.Fatalf("branch %d message %d: Encode failed: %v", branch, i, err)
+			}
+			result := msg.ProtoReflect().New().Interface()
+			if err := dec.Decode(frame, result); err != nil {
+				t.Fatalf("branch %d message %d: Decode failed: %v", branch, i, err)
+			}
+			if !proto.Equal(msg, result) {
+				t.Fatalf("branch %d message %d: roundtrip mismatch", branch, i)
+			}
+		}
+	}
+}
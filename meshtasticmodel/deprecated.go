@@ -0,0 +1,34 @@
+package meshtasticmodel
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
+)
+
+// DeprecatedFields are the fields the Meshtastic schema marks as
+// deprecated. Firmware may still send them, but newer schemas remove them.
+var DeprecatedFields = []protoreflect.FullName{
+	"meshtastic.AdminMessage.reboot_ota_seconds",
+	"meshtastic.ChannelSettings.channel_num",
+	"meshtastic.Config.DeviceConfig.is_managed",
+	"meshtastic.Config.DeviceConfig.serial_enabled",
+	"meshtastic.Config.DisplayConfig.compass_north_top",
+	"meshtastic.Config.DisplayConfig.gps_format",
+	"meshtastic.Config.PositionConfig.gps_attempt_time",
+	"meshtastic.Config.PositionConfig.gps_enabled",
+	"meshtastic.DeviceState.did_gps_reset",
+	"meshtastic.DeviceState.no_save",
+	"meshtastic.MeshPacket.delayed",
+	"meshtastic.ModuleConfig.CannedMessageConfig.allow_input_source",
+	"meshtastic.ModuleConfig.CannedMessageConfig.enabled",
+	"meshtastic.User.macaddr",
+	"meshtastic.UserLite.macaddr",
+}
+
+// WithDeprecatedFields configures pbmodel with DeprecatedFields, dropping
+// them when compressing if drop is set. Data compressed either way
+// decompresses with this option, with current and with newer schemas.
+func WithDeprecatedFields(drop bool) pbmodel.Option {
+	return pbmodel.WithDeprecatedFields(drop, DeprecatedFields...)
+}
@@ -0,0 +1,77 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
+)
+
+func TestDeprecatedFieldsTable(t *testing.T) {
+	// The table lists the fields the descriptors mark as deprecated
+	var marked []protoreflect.FullName
+	var walk func(messages protoreflect.MessageDescriptors)
+	walk = func(messages protoreflect.MessageDescriptors) {
+		for i := 0; i < messages.Len(); i++ {
+			md := messages.Get(i)
+			for j := 0; j < md.Fields().Len(); j++ {
+				fd := md.Fields().Get(j)
+				if opts, ok := fd.Options().(*descriptorpb.FieldOptions); ok && opts.GetDeprecated() {
+					marked = append(marked, fd.FullName())
+				}
+			}
+			walk(md.Messages())
+		}
+	}
+	protoregistry.GlobalFiles.RangeFilesByPackage("meshtastic", func(file protoreflect.FileDescriptor) bool {
+		walk(file.Messages())
+		return true
+	})
+
+	slices.Sort(marked)
+	if !slices.Equal(marked, DeprecatedFields) {
+		t.Errorf("DeprecatedFields = %v, want %v", DeprecatedFields, marked)
+	}
+}
+
+func TestDeprecatedFieldsRoundtrip(t *testing.T) {
+	s := meshfixtures.Scenarios()[0]
+	user := meshfixtures.FullUser(s)
+	user.Macaddr = []byte{0x24, 0x6f, 0x28, 0xa2, 0xe4, 0xf0}
+	config := &meshtastic.Config_PositionConfig{GpsEnabled: true, GpsAttemptTime: 900, PositionBroadcastSecs: 900}
+
+	for _, msg := range []proto.Message{user, config} {
+		for _, drop := range []bool{false, true} {
+			var buf bytes.Buffer
+			if err := pbmodel.Compress(msg, &buf, WithDeprecatedFields(drop)); err != nil {
+				t.Fatalf("Compress failed: %v", err)
+			}
+			decoded := msg.ProtoReflect().New().Interface()
+			if err := pbmodel.Decompress(&buf, decoded, WithDeprecatedFields(false)); err != nil {
+				t.Fatalf("Decompress failed: %v", err)
+			}
+
+			want := proto.Clone(msg)
+			if drop {
+				m := want.ProtoReflect()
+				m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+					if slices.Contains(DeprecatedFields, fd.FullName()) {
+						m.Clear(fd)
+					}
+					return true
+				})
+			}
+			if !proto.Equal(want, decoded) {
+				t.Errorf("drop %v: got %v, want %v", drop, decoded, want)
+			}
+		}
+	}
+}
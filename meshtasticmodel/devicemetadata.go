@@ -0,0 +1,246 @@
+package meshtasticmodel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+// Firmware version strings have the form MAJOR.MINOR.PATCH.HASH, for example
+// "2.5.15.abcdef1": three decimal numbers followed by the abbreviated git
+// commit hash of the build. V14 codes them structurally, the numbers as
+// varints and the hash as a length followed by 4 bits per hex digit. Strings
+// that do not follow the pattern are coded as text.
+
+// maxFirmwareHashLength is the longest commit hash coded structurally.
+const maxFirmwareHashLength = 16
+
+// firmwareVersion is a parsed firmware version string.
+type firmwareVersion struct {
+	numbers [3]uint64
+	hash    string
+}
+
+// parseFirmwareVersion parses s as a firmware version. It only accepts
+// strings that format back to s exactly: numbers without leading zeros and
+// a lowercase hex hash.
+func parseFirmwareVersion(s string) (firmwareVersion, bool) {
+	var version firmwareVersion
+	parts := strings.Split(s, ".")
+	if len(parts) != 4 {
+		return version, false
+	}
+	for i, part := range parts[:3] {
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || strconv.FormatUint(n, 10) != part {
+			return version, false
+		}
+		version.numbers[i] = n
+	}
+
+	hash := parts[3]
+	if len(hash) == 0 || len(hash) > maxFirmwareHashLength {
+		return version, false
+	}
+	for i := 0; i < len(hash); i++ {
+		if hexDigit(hash[i]) < 0 {
+			return version, false
+		}
+	}
+	version.hash = hash
+	return version, true
+}
+
+// String formats the version as MAJOR.MINOR.PATCH.HASH.
+func (v firmwareVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d.%s", v.numbers[0], v.numbers[1], v.numbers[2], v.hash)
+}
+
+// hexDigit returns the value of a lowercase hex digit, or -1.
+func hexDigit(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c-'a') + 10
+	}
+	return -1
+}
+
+// encodeFirmwareVersion encodes a firmware_version string, structurally when
+// it follows the firmware version pattern and as text otherwise.
+func encodeFirmwareVersion(enc *coder.Encoder, mcb *ContextualModelBuilder, s string) error {
+	version, ok := parseFirmwareVersion(s)
+	structured := 0
+	if ok {
+		structured = 1
+	}
+	if err := models.EncodeEscaped(enc, structured, mcb.GetBooleanModel("firmware_version_structured")); err != nil {
+		return err
+	}
+	if !ok {
+		return mcb.emojiTextModel.EncodeString(enc, s)
+	}
+
+	for _, n := range version.numbers {
+		if err := encodeVarintWithModels(n, enc, mcb); err != nil {
+			return err
+		}
+	}
+	if err := enc.Encode(len(version.hash)-1, sharedFirmwareHashLengthModel()); err != nil {
+		return err
+	}
+	for i := 0; i < len(version.hash); i++ {
+		if err := enc.EncodeUniform(uint64(hexDigit(version.hash[i])), 15); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeFirmwareVersion decodes a string written by encodeFirmwareVersion.
+func decodeFirmwareVersion(dec *coder.Decoder, mcb *ContextualModelBuilder) (string, error) {
+	structured, err := models.DecodeEscaped(dec, mcb.GetBooleanModel("firmware_version_structured"))
+	if err != nil {
+		return "", err
+	}
+	if structured == 0 {
+		return mcb.emojiTextModel.DecodeString(dec)
+	}
+
+	var version firmwareVersion
+	for i := range version.numbers {
+		n, err := decodeVarintWithModels(dec, mcb)
+		if err != nil {
+			return "", err
+		}
+		if n > 1<<32-1 {
+			return "", fmt.Errorf("firmware version number %d out of range", n)
+		}
+		version.numbers[i] = n
+	}
+	length, err := dec.Decode(sharedFirmwareHashLengthModel())
+	if err != nil {
+		return "", err
+	}
+	hash := make([]byte, length+1)
+	for i := range hash {
+		digit, err := dec.DecodeUniform(15)
+		if err != nil {
+			return "", err
+		}
+		hash[i] = "0123456789abcdef"[digit]
+	}
+	version.hash = string(hash)
+	return version.String(), nil
+}
+
+var sharedFirmwareHashLengthModel = sync.OnceValue(createFirmwareHashLengthModel)
+
+// createFirmwareHashLengthModel creates a model for the length of the commit
+// hash in firmware versions, minus one. Release builds use 7 hex digits;
+// shorter and longer abbreviations are rare.
+func createFirmwareHashLengthModel() coder.Model {
+	freqs := make([]uint64, maxFirmwareHashLength)
+	for i := range freqs {
+		freqs[i] = 2
+	}
+	freqs[6-1] = 40
+	freqs[7-1] = 400
+	freqs[8-1] = 40
+	return models.NewFrequencyTable(freqs)
+}
+
+// deviceEnumModel returns the model for the hardware model and device role
+// enums (V14+), or nil for other enums. The static tables replace the
+// single predicted value of V4 for these enums. Enums are matched by type,
+// as other messages have fields of the same name, such as Channel.role.
+func (mcb *ContextualModelBuilder) deviceEnumModel(ed protoreflect.EnumDescriptor) coder.Model {
+	if !mcb.configDownload {
+		return nil
+	}
+
+	var create func() coder.Model
+	switch ed.FullName() {
+	case meshtastic.HardwareModel(0).Descriptor().FullName():
+		create = sharedHardwareModelModel
+	case meshtastic.Config_DeviceConfig_Role(0).Descriptor().FullName():
+		create = sharedDeviceRoleModel
+	default:
+		return nil
+	}
+
+	contextKey := "enum:" + string(ed.FullName())
+	if model, ok := mcb.contextModels[contextKey]; ok {
+		return model
+	}
+	model := mcb.escapeModel(create())
+	mcb.contextModels[contextKey] = model
+	return model
+}
+
+var (
+	sharedHardwareModelModel = sync.OnceValue(createHardwareModelModel)
+	sharedDeviceRoleModel    = sync.OnceValue(createDeviceRoleModel)
+)
+
+// createHardwareModelModel creates a model over HardwareModel value indices
+// that favors the boards most commonly seen on public meshes.
+func createHardwareModelModel() coder.Model {
+	return createEnumTableModel(meshtastic.HardwareModel(0).Descriptor(), map[protoreflect.EnumNumber]uint64{
+		protoreflect.EnumNumber(meshtastic.HardwareModel_HELTEC_V3):                 200,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_RAK4631):                   200,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_TBEAM):                     150,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_T_ECHO):                    100,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_TRACKER_T1000_E):           100,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_STATION_G2):                60,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_HELTEC_WIRELESS_TRACKER):   60,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_HELTEC_WSL_V3):             50,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_HELTEC_MESH_NODE_T114):     50,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_LILYGO_TBEAM_S3_CORE):      40,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_TLORA_V2_1_1P6):            40,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_T_DECK):                    40,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_SEEED_XIAO_S3):             30,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_HELTEC_VISION_MASTER_E290): 20,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_PORTDUINO):                 20,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_UNSET):                     20,
+		protoreflect.EnumNumber(meshtastic.HardwareModel_PRIVATE_HW):                20,
+	}, 2)
+}
+
+// createDeviceRoleModel creates a model over Config.DeviceConfig.Role value
+// indices. Most nodes are clients; the infrastructure roles follow.
+func createDeviceRoleModel() coder.Model {
+	return createEnumTableModel(meshtastic.Config_DeviceConfig_Role(0).Descriptor(), map[protoreflect.EnumNumber]uint64{
+		protoreflect.EnumNumber(meshtastic.Config_DeviceConfig_CLIENT):      700,
+		protoreflect.EnumNumber(meshtastic.Config_DeviceConfig_CLIENT_MUTE): 80,
+		protoreflect.EnumNumber(meshtastic.Config_DeviceConfig_ROUTER):      60,
+		protoreflect.EnumNumber(meshtastic.Config_DeviceConfig_ROUTER_LATE): 30,
+		protoreflect.EnumNumber(meshtastic.Config_DeviceConfig_TRACKER):     30,
+		protoreflect.EnumNumber(meshtastic.Config_DeviceConfig_SENSOR):      20,
+		protoreflect.EnumNumber(meshtastic.Config_DeviceConfig_CLIENT_BASE): 20,
+	}, 5)
+}
+
+// createEnumTableModel creates a model over the value indices of ed, with
+// the given frequencies by enum number and rest for every other value.
+func createEnumTableModel(ed protoreflect.EnumDescriptor, freqByNumber map[protoreflect.EnumNumber]uint64, rest uint64) coder.Model {
+	values := ed.Values()
+	freqs := make([]uint64, values.Len()+1)
+	freqs[values.Len()] = 1 // The escape of undeclared numbers
+	for i := range values.Len() {
+		freq, ok := freqByNumber[values.Get(i).Number()]
+		if !ok {
+			freq = rest
+		}
+		freqs[i] = freq
+	}
+	return models.NewFrequencyTable(freqs)
+}
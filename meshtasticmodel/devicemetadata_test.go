@@ -0,0 +1,75 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestParseFirmwareVersion(t *testing.T) {
+	tests := []struct {
+		input string
+		ok    bool
+	}{
+		{"2.5.15.abcdef1", true},
+		{"2.6.0.0123456789abcdef", true},
+		{"0.0.0.f", true},
+		{"2.5.15", false},
+		{"2.5.15.", false},
+		{"2.05.15.abcdef1", false},
+		{"2.5.15.ABCDEF1", false},
+		{"2.5.15.abcdef1-dirty", false},
+		{"2.5.15.0123456789abcdef0", false},
+		{"v2.5.15.abcdef1", false},
+		{"2.5.99999999999.abcdef1", false},
+	}
+	for _, test := range tests {
+		version, ok := parseFirmwareVersion(test.input)
+		if ok != test.ok {
+			t.Errorf("parseFirmwareVersion(%q) ok = %v, want %v", test.input, ok, test.ok)
+			continue
+		}
+		if ok && version.String() != test.input {
+			t.Errorf("parseFirmwareVersion(%q) formats as %q", test.input, version.String())
+		}
+	}
+}
+
+func TestMeshtasticV14DeviceMetadata(t *testing.T) {
+	for _, firmware := range []string{"2.5.15.abcdef1", "2.6.11.60ec05e", "2.5.15.ABCDEF1", "custom build", ""} {
+		metadata := &meshtastic.DeviceMetadata{
+			FirmwareVersion:    firmware,
+			DeviceStateVersion: 24,
+			CanShutdown:        true,
+			HasWifi:            true,
+			HasBluetooth:       true,
+			Role:               meshtastic.Config_DeviceConfig_CLIENT,
+			PositionFlags:      811,
+			HwModel:            meshtastic.HardwareModel_HELTEC_V3,
+			HasPKC:             true,
+		}
+
+		var bufV13, bufV14 bytes.Buffer
+		if err := CompressV13(metadata, &bufV13); err != nil {
+			t.Fatalf("%q: V13 compress failed: %v", firmware, err)
+		}
+		if err := CompressV14(metadata, &bufV14); err != nil {
+			t.Fatalf("%q: V14 compress failed: %v", firmware, err)
+		}
+		t.Logf("%q: V13: %d bytes, V14: %d bytes", firmware, bufV13.Len(), bufV14.Len())
+		if _, ok := parseFirmwareVersion(firmware); ok && bufV14.Len() >= bufV13.Len() {
+			t.Errorf("%q: V14 (%d bytes) should be smaller than V13 (%d bytes)", firmware, bufV14.Len(), bufV13.Len())
+		}
+
+		result := &meshtastic.DeviceMetadata{}
+		if err := DecompressV14(&bufV14, result); err != nil {
+			t.Fatalf("%q: V14 decompress failed: %v", firmware, err)
+		}
+		if !proto.Equal(metadata, result) {
+			t.Errorf("%q: V14 roundtrip verification failed", firmware)
+		}
+	}
+}
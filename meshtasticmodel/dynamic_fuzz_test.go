@@ -0,0 +1,54 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/egonelbre/exp-protobuf-compression/internal/randproto"
+)
+
+// FuzzDynamicRoundtrip checks that every version decompresses the messages
+// it compresses to equal messages, including messages of schemas that are
+// not Meshtastic ones. Versions may refuse to compress a message.
+func FuzzDynamicRoundtrip(f *testing.F) {
+	schemas := randproto.Schemas()
+	rng := rand.New(rand.NewSource(1))
+	for schema := 0; schema <= len(schemas); schema++ {
+		f.Add(uint8(schema), []byte(nil))
+		for range 4 {
+			data := make([]byte, 64+rng.Intn(512))
+			rng.Read(data)
+			f.Add(uint8(schema), data)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, schema uint8, data []byte) {
+		src := randproto.NewSource(data)
+		md := schemas[int(schema)%len(schemas)]
+		if int(schema)%(len(schemas)+1) == len(schemas) {
+			var err error
+			if md, err = randproto.RandomSchema(src); err != nil {
+				t.Skipf("invalid random schema: %v", err)
+			}
+		}
+		original := randproto.NewMessage(md, src, 3).Interface()
+
+		for _, version := range Versions {
+			var buf bytes.Buffer
+			if err := version.Compress(original, &buf); err != nil {
+				continue
+			}
+			decoded := dynamicpb.NewMessage(md)
+			if err := version.Decompress(&buf, decoded); err != nil {
+				t.Fatalf("%s: Decompress failed: %v\noriginal: %v", version.Name, err, original)
+			}
+			if !proto.Equal(original, decoded) {
+				t.Fatalf("%s: roundtrip mismatch\noriginal: %v\ndecoded:  %v", version.Name, original, decoded)
+			}
+		}
+	})
+}
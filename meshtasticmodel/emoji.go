@@ -0,0 +1,132 @@
+package meshtasticmodel
+
+import (
+	"encoding/binary"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// emojiFields are fixed32 fields holding an emoji codepoint or flag.
+//
+// Data.emoji marks a reply as an emoji reaction (usually 1, some clients
+// store the codepoint) and Waypoint.icon is the codepoint shown on the map.
+var emojiFields = map[protoreflect.FullName]bool{
+	"meshtastic.Data.emoji":    true,
+	"meshtastic.Waypoint.icon": true,
+}
+
+// Symbols of the emoji field model; symbols from emojiFieldFirst onwards
+// are compact emoji indices.
+const (
+	emojiFieldZero  = 0
+	emojiFieldOne   = 1
+	emojiFieldFirst = 2
+)
+
+// isEmojiField reports whether fd holds an emoji codepoint.
+func isEmojiField(fd protoreflect.FieldDescriptor) bool {
+	return emojiFields[fd.FullName()]
+}
+
+// codesEmojiField reports whether mcb codes fd as an emoji field, which
+// builders with an emoji text model (V12+) do.
+func (mcb *ContextualModelBuilder) codesEmojiField(fd protoreflect.FieldDescriptor) bool {
+	return mcb.emojiTextModel != nil && isEmojiField(fd)
+}
+
+// encodeText encodes a string with the text model of mcb, through its
+// emoji-aware wrapper when it has one.
+func (mcb *ContextualModelBuilder) encodeText(enc *coder.Encoder, str string) error {
+	if mcb.emojiTextModel != nil {
+		return mcb.emojiTextModel.EncodeString(enc, str)
+	}
+	return mcb.textModel.EncodeString(enc, str)
+}
+
+// decodeText decodes a string written by encodeText.
+func (mcb *ContextualModelBuilder) decodeText(dec *coder.Decoder) (string, error) {
+	if mcb.emojiTextModel != nil {
+		return mcb.emojiTextModel.DecodeString(dec)
+	}
+	return mcb.textModel.DecodeString(dec)
+}
+
+// GetEmojiFieldModel returns the model for an emoji field: zero, one,
+// a common emoji, or any other value.
+func (mcb *ContextualModelBuilder) GetEmojiFieldModel(fd protoreflect.FieldDescriptor) coder.Model {
+	contextKey := "_emoji:" + string(fd.FullName())
+	if model, ok := mcb.contextModels[contextKey]; ok {
+		return model
+	}
+
+	count := models.EmojiCount()
+	freqs := make([]uint64, emojiFieldFirst+count+1)
+	freqs[emojiFieldZero] = 256
+	freqs[emojiFieldOne] = 128
+	for i := 0; i < count; i++ {
+		freqs[emojiFieldFirst+i] = 1 + 64/uint64(i+1)
+	}
+	freqs[emojiFieldFirst+count] = 4 // Other
+
+	model := models.NewFrequencyTable(freqs)
+	mcb.contextModels[contextKey] = model
+	return model
+}
+
+// encodeEmojiField encodes the value of an emoji field.
+func encodeEmojiField(fd protoreflect.FieldDescriptor, value uint32, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	model := mcb.GetEmojiFieldModel(fd)
+	otherSymbol := emojiFieldFirst + models.EmojiCount()
+
+	switch value {
+	case 0:
+		return enc.Encode(emojiFieldZero, model)
+	case 1:
+		return enc.Encode(emojiFieldOne, model)
+	}
+	if index, ok := models.EmojiIndex(rune(value)); ok {
+		return enc.Encode(emojiFieldFirst+index, model)
+	}
+
+	if err := enc.Encode(otherSymbol, model); err != nil {
+		return err
+	}
+	bytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(bytes, value)
+	for _, b := range bytes {
+		if err := enc.Encode(int(b), mcb.ByteModel()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeEmojiField decodes the value of an emoji field.
+func decodeEmojiField(fd protoreflect.FieldDescriptor, dec *coder.Decoder, mcb *ContextualModelBuilder) (uint32, error) {
+	symbol, err := dec.Decode(mcb.GetEmojiFieldModel(fd))
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case symbol == emojiFieldZero:
+		return 0, nil
+	case symbol == emojiFieldOne:
+		return 1, nil
+	case symbol < emojiFieldFirst+models.EmojiCount():
+		return uint32(models.EmojiRune(symbol - emojiFieldFirst)), nil
+	}
+
+	bytes := make([]byte, 4)
+	for i := range bytes {
+		b, err := dec.Decode(mcb.ByteModel())
+		if err != nil {
+			return 0, err
+		}
+		bytes[i] = byte(b)
+	}
+	return binary.LittleEndian.Uint32(bytes), nil
+}
@@ -0,0 +1,45 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+)
+
+func TestMeshtasticV12Emoji(t *testing.T) {
+	s := meshfixtures.Default
+	tests := []struct {
+		name string
+		msg  proto.Message
+	}{
+		{"Reaction", meshfixtures.ReactionPacket(s, 987654, "👍")},
+		{"Reaction with variant", meshfixtures.ReactionPacket(s, 987654, "❤️")},
+		{"Text with emoji", meshfixtures.TextPacket(s, "Made it to the top 🏔️ amazing view 😍")},
+		{"Waypoint icon", meshfixtures.Waypoint(s, "Home 🏠", '🏠')},
+		{"Waypoint uncommon icon", meshfixtures.Waypoint(s, "Flamingos", '🦩')},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bufV11, bufV12 bytes.Buffer
+			if err := CompressV11(tt.msg, &bufV11); err != nil {
+				t.Fatalf("V11 compress failed: %v", err)
+			}
+			if err := CompressV12(tt.msg, &bufV12); err != nil {
+				t.Fatalf("V12 compress failed: %v", err)
+			}
+			t.Logf("V11: %d bytes, V12: %d bytes", bufV11.Len(), bufV12.Len())
+
+			result := tt.msg.ProtoReflect().New().Interface()
+			if err := DecompressV12(&bufV12, result); err != nil {
+				t.Fatalf("V12 decompress failed: %v", err)
+			}
+			if !proto.Equal(tt.msg, result) {
+				t.Error("V12 roundtrip verification failed")
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package meshtasticmodel
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
+)
+
+// Newer firmware adds values to enums such as HardwareModel and PortNum
+// before the schema of a receiver knows them. Up to V13 the models of the
+// enum indexes have a symbol per declared value, so such values cannot be
+// compressed. V14 appends an escape symbol to the models, see
+// pbmodel.EnumModel, after which the number is coded as a zigzag varint.
+
+// enumModel returns the model of the value indexes of enum ed at fieldPath,
+// with the escape of undeclared numbers when openEnums is set.
+func (mcb *ContextualModelBuilder) enumModel(fieldPath string, ed protoreflect.EnumDescriptor) coder.Model {
+	if mcb.openEnums {
+		return pbmodel.EnumModel(ed.Values().Len())
+	}
+	return mcb.GetEnumModel(fieldPath, ed)
+}
+
+// compressUnknownEnumV10 compresses a number that the enum of fd does not
+// declare as the escape of the model the declared values of the field use,
+// followed by the number.
+func compressUnknownEnumV10(fieldPath string, fd protoreflect.FieldDescriptor, number protoreflect.EnumNumber, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	ed := fd.Enum()
+	escape := ed.Values().Len()
+	if model := mcb.deviceEnumModel(ed); model != nil {
+		if err := models.EncodeEscaped(enc, escape, model); err != nil {
+			return err
+		}
+	} else {
+		fieldName := string(fd.Name())
+		if _, hasPrediction := mcb.enumPredictions[fieldName]; hasPrediction {
+			predModel := mcb.GetBooleanModel(fieldName + "_is_predicted")
+			if err := models.EncodeEscaped(enc, 0, predModel); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(escape, mcb.enumModel(fieldPath, ed)); err != nil {
+			return err
+		}
+	}
+	return encodeVarintWithModels(pbmodel.ZigzagEncode(int64(number)), enc, mcb)
+}
+
+// decompressEnumIndexV10 returns the value of enum ed at index, decoding the
+// number that follows the escape of undeclared numbers.
+func decompressEnumIndexV10(index int, ed protoreflect.EnumDescriptor, dec *coder.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
+	if index < ed.Values().Len() {
+		return protoreflect.ValueOfEnum(ed.Values().Get(index).Number()), nil
+	}
+	if !mcb.openEnums {
+		return protoreflect.Value{}, fmt.Errorf("invalid enum index %d", index)
+	}
+	zigzag, err := decodeVarintWithModels(dec, mcb)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	number := pbmodel.ZigzagDecode(zigzag)
+	if number < math.MinInt32 || number > math.MaxInt32 {
+		return protoreflect.Value{}, fmt.Errorf("enum value %d: %w", number, pbmodel.ErrOutOfRange)
+	}
+	return protoreflect.ValueOfEnum(protoreflect.EnumNumber(number)), nil
+}
@@ -0,0 +1,43 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestMeshtasticV14UnknownEnums(t *testing.T) {
+	msgs := []proto.Message{
+		// Device enum tables
+		&meshtastic.User{Id: "!12345678", LongName: "New Board", HwModel: meshtastic.HardwareModel(250)},
+		&meshtastic.DeviceMetadata{FirmwareVersion: "2.9.0.abcdef1", Role: meshtastic.Config_DeviceConfig_Role(40)},
+		// Predicted enum
+		&meshtastic.Position{LatitudeI: proto.Int32(375317890), LocationSource: meshtastic.Position_LocSource(-1)},
+		// Other enums
+		&meshtastic.Data{Portnum: meshtastic.PortNum(1000), Payload: []byte{1, 2, 3}},
+		&meshtastic.MeshPacket{Priority: meshtastic.MeshPacket_Priority(math.MaxInt32)},
+		&meshtastic.MeshPacket{Priority: meshtastic.MeshPacket_Priority(math.MinInt32)},
+	}
+	for _, msg := range msgs {
+		var buf bytes.Buffer
+		if err := CompressV13(msg, &buf); err == nil {
+			t.Errorf("%T: V13 compressed an undeclared enum value", msg)
+		}
+
+		buf.Reset()
+		if err := CompressV14(msg, &buf); err != nil {
+			t.Fatalf("%T: V14 compress failed: %v", msg, err)
+		}
+		result := msg.ProtoReflect().New().Interface()
+		if err := DecompressV14(&buf, result); err != nil {
+			t.Fatalf("%T: V14 decompress failed: %v", msg, err)
+		}
+		if !proto.Equal(msg, result) {
+			t.Errorf("%T: got %v, want %v", msg, result, msg)
+		}
+	}
+}
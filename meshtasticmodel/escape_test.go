@@ -0,0 +1,48 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestMeshtasticV13EscapingModels(t *testing.T) {
+	// Every neighbor sets all of its fields, while the static presence
+	// models expect them to be missing fairly often
+	info := &meshtastic.NeighborInfo{
+		NodeId:                    0x12345678,
+		LastSentById:              0x12345678,
+		NodeBroadcastIntervalSecs: 900,
+	}
+	for i := 0; i < 40; i++ {
+		info.Neighbors = append(info.Neighbors, &meshtastic.Neighbor{
+			NodeId:                    0x20000000 + uint32(i)*977,
+			Snr:                       float32(i%12) - 4,
+			LastRxTime:                1700000000 + uint32(i)*60,
+			NodeBroadcastIntervalSecs: 900,
+		})
+	}
+
+	var bufV12, bufV13 bytes.Buffer
+	if err := CompressV12(info, &bufV12); err != nil {
+		t.Fatalf("V12 compress failed: %v", err)
+	}
+	if err := CompressV13(info, &bufV13); err != nil {
+		t.Fatalf("V13 compress failed: %v", err)
+	}
+	t.Logf("V12: %d bytes, V13: %d bytes", bufV12.Len(), bufV13.Len())
+	if bufV13.Len() >= bufV12.Len() {
+		t.Errorf("V13 (%d bytes) should be smaller than V12 (%d bytes)", bufV13.Len(), bufV12.Len())
+	}
+
+	result := &meshtastic.NeighborInfo{}
+	if err := DecompressV13(&bufV13, result); err != nil {
+		t.Fatalf("V13 decompress failed: %v", err)
+	}
+	if !proto.Equal(info, result) {
+		t.Error("V13 roundtrip verification failed")
+	}
+}
@@ -0,0 +1,242 @@
+package meshtasticmodel
+
+import (
+	"maps"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// FieldKey identifies a field by its message and number, which stay the
+// same when the field is renamed.
+type FieldKey struct {
+	Message protoreflect.FullName
+	Number  protoreflect.FieldNumber
+}
+
+// FieldKeyOf returns the key of fd.
+func FieldKeyOf(fd protoreflect.FieldDescriptor) FieldKey {
+	return FieldKey{Message: fd.ContainingMessage().FullName(), Number: fd.Number()}
+}
+
+// FieldModels selects the context-specific models of fields. A nil model
+// gives the field no context-specific model. Fields that are not in the
+// table fall back to a model selected by the field name.
+type FieldModels map[FieldKey]func() coder.Model
+
+// DefaultFieldModels returns a copy of the context-specific models of the
+// Meshtastic fields, for modifying and passing to SetFieldModels or
+// SessionOptions.FieldModels.
+func DefaultFieldModels() FieldModels {
+	return maps.Clone(defaultFieldModels)
+}
+
+// SetFieldModels replaces the context-specific models of fields, nil
+// restores DefaultFieldModels. The encoder and decoder must use the same
+// models.
+func (mcb *ContextualModelBuilder) SetFieldModels(fieldModels FieldModels) {
+	if fieldModels == nil {
+		fieldModels = defaultFieldModels
+	}
+	mcb.fieldModels = fieldModels
+}
+
+// createContextSpecificModel creates specialized models for known Meshtastic
+// fields, selected by message and field number and for other messages by
+// the field name.
+func (mcb *ContextualModelBuilder) createContextSpecificModel(fieldPath string, fd protoreflect.FieldDescriptor) coder.Model {
+	if model, ok := mcb.fieldModels[FieldKeyOf(fd)]; ok {
+		if model == nil {
+			return nil
+		}
+		return model()
+	}
+	return mcb.contextModelByName(fieldPath, fd)
+}
+
+// defaultFieldModels are the models that contextModelByName selects for
+// the Meshtastic fields, so that selecting them by number does not change
+// the coding of earlier versions.
+var defaultFieldModels = FieldModels{
+	{"meshtastic.AirQualityMetrics", 1}:  sharedParticulateModel,   // pm10_standard
+	{"meshtastic.AirQualityMetrics", 2}:  sharedParticulateModel,   // pm25_standard
+	{"meshtastic.AirQualityMetrics", 3}:  sharedParticulateModel,   // pm100_standard
+	{"meshtastic.AirQualityMetrics", 4}:  sharedParticulateModel,   // pm10_environmental
+	{"meshtastic.AirQualityMetrics", 5}:  sharedParticulateModel,   // pm25_environmental
+	{"meshtastic.AirQualityMetrics", 6}:  sharedParticulateModel,   // pm100_environmental
+	{"meshtastic.AirQualityMetrics", 7}:  sharedParticleCountModel, // particles_03um
+	{"meshtastic.AirQualityMetrics", 8}:  sharedParticleCountModel, // particles_05um
+	{"meshtastic.AirQualityMetrics", 9}:  sharedParticleCountModel, // particles_10um
+	{"meshtastic.AirQualityMetrics", 10}: sharedParticleCountModel, // particles_25um
+	{"meshtastic.AirQualityMetrics", 11}: sharedParticleCountModel, // particles_50um
+	{"meshtastic.AirQualityMetrics", 12}: sharedParticleCountModel, // particles_100um
+	{"meshtastic.AirQualityMetrics", 13}: sharedCO2Model,           // co2
+	{"meshtastic.AirQualityMetrics", 14}: sharedTemperatureModel,   // co2_temperature
+	{"meshtastic.AirQualityMetrics", 15}: sharedHumidityModel,      // co2_humidity
+	{"meshtastic.AirQualityMetrics", 16}: sharedFormaldehydeModel,  // form_formaldehyde
+	{"meshtastic.AirQualityMetrics", 17}: sharedHumidityModel,      // form_humidity
+	{"meshtastic.AirQualityMetrics", 18}: sharedTemperatureModel,   // form_temperature
+	{"meshtastic.AirQualityMetrics", 23}: sharedVOCNOxModel,        // pm_voc_idx
+	{"meshtastic.AirQualityMetrics", 24}: sharedVOCNOxModel,        // pm_nox_idx
+
+	{"meshtastic.BackupPreferences", 2}: sharedTimestampModel, // timestamp
+
+	{"meshtastic.ClientNotification", 1}: sharedRequestIDModel, // reply_id
+	{"meshtastic.ClientNotification", 2}: sharedTimestampModel, // time
+
+	{"meshtastic.Config.LoRaConfig", 8}: sharedHopCountModel, // hop_limit
+
+	{"meshtastic.Data", 4}: sharedNodeIDModel,     // dest
+	{"meshtastic.Data", 5}: sharedNodeIDModel,     // source
+	{"meshtastic.Data", 6}: sharedRequestIDModel,  // request_id
+	{"meshtastic.Data", 7}: sharedRequestIDModel,  // reply_id
+	{"meshtastic.Data", 8}: sharedWaypointIDModel, // emoji
+
+	{"meshtastic.DeviceMetrics", 1}: sharedBatteryLevelModel, // battery_level
+	{"meshtastic.DeviceMetrics", 2}: sharedVoltageModel,      // voltage
+	{"meshtastic.DeviceMetrics", 3}: sharedUtilizationModel,  // channel_utilization
+	{"meshtastic.DeviceMetrics", 4}: sharedUtilizationModel,  // air_util_tx
+	{"meshtastic.DeviceMetrics", 5}: sharedUptimeModel,       // uptime_seconds
+
+	{"meshtastic.EnvironmentMetrics", 1}:  sharedTemperatureModel,   // temperature
+	{"meshtastic.EnvironmentMetrics", 2}:  sharedHumidityModel,      // relative_humidity
+	{"meshtastic.EnvironmentMetrics", 3}:  sharedPressureModel,      // barometric_pressure
+	{"meshtastic.EnvironmentMetrics", 4}:  sharedGasResistanceModel, // gas_resistance
+	{"meshtastic.EnvironmentMetrics", 5}:  sharedVoltageModel,       // voltage
+	{"meshtastic.EnvironmentMetrics", 7}:  sharedIAQModel,           // iaq
+	{"meshtastic.EnvironmentMetrics", 8}:  sharedDistanceModel,      // distance
+	{"meshtastic.EnvironmentMetrics", 9}:  sharedLuxModel,           // lux
+	{"meshtastic.EnvironmentMetrics", 10}: sharedLuxModel,           // white_lux
+	{"meshtastic.EnvironmentMetrics", 11}: sharedLuxModel,           // ir_lux
+	{"meshtastic.EnvironmentMetrics", 12}: sharedLuxModel,           // uv_lux
+	{"meshtastic.EnvironmentMetrics", 13}: sharedDirectionModel,     // wind_direction
+	{"meshtastic.EnvironmentMetrics", 14}: sharedWindSpeedModel,     // wind_speed
+	{"meshtastic.EnvironmentMetrics", 16}: sharedWindSpeedModel,     // wind_gust
+	{"meshtastic.EnvironmentMetrics", 17}: sharedWindSpeedModel,     // wind_lull
+	{"meshtastic.EnvironmentMetrics", 19}: sharedRainfallModel,      // rainfall_1h
+	{"meshtastic.EnvironmentMetrics", 20}: sharedRainfallModel,      // rainfall_24h
+	{"meshtastic.EnvironmentMetrics", 21}: sharedSoilMoistureModel,  // soil_moisture
+	{"meshtastic.EnvironmentMetrics", 22}: sharedTemperatureModel,   // soil_temperature
+
+	{"meshtastic.FromRadio", 10}: sharedChannelNumberModel, // channel
+
+	{"meshtastic.GeoChat", 2}: sharedNodeIDModel, // to
+
+	{"meshtastic.HealthMetrics", 1}: sharedHeartRateModel,   // heart_bpm
+	{"meshtastic.HealthMetrics", 2}: sharedSpO2Model,        // spO2
+	{"meshtastic.HealthMetrics", 3}: sharedTemperatureModel, // temperature
+
+	{"meshtastic.HostMetrics", 1}: sharedUptimeModel,      // uptime_seconds
+	{"meshtastic.HostMetrics", 2}: sharedLargeMemoryModel, // freemem_bytes
+	{"meshtastic.HostMetrics", 3}: sharedLargeMemoryModel, // diskfree1_bytes
+	{"meshtastic.HostMetrics", 4}: sharedLargeMemoryModel, // diskfree2_bytes
+	{"meshtastic.HostMetrics", 5}: sharedLargeMemoryModel, // diskfree3_bytes
+	{"meshtastic.HostMetrics", 6}: sharedLoadAverageModel, // load1
+	{"meshtastic.HostMetrics", 7}: sharedLoadAverageModel, // load5
+	{"meshtastic.HostMetrics", 8}: sharedLoadAverageModel, // load15
+
+	{"meshtastic.LocalStats", 1}:  sharedUptimeModel,      // uptime_seconds
+	{"meshtastic.LocalStats", 2}:  sharedUtilizationModel, // channel_utilization
+	{"meshtastic.LocalStats", 3}:  sharedUtilizationModel, // air_util_tx
+	{"meshtastic.LocalStats", 4}:  sharedPacketCountModel, // num_packets_tx
+	{"meshtastic.LocalStats", 5}:  sharedPacketCountModel, // num_packets_rx
+	{"meshtastic.LocalStats", 6}:  sharedPacketCountModel, // num_packets_rx_bad
+	{"meshtastic.LocalStats", 7}:  sharedNodeCountModel,   // num_online_nodes
+	{"meshtastic.LocalStats", 8}:  sharedNodeCountModel,   // num_total_nodes
+	{"meshtastic.LocalStats", 9}:  sharedPacketCountModel, // num_rx_dupe
+	{"meshtastic.LocalStats", 10}: sharedPacketCountModel, // num_tx_relay
+	{"meshtastic.LocalStats", 11}: sharedPacketCountModel, // num_tx_relay_canceled
+	{"meshtastic.LocalStats", 12}: sharedMemoryBytesModel, // heap_total_bytes
+	{"meshtastic.LocalStats", 13}: sharedMemoryBytesModel, // heap_free_bytes
+
+	{"meshtastic.LogRecord", 2}: sharedTimestampModel, // time
+	{"meshtastic.LogRecord", 3}: sharedNodeIDModel,    // source
+
+	{"meshtastic.MapReport", 9}:  sharedCoordinateModel, // latitude_i
+	{"meshtastic.MapReport", 10}: sharedCoordinateModel, // longitude_i
+	{"meshtastic.MapReport", 11}: sharedAltitudeModel,   // altitude
+
+	{"meshtastic.MeshPacket", 1}:  sharedNodeIDModel,        // from
+	{"meshtastic.MeshPacket", 2}:  sharedNodeIDModel,        // to
+	{"meshtastic.MeshPacket", 3}:  sharedChannelNumberModel, // channel
+	{"meshtastic.MeshPacket", 6}:  sharedPacketIDModel,      // id
+	{"meshtastic.MeshPacket", 7}:  sharedTimestampModel,     // rx_time
+	{"meshtastic.MeshPacket", 8}:  sharedSNRModel,           // rx_snr
+	{"meshtastic.MeshPacket", 9}:  sharedHopCountModel,      // hop_limit
+	{"meshtastic.MeshPacket", 11}: sharedPriorityModel,      // priority
+	{"meshtastic.MeshPacket", 12}: sharedRSSIModel,          // rx_rssi
+	{"meshtastic.MeshPacket", 15}: sharedHopCountModel,      // hop_start
+	{"meshtastic.MeshPacket", 20}: sharedTimestampModel,     // tx_after
+
+	{"meshtastic.Neighbor", 2}: sharedSNRModel, // snr
+
+	{"meshtastic.NodeFilter", 4}: sharedHopCountModel,      // hops_away
+	{"meshtastic.NodeFilter", 7}: sharedChannelNumberModel, // channel
+
+	{"meshtastic.NodeInfo", 1}: sharedNodeIDModel,        // num
+	{"meshtastic.NodeInfo", 4}: sharedSNRModel,           // snr
+	{"meshtastic.NodeInfo", 7}: sharedChannelNumberModel, // channel
+	{"meshtastic.NodeInfo", 9}: sharedHopCountModel,      // hops_away
+
+	{"meshtastic.NodeInfoLite", 1}: sharedNodeIDModel,        // num
+	{"meshtastic.NodeInfoLite", 4}: sharedSNRModel,           // snr
+	{"meshtastic.NodeInfoLite", 7}: sharedChannelNumberModel, // channel
+	{"meshtastic.NodeInfoLite", 9}: sharedHopCountModel,      // hops_away
+
+	{"meshtastic.NodeRemoteHardwarePin", 1}: sharedNodeIDModel, // node_num
+
+	{"meshtastic.PLI", 1}: sharedCoordinateModel, // latitude_i
+	{"meshtastic.PLI", 2}: sharedCoordinateModel, // longitude_i
+	{"meshtastic.PLI", 3}: sharedAltitudeModel,   // altitude
+
+	{"meshtastic.Position", 1}:  sharedCoordinateModel,     // latitude_i
+	{"meshtastic.Position", 2}:  sharedCoordinateModel,     // longitude_i
+	{"meshtastic.Position", 3}:  sharedAltitudeModel,       // altitude
+	{"meshtastic.Position", 4}:  sharedTimestampModel,      // time
+	{"meshtastic.Position", 7}:  sharedTimestampModel,      // timestamp
+	{"meshtastic.Position", 8}:  sharedMillisAdjustModel,   // timestamp_millis_adjust
+	{"meshtastic.Position", 9}:  sharedAltitudeModel,       // altitude_hae
+	{"meshtastic.Position", 10}: sharedAltitudeModel,       // altitude_geoidal_separation
+	{"meshtastic.Position", 14}: sharedPrecisionModel,      // gps_accuracy
+	{"meshtastic.Position", 15}: sharedSpeedModel,          // ground_speed
+	{"meshtastic.Position", 16}: sharedDirectionModel,      // ground_track
+	{"meshtastic.Position", 17}: sharedGPSQualityModel,     // fix_quality
+	{"meshtastic.Position", 18}: sharedGPSQualityModel,     // fix_type
+	{"meshtastic.Position", 19}: sharedSatelliteCountModel, // sats_in_view
+	{"meshtastic.Position", 23}: sharedPrecisionModel,      // precision_bits
+
+	{"meshtastic.PositionLite", 1}: sharedCoordinateModel, // latitude_i
+	{"meshtastic.PositionLite", 2}: sharedCoordinateModel, // longitude_i
+	{"meshtastic.PositionLite", 3}: sharedAltitudeModel,   // altitude
+	{"meshtastic.PositionLite", 4}: sharedTimestampModel,  // time
+
+	{"meshtastic.PowerMetrics", 1}:  sharedChannelVoltageModel, // ch1_voltage
+	{"meshtastic.PowerMetrics", 2}:  sharedChannelCurrentModel, // ch1_current
+	{"meshtastic.PowerMetrics", 3}:  sharedChannelVoltageModel, // ch2_voltage
+	{"meshtastic.PowerMetrics", 4}:  sharedChannelCurrentModel, // ch2_current
+	{"meshtastic.PowerMetrics", 5}:  sharedChannelVoltageModel, // ch3_voltage
+	{"meshtastic.PowerMetrics", 6}:  sharedChannelCurrentModel, // ch3_current
+	{"meshtastic.PowerMetrics", 7}:  sharedChannelVoltageModel, // ch4_voltage
+	{"meshtastic.PowerMetrics", 8}:  sharedChannelCurrentModel, // ch4_current
+	{"meshtastic.PowerMetrics", 9}:  sharedChannelVoltageModel, // ch5_voltage
+	{"meshtastic.PowerMetrics", 10}: sharedChannelCurrentModel, // ch5_current
+	{"meshtastic.PowerMetrics", 11}: sharedChannelVoltageModel, // ch6_voltage
+	{"meshtastic.PowerMetrics", 12}: sharedChannelCurrentModel, // ch6_current
+	{"meshtastic.PowerMetrics", 13}: sharedChannelVoltageModel, // ch7_voltage
+	{"meshtastic.PowerMetrics", 14}: sharedChannelCurrentModel, // ch7_current
+	{"meshtastic.PowerMetrics", 15}: sharedChannelVoltageModel, // ch8_voltage
+	{"meshtastic.PowerMetrics", 16}: sharedChannelCurrentModel, // ch8_current
+
+	{"meshtastic.RouteDiscovery", 2}: sharedSNRArrayModel, // snr_towards
+	{"meshtastic.RouteDiscovery", 4}: sharedSNRArrayModel, // snr_back
+
+	{"meshtastic.SharedContact", 1}: sharedNodeIDModel, // node_num
+
+	{"meshtastic.Telemetry", 1}: sharedTimestampModel, // time
+
+	{"meshtastic.Waypoint", 2}: sharedCoordinateModel, // latitude_i
+	{"meshtastic.Waypoint", 3}: sharedCoordinateModel, // longitude_i
+	{"meshtastic.Waypoint", 4}: sharedExpireTimeModel, // expire
+	{"meshtastic.Waypoint", 5}: sharedNodeIDModel,     // locked_to
+}
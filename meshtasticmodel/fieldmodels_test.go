@@ -0,0 +1,90 @@
+package meshtasticmodel
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+// TestDefaultFieldModelsMatchNames checks that the default models of the
+// Meshtastic fields are the ones selected by their names, so that the
+// coding of the versions does not depend on how models are looked up.
+func TestDefaultFieldModelsMatchNames(t *testing.T) {
+	mcb := NewContextualModelBuilder()
+	var check func(md protoreflect.MessageDescriptor)
+	check = func(md protoreflect.MessageDescriptor) {
+		mcb.SetMessageType(string(md.Name()))
+		for i := 0; i < md.Fields().Len(); i++ {
+			fd := md.Fields().Get(i)
+			var model any
+			if newModel := defaultFieldModels[FieldKeyOf(fd)]; newModel != nil {
+				model = newModel()
+			}
+			var byName any
+			if m := mcb.contextModelByName("", fd); m != nil {
+				byName = m
+			}
+			if model != byName {
+				t.Errorf("%s: model does not match the model selected by name", fd.FullName())
+			}
+		}
+		for i := 0; i < md.Messages().Len(); i++ {
+			check(md.Messages().Get(i))
+		}
+	}
+	protoregistry.GlobalFiles.RangeFilesByPackage("meshtastic", func(file protoreflect.FileDescriptor) bool {
+		for i := 0; i < file.Messages().Len(); i++ {
+			check(file.Messages().Get(i))
+		}
+		return true
+	})
+}
+
+func TestFieldModelsOverride(t *testing.T) {
+	position := (&meshtastic.Position{}).ProtoReflect().Descriptor()
+	metrics := (&meshtastic.DeviceMetrics{}).ProtoReflect().Descriptor()
+	fieldModels := DefaultFieldModels()
+	fieldModels[FieldKeyOf(position.Fields().ByName("latitude_i"))] = nil
+	fieldModels[FieldKeyOf(metrics.Fields().ByName("voltage"))] = sharedBatteryLevelModel
+
+	if DefaultFieldModels()[FieldKeyOf(position.Fields().ByName("latitude_i"))] == nil {
+		t.Fatal("changing DefaultFieldModels changed the defaults")
+	}
+
+	sizes := map[bool]int{}
+	for _, override := range []bool{false, true} {
+		opts := SessionOptions{}
+		if override {
+			opts.FieldModels = fieldModels
+		}
+		enc := NewSessionEncoderWithOptions(opts)
+		dec := NewSessionDecoderWithOptions(opts)
+		for i, s := range meshfixtures.Scenarios() {
+			for _, msg := range []proto.Message{meshfixtures.Position(s), meshfixtures.DeviceTelemetry(s)} {
+				frame, err := enc.Encode(msg)
+				if err != nil {
+					t.Fatalf("scenario %d: Encode failed: %v", i, err)
+				}
+				sizes[override] += len(frame)
+
+				result := msg.ProtoReflect().New().Interface()
+				if err := dec.Decode(frame, result); err != nil {
+					t.Fatalf("scenario %d: Decode failed: %v", i, err)
+				}
+				if !proto.Equal(msg, result) {
+					t.Fatalf("scenario %d: roundtrip verification failed", i)
+				}
+			}
+		}
+	}
+
+	t.Logf("default models: %d bytes, overridden: %d bytes", sizes[false], sizes[true])
+	if sizes[false] == sizes[true] {
+		t.Error("overriding field models did not change the coding")
+	}
+}
@@ -0,0 +1,48 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestSpecialFloats(t *testing.T) {
+	specials := map[string]uint32{
+		"negative zero":     0x80000000,
+		"infinity":          0x7f800000,
+		"negative infinity": 0xff800000,
+		"quiet NaN":         0x7fc00000,
+		"NaN with payload":  0x7fc0beef,
+		"negative NaN":      0xffc00001,
+	}
+	for name, bits := range specials {
+		value := proto.Float32(math.Float32frombits(bits))
+		msg := &meshtastic.Telemetry{
+			Time: 1700000000,
+			Variant: &meshtastic.Telemetry_EnvironmentMetrics{EnvironmentMetrics: &meshtastic.EnvironmentMetrics{
+				Temperature: value,
+				Voltage:     value,
+			}},
+		}
+		for _, v := range Versions {
+			var buf bytes.Buffer
+			if err := v.Compress(msg, &buf); err != nil {
+				t.Fatalf("%s, %s: Compress failed: %v", v.Name, name, err)
+			}
+			decoded := &meshtastic.Telemetry{}
+			if err := v.Decompress(&buf, decoded); err != nil {
+				t.Fatalf("%s, %s: Decompress failed: %v", v.Name, name, err)
+			}
+			metrics := decoded.GetEnvironmentMetrics()
+			for field, got := range map[string]float32{"temperature": metrics.GetTemperature(), "voltage": metrics.GetVoltage()} {
+				if math.Float32bits(got) != bits {
+					t.Errorf("%s, %s: %s bits %#x, want %#x", v.Name, name, field, math.Float32bits(got), bits)
+				}
+			}
+		}
+	}
+}
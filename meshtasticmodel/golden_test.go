@@ -0,0 +1,86 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+// goldenHashes are the SHA-256 hashes of the data of goldenMessages for
+// every version. The format of a released version must not change, as data
+// it wrote earlier would no longer decode; a change of the coding is a new
+// version with a hash of its own.
+var goldenHashes = map[string]string{
+	"pbmodel":           "71f85bef87d31bedad93fc2707fa7ac36217580a8470032a977ec35805db7a56",
+	"pbmodel-o1":        "8643228181ed58a012360d50844cfd4ccd8feb30b753fde7bb0a61ff473a0abc",
+	"pbmodel-o2":        "8d3dc325f2353d5edc4726f77ca7c5248b6c3d771be7de5051658dc6e7edb10b",
+	"pbmodel-varint":    "a63946475b402940c9b59731df5430b148b1fcd49b8d7511933d9819db81cee9",
+	"pbmodel-varint-o1": "2636d361e63a516dd3790e3493a4af512b86cab1ab2ee524c179e4d21955a099",
+	"pbmodel-varint-o2": "52267247c3291ecabd47ed09516947e3f24fde78d87a9e8d594c69f29ce82063",
+	"V1":                "ac2c9cc3d0b4643be97a4198c422579728acc024de3a5371249c2448265d3bb9",
+	"V2":                "e66f6f4e9a00b0744aa9a3b81dd0fcc362870fa8f20edbde8a8fb2749c0bfe9a",
+	"V3":                "d23655e468b521bc3f68d04b64c102c02f9d2aff937b1d32844f06227b1d6a2e",
+	"V4":                "9a7ab96822dcdbbf3baadb028b54c1934eddb8b2fc511ff9e1931635b7a11325",
+	"V5":                "17deae069b5a22e45efb58bc5f34947299a54889fc8a0b4bfdac7423a0bdb61d",
+	"V6":                "837035da0ec6f9f4118e3dc9f6b7434b976d4124b2543ba6bf9980a25b35f4d7",
+	"V7":                "5fe823697fc2b69fd1c1dd67924498a8a55c7558e6a9f57d56e79bc06c51e327",
+	"V8":                "ff3fc3d06dec97dce68a10df5d6fcf59eb41ead669b01741e77eb9920eda34a3",
+	"V9":                "f6d9d371d6bb924d5e69646509df2ab03b24c29e0aef36ed658d091f96b54dfc",
+	"V10":               "c90a5ff403c6935e634d22c32678c6a218db4c6c2336eae49b6363b258c7126a",
+	"V11":               "dda1aa8e5458dd633bd6761edb936fe1a5f12f53a8aacef7f91361661dc15a3f",
+	"V12":               "73629e6531af9a76a2c3996f114cfc512704db86ce2d778aa06f3bd1b517a132",
+	"V13":               "7b2e7e9b62d8f9c5369105268b32c13d50a7a46c0fbdc570bc796e79e3df9b62",
+	"V14":               "c68c5b28e714c72c53bc2bf11f486af6e046fa2c6efe6cb92c92aa139195aa7b",
+}
+
+// goldenMessages returns messages covering the field kinds and the special
+// cases of the versions. They are built here instead of with meshfixtures,
+// so that changing the fixtures does not change the hashes.
+func goldenMessages() []proto.Message {
+	return []proto.Message{
+		&meshtastic.Position{LatitudeI: proto.Int32(375317890), LongitudeI: proto.Int32(-1223898570), Altitude: proto.Int32(100), Time: 1703520000, LocationSource: meshtastic.Position_LOC_MANUAL, PDOP: 150, SatsInView: 8, PrecisionBits: 32},
+		&meshtastic.User{Id: "!12345678", LongName: "Test User Node", ShortName: "TEST", Macaddr: []byte{1, 2, 3, 4, 5, 6}, HwModel: meshtastic.HardwareModel_TBEAM, PublicKey: bytes.Repeat([]byte{0xAA, 0x13}, 16)},
+		&meshtastic.User{Id: "!a1b2c3d4", LongName: "Ünïcödé Nöde 😀", ShortName: "Ü", HwModel: meshtastic.HardwareModel_HELTEC_V3, Role: meshtastic.Config_DeviceConfig_ROUTER},
+		&meshtastic.MeshPacket{From: 0x12345678, To: 0xffffffff, Channel: 0, Id: 42, HopLimit: 3, HopStart: 3, RxTime: 1703520000, RxSnr: 7.25, RxRssi: -90, Priority: meshtastic.MeshPacket_RELIABLE, PayloadVariant: &meshtastic.MeshPacket_Decoded{Decoded: &meshtastic.Data{Portnum: meshtastic.PortNum_TEXT_MESSAGE_APP, Payload: []byte("Hello everyone, meet at the park at noon.")}}},
+		&meshtastic.MeshPacket{From: 0x12345678, To: 0x87654321, Id: 43, PayloadVariant: &meshtastic.MeshPacket_Decoded{Decoded: &meshtastic.Data{Portnum: meshtastic.PortNum_TEXT_MESSAGE_APP, Payload: []byte("👍"), Emoji: 1, ReplyId: 42}}},
+		&meshtastic.MeshPacket{From: 1, To: 2, Id: 44, PayloadVariant: &meshtastic.MeshPacket_Encrypted{Encrypted: []byte{0xde, 0xad, 0xbe, 0xef, 0, 1, 2, 3, 4, 5, 6, 7}}},
+		&meshtastic.NodeInfo{Num: 0x12345678, User: &meshtastic.User{Id: "!12345678", LongName: "Base Station", ShortName: "BASE"}, Position: &meshtastic.Position{LatitudeI: proto.Int32(594370000), LongitudeI: proto.Int32(247536000)}, Snr: 9.5, LastHeard: 1703520100, DeviceMetrics: &meshtastic.DeviceMetrics{BatteryLevel: proto.Uint32(87), Voltage: proto.Float32(4.05), ChannelUtilization: proto.Float32(12.5), AirUtilTx: proto.Float32(1.25), UptimeSeconds: proto.Uint32(3600)}},
+		&meshtastic.Telemetry{Time: 1703520000, Variant: &meshtastic.Telemetry_EnvironmentMetrics{EnvironmentMetrics: &meshtastic.EnvironmentMetrics{Temperature: proto.Float32(21.5), RelativeHumidity: proto.Float32(45), BarometricPressure: proto.Float32(1013.25)}}},
+		&meshtastic.Telemetry{Time: 1703520060, Variant: &meshtastic.Telemetry_AirQualityMetrics{AirQualityMetrics: &meshtastic.AirQualityMetrics{Pm10Standard: proto.Uint32(5), Pm25Standard: proto.Uint32(7), Pm100Standard: proto.Uint32(9), Particles_03Um: proto.Uint32(612)}}},
+		&meshtastic.Waypoint{Id: 12345, LatitudeI: proto.Int32(375317890), LongitudeI: proto.Int32(-1223898570), Expire: 1703520000, Name: "Camp", Description: "Meeting point by the river", Icon: 0x26FA},
+		&meshtastic.Data{Portnum: meshtastic.PortNum_PRIVATE_APP, Payload: []byte{1, 2, 3, 0, 0, 255}},
+		&meshtastic.Data{Portnum: meshtastic.PortNum_TEXT_MESSAGE_APP, Payload: []byte{0xff, 0xfe, 'a'}},
+		&meshtastic.DeviceMetadata{FirmwareVersion: "2.5.15.abcdef1", DeviceStateVersion: 24, CanShutdown: true, Role: meshtastic.Config_DeviceConfig_CLIENT, HwModel: meshtastic.HardwareModel_HELTEC_V3},
+		&meshtastic.ChannelSettings{Psk: []byte{1}, Name: "LongFast", Id: 1},
+		&meshtastic.ChannelSettings{Psk: bytes.Repeat([]byte{0x5D, 0xE2, 0x19, 0x80}, 8), Name: "Secret", Id: 2},
+	}
+}
+
+func TestGoldenVectors(t *testing.T) {
+	for _, version := range Versions {
+		h := sha256.New()
+		for i, msg := range goldenMessages() {
+			var buf bytes.Buffer
+			if err := version.Compress(msg, &buf); err != nil {
+				t.Fatalf("%s: message %d: compress failed: %v", version.Name, i, err)
+			}
+			h.Write(binary.AppendUvarint(nil, uint64(buf.Len())))
+			h.Write(buf.Bytes())
+		}
+		got := hex.EncodeToString(h.Sum(nil))
+		want, ok := goldenHashes[version.Name]
+		if !ok {
+			t.Errorf("%s: no golden hash, add %q", version.Name, got)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: data changed, got hash %s, want %s", version.Name, got, want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Before exchanging compressed messages, the two ends of a link send each
+// other a Hello listing the versions and integrity checks they support.
+// Negotiate picks the same version and check on both ends from the two
+// hellos and the kind of transport, so that every transport gets the check
+// it needs without a global setting.
+
+// helloMagic starts encoded hellos.
+var helloMagic = [2]byte{0xb7, 0x48}
+
+// ErrNoCommonVersion is returned by Negotiate when the ends of a link
+// support no common version.
+var ErrNoCommonVersion = errors.New("meshtasticmodel: no common compression version")
+
+// ErrNoCommonIntegrity is returned by Negotiate when the ends of a link
+// support no integrity check at least as strong as the transport needs.
+var ErrNoCommonIntegrity = errors.New("meshtasticmodel: no common integrity check")
+
+// Hello is the capability handshake message of one end of a link.
+type Hello struct {
+	// Versions are the supported versions, most preferred first.
+	Versions []WireID
+	// Integrity are the supported integrity checks.
+	Integrity []Integrity
+}
+
+// DefaultHello returns a hello supporting all versions, newest first, and
+// all integrity checks.
+func DefaultHello() Hello {
+	hello := Hello{Integrity: []Integrity{IntegrityNone, IntegrityCRC, IntegrityHMAC}}
+	for _, v := range slices.Backward(Versions) {
+		hello.Versions = append(hello.Versions, v.ID)
+	}
+	return hello
+}
+
+// Marshal encodes the hello: the magic, the number of versions, the
+// versions, and a byte with a bit for each integrity check.
+func (h Hello) Marshal() ([]byte, error) {
+	if len(h.Versions) > 255 {
+		return nil, fmt.Errorf("meshtasticmodel: %d versions in hello, at most 255", len(h.Versions))
+	}
+	data := []byte{helloMagic[0], helloMagic[1], byte(len(h.Versions))}
+	for _, id := range h.Versions {
+		data = append(data, byte(id))
+	}
+	var checks byte
+	for _, check := range h.Integrity {
+		if !check.valid() {
+			return nil, fmt.Errorf("meshtasticmodel: unknown integrity %d in hello", uint8(check))
+		}
+		checks |= 1 << check
+	}
+	return append(data, checks), nil
+}
+
+// ParseHello decodes a hello encoded by Marshal. Unknown integrity checks
+// are ignored, so that newer peers can offer more of them.
+func ParseHello(data []byte) (Hello, error) {
+	if len(data) < len(helloMagic)+1 || !bytes.HasPrefix(data, helloMagic[:]) {
+		return Hello{}, errors.New("meshtasticmodel: not a hello")
+	}
+	data = data[len(helloMagic):]
+	count := int(data[0])
+	if len(data) != 1+count+1 {
+		return Hello{}, fmt.Errorf("meshtasticmodel: hello of %d versions has %d bytes", count, len(data)+len(helloMagic))
+	}
+
+	var h Hello
+	for _, id := range data[1 : 1+count] {
+		h.Versions = append(h.Versions, WireID(id))
+	}
+	checks := data[1+count]
+	for check := IntegrityNone; check.valid(); check++ {
+		if checks&(1<<check) != 0 {
+			h.Integrity = append(h.Integrity, check)
+		}
+	}
+	return h, nil
+}
+
+// Link is the format agreed on by both ends of a link.
+type Link struct {
+	Version   Version
+	Integrity Integrity
+	// Key is the shared secret of IntegrityHMAC, such as the channel key
+	// of an MQTT topic. It is not part of the handshake.
+	Key []byte
+}
+
+// Negotiate agrees on the format of a link over transport from the hellos
+// of the two ends. It gives the same result on both ends, whichever hello
+// is local. The version is the common one preferred most by the end whose
+// first preference is the older version, and the integrity check is the
+// transport's default or, when an end does not support it, the weakest
+// stronger check both support.
+func Negotiate(transport Transport, local, remote Hello) (Link, error) {
+	// Both ends must decide from the same preferences
+	first, second := local, remote
+	if len(remote.Versions) > 0 && (len(local.Versions) == 0 || remote.Versions[0] < local.Versions[0]) {
+		first, second = remote, local
+	}
+
+	var link Link
+	found := false
+	for _, id := range first.Versions {
+		if !slices.Contains(second.Versions, id) {
+			continue
+		}
+		if v, ok := VersionByID(id); ok {
+			link.Version, found = v, true
+			break
+		}
+	}
+	if !found {
+		return Link{}, ErrNoCommonVersion
+	}
+
+	for check := transport.DefaultIntegrity(); ; check++ {
+		if !check.valid() {
+			return Link{}, fmt.Errorf("%w for transport needing %s", ErrNoCommonIntegrity, transport.DefaultIntegrity())
+		}
+		if slices.Contains(local.Integrity, check) && slices.Contains(remote.Integrity, check) {
+			link.Integrity = check
+			break
+		}
+	}
+	return link, nil
+}
+
+// Compress compresses msg with the version of the link and appends the
+// integrity tag. The handshake agreed on the version, so the data has no
+// format header.
+func (link Link) Compress(msg proto.Message, w io.Writer) error {
+	var buf bytes.Buffer
+	if err := link.Version.Compress(msg, &buf, WithoutHeader()); err != nil {
+		return err
+	}
+	data, err := link.Integrity.seal(buf.Bytes(), link.Key)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Decompress verifies the integrity tag of the data written by Compress and
+// decompresses it into msg. Data that fails the check returns an error
+// wrapping ErrIntegrity before it is decoded.
+func (link Link) Decompress(r io.Reader, msg proto.Message) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	body, err := link.Integrity.open(data, link.Key)
+	if err != nil {
+		return err
+	}
+	return link.Version.Decompress(bytes.NewReader(body), msg, WithoutHeader())
+}
@@ -0,0 +1,129 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestHelloRoundtrip(t *testing.T) {
+	hello := Hello{Versions: []WireID{WireV14, WireV4}, Integrity: []Integrity{IntegrityNone, IntegrityCRC}}
+	data, err := hello.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	got, err := ParseHello(data)
+	if err != nil {
+		t.Fatalf("ParseHello failed: %v", err)
+	}
+	if !slicesEqual(got.Versions, hello.Versions) || !slicesEqual(got.Integrity, hello.Integrity) {
+		t.Errorf("ParseHello = %+v, want %+v", got, hello)
+	}
+
+	for _, bad := range [][]byte{nil, {0xb7}, {0xb7, 0x48, 2, byte(WireV14)}, {0, 0, 0, 0}} {
+		if _, err := ParseHello(bad); err == nil {
+			t.Errorf("ParseHello(%x) succeeded", bad)
+		}
+	}
+}
+
+func slicesEqual[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNegotiate(t *testing.T) {
+	all := DefaultHello()
+	noHMAC := Hello{Versions: []WireID{WireV13, WireV4}, Integrity: []Integrity{IntegrityNone, IntegrityCRC}}
+	crcOnly := Hello{Versions: []WireID{WireV4}, Integrity: []Integrity{IntegrityCRC}}
+
+	tests := []struct {
+		name          string
+		transport     Transport
+		a, b          Hello
+		wantVersion   WireID
+		wantIntegrity Integrity
+		wantErr       error
+	}{
+		{"lora", TransportLoRa, all, all, WireV14, IntegrityNone, nil},
+		{"serial", TransportSerial, all, all, WireV14, IntegrityCRC, nil},
+		{"mqtt", TransportMQTT, all, all, WireV14, IntegrityHMAC, nil},
+		{"older peer", TransportSerial, all, noHMAC, WireV13, IntegrityCRC, nil},
+		{"lora upgrades", TransportLoRa, all, crcOnly, WireV4, IntegrityCRC, nil},
+		{"mqtt without hmac", TransportMQTT, all, noHMAC, 0, 0, ErrNoCommonIntegrity},
+		{"no common version", TransportLoRa, noHMAC, Hello{Versions: []WireID{WireV1}, Integrity: []Integrity{IntegrityNone}}, 0, 0, ErrNoCommonVersion},
+	}
+	for _, test := range tests {
+		// Both ends agree, whichever hello is local
+		for _, order := range [][2]Hello{{test.a, test.b}, {test.b, test.a}} {
+			link, err := Negotiate(test.transport, order[0], order[1])
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Errorf("%s: got %v, want %v", test.name, err, test.wantErr)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: Negotiate failed: %v", test.name, err)
+			}
+			if link.Version.ID != test.wantVersion || link.Integrity != test.wantIntegrity {
+				t.Errorf("%s: got %s with %s, want %#x with %s", test.name, link.Version.Name, link.Integrity, uint8(test.wantVersion), test.wantIntegrity)
+			}
+		}
+	}
+}
+
+func TestLinkIntegrity(t *testing.T) {
+	packet := meshfixtures.PositionPacket(meshfixtures.Scenarios()[0])
+	key := []byte("channel key")
+
+	for _, transport := range []Transport{TransportLoRa, TransportSerial, TransportMQTT} {
+		link, err := Negotiate(transport, DefaultHello(), DefaultHello())
+		if err != nil {
+			t.Fatalf("Negotiate failed: %v", err)
+		}
+		link.Key = key
+
+		var buf bytes.Buffer
+		if err := link.Compress(packet, &buf); err != nil {
+			t.Fatalf("%s: Compress failed: %v", link.Integrity, err)
+		}
+		data := buf.Bytes()
+
+		result := &meshtastic.MeshPacket{}
+		if err := link.Decompress(bytes.NewReader(data), result); err != nil {
+			t.Fatalf("%s: Decompress failed: %v", link.Integrity, err)
+		}
+		if !proto.Equal(packet, result) {
+			t.Fatalf("%s: roundtrip mismatch", link.Integrity)
+		}
+
+		if link.Integrity == IntegrityNone {
+			continue
+		}
+		corrupt := bytes.Clone(data)
+		corrupt[len(corrupt)/2] ^= 0x10
+		if err := link.Decompress(bytes.NewReader(corrupt), &meshtastic.MeshPacket{}); !errors.Is(err, ErrIntegrity) {
+			t.Errorf("%s: corrupted data: got %v, want ErrIntegrity", link.Integrity, err)
+		}
+		if link.Integrity == IntegrityHMAC {
+			forged := link
+			forged.Key = []byte("other key")
+			if err := forged.Decompress(bytes.NewReader(data), &meshtastic.MeshPacket{}); !errors.Is(err, ErrIntegrity) {
+				t.Errorf("wrong key: got %v, want ErrIntegrity", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package meshtasticmodel
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// The compressed formats carry no marks of their own, so data decompressed
+// with the wrong version decodes into garbage instead of failing. A format
+// header prevents that: it starts with headerMagic and continues with the
+// WireID of the version and a byte of feature bits. Version.Compress and the
+// CompressV1 to CompressV14 functions write it, and Version.Decompress and
+// the DecompressV1 to DecompressV14 functions verify it.
+//
+// The header costs HeaderSize bytes per message. Links where every byte
+// counts, such as LoRa, can leave it out with WithoutHeader, and must then
+// agree on the version by other means, such as a Handshake.
+
+// headerMagic starts format headers.
+var headerMagic = [2]byte{0xb7, 0x5a}
+
+// HeaderSize is the size of a format header.
+const HeaderSize = len(headerMagic) + 2
+
+// Features are the feature bits of a format header. They mark coding
+// choices that are not implied by the version; none are defined yet, and
+// decoders reject data with bits they do not know.
+type Features uint8
+
+// knownFeatures are the feature bits this package understands.
+const knownFeatures Features = 0
+
+var (
+	// ErrNoHeader is returned for data that does not start with a format
+	// header.
+	ErrNoHeader = errors.New("meshtasticmodel: missing format header")
+
+	// ErrUnsupportedFeatures is returned for data whose header has feature
+	// bits that this package does not understand.
+	ErrUnsupportedFeatures = errors.New("meshtasticmodel: unsupported format features")
+)
+
+// VersionMismatchError is returned when data was compressed with another
+// version than the one decompressing it.
+type VersionMismatchError struct {
+	Want WireID // Version of the decompressor
+	Got  WireID // Version in the header
+}
+
+func (err *VersionMismatchError) Error() string {
+	got := fmt.Sprintf("%#x", uint8(err.Got))
+	if v, ok := VersionByID(err.Got); ok {
+		got = v.Name
+	}
+	want := fmt.Sprintf("%#x", uint8(err.Want))
+	if v, ok := VersionByID(err.Want); ok {
+		want = v.Name
+	}
+	return fmt.Sprintf("meshtasticmodel: data compressed with %s, not %s", got, want)
+}
+
+// WriteHeader writes a format header for the version id with features.
+func WriteHeader(w io.Writer, id WireID, features Features) error {
+	_, err := w.Write([]byte{headerMagic[0], headerMagic[1], byte(id), byte(features)})
+	return err
+}
+
+// ReadHeader reads a format header and returns the version and the
+// features in it. It reads exactly HeaderSize bytes.
+func ReadHeader(r io.Reader) (WireID, Features, error) {
+	var header [HeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, 0, ErrNoHeader
+		}
+		return 0, 0, err
+	}
+	if header[0] != headerMagic[0] || header[1] != headerMagic[1] {
+		return 0, 0, ErrNoHeader
+	}
+	id, features := WireID(header[2]), Features(header[3])
+	if features&^knownFeatures != 0 {
+		return 0, 0, fmt.Errorf("features %#x: %w", uint8(features&^knownFeatures), ErrUnsupportedFeatures)
+	}
+	return id, features, nil
+}
+
+// Option configures the compression and decompression of a version.
+type Option func(*options)
+
+// options holds the settings of the compression and decompression of a
+// version.
+type options struct {
+	noHeader bool
+}
+
+// WithoutHeader leaves out the format header. The data must be
+// decompressed with WithoutHeader and the same version, as data of another
+// version decodes into garbage instead of failing.
+func WithoutHeader() Option {
+	return func(o *options) { o.noHeader = true }
+}
+
+// collectOptions applies opts to the default options.
+func collectOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// compressHeader writes the format header of the version id, unless opts
+// leave it out.
+func compressHeader(w io.Writer, id WireID, opts []Option) error {
+	if collectOptions(opts).noHeader {
+		return nil
+	}
+	return WriteHeader(w, id, 0)
+}
+
+// decompressHeader reads the format header of data and verifies that it is
+// of the version id, unless opts leave it out.
+func decompressHeader(r io.Reader, id WireID, opts []Option) error {
+	if collectOptions(opts).noHeader {
+		return nil
+	}
+	got, _, err := ReadHeader(r)
+	if err != nil {
+		return err
+	}
+	if got != id {
+		return &VersionMismatchError{Want: id, Got: got}
+	}
+	return nil
+}
+
+// Compress compresses msg with v after a format header, see WithoutHeader.
+func (v Version) Compress(msg proto.Message, w io.Writer, opts ...Option) error {
+	return v.compress(msg, w, opts...)
+}
+
+// Decompress decompresses data written by Compress of the same version and
+// with the same options into msg. Data of another version fails with a
+// *VersionMismatchError and data without a header with ErrNoHeader, before
+// it is decoded.
+func (v Version) Decompress(r io.Reader, msg proto.Message, opts ...Option) error {
+	return v.decompress(r, msg, opts...)
+}
+
+// DecompressAny decompresses data written by Compress of any version in
+// Versions into msg and returns the version.
+func DecompressAny(r io.Reader, msg proto.Message) (Version, error) {
+	id, _, err := ReadHeader(r)
+	if err != nil {
+		return Version{}, err
+	}
+	v, ok := VersionByID(id)
+	if !ok {
+		return Version{}, fmt.Errorf("meshtasticmodel: unknown version %#x in header", uint8(id))
+	}
+	return v, v.decompress(r, msg, WithoutHeader())
+}
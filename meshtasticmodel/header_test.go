@@ -0,0 +1,111 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestHeader(t *testing.T) {
+	packet := meshfixtures.PositionPacket(meshfixtures.Default)
+	for _, version := range Versions {
+		var buf bytes.Buffer
+		if err := version.Compress(packet, &buf); err != nil {
+			t.Fatalf("%s: Compress failed: %v", version.Name, err)
+		}
+		data := buf.Bytes()
+		if id, _, err := ReadHeader(bytes.NewReader(data)); err != nil || id != version.ID {
+			t.Errorf("%s: header of %v, %v", version.Name, id, err)
+		}
+
+		result := &meshtastic.MeshPacket{}
+		if err := version.Decompress(bytes.NewReader(data), result); err != nil {
+			t.Fatalf("%s: Decompress failed: %v", version.Name, err)
+		}
+		if !proto.Equal(packet, result) {
+			t.Errorf("%s: roundtrip verification failed", version.Name)
+		}
+
+		result = &meshtastic.MeshPacket{}
+		found, err := DecompressAny(bytes.NewReader(data), result)
+		if err != nil {
+			t.Fatalf("%s: DecompressAny failed: %v", version.Name, err)
+		}
+		if found.ID != version.ID || !proto.Equal(packet, result) {
+			t.Errorf("%s: DecompressAny decoded as %s", version.Name, found.Name)
+		}
+
+		// Without the header the data is HeaderSize bytes shorter
+		var raw bytes.Buffer
+		if err := version.Compress(packet, &raw, WithoutHeader()); err != nil {
+			t.Fatalf("%s: Compress without header failed: %v", version.Name, err)
+		}
+		if !bytes.Equal(raw.Bytes(), data[HeaderSize:]) {
+			t.Errorf("%s: data without header differs", version.Name)
+		}
+		result = &meshtastic.MeshPacket{}
+		if err := version.Decompress(&raw, result, WithoutHeader()); err != nil || !proto.Equal(packet, result) {
+			t.Errorf("%s: Decompress without header: %v", version.Name, err)
+		}
+	}
+}
+
+func TestHeaderMismatch(t *testing.T) {
+	v2, _ := VersionByID(WireV2)
+	v5, _ := VersionByID(WireV5)
+	packet := meshfixtures.PositionPacket(meshfixtures.Default)
+
+	var buf bytes.Buffer
+	if err := v5.Compress(packet, &buf); err != nil {
+		t.Fatal(err)
+	}
+	err := v2.Decompress(bytes.NewReader(buf.Bytes()), &meshtastic.MeshPacket{})
+	var mismatch *VersionMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Got != WireV5 || mismatch.Want != WireV2 {
+		t.Errorf("V5 data decoded with V2: got %v, want a version mismatch", err)
+	}
+
+	// The functions of the versions verify the header too
+	buf.Reset()
+	if err := CompressV14(packet, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := DecompressV13(bytes.NewReader(buf.Bytes()), &meshtastic.MeshPacket{}); !errors.As(err, &mismatch) || mismatch.Got != WireV14 {
+		t.Errorf("V14 data decoded with DecompressV13: got %v, want a version mismatch", err)
+	}
+
+	// Data without a header
+	buf.Reset()
+	if err := v5.Compress(packet, &buf, WithoutHeader()); err != nil {
+		t.Fatal(err)
+	}
+	if err := v5.Decompress(bytes.NewReader(buf.Bytes()), &meshtastic.MeshPacket{}); !errors.Is(err, ErrNoHeader) {
+		t.Errorf("data without header: got %v, want ErrNoHeader", err)
+	}
+	if _, _, err := ReadHeader(bytes.NewReader(nil)); !errors.Is(err, ErrNoHeader) {
+		t.Errorf("empty data: got %v, want ErrNoHeader", err)
+	}
+
+	// Features from a later format
+	buf.Reset()
+	if err := WriteHeader(&buf, WireV5, 0x80); err != nil {
+		t.Fatal(err)
+	}
+	if err := v5.Decompress(&buf, &meshtastic.MeshPacket{}); !errors.Is(err, ErrUnsupportedFeatures) {
+		t.Errorf("unknown features: got %v, want ErrUnsupportedFeatures", err)
+	}
+
+	// Versions this package does not know
+	buf.Reset()
+	if err := WriteHeader(&buf, 0xff, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecompressAny(&buf, &meshtastic.MeshPacket{}); err == nil {
+		t.Error("unknown version decoded")
+	}
+}
@@ -0,0 +1,135 @@
+package meshtasticmodel
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// Compressed data has no redundancy, so corrupted data decodes into wrong
+// values instead of failing. Whether that needs an integrity check depends
+// on the transport: LoRa radios already drop frames that fail their CRC,
+// serial links do not check anything, and MQTT brokers relay messages that
+// anyone on the broker may have published. A Link appends the integrity tag
+// agreed on in the capability handshake to every compressed message.
+
+// Integrity is an integrity check of compressed messages.
+type Integrity uint8
+
+const (
+	// IntegrityNone appends nothing, for transports that check frames
+	// themselves.
+	IntegrityNone Integrity = iota
+	// IntegrityCRC appends a CRC-32C, which detects corruption.
+	IntegrityCRC
+	// IntegrityHMAC appends an HMAC-SHA256 truncated to 8 bytes, which also
+	// detects messages forged without the key.
+	IntegrityHMAC
+)
+
+// hmacTagSize is the size of a truncated HMAC tag.
+const hmacTagSize = 8
+
+// ErrIntegrity is returned when a message fails its integrity check.
+var ErrIntegrity = errors.New("meshtasticmodel: integrity check failed")
+
+var integrityNames = []string{"none", "crc32c", "hmac-sha256"}
+
+func (i Integrity) String() string {
+	if int(i) < len(integrityNames) {
+		return integrityNames[i]
+	}
+	return fmt.Sprintf("Integrity(%d)", uint8(i))
+}
+
+// Size returns the number of bytes the check adds to a message.
+func (i Integrity) Size() int {
+	switch i {
+	case IntegrityCRC:
+		return crc32.Size
+	case IntegrityHMAC:
+		return hmacTagSize
+	default:
+		return 0
+	}
+}
+
+// valid reports whether i is a known check.
+func (i Integrity) valid() bool {
+	return i <= IntegrityHMAC
+}
+
+// tag returns the integrity tag of data.
+func (i Integrity) tag(data, key []byte) ([]byte, error) {
+	switch i {
+	case IntegrityNone:
+		return nil, nil
+	case IntegrityCRC:
+		return binary.BigEndian.AppendUint32(nil, crc32.Checksum(data, checksumTable)), nil
+	case IntegrityHMAC:
+		if len(key) == 0 {
+			return nil, errors.New("meshtasticmodel: HMAC integrity needs a key")
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write(data)
+		return mac.Sum(nil)[:hmacTagSize], nil
+	default:
+		return nil, fmt.Errorf("meshtasticmodel: unknown integrity %d", uint8(i))
+	}
+}
+
+// seal appends the integrity tag of data to data.
+func (i Integrity) seal(data, key []byte) ([]byte, error) {
+	tag, err := i.tag(data, key)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, tag...), nil
+}
+
+// open verifies the integrity tag at the end of data and returns the data
+// without it.
+func (i Integrity) open(data, key []byte) ([]byte, error) {
+	if len(data) < i.Size() {
+		return nil, fmt.Errorf("%d bytes, shorter than the %s tag: %w", len(data), i, ErrIntegrity)
+	}
+	body, got := data[:len(data)-i.Size()], data[len(data)-i.Size():]
+	want, err := i.tag(body, key)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(got, want) {
+		return nil, fmt.Errorf("%s: %w", i, ErrIntegrity)
+	}
+	return body, nil
+}
+
+// checksumTable is the CRC-32C polynomial table.
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Transport is a kind of link that carries compressed messages.
+type Transport uint8
+
+const (
+	// TransportLoRa is a LoRa radio link, whose frames carry a CRC.
+	TransportLoRa Transport = iota
+	// TransportSerial is a serial or USB link to a device.
+	TransportSerial
+	// TransportMQTT is an MQTT broker shared with other clients.
+	TransportMQTT
+)
+
+// DefaultIntegrity returns the weakest integrity check suitable for t.
+func (t Transport) DefaultIntegrity() Integrity {
+	switch t {
+	case TransportSerial:
+		return IntegrityCRC
+	case TransportMQTT:
+		return IntegrityHMAC
+	default:
+		return IntegrityNone
+	}
+}
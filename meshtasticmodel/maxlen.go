@@ -0,0 +1,92 @@
+package meshtasticmodel
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
+)
+
+// fieldMaxLengths lists the documented maximum lengths (in bytes) of
+// Meshtastic string and bytes fields, keyed by full field name.
+// The limits follow the nanopb max_size options used by the firmware.
+var fieldMaxLengths = map[protoreflect.FullName]int{
+	"meshtastic.User.id":                         16,
+	"meshtastic.User.long_name":                  40,
+	"meshtastic.User.short_name":                 5,
+	"meshtastic.User.macaddr":                    6,
+	"meshtastic.User.public_key":                 32,
+	"meshtastic.Waypoint.name":                   30,
+	"meshtastic.Waypoint.description":            100,
+	"meshtastic.Data.payload":                    233,
+	"meshtastic.ChannelSettings.name":            12,
+	"meshtastic.ChannelSettings.psk":             32,
+	"meshtastic.MeshPacket.public_key":           32,
+	"meshtastic.MeshPacket.encrypted":            256,
+	"meshtastic.UserLite.long_name":              40,
+	"meshtastic.UserLite.short_name":             5,
+	"meshtastic.UserLite.macaddr":                6,
+	"meshtastic.UserLite.public_key":             32,
+	"meshtastic.DeviceMetadata.firmware_version": 18,
+}
+
+// MaxFieldLength returns the documented maximum length of a string or
+// bytes field in bytes. The second result is false when the field has no
+// known limit.
+func MaxFieldLength(fd protoreflect.FieldDescriptor) (int, bool) {
+	maxLen, ok := fieldMaxLengths[fd.FullName()]
+	return maxLen, ok
+}
+
+// checkFieldLength verifies that a value of length n fits the documented
+// maximum length of the field, when mcb bounds the lengths.
+func (mcb *ContextualModelBuilder) checkFieldLength(fd protoreflect.FieldDescriptor, n int) error {
+	if !mcb.fieldLengths {
+		return nil
+	}
+	if maxLen, ok := MaxFieldLength(fd); ok && n > maxLen {
+		return fmt.Errorf("length %d exceeds maximum %d", n, maxLen)
+	}
+	return nil
+}
+
+// encodeFieldLength encodes a field length, coding it uniformly in
+// [0, max] when mcb bounds the lengths and the field has a documented
+// maximum length.
+func encodeFieldLength(fd protoreflect.FieldDescriptor, n int, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	if err := mcb.checkFieldLength(fd, n); err != nil {
+		return err
+	}
+	if maxLen, ok := MaxFieldLength(fd); ok && mcb.fieldLengths {
+		return enc.EncodeUniform(uint64(n), uint64(maxLen))
+	}
+	return encodeVarintWithModels(uint64(n), enc, mcb)
+}
+
+// decodeFieldLength decodes a field length written by encodeFieldLength.
+func decodeFieldLength(fd protoreflect.FieldDescriptor, dec *coder.Decoder, mcb *ContextualModelBuilder) (int, error) {
+	if maxLen, ok := MaxFieldLength(fd); ok && mcb.fieldLengths {
+		n, err := dec.DecodeUniform(uint64(maxLen))
+		return int(n), err
+	}
+	n, err := decodeVarintWithModels(dec, mcb)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkDecodedLength(n, pbmodel.DefaultLimits.MaxBytes); err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// checkDecodedLength verifies a decoded length against a limit of
+// pbmodel.DefaultLimits, so that corrupted or hostile data fails before it
+// allocates or loops for the length.
+func checkDecodedLength(n uint64, limit int) error {
+	if n > uint64(limit) {
+		return fmt.Errorf("length %d exceeds limit %d: %w", n, limit, pbmodel.ErrLimit)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
+)
+
+func TestMaxFieldLengthRoundtrip(t *testing.T) {
+	msg := &meshtastic.User{
+		Id:        "!12345678",
+		LongName:  strings.Repeat("x", 40),
+		ShortName: "ABCDE",
+		Macaddr:   []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06},
+		PublicKey: bytes.Repeat([]byte{0xAA}, 32),
+	}
+
+	var buf bytes.Buffer
+	if err := CompressV11(msg, &buf); err != nil {
+		t.Fatalf("V11 compress failed: %v", err)
+	}
+
+	result := &meshtastic.User{}
+	if err := DecompressV11(&buf, result); err != nil {
+		t.Fatalf("V11 decompress failed: %v", err)
+	}
+	if !proto.Equal(msg, result) {
+		t.Error("V11 roundtrip verification failed")
+	}
+}
+
+func TestMaxFieldLengthExceeded(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+	}{
+		{
+			name: "long_name",
+			msg:  &meshtastic.User{LongName: strings.Repeat("x", 41)},
+		},
+		{
+			name: "short_name",
+			msg:  &meshtastic.User{ShortName: "TOOLONG"},
+		},
+		{
+			name: "macaddr",
+			msg:  &meshtastic.User{Macaddr: make([]byte, 7)},
+		},
+		{
+			name: "payload",
+			msg: &meshtastic.Data{
+				Portnum: meshtastic.PortNum_TEXT_MESSAGE_APP,
+				Payload: bytes.Repeat([]byte("a"), 234),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := CompressV11(tt.msg, &buf); err == nil {
+				t.Error("Expected error for field exceeding maximum length")
+			}
+
+			// The versions before V11 do not know the maximum lengths
+			buf.Reset()
+			if err := CompressV10(tt.msg, &buf); err != nil {
+				t.Fatalf("V10 compress failed: %v", err)
+			}
+			result := tt.msg.ProtoReflect().New().Interface()
+			if err := DecompressV10(&buf, result); err != nil {
+				t.Fatalf("V10 decompress failed: %v", err)
+			}
+			if !proto.Equal(tt.msg, result) {
+				t.Error("V10 roundtrip verification failed")
+			}
+		})
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	packet := meshfixtures.PositionPacket(meshfixtures.Default)
+
+	// The Data of a MeshPacket is nested two deep
+	mcb := newModelBuilderV13()
+	if err := mcb.SetLimits(pbmodel.Limits{MaxDepth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	err := compressMessageV10("", packet.ProtoReflect(), coder.NewEncoder(&bytes.Buffer{}), mcb)
+	if !errors.Is(err, pbmodel.ErrMaxDepth) {
+		t.Errorf("compress: got %v, want ErrMaxDepth", err)
+	}
+
+	var buf bytes.Buffer
+	if err := CompressV13(packet, &buf); err != nil {
+		t.Fatal(err)
+	}
+	mcb = newModelBuilderV13()
+	if err := mcb.SetLimits(pbmodel.Limits{MaxDepth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	dec, err := coder.NewDecoder(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = decompressMessageV10("", (&meshtastic.MeshPacket{}).ProtoReflect(), dec, mcb)
+	if !errors.Is(err, pbmodel.ErrMaxDepth) {
+		t.Errorf("decompress: got %v, want ErrMaxDepth", err)
+	}
+}
@@ -0,0 +1,70 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestMeshtasticV14ParticleCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics *meshtastic.AirQualityMetrics
+		smaller bool
+	}{
+		{"clean", &meshtastic.AirQualityMetrics{
+			Particles_03Um:  proto.Uint32(612),
+			Particles_05Um:  proto.Uint32(180),
+			Particles_10Um:  proto.Uint32(32),
+			Particles_25Um:  proto.Uint32(4),
+			Particles_50Um:  proto.Uint32(1),
+			Particles_100Um: proto.Uint32(0),
+		}, true},
+		{"smoke", &meshtastic.AirQualityMetrics{
+			Particles_03Um:  proto.Uint32(24310),
+			Particles_05Um:  proto.Uint32(7120),
+			Particles_10Um:  proto.Uint32(1480),
+			Particles_25Um:  proto.Uint32(96),
+			Particles_40Um:  proto.Uint32(20),
+			Particles_50Um:  proto.Uint32(12),
+			Particles_100Um: proto.Uint32(3),
+		}, false},
+		{"extremes", &meshtastic.AirQualityMetrics{
+			Particles_03Um:  proto.Uint32(0),
+			Particles_100Um: proto.Uint32(math.MaxUint32),
+		}, false},
+	}
+	for _, test := range tests {
+		telemetry := &meshtastic.Telemetry{
+			Time:    1703520000,
+			Variant: &meshtastic.Telemetry_AirQualityMetrics{AirQualityMetrics: test.metrics},
+		}
+
+		var bufV13, bufV14 bytes.Buffer
+		if err := CompressV13(telemetry, &bufV13); err != nil {
+			t.Fatalf("%s: V13 compress failed: %v", test.name, err)
+		}
+		if err := CompressV14(telemetry, &bufV14); err != nil {
+			t.Fatalf("%s: V14 compress failed: %v", test.name, err)
+		}
+		t.Logf("%s: V13: %d bytes, V14: %d bytes", test.name, bufV13.Len(), bufV14.Len())
+		if test.smaller && bufV14.Len() >= bufV13.Len() {
+			t.Errorf("%s: V14 (%d bytes) should be smaller than V13 (%d bytes)", test.name, bufV14.Len(), bufV13.Len())
+		}
+		if bufV14.Len() > bufV13.Len() {
+			t.Errorf("%s: V14 (%d bytes) should not be larger than V13 (%d bytes)", test.name, bufV14.Len(), bufV13.Len())
+		}
+
+		result := &meshtastic.Telemetry{}
+		if err := DecompressV14(&bufV14, result); err != nil {
+			t.Fatalf("%s: V14 decompress failed: %v", test.name, err)
+		}
+		if !proto.Equal(telemetry, result) {
+			t.Errorf("%s: V14 roundtrip verification failed\noriginal: %v\ndecoded:  %v", test.name, telemetry, result)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package meshtasticmodel
+
+import (
+	"sync"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// sharedEnglishPPMModel is the trained text model that V11+ builders start
+// from. Training it is most of the cost of creating a builder, so builders
+// copy it instead.
+var sharedEnglishPPMModel = sync.OnceValue(models.NewEnglishPPMModel)
+
+// builderPool reuses the model builders of a stateless version between
+// messages. A builder is reset to its initial state when it is returned, so
+// that the next message is coded as with a new builder.
+type builderPool struct {
+	pool       sync.Pool
+	newBuilder func() *ContextualModelBuilder
+}
+
+// Builder pools of the stateless versions.
+var (
+	v13Builders = &builderPool{newBuilder: newModelBuilderV13}
+	v14Builders = &builderPool{newBuilder: newModelBuilderV14}
+)
+
+// get returns a builder in its initial state.
+func (p *builderPool) get() *ContextualModelBuilder {
+	if mcb, ok := p.pool.Get().(*ContextualModelBuilder); ok {
+		return mcb
+	}
+	return p.newBuilder()
+}
+
+// put resets mcb and returns it to the pool.
+func (p *builderPool) put(mcb *ContextualModelBuilder) {
+	mcb.reset()
+	p.pool.Put(mcb)
+}
+
+// reset restores the initial state of a V13 or V14 builder, keeping the
+// memory of its model maps and of its text model. Session builders, which
+// carry state between messages, are not reset.
+func (mcb *ContextualModelBuilder) reset() {
+	mcb.AdaptiveModelBuilder.Reset()
+	mcb.currentPortNum = nil
+	mcb.messageType = ""
+	clear(mcb.contextModels)
+	clear(mcb.booleanModels)
+	mcb.textModel.ResetFrom(sharedEnglishPPMModel())
+	mcb.emojiTextModel = models.NewEmojiTextModel(mcb.textModel)
+}
@@ -0,0 +1,59 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestBuilderPool(t *testing.T) {
+	s := meshfixtures.Scenarios()[0]
+	packets := []*meshtastic.MeshPacket{
+		meshfixtures.TextPacket(s, meshfixtures.TextMessages[0]),
+		meshfixtures.PositionPacket(s),
+		meshfixtures.TelemetryPacket(s),
+		meshfixtures.TextPacket(s, meshfixtures.TextMessages[1]),
+	}
+
+	// Compressing with a new builder gives the reference output
+	var want [][]byte
+	for i, packet := range packets {
+		var buf bytes.Buffer
+		enc := coder.NewEncoder(&buf)
+		mcb := newModelBuilderV14()
+		mcb.SetMessageType(string(packet.ProtoReflect().Descriptor().Name()))
+		if err := compressMessageV10("", packet.ProtoReflect(), enc, mcb); err != nil {
+			t.Fatalf("packet %d: compress failed: %v", i, err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("packet %d: close failed: %v", i, err)
+		}
+		want = append(want, buf.Bytes())
+	}
+
+	// Pooled builders that coded other packets before give the same output
+	for round := range 3 {
+		for i, packet := range packets {
+			var buf bytes.Buffer
+			if err := CompressV14(packet, &buf, WithoutHeader()); err != nil {
+				t.Fatalf("round %d packet %d: CompressV14 failed: %v", round, i, err)
+			}
+			if !bytes.Equal(buf.Bytes(), want[i]) {
+				t.Fatalf("round %d packet %d: pooled builder output differs from a new builder", round, i)
+			}
+
+			result := &meshtastic.MeshPacket{}
+			if err := DecompressV14(&buf, result, WithoutHeader()); err != nil {
+				t.Fatalf("round %d packet %d: DecompressV14 failed: %v", round, i, err)
+			}
+			if !proto.Equal(packet, result) {
+				t.Fatalf("round %d packet %d: roundtrip mismatch", round, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package meshtasticmodel
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// Channel keys follow a few patterns. A single byte selects one of the
+// well-known keys built into the firmware: 1 is the default key, 2..10 are
+// the "simple" variants of it and 0 disables encryption. Private channels
+// use a random AES-128 or AES-256 key. V14 codes the class of the key
+// first, so that the common cases cost a couple of bits plus the random key
+// bytes, instead of a length followed by bytes coded with a generic model.
+
+// PSK classes, in the symbol order of sharedPSKClassModel.
+const (
+	pskClassDefault = iota // The single byte 0x01
+	pskClassSimple         // Any other single byte
+	pskClassAES128         // 16 random bytes
+	pskClassAES256         // 32 random bytes
+	pskClassOther          // Anything else up to maxPSKLength, with a length
+	pskClassCount
+)
+
+// defaultPSKIndex is the single byte that selects the default key.
+const defaultPSKIndex = 0x01
+
+// pskClass returns the class of psk.
+func pskClass(psk []byte) int {
+	switch {
+	case len(psk) == 1 && psk[0] == defaultPSKIndex:
+		return pskClassDefault
+	case len(psk) == 1:
+		return pskClassSimple
+	case len(psk) == 16:
+		return pskClassAES128
+	case len(psk) == 32:
+		return pskClassAES256
+	default:
+		return pskClassOther
+	}
+}
+
+// encodePSK encodes a channel key by class.
+func encodePSK(psk []byte, enc *coder.Encoder) error {
+	if len(psk) > maxPSKLength {
+		return fmt.Errorf("length %d exceeds maximum %d", len(psk), maxPSKLength)
+	}
+
+	class := pskClass(psk)
+	if err := enc.Encode(class, sharedPSKClassModel()); err != nil {
+		return err
+	}
+	switch class {
+	case pskClassDefault:
+		return nil
+	case pskClassSimple:
+		return enc.Encode(int(psk[0]), sharedSimplePSKModel())
+	case pskClassOther:
+		if err := enc.EncodeUniform(uint64(len(psk)), maxPSKLength); err != nil {
+			return err
+		}
+	}
+	for _, b := range psk {
+		if err := enc.EncodeUniform(uint64(b), 255); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodePSK decodes a channel key written by encodePSK.
+func decodePSK(dec *coder.Decoder) ([]byte, error) {
+	class, err := dec.Decode(sharedPSKClassModel())
+	if err != nil {
+		return nil, err
+	}
+
+	var length int
+	switch class {
+	case pskClassDefault:
+		return []byte{defaultPSKIndex}, nil
+	case pskClassSimple:
+		b, err := dec.Decode(sharedSimplePSKModel())
+		if err != nil {
+			return nil, err
+		}
+		if b == defaultPSKIndex {
+			return nil, errors.New("simple key uses the default key index")
+		}
+		return []byte{byte(b)}, nil
+	case pskClassAES128:
+		length = 16
+	case pskClassAES256:
+		length = 32
+	default:
+		n, err := dec.DecodeUniform(maxPSKLength)
+		if err != nil {
+			return nil, err
+		}
+		length = int(n)
+		if length == 0 {
+			return nil, nil
+		}
+	}
+
+	psk := make([]byte, length)
+	for i := range psk {
+		b, err := dec.DecodeUniform(255)
+		if err != nil {
+			return nil, err
+		}
+		psk[i] = byte(b)
+	}
+	return psk, nil
+}
+
+var (
+	// Most channels use the default key, private channels mostly use
+	// AES-256 keys generated by the apps
+	sharedPSKClassModel = sync.OnceValue(func() coder.Model {
+		return models.NewFrequencyTable([]uint64{500, 60, 120, 300, 20})
+	})
+
+	sharedSimplePSKModel = sync.OnceValue(createSimplePSKModel)
+)
+
+// createSimplePSKModel creates a model for single byte keys other than the
+// default one: no encryption and the simple keys 2..10 are likely.
+func createSimplePSKModel() coder.Model {
+	freqs := make([]uint64, 256)
+	for i := range freqs {
+		freqs[i] = 1
+	}
+	freqs[0] = 200
+	for i := 2; i <= 10; i++ {
+		freqs[i] = 100
+	}
+	// The default key has its own class
+	freqs[defaultPSKIndex] = 1
+	return models.NewFrequencyTable(freqs)
+}
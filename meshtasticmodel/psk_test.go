@@ -0,0 +1,57 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestMeshtasticV14ChannelKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		psk     []byte
+		smaller bool
+	}{
+		{"default", []byte{0x01}, false},
+		{"simple", []byte{0x05}, false},
+		{"disabled", []byte{0x00}, false},
+		{"aes128", bytes.Repeat([]byte{0xA7, 0x3C}, 8), true},
+		{"aes256", bytes.Repeat([]byte{0x5D, 0xE2, 0x19, 0x80}, 8), true},
+		{"other", []byte{1, 2, 3, 4, 5}, false},
+	}
+	for _, test := range tests {
+		settings := &meshtastic.ChannelSettings{
+			Psk:  test.psk,
+			Name: "LongFast",
+			Id:   1,
+		}
+
+		var bufV13, bufV14 bytes.Buffer
+		if err := CompressV13(settings, &bufV13); err != nil {
+			t.Fatalf("%s: V13 compress failed: %v", test.name, err)
+		}
+		if err := CompressV14(settings, &bufV14); err != nil {
+			t.Fatalf("%s: V14 compress failed: %v", test.name, err)
+		}
+		t.Logf("%s: V13: %d bytes, V14: %d bytes", test.name, bufV13.Len(), bufV14.Len())
+		if test.smaller && bufV14.Len() >= bufV13.Len() {
+			t.Errorf("%s: V14 (%d bytes) should be smaller than V13 (%d bytes)", test.name, bufV14.Len(), bufV13.Len())
+		}
+
+		result := &meshtastic.ChannelSettings{}
+		if err := DecompressV14(&bufV14, result); err != nil {
+			t.Fatalf("%s: V14 decompress failed: %v", test.name, err)
+		}
+		if !proto.Equal(settings, result) {
+			t.Errorf("%s: V14 roundtrip verification failed", test.name)
+		}
+	}
+
+	tooLong := &meshtastic.ChannelSettings{Psk: make([]byte, maxPSKLength+1)}
+	if err := CompressV14(tooLong, &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for an oversized key")
+	}
+}
@@ -0,0 +1,346 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// ErrStaleState is returned by SessionDecoder.Decode when a frame depends on
+// model state the decoder does not have, for example because an earlier frame
+// was lost. The decoder drops frames until the next keyframe.
+var ErrStaleState = errors.New("meshtasticmodel: stale session state")
+
+// ErrOldFrame is returned by SessionDecoder.Decode for duplicated or reordered
+// frames that were already decoded or superseded. The session state is not
+// affected.
+var ErrOldFrame = errors.New("meshtasticmodel: old session frame")
+
+// DefaultKeyframeInterval is the number of frames after which a session
+// encoder sends a keyframe even when no resync was requested.
+const DefaultKeyframeInterval = 64
+
+// frameKeyframe marks a frame that starts a new state epoch.
+const frameKeyframe = 1 << 0
+
+// SessionOptions configures a SessionEncoder.
+type SessionOptions struct {
+	// KeyframeInterval is the number of frames in an epoch before the encoder
+	// starts a new one with a keyframe. Shorter intervals limit how many
+	// frames are lost after a dropped frame on links where resync requests
+	// are slow or impossible, longer intervals let the models adapt for
+	// longer and compress better.
+	//
+	// Zero uses DefaultKeyframeInterval, 1 makes every frame a keyframe and
+	// a negative value disables periodic keyframes, so that keyframes are
+	// only sent on resync requests.
+	KeyframeInterval int
+
+	// DecayLimit is the count total at which the adaptive models halve their
+	// counts. A lower limit makes a long-running session follow changes in
+	// the traffic faster, for example when a node switches from telemetry to
+	// chat messages, at the cost of less precise statistics for steady
+	// traffic. Zero keeps the models' defaults.
+	//
+	// The encoder and decoder must use the same limit.
+	DecayLimit uint64
+
+	// AdminCorrelation codes AdminMessages, as carried in the payloads of
+	// ADMIN_APP packets, against the requests of the epoch: a response to
+	// an outstanding request codes only which request it answers and the
+	// requested content, and a repeated session passkey costs a fraction of
+	// a bit.
+	//
+	// The encoder and decoder must use the same setting.
+	AdminCorrelation bool
+
+	// MonotonicCounters codes uptimes and packet counters as the increase
+	// since the previous report of the same node in the epoch, falling back
+	// to the full value when a counter decreases after a reboot.
+	//
+	// The encoder and decoder must use the same setting.
+	MonotonicCounters bool
+
+	// FieldModels replaces the context-specific models of fields, for
+	// example to give the fields of an application's own messages the
+	// models of similar Meshtastic fields; start from DefaultFieldModels.
+	// Nil uses DefaultFieldModels.
+	//
+	// The encoder and decoder must use the same models.
+	FieldModels FieldModels
+
+	// EpochBase is the last epoch used by an earlier encoder of the session;
+	// the epochs of the encoder follow it. Decoders reject keyframes of
+	// epochs they have already seen as old, so an encoder that restarts
+	// should continue from the epoch it had reached, see
+	// SessionEncoder.Epoch. Decoders ignore it.
+	EpochBase uint64
+
+	// StateTrace receives a line with the hash of the model state after
+	// every coded frame, see SessionEncoder.StateHash. Encoders and decoders
+	// write the same lines for the same frames, so diffing their traces finds
+	// the first frame after which a decoder went out of sync. Nil disables
+	// the trace, which is meant for tests and debugging.
+	StateTrace io.Writer
+}
+
+// keyframeInterval returns the effective keyframe interval.
+func (opts SessionOptions) keyframeInterval() int {
+	if opts.KeyframeInterval == 0 {
+		return DefaultKeyframeInterval
+	}
+	return opts.KeyframeInterval
+}
+
+// newModelBuilder creates the models for a new epoch.
+func (opts SessionOptions) newModelBuilder() *ContextualModelBuilder {
+	mcb := newModelBuilderV13()
+	if opts.DecayLimit > 0 {
+		mcb.decayLimit = opts.DecayLimit
+		mcb.textModel.WithDecay(opts.DecayLimit)
+	}
+	if opts.AdminCorrelation {
+		mcb.admin = &adminState{}
+	}
+	if opts.MonotonicCounters {
+		mcb.counters = newCounterState()
+	}
+	if opts.FieldModels != nil {
+		mcb.SetFieldModels(opts.FieldModels)
+	}
+	return mcb
+}
+
+// SessionEncoder compresses a sequence of messages sent over one link. The
+// models are shared between messages, so later messages benefit from what
+// the earlier ones taught the adaptive models.
+//
+// The shared state is split into epochs. Every epoch starts with a keyframe,
+// which is coded with fresh models and can be decoded on its own, and
+// continues with frames that depend on all previous frames of the epoch.
+// Frames carry the epoch and a sequence number so that the decoder can
+// detect when its state went stale. Keyframes are sent periodically and
+// when the decoder requests a resync.
+type SessionEncoder struct {
+	mcb    *ContextualModelBuilder
+	shared bool // mcb is shared with a snapshot and must be copied before coding
+	epoch  uint64
+	seq    uint64
+
+	opts             SessionOptions
+	keyframeInterval int
+
+	sinceKeyframe int
+	forceKeyframe bool
+}
+
+// NewSessionEncoder creates a session encoder with default options. The first
+// frame is a keyframe.
+func NewSessionEncoder() *SessionEncoder {
+	return NewSessionEncoderWithOptions(SessionOptions{})
+}
+
+// NewSessionEncoderWithOptions creates a session encoder with the given
+// options. The first frame is a keyframe.
+func NewSessionEncoderWithOptions(opts SessionOptions) *SessionEncoder {
+	return &SessionEncoder{
+		epoch:            opts.EpochBase,
+		opts:             opts,
+		keyframeInterval: opts.keyframeInterval(),
+		forceKeyframe:    true,
+	}
+}
+
+// Encode compresses msg into a single frame.
+func (s *SessionEncoder) Encode(msg proto.Message) ([]byte, error) {
+	var flags byte
+	if s.forceKeyframe || (s.keyframeInterval > 0 && s.sinceKeyframe >= s.keyframeInterval) {
+		s.mcb, s.shared = s.opts.newModelBuilder(), false
+		s.epoch++
+		s.seq = 0
+		s.sinceKeyframe = 0
+		s.forceKeyframe = false
+		flags |= frameKeyframe
+	} else {
+		s.seq++
+	}
+	if s.shared {
+		s.mcb, s.shared = s.mcb.clone(), false
+	}
+	s.sinceKeyframe++
+
+	frame := []byte{flags}
+	frame = binary.AppendUvarint(frame, s.epoch)
+	frame = binary.AppendUvarint(frame, s.seq)
+
+	buf := bytes.NewBuffer(frame)
+	if err := compressSessionMessage(msg, buf, s.mcb); err != nil {
+		// The models may have been partially updated
+		s.forceKeyframe = true
+		return nil, err
+	}
+	s.opts.traceState(s.epoch, s.seq, s.mcb)
+	return buf.Bytes(), nil
+}
+
+// Epoch returns the epoch of the last encoded frame, or the EpochBase of
+// the options before the first frame.
+func (s *SessionEncoder) Epoch() uint64 {
+	return s.epoch
+}
+
+// HandleResyncRequest processes a request created by
+// SessionDecoder.ResyncRequest. The next frame is a keyframe.
+func (s *SessionEncoder) HandleResyncRequest(req []byte) error {
+	epoch, _, err := parseResyncRequest(req)
+	if err != nil {
+		return err
+	}
+	if epoch > s.epoch {
+		return fmt.Errorf("resync request for future epoch %d, current %d", epoch, s.epoch)
+	}
+	s.forceKeyframe = true
+	return nil
+}
+
+// SessionDecoder decompresses frames created by a SessionEncoder.
+type SessionDecoder struct {
+	mcb    *ContextualModelBuilder
+	shared bool // mcb is shared with a snapshot and must be copied before coding
+	epoch  uint64
+	seq    uint64
+	stale  bool
+
+	opts SessionOptions
+}
+
+// NewSessionDecoder creates a session decoder with default options. It needs
+// a keyframe before it can decode other frames.
+func NewSessionDecoder() *SessionDecoder {
+	return NewSessionDecoderWithOptions(SessionOptions{})
+}
+
+// NewSessionDecoderWithOptions creates a session decoder with the options
+// used by the encoder. It needs a keyframe before it can decode other frames.
+func NewSessionDecoderWithOptions(opts SessionOptions) *SessionDecoder {
+	return &SessionDecoder{stale: true, opts: opts}
+}
+
+// Decode decompresses a frame into msg.
+//
+// It returns ErrOldFrame for frames that were already decoded or belong to an
+// earlier epoch, and ErrStaleState when the frame cannot be decoded with the
+// current state. After ErrStaleState, NeedsResync reports true until the next
+// keyframe arrives.
+func (s *SessionDecoder) Decode(frame []byte, msg proto.Message) error {
+	r := bytes.NewReader(frame)
+	flags, err := r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("frame flags: %w", err)
+	}
+	epoch, err := binary.ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("frame epoch: %w", err)
+	}
+	seq, err := binary.ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("frame sequence: %w", err)
+	}
+
+	if flags&frameKeyframe != 0 {
+		if epoch < s.epoch || (epoch == s.epoch && s.mcb != nil) {
+			return fmt.Errorf("keyframe epoch %d: %w", epoch, ErrOldFrame)
+		}
+		s.mcb, s.shared = s.opts.newModelBuilder(), false
+		s.epoch = epoch
+		s.seq = seq
+		s.stale = false
+	} else {
+		switch {
+		case epoch < s.epoch || (epoch == s.epoch && seq <= s.seq && s.mcb != nil):
+			return fmt.Errorf("frame %d of epoch %d: %w", seq, epoch, ErrOldFrame)
+		case s.stale || epoch != s.epoch || seq != s.seq+1:
+			s.stale = true
+			return fmt.Errorf("frame %d of epoch %d after frame %d of epoch %d: %w", seq, epoch, s.seq, s.epoch, ErrStaleState)
+		}
+		s.seq = seq
+	}
+	if s.shared {
+		s.mcb, s.shared = s.mcb.clone(), false
+	}
+
+	if err := decompressSessionMessage(r, msg, s.mcb); err != nil {
+		// The models may have been partially updated
+		s.stale = true
+		return err
+	}
+	s.opts.traceState(s.epoch, s.seq, s.mcb)
+	return nil
+}
+
+// NeedsResync reports whether the decoder is waiting for a keyframe.
+func (s *SessionDecoder) NeedsResync() bool {
+	return s.stale
+}
+
+// ResyncRequest creates a request for the encoder to send a keyframe. It
+// contains the epoch and sequence number of the last decoded frame.
+func (s *SessionDecoder) ResyncRequest() []byte {
+	req := binary.AppendUvarint(nil, s.epoch)
+	return binary.AppendUvarint(req, s.seq)
+}
+
+// parseResyncRequest parses a request created by ResyncRequest.
+func parseResyncRequest(req []byte) (epoch, seq uint64, err error) {
+	r := bytes.NewReader(req)
+	if epoch, err = binary.ReadUvarint(r); err != nil {
+		return 0, 0, fmt.Errorf("resync epoch: %w", err)
+	}
+	if seq, err = binary.ReadUvarint(r); err != nil {
+		return 0, 0, fmt.Errorf("resync sequence: %w", err)
+	}
+	return epoch, seq, nil
+}
+
+// compressSessionMessage compresses msg with V13 using the session models.
+func compressSessionMessage(msg proto.Message, w *bytes.Buffer, mcb *ContextualModelBuilder) error {
+	enc := coder.NewEncoder(w)
+	mcb.currentPortNum = nil
+	if mcb.counters != nil {
+		mcb.counters.node = 0
+	}
+	mcb.SetMessageType(string(msg.ProtoReflect().Descriptor().Name()))
+	if mcb.admin != nil && msg.ProtoReflect().Descriptor() == adminDescriptor {
+		if err := compressAdminMessage(msg.ProtoReflect(), enc, mcb); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	if err := compressMessageV10("", msg.ProtoReflect(), enc, mcb); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// decompressSessionMessage decompresses msg with V13 using the session models.
+func decompressSessionMessage(r *bytes.Reader, msg proto.Message, mcb *ContextualModelBuilder) error {
+	dec, err := coder.NewDecoder(r)
+	if err != nil {
+		return err
+	}
+	mcb.currentPortNum = nil
+	if mcb.counters != nil {
+		mcb.counters.node = 0
+	}
+	mcb.SetMessageType(string(msg.ProtoReflect().Descriptor().Name()))
+	if mcb.admin != nil && msg.ProtoReflect().Descriptor() == adminDescriptor {
+		return decompressAdminMessage(msg.ProtoReflect(), dec, mcb)
+	}
+	return decompressMessageV10("", msg.ProtoReflect(), dec, mcb)
+}
@@ -0,0 +1,194 @@
+package meshtasticmodel
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+// lossModel describes how an unreliable link mangles frames.
+type lossModel struct {
+	name string
+
+	drop      float64 // probability that a frame is lost
+	duplicate float64 // probability that a frame is delivered twice
+	reorder   float64 // probability that a frame is delayed past the next one
+
+	feedbackDrop  float64 // probability that a resync request is lost
+	feedbackDelay int     // frames sent before a resync request arrives
+
+	keyframeInterval int // encoder keyframe interval, 0 for the default
+
+	// maxLatency bounds the number of frames between the decoder going
+	// stale and the encoder sending a keyframe.
+	maxLatency int
+	// minDecoded is the minimum fraction of frames that must be decoded.
+	minDecoded float64
+}
+
+// lossFrame is a frame in flight together with the index of its packet.
+type lossFrame struct {
+	index int
+	data  []byte
+}
+
+// lossRequest is a resync request in flight.
+type lossRequest struct {
+	due  int
+	data []byte
+}
+
+// lossResult summarizes a simulated session.
+type lossResult struct {
+	sent, decoded, stale, old int
+	maxLatency                int
+}
+
+// simulateLoss sends packets from a session encoder to a session decoder over
+// a link described by lm. It fails the test when the decoder returns a wrong
+// message or an unexpected error.
+func simulateLoss(t *testing.T, lm lossModel, packets []*meshtastic.MeshPacket, rng *rand.Rand) lossResult {
+	t.Helper()
+
+	enc := NewSessionEncoderWithOptions(SessionOptions{KeyframeInterval: lm.keyframeInterval})
+	dec := NewSessionDecoder()
+
+	var result lossResult
+	var held []lossFrame
+	var requests []lossRequest
+	staleSince := -1
+
+	deliver := func(f lossFrame) {
+		msg := &meshtastic.MeshPacket{}
+		err := dec.Decode(f.data, msg)
+		switch {
+		case err == nil:
+			if !proto.Equal(packets[f.index], msg) {
+				t.Fatalf("%s: packet %d: decoded a wrong message", lm.name, f.index)
+			}
+			result.decoded++
+		case errors.Is(err, ErrOldFrame):
+			result.old++
+		case errors.Is(err, ErrStaleState):
+			result.stale++
+		default:
+			t.Fatalf("%s: packet %d: unexpected error: %v", lm.name, f.index, err)
+		}
+	}
+
+	for i, packet := range packets {
+		// Deliver the resync requests that have arrived by now
+		pending := requests[:0]
+		for _, req := range requests {
+			if req.due > i {
+				pending = append(pending, req)
+				continue
+			}
+			if err := enc.HandleResyncRequest(req.data); err != nil {
+				t.Fatalf("%s: HandleResyncRequest failed: %v", lm.name, err)
+			}
+		}
+		requests = pending
+
+		frame, err := enc.Encode(packet)
+		if err != nil {
+			t.Fatalf("%s: packet %d: Encode failed: %v", lm.name, i, err)
+		}
+		result.sent++
+		if frame[0]&frameKeyframe != 0 && staleSince >= 0 {
+			result.maxLatency = max(result.maxLatency, i-staleSince)
+			staleSince = -1
+		}
+
+		var arrived []lossFrame
+		if rng.Float64() >= lm.drop {
+			f := lossFrame{index: i, data: frame}
+			if rng.Float64() < lm.reorder {
+				held = append(held, f)
+			} else {
+				arrived = append(arrived, f)
+				if rng.Float64() < lm.duplicate {
+					arrived = append(arrived, f)
+				}
+				arrived = append(arrived, held...)
+				held = held[:0]
+			}
+		}
+		for _, f := range arrived {
+			deliver(f)
+		}
+
+		if dec.NeedsResync() {
+			if staleSince < 0 {
+				staleSince = i
+			}
+			if rng.Float64() >= lm.feedbackDrop {
+				requests = append(requests, lossRequest{
+					due:  i + 1 + lm.feedbackDelay,
+					data: dec.ResyncRequest(),
+				})
+			}
+		}
+	}
+
+	return result
+}
+
+func TestSessionLossSimulation(t *testing.T) {
+	packets := sessionPackets()
+	for len(packets) < 1000 {
+		packets = append(packets, packets...)
+	}
+
+	lossModels := []lossModel{
+		{name: "lossless", minDecoded: 1},
+		{name: "duplicate", duplicate: 0.2, minDecoded: 1},
+		{name: "drop", drop: 0.1, maxLatency: 1, minDecoded: 0.8},
+		{name: "reorder", reorder: 0.1, maxLatency: 1, minDecoded: 0.75},
+		{name: "delayed feedback", drop: 0.05, feedbackDelay: 3, maxLatency: 4, minDecoded: 0.75},
+		{
+			name: "mixed",
+			drop: 0.05, duplicate: 0.05, reorder: 0.05,
+			feedbackDelay: 2,
+			maxLatency:    3,
+			minDecoded:    0.65,
+		},
+		{
+			// Without a working back channel only periodic keyframes
+			// bring the decoder back
+			name: "no feedback",
+			drop: 0.05, feedbackDrop: 1,
+			keyframeInterval: 16,
+			maxLatency:       16,
+			minDecoded:       0.6,
+		},
+		{
+			name: "lossy feedback",
+			drop: 0.1, feedbackDrop: 0.5, feedbackDelay: 1,
+			keyframeInterval: 32,
+			maxLatency:       32,
+			minDecoded:       0.6,
+		},
+	}
+
+	for _, lm := range lossModels {
+		t.Run(lm.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(518))
+			result := simulateLoss(t, lm, packets, rng)
+
+			t.Logf("sent %d, decoded %d, stale %d, old %d, keyframe latency %d",
+				result.sent, result.decoded, result.stale, result.old, result.maxLatency)
+
+			if result.maxLatency > lm.maxLatency {
+				t.Errorf("keyframe sent %d frames after the decoder went stale, want at most %d", result.maxLatency, lm.maxLatency)
+			}
+			if decoded := float64(result.decoded) / float64(result.sent); decoded < lm.minDecoded {
+				t.Errorf("decoded %.1f%% of frames, want at least %.1f%%", decoded*100, lm.minDecoded*100)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+// sessionPackets returns a stream of packets as seen on a busy link.
+func sessionPackets() []*meshtastic.MeshPacket {
+	var packets []*meshtastic.MeshPacket
+	for i := 0; i < 10; i++ {
+		for _, s := range meshfixtures.Scenarios() {
+			packets = append(packets,
+				meshfixtures.PositionPacket(s),
+				meshfixtures.TelemetryPacket(s),
+				meshfixtures.TextPacket(s, meshfixtures.TextMessages[i%len(meshfixtures.TextMessages)]),
+			)
+		}
+	}
+	return packets
+}
+
+func TestSessionRoundtrip(t *testing.T) {
+	enc := NewSessionEncoder()
+	dec := NewSessionDecoder()
+
+	var sessionSize, standaloneSize int
+	for i, packet := range sessionPackets() {
+		frame, err := enc.Encode(packet)
+		if err != nil {
+			t.Fatalf("packet %d: Encode failed: %v", i, err)
+		}
+		sessionSize += len(frame)
+
+		var buf bytes.Buffer
+		if err := CompressV13(packet, &buf); err != nil {
+			t.Fatalf("packet %d: CompressV13 failed: %v", i, err)
+		}
+		standaloneSize += buf.Len()
+
+		result := &meshtastic.MeshPacket{}
+		if err := dec.Decode(frame, result); err != nil {
+			t.Fatalf("packet %d: Decode failed: %v", i, err)
+		}
+		if !proto.Equal(packet, result) {
+			t.Fatalf("packet %d: roundtrip verification failed", i)
+		}
+	}
+
+	t.Logf("standalone V13: %d bytes, session: %d bytes", standaloneSize, sessionSize)
+	if sessionSize >= standaloneSize {
+		t.Errorf("session (%d bytes) should be smaller than standalone (%d bytes)", sessionSize, standaloneSize)
+	}
+}
+
+func TestSessionResync(t *testing.T) {
+	enc := NewSessionEncoder()
+	dec := NewSessionDecoder()
+	packets := sessionPackets()
+
+	decode := func(i int, frame []byte) error {
+		result := &meshtastic.MeshPacket{}
+		err := dec.Decode(frame, result)
+		if err == nil && !proto.Equal(packets[i], result) {
+			t.Fatalf("packet %d: roundtrip verification failed", i)
+		}
+		return err
+	}
+
+	var frames [][]byte
+	for i := 0; i < 5; i++ {
+		frame, err := enc.Encode(packets[i])
+		if err != nil {
+			t.Fatalf("packet %d: Encode failed: %v", i, err)
+		}
+		frames = append(frames, frame)
+	}
+
+	// Frame 2 is lost
+	for _, i := range []int{0, 1} {
+		if err := decode(i, frames[i]); err != nil {
+			t.Fatalf("packet %d: Decode failed: %v", i, err)
+		}
+	}
+	if err := decode(3, frames[3]); !errors.Is(err, ErrStaleState) {
+		t.Fatalf("packet 3: got %v, want ErrStaleState", err)
+	}
+	if err := decode(4, frames[4]); !errors.Is(err, ErrStaleState) {
+		t.Fatalf("packet 4: got %v, want ErrStaleState", err)
+	}
+	if !dec.NeedsResync() {
+		t.Fatal("decoder does not request a resync")
+	}
+
+	// Duplicates of decoded frames are rejected without touching the state
+	if err := decode(1, frames[1]); !errors.Is(err, ErrOldFrame) {
+		t.Fatalf("duplicate packet 1: got %v, want ErrOldFrame", err)
+	}
+
+	if err := enc.HandleResyncRequest(dec.ResyncRequest()); err != nil {
+		t.Fatalf("HandleResyncRequest failed: %v", err)
+	}
+	for i := 5; i < 8; i++ {
+		frame, err := enc.Encode(packets[i])
+		if err != nil {
+			t.Fatalf("packet %d: Encode failed: %v", i, err)
+		}
+		if err := decode(i, frame); err != nil {
+			t.Fatalf("packet %d after resync: Decode failed: %v", i, err)
+		}
+	}
+	if dec.NeedsResync() {
+		t.Error("decoder still needs a resync after the keyframe")
+	}
+}
+
+func TestSessionPeriodicKeyframe(t *testing.T) {
+	enc := NewSessionEncoder()
+	packet := meshfixtures.PositionPacket(meshfixtures.Default)
+
+	var lastFrame []byte
+	for i := 0; i <= DefaultKeyframeInterval; i++ {
+		frame, err := enc.Encode(packet)
+		if err != nil {
+			t.Fatalf("packet %d: Encode failed: %v", i, err)
+		}
+		lastFrame = frame
+	}
+
+	// A fresh decoder can join the session at the periodic keyframe
+	dec := NewSessionDecoder()
+	result := &meshtastic.MeshPacket{}
+	if err := dec.Decode(lastFrame, result); err != nil {
+		t.Fatalf("Decode of periodic keyframe failed: %v", err)
+	}
+	if !proto.Equal(packet, result) {
+		t.Error("roundtrip verification failed")
+	}
+}
+
+func TestSessionEpochBase(t *testing.T) {
+	packet := meshfixtures.PositionPacket(meshfixtures.Default)
+	enc := NewSessionEncoder()
+	dec := NewSessionDecoder()
+	for i := 0; i < 3; i++ {
+		frame, err := enc.Encode(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := dec.Decode(frame, &meshtastic.MeshPacket{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.HandleResyncRequest(dec.ResyncRequest()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A restarted encoder is rejected unless it continues the epochs
+	restarted := NewSessionEncoder()
+	frame, err := restarted.Encode(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(frame, &meshtastic.MeshPacket{}); !errors.Is(err, ErrOldFrame) {
+		t.Errorf("keyframe of a restarted encoder: got %v, want ErrOldFrame", err)
+	}
+
+	restarted = NewSessionEncoderWithOptions(SessionOptions{EpochBase: enc.Epoch()})
+	frame, err = restarted.Encode(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &meshtastic.MeshPacket{}
+	if err := dec.Decode(frame, result); err != nil {
+		t.Fatalf("keyframe of a continued encoder: %v", err)
+	}
+	if !proto.Equal(packet, result) {
+		t.Error("roundtrip verification failed")
+	}
+}
+
+func TestSessionKeyframeInterval(t *testing.T) {
+	packets := sessionPackets()
+
+	for _, interval := range []int{1, 8, DefaultKeyframeInterval, -1} {
+		enc := NewSessionEncoderWithOptions(SessionOptions{KeyframeInterval: interval})
+		dec := NewSessionDecoder()
+
+		var size, keyframes int
+		for i, packet := range packets {
+			frame, err := enc.Encode(packet)
+			if err != nil {
+				t.Fatalf("interval %d, packet %d: Encode failed: %v", interval, i, err)
+			}
+			size += len(frame)
+			if frame[0]&frameKeyframe != 0 {
+				keyframes++
+			}
+
+			result := &meshtastic.MeshPacket{}
+			if err := dec.Decode(frame, result); err != nil {
+				t.Fatalf("interval %d, packet %d: Decode failed: %v", interval, i, err)
+			}
+			if !proto.Equal(packet, result) {
+				t.Fatalf("interval %d, packet %d: roundtrip verification failed", interval, i)
+			}
+		}
+
+		want := 1
+		if interval > 0 {
+			want = (len(packets) + interval - 1) / interval
+		}
+		if keyframes != want {
+			t.Errorf("interval %d: got %d keyframes, want %d", interval, keyframes, want)
+		}
+		t.Logf("interval %d: %d keyframes, %d bytes", interval, keyframes, size)
+	}
+}
+
+func TestSessionDecay(t *testing.T) {
+	// A node that switches from telemetry-heavy to chat-heavy traffic
+	var packets []*meshtastic.MeshPacket
+	for i := 0; i < 20; i++ {
+		for _, s := range meshfixtures.Scenarios() {
+			packets = append(packets, meshfixtures.TelemetryPacket(s))
+		}
+	}
+	for i := 0; i < 5; i++ {
+		for _, s := range meshfixtures.Scenarios() {
+			packets = append(packets, meshfixtures.TextPacket(s, meshfixtures.TextMessages[i%len(meshfixtures.TextMessages)]))
+		}
+	}
+
+	for _, decay := range []uint64{0, 1024, 256} {
+		opts := SessionOptions{KeyframeInterval: -1, DecayLimit: decay}
+		enc := NewSessionEncoderWithOptions(opts)
+		dec := NewSessionDecoderWithOptions(opts)
+
+		var size int
+		for i, packet := range packets {
+			frame, err := enc.Encode(packet)
+			if err != nil {
+				t.Fatalf("decay %d, packet %d: Encode failed: %v", decay, i, err)
+			}
+			size += len(frame)
+
+			result := &meshtastic.MeshPacket{}
+			if err := dec.Decode(frame, result); err != nil {
+				t.Fatalf("decay %d, packet %d: Decode failed: %v", decay, i, err)
+			}
+			if !proto.Equal(packet, result) {
+				t.Fatalf("decay %d, packet %d: roundtrip verification failed", decay, i)
+			}
+		}
+		t.Logf("decay %d: %d bytes", decay, size)
+	}
+}
+
+func TestSessionStateTrace(t *testing.T) {
+	packets := sessionPackets()[:60]
+
+	trace := func(encOpts, decOpts SessionOptions) (encTrace, decTrace string) {
+		var encBuf, decBuf bytes.Buffer
+		encOpts.StateTrace, decOpts.StateTrace = &encBuf, &decBuf
+		enc := NewSessionEncoderWithOptions(encOpts)
+		dec := NewSessionDecoderWithOptions(decOpts)
+		for i, packet := range packets {
+			frame, err := enc.Encode(packet)
+			if err != nil {
+				t.Fatalf("packet %d: Encode failed: %v", i, err)
+			}
+			// A decoder out of sync decodes garbage or fails
+			_ = dec.Decode(frame, &meshtastic.MeshPacket{})
+			if dec.NeedsResync() {
+				break
+			}
+		}
+		return encBuf.String(), decBuf.String()
+	}
+
+	encTrace, decTrace := trace(SessionOptions{KeyframeInterval: 16}, SessionOptions{KeyframeInterval: 16})
+	if encTrace != decTrace {
+		t.Errorf("traces of a session in sync differ")
+	}
+	if lines := strings.Count(encTrace, "\n"); lines != len(packets) {
+		t.Errorf("trace has %d lines, want %d", lines, len(packets))
+	}
+
+	// Mismatched decay limits desync the models without corrupting frames
+	encTrace, decTrace = trace(SessionOptions{DecayLimit: 64}, SessionOptions{})
+	encLines, decLines := strings.Split(encTrace, "\n"), strings.Split(decTrace, "\n")
+	first := 0
+	for first < len(decLines) && encLines[first] == decLines[first] {
+		first++
+	}
+	if first == len(decLines) {
+		t.Fatalf("traces of a desynced session do not differ")
+	}
+	t.Logf("first desynced frame: %s", encLines[first])
+}
@@ -0,0 +1,171 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// FindVersion returns the version with the given name from Versions.
+func FindVersion(name string) (Version, bool) {
+	for _, v := range Versions {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Version{}, false
+}
+
+// ShadowStats summarizes a shadow evaluation of a candidate version.
+type ShadowStats struct {
+	Messages int64 // Messages compressed
+
+	RawBytes       int64 // Total protobuf wire size
+	CurrentBytes   int64 // Total size produced by the current version
+	CandidateBytes int64 // Total size produced by the candidate version
+
+	CandidateSmaller int64 // Messages where the candidate output was smaller
+	CandidateLarger  int64 // Messages where the candidate output was larger
+	CandidateErrors  int64 // Messages the candidate failed to compress or roundtrip
+}
+
+// CurrentRatio returns the compressed size of the current version
+// relative to the protobuf wire size.
+func (s ShadowStats) CurrentRatio() float64 {
+	if s.RawBytes == 0 {
+		return 0
+	}
+	return float64(s.CurrentBytes) / float64(s.RawBytes)
+}
+
+// CandidateRatio returns the compressed size of the candidate version
+// relative to the protobuf wire size.
+func (s ShadowStats) CandidateRatio() float64 {
+	if s.RawBytes == 0 {
+		return 0
+	}
+	return float64(s.CandidateBytes) / float64(s.RawBytes)
+}
+
+// RatioDelta returns CandidateRatio - CurrentRatio; negative values mean
+// the candidate compresses better.
+func (s ShadowStats) RatioDelta() float64 {
+	return s.CandidateRatio() - s.CurrentRatio()
+}
+
+func (s ShadowStats) String() string {
+	return fmt.Sprintf("%d messages: current %.2f%%, candidate %.2f%% (delta %+.2f%%), smaller %d, larger %d, errors %d",
+		s.Messages, s.CurrentRatio()*100, s.CandidateRatio()*100, s.RatioDelta()*100,
+		s.CandidateSmaller, s.CandidateLarger, s.CandidateErrors)
+}
+
+// ShadowCompressor compresses messages with the current version while also
+// compressing them with a candidate version.
+// Only the current version's output is written; the candidate output is used
+// to record size differences, allowing new models to be evaluated on live
+// traffic without affecting what is transmitted.
+//
+// ShadowCompressor is safe for concurrent use.
+type ShadowCompressor struct {
+	Current   Version
+	Candidate Version
+
+	// Verify additionally decompresses the candidate output and counts
+	// messages that do not roundtrip as candidate errors.
+	Verify bool
+
+	mu    sync.Mutex
+	stats ShadowStats
+}
+
+// NewShadowCompressor creates a shadow compressor for the given versions.
+func NewShadowCompressor(current, candidate Version) *ShadowCompressor {
+	return &ShadowCompressor{
+		Current:   current,
+		Candidate: candidate,
+		Verify:    true,
+	}
+}
+
+// Compress compresses msg with the current version into w and records the
+// result of the candidate version. Candidate failures are recorded but never
+// returned, so Compress fails only when the current version fails.
+func (sc *ShadowCompressor) Compress(msg proto.Message, w io.Writer) error {
+	var current bytes.Buffer
+	if err := sc.Current.Compress(msg, &current); err != nil {
+		return err
+	}
+
+	candidateSize, candidateErr := sc.compressCandidate(msg)
+	rawSize := proto.Size(msg)
+
+	sc.mu.Lock()
+	sc.stats.Messages++
+	sc.stats.RawBytes += int64(rawSize)
+	sc.stats.CurrentBytes += int64(current.Len())
+	if candidateErr != nil {
+		// Count a failed candidate as if it had sent the current output,
+		// so the totals remain comparable.
+		sc.stats.CandidateErrors++
+		sc.stats.CandidateBytes += int64(current.Len())
+	} else {
+		sc.stats.CandidateBytes += int64(candidateSize)
+		switch {
+		case candidateSize < current.Len():
+			sc.stats.CandidateSmaller++
+		case candidateSize > current.Len():
+			sc.stats.CandidateLarger++
+		}
+	}
+	sc.mu.Unlock()
+
+	_, err := w.Write(current.Bytes())
+	return err
+}
+
+// compressCandidate returns the candidate's compressed size for msg.
+func (sc *ShadowCompressor) compressCandidate(msg proto.Message) (size int, err error) {
+	// A broken candidate must never take down the transmit path.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("candidate %s panicked: %v", sc.Candidate.Name, r)
+		}
+	}()
+
+	var candidate bytes.Buffer
+	if err := sc.Candidate.Compress(msg, &candidate); err != nil {
+		return 0, err
+	}
+	size = candidate.Len()
+
+	if sc.Verify {
+		decoded := msg.ProtoReflect().New().Interface()
+		if err := sc.Candidate.Decompress(&candidate, decoded); err != nil {
+			return 0, err
+		}
+		if !proto.Equal(msg, decoded) {
+			return 0, fmt.Errorf("candidate %s roundtrip mismatch", sc.Candidate.Name)
+		}
+	}
+
+	return size, nil
+}
+
+// Stats returns the statistics recorded so far.
+func (sc *ShadowCompressor) Stats() ShadowStats {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.stats
+}
+
+// Reset clears the recorded statistics and returns the previous values.
+func (sc *ShadowCompressor) Reset() ShadowStats {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	stats := sc.stats
+	sc.stats = ShadowStats{}
+	return stats
+}
@@ -0,0 +1,77 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
+)
+
+func TestShadowCompressor(t *testing.T) {
+	current, ok := FindVersion("V10")
+	if !ok {
+		t.Fatal("V10 version not found")
+	}
+	candidate, ok := FindVersion("V11")
+	if !ok {
+		t.Fatal("V11 version not found")
+	}
+
+	sc := NewShadowCompressor(current, candidate)
+
+	msg := meshfixtures.TextPacket(meshfixtures.Default, meshfixtures.TextMessages[0])
+
+	var shadowed, direct bytes.Buffer
+	if err := sc.Compress(msg, &shadowed); err != nil {
+		t.Fatalf("Shadow compress failed: %v", err)
+	}
+	if err := current.Compress(msg, &direct); err != nil {
+		t.Fatalf("Current compress failed: %v", err)
+	}
+	if !bytes.Equal(shadowed.Bytes(), direct.Bytes()) {
+		t.Error("Shadow compressor must transmit the current version's output")
+	}
+
+	stats := sc.Stats()
+	t.Log(stats)
+	if stats.Messages != 1 || stats.CandidateErrors != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.CurrentBytes != int64(direct.Len()) {
+		t.Errorf("Expected current bytes %d, got %d", direct.Len(), stats.CurrentBytes)
+	}
+	if stats.RatioDelta() >= 0 {
+		t.Errorf("Expected V11 to beat V10 on text, delta %.2f%%", stats.RatioDelta()*100)
+	}
+}
+
+func TestShadowCompressorCandidateFailure(t *testing.T) {
+	current, _ := FindVersion("V10")
+	broken := Version{
+		Name: "broken",
+		compress: func(proto.Message, io.Writer, ...Option) error {
+			return errors.New("broken")
+		},
+	}
+	panicking := Version{
+		Name: "panicking",
+		compress: func(proto.Message, io.Writer, ...Option) error {
+			panic("boom")
+		},
+	}
+
+	for _, candidate := range []Version{broken, panicking} {
+		sc := NewShadowCompressor(current, candidate)
+		var buf bytes.Buffer
+		if err := sc.Compress(meshfixtures.Position(meshfixtures.Default), &buf); err != nil {
+			t.Fatalf("%s: candidate failure must not fail compression: %v", candidate.Name, err)
+		}
+		if stats := sc.Stats(); stats.CandidateErrors != 1 {
+			t.Errorf("%s: expected 1 candidate error, got %d", candidate.Name, stats.CandidateErrors)
+		}
+	}
+}
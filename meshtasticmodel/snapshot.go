@@ -0,0 +1,121 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// A gateway that forwards one upstream link to several downstream links
+// would otherwise warm up the models of every downstream session from
+// scratch. Snapshots let the downstream sessions branch from the warm state
+// of an existing session instead.
+//
+// Taking a snapshot copies nothing: the session and the snapshot share the
+// models, and whichever session codes a frame first with shared models
+// copies them before updating them. The models of a snapshot are never
+// updated, so any number of goroutines can branch from one snapshot.
+
+// SessionSnapshot is the model state of a session after a frame, see
+// SessionEncoder.Snapshot.
+type SessionSnapshot struct {
+	mcb   *ContextualModelBuilder // Shared models, nil before the first keyframe
+	epoch uint64
+	seq   uint64
+	opts  SessionOptions
+}
+
+// Snapshot returns the state of the session after the last encoded frame.
+// Encoders created with SessionSnapshot.NewEncoder continue the session
+// from that state, independently of s and of each other. Snapshot must not
+// be called concurrently with Encode.
+func (s *SessionEncoder) Snapshot() *SessionSnapshot {
+	s.shared = true
+	return &SessionSnapshot{mcb: s.mcb, epoch: s.epoch, seq: s.seq, opts: s.opts}
+}
+
+// Snapshot returns the state of the session after the last decoded frame,
+// see SessionEncoder.Snapshot. A stale decoder gives a snapshot that waits
+// for a keyframe. Snapshot must not be called concurrently with Decode.
+func (s *SessionDecoder) Snapshot() *SessionSnapshot {
+	snap := &SessionSnapshot{epoch: s.epoch, seq: s.seq, opts: s.opts}
+	if !s.stale {
+		s.shared = true
+		snap.mcb = s.mcb
+	}
+	return snap
+}
+
+// NewEncoder creates an encoder that continues the session of the
+// snapshot. Its frames decode with a decoder created by NewDecoder from a
+// snapshot of the same state, for example of a decoder that decoded the same
+// frames. A snapshot taken before the first frame gives an encoder that
+// starts with a keyframe.
+func (snap *SessionSnapshot) NewEncoder() *SessionEncoder {
+	s := NewSessionEncoderWithOptions(snap.opts)
+	if snap.mcb != nil {
+		s.mcb, s.shared = snap.mcb, true
+		s.epoch, s.seq = snap.epoch, snap.seq
+		s.forceKeyframe = false
+		// The keyframe interval counts from the start of the branch
+	}
+	return s
+}
+
+// NewDecoder creates a decoder that continues the session of the
+// snapshot, see NewEncoder.
+func (snap *SessionSnapshot) NewDecoder() *SessionDecoder {
+	s := NewSessionDecoderWithOptions(snap.opts)
+	s.epoch, s.seq = snap.epoch, snap.seq
+	if snap.mcb != nil {
+		s.mcb, s.shared = snap.mcb, true
+		s.stale = false
+	}
+	return s
+}
+
+// StateHash returns a hash of the model state of the snapshot, see
+// SessionEncoder.StateHash.
+func (snap *SessionSnapshot) StateHash() uint64 {
+	return stateHash(snap.mcb)
+}
+
+// clone returns a copy of the models, the admin and the counter state of mcb, which
+// adapt independently of mcb.
+func (mcb *ContextualModelBuilder) clone() *ContextualModelBuilder {
+	c := *mcb
+	c.ModelBuilderV1 = &ModelBuilderV1{
+		AdaptiveModelBuilder: mcb.AdaptiveModelBuilder.Clone(),
+		currentPortNum:       mcb.currentPortNum,
+	}
+	c.contextModels = cloneModels(mcb.contextModels)
+	c.booleanModels = cloneModels(mcb.booleanModels)
+	if mcb.textModel != nil {
+		c.textModel = mcb.textModel.Clone()
+	}
+	if mcb.emojiTextModel != nil {
+		c.emojiTextModel = mcb.emojiTextModel.Clone(c.textModel)
+	}
+	if mcb.admin != nil {
+		c.admin = &adminState{
+			pending: slices.Clone(mcb.admin.pending),
+			passkey: bytes.Clone(mcb.admin.passkey),
+		}
+	}
+	if mcb.counters != nil {
+		c.counters = mcb.counters.clone()
+	}
+	return &c
+}
+
+// cloneModels returns copies of the models by name.
+func cloneModels(byName map[string]coder.Model) map[string]coder.Model {
+	c := maps.Clone(byName)
+	for name, model := range c {
+		c[name] = models.Clone(model)
+	}
+	return c
+}
@@ -0,0 +1,128 @@
+package meshtasticmodel
+
+import (
+	"sync"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestSessionSnapshot(t *testing.T) {
+	opts := SessionOptions{KeyframeInterval: -1}
+	packets := sessionPackets()
+	warmup, rest := packets[:len(packets)/2], packets[len(packets)/2:]
+
+	// The gateway decodes the upstream link and warms up an encoder with the
+	// same traffic; the far ends of the downstream links do the same
+	upstream := NewSessionEncoderWithOptions(opts)
+	enc := NewSessionEncoderWithOptions(opts)
+	dec := NewSessionDecoderWithOptions(opts)
+	for i, packet := range warmup {
+		frame, err := upstream.Encode(packet)
+		if err != nil {
+			t.Fatalf("packet %d: Encode failed: %v", i, err)
+		}
+		if _, err := enc.Encode(packet); err != nil {
+			t.Fatalf("packet %d: Encode failed: %v", i, err)
+		}
+		if err := dec.Decode(frame, &meshtastic.MeshPacket{}); err != nil {
+			t.Fatalf("packet %d: Decode failed: %v", i, err)
+		}
+	}
+
+	encSnap, decSnap := enc.Snapshot(), dec.Snapshot()
+	if encSnap.StateHash() != decSnap.StateHash() {
+		t.Fatalf("encoder and decoder snapshots differ")
+	}
+	warm := encSnap.StateHash()
+
+	// The original sessions keep going without affecting the snapshots
+	for i, packet := range rest[:10] {
+		frame, err := enc.Encode(packet)
+		if err != nil {
+			t.Fatalf("packet %d: Encode failed: %v", i, err)
+		}
+		if err := dec.Decode(frame, &meshtastic.MeshPacket{}); err != nil {
+			t.Fatalf("packet %d: Decode failed: %v", i, err)
+		}
+	}
+	if encSnap.StateHash() != warm || decSnap.StateHash() != warm {
+		t.Fatalf("coding with the original sessions changed the snapshots")
+	}
+
+	// Downstream links branch concurrently, each forwarding different packets
+	const links = 4
+	sizes := make([]int, links)
+	var wg sync.WaitGroup
+	for link := range links {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			enc, dec := encSnap.NewEncoder(), decSnap.NewDecoder()
+			for i := link; i < len(rest); i += links {
+				frame, err := enc.Encode(rest[i])
+				if err != nil {
+					t.Errorf("link %d, packet %d: Encode failed: %v", link, i, err)
+					return
+				}
+				sizes[link] += len(frame)
+				result := &meshtastic.MeshPacket{}
+				if err := dec.Decode(frame, result); err != nil {
+					t.Errorf("link %d, packet %d: Decode failed: %v", link, i, err)
+					return
+				}
+				if !proto.Equal(rest[i], result) {
+					t.Errorf("link %d, packet %d: roundtrip verification failed", link, i)
+					return
+				}
+			}
+			if enc.StateHash() != dec.StateHash() {
+				t.Errorf("link %d: encoder and decoder state differ", link)
+			}
+		}()
+	}
+	wg.Wait()
+	if encSnap.StateHash() != warm || decSnap.StateHash() != warm {
+		t.Fatalf("coding with the branches changed the snapshots")
+	}
+
+	// Branching beats starting the downstream links cold
+	for link := range links {
+		cold := NewSessionEncoderWithOptions(opts)
+		coldSize := 0
+		for i := link; i < len(rest); i += links {
+			frame, err := cold.Encode(rest[i])
+			if err != nil {
+				t.Fatalf("link %d, packet %d: Encode failed: %v", link, i, err)
+			}
+			coldSize += len(frame)
+		}
+		t.Logf("link %d: cold %d bytes, branched %d bytes", link, coldSize, sizes[link])
+		if sizes[link] >= coldSize {
+			t.Errorf("link %d: branched session (%d bytes) should be smaller than a cold one (%d bytes)", link, sizes[link], coldSize)
+		}
+	}
+}
+
+func TestSessionSnapshotBeforeKeyframe(t *testing.T) {
+	snap := NewSessionEncoder().Snapshot()
+	enc, dec := snap.NewEncoder(), snap.NewDecoder()
+	if !dec.NeedsResync() {
+		t.Errorf("decoder of an empty snapshot does not wait for a keyframe")
+	}
+
+	packet := sessionPackets()[0]
+	frame, err := enc.Encode(packet)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	result := &meshtastic.MeshPacket{}
+	if err := dec.Decode(frame, result); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !proto.Equal(packet, result) {
+		t.Errorf("roundtrip verification failed")
+	}
+}
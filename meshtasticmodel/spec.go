@@ -0,0 +1,320 @@
+package meshtasticmodel
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// WriteSpec writes the wire format specification as Markdown: the format
+// header, the versions, and how V14 codes every field of the messages
+// reachable from roots. The field codings are derived from the model
+// builder of V14, so the specification follows the implementation;
+// cmd/genspec keeps docs/WIRE_FORMAT.md up to date.
+func WriteSpec(w io.Writer, roots ...protoreflect.MessageDescriptor) error {
+	var b strings.Builder
+	b.WriteString("# Meshtastic compression wire format\n\n")
+	b.WriteString("Generated by `go run ./cmd/genspec` from the meshtasticmodel package. Do not edit.\n\n")
+
+	writeSpecHeader(&b)
+	writeSpecVersions(&b)
+	writeSpecMessages(&b, roots)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeSpecHeader describes the format header.
+func writeSpecHeader(b *strings.Builder) {
+	b.WriteString("## Format header\n\n")
+	fmt.Fprintf(b, "Data written by `Version.Compress` and the `CompressV1` to `CompressV14` functions starts with a %d byte header. ", HeaderSize)
+	b.WriteString("Links that agree on the version by other means leave it out with `WithoutHeader`.\n\n")
+	b.WriteString("| Offset | Size | Content |\n|---|---|---|\n")
+	fmt.Fprintf(b, "| 0 | %d | Magic `%#02x %#02x` |\n", len(headerMagic), headerMagic[0], headerMagic[1])
+	fmt.Fprintf(b, "| %d | 1 | Wire ID of the version, see [Versions](#versions) |\n", len(headerMagic))
+	fmt.Fprintf(b, "| %d | 1 | Feature bits; known bits `%#02x`, decoders reject others |\n\n", len(headerMagic)+1, uint8(knownFeatures))
+}
+
+// writeSpecVersions lists the versions.
+func writeSpecVersions(b *strings.Builder) {
+	b.WriteString("## Versions\n\n")
+	b.WriteString("| Name | Wire ID | Capabilities | Description |\n|---|---|---|---|\n")
+	for _, v := range Versions {
+		fmt.Fprintf(b, "| %s | `%#02x` | %s | %s |\n", v.Name, uint8(v.ID), specCell(v.Features.String()), specCell(v.Description))
+	}
+	b.WriteString("\n")
+}
+
+// specCell escapes the separators of Markdown table cells in s.
+func specCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// writeSpecMessages describes how V14 codes the messages reachable from
+// roots.
+func writeSpecMessages(b *strings.Builder, roots []protoreflect.MessageDescriptor) {
+	mcb := newModelBuilderV14()
+
+	b.WriteString("## V14 message coding\n\n")
+	fmt.Fprintf(b, "Every symbol is coded with the arithmetic coder, whose models have total frequencies of at most %d. ", coder.MaxTotalFreq)
+	b.WriteString("A message codes the fields of its correlation group, if any, in group order and then the other fields in declaration order. ")
+	b.WriteString("Each field starts with a presence flag, coded with the boolean model `<field>_presence`, and continues with its value when present. ")
+	b.WriteString("Boolean models are selected by the field name alone, so fields of the same name share them across messages.\n\n")
+	fmt.Fprintf(b, "Boolean and context-specific models start from static tables and switch to adaptive models after %d mispredictions. ", mcb.escapeLimit)
+	b.WriteString("Varints are the bytes of the protobuf varint, the first coded with the `varint-first` model and the rest with `varint-continuation`. ")
+	b.WriteString("Repeated and map fields code their count as a varint and then their elements; map entries are sorted by key.")
+	if mcb.openEnums {
+		b.WriteString(" The models of enum value indexes have an escape after the last value, followed by a zigzag varint of numbers the enum does not declare.")
+	}
+	if mcb.unknownFields {
+		b.WriteString(" After its known fields a message codes whether it has unknown fields with the boolean model `unknown_fields`, and then their length as a varint and their bytes in protobuf wire format.")
+	}
+	b.WriteString("\n\n")
+
+	for _, md := range reachableMessages(roots) {
+		mcb.SetMessageType(string(md.Name()))
+		group := mcb.correlationOf(md)
+
+		fmt.Fprintf(b, "### %s\n\n", md.FullName())
+		b.WriteString("| # | Field | Type | Coding |\n|---|---|---|---|\n")
+		fields := md.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			coding := specFieldCoding(mcb, group, fd)
+			coding = strings.ToUpper(coding[:1]) + coding[1:]
+			fmt.Fprintf(b, "| %d | %s | %s | %s |\n", fd.Number(), fd.Name(), specFieldType(fd), coding)
+		}
+		b.WriteString("\n")
+	}
+}
+
+// reachableMessages returns roots and the messages of their fields,
+// recursively, in the order they are first reached.
+func reachableMessages(roots []protoreflect.MessageDescriptor) []protoreflect.MessageDescriptor {
+	var all []protoreflect.MessageDescriptor
+	seen := map[protoreflect.FullName]bool{}
+	var visit func(md protoreflect.MessageDescriptor)
+	visit = func(md protoreflect.MessageDescriptor) {
+		if md.IsMapEntry() || seen[md.FullName()] {
+			return
+		}
+		seen[md.FullName()] = true
+		all = append(all, md)
+		fields := md.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			if fd.IsMap() {
+				fd = fd.MapValue()
+			}
+			if fd.Message() != nil {
+				visit(fd.Message())
+			}
+		}
+	}
+	for _, md := range roots {
+		visit(md)
+	}
+	return all
+}
+
+// specFieldType returns the protobuf type of fd.
+func specFieldType(fd protoreflect.FieldDescriptor) string {
+	if fd.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", specFieldType(fd.MapKey()), specFieldType(fd.MapValue()))
+	}
+	var name string
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		name = string(fd.Message().FullName())
+	case protoreflect.EnumKind:
+		name = string(fd.Enum().FullName())
+	default:
+		name = fd.Kind().String()
+	}
+	if fd.IsList() {
+		return "repeated " + name
+	}
+	return name
+}
+
+// specFieldCoding describes how the walkers code the values of fd with mcb.
+func specFieldCoding(mcb *ContextualModelBuilder, group fieldCorrelation, fd protoreflect.FieldDescriptor) string {
+	for i, field := range group {
+		if field.name != fd.Name() {
+			continue
+		}
+		position := fmt.Sprintf("correlation group field %d of %d", i+1, len(group))
+		if field.base == "" {
+			return position + ", " + specValueCoding(mcb, fd)
+		}
+		return fmt.Sprintf("%s, signed difference from `%s` when it is present, else %s", position, field.base, specValueCoding(mcb, fd))
+	}
+
+	switch {
+	case fd.IsMap():
+		return "count varint, then the key and value of each entry"
+	case fd.IsList() && fd.Message() != nil:
+		return "count varint, then each message"
+	case fd.IsList():
+		return "count varint, then each element: " + specValueCoding(mcb, fd)
+	case fd.Message() != nil:
+		return "message"
+	}
+	return specValueCoding(mcb, fd)
+}
+
+// specValueCoding describes how compressFieldValueV10 codes a value of fd.
+// It follows the order of its cases.
+func specValueCoding(mcb *ContextualModelBuilder, fd protoreflect.FieldDescriptor) string {
+	fieldName := string(fd.Name())
+
+	if fd.Name() == "payload" && fd.Kind() == protoreflect.BytesKind {
+		return "boolean `payload_is_text`, then text with the emoji text model for text messages, else " + specLengthCoding(fd) + " and bytes"
+	}
+
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return fmt.Sprintf("boolean `%s`", fieldName)
+
+	case protoreflect.EnumKind:
+		ed := fd.Enum()
+		if mcb.deviceEnumModel(ed) != nil {
+			return fmt.Sprintf("value index with the `%s` table", ed.Name())
+		}
+		coding := fmt.Sprintf("value index, uniform over %d", ed.Values().Len())
+		if predicted, ok := mcb.enumPredictions[fieldName]; ok {
+			name := fmt.Sprint(int32(predicted))
+			if value := ed.Values().ByNumber(predicted); value != nil {
+				name = string(value.Name())
+			}
+			coding = fmt.Sprintf("boolean `%s_is_predicted` for `%s`, otherwise %s", fieldName, name, coding)
+		}
+		return coding
+
+	case protoreflect.Int32Kind, protoreflect.Int64Kind,
+		protoreflect.Uint32Kind, protoreflect.Uint64Kind:
+		if mcb.particleCountModel(fd) != nil {
+			return "bit length with the `" + fieldName + "` particle table, then the bits below the leading one"
+		}
+		if _, ok := mcb.counterKeyOf(fd); ok {
+			return "increase since the node's last report"
+		}
+		if mcb.isByteCount(fd) {
+			return fmt.Sprintf("exponent, %d mantissa bits, then boolean `byte_count_low_bits` and the low bits when not zero", byteCountMantissaBits)
+		}
+		return "varint"
+
+	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		return "zigzag varint"
+
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+		if isEmojiField(fd) {
+			return "emoji symbol: zero, one, a common emoji, or other followed by the value"
+		}
+		if name := specContextModelName(mcb, fd); name != "" {
+			return fmt.Sprintf("4 little-endian bytes with the `%s` model", name)
+		}
+		return "4 little-endian bytes, uniform"
+
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+		return "8 little-endian bytes, uniform"
+
+	case protoreflect.StringKind:
+		if mcb.configDownload && fieldName == "firmware_version" {
+			return "firmware version"
+		}
+		return "text with the emoji text model"
+
+	case protoreflect.BytesKind:
+		if mcb.configDownload && fieldName == "psk" {
+			return "pre-shared key"
+		}
+		return specLengthCoding(fd) + ", then bytes, uniform"
+
+	default:
+		return "unsupported"
+	}
+}
+
+// specLengthCoding describes how encodeFieldLength codes the length of fd.
+func specLengthCoding(fd protoreflect.FieldDescriptor) string {
+	if maxLen, ok := MaxFieldLength(fd); ok {
+		return fmt.Sprintf("length, uniform up to %d", maxLen)
+	}
+	return "length varint"
+}
+
+// specContextModelName returns the name of the context-specific model of
+// fd, or "" when fd has none.
+func specContextModelName(mcb *ContextualModelBuilder, fd protoreflect.FieldDescriptor) string {
+	model := mcb.createContextSpecificModel("", fd)
+	if model == nil {
+		return ""
+	}
+	for _, named := range contextModelNames {
+		if named.model() == model {
+			return named.name
+		}
+	}
+	return "unnamed"
+}
+
+// contextModelNames name the context-specific models in the specification.
+var contextModelNames = []struct {
+	name  string
+	model func() coder.Model
+}{
+	{"coordinate", sharedCoordinateModel},
+	{"altitude", sharedAltitudeModel},
+	{"node-id", sharedNodeIDModel},
+	{"battery-level", sharedBatteryLevelModel},
+	{"rssi", sharedRSSIModel},
+	{"snr", sharedSNRModel},
+	{"snr-array", sharedSNRArrayModel},
+	{"voltage", sharedVoltageModel},
+	{"channel-voltage", sharedChannelVoltageModel},
+	{"channel-current", sharedChannelCurrentModel},
+	{"utilization", sharedUtilizationModel},
+	{"hop-count", sharedHopCountModel},
+	{"channel-number", sharedChannelNumberModel},
+	{"satellite-count", sharedSatelliteCountModel},
+	{"gps-quality", sharedGPSQualityModel},
+	{"precision", sharedPrecisionModel},
+	{"dop", sharedDOPModel},
+	{"speed", sharedSpeedModel},
+	{"direction", sharedDirectionModel},
+	{"request-id", sharedRequestIDModel},
+	{"packet-id", sharedPacketIDModel},
+	{"uptime", sharedUptimeModel},
+	{"temperature", sharedTemperatureModel},
+	{"humidity", sharedHumidityModel},
+	{"pressure", sharedPressureModel},
+	{"gas-resistance", sharedGasResistanceModel},
+	{"iaq", sharedIAQModel},
+	{"lux", sharedLuxModel},
+	{"distance", sharedDistanceModel},
+	{"wind-speed", sharedWindSpeedModel},
+	{"rainfall", sharedRainfallModel},
+	{"soil-moisture", sharedSoilMoistureModel},
+	{"particulate", sharedParticulateModel},
+	{"particle-count", sharedParticleCountModel},
+	{"co2", sharedCO2Model},
+	{"formaldehyde", sharedFormaldehydeModel},
+	{"voc-nox", sharedVOCNOxModel},
+	{"heart-rate", sharedHeartRateModel},
+	{"spo2", sharedSpO2Model},
+	{"packet-count", sharedPacketCountModel},
+	{"node-count", sharedNodeCountModel},
+	{"memory-bytes", sharedMemoryBytesModel},
+	{"large-memory", sharedLargeMemoryModel},
+	{"load-average", sharedLoadAverageModel},
+	{"timestamp", sharedTimestampModel},
+	{"millis-adjust", sharedMillisAdjustModel},
+	{"priority", sharedPriorityModel},
+	{"waypoint-id", sharedWaypointIDModel},
+	{"expire-time", sharedExpireTimeModel},
+}
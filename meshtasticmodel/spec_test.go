@@ -0,0 +1,53 @@
+package meshtasticmodel
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestWriteSpec(t *testing.T) {
+	var roots []protoreflect.MessageDescriptor
+	protoregistry.GlobalTypes.RangeMessages(func(mt protoreflect.MessageType) bool {
+		if mt.Descriptor().ParentFile().Package() == "meshtastic" {
+			roots = append(roots, mt.Descriptor())
+		}
+		return true
+	})
+
+	var b strings.Builder
+	if err := WriteSpec(&b, roots...); err != nil {
+		t.Fatalf("WriteSpec failed: %v", err)
+	}
+	spec := b.String()
+
+	// Every context-specific model must be named in contextModelNames
+	if strings.Contains(spec, "`unnamed`") {
+		t.Error("spec has unnamed context-specific models")
+	}
+	for _, want := range []string{
+		"| 1 | latitude_i | sfixed32 | 4 little-endian bytes with the `coordinate` model |",
+		"| 2 | pm25_standard | uint32 | Correlation group field 1 of 6, varint |",
+		"| 2 | 1 | Wire ID of the version",
+		"| V14 | `0x1e` | stateless |",
+	} {
+		if !strings.Contains(spec, want) {
+			t.Errorf("spec does not contain %q", want)
+		}
+	}
+}
+
+func TestReachableMessages(t *testing.T) {
+	got := reachableMessages([]protoreflect.MessageDescriptor{(&meshtastic.NodeInfo{}).ProtoReflect().Descriptor()})
+	var names []string
+	for _, md := range got {
+		names = append(names, string(md.Name()))
+	}
+	if want := "NodeInfo User Position DeviceMetrics"; strings.Join(names, " ") != want {
+		t.Errorf("reachableMessages = %v, want %s", names, want)
+	}
+}
@@ -0,0 +1,80 @@
+package meshtasticmodel
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"slices"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// hashState writes the state of the adaptive models, the admin requests and
+// the counters to h, see models.StateHasher.
+func (mcb *ContextualModelBuilder) hashState(h hash.Hash) {
+	mcb.AdaptiveModelBuilder.HashState(h)
+	hashModels(h, mcb.contextModels)
+	hashModels(h, mcb.booleanModels)
+	if mcb.emojiTextModel != nil {
+		mcb.emojiTextModel.HashState(h)
+	} else if mcb.textModel != nil {
+		mcb.textModel.HashState(h)
+	}
+
+	if mcb.admin != nil {
+		fmt.Fprintf(h, "%x\x00", mcb.admin.passkey)
+		for _, req := range mcb.admin.pending {
+			fmt.Fprintf(h, "%d:%d\x00", req.response.Number(), req.section)
+		}
+	}
+	if mcb.counters != nil {
+		mcb.counters.hashState(h)
+	}
+}
+
+// hashModels writes the models in the order of their names to h.
+func hashModels(h hash.Hash, byName map[string]coder.Model) {
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00", name)
+		models.HashState(h, byName[name])
+	}
+}
+
+// stateHash returns a hash of the state of the models of a session, zero
+// before the first keyframe.
+func stateHash(mcb *ContextualModelBuilder) uint64 {
+	if mcb == nil {
+		return 0
+	}
+	h := fnv.New64a()
+	mcb.hashState(h)
+	return h.Sum64()
+}
+
+// StateHash returns a hash of the state of the session models. After every
+// frame it equals the StateHash of the SessionDecoder that decoded the
+// frame; the first frame after which they differ is where the decoder went
+// out of sync. See also SessionOptions.StateTrace.
+func (s *SessionEncoder) StateHash() uint64 {
+	return stateHash(s.mcb)
+}
+
+// StateHash returns a hash of the state of the session models, see
+// SessionEncoder.StateHash.
+func (s *SessionDecoder) StateHash() uint64 {
+	return stateHash(s.mcb)
+}
+
+// traceState writes the state hash after frame seq of epoch to the trace
+// of opts.
+func (opts SessionOptions) traceState(epoch, seq uint64, mcb *ContextualModelBuilder) {
+	if opts.StateTrace != nil {
+		fmt.Fprintf(opts.StateTrace, "epoch %d frame %d state %016x\n", epoch, seq, stateHash(mcb))
+	}
+}
@@ -0,0 +1,326 @@
+package meshtasticmodel
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"slices"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
+)
+
+// MaxTrackPositions is the largest number of positions of a track.
+const MaxTrackPositions = 1 << 16
+
+// maxTrackVelocityInterval is the longest time between fixes for which the
+// speed of the previous fixes is used to predict coordinates. It also keeps
+// the prediction from overflowing.
+const maxTrackVelocityInterval = 1 << 20
+
+// CompressTrack compresses a track, the position history of a node as kept
+// by store-and-forward or logging apps, as one batch. The positions are
+// sorted by time; DecompressTrack returns them in that order.
+//
+// The time of a fix is predicted from the interval between the previous two
+// fixes, the coordinates from the previous coordinates moving at the speed
+// between the previous two fixes, and the altitude from the previous
+// altitude. Only the difference from the prediction is coded, with adaptive
+// models of its magnitude, so that a steady track costs a few bits per fix.
+// The remaining position fields are coded like V13.
+func CompressTrack(positions []*meshtastic.Position, w io.Writer) error {
+	if len(positions) > MaxTrackPositions {
+		return fmt.Errorf("%d positions exceed the maximum of %d", len(positions), MaxTrackPositions)
+	}
+
+	sorted := slices.Clone(positions)
+	slices.SortStableFunc(sorted, func(a, b *meshtastic.Position) int {
+		return cmp.Compare(a.GetTime(), b.GetTime())
+	})
+
+	mcb := newModelBuilderV14()
+	enc := coder.NewEncoder(w)
+	if err := encodeVarintWithModels(uint64(len(sorted)), enc, mcb); err != nil {
+		return err
+	}
+
+	track := newTrackState()
+	for i, position := range sorted {
+		if err := compressTrackPosition(position, track, enc, mcb); err != nil {
+			return fmt.Errorf("position %d: %w", i, err)
+		}
+	}
+	return enc.Close()
+}
+
+// compressTrackPosition compresses a position of a track.
+func compressTrackPosition(position *meshtastic.Position, track *trackState, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	dt, err := track.time.encode(int64(position.GetTime()), 0, enc)
+	if err != nil {
+		return fmt.Errorf("field time: %w", err)
+	}
+
+	for _, field := range []struct {
+		name  string
+		value *int32
+		state *trackField
+	}{
+		{"latitude_i", position.LatitudeI, &track.latitude},
+		{"longitude_i", position.LongitudeI, &track.longitude},
+		{"altitude", position.Altitude, &track.altitude},
+	} {
+		present := 0
+		if field.value != nil {
+			present = 1
+		}
+		if err := models.EncodeEscaped(enc, present, mcb.GetBooleanModel("track_"+field.name+"_presence")); err != nil {
+			return fmt.Errorf("field %s presence: %w", field.name, err)
+		}
+		if field.value == nil {
+			continue
+		}
+		if _, err := field.state.encode(int64(*field.value), dt, enc); err != nil {
+			return fmt.Errorf("field %s: %w", field.name, err)
+		}
+	}
+
+	rest := proto.CloneOf(position)
+	rest.Time, rest.LatitudeI, rest.LongitudeI, rest.Altitude = 0, nil, nil, nil
+	return compressMessageV10("position", rest.ProtoReflect(), enc, mcb)
+}
+
+// DecompressTrack decompresses a track compressed with CompressTrack.
+func DecompressTrack(r io.Reader) ([]*meshtastic.Position, error) {
+	mcb := newModelBuilderV14()
+	dec, err := coder.NewDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := decodeVarintWithModels(dec, mcb)
+	if err != nil {
+		return nil, err
+	}
+	if count > MaxTrackPositions {
+		return nil, fmt.Errorf("%d positions exceed the maximum of %d", count, MaxTrackPositions)
+	}
+
+	track := newTrackState()
+	positions := make([]*meshtastic.Position, count)
+	for i := range positions {
+		position, err := decompressTrackPosition(track, dec, mcb)
+		if err != nil {
+			return nil, fmt.Errorf("position %d: %w", i, err)
+		}
+		positions[i] = position
+	}
+	return positions, nil
+}
+
+// decompressTrackPosition decompresses a position written by
+// compressTrackPosition.
+func decompressTrackPosition(track *trackState, dec *coder.Decoder, mcb *ContextualModelBuilder) (*meshtastic.Position, error) {
+	t, dt, err := track.time.decode(dec, 0)
+	if err != nil {
+		return nil, fmt.Errorf("field time: %w", err)
+	}
+	if t < 0 || t > math.MaxUint32 {
+		return nil, fmt.Errorf("field time: value %d out of range", t)
+	}
+
+	position := &meshtastic.Position{}
+	for _, field := range []struct {
+		name  string
+		value **int32
+		state *trackField
+	}{
+		{"latitude_i", &position.LatitudeI, &track.latitude},
+		{"longitude_i", &position.LongitudeI, &track.longitude},
+		{"altitude", &position.Altitude, &track.altitude},
+	} {
+		present, err := models.DecodeEscaped(dec, mcb.GetBooleanModel("track_"+field.name+"_presence"))
+		if err != nil {
+			return nil, fmt.Errorf("field %s presence: %w", field.name, err)
+		}
+		if present == 0 {
+			continue
+		}
+		value, _, err := field.state.decode(dec, dt)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.name, err)
+		}
+		if value < math.MinInt32 || value > math.MaxInt32 {
+			return nil, fmt.Errorf("field %s: value %d out of range", field.name, value)
+		}
+		*field.value = proto.Int32(int32(value))
+	}
+
+	if err := decompressMessageV10("position", position.ProtoReflect(), dec, mcb); err != nil {
+		return nil, err
+	}
+	position.Time = uint32(t)
+	return position, nil
+}
+
+// trackState holds the predictors of the coded fields of a track.
+type trackState struct {
+	time      trackField
+	latitude  trackField
+	longitude trackField
+	altitude  trackField
+}
+
+func newTrackState() *trackState {
+	return &trackState{
+		time:      trackField{residuals: newResidualModel(), interval: true},
+		latitude:  trackField{residuals: newResidualModel(), velocity: true},
+		longitude: trackField{residuals: newResidualModel(), velocity: true},
+		altitude:  trackField{residuals: newResidualModel()},
+	}
+}
+
+// trackField predicts the values of a field from its previous values.
+type trackField struct {
+	residuals *models.FrequencyTable // Bit lengths of the zigzag residuals
+
+	interval bool // Predict the previous difference to repeat
+	velocity bool // Predict the previous speed to continue
+
+	count    int   // Number of values seen, up to 2
+	previous int64 // Previous value
+	delta    int64 // Difference between the previous two values
+	dt       int64 // Time between the previous two values
+}
+
+// predict returns the expected value for a fix dt after the previous one.
+func (field *trackField) predict(dt int64) int64 {
+	switch {
+	case field.count == 0:
+		return 0
+	case field.count == 1:
+		return field.previous
+	case field.interval:
+		return field.previous + field.delta
+	case field.velocity && field.dt > 0 && dt >= 0 && dt < maxTrackVelocityInterval:
+		// |delta| < 2^33 and dt < 2^20, so the product cannot overflow
+		predicted := field.previous + field.delta*dt/field.dt
+		return min(max(predicted, math.MinInt32), math.MaxInt32)
+	default:
+		return field.previous
+	}
+}
+
+// update records value, coded dt after the previous one, and returns the
+// difference from the previous value.
+func (field *trackField) update(value, dt int64) int64 {
+	if field.count > 0 {
+		field.delta = value - field.previous
+		field.dt = dt
+	}
+	field.previous = value
+	field.count = min(field.count+1, 2)
+	return field.delta
+}
+
+// encode encodes value as the difference from its prediction and returns the
+// difference from the previous value.
+func (field *trackField) encode(value, dt int64, enc *coder.Encoder) (int64, error) {
+	residual := value - field.predict(dt)
+	if err := encodeResidual(residual, field.residuals, enc); err != nil {
+		return 0, err
+	}
+	return field.update(value, dt), nil
+}
+
+// decode decodes a value written by encode and returns it with the
+// difference from the previous value.
+func (field *trackField) decode(dec *coder.Decoder, dt int64) (value, delta int64, err error) {
+	residual, err := decodeResidual(field.residuals, dec)
+	if err != nil {
+		return 0, 0, err
+	}
+	value = field.predict(dt) + residual
+	return value, field.update(value, dt), nil
+}
+
+// Residual bit lengths adapt quickly, tracks change pace often.
+const (
+	residualMaxTotal  = 1 << 12
+	residualIncrement = 32
+)
+
+// newResidualModel returns an adaptive model of the bit lengths of zigzag
+// coded residuals, 0 to 64.
+func newResidualModel() *models.FrequencyTable {
+	freqs := make([]uint64, 65)
+	for i := range freqs {
+		freqs[i] = 1
+	}
+	model := models.NewFrequencyTable(freqs)
+	model.SetMaxTotal(residualMaxTotal)
+	return model
+}
+
+// encodeResidual encodes a residual as the bit length of its zigzag value
+// followed by the bits below the leading one.
+func encodeResidual(residual int64, lengths *models.FrequencyTable, enc *coder.Encoder) error {
+	value := pbmodel.ZigzagEncode(residual)
+	n := bits.Len64(value)
+	if err := enc.Encode(n, lengths); err != nil {
+		return err
+	}
+	lengths.Add(n, residualIncrement)
+	return encodeLowBits(value, n, enc)
+}
+
+// encodeLowBits encodes the n-1 bits of value below its leading one, where
+// n is the bit length of value.
+func encodeLowBits(value uint64, n int, enc *coder.Encoder) error {
+	for remaining := n - 1; remaining > 0; {
+		chunk := min(remaining, 8)
+		remaining -= chunk
+		symbol := int(value>>remaining) & (1<<chunk - 1)
+		if err := enc.Encode(symbol, models.SharedUniformModel(1<<chunk)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeResidual decodes a residual written by encodeResidual.
+func decodeResidual(lengths *models.FrequencyTable, dec *coder.Decoder) (int64, error) {
+	n, err := dec.Decode(lengths)
+	if err != nil {
+		return 0, err
+	}
+	lengths.Add(n, residualIncrement)
+	value, err := decodeLowBits(n, dec)
+	if err != nil {
+		return 0, err
+	}
+	return pbmodel.ZigzagDecode(value), nil
+}
+
+// decodeLowBits decodes a value of bit length n written by encodeLowBits.
+func decodeLowBits(n int, dec *coder.Decoder) (uint64, error) {
+	if n == 0 {
+		return 0, nil
+	}
+	value := uint64(1)
+	for remaining := n - 1; remaining > 0; {
+		chunk := min(remaining, 8)
+		remaining -= chunk
+		symbol, err := dec.Decode(models.SharedUniformModel(1 << chunk))
+		if err != nil {
+			return 0, err
+		}
+		value = value<<chunk | uint64(symbol)
+	}
+	return value, nil
+}
@@ -0,0 +1,122 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+// walkingTrack returns a track logged every 30 seconds while walking, with
+// GPS noise.
+func walkingTrack(n int) []*meshtastic.Position {
+	rng := rand.New(rand.NewSource(1))
+	lat, lon, alt := int32(594370000), int32(247536000), int32(32)
+	var track []*meshtastic.Position
+	for i := range n {
+		lat += 120 + int32(rng.Intn(21)-10)
+		lon += 80 + int32(rng.Intn(21)-10)
+		alt += int32(rng.Intn(3) - 1)
+		track = append(track, &meshtastic.Position{
+			LatitudeI:     proto.Int32(lat),
+			LongitudeI:    proto.Int32(lon),
+			Altitude:      proto.Int32(alt),
+			Time:          uint32(1760000000 + 30*i),
+			SatsInView:    uint32(9 + rng.Intn(3)),
+			PrecisionBits: 32,
+		})
+	}
+	return track
+}
+
+func TestCompressTrack(t *testing.T) {
+	track := walkingTrack(120)
+
+	// The track is logged out of order, it is sorted when compressed
+	shuffled := slices.Clone(track)
+	rand.New(rand.NewSource(2)).Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	var batch bytes.Buffer
+	if err := CompressTrack(shuffled, &batch); err != nil {
+		t.Fatalf("CompressTrack failed: %v", err)
+	}
+	separate := 0
+	for _, position := range track {
+		var buf bytes.Buffer
+		if err := CompressV14(position, &buf); err != nil {
+			t.Fatalf("CompressV14 failed: %v", err)
+		}
+		separate += buf.Len()
+	}
+	t.Logf("track: %d bytes (%.1f per position), separate V14: %d bytes", batch.Len(), float64(batch.Len())/float64(len(track)), separate)
+	if batch.Len()*3 >= separate {
+		t.Errorf("track (%d bytes) should be less than a third of separate messages (%d bytes)", batch.Len(), separate)
+	}
+
+	result, err := DecompressTrack(&batch)
+	if err != nil {
+		t.Fatalf("DecompressTrack failed: %v", err)
+	}
+	assertPositionsEqual(t, track, result)
+}
+
+func TestCompressTrackUnusual(t *testing.T) {
+	tests := map[string][]*meshtastic.Position{
+		"empty":  nil,
+		"single": {{LatitudeI: proto.Int32(1), LongitudeI: proto.Int32(2), Time: 3}},
+		"missing coordinates": {
+			{Time: 10, Altitude: proto.Int32(5)},
+			{Time: 20, LatitudeI: proto.Int32(100), LongitudeI: proto.Int32(200)},
+			{Time: 30},
+			{Time: 40, LatitudeI: proto.Int32(300), LongitudeI: proto.Int32(-400), Altitude: proto.Int32(-5)},
+		},
+		"extremes": {
+			{Time: 0, LatitudeI: proto.Int32(math.MinInt32), LongitudeI: proto.Int32(math.MaxInt32)},
+			{Time: 1, LatitudeI: proto.Int32(math.MaxInt32), LongitudeI: proto.Int32(math.MinInt32)},
+			{Time: 2, LatitudeI: proto.Int32(math.MinInt32), LongitudeI: proto.Int32(math.MaxInt32)},
+			{Time: math.MaxUint32, LatitudeI: proto.Int32(0), Altitude: proto.Int32(math.MinInt32)},
+		},
+		"same time": {
+			{Time: 5, LatitudeI: proto.Int32(10), GroundSpeed: proto.Uint32(3)},
+			{Time: 5, LatitudeI: proto.Int32(20)},
+			{Time: 5, LatitudeI: proto.Int32(40), SeqNumber: 9},
+		},
+	}
+	for name, track := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := CompressTrack(track, &buf); err != nil {
+				t.Fatalf("CompressTrack failed: %v", err)
+			}
+			result, err := DecompressTrack(&buf)
+			if err != nil {
+				t.Fatalf("DecompressTrack failed: %v", err)
+			}
+			assertPositionsEqual(t, track, result)
+		})
+	}
+
+	tooMany := make([]*meshtastic.Position, MaxTrackPositions+1)
+	if err := CompressTrack(tooMany, &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for too many positions")
+	}
+}
+
+func assertPositionsEqual(t *testing.T, want, got []*meshtastic.Position) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d positions, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !proto.Equal(want[i], got[i]) {
+			t.Errorf("position %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package meshtasticmodel
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
+)
+
+// Newer firmware adds fields to messages before the schema of a receiver
+// knows them, and protobuf keeps them as the unknown fields of the message
+// in their wire format. Up to V13 they are dropped. V14 codes them after the
+// known fields of every message, as a presence flag and, when present, their
+// length and bytes.
+
+// compressUnknownFieldsV10 compresses the unknown fields of msg.
+func compressUnknownFieldsV10(msg protoreflect.Message, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	raw := msg.GetUnknown()
+	present := 0
+	if len(raw) > 0 {
+		present = 1
+	}
+	if err := models.EncodeEscaped(enc, present, mcb.GetBooleanModel("unknown_fields")); err != nil {
+		return fmt.Errorf("unknown fields presence: %w", err)
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := encodeVarintWithModels(uint64(len(raw)), enc, mcb); err != nil {
+		return fmt.Errorf("unknown fields length: %w", err)
+	}
+	for _, b := range raw {
+		if err := enc.Encode(int(b), mcb.ByteModel()); err != nil {
+			return fmt.Errorf("unknown fields: %w", err)
+		}
+	}
+	return nil
+}
+
+// decompressUnknownFieldsV10 decompresses the unknown fields of msg.
+func decompressUnknownFieldsV10(msg protoreflect.Message, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
+	present, err := models.DecodeEscaped(dec, mcb.GetBooleanModel("unknown_fields"))
+	if err != nil {
+		return fmt.Errorf("unknown fields presence: %w", err)
+	}
+	if present == 0 {
+		return nil
+	}
+	length, err := decodeVarintWithModels(dec, mcb)
+	if err != nil {
+		return fmt.Errorf("unknown fields length: %w", err)
+	}
+	if err := checkDecodedLength(length, pbmodel.DefaultLimits.MaxBytes); err != nil {
+		return fmt.Errorf("unknown fields: %w", err)
+	}
+	raw := make([]byte, length)
+	for i := range raw {
+		symbol, err := dec.Decode(mcb.ByteModel())
+		if err != nil {
+			return fmt.Errorf("unknown fields: %w", err)
+		}
+		raw[i] = byte(symbol)
+	}
+	msg.SetUnknown(raw)
+	return nil
+}
@@ -0,0 +1,40 @@
+package meshtasticmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
+)
+
+func TestMeshtasticV14UnknownFields(t *testing.T) {
+	// Fields of a newer schema
+	var raw []byte
+	raw = protowire.AppendTag(raw, 1000, protowire.VarintType)
+	raw = protowire.AppendVarint(raw, 42)
+	raw = protowire.AppendTag(raw, 1001, protowire.BytesType)
+	raw = protowire.AppendString(raw, "from newer firmware")
+
+	user := &meshtastic.User{Id: "!12345678", LongName: "New Board"}
+	user.ProtoReflect().SetUnknown(raw)
+	position := &meshtastic.Position{LatitudeI: proto.Int32(375317890)}
+	position.ProtoReflect().SetUnknown(raw)
+	node := &meshtastic.NodeInfo{Num: 0x12345678, Position: position}
+
+	for _, msg := range []proto.Message{user, node} {
+		var buf bytes.Buffer
+		if err := CompressV14(msg, &buf); err != nil {
+			t.Fatalf("%T: compress failed: %v", msg, err)
+		}
+		result := msg.ProtoReflect().New().Interface()
+		if err := DecompressV14(&buf, result); err != nil {
+			t.Fatalf("%T: decompress failed: %v", msg, err)
+		}
+		if !proto.Equal(msg, result) {
+			t.Errorf("%T: got %v, want %v", msg, result, msg)
+		}
+	}
+}
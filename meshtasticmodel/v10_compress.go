@@ -11,15 +11,19 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // CompressV10 uses order-2 string compression on top of V8's varint byte models.
-func CompressV10(msg proto.Message, w io.Writer) error {
+func CompressV10(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := compressHeader(w, WireV10, opts); err != nil {
+		return err
+	}
 	mcb := NewContextualModelBuilder()
-	enc := arithcode.NewEncoder(w)
+	enc := coder.NewEncoder(w)
 
 	// Set initial message type context
 	msgType := string(msg.ProtoReflect().Descriptor().Name())
@@ -33,7 +37,8 @@ func CompressV10(msg proto.Message, w io.Writer) error {
 }
 
 // compressMessageV10 recursively compresses with field-specific boolean models.
-func compressMessageV10(fieldPath string, msg protoreflect.Message, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+// The later versions reuse it, selecting their codings with the flags of mcb.
+func compressMessageV10(fieldPath string, msg protoreflect.Message, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -42,17 +47,27 @@ func compressMessageV10(fieldPath string, msg protoreflect.Message, enc *arithco
 	mcb.SetMessageType(string(md.Name()))
 	defer func() { mcb.messageType = prevMsgType }()
 
+	group := mcb.correlationOf(md)
+	if group != nil {
+		if err := compressCorrelatedFields(fieldPath, msg, group, enc, mcb); err != nil {
+			return err
+		}
+	}
+
 	// Iterate through all fields in order
 	for i := 0; i < fields.Len(); i++ {
 		fd := fields.Get(i)
 		currentPath := pbmodel.BuildFieldPath(fieldPath, string(fd.Name()))
 		fieldName := string(fd.Name())
+		if group.has(fd.Name()) {
+			continue
+		}
 
 		if !msg.Has(fd) {
 			// Field not set, encode a "not present" marker
 			// Use field-specific boolean model for presence bits
 			presenceModel := mcb.GetBooleanModel(fieldName + "_presence")
-			if err := enc.Encode(0, presenceModel); err != nil {
+			if err := models.EncodeEscaped(enc, 0, presenceModel); err != nil {
 				return fmt.Errorf("field %s presence: %w", fd.Name(), err)
 			}
 			continue
@@ -60,7 +75,7 @@ func compressMessageV10(fieldPath string, msg protoreflect.Message, enc *arithco
 
 		// Field is present
 		presenceModel := mcb.GetBooleanModel(fieldName + "_presence")
-		if err := enc.Encode(1, presenceModel); err != nil {
+		if err := models.EncodeEscaped(enc, 1, presenceModel); err != nil {
 			return fmt.Errorf("field %s presence: %w", fd.Name(), err)
 		}
 
@@ -82,13 +97,14 @@ func compressMessageV10(fieldPath string, msg protoreflect.Message, enc *arithco
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV10(currentPath, value.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV10(currentPath, value.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
 			if err := compressFieldValueV10(currentPath, fd, value, enc, mcb); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
+			mcb.observeNode(md, fd, value)
 		}
 
 		// Reset portnum after processing Data message
@@ -97,11 +113,14 @@ func compressMessageV10(fieldPath string, msg protoreflect.Message, enc *arithco
 		}
 	}
 
+	if mcb.unknownFields {
+		return compressUnknownFieldsV10(msg, enc, mcb)
+	}
 	return nil
 }
 
 // compressRepeatedFieldV10 compresses repeated fields.
-func compressRepeatedFieldV10(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressRepeatedFieldV10(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	length := list.Len()
 	if err := encodeVarintWithModels(uint64(length), enc, mcb); err != nil {
 		return fmt.Errorf("length: %w", err)
@@ -112,7 +131,7 @@ func compressRepeatedFieldV10(fieldPath string, fd protoreflect.FieldDescriptor,
 		value := list.Get(i)
 
 		if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV10(elemPath, value.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV10(elemPath, value.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("element %d: %w", i, err)
 			}
 		} else {
@@ -126,7 +145,7 @@ func compressRepeatedFieldV10(fieldPath string, fd protoreflect.FieldDescriptor,
 }
 
 // compressMapFieldV10 compresses map fields.
-func compressMapFieldV10(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressMapFieldV10(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	length := mapVal.Len()
 	if err := encodeVarintWithModels(uint64(length), enc, mcb); err != nil {
 		return fmt.Errorf("map length: %w", err)
@@ -136,7 +155,7 @@ func compressMapFieldV10(fieldPath string, fd protoreflect.FieldDescriptor, mapV
 	valueFd := fd.MapValue()
 
 	var keys []protoreflect.MapKey
-	mapVal.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+	pbmodel.RangeMapSorted(mapVal, func(k protoreflect.MapKey, v protoreflect.Value) bool {
 		keys = append(keys, k)
 		return true
 	})
@@ -151,7 +170,7 @@ func compressMapFieldV10(fieldPath string, fd protoreflect.FieldDescriptor, mapV
 
 		v := mapVal.Get(k)
 		if valueFd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV10(valuePath, v.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV10(valuePath, v.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("map value %d: %w", i, err)
 			}
 		} else {
@@ -164,8 +183,20 @@ func compressMapFieldV10(fieldPath string, fd protoreflect.FieldDescriptor, mapV
 	return nil
 }
 
-// compressFieldValueV10 compresses a single field value with field-specific models.
-func compressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+// compressFieldValueV10 compresses a single field value with field-specific
+// models, or as a reference to the dictionary of a bundle.
+func compressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
+	// Only the bundle codec sets the dictionary
+	if mcb.dictionary != nil {
+		if table := mcb.dictionary.tableOf(fd); table != nil {
+			return encodeDictionaryValue(table, fieldPath, fd, value, enc, mcb)
+		}
+	}
+	return compressFieldLiteralV10(fieldPath, fd, value, enc, mcb)
+}
+
+// compressFieldLiteralV10 compresses a single field value in full.
+func compressFieldLiteralV10(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	model := mcb.GetContextualFieldModel(fieldPath, fd)
 	if model == nil {
 		model = mcb.GetFieldModel(fieldPath, fd)
@@ -176,19 +207,30 @@ func compressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor, va
 	// Handle special case for Data.payload field
 	if fd.Name() == "payload" && fd.Kind() == protoreflect.BytesKind {
 		data := value.Bytes()
+		if err := mcb.checkFieldLength(fd, len(data)); err != nil {
+			return err
+		}
+
 		isText := mcb.currentPortNum != nil && *mcb.currentPortNum == meshtastic.PortNum_TEXT_MESSAGE_APP
+		if mcb.textModel != nil {
+			// Text payloads that are not UTF-8 are flagged as binary
+			isText = isText && utf8.Valid(data)
+		}
 		textFlag := 0
 		if isText {
 			textFlag = 1
 		}
 		textModel := mcb.GetBooleanModel("payload_is_text")
-		if err := enc.Encode(textFlag, textModel); err != nil {
+		if err := models.EncodeEscaped(enc, textFlag, textModel); err != nil {
 			return err
 		}
 
 		if isText && utf8.Valid(data) {
+			if mcb.textModel != nil {
+				return mcb.encodeText(enc, string(data))
+			}
 			var buf bytes.Buffer
-			if err := arithcode.EncodeString(string(data), &buf); err != nil {
+			if err := models.EncodeString(string(data), &buf); err != nil {
 				return err
 			}
 			compressedBytes := buf.Bytes()
@@ -201,7 +243,7 @@ func compressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor, va
 				}
 			}
 		} else {
-			if err := encodeVarintWithModels(uint64(len(data)), enc, mcb); err != nil {
+			if err := encodeFieldLength(fd, len(data), enc, mcb); err != nil {
 				return err
 			}
 			for _, b := range data {
@@ -221,36 +263,51 @@ func compressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor, va
 		if value.Bool() {
 			b = 1
 		}
-		return enc.Encode(b, boolModel)
+		return models.EncodeEscaped(enc, b, boolModel)
 
 	case protoreflect.EnumKind:
 		enumValue := value.Enum()
+		ed := fd.Enum()
+		enumValueDesc := ed.Values().ByNumber(enumValue)
+		if enumValueDesc == nil {
+			if mcb.openEnums {
+				return compressUnknownEnumV10(fieldPath, fd, enumValue, enc, mcb)
+			}
+			return fmt.Errorf("unknown enum value: %d", enumValue)
+		}
+		enumIndex := enumValueDesc.Index()
+
+		if model := mcb.deviceEnumModel(ed); model != nil {
+			return models.EncodeEscaped(enc, enumIndex, model)
+		}
+
 		// Check if we have a prediction for this enum
 		if predictedValue, hasPrediction := mcb.enumPredictions[fieldName]; hasPrediction {
 			if enumValue == predictedValue {
 				predModel := mcb.GetBooleanModel(fieldName + "_is_predicted")
-				return enc.Encode(1, predModel)
+				return models.EncodeEscaped(enc, 1, predModel)
 			}
 			predModel := mcb.GetBooleanModel(fieldName + "_is_predicted")
-			if err := enc.Encode(0, predModel); err != nil {
+			if err := models.EncodeEscaped(enc, 0, predModel); err != nil {
 				return err
 			}
 		}
 
-		enumIndex := 0
-		ed := fd.Enum()
-		for i := 0; i < ed.Values().Len(); i++ {
-			if ed.Values().Get(i).Number() == enumValue {
-				enumIndex = i
-				break
-			}
-		}
-
-		enumModel := mcb.GetEnumModel(fieldPath, ed)
+		enumModel := mcb.enumModel(fieldPath, ed)
 		return enc.Encode(enumIndex, enumModel)
 
 	case protoreflect.Int32Kind, protoreflect.Int64Kind,
 		protoreflect.Uint32Kind, protoreflect.Uint64Kind:
+		if model := mcb.particleCountModel(fd); model != nil {
+			return encodeParticleCount(uint32(value.Uint()), model, enc)
+		}
+		if key, ok := mcb.counterKeyOf(fd); ok {
+			return encodeCounter(key, value.Uint(), enc, mcb)
+		}
+		if mcb.isByteCount(fd) {
+			return encodeByteCount(value.Uint(), enc, mcb)
+		}
+
 		var uintVal uint64
 		switch fd.Kind() {
 		case protoreflect.Int32Kind:
@@ -271,6 +328,10 @@ func compressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor, va
 		return encodeVarintWithModels(zigzagVal, enc, mcb)
 
 	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind:
+		if mcb.codesEmojiField(fd) {
+			return encodeEmojiField(fd, uint32(value.Uint()), enc, mcb)
+		}
+
 		var val uint32
 		if fd.Kind() == protoreflect.Fixed32Kind {
 			val = uint32(value.Uint())
@@ -281,7 +342,7 @@ func compressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor, va
 		binary.LittleEndian.PutUint32(bytes, val)
 		if model != nil && model != mcb.BoolModel() {
 			for _, b := range bytes {
-				if err := enc.Encode(int(b), model); err != nil {
+				if err := models.EncodeEscaped(enc, int(b), model); err != nil {
 					return err
 				}
 			}
@@ -316,7 +377,7 @@ func compressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor, va
 		binary.LittleEndian.PutUint32(bytes, bits)
 		if model != nil && model != mcb.BoolModel() {
 			for _, b := range bytes {
-				if err := enc.Encode(int(b), model); err != nil {
+				if err := models.EncodeEscaped(enc, int(b), model); err != nil {
 					return err
 				}
 			}
@@ -342,9 +403,20 @@ func compressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor, va
 
 	case protoreflect.StringKind:
 		str := value.String()
+		if err := mcb.checkFieldLength(fd, len(str)); err != nil {
+			return err
+		}
+		if mcb.textModel != nil {
+			if mcb.configDownload && fieldName == "firmware_version" {
+				return encodeFirmwareVersion(enc, mcb, str)
+			}
+			// Text strings are terminated by an end-of-string symbol
+			return mcb.encodeText(enc, str)
+		}
 		var buf bytes.Buffer
-		// Use order-2 model for better string compression
-		if err := arithcode.EncodeStringOrder2(str, &buf); err != nil {
+		// Use order-2 model for better string compression, with the
+		// bigram tables V10 was defined with
+		if err := models.EncodeStringBigram(str, &buf); err != nil {
 			return err
 		}
 		compressedBytes := buf.Bytes()
@@ -360,7 +432,10 @@ func compressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor, va
 
 	case protoreflect.BytesKind:
 		data := value.Bytes()
-		if err := encodeVarintWithModels(uint64(len(data)), enc, mcb); err != nil {
+		if mcb.configDownload && fieldName == "psk" {
+			return encodePSK(data, enc)
+		}
+		if err := encodeFieldLength(fd, len(data), enc, mcb); err != nil {
 			return err
 		}
 		for _, b := range data {
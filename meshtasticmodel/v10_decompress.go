@@ -10,15 +10,19 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // DecompressV10 decompresses a message using order-2 string compression.
-func DecompressV10(r io.Reader, msg proto.Message) error {
+func DecompressV10(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := decompressHeader(r, WireV10, opts); err != nil {
+		return err
+	}
 	mcb := NewContextualModelBuilder()
-	dec, err := arithcode.NewDecoder(r)
+	dec, err := coder.NewDecoder(r)
 	if err != nil {
 		return err
 	}
@@ -29,8 +33,9 @@ func DecompressV10(r io.Reader, msg proto.Message) error {
 	return decompressMessageV10("", msg.ProtoReflect(), dec, mcb)
 }
 
-// decompressMessageV10 recursively decompresses a message.
-func decompressMessageV10(fieldPath string, msg protoreflect.Message, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+// decompressMessageV10 recursively decompresses a message written by
+// compressMessageV10 with the same flags of mcb.
+func decompressMessageV10(fieldPath string, msg protoreflect.Message, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -38,14 +43,24 @@ func decompressMessageV10(fieldPath string, msg protoreflect.Message, dec *arith
 	mcb.SetMessageType(string(md.Name()))
 	defer func() { mcb.messageType = prevMsgType }()
 
+	group := mcb.correlationOf(md)
+	if group != nil {
+		if err := decompressCorrelatedFields(fieldPath, msg, group, dec, mcb); err != nil {
+			return err
+		}
+	}
+
 	for i := 0; i < fields.Len(); i++ {
 		fd := fields.Get(i)
 		currentPath := pbmodel.BuildFieldPath(fieldPath, string(fd.Name()))
 		fieldName := string(fd.Name())
+		if group.has(fd.Name()) {
+			continue
+		}
 
 		// Check if field is present
 		presenceModel := mcb.GetBooleanModel(fieldName + "_presence")
-		present, err := dec.Decode(presenceModel)
+		present, err := models.DecodeEscaped(dec, presenceModel)
 		if err != nil {
 			return fmt.Errorf("field %s presence: %w", fd.Name(), err)
 		}
@@ -78,7 +93,7 @@ func decompressMessageV10(fieldPath string, msg protoreflect.Message, dec *arith
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
 			subMsg := msg.Mutable(fd).Message()
-			if err := decompressMessageV10(currentPath, subMsg, dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV10(currentPath, subMsg, dec, mcb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -87,6 +102,7 @@ func decompressMessageV10(fieldPath string, msg protoreflect.Message, dec *arith
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 			msg.Set(fd, value)
+			mcb.observeNode(md, fd, value)
 		}
 
 		if md.Name() == "Data" && i == fields.Len()-1 {
@@ -94,23 +110,29 @@ func decompressMessageV10(fieldPath string, msg protoreflect.Message, dec *arith
 		}
 	}
 
+	if mcb.unknownFields {
+		return decompressUnknownFieldsV10(msg, dec, mcb)
+	}
 	return nil
 }
 
 // decompressRepeatedFieldV10 decompresses a repeated field.
-func decompressRepeatedFieldV10(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressRepeatedFieldV10(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	lengthVal, err := decodeVarintWithModels(dec, mcb)
 	if err != nil {
 		return fmt.Errorf("length: %w", err)
 	}
 	length := int(lengthVal)
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	for i := 0; i < length; i++ {
 		elemPath := fmt.Sprintf("%s[%d]", fieldPath, i)
 
 		if fd.Kind() == protoreflect.MessageKind {
 			elem := list.NewElement()
-			if err := decompressMessageV10(elemPath, elem.Message(), dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV10(elemPath, elem.Message(), dec, mcb) }); err != nil {
 				return fmt.Errorf("element %d: %w", i, err)
 			}
 			list.Append(elem)
@@ -127,12 +149,15 @@ func decompressRepeatedFieldV10(fieldPath string, fd protoreflect.FieldDescripto
 }
 
 // decompressMapFieldV10 decompresses a map field.
-func decompressMapFieldV10(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressMapFieldV10(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	lengthVal, err := decodeVarintWithModels(dec, mcb)
 	if err != nil {
 		return fmt.Errorf("map length: %w", err)
 	}
 	length := int(lengthVal)
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	keyFd := fd.MapKey()
 	valueFd := fd.MapValue()
@@ -149,7 +174,7 @@ func decompressMapFieldV10(fieldPath string, fd protoreflect.FieldDescriptor, ma
 		var value protoreflect.Value
 		if valueFd.Kind() == protoreflect.MessageKind {
 			valueMsg := mapVal.NewValue()
-			if err := decompressMessageV10(valuePath, valueMsg.Message(), dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV10(valuePath, valueMsg.Message(), dec, mcb) }); err != nil {
 				return fmt.Errorf("map value %d: %w", i, err)
 			}
 			value = valueMsg
@@ -168,7 +193,19 @@ func decompressMapFieldV10(fieldPath string, fd protoreflect.FieldDescriptor, ma
 }
 
 // decompressFieldValueV10 decompresses a single field value.
-func decompressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor, dec *arithcode.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
+func decompressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor, dec *coder.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
+	// Only the bundle codec sets the dictionary
+	if mcb.dictionary != nil {
+		if table := mcb.dictionary.tableOf(fd); table != nil {
+			return decodeDictionaryValue(table, fieldPath, fd, dec, mcb)
+		}
+	}
+	return decompressFieldLiteralV10(fieldPath, fd, dec, mcb)
+}
+
+// decompressFieldLiteralV10 decompresses a single field value written in
+// full.
+func decompressFieldLiteralV10(fieldPath string, fd protoreflect.FieldDescriptor, dec *coder.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
 	model := mcb.GetContextualFieldModel(fieldPath, fd)
 	if model == nil {
 		model = mcb.GetFieldModel(fieldPath, fd)
@@ -179,16 +216,26 @@ func decompressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor,
 	// Handle special case for Data.payload field
 	if fd.Name() == "payload" && fd.Kind() == protoreflect.BytesKind {
 		textModel := mcb.GetBooleanModel("payload_is_text")
-		textFlag, err := dec.Decode(textModel)
+		textFlag, err := models.DecodeEscaped(dec, textModel)
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
 
-		lengthVal, err := decodeVarintWithModels(dec, mcb)
+		if textFlag == 1 && mcb.textModel != nil {
+			str, err := mcb.decodeText(dec)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			if err := mcb.checkFieldLength(fd, len(str)); err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfBytes([]byte(str)), nil
+		}
+
+		length, err := decodeFieldLength(fd, dec, mcb)
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
-		length := int(lengthVal)
 
 		if textFlag == 1 {
 			compressedBytes := make([]byte, length)
@@ -200,7 +247,7 @@ func decompressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor,
 				compressedBytes[i] = byte(symbol)
 			}
 
-			str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+			str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 			if err != nil {
 				return protoreflect.Value{}, err
 			}
@@ -221,17 +268,26 @@ func decompressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor,
 	switch fd.Kind() {
 	case protoreflect.BoolKind:
 		boolModel := mcb.GetBooleanModel(fieldName)
-		symbol, err := dec.Decode(boolModel)
+		symbol, err := models.DecodeEscaped(dec, boolModel)
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
 		return protoreflect.ValueOfBool(symbol != 0), nil
 
 	case protoreflect.EnumKind:
+		ed := fd.Enum()
+		if model := mcb.deviceEnumModel(ed); model != nil {
+			enumIndex, err := models.DecodeEscaped(dec, model)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return decompressEnumIndexV10(enumIndex, ed, dec, mcb)
+		}
+
 		// Check if we have a prediction for this enum
 		if predictedValue, hasPrediction := mcb.enumPredictions[fieldName]; hasPrediction {
 			predModel := mcb.GetBooleanModel(fieldName + "_is_predicted")
-			flag, err := dec.Decode(predModel)
+			flag, err := models.DecodeEscaped(dec, predModel)
 			if err != nil {
 				return protoreflect.Value{}, err
 			}
@@ -240,21 +296,25 @@ func decompressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor,
 			}
 		}
 
-		ed := fd.Enum()
-		enumModel := mcb.GetEnumModel(fieldPath, ed)
+		enumModel := mcb.enumModel(fieldPath, ed)
 		enumIndex, err := dec.Decode(enumModel)
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
-
-		if enumIndex >= ed.Values().Len() {
-			return protoreflect.Value{}, fmt.Errorf("invalid enum index %d", enumIndex)
-		}
-		enumValue := ed.Values().Get(enumIndex).Number()
-		return protoreflect.ValueOfEnum(enumValue), nil
+		return decompressEnumIndexV10(enumIndex, ed, dec, mcb)
 
 	case protoreflect.Int32Kind, protoreflect.Int64Kind,
 		protoreflect.Uint32Kind, protoreflect.Uint64Kind:
+		if model := mcb.particleCountModel(fd); model != nil {
+			return decodeParticleCount(model, dec)
+		}
+		if key, ok := mcb.counterKeyOf(fd); ok {
+			return decodeCounter(key, dec, mcb)
+		}
+		if mcb.isByteCount(fd) {
+			return decodeByteCount(dec, mcb)
+		}
+
 		uintVal, err := decodeVarintWithModels(dec, mcb)
 		if err != nil {
 			return protoreflect.Value{}, err
@@ -284,10 +344,18 @@ func decompressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor,
 		return protoreflect.ValueOfInt64(signedVal), nil
 
 	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind:
+		if mcb.codesEmojiField(fd) {
+			val, err := decodeEmojiField(fd, dec, mcb)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfUint32(val), nil
+		}
+
 		bytes := make([]byte, 4)
 		if model != nil && model != mcb.BoolModel() {
 			for i := 0; i < 4; i++ {
-				symbol, err := dec.Decode(model)
+				symbol, err := models.DecodeEscaped(dec, model)
 				if err != nil {
 					return protoreflect.Value{}, err
 				}
@@ -329,7 +397,7 @@ func decompressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor,
 		bytes := make([]byte, 4)
 		if model != nil && model != mcb.BoolModel() {
 			for i := 0; i < 4; i++ {
-				symbol, err := dec.Decode(model)
+				symbol, err := models.DecodeEscaped(dec, model)
 				if err != nil {
 					return protoreflect.Value{}, err
 				}
@@ -362,11 +430,31 @@ func decompressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor,
 		return protoreflect.ValueOfFloat64(doubleVal), nil
 
 	case protoreflect.StringKind:
+		if mcb.textModel != nil {
+			var str string
+			var err error
+			if mcb.configDownload && fieldName == "firmware_version" {
+				str, err = decodeFirmwareVersion(dec, mcb)
+			} else {
+				str, err = mcb.decodeText(dec)
+			}
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			if err := mcb.checkFieldLength(fd, len(str)); err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfString(str), nil
+		}
+
 		compressedLengthVal, err := decodeVarintWithModels(dec, mcb)
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
 		compressedLength := int(compressedLengthVal)
+		if err := checkDecodedLength(uint64(compressedLength), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		compressedBytes := make([]byte, compressedLength)
 		for i := 0; i < compressedLength; i++ {
@@ -378,18 +466,27 @@ func decompressFieldValueV10(fieldPath string, fd protoreflect.FieldDescriptor,
 		}
 
 		// Use order-2 model for better string decompression
-		str, err := arithcode.DecodeStringOrder2(bytes.NewReader(compressedBytes))
+		str, err := models.DecodeStringBigram(bytes.NewReader(compressedBytes))
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
+		if err := mcb.checkFieldLength(fd, len(str)); err != nil {
+			return protoreflect.Value{}, err
+		}
 		return protoreflect.ValueOfString(str), nil
 
 	case protoreflect.BytesKind:
-		lengthVal, err := decodeVarintWithModels(dec, mcb)
+		if mcb.configDownload && fieldName == "psk" {
+			data, err := decodePSK(dec)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfBytes(data), nil
+		}
+		length, err := decodeFieldLength(fd, dec, mcb)
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
-		length := int(lengthVal)
 
 		data := make([]byte, length)
 		for i := 0; i < length; i++ {
@@ -0,0 +1,40 @@
+package meshtasticmodel
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// CompressV11 codes strings and text payloads with an order-3 PPM model on top
+// of V10. Text is coded directly into the message stream and shares one
+// adaptive PPM model across all string fields of the message. The lengths of
+// the fields with a documented maximum length, see MaxFieldLength, are coded
+// uniformly up to it, and longer values fail to compress.
+func CompressV11(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := compressHeader(w, WireV11, opts); err != nil {
+		return err
+	}
+	mcb := newModelBuilderV11()
+	enc := coder.NewEncoder(w)
+
+	// Set initial message type context
+	msgType := string(msg.ProtoReflect().Descriptor().Name())
+	mcb.SetMessageType(msgType)
+
+	if err := compressMessageV10("", msg.ProtoReflect(), enc, mcb); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// newModelBuilderV11 creates the model builder used by V11.
+func newModelBuilderV11() *ContextualModelBuilder {
+	mcb := NewContextualModelBuilder()
+	mcb.textModel = sharedEnglishPPMModel().Clone()
+	mcb.fieldLengths = true
+	return mcb
+}
@@ -0,0 +1,26 @@
+package meshtasticmodel
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// DecompressV11 decompresses a message using PPM string compression.
+func DecompressV11(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := decompressHeader(r, WireV11, opts); err != nil {
+		return err
+	}
+	mcb := newModelBuilderV11()
+	dec, err := coder.NewDecoder(r)
+	if err != nil {
+		return err
+	}
+
+	msgType := string(msg.ProtoReflect().Descriptor().Name())
+	mcb.SetMessageType(msgType)
+
+	return decompressMessageV10("", msg.ProtoReflect(), dec, mcb)
+}
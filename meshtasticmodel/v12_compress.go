@@ -0,0 +1,38 @@
+package meshtasticmodel
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// CompressV12 extends V11 with emoji-aware text coding. Common emoji in strings
+// and text payloads are coded as a compact index instead of UTF-8 bytes, and
+// the emoji codepoint fields (Data.emoji, Waypoint.icon) use the same index.
+func CompressV12(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := compressHeader(w, WireV12, opts); err != nil {
+		return err
+	}
+	mcb := newModelBuilderV12()
+	enc := coder.NewEncoder(w)
+
+	// Set initial message type context
+	msgType := string(msg.ProtoReflect().Descriptor().Name())
+	mcb.SetMessageType(msgType)
+
+	if err := compressMessageV10("", msg.ProtoReflect(), enc, mcb); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// newModelBuilderV12 creates the model builder used by V12.
+func newModelBuilderV12() *ContextualModelBuilder {
+	mcb := newModelBuilderV11()
+	mcb.emojiTextModel = models.NewEmojiTextModel(mcb.textModel)
+	return mcb
+}
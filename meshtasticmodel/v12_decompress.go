@@ -0,0 +1,26 @@
+package meshtasticmodel
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// DecompressV12 decompresses a message using emoji-aware PPM string compression.
+func DecompressV12(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := decompressHeader(r, WireV12, opts); err != nil {
+		return err
+	}
+	mcb := newModelBuilderV12()
+	dec, err := coder.NewDecoder(r)
+	if err != nil {
+		return err
+	}
+
+	msgType := string(msg.ProtoReflect().Descriptor().Name())
+	mcb.SetMessageType(msgType)
+
+	return decompressMessageV10("", msg.ProtoReflect(), dec, mcb)
+}
@@ -0,0 +1,43 @@
+package meshtasticmodel
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// CompressV13 extends V12 with escaping static models. Boolean and
+// context-specific field models start from their static tables, and a field
+// whose values keep contradicting its table switches to an adaptive model.
+// The switch is derived from the coded values, so it costs no extra bits.
+func CompressV13(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := compressHeader(w, WireV13, opts); err != nil {
+		return err
+	}
+	mcb := v13Builders.get()
+	defer v13Builders.put(mcb)
+	enc := coder.NewEncoder(w)
+
+	// Set initial message type context
+	msgType := string(msg.ProtoReflect().Descriptor().Name())
+	mcb.SetMessageType(msgType)
+
+	if err := compressMessageV10("", msg.ProtoReflect(), enc, mcb); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// newModelBuilderV13 creates the model builder used by V13 and by sessions.
+func newModelBuilderV13() *ContextualModelBuilder {
+	mcb := NewContextualModelBuilder()
+	mcb.textModel = sharedEnglishPPMModel().Clone()
+	mcb.emojiTextModel = models.NewEmojiTextModel(mcb.textModel)
+	mcb.escapeLimit = models.DefaultEscapeLimit
+	mcb.fieldLengths = true
+	return mcb
+}
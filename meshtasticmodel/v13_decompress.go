@@ -0,0 +1,27 @@
+package meshtasticmodel
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// DecompressV13 decompresses a message compressed with CompressV13.
+func DecompressV13(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := decompressHeader(r, WireV13, opts); err != nil {
+		return err
+	}
+	mcb := v13Builders.get()
+	defer v13Builders.put(mcb)
+	dec, err := coder.NewDecoder(r)
+	if err != nil {
+		return err
+	}
+
+	msgType := string(msg.ProtoReflect().Descriptor().Name())
+	mcb.SetMessageType(msgType)
+
+	return decompressMessageV10("", msg.ProtoReflect(), dec, mcb)
+}
@@ -0,0 +1,49 @@
+package meshtasticmodel
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// CompressV14 extends V13 for the messages of the config download phase.
+// Firmware version strings are coded as a numeric triplet and a short commit
+// hash, and the hardware model and device role use static tables of the
+// values seen on meshes instead of a single predicted value. Channel keys
+// are coded by class: the default key, a simple key, or a random AES-128 or
+// AES-256 key. Correlated fields, such as the particulate matter
+// concentrations of air quality metrics, are coded as differences from a
+// base field, particle counts by their magnitude, and the byte counts of
+// host metrics by exponent and mantissa. Unknown fields, such as the fields
+// of a newer schema, are kept.
+func CompressV14(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := compressHeader(w, WireV14, opts); err != nil {
+		return err
+	}
+	mcb := v14Builders.get()
+	defer v14Builders.put(mcb)
+	enc := coder.NewEncoder(w)
+
+	msgType := string(msg.ProtoReflect().Descriptor().Name())
+	mcb.SetMessageType(msgType)
+
+	if err := compressMessageV10("", msg.ProtoReflect(), enc, mcb); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// newModelBuilderV14 creates the model builder used by V14.
+func newModelBuilderV14() *ContextualModelBuilder {
+	mcb := newModelBuilderV13()
+	mcb.configDownload = true
+	mcb.correlatedFields = true
+	mcb.particleCounts = true
+	mcb.byteCounts = true
+	mcb.openEnums = true
+	mcb.unknownFields = true
+	return mcb
+}
@@ -0,0 +1,27 @@
+package meshtasticmodel
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// DecompressV14 decompresses a message compressed with CompressV14.
+func DecompressV14(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := decompressHeader(r, WireV14, opts); err != nil {
+		return err
+	}
+	mcb := v14Builders.get()
+	defer v14Builders.put(mcb)
+	dec, err := coder.NewDecoder(r)
+	if err != nil {
+		return err
+	}
+
+	msgType := string(msg.ProtoReflect().Descriptor().Name())
+	mcb.SetMessageType(msgType)
+
+	return decompressMessageV10("", msg.ProtoReflect(), dec, mcb)
+}
@@ -11,7 +11,8 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
@@ -21,9 +22,12 @@ import (
 // - Treating Data.payload as text when portnum is TEXT_MESSAGE_APP
 // - Delta encoding for coordinates
 // - Optimized models for common Meshtastic field patterns
-func CompressV1(msg proto.Message, w io.Writer) error {
+func CompressV1(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := compressHeader(w, WireV1, opts); err != nil {
+		return err
+	}
 	mmb := NewModelBuilderV1()
-	enc := arithcode.NewEncoder(w)
+	enc := coder.NewEncoder(w)
 
 	if err := compressMessageV1("", msg.ProtoReflect(), enc, mmb); err != nil {
 		return err
@@ -46,7 +50,7 @@ func NewModelBuilderV1() *ModelBuilderV1 {
 }
 
 // compressMessage recursively compresses with Meshtastic-specific optimizations.
-func compressMessageV1(fieldPath string, msg protoreflect.Message, enc *arithcode.Encoder, mmb *ModelBuilderV1) error {
+func compressMessageV1(fieldPath string, msg protoreflect.Message, enc *coder.Encoder, mmb *ModelBuilderV1) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -86,7 +90,7 @@ func compressMessageV1(fieldPath string, msg protoreflect.Message, enc *arithcod
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV1(currentPath, value.Message(), enc, mmb); err != nil {
+			if err := mmb.Nest(func() error { return compressMessageV1(currentPath, value.Message(), enc, mmb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -105,7 +109,7 @@ func compressMessageV1(fieldPath string, msg protoreflect.Message, enc *arithcod
 }
 
 // compressRepeatedField compresses repeated fields with Meshtastic optimizations.
-func compressRepeatedFieldV1(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *arithcode.Encoder, mmb *ModelBuilderV1) error {
+func compressRepeatedFieldV1(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *coder.Encoder, mmb *ModelBuilderV1) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mmb.GetFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -124,7 +128,7 @@ func compressRepeatedFieldV1(fieldPath string, fd protoreflect.FieldDescriptor,
 	for i := 0; i < length; i++ {
 		value := list.Get(i)
 		if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV1(elementPath, value.Message(), enc, mmb); err != nil {
+			if err := mmb.Nest(func() error { return compressMessageV1(elementPath, value.Message(), enc, mmb) }); err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 		} else {
@@ -138,7 +142,7 @@ func compressRepeatedFieldV1(fieldPath string, fd protoreflect.FieldDescriptor,
 }
 
 // compressFieldValue compresses field values with Meshtastic-specific logic.
-func compressFieldValueV1(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *arithcode.Encoder, mmb *ModelBuilderV1) error {
+func compressFieldValueV1(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *coder.Encoder, mmb *ModelBuilderV1) error {
 	// Special handling for Data.payload field
 	if fd.Name() == "payload" && fd.Kind() == protoreflect.BytesKind {
 		data := value.Bytes()
@@ -171,7 +175,7 @@ func compressFieldValueV1(fieldPath string, fd protoreflect.FieldDescriptor, val
 			// Compress as text using English model
 			str := string(data)
 			var buf bytes.Buffer
-			if err := arithcode.EncodeString(str, &buf); err != nil {
+			if err := models.EncodeString(str, &buf); err != nil {
 				return err
 			}
 			compressedBytes := buf.Bytes()
@@ -316,7 +320,7 @@ func compressFieldValueV1(fieldPath string, fd protoreflect.FieldDescriptor, val
 		// Use the English model for strings
 		str := value.String()
 		var buf bytes.Buffer
-		if err := arithcode.EncodeString(str, &buf); err != nil {
+		if err := models.EncodeString(str, &buf); err != nil {
 			return err
 		}
 		compressedBytes := buf.Bytes()
@@ -10,15 +10,19 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // DecompressV1 decompresses data into a protobuf message using Meshtastic-specific optimizations.
-func DecompressV1(r io.Reader, msg proto.Message) error {
+func DecompressV1(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := decompressHeader(r, WireV1, opts); err != nil {
+		return err
+	}
 	mmb := NewModelBuilderV1()
-	dec, err := arithcode.NewDecoder(r)
+	dec, err := coder.NewDecoder(r)
 	if err != nil {
 		return err
 	}
@@ -27,7 +31,7 @@ func DecompressV1(r io.Reader, msg proto.Message) error {
 }
 
 // decompressMessage recursively decompresses with Meshtastic-specific optimizations.
-func decompressMessageV1(fieldPath string, msg protoreflect.Message, dec *arithcode.Decoder, mmb *ModelBuilderV1) error {
+func decompressMessageV1(fieldPath string, msg protoreflect.Message, dec *coder.Decoder, mmb *ModelBuilderV1) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -69,7 +73,7 @@ func decompressMessageV1(fieldPath string, msg protoreflect.Message, dec *arithc
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
 			nestedMsg := msg.Mutable(fd).Message()
-			if err := decompressMessageV1(currentPath, nestedMsg, dec, mmb); err != nil {
+			if err := mmb.Nest(func() error { return decompressMessageV1(currentPath, nestedMsg, dec, mmb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -90,7 +94,7 @@ func decompressMessageV1(fieldPath string, msg protoreflect.Message, dec *arithc
 }
 
 // decompressRepeatedField decompresses repeated fields.
-func decompressRepeatedFieldV1(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *arithcode.Decoder, mmb *ModelBuilderV1) error {
+func decompressRepeatedFieldV1(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *coder.Decoder, mmb *ModelBuilderV1) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mmb.GetFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -101,12 +105,15 @@ func decompressRepeatedFieldV1(fieldPath string, fd protoreflect.FieldDescriptor
 	if err != nil {
 		return fmt.Errorf("list length: %w", err)
 	}
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	elementPath := fieldPath + "[]"
 	for i := 0; i < int(length); i++ {
 		if fd.Kind() == protoreflect.MessageKind {
 			elem := list.NewElement()
-			if err := decompressMessageV1(elementPath, elem.Message(), dec, mmb); err != nil {
+			if err := mmb.Nest(func() error { return decompressMessageV1(elementPath, elem.Message(), dec, mmb) }); err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 			list.Append(elem)
@@ -123,7 +130,7 @@ func decompressRepeatedFieldV1(fieldPath string, fd protoreflect.FieldDescriptor
 }
 
 // decodeFieldValue decodes field values with Meshtastic-specific logic.
-func decodeFieldValueV1(fieldPath string, fd protoreflect.FieldDescriptor, dec *arithcode.Decoder, mmb *ModelBuilderV1) (protoreflect.Value, error) {
+func decodeFieldValueV1(fieldPath string, fd protoreflect.FieldDescriptor, dec *coder.Decoder, mmb *ModelBuilderV1) (protoreflect.Value, error) {
 	// Special handling for Data.payload field
 	if fd.Name() == "payload" && fd.Kind() == protoreflect.BytesKind {
 		// Decode the text flag
@@ -138,6 +145,9 @@ func decodeFieldValueV1(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 			if err != nil {
 				return protoreflect.Value{}, err
 			}
+			if err := checkDecodedLength(uint64(compressedLen), pbmodel.DefaultLimits.MaxBytes); err != nil {
+				return protoreflect.Value{}, err
+			}
 
 			compressedBytes := make([]byte, compressedLen)
 			for i := 0; i < int(compressedLen); i++ {
@@ -148,7 +158,7 @@ func decodeFieldValueV1(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 				compressedBytes[i] = byte(b)
 			}
 
-			str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+			str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 			if err != nil {
 				return protoreflect.Value{}, err
 			}
@@ -305,6 +315,9 @@ func decodeFieldValueV1(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
+		if err := checkDecodedLength(uint64(compressedLen), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		// Decode the compressed bytes
 		compressedBytes := make([]byte, compressedLen)
@@ -317,7 +330,7 @@ func decodeFieldValueV1(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 		}
 
 		// Decompress the string using the English model
-		str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+		str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
@@ -330,6 +343,9 @@ func decodeFieldValueV1(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
+		if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		// Decode bytes
 		data := make([]byte, length)
@@ -349,7 +365,7 @@ func decodeFieldValueV1(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 }
 
 // decodeVarintFromDecoder decodes a varint from the decoder.
-func decodeVarintFromDecoderV1(dec *arithcode.Decoder, model arithcode.Model) (uint64, error) {
+func decodeVarintFromDecoderV1(dec *coder.Decoder, model coder.Model) (uint64, error) {
 	var value uint64
 	var shift uint
 	for {
@@ -11,7 +11,8 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
@@ -19,9 +20,12 @@ import (
 // CompressV2 compresses a protobuf message with optimized field encoding.
 // Instead of encoding a presence bit for each field, it encodes only present fields
 // using delta-encoded field numbers, significantly reducing overhead for sparse messages.
-func CompressV2(msg proto.Message, w io.Writer) error {
+func CompressV2(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := compressHeader(w, WireV2, opts); err != nil {
+		return err
+	}
 	mmb := NewModelBuilderV1()
-	enc := arithcode.NewEncoder(w)
+	enc := coder.NewEncoder(w)
 
 	if err := compressMessageV2("", msg.ProtoReflect(), enc, mmb); err != nil {
 		return err
@@ -31,7 +35,7 @@ func CompressV2(msg proto.Message, w io.Writer) error {
 }
 
 // compressMessageV2 recursively compresses with delta-encoded field numbers.
-func compressMessageV2(fieldPath string, msg protoreflect.Message, enc *arithcode.Encoder, mmb *ModelBuilderV1) error {
+func compressMessageV2(fieldPath string, msg protoreflect.Message, enc *coder.Encoder, mmb *ModelBuilderV1) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -91,7 +95,7 @@ func compressMessageV2(fieldPath string, msg protoreflect.Message, enc *arithcod
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV2(currentPath, value.Message(), enc, mmb); err != nil {
+			if err := mmb.Nest(func() error { return compressMessageV2(currentPath, value.Message(), enc, mmb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -110,7 +114,7 @@ func compressMessageV2(fieldPath string, msg protoreflect.Message, enc *arithcod
 }
 
 // compressRepeatedFieldV2 compresses repeated fields.
-func compressRepeatedFieldV2(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *arithcode.Encoder, mmb *ModelBuilderV1) error {
+func compressRepeatedFieldV2(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *coder.Encoder, mmb *ModelBuilderV1) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mmb.GetFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -129,7 +133,7 @@ func compressRepeatedFieldV2(fieldPath string, fd protoreflect.FieldDescriptor,
 	for i := 0; i < length; i++ {
 		value := list.Get(i)
 		if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV2(elementPath, value.Message(), enc, mmb); err != nil {
+			if err := mmb.Nest(func() error { return compressMessageV2(elementPath, value.Message(), enc, mmb) }); err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 		} else {
@@ -143,7 +147,7 @@ func compressRepeatedFieldV2(fieldPath string, fd protoreflect.FieldDescriptor,
 }
 
 // compressMapFieldV2 compresses map fields.
-func compressMapFieldV2(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *arithcode.Encoder, mmb *ModelBuilderV1) error {
+func compressMapFieldV2(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *coder.Encoder, mmb *ModelBuilderV1) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mmb.GetFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -164,14 +168,14 @@ func compressMapFieldV2(fieldPath string, fd protoreflect.FieldDescriptor, m pro
 	valuePath := fieldPath + "._value"
 
 	var encodeErr error
-	m.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+	pbmodel.RangeMapSorted(m, func(k protoreflect.MapKey, v protoreflect.Value) bool {
 		if err := compressFieldValueV2(keyPath, keyFd, k.Value(), enc, mmb); err != nil {
 			encodeErr = fmt.Errorf("map key: %w", err)
 			return false
 		}
 
 		if valueFd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV2(valuePath, v.Message(), enc, mmb); err != nil {
+			if err := mmb.Nest(func() error { return compressMessageV2(valuePath, v.Message(), enc, mmb) }); err != nil {
 				encodeErr = fmt.Errorf("map value: %w", err)
 				return false
 			}
@@ -189,7 +193,7 @@ func compressMapFieldV2(fieldPath string, fd protoreflect.FieldDescriptor, m pro
 }
 
 // compressFieldValueV2 compresses field values with Meshtastic-specific logic.
-func compressFieldValueV2(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *arithcode.Encoder, mmb *ModelBuilderV1) error {
+func compressFieldValueV2(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *coder.Encoder, mmb *ModelBuilderV1) error {
 	// Special handling for Data.payload field
 	if fd.Name() == "payload" && fd.Kind() == protoreflect.BytesKind {
 		data := value.Bytes()
@@ -220,7 +224,7 @@ func compressFieldValueV2(fieldPath string, fd protoreflect.FieldDescriptor, val
 			// Compress as text
 			str := string(data)
 			var buf bytes.Buffer
-			if err := arithcode.EncodeString(str, &buf); err != nil {
+			if err := models.EncodeString(str, &buf); err != nil {
 				return err
 			}
 			compressedBytes := buf.Bytes()
@@ -361,7 +365,7 @@ func compressFieldValueV2(fieldPath string, fd protoreflect.FieldDescriptor, val
 	case protoreflect.StringKind:
 		str := value.String()
 		var buf bytes.Buffer
-		if err := arithcode.EncodeString(str, &buf); err != nil {
+		if err := models.EncodeString(str, &buf); err != nil {
 			return err
 		}
 		compressedBytes := buf.Bytes()
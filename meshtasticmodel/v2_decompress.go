@@ -10,15 +10,19 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // DecompressV2 decompresses data with optimized field encoding.
-func DecompressV2(r io.Reader, msg proto.Message) error {
+func DecompressV2(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := decompressHeader(r, WireV2, opts); err != nil {
+		return err
+	}
 	mmb := NewModelBuilderV1()
-	dec, err := arithcode.NewDecoder(r)
+	dec, err := coder.NewDecoder(r)
 	if err != nil {
 		return err
 	}
@@ -27,7 +31,7 @@ func DecompressV2(r io.Reader, msg proto.Message) error {
 }
 
 // decompressMessageV2 recursively decompresses with delta-encoded field numbers.
-func decompressMessageV2(fieldPath string, msg protoreflect.Message, dec *arithcode.Decoder, mmb *ModelBuilderV1) error {
+func decompressMessageV2(fieldPath string, msg protoreflect.Message, dec *coder.Decoder, mmb *ModelBuilderV1) error {
 	md := msg.Descriptor()
 
 	// Decode number of present fields
@@ -81,7 +85,7 @@ func decompressMessageV2(fieldPath string, msg protoreflect.Message, dec *arithc
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
 			nestedMsg := msg.Mutable(fd).Message()
-			if err := decompressMessageV2(currentPath, nestedMsg, dec, mmb); err != nil {
+			if err := mmb.Nest(func() error { return decompressMessageV2(currentPath, nestedMsg, dec, mmb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -102,7 +106,7 @@ func decompressMessageV2(fieldPath string, msg protoreflect.Message, dec *arithc
 }
 
 // decompressRepeatedFieldV2 decompresses repeated fields.
-func decompressRepeatedFieldV2(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *arithcode.Decoder, mmb *ModelBuilderV1) error {
+func decompressRepeatedFieldV2(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *coder.Decoder, mmb *ModelBuilderV1) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mmb.GetFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -113,12 +117,15 @@ func decompressRepeatedFieldV2(fieldPath string, fd protoreflect.FieldDescriptor
 	if err != nil {
 		return fmt.Errorf("list length: %w", err)
 	}
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	elementPath := fieldPath + "[]"
 	for i := 0; i < int(length); i++ {
 		if fd.Kind() == protoreflect.MessageKind {
 			elem := list.NewElement()
-			if err := decompressMessageV2(elementPath, elem.Message(), dec, mmb); err != nil {
+			if err := mmb.Nest(func() error { return decompressMessageV2(elementPath, elem.Message(), dec, mmb) }); err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 			list.Append(elem)
@@ -135,7 +142,7 @@ func decompressRepeatedFieldV2(fieldPath string, fd protoreflect.FieldDescriptor
 }
 
 // decompressMapFieldV2 decompresses map fields.
-func decompressMapFieldV2(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, dec *arithcode.Decoder, mmb *ModelBuilderV1) error {
+func decompressMapFieldV2(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, dec *coder.Decoder, mmb *ModelBuilderV1) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mmb.GetFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -146,6 +153,9 @@ func decompressMapFieldV2(fieldPath string, fd protoreflect.FieldDescriptor, m p
 	if err != nil {
 		return fmt.Errorf("map length: %w", err)
 	}
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	keyFd := fd.MapKey()
 	valueFd := fd.MapValue()
@@ -161,7 +171,7 @@ func decompressMapFieldV2(fieldPath string, fd protoreflect.FieldDescriptor, m p
 		var mapValue protoreflect.Value
 		if valueFd.Kind() == protoreflect.MessageKind {
 			elem := m.NewValue()
-			if err := decompressMessageV2(valuePath, elem.Message(), dec, mmb); err != nil {
+			if err := mmb.Nest(func() error { return decompressMessageV2(valuePath, elem.Message(), dec, mmb) }); err != nil {
 				return fmt.Errorf("map value %d: %w", i, err)
 			}
 			mapValue = elem
@@ -180,7 +190,7 @@ func decompressMapFieldV2(fieldPath string, fd protoreflect.FieldDescriptor, m p
 }
 
 // decodeFieldValueV2 decodes field values with Meshtastic-specific logic.
-func decodeFieldValueV2(fieldPath string, fd protoreflect.FieldDescriptor, dec *arithcode.Decoder, mmb *ModelBuilderV1) (protoreflect.Value, error) {
+func decodeFieldValueV2(fieldPath string, fd protoreflect.FieldDescriptor, dec *coder.Decoder, mmb *ModelBuilderV1) (protoreflect.Value, error) {
 	// Special handling for Data.payload field
 	if fd.Name() == "payload" && fd.Kind() == protoreflect.BytesKind {
 		// Decode the text flag
@@ -195,6 +205,9 @@ func decodeFieldValueV2(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 			if err != nil {
 				return protoreflect.Value{}, err
 			}
+			if err := checkDecodedLength(uint64(compressedLen), pbmodel.DefaultLimits.MaxBytes); err != nil {
+				return protoreflect.Value{}, err
+			}
 
 			compressedBytes := make([]byte, compressedLen)
 			for i := 0; i < int(compressedLen); i++ {
@@ -205,7 +218,7 @@ func decodeFieldValueV2(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 				compressedBytes[i] = byte(b)
 			}
 
-			str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+			str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 			if err != nil {
 				return protoreflect.Value{}, err
 			}
@@ -359,6 +372,9 @@ func decodeFieldValueV2(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
+		if err := checkDecodedLength(uint64(compressedLen), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		compressedBytes := make([]byte, compressedLen)
 		for i := 0; i < int(compressedLen); i++ {
@@ -369,7 +385,7 @@ func decodeFieldValueV2(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 			compressedBytes[i] = byte(b)
 		}
 
-		str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+		str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
@@ -381,6 +397,9 @@ func decodeFieldValueV2(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
+		if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		data := make([]byte, length)
 		for i := 0; i < int(length); i++ {
@@ -399,7 +418,7 @@ func decodeFieldValueV2(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 }
 
 // decodeVarintFromDecoderV2 decodes a varint from the decoder.
-func decodeVarintFromDecoderV2(dec *arithcode.Decoder, model arithcode.Model) (uint64, error) {
+func decodeVarintFromDecoderV2(dec *coder.Decoder, model coder.Model) (uint64, error) {
 	var value uint64
 	var shift uint
 	for {
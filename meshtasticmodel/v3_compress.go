@@ -7,7 +7,7 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
@@ -15,9 +15,12 @@ import (
 // CompressV3 uses hybrid encoding: automatically chooses between
 // presence-bit encoding (for dense messages) and delta-encoded field numbers
 // (for sparse messages) based on which is more efficient.
-func CompressV3(msg proto.Message, w io.Writer) error {
+func CompressV3(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := compressHeader(w, WireV3, opts); err != nil {
+		return err
+	}
 	mmb := NewModelBuilderV1()
-	enc := arithcode.NewEncoder(w)
+	enc := coder.NewEncoder(w)
 
 	if err := compressMessageV3("", msg.ProtoReflect(), enc, mmb); err != nil {
 		return err
@@ -27,7 +30,7 @@ func CompressV3(msg proto.Message, w io.Writer) error {
 }
 
 // compressMessageV3 uses hybrid encoding strategy.
-func compressMessageV3(fieldPath string, msg protoreflect.Message, enc *arithcode.Encoder, mmb *ModelBuilderV1) error {
+func compressMessageV3(fieldPath string, msg protoreflect.Message, enc *coder.Encoder, mmb *ModelBuilderV1) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -66,7 +69,7 @@ func compressMessageV3(fieldPath string, msg protoreflect.Message, enc *arithcod
 }
 
 // compressMessagePresenceBits encodes using presence bits.
-func compressMessagePresenceBits(fieldPath string, msg protoreflect.Message, enc *arithcode.Encoder, mmb *ModelBuilderV1, fields protoreflect.FieldDescriptors, presentFields []protoreflect.FieldDescriptor) error {
+func compressMessagePresenceBits(fieldPath string, msg protoreflect.Message, enc *coder.Encoder, mmb *ModelBuilderV1, fields protoreflect.FieldDescriptors, presentFields []protoreflect.FieldDescriptor) error {
 	md := msg.Descriptor()
 
 	// Create a map for quick lookup
@@ -113,7 +116,7 @@ func compressMessagePresenceBits(fieldPath string, msg protoreflect.Message, enc
 }
 
 // compressMessageDelta encodes using delta-encoded field numbers.
-func compressMessageDelta(fieldPath string, msg protoreflect.Message, enc *arithcode.Encoder, mmb *ModelBuilderV1, presentFields []protoreflect.FieldDescriptor) error {
+func compressMessageDelta(fieldPath string, msg protoreflect.Message, enc *coder.Encoder, mmb *ModelBuilderV1, presentFields []protoreflect.FieldDescriptor) error {
 	md := msg.Descriptor()
 
 	// Encode number of present fields
@@ -163,19 +166,19 @@ func compressMessageDelta(fieldPath string, msg protoreflect.Message, enc *arith
 }
 
 // encodeFieldV3 encodes a field value (shared by both strategies).
-func encodeFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *arithcode.Encoder, mmb *ModelBuilderV1) error {
+func encodeFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *coder.Encoder, mmb *ModelBuilderV1) error {
 	if fd.IsList() {
 		return compressRepeatedFieldV3(fieldPath, fd, value.List(), enc, mmb)
 	} else if fd.IsMap() {
 		return compressMapFieldV3(fieldPath, fd, value.Map(), enc, mmb)
 	} else if fd.Kind() == protoreflect.MessageKind {
-		return compressMessageV3(fieldPath, value.Message(), enc, mmb)
+		return mmb.Nest(func() error { return compressMessageV3(fieldPath, value.Message(), enc, mmb) })
 	}
 	return compressFieldValueV3(fieldPath, fd, value, enc, mmb)
 }
 
 // compressRepeatedFieldV3 compresses repeated fields.
-func compressRepeatedFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *arithcode.Encoder, mmb *ModelBuilderV1) error {
+func compressRepeatedFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *coder.Encoder, mmb *ModelBuilderV1) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mmb.GetFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -194,7 +197,7 @@ func compressRepeatedFieldV3(fieldPath string, fd protoreflect.FieldDescriptor,
 	for i := 0; i < length; i++ {
 		value := list.Get(i)
 		if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV3(elementPath, value.Message(), enc, mmb); err != nil {
+			if err := mmb.Nest(func() error { return compressMessageV3(elementPath, value.Message(), enc, mmb) }); err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 		} else {
@@ -208,7 +211,7 @@ func compressRepeatedFieldV3(fieldPath string, fd protoreflect.FieldDescriptor,
 }
 
 // compressMapFieldV3 compresses map fields.
-func compressMapFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *arithcode.Encoder, mmb *ModelBuilderV1) error {
+func compressMapFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *coder.Encoder, mmb *ModelBuilderV1) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mmb.GetFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -229,14 +232,14 @@ func compressMapFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, m pro
 	valuePath := fieldPath + "._value"
 
 	var encodeErr error
-	m.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+	pbmodel.RangeMapSorted(m, func(k protoreflect.MapKey, v protoreflect.Value) bool {
 		if err := compressFieldValueV3(keyPath, keyFd, k.Value(), enc, mmb); err != nil {
 			encodeErr = fmt.Errorf("map key: %w", err)
 			return false
 		}
 
 		if valueFd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV3(valuePath, v.Message(), enc, mmb); err != nil {
+			if err := mmb.Nest(func() error { return compressMessageV3(valuePath, v.Message(), enc, mmb) }); err != nil {
 				encodeErr = fmt.Errorf("map value: %w", err)
 				return false
 			}
@@ -254,7 +257,7 @@ func compressMapFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, m pro
 }
 
 // compressFieldValueV3 compresses a field value (reuses V1/V2 logic).
-func compressFieldValueV3(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *arithcode.Encoder, mmb *ModelBuilderV1) error {
+func compressFieldValueV3(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *coder.Encoder, mmb *ModelBuilderV1) error {
 	// Reuse the V2 implementation
 	return compressFieldValueV2(fieldPath, fd, value, enc, mmb)
 }
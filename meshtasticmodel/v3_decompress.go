@@ -7,15 +7,18 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // DecompressV3 decompresses data with hybrid encoding.
-func DecompressV3(r io.Reader, msg proto.Message) error {
+func DecompressV3(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := decompressHeader(r, WireV3, opts); err != nil {
+		return err
+	}
 	mmb := NewModelBuilderV1()
-	dec, err := arithcode.NewDecoder(r)
+	dec, err := coder.NewDecoder(r)
 	if err != nil {
 		return err
 	}
@@ -24,7 +27,7 @@ func DecompressV3(r io.Reader, msg proto.Message) error {
 }
 
 // decompressMessageV3 uses hybrid decoding strategy.
-func decompressMessageV3(fieldPath string, msg protoreflect.Message, dec *arithcode.Decoder, mmb *ModelBuilderV1) error {
+func decompressMessageV3(fieldPath string, msg protoreflect.Message, dec *coder.Decoder, mmb *ModelBuilderV1) error {
 	// Decode strategy flag
 	strategyFlag, err := dec.Decode(mmb.BoolModel())
 	if err != nil {
@@ -40,7 +43,7 @@ func decompressMessageV3(fieldPath string, msg protoreflect.Message, dec *arithc
 }
 
 // decompressMessagePresenceBits decodes using presence bits.
-func decompressMessagePresenceBits(fieldPath string, msg protoreflect.Message, dec *arithcode.Decoder, mmb *ModelBuilderV1) error {
+func decompressMessagePresenceBits(fieldPath string, msg protoreflect.Message, dec *coder.Decoder, mmb *ModelBuilderV1) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -87,7 +90,7 @@ func decompressMessagePresenceBits(fieldPath string, msg protoreflect.Message, d
 }
 
 // decompressMessageDelta decodes using delta-encoded field numbers.
-func decompressMessageDelta(fieldPath string, msg protoreflect.Message, dec *arithcode.Decoder, mmb *ModelBuilderV1) error {
+func decompressMessageDelta(fieldPath string, msg protoreflect.Message, dec *coder.Decoder, mmb *ModelBuilderV1) error {
 	md := msg.Descriptor()
 
 	// Decode number of present fields
@@ -143,7 +146,7 @@ func decompressMessageDelta(fieldPath string, msg protoreflect.Message, dec *ari
 }
 
 // decodeFieldV3 decodes a field (shared by both strategies).
-func decodeFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, msg protoreflect.Message, dec *arithcode.Decoder, mmb *ModelBuilderV1) error {
+func decodeFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, msg protoreflect.Message, dec *coder.Decoder, mmb *ModelBuilderV1) error {
 	if fd.IsList() {
 		list := msg.Mutable(fd).List()
 		return decompressRepeatedFieldV3(fieldPath, fd, list, dec, mmb)
@@ -152,7 +155,7 @@ func decodeFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, msg protor
 		return decompressMapFieldV3(fieldPath, fd, m, dec, mmb)
 	} else if fd.Kind() == protoreflect.MessageKind {
 		nestedMsg := msg.Mutable(fd).Message()
-		return decompressMessageV3(fieldPath, nestedMsg, dec, mmb)
+		return mmb.Nest(func() error { return decompressMessageV3(fieldPath, nestedMsg, dec, mmb) })
 	}
 
 	value, err := decodeFieldValueV3(fieldPath, fd, dec, mmb)
@@ -164,7 +167,7 @@ func decodeFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, msg protor
 }
 
 // decompressRepeatedFieldV3 decompresses repeated fields.
-func decompressRepeatedFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *arithcode.Decoder, mmb *ModelBuilderV1) error {
+func decompressRepeatedFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *coder.Decoder, mmb *ModelBuilderV1) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mmb.GetFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -175,12 +178,15 @@ func decompressRepeatedFieldV3(fieldPath string, fd protoreflect.FieldDescriptor
 	if err != nil {
 		return fmt.Errorf("list length: %w", err)
 	}
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	elementPath := fieldPath + "[]"
 	for i := 0; i < int(length); i++ {
 		if fd.Kind() == protoreflect.MessageKind {
 			elem := list.NewElement()
-			if err := decompressMessageV3(elementPath, elem.Message(), dec, mmb); err != nil {
+			if err := mmb.Nest(func() error { return decompressMessageV3(elementPath, elem.Message(), dec, mmb) }); err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 			list.Append(elem)
@@ -197,7 +203,7 @@ func decompressRepeatedFieldV3(fieldPath string, fd protoreflect.FieldDescriptor
 }
 
 // decompressMapFieldV3 decompresses map fields.
-func decompressMapFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, dec *arithcode.Decoder, mmb *ModelBuilderV1) error {
+func decompressMapFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, dec *coder.Decoder, mmb *ModelBuilderV1) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mmb.GetFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -208,6 +214,9 @@ func decompressMapFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, m p
 	if err != nil {
 		return fmt.Errorf("map length: %w", err)
 	}
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	keyFd := fd.MapKey()
 	valueFd := fd.MapValue()
@@ -223,7 +232,7 @@ func decompressMapFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, m p
 		var mapValue protoreflect.Value
 		if valueFd.Kind() == protoreflect.MessageKind {
 			elem := m.NewValue()
-			if err := decompressMessageV3(valuePath, elem.Message(), dec, mmb); err != nil {
+			if err := mmb.Nest(func() error { return decompressMessageV3(valuePath, elem.Message(), dec, mmb) }); err != nil {
 				return fmt.Errorf("map value %d: %w", i, err)
 			}
 			mapValue = elem
@@ -242,7 +251,7 @@ func decompressMapFieldV3(fieldPath string, fd protoreflect.FieldDescriptor, m p
 }
 
 // decodeFieldValueV3 decodes a field value (reuses V2 logic).
-func decodeFieldValueV3(fieldPath string, fd protoreflect.FieldDescriptor, dec *arithcode.Decoder, mmb *ModelBuilderV1) (protoreflect.Value, error) {
+func decodeFieldValueV3(fieldPath string, fd protoreflect.FieldDescriptor, dec *coder.Decoder, mmb *ModelBuilderV1) (protoreflect.Value, error) {
 	// Reuse the V2 implementation
 	return decodeFieldValueV2(fieldPath, fd, dec, mmb)
 }
@@ -11,16 +11,20 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // CompressV4 adds enum value prediction on top of V1.
 // Common enum values are encoded with just 1 bit instead of full enum encoding.
-func CompressV4(msg proto.Message, w io.Writer) error {
+func CompressV4(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := compressHeader(w, WireV4, opts); err != nil {
+		return err
+	}
 	mmb := NewModelBuilderV4()
-	enc := arithcode.NewEncoder(w)
+	enc := coder.NewEncoder(w)
 
 	if err := compressMessageV4("", msg.ProtoReflect(), enc, mmb); err != nil {
 		return err
@@ -67,7 +71,7 @@ func getCommonEnumValues() map[string]protoreflect.EnumNumber {
 }
 
 // compressMessageV4 recursively compresses with enum prediction.
-func compressMessageV4(fieldPath string, msg protoreflect.Message, enc *arithcode.Encoder, mmb *ModelBuilderV4) error {
+func compressMessageV4(fieldPath string, msg protoreflect.Message, enc *coder.Encoder, mmb *ModelBuilderV4) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -107,7 +111,7 @@ func compressMessageV4(fieldPath string, msg protoreflect.Message, enc *arithcod
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV4(currentPath, value.Message(), enc, mmb); err != nil {
+			if err := mmb.Nest(func() error { return compressMessageV4(currentPath, value.Message(), enc, mmb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -126,7 +130,7 @@ func compressMessageV4(fieldPath string, msg protoreflect.Message, enc *arithcod
 }
 
 // compressRepeatedFieldV4 compresses repeated fields.
-func compressRepeatedFieldV4(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *arithcode.Encoder, mmb *ModelBuilderV4) error {
+func compressRepeatedFieldV4(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *coder.Encoder, mmb *ModelBuilderV4) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mmb.GetFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -145,7 +149,7 @@ func compressRepeatedFieldV4(fieldPath string, fd protoreflect.FieldDescriptor,
 	for i := 0; i < length; i++ {
 		value := list.Get(i)
 		if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV4(elementPath, value.Message(), enc, mmb); err != nil {
+			if err := mmb.Nest(func() error { return compressMessageV4(elementPath, value.Message(), enc, mmb) }); err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 		} else {
@@ -159,7 +163,7 @@ func compressRepeatedFieldV4(fieldPath string, fd protoreflect.FieldDescriptor,
 }
 
 // compressMapFieldV4 compresses map fields.
-func compressMapFieldV4(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *arithcode.Encoder, mmb *ModelBuilderV4) error {
+func compressMapFieldV4(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *coder.Encoder, mmb *ModelBuilderV4) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mmb.GetFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -180,14 +184,14 @@ func compressMapFieldV4(fieldPath string, fd protoreflect.FieldDescriptor, m pro
 	valuePath := fieldPath + "._value"
 
 	var encodeErr error
-	m.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+	pbmodel.RangeMapSorted(m, func(k protoreflect.MapKey, v protoreflect.Value) bool {
 		if err := compressFieldValueV4(keyPath, keyFd, k.Value(), enc, mmb); err != nil {
 			encodeErr = fmt.Errorf("map key: %w", err)
 			return false
 		}
 
 		if valueFd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV4(valuePath, v.Message(), enc, mmb); err != nil {
+			if err := mmb.Nest(func() error { return compressMessageV4(valuePath, v.Message(), enc, mmb) }); err != nil {
 				encodeErr = fmt.Errorf("map value: %w", err)
 				return false
 			}
@@ -205,7 +209,7 @@ func compressMapFieldV4(fieldPath string, fd protoreflect.FieldDescriptor, m pro
 }
 
 // compressFieldValueV4 compresses field values with enum prediction.
-func compressFieldValueV4(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *arithcode.Encoder, mmb *ModelBuilderV4) error {
+func compressFieldValueV4(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *coder.Encoder, mmb *ModelBuilderV4) error {
 	// Special handling for Data.payload field
 	if fd.Name() == "payload" && fd.Kind() == protoreflect.BytesKind {
 		data := value.Bytes()
@@ -233,7 +237,7 @@ func compressFieldValueV4(fieldPath string, fd protoreflect.FieldDescriptor, val
 		if isText {
 			str := string(data)
 			var buf bytes.Buffer
-			if err := arithcode.EncodeString(str, &buf); err != nil {
+			if err := models.EncodeString(str, &buf); err != nil {
 				return err
 			}
 			compressedBytes := buf.Bytes()
@@ -389,7 +393,7 @@ func compressFieldValueV4(fieldPath string, fd protoreflect.FieldDescriptor, val
 	case protoreflect.StringKind:
 		str := value.String()
 		var buf bytes.Buffer
-		if err := arithcode.EncodeString(str, &buf); err != nil {
+		if err := models.EncodeString(str, &buf); err != nil {
 			return err
 		}
 		compressedBytes := buf.Bytes()
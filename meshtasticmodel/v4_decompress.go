@@ -10,15 +10,19 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // DecompressV4 decompresses data with enum value prediction.
-func DecompressV4(r io.Reader, msg proto.Message) error {
+func DecompressV4(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := decompressHeader(r, WireV4, opts); err != nil {
+		return err
+	}
 	mmb := NewModelBuilderV4()
-	dec, err := arithcode.NewDecoder(r)
+	dec, err := coder.NewDecoder(r)
 	if err != nil {
 		return err
 	}
@@ -27,7 +31,7 @@ func DecompressV4(r io.Reader, msg proto.Message) error {
 }
 
 // decompressMessageV4 recursively decompresses with enum prediction.
-func decompressMessageV4(fieldPath string, msg protoreflect.Message, dec *arithcode.Decoder, mmb *ModelBuilderV4) error {
+func decompressMessageV4(fieldPath string, msg protoreflect.Message, dec *coder.Decoder, mmb *ModelBuilderV4) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -69,7 +73,7 @@ func decompressMessageV4(fieldPath string, msg protoreflect.Message, dec *arithc
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
 			nestedMsg := msg.Mutable(fd).Message()
-			if err := decompressMessageV4(currentPath, nestedMsg, dec, mmb); err != nil {
+			if err := mmb.Nest(func() error { return decompressMessageV4(currentPath, nestedMsg, dec, mmb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -90,7 +94,7 @@ func decompressMessageV4(fieldPath string, msg protoreflect.Message, dec *arithc
 }
 
 // decompressRepeatedFieldV4 decompresses repeated fields.
-func decompressRepeatedFieldV4(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *arithcode.Decoder, mmb *ModelBuilderV4) error {
+func decompressRepeatedFieldV4(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *coder.Decoder, mmb *ModelBuilderV4) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mmb.GetFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -101,12 +105,15 @@ func decompressRepeatedFieldV4(fieldPath string, fd protoreflect.FieldDescriptor
 	if err != nil {
 		return fmt.Errorf("list length: %w", err)
 	}
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	elementPath := fieldPath + "[]"
 	for i := 0; i < int(length); i++ {
 		if fd.Kind() == protoreflect.MessageKind {
 			elem := list.NewElement()
-			if err := decompressMessageV4(elementPath, elem.Message(), dec, mmb); err != nil {
+			if err := mmb.Nest(func() error { return decompressMessageV4(elementPath, elem.Message(), dec, mmb) }); err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 			list.Append(elem)
@@ -123,7 +130,7 @@ func decompressRepeatedFieldV4(fieldPath string, fd protoreflect.FieldDescriptor
 }
 
 // decompressMapFieldV4 decompresses map fields.
-func decompressMapFieldV4(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, dec *arithcode.Decoder, mmb *ModelBuilderV4) error {
+func decompressMapFieldV4(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, dec *coder.Decoder, mmb *ModelBuilderV4) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mmb.GetFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -134,6 +141,9 @@ func decompressMapFieldV4(fieldPath string, fd protoreflect.FieldDescriptor, m p
 	if err != nil {
 		return fmt.Errorf("map length: %w", err)
 	}
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	keyFd := fd.MapKey()
 	valueFd := fd.MapValue()
@@ -149,7 +159,7 @@ func decompressMapFieldV4(fieldPath string, fd protoreflect.FieldDescriptor, m p
 		var mapValue protoreflect.Value
 		if valueFd.Kind() == protoreflect.MessageKind {
 			elem := m.NewValue()
-			if err := decompressMessageV4(valuePath, elem.Message(), dec, mmb); err != nil {
+			if err := mmb.Nest(func() error { return decompressMessageV4(valuePath, elem.Message(), dec, mmb) }); err != nil {
 				return fmt.Errorf("map value %d: %w", i, err)
 			}
 			mapValue = elem
@@ -168,7 +178,7 @@ func decompressMapFieldV4(fieldPath string, fd protoreflect.FieldDescriptor, m p
 }
 
 // decodeFieldValueV4 decodes field values with enum prediction.
-func decodeFieldValueV4(fieldPath string, fd protoreflect.FieldDescriptor, dec *arithcode.Decoder, mmb *ModelBuilderV4) (protoreflect.Value, error) {
+func decodeFieldValueV4(fieldPath string, fd protoreflect.FieldDescriptor, dec *coder.Decoder, mmb *ModelBuilderV4) (protoreflect.Value, error) {
 	// Special handling for Data.payload field
 	if fd.Name() == "payload" && fd.Kind() == protoreflect.BytesKind {
 		textFlag, err := dec.Decode(mmb.BoolModel())
@@ -181,6 +191,9 @@ func decodeFieldValueV4(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 			if err != nil {
 				return protoreflect.Value{}, err
 			}
+			if err := checkDecodedLength(uint64(compressedLen), pbmodel.DefaultLimits.MaxBytes); err != nil {
+				return protoreflect.Value{}, err
+			}
 
 			compressedBytes := make([]byte, compressedLen)
 			for i := 0; i < int(compressedLen); i++ {
@@ -191,7 +204,7 @@ func decodeFieldValueV4(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 				compressedBytes[i] = byte(b)
 			}
 
-			str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+			str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 			if err != nil {
 				return protoreflect.Value{}, err
 			}
@@ -363,6 +376,9 @@ func decodeFieldValueV4(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
+		if err := checkDecodedLength(uint64(compressedLen), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		compressedBytes := make([]byte, compressedLen)
 		for i := 0; i < int(compressedLen); i++ {
@@ -373,7 +389,7 @@ func decodeFieldValueV4(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 			compressedBytes[i] = byte(b)
 		}
 
-		str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+		str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
@@ -385,6 +401,9 @@ func decodeFieldValueV4(fieldPath string, fd protoreflect.FieldDescriptor, dec *
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
+		if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		data := make([]byte, length)
 		for i := 0; i < int(length); i++ {
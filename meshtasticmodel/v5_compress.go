@@ -11,16 +11,20 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // CompressV5 uses context-aware models that are optimized for specific
 // field types and value ranges commonly found in Meshtastic messages.
-func CompressV5(msg proto.Message, w io.Writer) error {
+func CompressV5(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := compressHeader(w, WireV5, opts); err != nil {
+		return err
+	}
 	mcb := NewContextualModelBuilder()
-	enc := arithcode.NewEncoder(w)
+	enc := coder.NewEncoder(w)
 
 	// Set initial message type context
 	msgType := string(msg.ProtoReflect().Descriptor().Name())
@@ -34,7 +38,7 @@ func CompressV5(msg proto.Message, w io.Writer) error {
 }
 
 // compressMessageV5 recursively compresses with context-aware models.
-func compressMessageV5(fieldPath string, msg protoreflect.Message, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressMessageV5(fieldPath string, msg protoreflect.Message, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -79,7 +83,7 @@ func compressMessageV5(fieldPath string, msg protoreflect.Message, enc *arithcod
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV5(currentPath, value.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV5(currentPath, value.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -98,7 +102,7 @@ func compressMessageV5(fieldPath string, msg protoreflect.Message, enc *arithcod
 }
 
 // compressRepeatedFieldV5 compresses repeated fields with context-aware models.
-func compressRepeatedFieldV5(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressRepeatedFieldV5(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mcb.GetContextualFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -117,7 +121,7 @@ func compressRepeatedFieldV5(fieldPath string, fd protoreflect.FieldDescriptor,
 	for i := 0; i < length; i++ {
 		value := list.Get(i)
 		if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV5(elementPath, value.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV5(elementPath, value.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 		} else {
@@ -131,7 +135,7 @@ func compressRepeatedFieldV5(fieldPath string, fd protoreflect.FieldDescriptor,
 }
 
 // compressMapFieldV5 compresses map fields with context-aware models.
-func compressMapFieldV5(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressMapFieldV5(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mcb.GetContextualFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -152,14 +156,14 @@ func compressMapFieldV5(fieldPath string, fd protoreflect.FieldDescriptor, m pro
 	valuePath := fieldPath + "._value"
 
 	var encodeErr error
-	m.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+	pbmodel.RangeMapSorted(m, func(k protoreflect.MapKey, v protoreflect.Value) bool {
 		if err := compressFieldValueV5(keyPath, keyFd, k.Value(), enc, mcb); err != nil {
 			encodeErr = fmt.Errorf("map key: %w", err)
 			return false
 		}
 
 		if valueFd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV5(valuePath, v.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV5(valuePath, v.Message(), enc, mcb) }); err != nil {
 				encodeErr = fmt.Errorf("map value: %w", err)
 				return false
 			}
@@ -177,7 +181,7 @@ func compressMapFieldV5(fieldPath string, fd protoreflect.FieldDescriptor, m pro
 }
 
 // compressFieldValueV5 compresses field values with context-aware models.
-func compressFieldValueV5(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressFieldValueV5(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	// Special handling for Data.payload field
 	if fd.Name() == "payload" && fd.Kind() == protoreflect.BytesKind {
 		data := value.Bytes()
@@ -205,7 +209,7 @@ func compressFieldValueV5(fieldPath string, fd protoreflect.FieldDescriptor, val
 		if isText {
 			str := string(data)
 			var buf bytes.Buffer
-			if err := arithcode.EncodeString(str, &buf); err != nil {
+			if err := models.EncodeString(str, &buf); err != nil {
 				return err
 			}
 			compressedBytes := buf.Bytes()
@@ -368,7 +372,7 @@ func compressFieldValueV5(fieldPath string, fd protoreflect.FieldDescriptor, val
 	case protoreflect.StringKind:
 		str := value.String()
 		var buf bytes.Buffer
-		if err := arithcode.EncodeString(str, &buf); err != nil {
+		if err := models.EncodeString(str, &buf); err != nil {
 			return err
 		}
 		compressedBytes := buf.Bytes()
@@ -10,15 +10,19 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // DecompressV5 decompresses a message using context-aware models.
-func DecompressV5(r io.Reader, msg proto.Message) error {
+func DecompressV5(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := decompressHeader(r, WireV5, opts); err != nil {
+		return err
+	}
 	mcb := NewContextualModelBuilder()
-	dec, err := arithcode.NewDecoder(r)
+	dec, err := coder.NewDecoder(r)
 	if err != nil {
 		return err
 	}
@@ -31,7 +35,7 @@ func DecompressV5(r io.Reader, msg proto.Message) error {
 }
 
 // decompressMessageV5 recursively decompresses with context-aware models.
-func decompressMessageV5(fieldPath string, msg protoreflect.Message, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressMessageV5(fieldPath string, msg protoreflect.Message, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -68,7 +72,7 @@ func decompressMessageV5(fieldPath string, msg protoreflect.Message, dec *arithc
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
 			nestedMsg := msg.Mutable(fd).Message()
-			if err := decompressMessageV5(currentPath, nestedMsg, dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV5(currentPath, nestedMsg, dec, mcb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -96,7 +100,7 @@ func decompressMessageV5(fieldPath string, msg protoreflect.Message, dec *arithc
 }
 
 // decompressRepeatedFieldV5 decompresses repeated fields.
-func decompressRepeatedFieldV5(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressRepeatedFieldV5(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mcb.GetContextualFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -116,12 +120,15 @@ func decompressRepeatedFieldV5(fieldPath string, fd protoreflect.FieldDescriptor
 		}
 	}
 	length := int(pbmodel.DecodeVarint(lengthBytes))
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	elementPath := fieldPath + "[]"
 	for i := 0; i < length; i++ {
 		if fd.Kind() == protoreflect.MessageKind {
 			nestedMsg := list.NewElement().Message()
-			if err := decompressMessageV5(elementPath, nestedMsg, dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV5(elementPath, nestedMsg, dec, mcb) }); err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 			list.Append(protoreflect.ValueOfMessage(nestedMsg))
@@ -138,7 +145,7 @@ func decompressRepeatedFieldV5(fieldPath string, fd protoreflect.FieldDescriptor
 }
 
 // decompressMapFieldV5 decompresses map fields.
-func decompressMapFieldV5(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressMapFieldV5(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mcb.GetContextualFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -158,6 +165,9 @@ func decompressMapFieldV5(fieldPath string, fd protoreflect.FieldDescriptor, m p
 		}
 	}
 	length := int(pbmodel.DecodeVarint(lengthBytes))
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	keyFd := fd.MapKey()
 	valueFd := fd.MapValue()
@@ -175,7 +185,7 @@ func decompressMapFieldV5(fieldPath string, fd protoreflect.FieldDescriptor, m p
 		var mapValue protoreflect.Value
 		if valueFd.Kind() == protoreflect.MessageKind {
 			nestedMsg := m.NewValue().Message()
-			if err := decompressMessageV5(valuePath, nestedMsg, dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV5(valuePath, nestedMsg, dec, mcb) }); err != nil {
 				return fmt.Errorf("map value: %w", err)
 			}
 			mapValue = protoreflect.ValueOfMessage(nestedMsg)
@@ -194,7 +204,7 @@ func decompressMapFieldV5(fieldPath string, fd protoreflect.FieldDescriptor, m p
 }
 
 // decompressFieldValueV5 decompresses a field value.
-func decompressFieldValueV5(fieldPath string, fd protoreflect.FieldDescriptor, dec *arithcode.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
+func decompressFieldValueV5(fieldPath string, fd protoreflect.FieldDescriptor, dec *coder.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
 	// Special handling for Data.payload field
 	if fd.Name() == "payload" && fd.Kind() == protoreflect.BytesKind {
 		// Decode text flag
@@ -217,6 +227,9 @@ func decompressFieldValueV5(fieldPath string, fd protoreflect.FieldDescriptor, d
 				}
 			}
 			compressedLen := int(pbmodel.DecodeVarint(lengthBytes))
+			if err := checkDecodedLength(uint64(compressedLen), pbmodel.DefaultLimits.MaxBytes); err != nil {
+				return protoreflect.Value{}, err
+			}
 
 			compressedBytes := make([]byte, compressedLen)
 			for i := 0; i < compressedLen; i++ {
@@ -227,7 +240,7 @@ func decompressFieldValueV5(fieldPath string, fd protoreflect.FieldDescriptor, d
 				compressedBytes[i] = byte(symbol)
 			}
 
-			str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+			str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 			if err != nil {
 				return protoreflect.Value{}, err
 			}
@@ -423,6 +436,9 @@ func decompressFieldValueV5(fieldPath string, fd protoreflect.FieldDescriptor, d
 			}
 		}
 		compressedLen := int(pbmodel.DecodeVarint(lengthBytes))
+		if err := checkDecodedLength(uint64(compressedLen), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		// Decode compressed bytes
 		compressedBytes := make([]byte, compressedLen)
@@ -435,7 +451,7 @@ func decompressFieldValueV5(fieldPath string, fd protoreflect.FieldDescriptor, d
 		}
 
 		// Decompress string
-		str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+		str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
@@ -455,6 +471,9 @@ func decompressFieldValueV5(fieldPath string, fd protoreflect.FieldDescriptor, d
 			}
 		}
 		length := int(pbmodel.DecodeVarint(lengthBytes))
+		if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		// Decode bytes
 		data := make([]byte, length)
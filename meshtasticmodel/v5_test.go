@@ -6,6 +6,7 @@ import (
 
 	"google.golang.org/protobuf/proto"
 
+	"github.com/egonelbre/exp-protobuf-compression/meshfixtures"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 )
 
@@ -32,18 +33,7 @@ func TestMeshtasticV5ContextAwareness(t *testing.T) {
 		},
 		{
 			name: "Telemetry with typical battery and signal",
-			msg: &meshtastic.Telemetry{
-				Time: 1703520000,
-				Variant: &meshtastic.Telemetry_DeviceMetrics{
-					DeviceMetrics: &meshtastic.DeviceMetrics{
-						BatteryLevel:       proto.Uint32(75), // Mid-high battery
-						Voltage:            proto.Float32(4.1),
-						ChannelUtilization: proto.Float32(15.5), // Low utilization
-						AirUtilTx:          proto.Float32(5.2),
-						UptimeSeconds:      proto.Uint32(86400),
-					},
-				},
-			},
+			msg:  meshfixtures.DeviceTelemetry(meshfixtures.Default),
 		},
 		{
 			name: "MeshPacket with typical hop count and signal",
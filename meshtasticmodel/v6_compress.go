@@ -11,15 +11,19 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // CompressV6 uses bit packing for boolean clusters on top of V5 context-aware models.
-func CompressV6(msg proto.Message, w io.Writer) error {
+func CompressV6(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := compressHeader(w, WireV6, opts); err != nil {
+		return err
+	}
 	mcb := NewContextualModelBuilder()
-	enc := arithcode.NewEncoder(w)
+	enc := coder.NewEncoder(w)
 
 	// Set initial message type context
 	msgType := string(msg.ProtoReflect().Descriptor().Name())
@@ -33,7 +37,7 @@ func CompressV6(msg proto.Message, w io.Writer) error {
 }
 
 // compressMessageV6 recursively compresses with bit-packed booleans.
-func compressMessageV6(fieldPath string, msg protoreflect.Message, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressMessageV6(fieldPath string, msg protoreflect.Message, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -100,7 +104,7 @@ func compressMessageV6(fieldPath string, msg protoreflect.Message, enc *arithcod
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV6(currentPath, value.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV6(currentPath, value.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -160,7 +164,7 @@ func identifyBooleanClusters(fields protoreflect.FieldDescriptors, msg protorefl
 }
 
 // encodeBooleanCluster packs multiple boolean fields into a compact representation.
-func encodeBooleanCluster(cluster BooleanCluster, msg protoreflect.Message, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func encodeBooleanCluster(cluster BooleanCluster, msg protoreflect.Message, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	// First, encode presence bits for all booleans in cluster
 	var presenceBits uint8
 	for i, fd := range cluster.fieldDescs {
@@ -194,7 +198,7 @@ func encodeBooleanCluster(cluster BooleanCluster, msg protoreflect.Message, enc
 }
 
 // compressRepeatedFieldV6 compresses repeated fields.
-func compressRepeatedFieldV6(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressRepeatedFieldV6(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mcb.GetContextualFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -213,7 +217,7 @@ func compressRepeatedFieldV6(fieldPath string, fd protoreflect.FieldDescriptor,
 	for i := 0; i < length; i++ {
 		value := list.Get(i)
 		if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV6(elementPath, value.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV6(elementPath, value.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 		} else {
@@ -227,7 +231,7 @@ func compressRepeatedFieldV6(fieldPath string, fd protoreflect.FieldDescriptor,
 }
 
 // compressMapFieldV6 compresses map fields.
-func compressMapFieldV6(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressMapFieldV6(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mcb.GetContextualFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -248,14 +252,14 @@ func compressMapFieldV6(fieldPath string, fd protoreflect.FieldDescriptor, m pro
 	valuePath := fieldPath + "._value"
 
 	var encodeErr error
-	m.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+	pbmodel.RangeMapSorted(m, func(k protoreflect.MapKey, v protoreflect.Value) bool {
 		if err := compressFieldValueV6(keyPath, keyFd, k.Value(), enc, mcb); err != nil {
 			encodeErr = fmt.Errorf("map key: %w", err)
 			return false
 		}
 
 		if valueFd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV6(valuePath, v.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV6(valuePath, v.Message(), enc, mcb) }); err != nil {
 				encodeErr = fmt.Errorf("map value: %w", err)
 				return false
 			}
@@ -273,7 +277,7 @@ func compressMapFieldV6(fieldPath string, fd protoreflect.FieldDescriptor, m pro
 }
 
 // compressFieldValueV6 compresses field values (reuses V5 logic).
-func compressFieldValueV6(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressFieldValueV6(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	// Special handling for Data.payload field
 	if fd.Name() == "payload" && fd.Kind() == protoreflect.BytesKind {
 		data := value.Bytes()
@@ -301,7 +305,7 @@ func compressFieldValueV6(fieldPath string, fd protoreflect.FieldDescriptor, val
 		if isText {
 			str := string(data)
 			var buf bytes.Buffer
-			if err := arithcode.EncodeString(str, &buf); err != nil {
+			if err := models.EncodeString(str, &buf); err != nil {
 				return err
 			}
 			compressedBytes := buf.Bytes()
@@ -465,7 +469,7 @@ func compressFieldValueV6(fieldPath string, fd protoreflect.FieldDescriptor, val
 	case protoreflect.StringKind:
 		str := value.String()
 		var buf bytes.Buffer
-		if err := arithcode.EncodeString(str, &buf); err != nil {
+		if err := models.EncodeString(str, &buf); err != nil {
 			return err
 		}
 		compressedBytes := buf.Bytes()
@@ -10,15 +10,19 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // DecompressV6 decompresses a message using bit-packed booleans.
-func DecompressV6(r io.Reader, msg proto.Message) error {
+func DecompressV6(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := decompressHeader(r, WireV6, opts); err != nil {
+		return err
+	}
 	mcb := NewContextualModelBuilder()
-	dec, err := arithcode.NewDecoder(r)
+	dec, err := coder.NewDecoder(r)
 	if err != nil {
 		return err
 	}
@@ -31,7 +35,7 @@ func DecompressV6(r io.Reader, msg proto.Message) error {
 }
 
 // decompressMessageV6 recursively decompresses with bit-packed booleans.
-func decompressMessageV6(fieldPath string, msg protoreflect.Message, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressMessageV6(fieldPath string, msg protoreflect.Message, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -90,7 +94,7 @@ func decompressMessageV6(fieldPath string, msg protoreflect.Message, dec *arithc
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
 			nestedMsg := msg.Mutable(fd).Message()
-			if err := decompressMessageV6(currentPath, nestedMsg, dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV6(currentPath, nestedMsg, dec, mcb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -118,7 +122,7 @@ func decompressMessageV6(fieldPath string, msg protoreflect.Message, dec *arithc
 }
 
 // decodeBooleanCluster unpacks multiple boolean fields from compact representation.
-func decodeBooleanCluster(cluster BooleanCluster, msg protoreflect.Message, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decodeBooleanCluster(cluster BooleanCluster, msg protoreflect.Message, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	// Decode presence bits
 	presenceBitsSymbol, err := dec.Decode(mcb.ByteModel())
 	if err != nil {
@@ -146,7 +150,7 @@ func decodeBooleanCluster(cluster BooleanCluster, msg protoreflect.Message, dec
 }
 
 // decompressRepeatedFieldV6 decompresses repeated fields.
-func decompressRepeatedFieldV6(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressRepeatedFieldV6(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mcb.GetContextualFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -166,12 +170,15 @@ func decompressRepeatedFieldV6(fieldPath string, fd protoreflect.FieldDescriptor
 		}
 	}
 	length := int(pbmodel.DecodeVarint(lengthBytes))
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	elementPath := fieldPath + "[]"
 	for i := 0; i < length; i++ {
 		if fd.Kind() == protoreflect.MessageKind {
 			nestedMsg := list.NewElement().Message()
-			if err := decompressMessageV6(elementPath, nestedMsg, dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV6(elementPath, nestedMsg, dec, mcb) }); err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 			list.Append(protoreflect.ValueOfMessage(nestedMsg))
@@ -188,7 +195,7 @@ func decompressRepeatedFieldV6(fieldPath string, fd protoreflect.FieldDescriptor
 }
 
 // decompressMapFieldV6 decompresses map fields.
-func decompressMapFieldV6(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressMapFieldV6(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mcb.GetContextualFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -208,6 +215,9 @@ func decompressMapFieldV6(fieldPath string, fd protoreflect.FieldDescriptor, m p
 		}
 	}
 	length := int(pbmodel.DecodeVarint(lengthBytes))
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	keyFd := fd.MapKey()
 	valueFd := fd.MapValue()
@@ -225,7 +235,7 @@ func decompressMapFieldV6(fieldPath string, fd protoreflect.FieldDescriptor, m p
 		var mapValue protoreflect.Value
 		if valueFd.Kind() == protoreflect.MessageKind {
 			nestedMsg := m.NewValue().Message()
-			if err := decompressMessageV6(valuePath, nestedMsg, dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV6(valuePath, nestedMsg, dec, mcb) }); err != nil {
 				return fmt.Errorf("map value: %w", err)
 			}
 			mapValue = protoreflect.ValueOfMessage(nestedMsg)
@@ -244,7 +254,7 @@ func decompressMapFieldV6(fieldPath string, fd protoreflect.FieldDescriptor, m p
 }
 
 // decompressFieldValueV6 decompresses a field value (reuses V5 logic).
-func decompressFieldValueV6(fieldPath string, fd protoreflect.FieldDescriptor, dec *arithcode.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
+func decompressFieldValueV6(fieldPath string, fd protoreflect.FieldDescriptor, dec *coder.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
 	// Special handling for Data.payload field
 	if fd.Name() == "payload" && fd.Kind() == protoreflect.BytesKind {
 		// Decode text flag
@@ -267,6 +277,9 @@ func decompressFieldValueV6(fieldPath string, fd protoreflect.FieldDescriptor, d
 				}
 			}
 			compressedLen := int(pbmodel.DecodeVarint(lengthBytes))
+			if err := checkDecodedLength(uint64(compressedLen), pbmodel.DefaultLimits.MaxBytes); err != nil {
+				return protoreflect.Value{}, err
+			}
 
 			compressedBytes := make([]byte, compressedLen)
 			for i := 0; i < compressedLen; i++ {
@@ -277,7 +290,7 @@ func decompressFieldValueV6(fieldPath string, fd protoreflect.FieldDescriptor, d
 				compressedBytes[i] = byte(symbol)
 			}
 
-			str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+			str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 			if err != nil {
 				return protoreflect.Value{}, err
 			}
@@ -473,6 +486,9 @@ func decompressFieldValueV6(fieldPath string, fd protoreflect.FieldDescriptor, d
 			}
 		}
 		compressedLen := int(pbmodel.DecodeVarint(lengthBytes))
+		if err := checkDecodedLength(uint64(compressedLen), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		// Decode compressed bytes
 		compressedBytes := make([]byte, compressedLen)
@@ -485,7 +501,7 @@ func decompressFieldValueV6(fieldPath string, fd protoreflect.FieldDescriptor, d
 		}
 
 		// Decompress string
-		str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+		str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
@@ -505,6 +521,9 @@ func decompressFieldValueV6(fieldPath string, fd protoreflect.FieldDescriptor, d
 			}
 		}
 		length := int(pbmodel.DecodeVarint(lengthBytes))
+		if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		// Decode bytes
 		data := make([]byte, length)
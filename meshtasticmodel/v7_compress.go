@@ -11,16 +11,20 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // CompressV7 uses field-specific boolean models on top of V6's bit packing
 // and V5's context-aware models.
-func CompressV7(msg proto.Message, w io.Writer) error {
+func CompressV7(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := compressHeader(w, WireV7, opts); err != nil {
+		return err
+	}
 	mcb := NewContextualModelBuilder()
-	enc := arithcode.NewEncoder(w)
+	enc := coder.NewEncoder(w)
 
 	// Set initial message type context
 	msgType := string(msg.ProtoReflect().Descriptor().Name())
@@ -34,7 +38,7 @@ func CompressV7(msg proto.Message, w io.Writer) error {
 }
 
 // compressMessageV7 recursively compresses with field-specific boolean models.
-func compressMessageV7(fieldPath string, msg protoreflect.Message, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressMessageV7(fieldPath string, msg protoreflect.Message, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -83,7 +87,7 @@ func compressMessageV7(fieldPath string, msg protoreflect.Message, enc *arithcod
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV7(currentPath, value.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV7(currentPath, value.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -102,7 +106,7 @@ func compressMessageV7(fieldPath string, msg protoreflect.Message, enc *arithcod
 }
 
 // compressRepeatedFieldV7 compresses repeated fields.
-func compressRepeatedFieldV7(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressRepeatedFieldV7(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mcb.GetContextualFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -122,7 +126,7 @@ func compressRepeatedFieldV7(fieldPath string, fd protoreflect.FieldDescriptor,
 		value := list.Get(i)
 
 		if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV7(elemPath, value.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV7(elemPath, value.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("element %d: %w", i, err)
 			}
 		} else {
@@ -136,7 +140,7 @@ func compressRepeatedFieldV7(fieldPath string, fd protoreflect.FieldDescriptor,
 }
 
 // compressMapFieldV7 compresses map fields.
-func compressMapFieldV7(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressMapFieldV7(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mcb.GetContextualFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -155,7 +159,7 @@ func compressMapFieldV7(fieldPath string, fd protoreflect.FieldDescriptor, mapVa
 	valueFd := fd.MapValue()
 
 	var keys []protoreflect.MapKey
-	mapVal.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+	pbmodel.RangeMapSorted(mapVal, func(k protoreflect.MapKey, v protoreflect.Value) bool {
 		keys = append(keys, k)
 		return true
 	})
@@ -170,7 +174,7 @@ func compressMapFieldV7(fieldPath string, fd protoreflect.FieldDescriptor, mapVa
 
 		v := mapVal.Get(k)
 		if valueFd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV7(valuePath, v.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV7(valuePath, v.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("map value %d: %w", i, err)
 			}
 		} else {
@@ -184,7 +188,7 @@ func compressMapFieldV7(fieldPath string, fd protoreflect.FieldDescriptor, mapVa
 }
 
 // compressFieldValueV7 compresses a single field value with field-specific models.
-func compressFieldValueV7(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressFieldValueV7(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	model := mcb.GetContextualFieldModel(fieldPath, fd)
 	if model == nil {
 		model = mcb.GetFieldModel(fieldPath, fd)
@@ -207,7 +211,7 @@ func compressFieldValueV7(fieldPath string, fd protoreflect.FieldDescriptor, val
 
 		if isText && utf8.Valid(data) {
 			var buf bytes.Buffer
-			if err := arithcode.EncodeString(string(data), &buf); err != nil {
+			if err := models.EncodeString(string(data), &buf); err != nil {
 				return err
 			}
 			compressedBytes := buf.Bytes()
@@ -262,14 +266,12 @@ func compressFieldValueV7(fieldPath string, fd protoreflect.FieldDescriptor, val
 			}
 		}
 
-		enumIndex := 0
 		ed := fd.Enum()
-		for i := 0; i < ed.Values().Len(); i++ {
-			if ed.Values().Get(i).Number() == enumValue {
-				enumIndex = i
-				break
-			}
+		enumValueDesc := ed.Values().ByNumber(enumValue)
+		if enumValueDesc == nil {
+			return fmt.Errorf("unknown enum value: %d", enumValue)
 		}
+		enumIndex := enumValueDesc.Index()
 
 		enumModel := mcb.GetEnumModel(fieldPath, ed)
 		return enc.Encode(enumIndex, enumModel)
@@ -380,7 +382,7 @@ func compressFieldValueV7(fieldPath string, fd protoreflect.FieldDescriptor, val
 	case protoreflect.StringKind:
 		str := value.String()
 		var buf bytes.Buffer
-		if err := arithcode.EncodeString(str, &buf); err != nil {
+		if err := models.EncodeString(str, &buf); err != nil {
 			return err
 		}
 		compressedBytes := buf.Bytes()
@@ -10,15 +10,19 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // DecompressV7 decompresses a message using field-specific boolean models.
-func DecompressV7(r io.Reader, msg proto.Message) error {
+func DecompressV7(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := decompressHeader(r, WireV7, opts); err != nil {
+		return err
+	}
 	mcb := NewContextualModelBuilder()
-	dec, err := arithcode.NewDecoder(r)
+	dec, err := coder.NewDecoder(r)
 	if err != nil {
 		return err
 	}
@@ -30,7 +34,7 @@ func DecompressV7(r io.Reader, msg proto.Message) error {
 }
 
 // decompressMessageV7 recursively decompresses a message.
-func decompressMessageV7(fieldPath string, msg protoreflect.Message, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressMessageV7(fieldPath string, msg protoreflect.Message, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -78,7 +82,7 @@ func decompressMessageV7(fieldPath string, msg protoreflect.Message, dec *arithc
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
 			subMsg := msg.Mutable(fd).Message()
-			if err := decompressMessageV7(currentPath, subMsg, dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV7(currentPath, subMsg, dec, mcb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -98,7 +102,7 @@ func decompressMessageV7(fieldPath string, msg protoreflect.Message, dec *arithc
 }
 
 // decompressRepeatedFieldV7 decompresses a repeated field.
-func decompressRepeatedFieldV7(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressRepeatedFieldV7(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mcb.GetContextualFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -117,13 +121,16 @@ func decompressRepeatedFieldV7(fieldPath string, fd protoreflect.FieldDescriptor
 		}
 	}
 	length := int(pbmodel.DecodeVarint(lengthBytes))
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	for i := 0; i < length; i++ {
 		elemPath := fmt.Sprintf("%s[%d]", fieldPath, i)
 
 		if fd.Kind() == protoreflect.MessageKind {
 			elem := list.NewElement()
-			if err := decompressMessageV7(elemPath, elem.Message(), dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV7(elemPath, elem.Message(), dec, mcb) }); err != nil {
 				return fmt.Errorf("element %d: %w", i, err)
 			}
 			list.Append(elem)
@@ -140,7 +147,7 @@ func decompressRepeatedFieldV7(fieldPath string, fd protoreflect.FieldDescriptor
 }
 
 // decompressMapFieldV7 decompresses a map field.
-func decompressMapFieldV7(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressMapFieldV7(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	lengthPath := fieldPath + "._length"
 	lengthModel := mcb.GetContextualFieldModel(lengthPath, fd)
 	if lengthModel == nil {
@@ -159,6 +166,9 @@ func decompressMapFieldV7(fieldPath string, fd protoreflect.FieldDescriptor, map
 		}
 	}
 	length := int(pbmodel.DecodeVarint(lengthBytes))
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	keyFd := fd.MapKey()
 	valueFd := fd.MapValue()
@@ -175,7 +185,7 @@ func decompressMapFieldV7(fieldPath string, fd protoreflect.FieldDescriptor, map
 		var value protoreflect.Value
 		if valueFd.Kind() == protoreflect.MessageKind {
 			valueMsg := mapVal.NewValue()
-			if err := decompressMessageV7(valuePath, valueMsg.Message(), dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV7(valuePath, valueMsg.Message(), dec, mcb) }); err != nil {
 				return fmt.Errorf("map value %d: %w", i, err)
 			}
 			value = valueMsg
@@ -194,7 +204,7 @@ func decompressMapFieldV7(fieldPath string, fd protoreflect.FieldDescriptor, map
 }
 
 // decompressFieldValueV7 decompresses a single field value.
-func decompressFieldValueV7(fieldPath string, fd protoreflect.FieldDescriptor, dec *arithcode.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
+func decompressFieldValueV7(fieldPath string, fd protoreflect.FieldDescriptor, dec *coder.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
 	model := mcb.GetContextualFieldModel(fieldPath, fd)
 	if model == nil {
 		model = mcb.GetFieldModel(fieldPath, fd)
@@ -222,6 +232,9 @@ func decompressFieldValueV7(fieldPath string, fd protoreflect.FieldDescriptor, d
 			}
 		}
 		length := int(pbmodel.DecodeVarint(lengthBytes))
+		if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		if textFlag == 1 {
 			compressedBytes := make([]byte, length)
@@ -233,7 +246,7 @@ func decompressFieldValueV7(fieldPath string, fd protoreflect.FieldDescriptor, d
 				compressedBytes[i] = byte(symbol)
 			}
 
-			str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+			str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 			if err != nil {
 				return protoreflect.Value{}, err
 			}
@@ -423,6 +436,9 @@ func decompressFieldValueV7(fieldPath string, fd protoreflect.FieldDescriptor, d
 			}
 		}
 		compressedLength := int(pbmodel.DecodeVarint(lengthBytes))
+		if err := checkDecodedLength(uint64(compressedLength), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		compressedBytes := make([]byte, compressedLength)
 		for i := 0; i < compressedLength; i++ {
@@ -433,7 +449,7 @@ func decompressFieldValueV7(fieldPath string, fd protoreflect.FieldDescriptor, d
 			compressedBytes[i] = byte(symbol)
 		}
 
-		str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+		str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
@@ -452,6 +468,9 @@ func decompressFieldValueV7(fieldPath string, fd protoreflect.FieldDescriptor, d
 			}
 		}
 		length := int(pbmodel.DecodeVarint(lengthBytes))
+		if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		data := make([]byte, length)
 		for i := 0; i < length; i++ {
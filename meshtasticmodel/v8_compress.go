@@ -11,15 +11,19 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // CompressV8 uses varint byte models on top of V7's field-specific boolean models.
-func CompressV8(msg proto.Message, w io.Writer) error {
+func CompressV8(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := compressHeader(w, WireV8, opts); err != nil {
+		return err
+	}
 	mcb := NewContextualModelBuilder()
-	enc := arithcode.NewEncoder(w)
+	enc := coder.NewEncoder(w)
 
 	// Set initial message type context
 	msgType := string(msg.ProtoReflect().Descriptor().Name())
@@ -33,7 +37,7 @@ func CompressV8(msg proto.Message, w io.Writer) error {
 }
 
 // encodeVarintWithModels encodes a varint using position-specific byte models.
-func encodeVarintWithModels(value uint64, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func encodeVarintWithModels(value uint64, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	varintBytes := pbmodel.EncodeVarint(value)
 	for i, b := range varintBytes {
 		model := mcb.GetVarintByteModel(i)
@@ -45,7 +49,7 @@ func encodeVarintWithModels(value uint64, enc *arithcode.Encoder, mcb *Contextua
 }
 
 // compressMessageV8 recursively compresses with field-specific boolean models.
-func compressMessageV8(fieldPath string, msg protoreflect.Message, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressMessageV8(fieldPath string, msg protoreflect.Message, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -94,7 +98,7 @@ func compressMessageV8(fieldPath string, msg protoreflect.Message, enc *arithcod
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV8(currentPath, value.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV8(currentPath, value.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -113,7 +117,7 @@ func compressMessageV8(fieldPath string, msg protoreflect.Message, enc *arithcod
 }
 
 // compressRepeatedFieldV8 compresses repeated fields.
-func compressRepeatedFieldV8(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressRepeatedFieldV8(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	length := list.Len()
 	if err := encodeVarintWithModels(uint64(length), enc, mcb); err != nil {
 		return fmt.Errorf("length: %w", err)
@@ -124,7 +128,7 @@ func compressRepeatedFieldV8(fieldPath string, fd protoreflect.FieldDescriptor,
 		value := list.Get(i)
 
 		if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV8(elemPath, value.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV8(elemPath, value.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("element %d: %w", i, err)
 			}
 		} else {
@@ -138,7 +142,7 @@ func compressRepeatedFieldV8(fieldPath string, fd protoreflect.FieldDescriptor,
 }
 
 // compressMapFieldV8 compresses map fields.
-func compressMapFieldV8(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressMapFieldV8(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	length := mapVal.Len()
 	if err := encodeVarintWithModels(uint64(length), enc, mcb); err != nil {
 		return fmt.Errorf("map length: %w", err)
@@ -148,7 +152,7 @@ func compressMapFieldV8(fieldPath string, fd protoreflect.FieldDescriptor, mapVa
 	valueFd := fd.MapValue()
 
 	var keys []protoreflect.MapKey
-	mapVal.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+	pbmodel.RangeMapSorted(mapVal, func(k protoreflect.MapKey, v protoreflect.Value) bool {
 		keys = append(keys, k)
 		return true
 	})
@@ -163,7 +167,7 @@ func compressMapFieldV8(fieldPath string, fd protoreflect.FieldDescriptor, mapVa
 
 		v := mapVal.Get(k)
 		if valueFd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV8(valuePath, v.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV8(valuePath, v.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("map value %d: %w", i, err)
 			}
 		} else {
@@ -177,7 +181,7 @@ func compressMapFieldV8(fieldPath string, fd protoreflect.FieldDescriptor, mapVa
 }
 
 // compressFieldValueV8 compresses a single field value with field-specific models.
-func compressFieldValueV8(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressFieldValueV8(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	model := mcb.GetContextualFieldModel(fieldPath, fd)
 	if model == nil {
 		model = mcb.GetFieldModel(fieldPath, fd)
@@ -200,7 +204,7 @@ func compressFieldValueV8(fieldPath string, fd protoreflect.FieldDescriptor, val
 
 		if isText && utf8.Valid(data) {
 			var buf bytes.Buffer
-			if err := arithcode.EncodeString(string(data), &buf); err != nil {
+			if err := models.EncodeString(string(data), &buf); err != nil {
 				return err
 			}
 			compressedBytes := buf.Bytes()
@@ -249,14 +253,12 @@ func compressFieldValueV8(fieldPath string, fd protoreflect.FieldDescriptor, val
 			}
 		}
 
-		enumIndex := 0
 		ed := fd.Enum()
-		for i := 0; i < ed.Values().Len(); i++ {
-			if ed.Values().Get(i).Number() == enumValue {
-				enumIndex = i
-				break
-			}
+		enumValueDesc := ed.Values().ByNumber(enumValue)
+		if enumValueDesc == nil {
+			return fmt.Errorf("unknown enum value: %d", enumValue)
 		}
+		enumIndex := enumValueDesc.Index()
 
 		enumModel := mcb.GetEnumModel(fieldPath, ed)
 		return enc.Encode(enumIndex, enumModel)
@@ -355,7 +357,7 @@ func compressFieldValueV8(fieldPath string, fd protoreflect.FieldDescriptor, val
 	case protoreflect.StringKind:
 		str := value.String()
 		var buf bytes.Buffer
-		if err := arithcode.EncodeString(str, &buf); err != nil {
+		if err := models.EncodeString(str, &buf); err != nil {
 			return err
 		}
 		compressedBytes := buf.Bytes()
@@ -10,15 +10,19 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // DecompressV8 decompresses a message using varint byte models.
-func DecompressV8(r io.Reader, msg proto.Message) error {
+func DecompressV8(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := decompressHeader(r, WireV8, opts); err != nil {
+		return err
+	}
 	mcb := NewContextualModelBuilder()
-	dec, err := arithcode.NewDecoder(r)
+	dec, err := coder.NewDecoder(r)
 	if err != nil {
 		return err
 	}
@@ -30,7 +34,7 @@ func DecompressV8(r io.Reader, msg proto.Message) error {
 }
 
 // decodeVarintWithModels decodes a varint using position-specific byte models.
-func decodeVarintWithModels(dec *arithcode.Decoder, mcb *ContextualModelBuilder) (uint64, error) {
+func decodeVarintWithModels(dec *coder.Decoder, mcb *ContextualModelBuilder) (uint64, error) {
 	var varintBytes []byte
 	byteIndex := 0
 	for {
@@ -49,7 +53,7 @@ func decodeVarintWithModels(dec *arithcode.Decoder, mcb *ContextualModelBuilder)
 }
 
 // decompressMessageV8 recursively decompresses a message.
-func decompressMessageV8(fieldPath string, msg protoreflect.Message, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressMessageV8(fieldPath string, msg protoreflect.Message, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -97,7 +101,7 @@ func decompressMessageV8(fieldPath string, msg protoreflect.Message, dec *arithc
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
 			subMsg := msg.Mutable(fd).Message()
-			if err := decompressMessageV8(currentPath, subMsg, dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV8(currentPath, subMsg, dec, mcb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -117,19 +121,22 @@ func decompressMessageV8(fieldPath string, msg protoreflect.Message, dec *arithc
 }
 
 // decompressRepeatedFieldV8 decompresses a repeated field.
-func decompressRepeatedFieldV8(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressRepeatedFieldV8(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	lengthVal, err := decodeVarintWithModels(dec, mcb)
 	if err != nil {
 		return fmt.Errorf("length: %w", err)
 	}
 	length := int(lengthVal)
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	for i := 0; i < length; i++ {
 		elemPath := fmt.Sprintf("%s[%d]", fieldPath, i)
 
 		if fd.Kind() == protoreflect.MessageKind {
 			elem := list.NewElement()
-			if err := decompressMessageV8(elemPath, elem.Message(), dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV8(elemPath, elem.Message(), dec, mcb) }); err != nil {
 				return fmt.Errorf("element %d: %w", i, err)
 			}
 			list.Append(elem)
@@ -146,12 +153,15 @@ func decompressRepeatedFieldV8(fieldPath string, fd protoreflect.FieldDescriptor
 }
 
 // decompressMapFieldV8 decompresses a map field.
-func decompressMapFieldV8(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressMapFieldV8(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	lengthVal, err := decodeVarintWithModels(dec, mcb)
 	if err != nil {
 		return fmt.Errorf("map length: %w", err)
 	}
 	length := int(lengthVal)
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	keyFd := fd.MapKey()
 	valueFd := fd.MapValue()
@@ -168,7 +178,7 @@ func decompressMapFieldV8(fieldPath string, fd protoreflect.FieldDescriptor, map
 		var value protoreflect.Value
 		if valueFd.Kind() == protoreflect.MessageKind {
 			valueMsg := mapVal.NewValue()
-			if err := decompressMessageV8(valuePath, valueMsg.Message(), dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV8(valuePath, valueMsg.Message(), dec, mcb) }); err != nil {
 				return fmt.Errorf("map value %d: %w", i, err)
 			}
 			value = valueMsg
@@ -187,7 +197,7 @@ func decompressMapFieldV8(fieldPath string, fd protoreflect.FieldDescriptor, map
 }
 
 // decompressFieldValueV8 decompresses a single field value.
-func decompressFieldValueV8(fieldPath string, fd protoreflect.FieldDescriptor, dec *arithcode.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
+func decompressFieldValueV8(fieldPath string, fd protoreflect.FieldDescriptor, dec *coder.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
 	model := mcb.GetContextualFieldModel(fieldPath, fd)
 	if model == nil {
 		model = mcb.GetFieldModel(fieldPath, fd)
@@ -208,6 +218,9 @@ func decompressFieldValueV8(fieldPath string, fd protoreflect.FieldDescriptor, d
 			return protoreflect.Value{}, err
 		}
 		length := int(lengthVal)
+		if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		if textFlag == 1 {
 			compressedBytes := make([]byte, length)
@@ -219,7 +232,7 @@ func decompressFieldValueV8(fieldPath string, fd protoreflect.FieldDescriptor, d
 				compressedBytes[i] = byte(symbol)
 			}
 
-			str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+			str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 			if err != nil {
 				return protoreflect.Value{}, err
 			}
@@ -386,6 +399,9 @@ func decompressFieldValueV8(fieldPath string, fd protoreflect.FieldDescriptor, d
 			return protoreflect.Value{}, err
 		}
 		compressedLength := int(compressedLengthVal)
+		if err := checkDecodedLength(uint64(compressedLength), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		compressedBytes := make([]byte, compressedLength)
 		for i := 0; i < compressedLength; i++ {
@@ -396,7 +412,7 @@ func decompressFieldValueV8(fieldPath string, fd protoreflect.FieldDescriptor, d
 			compressedBytes[i] = byte(symbol)
 		}
 
-		str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+		str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
@@ -408,6 +424,9 @@ func decompressFieldValueV8(fieldPath string, fd protoreflect.FieldDescriptor, d
 			return protoreflect.Value{}, err
 		}
 		length := int(lengthVal)
+		if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		data := make([]byte, length)
 		for i := 0; i < length; i++ {
@@ -11,15 +11,19 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // CompressV9 uses order-1 string compression on top of V8's varint byte models.
-func CompressV9(msg proto.Message, w io.Writer) error {
+func CompressV9(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := compressHeader(w, WireV9, opts); err != nil {
+		return err
+	}
 	mcb := NewContextualModelBuilder()
-	enc := arithcode.NewEncoder(w)
+	enc := coder.NewEncoder(w)
 
 	// Set initial message type context
 	msgType := string(msg.ProtoReflect().Descriptor().Name())
@@ -33,7 +37,7 @@ func CompressV9(msg proto.Message, w io.Writer) error {
 }
 
 // compressMessageV9 recursively compresses with field-specific boolean models.
-func compressMessageV9(fieldPath string, msg protoreflect.Message, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressMessageV9(fieldPath string, msg protoreflect.Message, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -82,7 +86,7 @@ func compressMessageV9(fieldPath string, msg protoreflect.Message, enc *arithcod
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV9(currentPath, value.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV9(currentPath, value.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -101,7 +105,7 @@ func compressMessageV9(fieldPath string, msg protoreflect.Message, enc *arithcod
 }
 
 // compressRepeatedFieldV9 compresses repeated fields.
-func compressRepeatedFieldV9(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressRepeatedFieldV9(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	length := list.Len()
 	if err := encodeVarintWithModels(uint64(length), enc, mcb); err != nil {
 		return fmt.Errorf("length: %w", err)
@@ -112,7 +116,7 @@ func compressRepeatedFieldV9(fieldPath string, fd protoreflect.FieldDescriptor,
 		value := list.Get(i)
 
 		if fd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV9(elemPath, value.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV9(elemPath, value.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("element %d: %w", i, err)
 			}
 		} else {
@@ -126,7 +130,7 @@ func compressRepeatedFieldV9(fieldPath string, fd protoreflect.FieldDescriptor,
 }
 
 // compressMapFieldV9 compresses map fields.
-func compressMapFieldV9(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressMapFieldV9(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	length := mapVal.Len()
 	if err := encodeVarintWithModels(uint64(length), enc, mcb); err != nil {
 		return fmt.Errorf("map length: %w", err)
@@ -136,7 +140,7 @@ func compressMapFieldV9(fieldPath string, fd protoreflect.FieldDescriptor, mapVa
 	valueFd := fd.MapValue()
 
 	var keys []protoreflect.MapKey
-	mapVal.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+	pbmodel.RangeMapSorted(mapVal, func(k protoreflect.MapKey, v protoreflect.Value) bool {
 		keys = append(keys, k)
 		return true
 	})
@@ -151,7 +155,7 @@ func compressMapFieldV9(fieldPath string, fd protoreflect.FieldDescriptor, mapVa
 
 		v := mapVal.Get(k)
 		if valueFd.Kind() == protoreflect.MessageKind {
-			if err := compressMessageV9(valuePath, v.Message(), enc, mcb); err != nil {
+			if err := mcb.Nest(func() error { return compressMessageV9(valuePath, v.Message(), enc, mcb) }); err != nil {
 				return fmt.Errorf("map value %d: %w", i, err)
 			}
 		} else {
@@ -165,7 +169,7 @@ func compressMapFieldV9(fieldPath string, fd protoreflect.FieldDescriptor, mapVa
 }
 
 // compressFieldValueV9 compresses a single field value with field-specific models.
-func compressFieldValueV9(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *arithcode.Encoder, mcb *ContextualModelBuilder) error {
+func compressFieldValueV9(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *coder.Encoder, mcb *ContextualModelBuilder) error {
 	model := mcb.GetContextualFieldModel(fieldPath, fd)
 	if model == nil {
 		model = mcb.GetFieldModel(fieldPath, fd)
@@ -188,7 +192,7 @@ func compressFieldValueV9(fieldPath string, fd protoreflect.FieldDescriptor, val
 
 		if isText && utf8.Valid(data) {
 			var buf bytes.Buffer
-			if err := arithcode.EncodeString(string(data), &buf); err != nil {
+			if err := models.EncodeString(string(data), &buf); err != nil {
 				return err
 			}
 			compressedBytes := buf.Bytes()
@@ -237,14 +241,12 @@ func compressFieldValueV9(fieldPath string, fd protoreflect.FieldDescriptor, val
 			}
 		}
 
-		enumIndex := 0
 		ed := fd.Enum()
-		for i := 0; i < ed.Values().Len(); i++ {
-			if ed.Values().Get(i).Number() == enumValue {
-				enumIndex = i
-				break
-			}
+		enumValueDesc := ed.Values().ByNumber(enumValue)
+		if enumValueDesc == nil {
+			return fmt.Errorf("unknown enum value: %d", enumValue)
 		}
+		enumIndex := enumValueDesc.Index()
 
 		enumModel := mcb.GetEnumModel(fieldPath, ed)
 		return enc.Encode(enumIndex, enumModel)
@@ -344,7 +346,7 @@ func compressFieldValueV9(fieldPath string, fd protoreflect.FieldDescriptor, val
 		str := value.String()
 		var buf bytes.Buffer
 		// Use order-1 model for better string compression
-		if err := arithcode.EncodeStringOrder1(str, &buf); err != nil {
+		if err := models.EncodeStringOrder1(str, &buf); err != nil {
 			return err
 		}
 		compressedBytes := buf.Bytes()
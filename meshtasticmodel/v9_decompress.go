@@ -10,15 +10,19 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 	"github.com/egonelbre/exp-protobuf-compression/meshtastic"
 	"github.com/egonelbre/exp-protobuf-compression/pbmodel"
 )
 
 // DecompressV9 decompresses a message using order-1 string compression.
-func DecompressV9(r io.Reader, msg proto.Message) error {
+func DecompressV9(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := decompressHeader(r, WireV9, opts); err != nil {
+		return err
+	}
 	mcb := NewContextualModelBuilder()
-	dec, err := arithcode.NewDecoder(r)
+	dec, err := coder.NewDecoder(r)
 	if err != nil {
 		return err
 	}
@@ -30,7 +34,7 @@ func DecompressV9(r io.Reader, msg proto.Message) error {
 }
 
 // decompressMessageV9 recursively decompresses a message.
-func decompressMessageV9(fieldPath string, msg protoreflect.Message, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressMessageV9(fieldPath string, msg protoreflect.Message, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -78,7 +82,7 @@ func decompressMessageV9(fieldPath string, msg protoreflect.Message, dec *arithc
 			}
 		} else if fd.Kind() == protoreflect.MessageKind {
 			subMsg := msg.Mutable(fd).Message()
-			if err := decompressMessageV9(currentPath, subMsg, dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV9(currentPath, subMsg, dec, mcb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -98,19 +102,22 @@ func decompressMessageV9(fieldPath string, msg protoreflect.Message, dec *arithc
 }
 
 // decompressRepeatedFieldV9 decompresses a repeated field.
-func decompressRepeatedFieldV9(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressRepeatedFieldV9(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	lengthVal, err := decodeVarintWithModels(dec, mcb)
 	if err != nil {
 		return fmt.Errorf("length: %w", err)
 	}
 	length := int(lengthVal)
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	for i := 0; i < length; i++ {
 		elemPath := fmt.Sprintf("%s[%d]", fieldPath, i)
 
 		if fd.Kind() == protoreflect.MessageKind {
 			elem := list.NewElement()
-			if err := decompressMessageV9(elemPath, elem.Message(), dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV9(elemPath, elem.Message(), dec, mcb) }); err != nil {
 				return fmt.Errorf("element %d: %w", i, err)
 			}
 			list.Append(elem)
@@ -127,12 +134,15 @@ func decompressRepeatedFieldV9(fieldPath string, fd protoreflect.FieldDescriptor
 }
 
 // decompressMapFieldV9 decompresses a map field.
-func decompressMapFieldV9(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, dec *arithcode.Decoder, mcb *ContextualModelBuilder) error {
+func decompressMapFieldV9(fieldPath string, fd protoreflect.FieldDescriptor, mapVal protoreflect.Map, dec *coder.Decoder, mcb *ContextualModelBuilder) error {
 	lengthVal, err := decodeVarintWithModels(dec, mcb)
 	if err != nil {
 		return fmt.Errorf("map length: %w", err)
 	}
 	length := int(lengthVal)
+	if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxListLen); err != nil {
+		return err
+	}
 
 	keyFd := fd.MapKey()
 	valueFd := fd.MapValue()
@@ -149,7 +159,7 @@ func decompressMapFieldV9(fieldPath string, fd protoreflect.FieldDescriptor, map
 		var value protoreflect.Value
 		if valueFd.Kind() == protoreflect.MessageKind {
 			valueMsg := mapVal.NewValue()
-			if err := decompressMessageV9(valuePath, valueMsg.Message(), dec, mcb); err != nil {
+			if err := mcb.Nest(func() error { return decompressMessageV9(valuePath, valueMsg.Message(), dec, mcb) }); err != nil {
 				return fmt.Errorf("map value %d: %w", i, err)
 			}
 			value = valueMsg
@@ -168,7 +178,7 @@ func decompressMapFieldV9(fieldPath string, fd protoreflect.FieldDescriptor, map
 }
 
 // decompressFieldValueV9 decompresses a single field value.
-func decompressFieldValueV9(fieldPath string, fd protoreflect.FieldDescriptor, dec *arithcode.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
+func decompressFieldValueV9(fieldPath string, fd protoreflect.FieldDescriptor, dec *coder.Decoder, mcb *ContextualModelBuilder) (protoreflect.Value, error) {
 	model := mcb.GetContextualFieldModel(fieldPath, fd)
 	if model == nil {
 		model = mcb.GetFieldModel(fieldPath, fd)
@@ -189,6 +199,9 @@ func decompressFieldValueV9(fieldPath string, fd protoreflect.FieldDescriptor, d
 			return protoreflect.Value{}, err
 		}
 		length := int(lengthVal)
+		if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		if textFlag == 1 {
 			compressedBytes := make([]byte, length)
@@ -200,7 +213,7 @@ func decompressFieldValueV9(fieldPath string, fd protoreflect.FieldDescriptor, d
 				compressedBytes[i] = byte(symbol)
 			}
 
-			str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+			str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 			if err != nil {
 				return protoreflect.Value{}, err
 			}
@@ -367,6 +380,9 @@ func decompressFieldValueV9(fieldPath string, fd protoreflect.FieldDescriptor, d
 			return protoreflect.Value{}, err
 		}
 		compressedLength := int(compressedLengthVal)
+		if err := checkDecodedLength(uint64(compressedLength), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		compressedBytes := make([]byte, compressedLength)
 		for i := 0; i < compressedLength; i++ {
@@ -378,7 +394,7 @@ func decompressFieldValueV9(fieldPath string, fd protoreflect.FieldDescriptor, d
 		}
 
 		// Use order-1 model for better string decompression
-		str, err := arithcode.DecodeStringOrder1(bytes.NewReader(compressedBytes))
+		str, err := models.DecodeStringOrder1(bytes.NewReader(compressedBytes))
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
@@ -390,6 +406,9 @@ func decompressFieldValueV9(fieldPath string, fd protoreflect.FieldDescriptor, d
 			return protoreflect.Value{}, err
 		}
 		length := int(lengthVal)
+		if err := checkDecodedLength(uint64(length), pbmodel.DefaultLimits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		data := make([]byte, length)
 		for i := 0; i < length; i++ {
@@ -11,124 +11,222 @@ import (
 // Version represents a compression/decompression implementation version
 type Version struct {
 	Name        string
-	Short       string // Short description for compact display
-	Description string // Full description
-	Compress    func(proto.Message, io.Writer) error
-	Decompress  func(io.Reader, proto.Message) error
+	ID          WireID       // Stable identifier of the format
+	Short       string       // Short description for compact display
+	Description string       // Full description
+	Features    Capabilities // Supported features, for selecting versions programmatically
+
+	// The coding of the version, after the format header unless the options
+	// leave it out
+	compress   func(proto.Message, io.Writer, ...Option) error
+	decompress func(io.Reader, proto.Message, ...Option) error
 }
 
 // Versions is a table of all compression implementations
 var Versions = []Version{
 	{
 		Name:        "pbmodel",
+		ID:          WirePbmodel,
 		Short:       "baseline",
 		Description: "Generic protobuf compression baseline (order-0 strings)",
-		Compress:    pbmodel.Compress,
-		Decompress:  pbmodel.Decompress,
+		Features:    Stateless | EmbeddedFriendly,
+		compress:    pbmodelCompress(WirePbmodel),
+		decompress:  pbmodelDecompress(WirePbmodel),
 	},
 	{
 		Name:        "pbmodel-o1",
+		ID:          WirePbmodelOrder1,
 		Short:       "baseline+order-1",
 		Description: "Generic protobuf compression with order-1 string compression",
-		Compress:    pbmodel.CompressOrder1,
-		Decompress:  pbmodel.DecompressOrder1,
+		Features:    Stateless | EmbeddedFriendly,
+		compress:    pbmodelCompress(WirePbmodelOrder1, pbmodel.WithStringOrder(1)),
+		decompress:  pbmodelDecompress(WirePbmodelOrder1, pbmodel.WithStringOrder(1)),
 	},
 	{
 		Name:        "pbmodel-o2",
+		ID:          WirePbmodelOrder2,
 		Short:       "baseline+order-2",
 		Description: "Generic protobuf compression with order-2 string compression",
-		Compress:    pbmodel.CompressOrder2,
-		Decompress:  pbmodel.DecompressOrder2,
+		Features:    Stateless,
+		compress:    pbmodelCompress(WirePbmodelOrder2, pbmodel.WithStringOrder(2)),
+		decompress:  pbmodelDecompress(WirePbmodelOrder2, pbmodel.WithStringOrder(2)),
 	},
 	{
 		Name:        "pbmodel-varint",
+		ID:          WirePbmodelVarint,
 		Short:       "baseline+varint models",
 		Description: "Generic protobuf compression with position-specific varint byte models",
-		Compress:    pbmodel.CompressVarintModels,
-		Decompress:  pbmodel.DecompressVarintModels,
+		Features:    Stateless | EmbeddedFriendly,
+		compress:    pbmodelCompress(WirePbmodelVarint, pbmodel.WithVarintByteModels()),
+		decompress:  pbmodelDecompress(WirePbmodelVarint, pbmodel.WithVarintByteModels()),
 	},
 	{
 		Name:        "pbmodel-varint-o1",
+		ID:          WirePbmodelVarintOrder1,
 		Short:       "varint+order-1",
 		Description: "Varint byte models combined with order-1 string compression",
-		Compress:    pbmodel.CompressVarintModelsOrder1,
-		Decompress:  pbmodel.DecompressVarintModelsOrder1,
+		Features:    Stateless | EmbeddedFriendly,
+		compress:    pbmodelCompress(WirePbmodelVarintOrder1, pbmodel.WithVarintByteModels(), pbmodel.WithStringOrder(1)),
+		decompress:  pbmodelDecompress(WirePbmodelVarintOrder1, pbmodel.WithVarintByteModels(), pbmodel.WithStringOrder(1)),
 	},
 	{
 		Name:        "pbmodel-varint-o2",
+		ID:          WirePbmodelVarintOrder2,
 		Short:       "varint+order-2",
 		Description: "Varint byte models combined with order-2 string compression",
-		Compress:    pbmodel.CompressVarintModelsOrder2,
-		Decompress:  pbmodel.DecompressVarintModelsOrder2,
+		Features:    Stateless,
+		compress:    pbmodelCompress(WirePbmodelVarintOrder2, pbmodel.WithVarintByteModels(), pbmodel.WithStringOrder(2)),
+		decompress:  pbmodelDecompress(WirePbmodelVarintOrder2, pbmodel.WithVarintByteModels(), pbmodel.WithStringOrder(2)),
 	},
 	{
 		Name:        "V1",
+		ID:          WireV1,
 		Short:       "presence bits",
 		Description: "Meshtastic-specific optimizations: text payload detection, coordinate delta encoding, optimized field models",
-		Compress:    CompressV1,
-		Decompress:  DecompressV1,
+		Features:    Stateless | EmbeddedFriendly,
+		compress:    CompressV1,
+		decompress:  DecompressV1,
 	},
 	{
 		Name:        "V2",
+		ID:          WireV2,
 		Short:       "delta fields",
 		Description: "Delta-encoded field numbers for sparse messages (no presence bits)",
-		Compress:    CompressV2,
-		Decompress:  DecompressV2,
+		Features:    Stateless | EmbeddedFriendly,
+		compress:    CompressV2,
+		decompress:  DecompressV2,
 	},
 	{
 		Name:        "V3",
+		ID:          WireV3,
 		Short:       "hybrid",
 		Description: "Hybrid encoding: auto-selects between presence-bit and delta-encoded field numbers",
-		Compress:    CompressV3,
-		Decompress:  DecompressV3,
+		Features:    Stateless | EmbeddedFriendly,
+		compress:    CompressV3,
+		decompress:  DecompressV3,
 	},
 	{
 		Name:        "V4",
+		ID:          WireV4,
 		Short:       "enum prediction",
 		Description: "V1 + enum value prediction (common enums encoded with 1 bit)",
-		Compress:    CompressV4,
-		Decompress:  DecompressV4,
+		Features:    Stateless | EmbeddedFriendly,
+		compress:    CompressV4,
+		decompress:  DecompressV4,
 	},
 	{
 		Name:        "V5",
+		ID:          WireV5,
 		Short:       "context-aware",
 		Description: "Context-aware models optimized for specific field types and value ranges",
-		Compress:    CompressV5,
-		Decompress:  DecompressV5,
+		Features:    Stateless,
+		compress:    CompressV5,
+		decompress:  DecompressV5,
 	},
 	{
 		Name:        "V6",
+		ID:          WireV6,
 		Short:       "bit-packed bools",
 		Description: "V5 + bit packing for boolean clusters",
-		Compress:    CompressV6,
-		Decompress:  DecompressV6,
+		Features:    Stateless,
+		compress:    CompressV6,
+		decompress:  DecompressV6,
 	},
 	{
 		Name:        "V7",
+		ID:          WireV7,
 		Short:       "boolean models",
 		Description: "V6 + field-specific boolean models",
-		Compress:    CompressV7,
-		Decompress:  DecompressV7,
+		Features:    Stateless,
+		compress:    CompressV7,
+		decompress:  DecompressV7,
 	},
 	{
 		Name:        "V8",
+		ID:          WireV8,
 		Short:       "varint models",
 		Description: "V7 + varint byte models",
-		Compress:    CompressV8,
-		Decompress:  DecompressV8,
+		Features:    Stateless,
+		compress:    CompressV8,
+		decompress:  DecompressV8,
 	},
 	{
 		Name:        "V9",
+		ID:          WireV9,
 		Short:       "order-1 strings",
 		Description: "V8 + order-1 English string compression",
-		Compress:    CompressV9,
-		Decompress:  DecompressV9,
+		Features:    Stateless,
+		compress:    CompressV9,
+		decompress:  DecompressV9,
 	},
 	{
 		Name:        "V10",
+		ID:          WireV10,
 		Short:       "order-2 strings",
 		Description: "V8 + order-2 English string compression",
-		Compress:    CompressV10,
-		Decompress:  DecompressV10,
+		Features:    Stateless,
+		compress:    CompressV10,
+		decompress:  DecompressV10,
+	},
+	{
+		Name:        "V11",
+		ID:          WireV11,
+		Short:       "PPM strings",
+		Description: "V10 + adaptive order-3 PPM text coding for strings and text payloads, and schema maximum length bounds",
+		Features:    Stateless,
+		compress:    CompressV11,
+		decompress:  DecompressV11,
+	},
+	{
+		Name:        "V12",
+		ID:          WireV12,
+		Short:       "emoji",
+		Description: "V11 + compact emoji indices in text and emoji codepoint fields",
+		Features:    Stateless,
+		compress:    CompressV12,
+		decompress:  DecompressV12,
+	},
+	{
+		Name:        "V13",
+		ID:          WireV13,
+		Short:       "escaping models",
+		Description: "V12 + static field models that switch to adaptive ones after repeated mispredictions",
+		Features:    Stateless,
+		compress:    CompressV13,
+		decompress:  DecompressV13,
+	},
+	{
+		Name:        "V14",
+		ID:          WireV14,
+		Short:       "device metadata",
+		Description: "V13 + structured firmware version strings, static hardware model and role tables, channel key classes, air quality models, host byte count exponents, escapes for undeclared enum numbers, and unknown fields",
+		Features:    Stateless,
+		compress:    CompressV14,
+		decompress:  DecompressV14,
 	},
 }
+
+// pbmodelCompress returns the coding of pbmodel.Compress with opts for the
+// version id. The header of the version identifies the options, so the one
+// of pbmodel is left out.
+func pbmodelCompress(id WireID, opts ...pbmodel.Option) func(proto.Message, io.Writer, ...Option) error {
+	opts = append(opts, pbmodel.WithoutHeader())
+	return func(msg proto.Message, w io.Writer, headerOpts ...Option) error {
+		if err := compressHeader(w, id, headerOpts); err != nil {
+			return err
+		}
+		return pbmodel.Compress(msg, w, opts...)
+	}
+}
+
+// pbmodelDecompress returns the decoding of data written by the coding of
+// pbmodelCompress with id and opts.
+func pbmodelDecompress(id WireID, opts ...pbmodel.Option) func(io.Reader, proto.Message, ...Option) error {
+	opts = append(opts, pbmodel.WithoutHeader())
+	return func(r io.Reader, msg proto.Message, headerOpts ...Option) error {
+		if err := decompressHeader(r, id, headerOpts); err != nil {
+			return err
+		}
+		return pbmodel.Decompress(r, msg, opts...)
+	}
+}
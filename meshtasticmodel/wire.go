@@ -0,0 +1,44 @@
+package meshtasticmodel
+
+// WireID identifies a compression version in data that records its format,
+// so that a receiver can select the matching decompressor. IDs are stable:
+// a version keeps its ID when versions are added, and IDs are never reused.
+// Zero is not a valid ID.
+//
+// The pbmodel variants use 0x01-0x0F and the numbered versions Vn use
+// 0x10+n.
+type WireID uint8
+
+// Wire IDs of the versions in Versions.
+const (
+	WirePbmodel             WireID = 0x01
+	WirePbmodelOrder1       WireID = 0x02
+	WirePbmodelOrder2       WireID = 0x03
+	WirePbmodelVarint       WireID = 0x04
+	WirePbmodelVarintOrder1 WireID = 0x05
+	WirePbmodelVarintOrder2 WireID = 0x06
+	WireV1                  WireID = 0x11
+	WireV2                  WireID = 0x12
+	WireV3                  WireID = 0x13
+	WireV4                  WireID = 0x14
+	WireV5                  WireID = 0x15
+	WireV6                  WireID = 0x16
+	WireV7                  WireID = 0x17
+	WireV8                  WireID = 0x18
+	WireV9                  WireID = 0x19
+	WireV10                 WireID = 0x1A
+	WireV11                 WireID = 0x1B
+	WireV12                 WireID = 0x1C
+	WireV13                 WireID = 0x1D
+	WireV14                 WireID = 0x1E
+)
+
+// VersionByID returns the version with the given wire ID.
+func VersionByID(id WireID) (Version, bool) {
+	for _, version := range Versions {
+		if version.ID == id {
+			return version, true
+		}
+	}
+	return Version{}, false
+}
@@ -0,0 +1,26 @@
+package meshtasticmodel
+
+import "testing"
+
+func TestVersionWireIDs(t *testing.T) {
+	seen := map[WireID]string{}
+	for _, version := range Versions {
+		if version.ID == 0 {
+			t.Errorf("%s has no wire ID", version.Name)
+			continue
+		}
+		if other, ok := seen[version.ID]; ok {
+			t.Errorf("%s and %s share wire ID %#x", version.Name, other, version.ID)
+		}
+		seen[version.ID] = version.Name
+
+		found, ok := VersionByID(version.ID)
+		if !ok || found.Name != version.Name {
+			t.Errorf("VersionByID(%#x) = %q, %v, want %q", version.ID, found.Name, ok, version.Name)
+		}
+	}
+
+	if _, ok := VersionByID(0); ok {
+		t.Error("VersionByID(0) found a version")
+	}
+}
@@ -1,9 +1,12 @@
 package pbmodel
 
 import (
+	"sync"
+
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 )
 
 // AdaptiveModelBuilder creates field-specific compression models.
@@ -11,50 +14,100 @@ import (
 // a separate model for each field, allowing it to learn field-specific patterns.
 type AdaptiveModelBuilder struct {
 	// Field-specific models indexed by full field path
-	fieldModels map[string]arithcode.Model
+	fieldModels map[string]coder.Model
 
 	// Shared models for common types
-	boolModel    arithcode.Model
-	byteModel    arithcode.Model
-	englishModel *arithcode.EnglishModel
+	boolModel    coder.Model
+	byteModel    coder.Model
+	englishModel *models.EnglishModel
+
+	// Bounds of decompressed messages and the number of messages the
+	// message being coded is nested in, see Limits
+	limits Limits
+	depth  int
 }
 
 // NewAdaptiveModelBuilder creates a new adaptive model builder.
 func NewAdaptiveModelBuilder() *AdaptiveModelBuilder {
 	return &AdaptiveModelBuilder{
-		fieldModels:  make(map[string]arithcode.Model),
-		boolModel:    arithcode.NewUniformModel(2),
-		byteModel:    arithcode.NewUniformModel(256),
-		englishModel: arithcode.NewEnglishModel(),
+		fieldModels:  make(map[string]coder.Model),
+		boolModel:    models.SharedUniformModel(2),
+		byteModel:    models.SharedUniformModel(256),
+		englishModel: models.SharedEnglishModel(),
+		limits:       DefaultLimits,
+	}
+}
+
+// Clone returns a builder with copies of the field models, which adapt
+// independently of the models of amb.
+func (amb *AdaptiveModelBuilder) Clone() *AdaptiveModelBuilder {
+	c := *amb
+	c.fieldModels = make(map[string]coder.Model, len(amb.fieldModels))
+	for path, model := range amb.fieldModels {
+		c.fieldModels[path] = models.Clone(model)
 	}
+	return &c
+}
+
+// Reset forgets the field models, so that the builder codes the next
+// message like a new builder, keeping its limits and the memory of its
+// model map.
+func (amb *AdaptiveModelBuilder) Reset() {
+	clear(amb.fieldModels)
+	amb.depth = 0
+}
+
+// SetLimits sets the limits of decompression; zero fields keep the
+// DefaultLimits. The depth limit applies to compression too.
+func (amb *AdaptiveModelBuilder) SetLimits(limits Limits) error {
+	limits = limits.withDefaults()
+	if err := limits.check(); err != nil {
+		return err
+	}
+	amb.limits = limits
+	return nil
+}
+
+// Nest codes a message nested in the message being coded with code. It
+// fails with ErrMaxDepth when the nested message would exceed the depth
+// limit. Walkers call it where they recurse into a nested message; the
+// top-level message has depth 1.
+func (amb *AdaptiveModelBuilder) Nest(code func() error) error {
+	if amb.depth+1 >= amb.limits.MaxDepth {
+		return depthError(amb.limits.MaxDepth)
+	}
+	amb.depth++
+	err := code()
+	amb.depth--
+	return err
 }
 
 // BoolModel returns the boolean model.
-func (amb *AdaptiveModelBuilder) BoolModel() arithcode.Model {
+func (amb *AdaptiveModelBuilder) BoolModel() coder.Model {
 	return amb.boolModel
 }
 
 // ByteModel returns the byte model.
-func (amb *AdaptiveModelBuilder) ByteModel() arithcode.Model {
+func (amb *AdaptiveModelBuilder) ByteModel() coder.Model {
 	return amb.byteModel
 }
 
 // EnglishModel returns the English text model.
-func (amb *AdaptiveModelBuilder) EnglishModel() *arithcode.EnglishModel {
+func (amb *AdaptiveModelBuilder) EnglishModel() *models.EnglishModel {
 	return amb.englishModel
 }
 
 // GetFieldModel returns a field-specific model for the given field descriptor.
 // The model is created based on the field's path in the message hierarchy,
 // allowing different fields of the same type to have different compression characteristics.
-func (amb *AdaptiveModelBuilder) GetFieldModel(fieldPath string, fd protoreflect.FieldDescriptor) arithcode.Model {
+func (amb *AdaptiveModelBuilder) GetFieldModel(fieldPath string, fd protoreflect.FieldDescriptor) coder.Model {
 	// Check if we already have a model for this specific field
 	if model, ok := amb.fieldModels[fieldPath]; ok {
 		return model
 	}
 
 	// Create a new model based on field type
-	var model arithcode.Model
+	var model coder.Model
 
 	switch fd.Kind() {
 	case protoreflect.BoolKind:
@@ -63,7 +116,7 @@ func (amb *AdaptiveModelBuilder) GetFieldModel(fieldPath string, fd protoreflect
 	case protoreflect.EnumKind:
 		// Create enum-specific model
 		numValues := fd.Enum().Values().Len()
-		model = arithcode.NewUniformModel(numValues)
+		model = models.SharedUniformModel(numValues)
 
 	case protoreflect.Int32Kind, protoreflect.Int64Kind,
 		protoreflect.Uint32Kind, protoreflect.Uint64Kind,
@@ -100,57 +153,47 @@ func (amb *AdaptiveModelBuilder) GetFieldModel(fieldPath string, fd protoreflect
 }
 
 // GetEnumModel returns the enum model for a specific field path.
-func (amb *AdaptiveModelBuilder) GetEnumModel(fieldPath string, ed protoreflect.EnumDescriptor) arithcode.Model {
+func (amb *AdaptiveModelBuilder) GetEnumModel(fieldPath string, ed protoreflect.EnumDescriptor) coder.Model {
 	if model, ok := amb.fieldModels[fieldPath]; ok {
 		return model
 	}
 
 	numValues := ed.Values().Len()
-	model := arithcode.NewUniformModel(numValues)
+	model := models.SharedUniformModel(numValues)
 	amb.fieldModels[fieldPath] = model
 	return model
 }
 
-// createAdaptiveVarintModel creates a varint model that could be adapted
-// based on the field's typical value distribution.
-func createAdaptiveVarintModel(fieldPath string) arithcode.Model {
-	// For now, use a simple model that favors smaller values
-	// In a production implementation, this could analyze the field name
-	// or be trained on sample data to determine optimal frequency distribution
-
-	freqs := make([]uint64, 256)
-
-	// Default distribution favoring small values
-	for i := 0; i < 128; i++ {
-		freqs[i] = 100 - uint64(i/2)
-		if freqs[i] < 1 {
-			freqs[i] = 1
-		}
-	}
-	for i := 128; i < 256; i++ {
-		freqs[i] = 10
-	}
-
+// createAdaptiveVarintModel returns a varint model suited to the field's
+// typical value distribution. The models are static and shared between
+// builders; fields are only distinguished by their name.
+func createAdaptiveVarintModel(fieldPath string) coder.Model {
 	// Field-specific optimizations based on common field names
 	// This is a simple heuristic; a real implementation would learn from data
 	switch {
 	case containsPattern(fieldPath, "id", "user_id", "account_id"):
-		// IDs tend to be larger numbers, adjust distribution
-		for i := 0; i < 256; i++ {
-			freqs[i] = 50 // More uniform for IDs
-		}
+		// IDs tend to be larger numbers, use a more uniform distribution
+		return sharedWideVarintModel()
 	case containsPattern(fieldPath, "count", "size", "length"):
 		// Counts tend to be small, keep default
 	case containsPattern(fieldPath, "timestamp", "created_at", "updated_at"):
 		// Timestamps are large, uniform distribution
-		for i := 0; i < 256; i++ {
-			freqs[i] = 50
-		}
+		return sharedWideVarintModel()
 	}
 
-	return arithcode.NewFrequencyTable(freqs)
+	// Default distribution favoring small values
+	return sharedVarintModel()
 }
 
+// sharedWideVarintModel returns a varint model for fields with large values.
+var sharedWideVarintModel = sync.OnceValue(func() coder.Model {
+	freqs := make([]uint64, 256)
+	for i := range freqs {
+		freqs[i] = 50
+	}
+	return models.NewFrequencyTable(freqs)
+})
+
 // containsPattern checks if the field path contains any of the given patterns.
 func containsPattern(fieldPath string, patterns ...string) bool {
 	for _, pattern := range patterns {
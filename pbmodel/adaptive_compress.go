@@ -10,15 +10,20 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 )
 
 // AdaptiveCompress compresses a protobuf message using field-specific models.
 // This variant creates a separate compression model for each field, allowing
-// better compression by learning field-specific patterns.
-func AdaptiveCompress(msg proto.Message, w io.Writer) error {
+// better compression by learning field-specific patterns. The data starts
+// with a format header; of the options only WithoutHeader applies.
+func AdaptiveCompress(msg proto.Message, w io.Writer, opts ...Option) error {
+	if err := headerOptions(opts).writeHeader(w, formatAdaptive); err != nil {
+		return err
+	}
 	amb := NewAdaptiveModelBuilder()
-	enc := arithcode.NewEncoder(w)
+	enc := coder.NewEncoder(w)
 
 	if err := adaptiveCompressMessage("", msg.ProtoReflect(), enc, amb); err != nil {
 		return err
@@ -28,7 +33,7 @@ func AdaptiveCompress(msg proto.Message, w io.Writer) error {
 }
 
 // adaptiveCompressMessage recursively compresses a protobuf message using adaptive models.
-func adaptiveCompressMessage(fieldPath string, msg protoreflect.Message, enc *arithcode.Encoder, amb *AdaptiveModelBuilder) error {
+func adaptiveCompressMessage(fieldPath string, msg protoreflect.Message, enc *coder.Encoder, amb *AdaptiveModelBuilder) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -63,7 +68,7 @@ func adaptiveCompressMessage(fieldPath string, msg protoreflect.Message, enc *ar
 		} else if fd.Kind() == protoreflect.MessageKind {
 			// For message fields, recurse with updated path
 			nestedMsg := value.Message()
-			if err := adaptiveCompressMessage(currentPath, nestedMsg, enc, amb); err != nil {
+			if err := amb.Nest(func() error { return adaptiveCompressMessage(currentPath, nestedMsg, enc, amb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -77,7 +82,7 @@ func adaptiveCompressMessage(fieldPath string, msg protoreflect.Message, enc *ar
 }
 
 // adaptiveCompressRepeatedField compresses a repeated field with field-specific model.
-func adaptiveCompressRepeatedField(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *arithcode.Encoder, amb *AdaptiveModelBuilder) error {
+func adaptiveCompressRepeatedField(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, enc *coder.Encoder, amb *AdaptiveModelBuilder) error {
 	// Encode the length using a field-specific model for list lengths
 	lengthPath := fieldPath + "._length"
 	lengthModel := amb.GetFieldModel(lengthPath, fd)
@@ -98,7 +103,7 @@ func adaptiveCompressRepeatedField(fieldPath string, fd protoreflect.FieldDescri
 	for i := 0; i < length; i++ {
 		value := list.Get(i)
 		if fd.Kind() == protoreflect.MessageKind {
-			if err := adaptiveCompressMessage(elementPath, value.Message(), enc, amb); err != nil {
+			if err := amb.Nest(func() error { return adaptiveCompressMessage(elementPath, value.Message(), enc, amb) }); err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 		} else {
@@ -112,7 +117,7 @@ func adaptiveCompressRepeatedField(fieldPath string, fd protoreflect.FieldDescri
 }
 
 // adaptiveCompressMapField compresses a map field with field-specific models.
-func AdaptiveCompressMapField(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *arithcode.Encoder, amb *AdaptiveModelBuilder) error {
+func AdaptiveCompressMapField(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *coder.Encoder, amb *AdaptiveModelBuilder) error {
 	// Encode the length
 	lengthPath := fieldPath + "._length"
 	lengthModel := amb.GetFieldModel(lengthPath, fd)
@@ -136,7 +141,7 @@ func AdaptiveCompressMapField(fieldPath string, fd protoreflect.FieldDescriptor,
 
 	// Encode each key-value pair
 	var encodeErr error
-	m.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+	RangeMapSorted(m, func(k protoreflect.MapKey, v protoreflect.Value) bool {
 		// Encode key
 		if err := adaptiveCompressFieldValue(keyPath, keyFd, k.Value(), enc, amb); err != nil {
 			encodeErr = fmt.Errorf("map key: %w", err)
@@ -145,7 +150,7 @@ func AdaptiveCompressMapField(fieldPath string, fd protoreflect.FieldDescriptor,
 
 		// Encode value
 		if valueFd.Kind() == protoreflect.MessageKind {
-			if err := adaptiveCompressMessage(valuePath, v.Message(), enc, amb); err != nil {
+			if err := amb.Nest(func() error { return adaptiveCompressMessage(valuePath, v.Message(), enc, amb) }); err != nil {
 				encodeErr = fmt.Errorf("map value: %w", err)
 				return false
 			}
@@ -163,7 +168,7 @@ func AdaptiveCompressMapField(fieldPath string, fd protoreflect.FieldDescriptor,
 }
 
 // adaptiveCompressFieldValue compresses a single field value using field-specific model.
-func adaptiveCompressFieldValue(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *arithcode.Encoder, amb *AdaptiveModelBuilder) error {
+func adaptiveCompressFieldValue(fieldPath string, fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *coder.Encoder, amb *AdaptiveModelBuilder) error {
 	model := amb.GetFieldModel(fieldPath, fd)
 
 	switch fd.Kind() {
@@ -287,7 +292,7 @@ func adaptiveCompressFieldValue(fieldPath string, fd protoreflect.FieldDescripto
 		// Use the English model for strings
 		str := value.String()
 		var buf bytes.Buffer
-		if err := arithcode.EncodeString(str, &buf); err != nil {
+		if err := models.EncodeString(str, &buf); err != nil {
 			return err
 		}
 		// Encode the compressed string bytes
@@ -298,12 +303,7 @@ func adaptiveCompressFieldValue(fieldPath string, fd protoreflect.FieldDescripto
 				return err
 			}
 		}
-		for _, b := range compressedBytes {
-			if err := enc.Encode(int(b), amb.byteModel); err != nil {
-				return err
-			}
-		}
-		return nil
+		return enc.EncodeBytes(compressedBytes, amb.byteModel)
 
 	case protoreflect.BytesKind:
 		data := value.Bytes()
@@ -314,13 +314,8 @@ func adaptiveCompressFieldValue(fieldPath string, fd protoreflect.FieldDescripto
 				return err
 			}
 		}
-		// Encode bytes
-		for _, b := range data {
-			if err := enc.Encode(int(b), amb.byteModel); err != nil {
-				return err
-			}
-		}
-		return nil
+		// Encode bytes, the byte model is static, see coder.Encoder.EncodeBytes
+		return enc.EncodeBytes(data, amb.byteModel)
 
 	default:
 		return fmt.Errorf("unsupported field kind: %v", fd.Kind())
@@ -103,9 +103,10 @@ func TestAdaptiveVsTypeBasedCompression(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Type-based compression
+			// Type-based compression, without the header that adaptive
+			// compression does not have
 			var typeBuf bytes.Buffer
-			err := Compress(tt.msg, &typeBuf)
+			err := Compress(tt.msg, &typeBuf, WithoutHeader())
 			if err != nil {
 				t.Fatalf("Compress failed: %v", err)
 			}
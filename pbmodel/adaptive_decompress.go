@@ -11,22 +11,32 @@ import (
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/dynamicpb"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 )
 
 // AdaptiveDecompress decompresses data into a protobuf message using field-specific models.
-func AdaptiveDecompress(r io.Reader, msg proto.Message) error {
+// The message is reset first. The options must match the ones of
+// AdaptiveCompress.
+func AdaptiveDecompress(r io.Reader, msg proto.Message, opts ...Option) error {
+	if err := headerOptions(opts).readHeader(r, formatAdaptive); err != nil {
+		return err
+	}
+	proto.Reset(msg)
 	amb := NewAdaptiveModelBuilder()
-	dec, err := arithcode.NewDecoder(r)
+	dec, err := newDecoder(r)
 	if err != nil {
 		return err
 	}
 
-	return adaptiveDecompressMessage("", msg.ProtoReflect(), dec, amb)
+	if err := adaptiveDecompressMessage("", msg.ProtoReflect(), dec, amb); err != nil {
+		return err
+	}
+	return dec.Finish()
 }
 
 // adaptiveDecompressMessage recursively decompresses a protobuf message using adaptive models.
-func adaptiveDecompressMessage(fieldPath string, msg protoreflect.Message, dec *arithcode.Decoder, amb *AdaptiveModelBuilder) error {
+func adaptiveDecompressMessage(fieldPath string, msg protoreflect.Message, dec *coder.Decoder, amb *AdaptiveModelBuilder) error {
 	md := msg.Descriptor()
 	fields := md.Fields()
 
@@ -59,7 +69,7 @@ func adaptiveDecompressMessage(fieldPath string, msg protoreflect.Message, dec *
 		} else if fd.Kind() == protoreflect.MessageKind {
 			// For message fields, decompress directly into the mutable field
 			nestedMsg := msg.Mutable(fd).Message()
-			if err := adaptiveDecompressMessage(currentPath, nestedMsg, dec, amb); err != nil {
+			if err := amb.Nest(func() error { return adaptiveDecompressMessage(currentPath, nestedMsg, dec, amb) }); err != nil {
 				return fmt.Errorf("field %s: %w", fd.Name(), err)
 			}
 		} else {
@@ -75,7 +85,7 @@ func adaptiveDecompressMessage(fieldPath string, msg protoreflect.Message, dec *
 }
 
 // adaptiveDecompressRepeatedField decompresses a repeated field using field-specific models.
-func adaptiveDecompressRepeatedField(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *arithcode.Decoder, amb *AdaptiveModelBuilder) error {
+func adaptiveDecompressRepeatedField(fieldPath string, fd protoreflect.FieldDescriptor, list protoreflect.List, dec *coder.Decoder, amb *AdaptiveModelBuilder) error {
 	// Decode the length using field-specific model
 	lengthPath := fieldPath + "._length"
 	lengthModel := amb.GetFieldModel(lengthPath, fd)
@@ -87,13 +97,16 @@ func adaptiveDecompressRepeatedField(fieldPath string, fd protoreflect.FieldDesc
 	if err != nil {
 		return fmt.Errorf("list length: %w", err)
 	}
+	if err := checkLimit("list length", length, amb.limits.MaxListLen); err != nil {
+		return err
+	}
 
 	// Decode each element
 	elementPath := fieldPath + "[]"
 	for i := 0; i < int(length); i++ {
 		if fd.Kind() == protoreflect.MessageKind {
 			elem := list.NewElement()
-			if err := adaptiveDecompressMessage(elementPath, elem.Message(), dec, amb); err != nil {
+			if err := amb.Nest(func() error { return adaptiveDecompressMessage(elementPath, elem.Message(), dec, amb) }); err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 			list.Append(elem)
@@ -110,7 +123,7 @@ func adaptiveDecompressRepeatedField(fieldPath string, fd protoreflect.FieldDesc
 }
 
 // adaptiveDecompressMapField decompresses a map field using field-specific models.
-func AdaptiveDecompressMapField(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, dec *arithcode.Decoder, amb *AdaptiveModelBuilder) error {
+func AdaptiveDecompressMapField(fieldPath string, fd protoreflect.FieldDescriptor, m protoreflect.Map, dec *coder.Decoder, amb *AdaptiveModelBuilder) error {
 	// Decode the length
 	lengthPath := fieldPath + "._length"
 	lengthModel := amb.GetFieldModel(lengthPath, fd)
@@ -122,6 +135,9 @@ func AdaptiveDecompressMapField(fieldPath string, fd protoreflect.FieldDescripto
 	if err != nil {
 		return fmt.Errorf("map length: %w", err)
 	}
+	if err := checkLimit("map length", length, amb.limits.MaxListLen); err != nil {
+		return err
+	}
 
 	// Get key and value descriptors
 	keyFd := fd.MapKey()
@@ -142,7 +158,7 @@ func AdaptiveDecompressMapField(fieldPath string, fd protoreflect.FieldDescripto
 		if valueFd.Kind() == protoreflect.MessageKind {
 			msgDesc := valueFd.Message()
 			valueMsg := dynamicpb.NewMessage(msgDesc)
-			if err := adaptiveDecompressMessage(valuePath, valueMsg, dec, amb); err != nil {
+			if err := amb.Nest(func() error { return adaptiveDecompressMessage(valuePath, valueMsg, dec, amb) }); err != nil {
 				return fmt.Errorf("map value %d: %w", i, err)
 			}
 			valueValue = protoreflect.ValueOfMessage(valueMsg)
@@ -160,7 +176,7 @@ func AdaptiveDecompressMapField(fieldPath string, fd protoreflect.FieldDescripto
 }
 
 // adaptiveDecompressFieldValue decompresses a single field value using field-specific model.
-func adaptiveDecompressFieldValue(fieldPath string, fd protoreflect.FieldDescriptor, dec *arithcode.Decoder, amb *AdaptiveModelBuilder) (protoreflect.Value, error) {
+func adaptiveDecompressFieldValue(fieldPath string, fd protoreflect.FieldDescriptor, dec *coder.Decoder, amb *AdaptiveModelBuilder) (protoreflect.Value, error) {
 	model := amb.GetFieldModel(fieldPath, fd)
 
 	switch fd.Kind() {
@@ -304,22 +320,24 @@ func adaptiveDecompressFieldValue(fieldPath string, fd protoreflect.FieldDescrip
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
+		if err := checkLimit("coded string length", compressedLen, amb.limits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		// Decode the compressed bytes
 		compressedBytes := make([]byte, compressedLen)
-		for i := 0; i < int(compressedLen); i++ {
-			b, err := dec.Decode(amb.byteModel)
-			if err != nil {
-				return protoreflect.Value{}, err
-			}
-			compressedBytes[i] = byte(b)
+		if err := dec.DecodeBytes(compressedBytes, amb.byteModel); err != nil {
+			return protoreflect.Value{}, err
 		}
 
 		// Decompress the string using the English model
-		str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
+		str, err := models.DecodeString(bytes.NewReader(compressedBytes))
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
+		if err := checkLimit("string length", uint64(len(str)), amb.limits.MaxStringLen); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		return protoreflect.ValueOfString(str), nil
 
@@ -329,15 +347,14 @@ func adaptiveDecompressFieldValue(fieldPath string, fd protoreflect.FieldDescrip
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
+		if err := checkLimit("bytes length", length, amb.limits.MaxBytes); err != nil {
+			return protoreflect.Value{}, err
+		}
 
 		// Decode bytes
 		data := make([]byte, length)
-		for i := 0; i < int(length); i++ {
-			b, err := dec.Decode(amb.byteModel)
-			if err != nil {
-				return protoreflect.Value{}, err
-			}
-			data[i] = byte(b)
+		if err := dec.DecodeBytes(data, amb.byteModel); err != nil {
+			return protoreflect.Value{}, err
 		}
 
 		return protoreflect.ValueOfBytes(data), nil
@@ -348,7 +365,7 @@ func adaptiveDecompressFieldValue(fieldPath string, fd protoreflect.FieldDescrip
 }
 
 // adaptiveDecodeVarintFromDecoder decodes a varint using the decoder and field-specific model.
-func adaptiveDecodeVarintFromDecoder(dec *arithcode.Decoder, model arithcode.Model) (uint64, error) {
+func adaptiveDecodeVarintFromDecoder(dec *coder.Decoder, model coder.Model) (uint64, error) {
 	var value uint64
 	for i := 0; i < 10; i++ { // Max 10 bytes for uint64
 		b, err := dec.Decode(model)
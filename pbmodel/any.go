@@ -0,0 +1,160 @@
+package pbmodel
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// Data with Any fields usually packs a handful of types, so a type URL is
+// coded as its position among the URLs coded before it, most recent first;
+// only URLs that have not been seen yet are coded as strings. The value of
+// an Any is serialized message, which compresses poorly as bytes. When the
+// type of the value resolves, the message is coded instead, as long as it
+// serializes back to the exact bytes of the value.
+
+// maxTypeURLs limits the type URLs remembered by a compressor; the least
+// recently used ones are forgotten first.
+const maxTypeURLs = 64
+
+// Symbols of the packed flag of Any values.
+const (
+	anyBytes  = 0 // The value is coded as bytes
+	anyPacked = 1 // The value is coded as a message of its type
+)
+
+// WithAnyResolver resolves the types of values in google.protobuf.Any
+// messages, so that the values are coded as messages instead of bytes. It
+// takes effect together with WithWellKnownTypes. Data compressed with a
+// resolver decompresses only with a resolver for the same types.
+func WithAnyResolver(resolver protoregistry.MessageTypeResolver) Option {
+	return func(o *options) { o.anyResolver = resolver }
+}
+
+// encodeAny encodes an Any message.
+func (mb *ModelBuilder) encodeAny(enc coder.SymbolEncoder, msg protoreflect.Message) error {
+	fields := msg.Descriptor().Fields()
+	urlFd, valueFd := fields.ByName("type_url"), fields.ByName("value")
+	url, value := msg.Get(urlFd).String(), msg.Get(valueFd).Bytes()
+
+	i := slices.Index(mb.typeURLs, url)
+	if err := mb.encodeVarint(enc, uint64(i+1)); err != nil {
+		return fmt.Errorf("type_url: %w", err)
+	}
+	if i < 0 {
+		if err := compressFieldValue(urlFd, msg.Get(urlFd), enc, mb); err != nil {
+			return fmt.Errorf("type_url: %w", err)
+		}
+	}
+	mb.useTypeURL(i, url)
+
+	if mb.anyResolver == nil {
+		return compressFieldValue(valueFd, msg.Get(valueFd), enc, mb)
+	}
+	packed := mb.unpackAny(url, value)
+	if err := enc.Encode(boolSymbol(packed != nil), mb.boolModel); err != nil {
+		return fmt.Errorf("value: %w", err)
+	}
+	if packed == nil {
+		return compressFieldValue(valueFd, msg.Get(valueFd), enc, mb)
+	}
+	if err := compressMessage(packed, enc, mb); err != nil {
+		return fmt.Errorf("value: %w", err)
+	}
+	return nil
+}
+
+// decodeAny decodes an Any message written by encodeAny into msg.
+func (mb *ModelBuilder) decodeAny(dec coder.SymbolDecoder, msg protoreflect.Message) error {
+	fields := msg.Descriptor().Fields()
+	urlFd, valueFd := fields.ByName("type_url"), fields.ByName("value")
+
+	index, err := mb.decodeVarint(dec)
+	if err != nil {
+		return fmt.Errorf("type_url: %w", err)
+	}
+	var url string
+	switch {
+	case index == 0:
+		value, err := decompressFieldValue(urlFd, dec, mb)
+		if err != nil {
+			return fmt.Errorf("type_url: %w", err)
+		}
+		url = value.String()
+	case index <= uint64(len(mb.typeURLs)):
+		url = mb.typeURLs[index-1]
+	default:
+		return fmt.Errorf("type_url %d of %d: %w", index, len(mb.typeURLs), ErrOutOfRange)
+	}
+	mb.useTypeURL(int(index)-1, url)
+	msg.Set(urlFd, protoreflect.ValueOfString(url))
+
+	packed := anyBytes
+	if mb.anyResolver != nil {
+		if packed, err = dec.Decode(mb.boolModel); err != nil {
+			return fmt.Errorf("value: %w", err)
+		}
+	}
+	var value protoreflect.Value
+	if packed == anyBytes {
+		if value, err = decompressFieldValue(valueFd, dec, mb); err != nil {
+			return err
+		}
+	} else {
+		typ, err := mb.anyResolver.FindMessageByURL(url)
+		if err != nil {
+			return fmt.Errorf("value: %w", err)
+		}
+		m := typ.New()
+		if err := decompressMessage(m, dec, mb); err != nil {
+			return fmt.Errorf("value: %w", err)
+		}
+		raw, err := proto.MarshalOptions{Deterministic: true}.Marshal(m.Interface())
+		if err != nil {
+			return fmt.Errorf("value: %w", err)
+		}
+		value = protoreflect.ValueOfBytes(raw)
+	}
+	if len(value.Bytes()) > 0 {
+		msg.Set(valueFd, value)
+	}
+	return nil
+}
+
+// useTypeURL moves url, found at index i of the recent type URLs or new
+// when i is negative, to the front of the recent type URLs.
+func (mb *ModelBuilder) useTypeURL(i int, url string) {
+	if i < 0 {
+		if len(mb.typeURLs) == maxTypeURLs {
+			mb.typeURLs = mb.typeURLs[:maxTypeURLs-1]
+		}
+		mb.typeURLs = append(mb.typeURLs, "")
+		i = len(mb.typeURLs) - 1
+	}
+	copy(mb.typeURLs[1:i+1], mb.typeURLs[:i])
+	mb.typeURLs[0] = url
+}
+
+// unpackAny returns the message packed in value when its type resolves and
+// it serializes back to value, nil otherwise.
+func (mb *ModelBuilder) unpackAny(url string, value []byte) protoreflect.Message {
+	typ, err := mb.anyResolver.FindMessageByURL(url)
+	if err != nil {
+		return nil
+	}
+	m := typ.New()
+	if err := (proto.UnmarshalOptions{DiscardUnknown: !mb.unknownFields}).Unmarshal(value, m.Interface()); err != nil {
+		return nil
+	}
+	raw, err := proto.MarshalOptions{Deterministic: true}.Marshal(m.Interface())
+	if err != nil || !bytes.Equal(raw, value) {
+		return nil
+	}
+	return m
+}
@@ -0,0 +1,114 @@
+package pbmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+// envelopeSchema returns the descriptor of a message with a list of Any.
+func envelopeSchema(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("envelope.proto"),
+		Package:    proto.String("envelope"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/any.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Envelope"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("items"),
+				Number:   proto.Int32(1),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				TypeName: proto.String(".google.protobuf.Any"),
+			}},
+		}},
+	}
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd.Messages().Get(0)
+}
+
+func TestAny(t *testing.T) {
+	md := envelopeSchema(t)
+	items := []*anypb.Any{
+		{},
+		{TypeUrl: "type.example.com/unknown.Type", Value: []byte{0x08, 0x96, 0x01}},
+		// Not the serialization of its type, coded as bytes
+		{TypeUrl: "type.googleapis.com/google.protobuf.Timestamp", Value: []byte{0x10, 0x01, 0x08, 0x01}},
+	}
+	for i := range 12 {
+		simple, err := anypb.New(&testdata.SimpleMessage{Id: int32(1000 + i), Name: "sensor reading", Active: i%3 == 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts, err := anypb.New(&timestamppb.Timestamp{Seconds: 1_712_345_678 + int64(60*i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, simple, ts)
+	}
+
+	envelope := dynamicpb.NewMessage(md)
+	list := envelope.Mutable(md.Fields().ByName("items")).List()
+	for _, item := range items {
+		list.Append(protoreflect.ValueOfMessage(item.ProtoReflect()))
+	}
+
+	configs := []struct {
+		name string
+		opts []Option
+	}{
+		{"generic", nil},
+		{"dictionary", []Option{WithWellKnownTypes()}},
+		{"resolver", []Option{WithWellKnownTypes(), WithAnyResolver(protoregistry.GlobalTypes)}},
+		{"resolver, huffman", []Option{WithWellKnownTypes(), WithAnyResolver(protoregistry.GlobalTypes), WithBackend(Huffman)}},
+	}
+	sizes := map[string]int{}
+	for _, config := range configs {
+		var buf bytes.Buffer
+		if err := Compress(envelope, &buf, config.opts...); err != nil {
+			t.Fatalf("%s: Compress failed: %v", config.name, err)
+		}
+		sizes[config.name] = buf.Len()
+		decoded := dynamicpb.NewMessage(md)
+		if err := Decompress(&buf, decoded, config.opts...); err != nil {
+			t.Fatalf("%s: Decompress failed: %v", config.name, err)
+		}
+		if !proto.Equal(envelope, decoded) {
+			t.Errorf("%s: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", config.name, envelope, decoded)
+		}
+	}
+
+	t.Logf("envelope: %d bytes generic, %d bytes with the type URL dictionary, %d bytes with the resolver",
+		sizes["generic"], sizes["dictionary"], sizes["resolver"])
+	if sizes["dictionary"] >= sizes["generic"] {
+		t.Errorf("type URL dictionary %d bytes, want less than %d bytes", sizes["dictionary"], sizes["generic"])
+	}
+	if sizes["resolver"] >= sizes["dictionary"] {
+		t.Errorf("resolved values %d bytes, want less than %d bytes", sizes["resolver"], sizes["dictionary"])
+	}
+
+	// The packed values need the resolver to decompress
+	var buf bytes.Buffer
+	if err := Compress(envelope, &buf, WithWellKnownTypes(), WithAnyResolver(protoregistry.GlobalTypes)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Decompress(&buf, dynamicpb.NewMessage(md), WithWellKnownTypes(), WithAnyResolver(new(protoregistry.Types))); err == nil {
+		t.Errorf("Decompress without the types succeeded")
+	}
+}
@@ -0,0 +1,294 @@
+package pbmodel
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Compressed messages can only be decompressed with their descriptors. An
+// archive carries the descriptors along with the messages, so that captured
+// traffic of any types can be decoded later without the generated types,
+// into dynamicpb messages.
+//
+// An archive starts with archiveMagic and continues with records, each
+// starting with its kind as a uvarint:
+//
+//   - archiveFiles: the length of a FileDescriptorSet and the set,
+//     compressed with this package. The set holds the files that the
+//     following messages need and the earlier records did not have, with
+//     every file after its dependencies.
+//   - archiveMessage: the index of the message type among the types of the
+//     earlier messages. A new type has the next index and is followed by its
+//     length-prefixed full name. Then follows the length of the compressed
+//     message and the message, compressed with the options of the archive.
+
+// archiveMagic starts archives; its last byte is the version of the format.
+var archiveMagic = []byte("PBZA\x01")
+
+// Kinds of archive records.
+const (
+	archiveFiles   = 0
+	archiveMessage = 1
+)
+
+// maxArchiveRecord limits the lengths in archives, so that corrupted
+// lengths fail before allocating.
+const maxArchiveRecord = 1 << 26
+
+// ArchiveWriter writes messages to an archive with their descriptors.
+type ArchiveWriter struct {
+	w     io.Writer
+	opts  []Option
+	files map[string]bool                  // Paths of the files written
+	types map[protoreflect.FullName]uint64 // Indices of the message types written
+}
+
+// NewArchiveWriter writes the start of an archive to w. The messages are
+// compressed with opts, which must also be passed to NewArchiveReader.
+func NewArchiveWriter(w io.Writer, opts ...Option) (*ArchiveWriter, error) {
+	if _, err := collectOptions(opts); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(archiveMagic); err != nil {
+		return nil, err
+	}
+	return &ArchiveWriter{
+		w:     w,
+		opts:  opts,
+		files: make(map[string]bool),
+		types: make(map[protoreflect.FullName]uint64),
+	}, nil
+}
+
+// Write appends msg to the archive, preceded by the descriptor files it
+// needs that the archive does not have yet.
+func (a *ArchiveWriter) Write(msg proto.Message) error {
+	md := msg.ProtoReflect().Descriptor()
+	if err := a.writeFiles(md.ParentFile()); err != nil {
+		return err
+	}
+
+	var compressed bytes.Buffer
+	if err := Compress(msg, &compressed, a.opts...); err != nil {
+		return err
+	}
+
+	record := binary.AppendUvarint(nil, archiveMessage)
+	index, ok := a.types[md.FullName()]
+	if !ok {
+		index = uint64(len(a.types))
+	}
+	record = binary.AppendUvarint(record, index)
+	if !ok {
+		record = binary.AppendUvarint(record, uint64(len(md.FullName())))
+		record = append(record, md.FullName()...)
+	}
+	record = binary.AppendUvarint(record, uint64(compressed.Len()))
+	if _, err := a.w.Write(append(record, compressed.Bytes()...)); err != nil {
+		return err
+	}
+	a.types[md.FullName()] = index
+	return nil
+}
+
+// writeFiles writes a record with file and its dependencies, leaving out
+// the files that were already written.
+func (a *ArchiveWriter) writeFiles(file protoreflect.FileDescriptor) error {
+	set := &descriptorpb.FileDescriptorSet{}
+	var visit func(protoreflect.FileDescriptor)
+	visit = func(fd protoreflect.FileDescriptor) {
+		if a.files[fd.Path()] {
+			return
+		}
+		a.files[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			visit(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	visit(file)
+	if len(set.File) == 0 {
+		return nil
+	}
+
+	// Custom options, such as the hints, are extensions of the options
+	// messages; as unknown fields they are kept by WithUnknownFields.
+	raw, err := proto.MarshalOptions{Deterministic: true}.Marshal(set)
+	if err != nil {
+		return err
+	}
+	set.Reset()
+	if err := (proto.UnmarshalOptions{Resolver: new(protoregistry.Types)}).Unmarshal(raw, set); err != nil {
+		return err
+	}
+	var compressed bytes.Buffer
+	if err := Compress(set, &compressed, WithUnknownFields()); err != nil {
+		return fmt.Errorf("descriptors: %w", err)
+	}
+
+	record := binary.AppendUvarint(nil, archiveFiles)
+	record = binary.AppendUvarint(record, uint64(compressed.Len()))
+	_, err = a.w.Write(append(record, compressed.Bytes()...))
+	return err
+}
+
+// ArchiveReader reads the messages of an archive written by ArchiveWriter.
+type ArchiveReader struct {
+	r     *bufio.Reader
+	opts  []Option
+	files *protoregistry.Files
+	types []protoreflect.MessageDescriptor
+}
+
+// NewArchiveReader reads the start of an archive from r. The options must
+// be the ones the archive was written with.
+func NewArchiveReader(r io.Reader, opts ...Option) (*ArchiveReader, error) {
+	if _, err := collectOptions(opts); err != nil {
+		return nil, err
+	}
+	a := &ArchiveReader{r: bufio.NewReader(r), opts: opts, files: new(protoregistry.Files)}
+	magic := make([]byte, len(archiveMagic))
+	if _, err := io.ReadFull(a.r, magic); err != nil {
+		return nil, archiveError(err)
+	}
+	if !bytes.Equal(magic, archiveMagic) {
+		return nil, fmt.Errorf("pbmodel: not an archive or unsupported version")
+	}
+	return a, nil
+}
+
+// Files returns the descriptor files read so far.
+func (a *ArchiveReader) Files() *protoregistry.Files {
+	return a.files
+}
+
+// Next returns the next message of the archive as a dynamicpb message, and
+// io.EOF at the end of the archive.
+func (a *ArchiveReader) Next() (proto.Message, error) {
+	for {
+		kind, err := binary.ReadUvarint(a.r)
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, archiveError(err)
+		}
+
+		switch kind {
+		case archiveFiles:
+			if err := a.readFiles(); err != nil {
+				return nil, err
+			}
+		case archiveMessage:
+			return a.readMessage()
+		default:
+			return nil, fmt.Errorf("archive record kind %d: %w", kind, ErrOutOfRange)
+		}
+	}
+}
+
+// readFiles reads the descriptor files of a record.
+func (a *ArchiveReader) readFiles() error {
+	data, err := a.readBytes()
+	if err != nil {
+		return err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := Decompress(bytes.NewReader(data), set, WithUnknownFields()); err != nil {
+		return fmt.Errorf("descriptors: %w", err)
+	}
+
+	// Resolve the custom options known to the program, such as the hints
+	raw, err := proto.Marshal(set)
+	if err != nil {
+		return err
+	}
+	set.Reset()
+	if err := proto.Unmarshal(raw, set); err != nil {
+		return err
+	}
+
+	for _, fdp := range set.File {
+		fd, err := protodesc.NewFile(fdp, a.files)
+		if err != nil {
+			return fmt.Errorf("descriptors: %w", err)
+		}
+		if err := a.files.RegisterFile(fd); err != nil {
+			return fmt.Errorf("descriptors: %w", err)
+		}
+	}
+	return nil
+}
+
+// readMessage reads the message of a record.
+func (a *ArchiveReader) readMessage() (proto.Message, error) {
+	index, err := binary.ReadUvarint(a.r)
+	if err != nil {
+		return nil, archiveError(err)
+	}
+	switch {
+	case index == uint64(len(a.types)):
+		name, err := a.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		d, err := a.files.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return nil, fmt.Errorf("archive message type %s: %w", name, err)
+		}
+		md, ok := d.(protoreflect.MessageDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("archive message type %s is not a message", name)
+		}
+		a.types = append(a.types, md)
+	case index > uint64(len(a.types)):
+		return nil, fmt.Errorf("archive message type %d of %d: %w", index, len(a.types), ErrOutOfRange)
+	}
+
+	data, err := a.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	msg := dynamicpb.NewMessage(a.types[index])
+	if err := Decompress(bytes.NewReader(data), msg, a.opts...); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// readBytes reads length-prefixed bytes.
+func (a *ArchiveReader) readBytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(a.r)
+	if err != nil {
+		return nil, archiveError(err)
+	}
+	if n > maxArchiveRecord {
+		return nil, fmt.Errorf("archive record length %d: %w", n, ErrOutOfRange)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(a.r, data); err != nil {
+		return nil, archiveError(err)
+	}
+	return data, nil
+}
+
+// archiveError reports the end of the input within an archive as
+// ErrTruncated.
+func archiveError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrTruncated
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package pbmodel
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestArchive(t *testing.T) {
+	hinted := hintedMessage(t, true)
+	telemetry := dynamicpb.NewMessage(hinted)
+	telemetry.Set(hinted.Fields().ByName("battery_level"), protoreflect.ValueOfUint32(87))
+	telemetry.Set(hinted.Fields().ByName("latitude_i"), protoreflect.ValueOfInt32(594370000))
+	telemetry.Set(hinted.Fields().ByName("temperature"), protoreflect.ValueOfInt32(-12))
+
+	event := eventSchema(t)
+	timed := dynamicpb.NewMessage(event)
+	timed.Mutable(event.Fields().ByName("times")).List().Append(
+		protoreflect.ValueOfMessage((&timestamppb.Timestamp{Seconds: 1_712_345_678, Nanos: 5e8}).ProtoReflect()))
+	timed.Set(event.Fields().ByName("elapsed"), protoreflect.ValueOfMessage((&durationpb.Duration{Seconds: 3}).ProtoReflect()))
+
+	messages := []proto.Message{
+		&testdata.SimpleMessage{Id: 1, Name: "first", Active: true},
+		telemetry,
+		&testdata.NestedMessage{Inner: &testdata.NestedMessage_Inner{Value: "inner", Count: 2}, OuterField: "outer"},
+		timed,
+		&testdata.SimpleMessage{Id: 3, Name: "second"},
+	}
+
+	var archive bytes.Buffer
+	w, err := NewArchiveWriter(&archive, WithWellKnownTypes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range messages {
+		if err := w.Write(msg); err != nil {
+			t.Fatalf("Write(%v) failed: %v", msg, err)
+		}
+	}
+
+	r, err := NewArchiveReader(bytes.NewReader(archive.Bytes()), WithWellKnownTypes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range messages {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("message %d: Next failed: %v", i, err)
+		}
+		if got.ProtoReflect().Descriptor().FullName() != want.ProtoReflect().Descriptor().FullName() {
+			t.Fatalf("message %d: got type %s, want %s", i, got.ProtoReflect().Descriptor().FullName(), want.ProtoReflect().Descriptor().FullName())
+		}
+		// The decoded messages have the descriptors of the archive, so they
+		// compare by their wire format
+		gotRaw, _ := proto.MarshalOptions{Deterministic: true}.Marshal(got)
+		wantRaw, _ := proto.MarshalOptions{Deterministic: true}.Marshal(want)
+		if !bytes.Equal(gotRaw, wantRaw) {
+			t.Errorf("message %d: got %v, want %v", i, got, want)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Next at the end: got %v, want io.EOF", err)
+	}
+
+	// test.proto, hinted.proto, events.proto and the three well-known files
+	if n := r.Files().NumFiles(); n != 6 {
+		t.Errorf("archive has %d files, want 6", n)
+	}
+	t.Logf("archive of %d messages: %d bytes", len(messages), archive.Len())
+
+	// A truncated archive fails instead of ending early
+	r, err = NewArchiveReader(bytes.NewReader(archive.Bytes()[:archive.Len()-3]), WithWellKnownTypes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for err == nil {
+		_, err = r.Next()
+	}
+	if !errors.Is(err, ErrTruncated) {
+		t.Errorf("truncated archive: got %v, want ErrTruncated", err)
+	}
+}
@@ -0,0 +1,80 @@
+package pbmodel
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/huffman"
+)
+
+// Backend selects the entropy coder that codes the symbols of the models.
+type Backend int
+
+const (
+	// Arithmetic codes with the arithmetic coder, which compresses best.
+	Arithmetic Backend = iota
+	// Huffman codes with static Huffman codes derived from the same models.
+	// It compresses worse, especially booleans and other skewed fields, but
+	// the decoder is faster and simple enough to port to firmware. Binary
+	// bytes fields are coded raw instead of with the adaptive order-1 model.
+	Huffman
+)
+
+// CompressOptions configures CompressWithOptions.
+type CompressOptions struct {
+	Backend Backend
+}
+
+// CompressWithOptions compresses msg using the given options. The data must
+// be decompressed with DecompressWithOptions using the same backend.
+func CompressWithOptions(msg proto.Message, w io.Writer, opts CompressOptions) error {
+	return Compress(msg, w, WithBackend(opts.Backend))
+}
+
+// streamDecoder is a symbol decoder that can verify the end of its input and
+// report the length of the data it decoded.
+type streamDecoder interface {
+	coder.SymbolDecoder
+	Finish() error
+	Len() int
+	HasTrailingData() (bool, error)
+}
+
+// newBackendDecoder creates a decoder for the backend.
+func newBackendDecoder(r io.Reader, backend Backend) (streamDecoder, error) {
+	if backend == Huffman {
+		return huffman.NewDecoder(r), nil
+	}
+	return newDecoder(r)
+}
+
+// encodeBytes encodes the bytes of data with the static model, in one batch
+// on the arithmetic coder.
+func encodeBytes(enc coder.SymbolEncoder, data []byte, model coder.Model) error {
+	if enc, ok := enc.(*coder.Encoder); ok {
+		return enc.EncodeBytes(data, model)
+	}
+	for _, b := range data {
+		if err := enc.Encode(int(b), model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeBytes decodes len(dst) bytes written by encodeBytes into dst.
+func decodeBytes(dec coder.SymbolDecoder, dst []byte, model coder.Model) error {
+	if dec, ok := dec.(*coder.Decoder); ok {
+		return dec.DecodeBytes(dst, model)
+	}
+	for i := range dst {
+		b, err := dec.Decode(model)
+		if err != nil {
+			return err
+		}
+		dst[i] = byte(b)
+	}
+	return nil
+}
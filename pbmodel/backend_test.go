@@ -0,0 +1,83 @@
+package pbmodel
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestHuffmanBackendRoundtrip(t *testing.T) {
+	binaryData := make([]byte, 200)
+	for i := range binaryData {
+		binaryData[i] = byte(i * i % 7)
+	}
+
+	testCases := []struct {
+		name string
+		msg  proto.Message
+	}{
+		{"simple", &testdata.SimpleMessage{Id: 12345, Name: "Alice", Active: true}},
+		{"enum", &testdata.MessageWithEnum{Status: testdata.Status_ACTIVE, Description: "active"}},
+		{"bytes", &testdata.MessageWithBytes{Data: binaryData, Label: "binary data"}},
+		{"text bytes", &testdata.MessageWithBytes{Data: []byte("hello world")}},
+		{"map", &testdata.MessageWithMap{
+			Counts: map[string]int32{"apples": 5, "pears": 7},
+			Lookup: map[int32]string{1: "one", 2: "two"},
+		}},
+		{"user profile", createLargeUserProfile()},
+		{"empty", &testdata.SimpleMessage{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var arithmetic, huffman bytes.Buffer
+			if err := CompressWithOptions(tc.msg, &arithmetic, CompressOptions{Backend: Arithmetic}); err != nil {
+				t.Fatalf("Compress failed: %v", err)
+			}
+			if err := CompressWithOptions(tc.msg, &huffman, CompressOptions{Backend: Huffman}); err != nil {
+				t.Fatalf("Compress with Huffman failed: %v", err)
+			}
+			t.Logf("arithmetic %d bytes, Huffman %d bytes", arithmetic.Len(), huffman.Len())
+
+			decoded := tc.msg.ProtoReflect().New().Interface()
+			warnings, err := DecompressWithOptions(&huffman, decoded, DecodeOptions{Backend: Huffman})
+			if err != nil {
+				t.Fatalf("Decompress with Huffman failed: %v", err)
+			}
+			if len(warnings) != 0 {
+				t.Errorf("unexpected warnings: %v", warnings)
+			}
+			if !proto.Equal(tc.msg, decoded) {
+				t.Errorf("Messages don't match.\nOriginal: %v\nDecoded: %v", tc.msg, decoded)
+			}
+		})
+	}
+}
+
+func TestHuffmanBackendTruncated(t *testing.T) {
+	original := createLargeUserProfile()
+
+	var buf bytes.Buffer
+	if err := CompressWithOptions(original, &buf, CompressOptions{Backend: Huffman}); err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	data := buf.Bytes()
+
+	for n := 0; n < len(data); n++ {
+		decoded := &testdata.UserProfile{}
+		_, err := DecompressWithOptions(bytes.NewReader(data[:n]), decoded, DecodeOptions{Backend: Huffman})
+		if !errors.Is(err, ErrTruncated) {
+			t.Fatalf("data truncated to %d of %d bytes: expected ErrTruncated, got %v", n, len(data), err)
+		}
+	}
+
+	decoded := &testdata.UserProfile{}
+	_, err := DecompressWithOptions(bytes.NewReader(append(data, 0)), decoded, DecodeOptions{Backend: Huffman})
+	if !errors.Is(err, ErrTrailingData) {
+		t.Errorf("expected ErrTrailingData, got %v", err)
+	}
+}
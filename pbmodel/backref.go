@@ -0,0 +1,116 @@
+package pbmodel
+
+import (
+	"fmt"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// Lists of messages often hold equal or nearly equal elements, such as the
+// settings of channels that only differ by their index. With back
+// references every element after the first starts with the distance to an
+// earlier element of the list, up to backReferenceWindow elements back, or
+// with zero when it is coded on its own. An element with a reference is
+// coded as a delta against it, see CompressDelta, so only the fields that
+// differ are coded.
+
+// backReferenceWindow is the farthest an element can refer back.
+const backReferenceWindow = 8
+
+// backReferenceModel codes the distance to the referenced element, most
+// often the previous one.
+var backReferenceModel = sync.OnceValue(func() coder.Model {
+	freqs := make([]uint64, backReferenceWindow+1)
+	for i := range freqs {
+		freqs[i] = 1
+	}
+	freqs[0], freqs[1] = 8, 8
+	return models.NewFrequencyTable(freqs)
+})
+
+// WithBackReferences codes elements of lists of messages as the
+// differences to an earlier, similar element of the list.
+func WithBackReferences() Option {
+	return func(o *options) { o.backReferences = true }
+}
+
+// refersBack reports whether elements of the list fd are coded with back
+// references.
+func (mb *ModelBuilder) refersBack(fd protoreflect.FieldDescriptor) bool {
+	return mb.backReferences && fd.Kind() == protoreflect.MessageKind &&
+		mb.wellKnownOf(fd.Message()) == notWellKnown
+}
+
+// chooseBackReference returns the distance to the earlier element of list
+// that element i differs least from, or zero when no element shares enough
+// of its fields to pay off.
+func chooseBackReference(list protoreflect.List, i int) int {
+	elem := list.Get(i).Message()
+	fields := elem.Descriptor().Fields()
+
+	// Coding a set field costs more than the flag telling that it differs,
+	// so a reference pays off when fewer fields differ than are set
+	present := 0
+	for j := 0; j < fields.Len(); j++ {
+		if elem.Has(fields.Get(j)) {
+			present++
+		}
+	}
+
+	best, bestDiffers := 0, present
+	for distance := 1; distance <= min(i, backReferenceWindow); distance++ {
+		ref := list.Get(i - distance).Message()
+		differs := 0
+		for j := 0; j < fields.Len() && differs < bestDiffers; j++ {
+			if !sameField(fields.Get(j), elem, ref) {
+				differs++
+			}
+		}
+		if differs < bestDiffers {
+			best, bestDiffers = distance, differs
+		}
+	}
+	return best
+}
+
+// compressListElement compresses element i of a list of messages, with a
+// back reference.
+func compressListElement(list protoreflect.List, i int, enc coder.SymbolEncoder, mb *ModelBuilder) error {
+	elem := list.Get(i).Message()
+	if i == 0 {
+		return compressMessage(elem, enc, mb)
+	}
+
+	distance := chooseBackReference(list, i)
+	if err := enc.Encode(distance, backReferenceModel()); err != nil {
+		return fmt.Errorf("back reference: %w", err)
+	}
+	if distance == 0 {
+		return compressMessage(elem, enc, mb)
+	}
+	return compressDeltaMessage(elem, list.Get(i-distance).Message(), enc, mb)
+}
+
+// decompressListElement decompresses element i written by
+// compressListElement into elem.
+func decompressListElement(list protoreflect.List, i int, elem protoreflect.Message, dec coder.SymbolDecoder, mb *ModelBuilder) error {
+	if i == 0 {
+		return decompressMessage(elem, dec, mb)
+	}
+
+	distance, err := dec.Decode(backReferenceModel())
+	if err != nil {
+		return fmt.Errorf("back reference: %w", err)
+	}
+	if distance == 0 {
+		return decompressMessage(elem, dec, mb)
+	}
+	if distance > i {
+		return fmt.Errorf("back reference %d before the start of the list", distance)
+	}
+	return decompressDeltaMessage(elem, list.Get(i-distance).Message(), dec, mb)
+}
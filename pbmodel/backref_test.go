@@ -0,0 +1,76 @@
+package pbmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestBackReferences(t *testing.T) {
+	// Channels that only differ by their index, and one that differs
+	// entirely
+	var channels []*testdata.NestedMessage_Inner
+	for i := range 12 {
+		channels = append(channels, &testdata.NestedMessage_Inner{Value: "LongFast mesh channel", Count: int32(i % 3)})
+	}
+	channels[7] = &testdata.NestedMessage_Inner{Value: "Admin", Count: 1000}
+
+	messages := []*testdata.NestedMessage{
+		{InnerList: channels},
+		{InnerList: []*testdata.NestedMessage_Inner{{Value: "one"}}},
+		{InnerList: []*testdata.NestedMessage_Inner{{}, {}, {Value: "x"}, {}}},
+		{Inner: &testdata.NestedMessage_Inner{Value: "single"}, OuterField: "outer"},
+	}
+	for _, backend := range []Backend{Arithmetic, Huffman} {
+		for i, msg := range messages {
+			var buf bytes.Buffer
+			if err := Compress(msg, &buf, WithBackend(backend), WithBackReferences()); err != nil {
+				t.Fatalf("backend %v, message %d: Compress failed: %v", backend, i, err)
+			}
+			decoded := &testdata.NestedMessage{}
+			if err := Decompress(&buf, decoded, WithBackend(backend), WithBackReferences()); err != nil {
+				t.Fatalf("backend %v, message %d: Decompress failed: %v", backend, i, err)
+			}
+			if !proto.Equal(msg, decoded) {
+				t.Errorf("backend %v, message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", backend, i, msg, decoded)
+			}
+		}
+	}
+
+	var plain, referenced bytes.Buffer
+	if err := Compress(messages[0], &plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := Compress(messages[0], &referenced, WithBackReferences()); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("channels: %d bytes, %d bytes with back references", plain.Len(), referenced.Len())
+	if referenced.Len()*3 > plain.Len() {
+		t.Errorf("channels with back references %d bytes, want at most a third of %d bytes", referenced.Len(), plain.Len())
+	}
+}
+
+func TestChooseBackReference(t *testing.T) {
+	msg := &testdata.NestedMessage{InnerList: []*testdata.NestedMessage_Inner{
+		{Value: "a", Count: 1},
+		{Value: "b", Count: 2},
+		{Value: "c", Count: 3},
+		{Value: "a", Count: 1},
+		{Value: "b", Count: 5},
+		{Value: "d", Count: 4},
+	}}
+	list := msg.ProtoReflect().Get(msg.ProtoReflect().Descriptor().Fields().ByName("inner_list")).List()
+	// Element 3 repeats element 0, element 4 differs from element 1 by one
+	// field and element 5 shares no fields with the others
+	for i, want := range []int{1: 0, 2: 0, 3: 3, 4: 3, 5: 0} {
+		if i == 0 {
+			continue
+		}
+		if got := chooseBackReference(list, i); got != want {
+			t.Errorf("element %d: got distance %d, want %d", i, got, want)
+		}
+	}
+}
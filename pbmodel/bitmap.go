@@ -0,0 +1,201 @@
+package pbmodel
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// Large messages are mostly empty: a position has some twenty fields of
+// which a device sets a handful. Coding the presence of every field on its
+// own spends about a bit on each absent field, while the combinations that
+// occur are few. With presence bitmaps the coded fields of a message are
+// split into groups of up to presenceGroupSize fields in field order, and
+// the presence of each group is coded as a single symbol, with bit i set
+// when field i of the group is present, before the values of its present
+// fields.
+//
+// The model of a group starts from the presence models of its fields, where
+// a field without trained models is taken to be present two times in five,
+// and learns the bitmaps as messages of the type are coded, so that fields
+// that are set together become cheap together.
+
+// presenceGroupSize is the number of fields whose presence is coded as one
+// symbol.
+const presenceGroupSize = 8
+
+// Models of the presence bitmaps.
+const (
+	presenceBitmapTotal     = 1 << 12 // Total frequency of a new model
+	presenceBitmapMaxTotal  = 1 << 14 // Total at which the frequencies are halved
+	presenceBitmapIncrement = 256     // Frequency added to a coded bitmap
+	untrainedPresence       = 0.4     // Probability of a field without trained models being present
+)
+
+// WithPresenceBitmaps codes the presence of the fields of a message in
+// groups instead of field by field. With the Huffman backend the models of
+// the groups do not learn, but a group still spends a whole number of bits
+// on all of its fields instead of on each of them.
+func WithPresenceBitmaps() Option {
+	return func(o *options) { o.bitmaps = true }
+}
+
+// presenceBitmaps holds the presence groups of the coded message types.
+type presenceBitmaps struct {
+	adaptive bool // Learn the bitmaps while coding
+	messages map[protoreflect.FullName][]presenceGroup
+}
+
+// presenceGroup holds fields whose presence is coded as one bitmap.
+type presenceGroup struct {
+	fields []protoreflect.FieldDescriptor
+	model  *models.FrequencyTable
+}
+
+func newPresenceBitmaps(adaptive bool) *presenceBitmaps {
+	return &presenceBitmaps{
+		adaptive: adaptive,
+		messages: make(map[protoreflect.FullName][]presenceGroup),
+	}
+}
+
+// presenceGroups returns the presence groups of md, creating them on first
+// use.
+func (mb *ModelBuilder) presenceGroups(md protoreflect.MessageDescriptor) []presenceGroup {
+	if groups, ok := mb.bitmaps.messages[md.FullName()]; ok {
+		return groups
+	}
+
+	var groups []presenceGroup
+	var group []protoreflect.FieldDescriptor
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if mb.skipsField(fields.Get(i)) {
+			continue
+		}
+		group = append(group, fields.Get(i))
+		if len(group) == presenceGroupSize {
+			groups = append(groups, mb.newPresenceGroup(group))
+			group = nil
+		}
+	}
+	if len(group) > 0 {
+		groups = append(groups, mb.newPresenceGroup(group))
+	}
+	mb.bitmaps.messages[md.FullName()] = groups
+	return groups
+}
+
+// newPresenceGroup creates the group of fields, with a model that codes
+// every bitmap as the presence models of the fields would.
+func (mb *ModelBuilder) newPresenceGroup(fields []protoreflect.FieldDescriptor) presenceGroup {
+	freqs := make([]uint64, 1<<len(fields))
+	for bitmap := range freqs {
+		p := float64(presenceBitmapTotal)
+		for i, fd := range fields {
+			p *= mb.presenceProbability(fd, bitmap>>i&1)
+		}
+		freqs[bitmap] = max(1, uint64(p))
+	}
+	model := models.NewFrequencyTable(freqs)
+	model.SetMaxTotal(presenceBitmapMaxTotal)
+	return presenceGroup{fields: fields, model: model}
+}
+
+// presenceProbability returns the probability of the presence marker of fd.
+func (mb *ModelBuilder) presenceProbability(fd protoreflect.FieldDescriptor, present int) float64 {
+	if field := mb.trainedField(fd); field != nil && field.presence != nil {
+		low, high := field.presence.Freq(present)
+		return float64(high-low) / float64(field.presence.TotalFreq())
+	}
+	if present == 1 {
+		return untrainedPresence
+	}
+	return 1 - untrainedPresence
+}
+
+// encodeBitmap encodes the presence bitmap of group.
+func (mb *ModelBuilder) encodeBitmap(enc coder.SymbolEncoder, group presenceGroup, bitmap int) error {
+	if mb.stats != nil {
+		defer mb.stats.presence(mb.stats.enc.Bits())
+	}
+	if err := enc.Encode(bitmap, group.model); err != nil {
+		return err
+	}
+	if mb.bitmaps.adaptive {
+		group.model.Add(bitmap, presenceBitmapIncrement)
+	}
+	return nil
+}
+
+// decodeBitmap decodes a bitmap written by encodeBitmap.
+func (mb *ModelBuilder) decodeBitmap(dec coder.SymbolDecoder, group presenceGroup) (int, error) {
+	bitmap, err := dec.Decode(group.model)
+	if err != nil {
+		return 0, err
+	}
+	if mb.bitmaps.adaptive {
+		group.model.Add(bitmap, presenceBitmapIncrement)
+	}
+	return bitmap, nil
+}
+
+// compressBitmapFields compresses the fields of msg, group by group after
+// the bitmap of their presence.
+func compressBitmapFields(msg protoreflect.Message, enc coder.SymbolEncoder, mb *ModelBuilder) error {
+	for _, group := range mb.presenceGroups(msg.Descriptor()) {
+		bitmap := 0
+		for i, fd := range group.fields {
+			if msg.Has(fd) {
+				bitmap |= 1 << i
+			}
+		}
+		if err := mb.encodeBitmap(enc, group, bitmap); err != nil {
+			return fmt.Errorf("field %s presence: %w", group.fields[0].Name(), err)
+		}
+
+		for i, fd := range group.fields {
+			if bitmap&(1<<i) == 0 {
+				continue
+			}
+			if err := compressUnmarkedField(msg, fd, enc, mb); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compressUnmarkedField compresses a present field of msg without a
+// presence marker.
+func compressUnmarkedField(msg protoreflect.Message, fd protoreflect.FieldDescriptor, enc coder.SymbolEncoder, mb *ModelBuilder) error {
+	if mb.stats != nil {
+		end := mb.stats.enter(fd, true)
+		defer end()
+	}
+	return compressPresentField(msg, fd, enc, mb)
+}
+
+// decompressBitmapFields decompresses fields written by compressBitmapFields
+// into msg.
+func decompressBitmapFields(msg protoreflect.Message, dec coder.SymbolDecoder, mb *ModelBuilder) error {
+	for _, group := range mb.presenceGroups(msg.Descriptor()) {
+		bitmap, err := mb.decodeBitmap(dec, group)
+		if err != nil {
+			return fmt.Errorf("field %s presence: %w", group.fields[0].Name(), err)
+		}
+
+		for i, fd := range group.fields {
+			if bitmap&(1<<i) == 0 {
+				continue
+			}
+			if err := decompressPresentField(msg, fd, dec, mb); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
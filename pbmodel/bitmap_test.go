@@ -0,0 +1,74 @@
+package pbmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestPresenceBitmaps(t *testing.T) {
+	trained, err := TrainModels(trainingSamples(50))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := []proto.Message{
+		&testdata.UserProfile{UserId: 42, Address: &testdata.UserProfile_Address{City: "Tallinn"}},
+		&testdata.NumericMessage{Int32Field: 21, DoubleField: 1013.25},
+		&testdata.NestedMessage{InnerList: []*testdata.NestedMessage_Inner{{Value: "a"}, {}, {Count: 3}}},
+		&testdata.MessageWithMap{Counts: map[string]int32{"a": 1}},
+		&testdata.MessageWithOneof{Value: &testdata.MessageWithOneof_IntValue{IntValue: 7}},
+		&testdata.EmptyMessage{},
+	}
+	variants := map[string][]Option{
+		"arithmetic": {WithPresenceBitmaps()},
+		"huffman":    {WithPresenceBitmaps(), WithBackend(Huffman)},
+		"trained":    {WithPresenceBitmaps(), WithTrainedModels(trained)},
+		"references": {WithPresenceBitmaps(), WithBackReferences()},
+	}
+	for name, opts := range variants {
+		for i, msg := range messages {
+			var buf bytes.Buffer
+			if err := Compress(msg, &buf, opts...); err != nil {
+				t.Fatalf("%s, message %d: Compress failed: %v", name, i, err)
+			}
+			decoded := msg.ProtoReflect().New().Interface()
+			if err := Decompress(&buf, decoded, opts...); err != nil {
+				t.Fatalf("%s, message %d: Decompress failed: %v", name, i, err)
+			}
+			if !proto.Equal(msg, decoded) {
+				t.Errorf("%s, message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", name, i, msg, decoded)
+			}
+		}
+	}
+}
+
+func TestPresenceBitmapsOverhead(t *testing.T) {
+	// Sparse messages, and a list of elements that set the same fields
+	var inner []*testdata.NestedMessage_Inner
+	for i := range 20 {
+		inner = append(inner, &testdata.NestedMessage_Inner{Value: "x", Count: int32(i)})
+	}
+	messages := []proto.Message{
+		&testdata.UserProfile{UserId: 42, Username: "alice"},
+		&testdata.NumericMessage{Int32Field: 21, DoubleField: 1013.25},
+		&testdata.NestedMessage{InnerList: inner},
+	}
+	for i, msg := range messages {
+		var fields, bitmaps Stats
+		var buf bytes.Buffer
+		if err := Compress(msg, &buf, WithStats(&fields)); err != nil {
+			t.Fatal(err)
+		}
+		if err := Compress(msg, &buf, WithStats(&bitmaps), WithPresenceBitmaps()); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("message %d: presence %.1f bits, %.1f bits with bitmaps", i, fields.Presence, bitmaps.Presence)
+		if bitmaps.Presence >= fields.Presence {
+			t.Errorf("message %d: presence %.1f bits with bitmaps, want less than %.1f bits", i, bitmaps.Presence, fields.Presence)
+		}
+	}
+}
@@ -3,55 +3,109 @@
 package pbmodel
 
 import (
+	"encoding/binary"
+	"sync"
+
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 )
 
 // ModelBuilder creates compression models for protobuf messages.
 type ModelBuilder struct {
 	// Models for different data types
-	boolModel    arithcode.Model
-	byteModel    arithcode.Model
-	varintModel  arithcode.Model
-	enumModels   map[string]arithcode.Model
-	englishModel *arithcode.EnglishModel
+	boolModel    coder.Model
+	byteModel    coder.Model
+	varintModel  coder.Model
+	englishModel *models.EnglishModel
+	bytesModel   *models.BytesOrder1Model
+
+	// Field coding selected by the options
+	stringOrder    int                                 // Order of the English model for strings
+	varintBytes    *varintByteModels                   // Varint models by byte position, nil uses varintModel
+	stream         *streamModels                       // Per-field models of a stream, see StreamCompressor
+	trained        *TrainedModelSet                    // Per-field models trained on samples, nil uses the generic ones
+	unknownFields  bool                                // Code unknown fields, see WithUnknownFields
+	listTransforms bool                                // Code integer lists with transforms, see WithListTransforms
+	backReferences bool                                // Code message lists with back references, see WithBackReferences
+	bitmaps        *presenceBitmaps                    // Presence of fields coded in groups, see WithPresenceBitmaps
+	sparseFields   bool                                // Code only the present fields, see WithSparseFields
+	deprecated     *deprecatedFields                   // Fields coded after the others, see WithDeprecatedFields
+	tolerances     map[protoreflect.FullName]Tolerance // Lossy float fields, see WithFloatTolerance
+	canonicalNaNs  bool                                // Code every NaN alike, see WithCanonicalNaNs
+	wellKnown      bool                                // Code well-known types with their own models, see WithWellKnownTypes
+	anyResolver    protoregistry.MessageTypeResolver   // Types of Any values, see WithAnyResolver
+
+	// Seconds of the last Timestamp coded, see WithWellKnownTypes
+	timestampSeconds int64
+	hasTimestamp     bool
+
+	// Type URLs of Any messages, most recently coded first
+	typeURLs []string
+
+	// Nesting depth of the message being coded, see MaxDepth
+	depth int
+
+	// Cost of the fields being compressed, see WithStats
+	stats *statsRecorder
+
+	// Reused buffer for decoding, see setScalar
+	scratch []byte
+
+	// Bounds of decompressed messages, see WithLimits
+	limits Limits
+
+	// Decoding anomalies, see DecodeOptions
+	strictness Strictness
+	warnings   []error
 }
 
 // NewModelBuilder creates a new protobuf model builder.
 func NewModelBuilder() *ModelBuilder {
 	return &ModelBuilder{
-		boolModel:    arithcode.NewUniformModel(2), // true/false
-		byteModel:    arithcode.NewUniformModel(256),
-		varintModel:  createVarintModel(),
-		enumModels:   make(map[string]arithcode.Model),
-		englishModel: arithcode.NewEnglishModel(),
+		boolModel:    models.SharedUniformModel(2), // true/false
+		byteModel:    models.SharedUniformModel(256),
+		varintModel:  sharedVarintModel(),
+		englishModel: models.SharedEnglishModelEOS(),
+		bytesModel:   models.NewBytesOrder1Model(),
+		limits:       DefaultLimits,
 	}
 }
 
 // BoolModel returns the boolean model.
-func (mb *ModelBuilder) BoolModel() arithcode.Model {
+func (mb *ModelBuilder) BoolModel() coder.Model {
 	return mb.boolModel
 }
 
 // ByteModel returns the byte model.
-func (mb *ModelBuilder) ByteModel() arithcode.Model {
+func (mb *ModelBuilder) ByteModel() coder.Model {
 	return mb.byteModel
 }
 
 // VarintModel returns the varint model.
-func (mb *ModelBuilder) VarintModel() arithcode.Model {
+func (mb *ModelBuilder) VarintModel() coder.Model {
 	return mb.varintModel
 }
 
 // EnglishModel returns the English text model.
-func (mb *ModelBuilder) EnglishModel() *arithcode.EnglishModel {
+// The model includes an end-of-string symbol for direct string coding.
+func (mb *ModelBuilder) EnglishModel() *models.EnglishModel {
 	return mb.englishModel
 }
 
+// BytesModel returns the adaptive order-1 model for binary bytes fields.
+func (mb *ModelBuilder) BytesModel() *models.BytesOrder1Model {
+	return mb.bytesModel
+}
+
+// sharedVarintModel returns the varint model shared by all builders.
+var sharedVarintModel = sync.OnceValue(createVarintModel)
+
 // createVarintModel creates a model optimized for variable-length integers.
 // Small integers are more common in practice, so we give them higher probability.
-func createVarintModel() arithcode.Model {
+func createVarintModel() coder.Model {
 	// Create a model that favors smaller values
 	// This is a simplified model; a more sophisticated one could be adaptive
 	freqs := make([]uint64, 256)
@@ -64,26 +118,28 @@ func createVarintModel() arithcode.Model {
 	for i := 128; i < 256; i++ {
 		freqs[i] = 10 // Larger values less likely
 	}
-	return arithcode.NewFrequencyTable(freqs)
+	return models.NewFrequencyTable(freqs)
 }
 
 // GetEnumModel returns a model for the given enum type.
-// Enum models assume uniform distribution across enum values.
-func (mb *ModelBuilder) GetEnumModel(ed protoreflect.EnumDescriptor) arithcode.Model {
-	fullName := string(ed.FullName())
-	if model, ok := mb.enumModels[fullName]; ok {
-		return model
+// Enum models assume uniform distribution across enum values, see EnumModel.
+// The varint byte models keep the models without the escape.
+func (mb *ModelBuilder) GetEnumModel(ed protoreflect.EnumDescriptor) coder.Model {
+	if mb.varintBytes != nil {
+		return models.SharedUniformModel(ed.Values().Len())
 	}
+	return EnumModel(ed.Values().Len())
+}
 
-	// Create uniform model for enum values
-	numValues := ed.Values().Len()
-	model := arithcode.NewUniformModel(numValues)
-	mb.enumModels[fullName] = model
-	return model
+// reset restores the initial state of the builder for coding the next
+// message, keeping the models it has created.
+func (mb *ModelBuilder) reset() {
+	mb.bytesModel.Reset()
+	mb.warnings = nil
 }
 
 // GetFieldModel returns the appropriate model for a protobuf field.
-func (mb *ModelBuilder) GetFieldModel(fd protoreflect.FieldDescriptor) arithcode.Model {
+func (mb *ModelBuilder) GetFieldModel(fd protoreflect.FieldDescriptor) coder.Model {
 	switch fd.Kind() {
 	case protoreflect.BoolKind:
 		return mb.boolModel
@@ -133,8 +189,15 @@ func (mb *ModelBuilder) GetFieldModel(fd protoreflect.FieldDescriptor) arithcode
 
 // EncodeVarint encodes an integer as a variable-length quantity.
 // Returns the bytes to encode with the varint model.
+//
+// The buffer has a constant capacity, so that callers that do not keep it
+// get it on the stack once EncodeVarint is inlined.
 func EncodeVarint(value uint64) []byte {
-	var buf []byte
+	return appendVarint(make([]byte, 0, binary.MaxVarintLen64), value)
+}
+
+// appendVarint appends the variable-length encoding of value to buf.
+func appendVarint(buf []byte, value uint64) []byte {
 	for {
 		b := byte(value & 0x7F)
 		value >>= 7
@@ -143,10 +206,9 @@ func EncodeVarint(value uint64) []byte {
 		}
 		buf = append(buf, b)
 		if value == 0 {
-			break
+			return buf
 		}
 	}
-	return buf
 }
 
 // DecodeVarint decodes a variable-length quantity.
@@ -0,0 +1,111 @@
+package pbmodel
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// Compressed data has no redundancy, so a flipped bit, as a lossy radio link
+// may deliver, usually decodes into a plausible message with wrong values
+// instead of failing. A checksum turns that into an error: WithChecksum codes
+// the CRC-32C of the deterministic protobuf encoding of the message after
+// it, and decompression compares it with the checksum of the decoded
+// message.
+
+// ErrChecksum is returned when the decoded message does not match the
+// checksum of the original message.
+var ErrChecksum = errors.New("pbmodel: checksum mismatch")
+
+// checksumTable is the CRC-32C polynomial table.
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// checksumSize is the size of a checksum in bytes.
+const checksumSize = 4
+
+// WithChecksum appends a CRC-32C of the message to the compressed data and
+// verifies it on decompression, at a cost of 4 bytes. Unknown fields are
+// left out of the checksum unless WithUnknownFields keeps them. The checksum
+// needs lossless compression, so it cannot be combined with float
+// tolerances, canonical NaNs or dropped deprecated fields.
+func WithChecksum() Option {
+	return func(o *options) { o.checksum = true }
+}
+
+// checkChecksum verifies that the options keep the messages intact, so
+// that the checksum of the decoded message matches.
+func (o *options) checkChecksum() error {
+	if !o.checksum {
+		return nil
+	}
+	if len(o.tolerances) > 0 {
+		return fmt.Errorf("pbmodel: checksum with lossy float tolerances")
+	}
+	if o.canonicalNaNs {
+		return fmt.Errorf("pbmodel: checksum with canonical NaNs")
+	}
+	if o.deprecated != nil && o.deprecated.drop {
+		return fmt.Errorf("pbmodel: checksum with dropped deprecated fields")
+	}
+	return nil
+}
+
+// messageChecksum returns the checksum of msg. With discardUnknown the
+// unknown fields, which are not compressed, are left out.
+func messageChecksum(msg proto.Message, discardUnknown bool) (uint32, error) {
+	marshal := proto.MarshalOptions{Deterministic: true}
+	data, err := marshal.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+	if discardUnknown {
+		known := msg.ProtoReflect().New().Interface()
+		if err := (proto.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, known); err != nil {
+			return 0, err
+		}
+		if data, err = marshal.Marshal(known); err != nil {
+			return 0, err
+		}
+	}
+	return crc32.Checksum(data, checksumTable), nil
+}
+
+// encodeChecksum encodes the checksum of msg.
+func (mb *ModelBuilder) encodeChecksum(enc coder.SymbolEncoder, msg proto.Message) error {
+	sum, err := messageChecksum(msg, !mb.unknownFields)
+	if err != nil {
+		return fmt.Errorf("checksum: %w", err)
+	}
+	for i := checksumSize - 1; i >= 0; i-- {
+		if err := enc.Encode(int(sum>>(8*i))&0xff, models.SharedUniformModel(256)); err != nil {
+			return fmt.Errorf("checksum: %w", err)
+		}
+	}
+	return nil
+}
+
+// decodeChecksum decodes a checksum and verifies it against the decoded
+// msg.
+func (mb *ModelBuilder) decodeChecksum(dec coder.SymbolDecoder, msg proto.Message) error {
+	var want uint32
+	for i := 0; i < checksumSize; i++ {
+		b, err := dec.Decode(models.SharedUniformModel(256))
+		if err != nil {
+			return fmt.Errorf("checksum: %w", err)
+		}
+		want = want<<8 | uint32(b)
+	}
+	got, err := messageChecksum(msg, false)
+	if err != nil {
+		return fmt.Errorf("checksum: %w", err)
+	}
+	if got != want {
+		return fmt.Errorf("checksum %08x, want %08x: %w", got, want, ErrChecksum)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package pbmodel
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestChecksum(t *testing.T) {
+	msg := &testdata.NumericMessage{
+		Int32Field:  -42,
+		Uint32Field: 1_000_000,
+		Sint64Field: -7,
+		FloatField:  3.25,
+		DoubleField: 59.437,
+	}
+
+	for _, backend := range []Backend{Arithmetic, Huffman} {
+		var plain, summed bytes.Buffer
+		if err := Compress(msg, &plain, WithBackend(backend)); err != nil {
+			t.Fatal(err)
+		}
+		if err := Compress(msg, &summed, WithBackend(backend), WithChecksum()); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("backend %d: %d bytes, with checksum %d bytes", backend, plain.Len(), summed.Len())
+
+		result := &testdata.NumericMessage{}
+		if err := Decompress(bytes.NewReader(summed.Bytes()), result, WithBackend(backend), WithChecksum()); err != nil {
+			t.Fatalf("backend %d: Decompress failed: %v", backend, err)
+		}
+		if !proto.Equal(msg, result) {
+			t.Errorf("backend %d: got %v, want %v", backend, result, msg)
+		}
+
+		// Every flipped bit either fails or, in the padding, decodes the
+		// original message
+		data := summed.Bytes()
+		detected := 0
+		for bit := 0; bit < 8*len(data); bit++ {
+			corrupted := bytes.Clone(data)
+			corrupted[bit/8] ^= 1 << (bit % 8)
+			result := &testdata.NumericMessage{}
+			err := Decompress(bytes.NewReader(corrupted), result, WithBackend(backend), WithChecksum())
+			if err == nil && !proto.Equal(msg, result) {
+				t.Errorf("backend %d: bit %d flipped: decoded %v", backend, bit, result)
+			}
+			if errors.Is(err, ErrChecksum) {
+				detected++
+			}
+		}
+		if detected == 0 {
+			t.Errorf("backend %d: no flipped bit failed the checksum", backend)
+		}
+	}
+}
+
+func TestChecksumUnknownFields(t *testing.T) {
+	msg := &testdata.SimpleMessage{Id: 1, Name: "node"}
+	msg.ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, 99, protowire.VarintType), 7))
+
+	// Without WithUnknownFields the unknown fields are not compressed and
+	// not part of the checksum
+	for _, opts := range [][]Option{
+		{WithChecksum()},
+		{WithChecksum(), WithUnknownFields()},
+	} {
+		var buf bytes.Buffer
+		if err := Compress(msg, &buf, opts...); err != nil {
+			t.Fatal(err)
+		}
+		if err := Decompress(&buf, &testdata.SimpleMessage{}, opts...); err != nil {
+			t.Errorf("Decompress with %d options failed: %v", len(opts), err)
+		}
+	}
+}
+
+func TestChecksumLossy(t *testing.T) {
+	for _, opt := range []Option{
+		WithFloatTolerance("testdata.NumericMessage.float_field", Tolerance{Absolute: 0.1}),
+		WithDeprecatedFields(true, "testdata.SimpleMessage.name"),
+	} {
+		if err := Compress(&testdata.NumericMessage{}, &bytes.Buffer{}, WithChecksum(), opt); err == nil {
+			t.Error("checksum with lossy compression accepted")
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package pbmodel
+
+import (
+	"errors"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// Codec compresses and decompresses messages in the format of Compress and
+// Decompress, reusing its models and arithmetic coder between messages.
+//
+// Once the first messages have created the models, coding a message does not
+// allocate, apart from the decoded strings and bytes themselves and the
+// nested messages, lists and maps that hold them. This suits
+// memory constrained targets, such as microcontrollers running TinyGo, where
+// a long-lived Codec can be allocated once at startup.
+//
+// A Codec must not be used concurrently.
+type Codec struct {
+	mb  *ModelBuilder
+	enc *coder.Encoder
+	dec *coder.Decoder
+
+	// Coders of generated compressors, kept so that passing them to the
+	// generated code does not allocate
+	genEnc GenEncoder
+	genDec GenDecoder
+
+	// header holds the format header, so that writing it does not allocate
+	header [HeaderSize]byte
+}
+
+// NewCodec creates a codec.
+func NewCodec() *Codec {
+	return &Codec{mb: NewModelBuilder()}
+}
+
+// Compress compresses msg to w, like Compress.
+func (c *Codec) Compress(msg proto.Message, w io.Writer) error {
+	if err := c.startEncoding(w); err != nil {
+		return err
+	}
+	if err := compressMessage(msg.ProtoReflect(), c.enc, c.mb); err != nil {
+		return err
+	}
+	return c.enc.Close()
+}
+
+// Decompress decompresses data written by Compress into msg, like
+// Decompress.
+func (c *Codec) Decompress(r io.Reader, msg proto.Message) error {
+	if err := c.startDecoding(r); err != nil {
+		return err
+	}
+	proto.Reset(msg)
+	if err := decompressMessage(msg.ProtoReflect(), c.dec, c.mb); err != nil {
+		return err
+	}
+	return c.mb.finish(c.dec)
+}
+
+// startEncoding resets the models and writes the format header to w,
+// followed by the data of the encoder.
+func (c *Codec) startEncoding(w io.Writer) error {
+	c.mb.reset()
+	putHeader(&c.header, formatMessage, 0)
+	if _, err := w.Write(c.header[:]); err != nil {
+		return err
+	}
+	if c.enc == nil {
+		c.enc = coder.NewEncoder(w)
+	} else {
+		c.enc.Reset(w)
+	}
+	return nil
+}
+
+// startDecoding resets the models and reads the format header from r,
+// followed by the data of the decoder.
+func (c *Codec) startDecoding(r io.Reader) error {
+	c.mb.reset()
+	if err := readHeader(r, &c.header, formatMessage, 0); err != nil {
+		return err
+	}
+	if c.dec == nil {
+		dec, err := newDecoder(r)
+		if err != nil {
+			return err
+		}
+		c.dec = dec
+	} else if err := c.dec.Reset(r); err != nil {
+		if errors.Is(err, io.EOF) {
+			return ErrTruncated
+		}
+		return err
+	}
+	return nil
+}
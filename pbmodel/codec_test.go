@@ -0,0 +1,111 @@
+package pbmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestCodecMatchesCompress(t *testing.T) {
+	binaryData := make([]byte, 200)
+	for i := range binaryData {
+		binaryData[i] = byte(i * i % 7)
+	}
+
+	// The bytes messages repeat, so that a codec that keeps its adaptive
+	// state between messages would produce different output
+	messages := []proto.Message{
+		&testdata.SimpleMessage{Id: 12345, Name: "Alice", Active: true},
+		&testdata.MessageWithBytes{Data: binaryData, Label: "binary data"},
+		&testdata.MessageWithEnum{Status: testdata.Status_ACTIVE, Description: "active"},
+		&testdata.MessageWithBytes{Data: binaryData, Label: "binary data"},
+		&testdata.MessageWithBytes{Data: []byte("hello world")},
+		&testdata.SimpleMessage{},
+	}
+
+	codec := NewCodec()
+	for i, msg := range messages {
+		var want, got bytes.Buffer
+		if err := Compress(msg, &want); err != nil {
+			t.Fatalf("message %d: Compress failed: %v", i, err)
+		}
+		if err := codec.Compress(msg, &got); err != nil {
+			t.Fatalf("message %d: Codec.Compress failed: %v", i, err)
+		}
+		if !bytes.Equal(want.Bytes(), got.Bytes()) {
+			t.Fatalf("message %d: Codec.Compress output differs from Compress", i)
+		}
+
+		decoded := msg.ProtoReflect().New().Interface()
+		if err := codec.Decompress(&got, decoded); err != nil {
+			t.Fatalf("message %d: Codec.Decompress failed: %v", i, err)
+		}
+		if !proto.Equal(msg, decoded) {
+			t.Errorf("message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", i, msg, decoded)
+		}
+	}
+
+	if err := codec.Decompress(bytes.NewReader(nil), &testdata.SimpleMessage{}); err != ErrTruncated {
+		t.Errorf("Decompress of empty input: got %v, want ErrTruncated", err)
+	}
+}
+
+func TestCodecAllocations(t *testing.T) {
+	msg := &testdata.MessageWithBytes{Data: []byte{0, 0, 1, 2, 3, 0xFF}, Label: "binary data"}
+	codec := NewCodec()
+	var buf bytes.Buffer
+	buf.Grow(256)
+
+	compress := func() {
+		buf.Reset()
+		if err := codec.Compress(msg, &buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	compress()
+	if allocs := testing.AllocsPerRun(100, compress); allocs != 0 {
+		t.Errorf("Codec.Compress allocates %v times per message, want 0", allocs)
+	}
+}
+
+func TestCodecDecompressAllocations(t *testing.T) {
+	msg := &testdata.NumericMessage{
+		Int32Field:    -42,
+		Int64Field:    1 << 40,
+		Uint32Field:   1_000_000,
+		Uint64Field:   1 << 50,
+		Sint32Field:   -300,
+		Sint64Field:   -7,
+		Fixed32Field:  0xdeadbeef,
+		Fixed64Field:  1 << 60,
+		Sfixed32Field: -5,
+		Sfixed64Field: -1 << 40,
+		FloatField:    3.25,
+		DoubleField:   59.437,
+	}
+	codec := NewCodec()
+	var buf bytes.Buffer
+	if err := codec.Compress(msg, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	r := bytes.NewReader(nil)
+	decoded := &testdata.NumericMessage{}
+	decompress := func() {
+		r.Reset(buf.Bytes())
+		decoded.Reset()
+		if err := codec.Decompress(r, decoded); err != nil {
+			t.Fatal(err)
+		}
+	}
+	decompress()
+	if !proto.Equal(msg, decoded) {
+		t.Fatalf("roundtrip mismatch.\nOriginal: %v\nDecoded: %v", msg, decoded)
+	}
+	if allocs := testing.AllocsPerRun(100, decompress); allocs != 0 {
+		t.Errorf("Codec.Decompress allocates %v times per numeric message, want 0", allocs)
+	}
+}
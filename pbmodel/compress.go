@@ -5,81 +5,205 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"math"
+	"unicode"
+	"unicode/utf8"
 
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/huffman"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 )
 
 // Compress compresses a protobuf message using arithmetic coding.
-func Compress(msg proto.Message, w io.Writer) error {
-	mb := NewModelBuilder()
-	enc := arithcode.NewEncoder(w)
+// The options select the models of the fields, see Option. The output is
+// canonical: equal messages compress to the same bytes, see RangeMapSorted.
+// It starts with a format header, see WithoutHeader.
+func Compress(msg proto.Message, w io.Writer, opts ...Option) error {
+	if len(opts) == 0 {
+		// Without options the format is the one of Codec, whose models can
+		// be reused
+		codec := codecPool.Get().(*Codec)
+		defer codecPool.Put(codec)
+		return codec.Compress(msg, w)
+	}
 
-	if err := compressMessage(msg.ProtoReflect(), enc, mb); err != nil {
+	o, err := collectOptions(opts)
+	if err != nil {
+		return err
+	}
+	if err := o.writeHeader(w, formatMessage); err != nil {
 		return err
 	}
+	mb := newModelBuilderWithOptions(o)
 
+	if o.backend == Huffman {
+		enc := huffman.NewEncoder(w)
+		if err := compressRoot(msg, enc, mb, o, compressMessage); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	enc := coder.NewEncoder(w)
+	if o.stats != nil {
+		mb.stats = newStatsRecorder(o.stats, enc)
+	}
+	if err := compressRoot(msg, enc, mb, o, compressMessage); err != nil {
+		return err
+	}
+	if o.stats != nil {
+		o.stats.Total = enc.Bits()
+	}
 	return enc.Close()
 }
 
+// compressRoot compresses the top-level message with compress, usually
+// compressMessage, together with its schema fingerprint and checksum.
+func compressRoot(msg proto.Message, enc coder.SymbolEncoder, mb *ModelBuilder, o options, compress func(protoreflect.Message, coder.SymbolEncoder, *ModelBuilder) error) error {
+	if o.fingerprint {
+		if err := encodeFingerprint(enc, msg.ProtoReflect().Descriptor()); err != nil {
+			return err
+		}
+	}
+	if err := compress(msg.ProtoReflect(), enc, mb); err != nil {
+		return err
+	}
+	if o.checksum {
+		return mb.encodeChecksum(enc, msg)
+	}
+	return nil
+}
+
 // compressMessage recursively compresses a protobuf message.
-func compressMessage(msg protoreflect.Message, enc *arithcode.Encoder, mb *ModelBuilder) error {
+func compressMessage(msg protoreflect.Message, enc coder.SymbolEncoder, mb *ModelBuilder) error {
+	if mb.depth >= mb.limits.MaxDepth {
+		return depthError(mb.limits.MaxDepth)
+	}
+	mb.depth++
+	defer func() { mb.depth-- }()
+
 	md := msg.Descriptor()
+	if typ := mb.wellKnownOf(md); typ != notWellKnown {
+		if err := mb.encodeWellKnown(enc, msg, typ); err != nil {
+			return fmt.Errorf("%s: %w", md.Name(), err)
+		}
+		if mb.unknownFields {
+			return mb.encodeUnknown(enc, msg)
+		}
+		return nil
+	}
 	fields := md.Fields()
 
-	// Iterate through all fields in order
-	for i := 0; i < fields.Len(); i++ {
-		fd := fields.Get(i)
-
-		if !msg.Has(fd) {
-			// Field not set, encode a "not present" marker
-			if err := enc.Encode(0, mb.boolModel); err != nil {
-				return fmt.Errorf("field %s presence: %w", fd.Name(), err)
+	switch {
+	case mb.bitmaps != nil:
+		if err := compressBitmapFields(msg, enc, mb); err != nil {
+			return err
+		}
+	case mb.sparseFields:
+		if err := compressSparseFields(msg, enc, mb); err != nil {
+			return err
+		}
+	default:
+		// Iterate through all fields in order
+		for i := 0; i < fields.Len(); i++ {
+			if mb.skipsField(fields.Get(i)) {
+				continue
+			}
+			if err := compressField(msg, fields.Get(i), enc, mb); err != nil {
+				return err
 			}
-			continue
 		}
+	}
+
+	if err := mb.encodeDeprecated(enc, msg); err != nil {
+		return err
+	}
+	if mb.unknownFields {
+		return mb.encodeUnknown(enc, msg)
+	}
+	return nil
+}
 
-		// Field is present
-		if err := enc.Encode(1, mb.boolModel); err != nil {
+// encodeRawBytes encodes data of a known length.
+func (mb *ModelBuilder) encodeRawBytes(enc coder.SymbolEncoder, data []byte) error {
+	if enc, ok := enc.(*coder.Encoder); ok {
+		return mb.bytesModel.Encode(enc, data)
+	}
+	// The order-1 model adapts, other backends need static models
+	return encodeBytes(enc, data, mb.byteModel)
+}
+
+// compressField compresses the presence and the value of a field of msg.
+func compressField(msg protoreflect.Message, fd protoreflect.FieldDescriptor, enc coder.SymbolEncoder, mb *ModelBuilder) error {
+	if mb.stats != nil {
+		end := mb.stats.enter(fd, msg.Has(fd))
+		defer end()
+	}
+
+	if !msg.Has(fd) {
+		// Field not set, encode a "not present" marker
+		if err := mb.encodePresence(enc, fd, 0); err != nil {
 			return fmt.Errorf("field %s presence: %w", fd.Name(), err)
 		}
+		return nil
+	}
 
-		value := msg.Get(fd)
+	// Field is present
+	if err := mb.encodePresence(enc, fd, 1); err != nil {
+		return fmt.Errorf("field %s presence: %w", fd.Name(), err)
+	}
+	return compressPresentField(msg, fd, enc, mb)
+}
 
-		if fd.IsList() {
-			if err := compressRepeatedField(fd, value.List(), enc, mb); err != nil {
-				return fmt.Errorf("field %s: %w", fd.Name(), err)
-			}
-		} else if fd.IsMap() {
-			if err := compressMapField(fd, value.Map(), enc, mb); err != nil {
-				return fmt.Errorf("field %s: %w", fd.Name(), err)
-			}
-		} else {
-			if err := compressFieldValue(fd, value, enc, mb); err != nil {
-				return fmt.Errorf("field %s: %w", fd.Name(), err)
-			}
+// compressPresentField compresses the value of a field of msg that is
+// present.
+func compressPresentField(msg protoreflect.Message, fd protoreflect.FieldDescriptor, enc coder.SymbolEncoder, mb *ModelBuilder) error {
+	value := msg.Get(fd)
+
+	if fd.IsList() {
+		if err := compressRepeatedField(fd, value.List(), enc, mb); err != nil {
+			return fmt.Errorf("field %s: %w", fd.Name(), err)
+		}
+	} else if fd.IsMap() {
+		if err := compressMapField(fd, value.Map(), enc, mb); err != nil {
+			return fmt.Errorf("field %s: %w", fd.Name(), err)
+		}
+	} else if mb.stream != nil && streamsScalar(fd) {
+		if err := compressStreamScalar(fd, value, enc, mb); err != nil {
+			return fmt.Errorf("field %s: %w", fd.Name(), err)
+		}
+	} else {
+		if err := compressFieldValue(fd, value, enc, mb); err != nil {
+			return fmt.Errorf("field %s: %w", fd.Name(), err)
 		}
 	}
-
 	return nil
 }
 
 // compressRepeatedField compresses a repeated field.
-func compressRepeatedField(fd protoreflect.FieldDescriptor, list protoreflect.List, enc *arithcode.Encoder, mb *ModelBuilder) error {
+func compressRepeatedField(fd protoreflect.FieldDescriptor, list protoreflect.List, enc coder.SymbolEncoder, mb *ModelBuilder) error {
 	// Encode the length
 	length := list.Len()
-	lengthBytes := EncodeVarint(uint64(length))
-	for _, b := range lengthBytes {
-		if err := enc.Encode(int(b), mb.varintModel); err != nil {
-			return fmt.Errorf("list length: %w", err)
+	if err := mb.encodeVarint(enc, uint64(length)); err != nil {
+		return fmt.Errorf("list length: %w", err)
+	}
+	if mb.transformsList(fd, length) {
+		if done, err := mb.encodeListTransform(enc, fd, list); done || err != nil {
+			return err
 		}
 	}
 
 	// Encode each element
+	refersBack := mb.refersBack(fd)
 	for i := 0; i < length; i++ {
+		if refersBack {
+			if err := compressListElement(list, i, enc, mb); err != nil {
+				return fmt.Errorf("list element %d: %w", i, err)
+			}
+			continue
+		}
 		value := list.Get(i)
 		if err := compressFieldValue(fd, value, enc, mb); err != nil {
 			return fmt.Errorf("list element %d: %w", i, err)
@@ -90,14 +214,11 @@ func compressRepeatedField(fd protoreflect.FieldDescriptor, list protoreflect.Li
 }
 
 // compressMapField compresses a map field.
-func compressMapField(fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *arithcode.Encoder, mb *ModelBuilder) error {
+func compressMapField(fd protoreflect.FieldDescriptor, m protoreflect.Map, enc coder.SymbolEncoder, mb *ModelBuilder) error {
 	// Encode the length
 	length := m.Len()
-	lengthBytes := EncodeVarint(uint64(length))
-	for _, b := range lengthBytes {
-		if err := enc.Encode(int(b), mb.varintModel); err != nil {
-			return fmt.Errorf("map length: %w", err)
-		}
+	if err := mb.encodeVarint(enc, uint64(length)); err != nil {
+		return fmt.Errorf("map length: %w", err)
 	}
 
 	// Get key and value descriptors
@@ -106,7 +227,7 @@ func compressMapField(fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *
 
 	// Encode each key-value pair
 	var encodeErr error
-	m.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+	RangeMapSorted(m, func(k protoreflect.MapKey, v protoreflect.Value) bool {
 		// Encode key
 		if err := compressFieldValue(keyFd, k.Value(), enc, mb); err != nil {
 			encodeErr = fmt.Errorf("map key: %w", err)
@@ -126,64 +247,44 @@ func compressMapField(fd protoreflect.FieldDescriptor, m protoreflect.Map, enc *
 }
 
 // compressFieldValue compresses a single field value.
-func compressFieldValue(fd protoreflect.FieldDescriptor, value protoreflect.Value, enc *arithcode.Encoder, mb *ModelBuilder) error {
+func compressFieldValue(fd protoreflect.FieldDescriptor, value protoreflect.Value, enc coder.SymbolEncoder, mb *ModelBuilder) error {
+	if hint := lookupHint(fd); hint != nil {
+		return mb.encodeHinted(enc, fd, hint, value)
+	}
+	if tolerance := mb.tolerance(fd); tolerance != nil {
+		return mb.encodeQuantized(enc, fd, tolerance, value)
+	}
+
 	switch fd.Kind() {
 	case protoreflect.BoolKind:
 		b := 0
 		if value.Bool() {
 			b = 1
 		}
-		return enc.Encode(b, mb.boolModel)
+		return encodeLearned(enc, b, mb.boolValueModel(fd))
 
 	case protoreflect.EnumKind:
-		// Encode enum as its index in the enum descriptor
-		enumValue := value.Enum()
-		enumDesc := fd.Enum()
-		enumValueDesc := enumDesc.Values().ByNumber(enumValue)
-		if enumValueDesc == nil {
-			// Unknown enum value, encode as -1 (will need special handling)
-			return fmt.Errorf("unknown enum value: %d", enumValue)
-		}
-		idx := enumValueDesc.Index()
-		return enc.Encode(idx, mb.GetEnumModel(enumDesc))
+		return mb.encodeEnum(enc, fd.Enum(), value.Enum(), mb.enumModel(fd))
 
 	case protoreflect.Int32Kind, protoreflect.Int64Kind:
 		val := value.Int()
-		bytes := EncodeVarint(uint64(val))
-		for _, b := range bytes {
-			if err := enc.Encode(int(b), mb.varintModel); err != nil {
-				return err
-			}
-		}
-		return nil
+		return mb.encodeFieldVarint(enc, fd, uint64(val))
 
 	case protoreflect.Uint32Kind, protoreflect.Uint64Kind:
 		val := value.Uint()
-		bytes := EncodeVarint(val)
-		for _, b := range bytes {
-			if err := enc.Encode(int(b), mb.varintModel); err != nil {
-				return err
-			}
-		}
-		return nil
+		return mb.encodeFieldVarint(enc, fd, val)
 
 	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
 		val := value.Int()
 		zigzag := ZigzagEncode(val)
-		bytes := EncodeVarint(zigzag)
-		for _, b := range bytes {
-			if err := enc.Encode(int(b), mb.varintModel); err != nil {
-				return err
-			}
-		}
-		return nil
+		return mb.encodeFieldVarint(enc, fd, zigzag)
 
 	case protoreflect.Fixed32Kind:
 		val := uint32(value.Uint())
 		bytes := make([]byte, 4)
 		binary.LittleEndian.PutUint32(bytes, val)
-		for _, b := range bytes {
-			if err := enc.Encode(int(b), mb.byteModel); err != nil {
+		for i, b := range bytes {
+			if err := encodeLearned(enc, int(b), mb.fixedByteModel(fd, i)); err != nil {
 				return err
 			}
 		}
@@ -193,8 +294,8 @@ func compressFieldValue(fd protoreflect.FieldDescriptor, value protoreflect.Valu
 		val := int32(value.Int())
 		bytes := make([]byte, 4)
 		binary.LittleEndian.PutUint32(bytes, uint32(val))
-		for _, b := range bytes {
-			if err := enc.Encode(int(b), mb.byteModel); err != nil {
+		for i, b := range bytes {
+			if err := encodeLearned(enc, int(b), mb.fixedByteModel(fd, i)); err != nil {
 				return err
 			}
 		}
@@ -204,8 +305,8 @@ func compressFieldValue(fd protoreflect.FieldDescriptor, value protoreflect.Valu
 		val := value.Uint()
 		bytes := make([]byte, 8)
 		binary.LittleEndian.PutUint64(bytes, val)
-		for _, b := range bytes {
-			if err := enc.Encode(int(b), mb.byteModel); err != nil {
+		for i, b := range bytes {
+			if err := encodeLearned(enc, int(b), mb.fixedByteModel(fd, i)); err != nil {
 				return err
 			}
 		}
@@ -215,75 +316,43 @@ func compressFieldValue(fd protoreflect.FieldDescriptor, value protoreflect.Valu
 		val := int64(value.Int())
 		bytes := make([]byte, 8)
 		binary.LittleEndian.PutUint64(bytes, uint64(val))
-		for _, b := range bytes {
-			if err := enc.Encode(int(b), mb.byteModel); err != nil {
+		for i, b := range bytes {
+			if err := encodeLearned(enc, int(b), mb.fixedByteModel(fd, i)); err != nil {
 				return err
 			}
 		}
 		return nil
 
 	case protoreflect.FloatKind:
-		val := float32(value.Float())
-		bits := math.Float32bits(val)
+		bits := uint32(mb.codedBits(fd, value.Float()))
 		bytes := make([]byte, 4)
 		binary.LittleEndian.PutUint32(bytes, bits)
-		for _, b := range bytes {
-			if err := enc.Encode(int(b), mb.byteModel); err != nil {
+		for i, b := range bytes {
+			if err := encodeLearned(enc, int(b), mb.fixedByteModel(fd, i)); err != nil {
 				return err
 			}
 		}
 		return nil
 
 	case protoreflect.DoubleKind:
-		val := value.Float()
-		bits := math.Float64bits(val)
+		bits := mb.codedBits(fd, value.Float())
 		bytes := make([]byte, 8)
 		binary.LittleEndian.PutUint64(bytes, bits)
-		for _, b := range bytes {
-			if err := enc.Encode(int(b), mb.byteModel); err != nil {
+		for i, b := range bytes {
+			if err := encodeLearned(enc, int(b), mb.fixedByteModel(fd, i)); err != nil {
 				return err
 			}
 		}
 		return nil
 
 	case protoreflect.StringKind:
-		// Use the English model for strings
-		str := value.String()
-		var buf bytes.Buffer
-		if err := arithcode.EncodeString(str, &buf); err != nil {
-			return err
+		if mb.stream != nil {
+			return mb.stream.strings.encodeString(enc, mb, value.String())
 		}
-		// Encode the compressed string bytes
-		compressedBytes := buf.Bytes()
-		lengthBytes := EncodeVarint(uint64(len(compressedBytes)))
-		for _, b := range lengthBytes {
-			if err := enc.Encode(int(b), mb.varintModel); err != nil {
-				return err
-			}
-		}
-		for _, b := range compressedBytes {
-			if err := enc.Encode(int(b), mb.byteModel); err != nil {
-				return err
-			}
-		}
-		return nil
+		return mb.encodeString(enc, value.String())
 
 	case protoreflect.BytesKind:
-		data := value.Bytes()
-		// Encode length
-		lengthBytes := EncodeVarint(uint64(len(data)))
-		for _, b := range lengthBytes {
-			if err := enc.Encode(int(b), mb.varintModel); err != nil {
-				return err
-			}
-		}
-		// Encode bytes
-		for _, b := range data {
-			if err := enc.Encode(int(b), mb.byteModel); err != nil {
-				return err
-			}
-		}
-		return nil
+		return mb.encodeBytesValue(enc, value.Bytes())
 
 	case protoreflect.MessageKind:
 		// Recursively compress the nested message
@@ -293,3 +362,95 @@ func compressFieldValue(fd protoreflect.FieldDescriptor, value protoreflect.Valu
 		return fmt.Errorf("unsupported field kind: %v", fd.Kind())
 	}
 }
+
+// encodeString encodes the value of a string field.
+func (mb *ModelBuilder) encodeString(enc coder.SymbolEncoder, str string) error {
+	if n := len(str); n > MaxStringLength {
+		return fmt.Errorf("string length %d exceeds MaxStringLength", n)
+	}
+	if mb.stringOrder > 0 || mb.varintBytes != nil {
+		return mb.encodeStringOrderN(enc, str)
+	}
+	// Code the string directly with the English model; the end-of-string
+	// symbol replaces the length prefix.
+	return models.EncodeStringEOS(enc, mb.englishModel, str)
+}
+
+// encodeBytesValue encodes the value of a bytes field.
+func (mb *ModelBuilder) encodeBytesValue(enc coder.SymbolEncoder, data []byte) error {
+	if len(data) > MaxBytesLength {
+		return fmt.Errorf("bytes length %d exceeds MaxBytesLength", len(data))
+	}
+
+	// The varint variants code bytes with the byte model after their
+	// length, as they did before bytes fields had a text flag
+	if mb.varintBytes != nil {
+		if err := mb.encodeVarint(enc, uint64(len(data))); err != nil {
+			return err
+		}
+		return encodeBytes(enc, data, mb.byteModel)
+	}
+
+	// Bytes holding text are coded like strings, anything else with
+	// the order-1 bytes model
+	isText := IsText(data)
+	textFlag := 0
+	if isText {
+		textFlag = 1
+	}
+	if err := enc.Encode(textFlag, mb.boolModel); err != nil {
+		return err
+	}
+	if isText {
+		return models.EncodeBytesEOS(enc, mb.englishModel, data)
+	}
+
+	// Encode length
+	if err := mb.encodeVarint(enc, uint64(len(data))); err != nil {
+		return err
+	}
+	return mb.encodeRawBytes(enc, data)
+}
+
+// encodeStringOrderN encodes a string with the string coder of its order,
+// prefixed with the length of its output. The varint variants code strings
+// of every order this way, with the order-0 and bigram string coders they
+// were defined with.
+func (mb *ModelBuilder) encodeStringOrderN(enc coder.SymbolEncoder, str string) error {
+	var buf bytes.Buffer
+	var err error
+	switch {
+	case mb.stringOrder == 0:
+		err = models.EncodeString(str, &buf)
+	case mb.stringOrder == 1:
+		err = models.EncodeStringOrder1(str, &buf)
+	case mb.varintBytes != nil:
+		err = models.EncodeStringBigram(str, &buf)
+	default:
+		err = models.EncodeStringOrder2(str, &buf)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := mb.encodeVarint(enc, uint64(buf.Len())); err != nil {
+		return err
+	}
+	return encodeBytes(enc, buf.Bytes(), mb.byteModel)
+}
+
+// IsText reports whether data looks like text: valid UTF-8, non-empty and
+// without control characters other than whitespace.
+func IsText(data []byte) bool {
+	if len(data) == 0 || !utf8.Valid(data) {
+		return false
+	}
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+		data = data[size:]
+	}
+	return true
+}
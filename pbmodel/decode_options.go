@@ -0,0 +1,80 @@
+package pbmodel
+
+import (
+	"errors"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Strictness selects how decompression handles anomalies in the compressed
+// data, such as values that do not fit their field.
+type Strictness int
+
+const (
+	// Strict decompression fails on any anomaly.
+	Strict Strictness = iota
+	// Permissive decompression applies a fallback where one exists and
+	// reports the anomaly as a warning. Anomalies without a fallback, such
+	// as corrupted lengths, still fail.
+	Permissive
+)
+
+var (
+	// ErrOutOfRange is reported for decoded values that do not fit the
+	// field type. Permissive decompression truncates them like protobuf
+	// does for mismatched integer types.
+	ErrOutOfRange = errors.New("pbmodel: value out of range")
+
+	// ErrTrailingData is reported when the input continues after the end of
+	// the compressed message. Permissive decompression ignores the rest.
+	ErrTrailingData = errors.New("pbmodel: trailing data after compressed message")
+)
+
+// DecodeOptions configures DecompressWithOptions.
+type DecodeOptions struct {
+	Strictness Strictness
+	// Backend must match the backend the data was compressed with.
+	Backend Backend
+	// Limits bound the decompressed message; zero fields keep the
+	// DefaultLimits, see WithLimits.
+	Limits Limits
+}
+
+// DecompressWithOptions decompresses data written by Compress or
+// CompressWithOptions into msg.
+// In permissive mode it returns the anomalies it recovered from as
+// warnings, which wrap ErrOutOfRange or ErrTrailingData.
+func DecompressWithOptions(r io.Reader, msg proto.Message, opts DecodeOptions) (warnings []error, err error) {
+	limits := opts.Limits.withDefaults()
+	if err := limits.check(); err != nil {
+		return nil, err
+	}
+	return decompress(r, msg, options{backend: opts.Backend, limits: limits}, opts.Strictness)
+}
+
+// anomaly handles an anomaly in the decoded data. In strict mode it returns
+// err; in permissive mode it records err as a warning and returns nil, after
+// which the caller applies its fallback.
+func (mb *ModelBuilder) anomaly(err error) error {
+	if mb.strictness == Strict {
+		return err
+	}
+	mb.warnings = append(mb.warnings, err)
+	return nil
+}
+
+// finish verifies the end of a compressed message.
+func (mb *ModelBuilder) finish(dec streamDecoder) error {
+	if err := dec.Finish(); err != nil {
+		return err
+	}
+	trailing, err := dec.HasTrailingData()
+	if err != nil {
+		return err
+	}
+	if trailing {
+		return mb.anomaly(ErrTrailingData)
+	}
+	return nil
+}
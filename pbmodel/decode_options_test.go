@@ -0,0 +1,157 @@
+package pbmodel
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+// outOfRangeStream returns a stream for a SimpleMessage whose id does not fit
+// in an int32.
+func outOfRangeStream(t *testing.T, id uint64) []byte {
+	t.Helper()
+
+	mb := NewModelBuilder()
+	var header [HeaderSize]byte
+	putHeader(&header, formatMessage, 0)
+	buf := bytes.NewBuffer(header[:])
+	enc := coder.NewEncoder(buf)
+	encode := func(symbol int, model coder.Model) {
+		if err := enc.Encode(symbol, model); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+
+	encode(1, mb.boolModel)
+	for _, b := range EncodeVarint(id) {
+		encode(int(b), mb.varintModel)
+	}
+	encode(0, mb.boolModel) // name
+	encode(0, mb.boolModel) // active
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeOutOfRange(t *testing.T) {
+	data := outOfRangeStream(t, 1<<40|7)
+
+	decoded := &testdata.SimpleMessage{}
+	_, err := DecompressWithOptions(bytes.NewReader(data), decoded, DecodeOptions{Strictness: Strict})
+	if !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("strict: expected ErrOutOfRange, got %v", err)
+	}
+
+	decoded = &testdata.SimpleMessage{}
+	warnings, err := DecompressWithOptions(bytes.NewReader(data), decoded, DecodeOptions{Strictness: Permissive})
+	if err != nil {
+		t.Fatalf("permissive: unexpected error: %v", err)
+	}
+	if len(warnings) != 1 || !errors.Is(warnings[0], ErrOutOfRange) {
+		t.Fatalf("permissive: expected one ErrOutOfRange warning, got %v", warnings)
+	}
+	if decoded.Id != 7 {
+		t.Errorf("permissive: expected truncated id 7, got %d", decoded.Id)
+	}
+}
+
+func TestDecodeTrailingData(t *testing.T) {
+	original := &testdata.SimpleMessage{Id: 42, Name: "trailing", Active: true}
+
+	var buf bytes.Buffer
+	if err := Compress(original, &buf); err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	data := append(buf.Bytes(), 0x00, 0x5A)
+
+	decoded := &testdata.SimpleMessage{}
+	_, err := DecompressWithOptions(bytes.NewReader(data), decoded, DecodeOptions{Strictness: Strict})
+	if !errors.Is(err, ErrTrailingData) {
+		t.Fatalf("strict: expected ErrTrailingData, got %v", err)
+	}
+
+	decoded = &testdata.SimpleMessage{}
+	warnings, err := DecompressWithOptions(bytes.NewReader(data), decoded, DecodeOptions{Strictness: Permissive})
+	if err != nil {
+		t.Fatalf("permissive: unexpected error: %v", err)
+	}
+	if len(warnings) != 1 || !errors.Is(warnings[0], ErrTrailingData) {
+		t.Fatalf("permissive: expected one ErrTrailingData warning, got %v", warnings)
+	}
+	if decoded.Id != original.Id || decoded.Name != original.Name || decoded.Active != original.Active {
+		t.Errorf("permissive: expected %v, got %v", original, decoded)
+	}
+}
+
+func TestDecompressLen(t *testing.T) {
+	messages := []*testdata.SimpleMessage{
+		{Id: 42, Name: "first", Active: true},
+		{},
+		{Id: -7, Name: "third"},
+		{Id: 1 << 30, Name: "a longer name, for a message that spans more bytes"},
+	}
+
+	for _, opts := range [][]Option{nil, {WithBackend(Huffman)}, {WithChecksum()}, {WithStringOrder(2)}} {
+		var buf bytes.Buffer
+		var lengths []int
+		for _, msg := range messages {
+			before := buf.Len()
+			if err := Compress(msg, &buf, opts...); err != nil {
+				t.Fatalf("Compress failed: %v", err)
+			}
+			lengths = append(lengths, buf.Len()-before)
+		}
+
+		data := buf.Bytes()
+		for i, msg := range messages {
+			decoded := &testdata.SimpleMessage{}
+			n, err := DecompressLen(data, decoded, opts...)
+			if err != nil {
+				t.Fatalf("message %d: DecompressLen failed: %v", i, err)
+			}
+			if n != lengths[i] {
+				t.Errorf("message %d: consumed %d bytes, want %d", i, n, lengths[i])
+			}
+			if !proto.Equal(msg, decoded) {
+				t.Errorf("message %d: got %v, want %v", i, decoded, msg)
+			}
+			data = data[n:]
+		}
+		if len(data) != 0 {
+			t.Errorf("%d bytes left", len(data))
+		}
+	}
+
+	if _, err := DecompressLen(nil, &testdata.SimpleMessage{}); !errors.Is(err, ErrTruncated) {
+		t.Errorf("empty data: got %v, want ErrTruncated", err)
+	}
+}
+
+func TestDecodeOptionsValid(t *testing.T) {
+	original := &testdata.SimpleMessage{Id: -5, Name: "valid", Active: true}
+
+	var buf bytes.Buffer
+	if err := Compress(original, &buf); err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+
+	for _, strictness := range []Strictness{Strict, Permissive} {
+		decoded := &testdata.SimpleMessage{}
+		warnings, err := DecompressWithOptions(bytes.NewReader(buf.Bytes()), decoded, DecodeOptions{Strictness: strictness})
+		if err != nil {
+			t.Fatalf("strictness %d: unexpected error: %v", strictness, err)
+		}
+		if len(warnings) != 0 {
+			t.Errorf("strictness %d: unexpected warnings: %v", strictness, warnings)
+		}
+		if decoded.Id != original.Id || decoded.Name != original.Name {
+			t.Errorf("strictness %d: expected %v, got %v", strictness, original, decoded)
+		}
+	}
+}
@@ -2,93 +2,280 @@ package pbmodel
 
 import (
 	"bytes"
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"math"
+	"slices"
 
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/dynamicpb"
 
-	"github.com/egonelbre/exp-protobuf-compression/arithcode"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
 )
 
+// ErrTruncated is returned by the decompressors when the compressed data
+// ends in the middle of a message.
+var ErrTruncated = coder.ErrTruncated
+
+// newDecoder creates a decoder for a compressed message. Empty input is
+// reported as ErrTruncated, every compressed message has at least one byte.
+func newDecoder(r io.Reader) (*coder.Decoder, error) {
+	dec, err := coder.NewDecoder(r)
+	if errors.Is(err, io.EOF) {
+		return nil, ErrTruncated
+	}
+	return dec, err
+}
+
 // Decompress decompresses data into a protobuf message using arithmetic coding.
-func Decompress(r io.Reader, msg proto.Message) error {
-	mb := NewModelBuilder()
-	dec, err := arithcode.NewDecoder(r)
+// The options must match the ones the data was compressed with.
+// It fails on any anomaly in the data, see DecompressWithOptions.
+// The message is reset first, see DecompressMerge to keep its fields.
+func Decompress(r io.Reader, msg proto.Message, opts ...Option) error {
+	if len(opts) == 0 {
+		codec := codecPool.Get().(*Codec)
+		defer codecPool.Put(codec)
+		return codec.Decompress(r, msg)
+	}
+
+	o, err := collectOptions(opts)
 	if err != nil {
 		return err
 	}
+	_, err = decompress(r, msg, o, Strict)
+	return err
+}
 
-	return decompressMessage(msg.ProtoReflect(), dec, mb)
+// DecompressMerge decompresses data written by Compress and merges it into
+// msg with the semantics of proto.Merge: singular fields are replaced,
+// messages merged and list elements and map entries appended. msg is left
+// unchanged when decompression fails.
+func DecompressMerge(r io.Reader, msg proto.Message, opts ...Option) error {
+	decoded := msg.ProtoReflect().New().Interface()
+	if err := Decompress(r, decoded, opts...); err != nil {
+		return err
+	}
+	proto.Merge(msg, decoded)
+	return nil
 }
 
-// decompressMessage recursively decompresses a protobuf message.
-func decompressMessage(msg protoreflect.Message, dec *arithcode.Decoder, mb *ModelBuilder) error {
-	md := msg.Descriptor()
-	fields := md.Fields()
+// DecompressLen decompresses the message at the start of data, written by
+// Compress with the same options, into msg and returns the length of its
+// compressed data. Unlike Decompress it ignores the data that follows the
+// message, so that messages compressed one after another can be
+// decompressed from a single buffer.
+func DecompressLen(data []byte, msg proto.Message, opts ...Option) (int, error) {
+	o, err := collectOptions(opts)
+	if err != nil {
+		return 0, err
+	}
+	proto.Reset(msg)
+	mb := newModelBuilderWithOptions(o)
 
-	// Iterate through all fields in order
-	for i := 0; i < fields.Len(); i++ {
-		fd := fields.Get(i)
+	r := bytes.NewReader(data)
+	if err := o.readHeader(r, formatMessage); err != nil {
+		return 0, err
+	}
+	headerLen := len(data) - r.Len()
+	dec, err := newBackendDecoder(r, o.backend)
+	if err != nil {
+		return 0, err
+	}
+	if err := decompressRoot(msg, dec, mb, o, decompressMessage); err != nil {
+		return 0, err
+	}
+	if err := dec.Finish(); err != nil {
+		return 0, err
+	}
+	return headerLen + dec.Len(), nil
+}
 
-		// Decode presence marker
-		present, err := dec.Decode(mb.boolModel)
-		if err != nil {
-			return fmt.Errorf("field %s presence: %w", fd.Name(), err)
+// decompress decompresses data into msg. It returns the anomalies it
+// recovered from in permissive mode.
+func decompress(r io.Reader, msg proto.Message, o options, strictness Strictness) (warnings []error, err error) {
+	proto.Reset(msg)
+	mb := newModelBuilderWithOptions(o)
+	mb.strictness = strictness
+
+	if err := o.readHeader(r, formatMessage); err != nil {
+		return nil, err
+	}
+	dec, err := newBackendDecoder(r, o.backend)
+	if err != nil {
+		return nil, err
+	}
+	if err := decompressRoot(msg, dec, mb, o, decompressMessage); err != nil {
+		return mb.warnings, err
+	}
+	return mb.warnings, mb.finish(dec)
+}
+
+// decompressRoot decompresses the top-level message written by
+// compressRoot into msg with decompress, the counterpart of the compress
+// function, and verifies its schema fingerprint and checksum.
+func decompressRoot(msg proto.Message, dec coder.SymbolDecoder, mb *ModelBuilder, o options, decompress func(protoreflect.Message, coder.SymbolDecoder, *ModelBuilder) error) error {
+	if o.fingerprint {
+		if err := decodeFingerprint(dec, msg.ProtoReflect().Descriptor()); err != nil {
+			return err
 		}
+	}
+	if err := decompress(msg.ProtoReflect(), dec, mb); err != nil {
+		return err
+	}
+	if o.checksum {
+		return mb.decodeChecksum(dec, msg)
+	}
+	return nil
+}
+
+// decompressMessage recursively decompresses a protobuf message.
+func decompressMessage(msg protoreflect.Message, dec coder.SymbolDecoder, mb *ModelBuilder) error {
+	if mb.depth >= mb.limits.MaxDepth {
+		return depthError(mb.limits.MaxDepth)
+	}
+	mb.depth++
+	defer func() { mb.depth-- }()
 
-		if present == 0 {
-			// Field not present, skip
-			continue
+	md := msg.Descriptor()
+	if typ := mb.wellKnownOf(md); typ != notWellKnown {
+		if err := mb.decodeWellKnown(dec, msg, typ); err != nil {
+			return fmt.Errorf("%s: %w", md.Name(), err)
 		}
+		if mb.unknownFields {
+			return mb.decodeUnknown(dec, msg)
+		}
+		return nil
+	}
+	fields := md.Fields()
 
-		if fd.IsList() {
-			list := msg.Mutable(fd).List()
-			if err := decompressRepeatedField(fd, list, dec, mb); err != nil {
-				return fmt.Errorf("field %s: %w", fd.Name(), err)
-			}
-		} else if fd.IsMap() {
-			m := msg.Mutable(fd).Map()
-			if err := decompressMapField(fd, m, dec, mb); err != nil {
-				return fmt.Errorf("field %s: %w", fd.Name(), err)
-			}
-		} else if fd.Kind() == protoreflect.MessageKind {
-			// For message fields, decompress directly into the mutable field
-			// to preserve the concrete type
-			nestedMsg := msg.Mutable(fd).Message()
-			if err := decompressMessage(nestedMsg, dec, mb); err != nil {
-				return fmt.Errorf("field %s: %w", fd.Name(), err)
+	switch {
+	case mb.bitmaps != nil:
+		if err := decompressBitmapFields(msg, dec, mb); err != nil {
+			return err
+		}
+	case mb.sparseFields:
+		if err := decompressSparseFields(msg, dec, mb); err != nil {
+			return err
+		}
+	default:
+		// Iterate through all fields in order
+		for i := 0; i < fields.Len(); i++ {
+			if mb.skipsField(fields.Get(i)) {
+				continue
 			}
-		} else {
-			value, err := decompressFieldValue(fd, dec, mb)
-			if err != nil {
-				return fmt.Errorf("field %s: %w", fd.Name(), err)
+			if err := decompressField(msg, fields.Get(i), dec, mb); err != nil {
+				return err
 			}
-			msg.Set(fd, value)
 		}
 	}
 
+	deprecated, err := mb.decodeDeprecated(dec, msg)
+	if err != nil {
+		return err
+	}
+	if mb.unknownFields {
+		if err := mb.decodeUnknown(dec, msg); err != nil {
+			return err
+		}
+	}
+	return mergeDeprecated(msg, deprecated)
+}
+
+// decodeRawBytes decodes length bytes written by encodeRawBytes.
+func (mb *ModelBuilder) decodeRawBytes(dec coder.SymbolDecoder, length int) ([]byte, error) {
+	if dec, ok := dec.(*coder.Decoder); ok {
+		return mb.bytesModel.Decode(dec, length)
+	}
+	data := make([]byte, length)
+	if err := decodeBytes(dec, data, mb.byteModel); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// decompressField decompresses a field written by compressField into msg.
+func decompressField(msg protoreflect.Message, fd protoreflect.FieldDescriptor, dec coder.SymbolDecoder, mb *ModelBuilder) error {
+	// Decode presence marker
+	present, err := mb.decodePresence(dec, fd)
+	if err != nil {
+		return fmt.Errorf("field %s presence: %w", fd.Name(), err)
+	}
+
+	if present == 0 {
+		// Field not present, skip
+		return nil
+	}
+	return decompressPresentField(msg, fd, dec, mb)
+}
+
+// decompressPresentField decompresses the value of a field written by
+// compressPresentField into msg.
+func decompressPresentField(msg protoreflect.Message, fd protoreflect.FieldDescriptor, dec coder.SymbolDecoder, mb *ModelBuilder) error {
+	if fd.IsList() {
+		list := msg.Mutable(fd).List()
+		if err := decompressRepeatedField(fd, list, dec, mb); err != nil {
+			return fmt.Errorf("field %s: %w", fd.Name(), err)
+		}
+	} else if fd.IsMap() {
+		m := msg.Mutable(fd).Map()
+		if err := decompressMapField(fd, m, dec, mb); err != nil {
+			return fmt.Errorf("field %s: %w", fd.Name(), err)
+		}
+	} else if fd.Kind() == protoreflect.MessageKind {
+		// For message fields, decompress directly into the mutable field
+		// to preserve the concrete type
+		nestedMsg := msg.Mutable(fd).Message()
+		if err := decompressMessage(nestedMsg, dec, mb); err != nil {
+			return fmt.Errorf("field %s: %w", fd.Name(), err)
+		}
+	} else if mb.stream != nil && streamsScalar(fd) {
+		value, err := decompressStreamScalar(fd, dec, mb)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", fd.Name(), err)
+		}
+		return mb.setScalar(msg, fd, value)
+	} else {
+		value, err := decompressFieldValue(fd, dec, mb)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", fd.Name(), err)
+		}
+		return mb.setScalar(msg, fd, value)
+	}
 	return nil
 }
 
 // decompressRepeatedField decompresses a repeated field.
-func decompressRepeatedField(fd protoreflect.FieldDescriptor, list protoreflect.List, dec *arithcode.Decoder, mb *ModelBuilder) error {
+func decompressRepeatedField(fd protoreflect.FieldDescriptor, list protoreflect.List, dec coder.SymbolDecoder, mb *ModelBuilder) error {
 	// Decode the length
-	length, err := decodeVarintFromDecoder(dec, mb.varintModel)
+	length, err := mb.decodeVarint(dec)
 	if err != nil {
 		return fmt.Errorf("list length: %w", err)
 	}
+	if err := checkLimit("list length", length, mb.limits.MaxListLen); err != nil {
+		return err
+	}
+	if mb.transformsList(fd, int(length)) {
+		if done, err := mb.decodeListTransform(dec, fd, list, int(length)); done || err != nil {
+			return err
+		}
+	}
 
 	// Decode each element
+	refersBack := mb.refersBack(fd)
 	for i := 0; i < int(length); i++ {
 		// For message fields, we need to create the element through the list
 		// to get the proper concrete type, not a dynamic message
 		if fd.Kind() == protoreflect.MessageKind {
 			elem := list.NewElement()
-			if err := decompressMessage(elem.Message(), dec, mb); err != nil {
+			if refersBack {
+				err = decompressListElement(list, i, elem.Message(), dec, mb)
+			} else {
+				err = decompressMessage(elem.Message(), dec, mb)
+			}
+			if err != nil {
 				return fmt.Errorf("list element %d: %w", i, err)
 			}
 			list.Append(elem)
@@ -105,12 +292,15 @@ func decompressRepeatedField(fd protoreflect.FieldDescriptor, list protoreflect.
 }
 
 // decompressMapField decompresses a map field.
-func decompressMapField(fd protoreflect.FieldDescriptor, m protoreflect.Map, dec *arithcode.Decoder, mb *ModelBuilder) error {
+func decompressMapField(fd protoreflect.FieldDescriptor, m protoreflect.Map, dec coder.SymbolDecoder, mb *ModelBuilder) error {
 	// Decode the length
-	length, err := decodeVarintFromDecoder(dec, mb.varintModel)
+	length, err := mb.decodeVarint(dec)
 	if err != nil {
 		return fmt.Errorf("map length: %w", err)
 	}
+	if err := checkLimit("map length", length, mb.limits.MaxListLen); err != nil {
+		return err
+	}
 
 	// Get key and value descriptors
 	keyFd := fd.MapKey()
@@ -137,62 +327,82 @@ func decompressMapField(fd protoreflect.FieldDescriptor, m protoreflect.Map, dec
 }
 
 // decompressFieldValue decompresses a single field value.
-func decompressFieldValue(fd protoreflect.FieldDescriptor, dec *arithcode.Decoder, mb *ModelBuilder) (protoreflect.Value, error) {
+func decompressFieldValue(fd protoreflect.FieldDescriptor, dec coder.SymbolDecoder, mb *ModelBuilder) (protoreflect.Value, error) {
+	if hint := lookupHint(fd); hint != nil {
+		return mb.decodeHinted(dec, fd, hint)
+	}
+	if tolerance := mb.tolerance(fd); tolerance != nil {
+		return mb.decodeQuantized(dec, fd, tolerance)
+	}
+
 	switch fd.Kind() {
 	case protoreflect.BoolKind:
-		b, err := dec.Decode(mb.boolModel)
+		b, err := decodeLearned(dec, mb.boolValueModel(fd))
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
 		return protoreflect.ValueOfBool(b != 0), nil
 
 	case protoreflect.EnumKind:
-		enumDesc := fd.Enum()
-		idx, err := dec.Decode(mb.GetEnumModel(enumDesc))
+		number, err := mb.decodeEnum(dec, fd.Enum(), mb.enumModel(fd))
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
-		enumValueDesc := enumDesc.Values().Get(idx)
-		return protoreflect.ValueOfEnum(enumValueDesc.Number()), nil
+		return protoreflect.ValueOfEnum(number), nil
 
 	case protoreflect.Int32Kind:
-		val, err := decodeVarintFromDecoder(dec, mb.varintModel)
+		val, err := mb.decodeFieldVarint(dec, fd)
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
+		if int64(val) < math.MinInt32 || int64(val) > math.MaxInt32 {
+			if err := mb.anomaly(fmt.Errorf("field %s value %d: %w", fd.FullName(), int64(val), ErrOutOfRange)); err != nil {
+				return protoreflect.Value{}, err
+			}
+		}
 		return protoreflect.ValueOfInt32(int32(val)), nil
 
 	case protoreflect.Int64Kind:
-		val, err := decodeVarintFromDecoder(dec, mb.varintModel)
+		val, err := mb.decodeFieldVarint(dec, fd)
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
 		return protoreflect.ValueOfInt64(int64(val)), nil
 
 	case protoreflect.Uint32Kind:
-		val, err := decodeVarintFromDecoder(dec, mb.varintModel)
+		val, err := mb.decodeFieldVarint(dec, fd)
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
+		if val > math.MaxUint32 {
+			if err := mb.anomaly(fmt.Errorf("field %s value %d: %w", fd.FullName(), val, ErrOutOfRange)); err != nil {
+				return protoreflect.Value{}, err
+			}
+		}
 		return protoreflect.ValueOfUint32(uint32(val)), nil
 
 	case protoreflect.Uint64Kind:
-		val, err := decodeVarintFromDecoder(dec, mb.varintModel)
+		val, err := mb.decodeFieldVarint(dec, fd)
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
 		return protoreflect.ValueOfUint64(val), nil
 
 	case protoreflect.Sint32Kind:
-		zigzag, err := decodeVarintFromDecoder(dec, mb.varintModel)
+		zigzag, err := mb.decodeFieldVarint(dec, fd)
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
 		val := ZigzagDecode(zigzag)
+		if val < math.MinInt32 || val > math.MaxInt32 {
+			if err := mb.anomaly(fmt.Errorf("field %s value %d: %w", fd.FullName(), val, ErrOutOfRange)); err != nil {
+				return protoreflect.Value{}, err
+			}
+		}
 		return protoreflect.ValueOfInt32(int32(val)), nil
 
 	case protoreflect.Sint64Kind:
-		zigzag, err := decodeVarintFromDecoder(dec, mb.varintModel)
+		zigzag, err := mb.decodeFieldVarint(dec, fd)
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
@@ -200,121 +410,65 @@ func decompressFieldValue(fd protoreflect.FieldDescriptor, dec *arithcode.Decode
 		return protoreflect.ValueOfInt64(val), nil
 
 	case protoreflect.Fixed32Kind:
-		bytes := make([]byte, 4)
-		for i := 0; i < 4; i++ {
-			b, err := dec.Decode(mb.byteModel)
-			if err != nil {
-				return protoreflect.Value{}, err
-			}
-			bytes[i] = byte(b)
+		val, err := mb.decodeFixed(dec, fd, 4)
+		if err != nil {
+			return protoreflect.Value{}, err
 		}
-		val := binary.LittleEndian.Uint32(bytes)
-		return protoreflect.ValueOfUint32(val), nil
+		return protoreflect.ValueOfUint32(uint32(val)), nil
 
 	case protoreflect.Sfixed32Kind:
-		bytes := make([]byte, 4)
-		for i := 0; i < 4; i++ {
-			b, err := dec.Decode(mb.byteModel)
-			if err != nil {
-				return protoreflect.Value{}, err
-			}
-			bytes[i] = byte(b)
+		val, err := mb.decodeFixed(dec, fd, 4)
+		if err != nil {
+			return protoreflect.Value{}, err
 		}
-		val := binary.LittleEndian.Uint32(bytes)
 		return protoreflect.ValueOfInt32(int32(val)), nil
 
 	case protoreflect.Fixed64Kind:
-		bytes := make([]byte, 8)
-		for i := 0; i < 8; i++ {
-			b, err := dec.Decode(mb.byteModel)
-			if err != nil {
-				return protoreflect.Value{}, err
-			}
-			bytes[i] = byte(b)
+		val, err := mb.decodeFixed(dec, fd, 8)
+		if err != nil {
+			return protoreflect.Value{}, err
 		}
-		val := binary.LittleEndian.Uint64(bytes)
 		return protoreflect.ValueOfUint64(val), nil
 
 	case protoreflect.Sfixed64Kind:
-		bytes := make([]byte, 8)
-		for i := 0; i < 8; i++ {
-			b, err := dec.Decode(mb.byteModel)
-			if err != nil {
-				return protoreflect.Value{}, err
-			}
-			bytes[i] = byte(b)
+		val, err := mb.decodeFixed(dec, fd, 8)
+		if err != nil {
+			return protoreflect.Value{}, err
 		}
-		val := binary.LittleEndian.Uint64(bytes)
 		return protoreflect.ValueOfInt64(int64(val)), nil
 
 	case protoreflect.FloatKind:
-		bytes := make([]byte, 4)
-		for i := 0; i < 4; i++ {
-			b, err := dec.Decode(mb.byteModel)
-			if err != nil {
-				return protoreflect.Value{}, err
-			}
-			bytes[i] = byte(b)
+		val, err := mb.decodeFixed(dec, fd, 4)
+		if err != nil {
+			return protoreflect.Value{}, err
 		}
-		bits := binary.LittleEndian.Uint32(bytes)
-		val := math.Float32frombits(bits)
-		return protoreflect.ValueOfFloat32(val), nil
+		return protoreflect.ValueOfFloat32(math.Float32frombits(uint32(val))), nil
 
 	case protoreflect.DoubleKind:
-		bytes := make([]byte, 8)
-		for i := 0; i < 8; i++ {
-			b, err := dec.Decode(mb.byteModel)
-			if err != nil {
-				return protoreflect.Value{}, err
-			}
-			bytes[i] = byte(b)
-		}
-		bits := binary.LittleEndian.Uint64(bytes)
-		val := math.Float64frombits(bits)
-		return protoreflect.ValueOfFloat64(val), nil
-
-	case protoreflect.StringKind:
-		// Decode the compressed string length
-		compressedLen, err := decodeVarintFromDecoder(dec, mb.varintModel)
+		val, err := mb.decodeFixed(dec, fd, 8)
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
+		return protoreflect.ValueOfFloat64(math.Float64frombits(val)), nil
 
-		// Decode the compressed bytes
-		compressedBytes := make([]byte, compressedLen)
-		for i := 0; i < int(compressedLen); i++ {
-			b, err := dec.Decode(mb.byteModel)
-			if err != nil {
-				return protoreflect.Value{}, err
-			}
-			compressedBytes[i] = byte(b)
+	case protoreflect.StringKind:
+		var str string
+		var err error
+		if mb.stream != nil {
+			str, err = mb.stream.strings.decodeString(dec, mb)
+		} else {
+			str, err = mb.decodeString(dec)
 		}
-
-		// Decompress the string using the English model
-		str, err := arithcode.DecodeString(bytes.NewReader(compressedBytes))
 		if err != nil {
 			return protoreflect.Value{}, err
 		}
-
 		return protoreflect.ValueOfString(str), nil
 
 	case protoreflect.BytesKind:
-		// Decode length
-		length, err := decodeVarintFromDecoder(dec, mb.varintModel)
+		data, err := mb.decodeBytesValue(dec)
 		if err != nil {
-			return protoreflect.Value{}, err
+			return protoreflect.Value{}, fmt.Errorf("field %s: %w", fd.FullName(), err)
 		}
-
-		// Decode bytes
-		data := make([]byte, length)
-		for i := 0; i < int(length); i++ {
-			b, err := dec.Decode(mb.byteModel)
-			if err != nil {
-				return protoreflect.Value{}, err
-			}
-			data[i] = byte(b)
-		}
-
 		return protoreflect.ValueOfBytes(data), nil
 
 	case protoreflect.MessageKind:
@@ -331,8 +485,97 @@ func decompressFieldValue(fd protoreflect.FieldDescriptor, dec *arithcode.Decode
 	}
 }
 
+// decodeString decodes a string written by encodeString.
+func (mb *ModelBuilder) decodeString(dec coder.SymbolDecoder) (string, error) {
+	if mb.stringOrder > 0 || mb.varintBytes != nil {
+		return mb.decodeStringOrderN(dec)
+	}
+	// Strings are coded directly and terminated by the end-of-string symbol
+	str, err := models.DecodeStringEOSLimit(dec, mb.englishModel, mb.limits.MaxStringLen)
+	if errors.Is(err, models.ErrStringTooLong) {
+		return "", fmt.Errorf("string length exceeds limit %d: %w", mb.limits.MaxStringLen, ErrLimit)
+	}
+	return str, err
+}
+
+// decodeBytesValue decodes a value written by encodeBytesValue.
+func (mb *ModelBuilder) decodeBytesValue(dec coder.SymbolDecoder) ([]byte, error) {
+	if mb.varintBytes != nil {
+		length, err := mb.decodeVarint(dec)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkLimit("bytes length", length, mb.limits.MaxBytes); err != nil {
+			return nil, err
+		}
+		data := make([]byte, length)
+		if err := decodeBytes(dec, data, mb.byteModel); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	textFlag, err := dec.Decode(mb.boolModel)
+	if err != nil {
+		return nil, err
+	}
+	if textFlag == 1 {
+		str, err := models.DecodeStringEOSLimit(dec, mb.englishModel, mb.limits.MaxBytes)
+		if errors.Is(err, models.ErrStringTooLong) {
+			return nil, fmt.Errorf("bytes length exceeds limit %d: %w", mb.limits.MaxBytes, ErrLimit)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []byte(str), nil
+	}
+
+	// Decode length
+	length, err := mb.decodeVarint(dec)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLimit("bytes length", length, mb.limits.MaxBytes); err != nil {
+		return nil, err
+	}
+	return mb.decodeRawBytes(dec, int(length))
+}
+
+// decodeStringOrderN decodes a string written by encodeStringOrderN.
+func (mb *ModelBuilder) decodeStringOrderN(dec coder.SymbolDecoder) (string, error) {
+	length, err := mb.decodeVarint(dec)
+	if err != nil {
+		return "", err
+	}
+	if err := checkLimit("coded string length", length, mb.limits.MaxBytes); err != nil {
+		return "", err
+	}
+
+	mb.scratch = slices.Grow(mb.scratch[:0], int(length))[:length]
+	compressed := mb.scratch
+	if err := decodeBytes(dec, compressed, mb.byteModel); err != nil {
+		return "", err
+	}
+
+	var str string
+	switch {
+	case mb.stringOrder == 0:
+		str, err = models.DecodeStringLimit(bytes.NewReader(compressed), mb.limits.MaxStringLen)
+	case mb.stringOrder == 1:
+		str, err = models.DecodeStringOrder1Limit(bytes.NewReader(compressed), mb.limits.MaxStringLen)
+	case mb.varintBytes != nil:
+		str, err = models.DecodeStringBigramLimit(bytes.NewReader(compressed), mb.limits.MaxStringLen)
+	default:
+		str, err = models.DecodeStringOrder2Limit(bytes.NewReader(compressed), mb.limits.MaxStringLen)
+	}
+	if errors.Is(err, models.ErrStringTooLong) {
+		return "", fmt.Errorf("string length exceeds limit %d: %w", mb.limits.MaxStringLen, ErrLimit)
+	}
+	return str, err
+}
+
 // decodeVarintFromDecoder decodes a varint using the decoder and model.
-func decodeVarintFromDecoder(dec *arithcode.Decoder, model arithcode.Model) (uint64, error) {
+func decodeVarintFromDecoder(dec coder.SymbolDecoder, model coder.Model) (uint64, error) {
 	var value uint64
 	for i := 0; i < 10; i++ { // Max 10 bytes for uint64
 		b, err := dec.Decode(model)
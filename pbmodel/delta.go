@@ -0,0 +1,314 @@
+package pbmodel
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/huffman"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// Periodic updates, such as positions and telemetry, mostly repeat the
+// previous update. Delta compression codes a message against a reference
+// message known to both sides: every field starts with a flag telling
+// whether it equals the field of the reference, and only the fields that
+// differ are coded. A message field set in both messages is itself coded as
+// a delta against the reference.
+
+// deltaModel codes whether a field equals the field of the reference.
+var deltaModel = sync.OnceValue(func() coder.Model {
+	return models.NewFrequencyTable([]uint64{6, 1})
+})
+
+// Symbols of deltaModel.
+const (
+	deltaSame    = 0
+	deltaChanged = 1
+)
+
+// CompressDelta compresses msg against reference, which must have the same
+// type. Fields equal to the field of the reference cost a fraction of a bit.
+// The data must be decompressed with DecompressDelta, the same reference and
+// the same options.
+func CompressDelta(msg, reference proto.Message, w io.Writer, opts ...Option) error {
+	o, err := collectOptions(opts)
+	if err != nil {
+		return err
+	}
+	m, ref := msg.ProtoReflect(), reference.ProtoReflect()
+	if err := checkDeltaReference(m, ref); err != nil {
+		return err
+	}
+	if err := o.writeHeader(w, formatDelta); err != nil {
+		return err
+	}
+	mb := newModelBuilderWithOptions(o)
+
+	if o.backend == Huffman {
+		enc := huffman.NewEncoder(w)
+		if err := compressDeltaMessage(m, ref, enc, mb); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	enc := coder.NewEncoder(w)
+	if err := compressDeltaMessage(m, ref, enc, mb); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// DecompressDelta decompresses data written by CompressDelta into msg, which
+// is reset first. The reference and the options must match the ones the
+// data was compressed with.
+func DecompressDelta(r io.Reader, reference, msg proto.Message, opts ...Option) error {
+	o, err := collectOptions(opts)
+	if err != nil {
+		return err
+	}
+	m, ref := msg.ProtoReflect(), reference.ProtoReflect()
+	if err := checkDeltaReference(m, ref); err != nil {
+		return err
+	}
+	if err := o.readHeader(r, formatDelta); err != nil {
+		return err
+	}
+	proto.Reset(msg)
+	mb := newModelBuilderWithOptions(o)
+
+	dec, err := newBackendDecoder(r, o.backend)
+	if err != nil {
+		return err
+	}
+	if err := decompressDeltaMessage(m, ref, dec, mb); err != nil {
+		return err
+	}
+	return mb.finish(dec)
+}
+
+// checkDeltaReference checks that msg and reference have the same type.
+func checkDeltaReference(msg, reference protoreflect.Message) error {
+	if got, want := reference.Descriptor().FullName(), msg.Descriptor().FullName(); got != want {
+		return fmt.Errorf("pbmodel: reference is %s, want %s", got, want)
+	}
+	return nil
+}
+
+// compressDeltaMessage compresses msg against ref.
+func compressDeltaMessage(msg, ref protoreflect.Message, enc coder.SymbolEncoder, mb *ModelBuilder) error {
+	if mb.depth >= mb.limits.MaxDepth {
+		return depthError(mb.limits.MaxDepth)
+	}
+	mb.depth++
+	defer func() { mb.depth-- }()
+
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if mb.skipsField(fd) {
+			continue
+		}
+
+		if sameField(fd, msg, ref) {
+			if err := enc.Encode(deltaSame, deltaModel()); err != nil {
+				return fmt.Errorf("field %s delta: %w", fd.Name(), err)
+			}
+			continue
+		}
+		if err := enc.Encode(deltaChanged, deltaModel()); err != nil {
+			return fmt.Errorf("field %s delta: %w", fd.Name(), err)
+		}
+
+		if deltaNested(fd, ref) {
+			// The field may have been cleared
+			if !msg.Has(fd) {
+				if err := mb.encodePresence(enc, fd, 0); err != nil {
+					return fmt.Errorf("field %s presence: %w", fd.Name(), err)
+				}
+				continue
+			}
+			if err := mb.encodePresence(enc, fd, 1); err != nil {
+				return fmt.Errorf("field %s presence: %w", fd.Name(), err)
+			}
+			if err := compressDeltaMessage(msg.Get(fd).Message(), ref.Get(fd).Message(), enc, mb); err != nil {
+				return fmt.Errorf("field %s: %w", fd.Name(), err)
+			}
+			continue
+		}
+		if err := compressField(msg, fd, enc, mb); err != nil {
+			return err
+		}
+	}
+
+	if err := mb.encodeDeprecated(enc, msg); err != nil {
+		return err
+	}
+	if mb.unknownFields {
+		return mb.encodeUnknownDelta(enc, msg, ref)
+	}
+	return nil
+}
+
+// decompressDeltaMessage decompresses a message written by
+// compressDeltaMessage into msg.
+func decompressDeltaMessage(msg, ref protoreflect.Message, dec coder.SymbolDecoder, mb *ModelBuilder) error {
+	if mb.depth >= mb.limits.MaxDepth {
+		return depthError(mb.limits.MaxDepth)
+	}
+	mb.depth++
+	defer func() { mb.depth-- }()
+
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if mb.skipsField(fd) {
+			continue
+		}
+
+		delta, err := dec.Decode(deltaModel())
+		if err != nil {
+			return fmt.Errorf("field %s delta: %w", fd.Name(), err)
+		}
+		if delta == deltaSame {
+			copyField(msg, ref, fd)
+			continue
+		}
+
+		if deltaNested(fd, ref) {
+			present, err := mb.decodePresence(dec, fd)
+			if err != nil {
+				return fmt.Errorf("field %s presence: %w", fd.Name(), err)
+			}
+			if present == 0 {
+				continue
+			}
+			if err := decompressDeltaMessage(msg.Mutable(fd).Message(), ref.Get(fd).Message(), dec, mb); err != nil {
+				return fmt.Errorf("field %s: %w", fd.Name(), err)
+			}
+			continue
+		}
+		if err := decompressField(msg, fd, dec, mb); err != nil {
+			return err
+		}
+	}
+
+	deprecated, err := mb.decodeDeprecated(dec, msg)
+	if err != nil {
+		return err
+	}
+	if mb.unknownFields {
+		if err := mb.decodeUnknownDelta(dec, msg, ref); err != nil {
+			return err
+		}
+	}
+	return mergeDeprecated(msg, deprecated)
+}
+
+// deltaNested reports whether a changed field is coded as a delta against
+// the field of the reference: singular message fields set in the reference
+// are, unless they were cleared.
+func deltaNested(fd protoreflect.FieldDescriptor, ref protoreflect.Message) bool {
+	return !fd.IsList() && !fd.IsMap() && fd.Kind() == protoreflect.MessageKind && ref.Has(fd)
+}
+
+// sameField reports whether fd has the same presence and value in x and y.
+func sameField(fd protoreflect.FieldDescriptor, x, y protoreflect.Message) bool {
+	if x.Has(fd) != y.Has(fd) {
+		return false
+	}
+	if !x.Has(fd) {
+		return true
+	}
+
+	xv, yv := x.Get(fd), y.Get(fd)
+	switch {
+	case fd.IsList():
+		xl, yl := xv.List(), yv.List()
+		if xl.Len() != yl.Len() {
+			return false
+		}
+		for i := 0; i < xl.Len(); i++ {
+			if !sameElement(fd, xl.Get(i), yl.Get(i)) {
+				return false
+			}
+		}
+		return true
+	case fd.IsMap():
+		xm, ym := xv.Map(), yv.Map()
+		if xm.Len() != ym.Len() {
+			return false
+		}
+		same := true
+		xm.Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			same = ym.Has(key) && sameElement(fd.MapValue(), value, ym.Get(key))
+			return same
+		})
+		return same
+	default:
+		return sameElement(fd, xv, yv)
+	}
+}
+
+// sameElement reports whether the single values x and y of fd code
+// identically.
+func sameElement(fd protoreflect.FieldDescriptor, x, y protoreflect.Value) bool {
+	if fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind {
+		return sameScalar(fd, x, y)
+	}
+	xm, ym := x.Message(), y.Message()
+	if !bytes.Equal(xm.GetUnknown(), ym.GetUnknown()) {
+		return false
+	}
+	fields := xm.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if !sameField(fields.Get(i), xm, ym) {
+			return false
+		}
+	}
+	return true
+}
+
+// copyField sets fd of dst to a copy of the field of src.
+func copyField(dst, src protoreflect.Message, fd protoreflect.FieldDescriptor) {
+	if !src.Has(fd) {
+		return
+	}
+
+	value := src.Get(fd)
+	switch {
+	case fd.IsList():
+		list, from := dst.Mutable(fd).List(), value.List()
+		for i := 0; i < from.Len(); i++ {
+			list.Append(copyElement(fd, from.Get(i), list.NewElement))
+		}
+	case fd.IsMap():
+		m := dst.Mutable(fd).Map()
+		value.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			m.Set(key, copyElement(fd.MapValue(), value, m.NewValue))
+			return true
+		})
+	case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+		proto.Merge(dst.Mutable(fd).Message().Interface(), value.Message().Interface())
+	default:
+		dst.Set(fd, cloneScalar(fd, value))
+	}
+}
+
+// copyElement returns a copy of a single value of fd. Messages are copied
+// into a value created by newMessage, so that they have the concrete type of
+// the destination.
+func copyElement(fd protoreflect.FieldDescriptor, value protoreflect.Value, newMessage func() protoreflect.Value) protoreflect.Value {
+	if fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind {
+		return cloneScalar(fd, value)
+	}
+	copied := newMessage()
+	proto.Merge(copied.Message().Interface(), value.Message().Interface())
+	return copied
+}
@@ -0,0 +1,127 @@
+package pbmodel
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestDeltaRoundtrip(t *testing.T) {
+	profile := &testdata.UserProfile{
+		UserId:        1234567,
+		Username:      "gateway",
+		Email:         "gateway@example.com",
+		Tags:          []string{"solar", "roof"},
+		AccountStatus: testdata.Status_ACTIVE,
+		Address:       &testdata.UserProfile_Address{City: "Tartu", Country: "EE"},
+		CreatedAt:     1700000000,
+		UpdatedAt:     1700000030,
+		Metadata:      map[string]string{"fw": "2.5"},
+	}
+	updated := proto.Clone(profile).(*testdata.UserProfile)
+	updated.UpdatedAt = 1700000060
+	updated.Address.City = "Tallinn"
+	updated.Tags = append(updated.Tags, "backup")
+	updated.Metadata["fw"] = "2.6"
+
+	cleared := proto.Clone(profile).(*testdata.UserProfile)
+	cleared.Address = nil
+	cleared.Tags = nil
+	cleared.Email = ""
+
+	tests := []struct {
+		name           string
+		msg, reference proto.Message
+	}{
+		{"same", profile, profile},
+		{"updated", updated, profile},
+		{"cleared", cleared, profile},
+		{"from empty", profile, &testdata.UserProfile{}},
+		{"to empty", &testdata.UserProfile{}, profile},
+		{"nested", &testdata.NestedMessage{
+			Inner:     &testdata.NestedMessage_Inner{Value: "a", Count: 2},
+			InnerList: []*testdata.NestedMessage_Inner{{Value: "x"}, {Count: 1}},
+		}, &testdata.NestedMessage{
+			Inner:     &testdata.NestedMessage_Inner{Value: "a", Count: 1},
+			InnerList: []*testdata.NestedMessage_Inner{{Value: "x"}, {Count: 1}},
+		}},
+		{"float bits", &testdata.NumericMessage{
+			FloatField:  float32(math.Copysign(0, -1)),
+			DoubleField: math.Float64frombits(0x7FF8000000000001),
+		}, &testdata.NumericMessage{
+			DoubleField: math.NaN(),
+		}},
+	}
+
+	for _, test := range tests {
+		for _, backend := range []Backend{Arithmetic, Huffman} {
+			var buf bytes.Buffer
+			if err := CompressDelta(test.msg, test.reference, &buf, WithBackend(backend)); err != nil {
+				t.Fatalf("%s: CompressDelta failed: %v", test.name, err)
+			}
+			decoded := test.msg.ProtoReflect().New().Interface()
+			if err := DecompressDelta(&buf, test.reference, decoded, WithBackend(backend)); err != nil {
+				t.Fatalf("%s: DecompressDelta failed: %v", test.name, err)
+			}
+			// proto.Equal treats all NaNs and both zeros as equal, compare
+			// the wire format instead
+			want, _ := proto.MarshalOptions{Deterministic: true}.Marshal(test.msg)
+			got, _ := proto.MarshalOptions{Deterministic: true}.Marshal(decoded)
+			if !bytes.Equal(want, got) {
+				t.Errorf("%s: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", test.name, test.msg, decoded)
+			}
+		}
+	}
+
+	// The decoded message must not share memory with the reference
+	decoded := &testdata.UserProfile{}
+	var buf bytes.Buffer
+	if err := CompressDelta(profile, profile, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := DecompressDelta(&buf, profile, decoded); err != nil {
+		t.Fatal(err)
+	}
+	decoded.Address.City = "changed"
+	decoded.Tags[0] = "changed"
+	if profile.Address.City != "Tartu" || profile.Tags[0] != "solar" {
+		t.Errorf("modifying the decoded message modified the reference")
+	}
+}
+
+func TestDeltaSize(t *testing.T) {
+	previous := &testdata.UserProfile{
+		UserId:    1234567,
+		Username:  "gateway",
+		Email:     "gateway@example.com",
+		Address:   &testdata.UserProfile_Address{City: "Tartu", Country: "EE"},
+		CreatedAt: 1700000000,
+		UpdatedAt: 1700000030,
+	}
+	current := proto.Clone(previous).(*testdata.UserProfile)
+	current.UpdatedAt += 30
+
+	var full, delta bytes.Buffer
+	if err := Compress(current, &full); err != nil {
+		t.Fatal(err)
+	}
+	if err := CompressDelta(current, previous, &delta); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("full %d bytes, delta %d bytes", full.Len(), delta.Len())
+	if delta.Len()*4 > full.Len() {
+		t.Errorf("delta is %d bytes, full message %d", delta.Len(), full.Len())
+	}
+}
+
+func TestDeltaReferenceType(t *testing.T) {
+	var buf bytes.Buffer
+	err := CompressDelta(&testdata.SimpleMessage{}, &testdata.EmptyMessage{}, &buf)
+	if err == nil {
+		t.Fatal("CompressDelta accepted a reference of another type")
+	}
+}
@@ -0,0 +1,132 @@
+package pbmodel
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// Schemas deprecate fields before removing them, so senders and receivers
+// may disagree on whether a deprecated field exists. Deprecated fields are
+// therefore not coded with the fields of their message: they follow the
+// other fields in their protobuf wire format, behind a flag, and only in
+// messages that the table of deprecated fields names as their parents. The
+// coding of every other field is the same whether the local descriptor has
+// the deprecated fields or not.
+
+// deprecatedFields is the table of deprecated fields of WithDeprecatedFields.
+type deprecatedFields struct {
+	fields  map[protoreflect.FullName]bool // Deprecated fields
+	parents map[protoreflect.FullName]bool // Messages with deprecated fields
+	drop    bool                           // Drop the fields when compressing
+}
+
+// WithDeprecatedFields names the deprecated fields of the schema. When drop
+// is set, Compress drops them; otherwise their values are kept in their
+// protobuf wire format. Decompress accepts data compressed either way, and
+// with descriptors that have the fields or have already removed them. A
+// descriptor without a kept field receives its value as an unknown field.
+func WithDeprecatedFields(drop bool, names ...protoreflect.FullName) Option {
+	return func(o *options) {
+		d := &deprecatedFields{
+			fields:  make(map[protoreflect.FullName]bool, len(names)),
+			parents: make(map[protoreflect.FullName]bool, len(names)),
+			drop:    drop,
+		}
+		for _, name := range names {
+			d.fields[name] = true
+			d.parents[name.Parent()] = true
+		}
+		o.deprecated = d
+	}
+}
+
+// skipsField reports whether fd is coded with encodeDeprecated instead of
+// with the other fields of its message.
+func (mb *ModelBuilder) skipsField(fd protoreflect.FieldDescriptor) bool {
+	return mb.deprecated != nil && mb.deprecated.fields[fd.FullName()]
+}
+
+// encodeDeprecated encodes the deprecated fields of msg.
+func (mb *ModelBuilder) encodeDeprecated(enc coder.SymbolEncoder, msg protoreflect.Message) error {
+	if mb.deprecated == nil || !mb.deprecated.parents[msg.Descriptor().FullName()] {
+		return nil
+	}
+
+	var raw []byte
+	if !mb.deprecated.drop {
+		kept := msg.New()
+		msg.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			if mb.skipsField(fd) {
+				kept.Set(fd, value)
+			}
+			return true
+		})
+		var err error
+		raw, err = proto.MarshalOptions{Deterministic: true}.Marshal(kept.Interface())
+		if err != nil {
+			return fmt.Errorf("deprecated fields: %w", err)
+		}
+	}
+	if len(raw) > MaxBytesLength {
+		return fmt.Errorf("deprecated fields length %d exceeds MaxBytesLength", len(raw))
+	}
+
+	if err := enc.Encode(boolSymbol(len(raw) > 0), mb.boolModel); err != nil {
+		return fmt.Errorf("deprecated fields presence: %w", err)
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := mb.encodeVarint(enc, uint64(len(raw))); err != nil {
+		return fmt.Errorf("deprecated fields: %w", err)
+	}
+	if err := mb.encodeRawBytes(enc, raw); err != nil {
+		return fmt.Errorf("deprecated fields: %w", err)
+	}
+	return nil
+}
+
+// decodeDeprecated decodes the deprecated fields written by
+// encodeDeprecated for msg, in their protobuf wire format. They are added to
+// msg with mergeDeprecated after its unknown fields are decoded.
+func (mb *ModelBuilder) decodeDeprecated(dec coder.SymbolDecoder, msg protoreflect.Message) ([]byte, error) {
+	if mb.deprecated == nil || !mb.deprecated.parents[msg.Descriptor().FullName()] {
+		return nil, nil
+	}
+
+	present, err := dec.Decode(mb.boolModel)
+	if err != nil {
+		return nil, fmt.Errorf("deprecated fields presence: %w", err)
+	}
+	if present == 0 {
+		return nil, nil
+	}
+	length, err := mb.decodeVarint(dec)
+	if err != nil {
+		return nil, fmt.Errorf("deprecated fields: %w", err)
+	}
+	if err := checkLimit("deprecated fields length", length, mb.limits.MaxBytes); err != nil {
+		return nil, err
+	}
+	raw, err := mb.decodeRawBytes(dec, int(length))
+	if err != nil {
+		return nil, fmt.Errorf("deprecated fields: %w", err)
+	}
+	return raw, nil
+}
+
+// mergeDeprecated adds deprecated fields decoded by decodeDeprecated to msg.
+// Fields missing from the descriptor of msg become unknown fields.
+func mergeDeprecated(msg protoreflect.Message, raw []byte) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := (proto.UnmarshalOptions{Merge: true}).Unmarshal(raw, msg.Interface()); err != nil {
+		return fmt.Errorf("deprecated fields: %w", err)
+	}
+	return nil
+}
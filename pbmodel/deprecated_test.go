@@ -0,0 +1,112 @@
+package pbmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// nodeSchema returns the descriptor of a message in the old version of a
+// schema, which has the deprecated field macaddr, or in the new version,
+// which removed it.
+func nodeSchema(t *testing.T, old bool) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:   typ.Enum(),
+		}
+	}
+	fields := []*descriptorpb.FieldDescriptorProto{
+		field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+		field("long_name", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+	}
+	if old {
+		macaddr := field("macaddr", 4, descriptorpb.FieldDescriptorProto_TYPE_BYTES)
+		macaddr.Options = &descriptorpb.FieldOptions{Deprecated: proto.Bool(true)}
+		fields = append(fields, macaddr)
+	}
+	fields = append(fields, field("hw_model", 5, descriptorpb.FieldDescriptorProto_TYPE_UINT32))
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("node.proto"),
+		Package: proto.String("node"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:  proto.String("User"),
+			Field: fields,
+		}},
+	}
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd.Messages().Get(0)
+}
+
+func TestDeprecatedFields(t *testing.T) {
+	oldSchema, newSchema := nodeSchema(t, true), nodeSchema(t, false)
+	macaddr := []byte{0xde, 0xad, 0xbe, 0xef, 0x01, 0x02}
+
+	newUser := func(md protoreflect.MessageDescriptor) *dynamicpb.Message {
+		msg := dynamicpb.NewMessage(md)
+		msg.Set(md.Fields().ByName("id"), protoreflect.ValueOfString("!a2e4f010"))
+		msg.Set(md.Fields().ByName("long_name"), protoreflect.ValueOfString("Base camp"))
+		msg.Set(md.Fields().ByName("hw_model"), protoreflect.ValueOfUint32(43))
+		if fd := md.Fields().ByName("macaddr"); fd != nil {
+			msg.Set(fd, protoreflect.ValueOfBytes(macaddr))
+		}
+		return msg
+	}
+
+	for _, sender := range []protoreflect.MessageDescriptor{oldSchema, newSchema} {
+		for _, drop := range []bool{false, true} {
+			msg := newUser(sender)
+			var buf bytes.Buffer
+			if err := Compress(msg, &buf, WithDeprecatedFields(drop, "node.User.macaddr")); err != nil {
+				t.Fatalf("Compress failed: %v", err)
+			}
+
+			// Decompression does not depend on drop
+			for _, receiver := range []protoreflect.MessageDescriptor{oldSchema, newSchema} {
+				decoded := dynamicpb.NewMessage(receiver)
+				if err := Decompress(bytes.NewReader(buf.Bytes()), decoded, WithDeprecatedFields(false, "node.User.macaddr")); err != nil {
+					t.Fatalf("Decompress failed: %v", err)
+				}
+
+				want := newUser(receiver)
+				kept := sender == oldSchema && !drop
+				if fd := receiver.Fields().ByName("macaddr"); fd != nil && !kept {
+					want.Clear(fd)
+				}
+				if receiver == newSchema && kept {
+					// The receiver keeps the field it does not know as an unknown field
+					raw, _ := proto.Marshal(want)
+					if err := proto.Unmarshal(append(raw, protoUnknownMacaddr(macaddr)...), want); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if !proto.Equal(want, decoded) {
+					t.Errorf("old sender %v, drop %v, old receiver %v: got %v, want %v",
+						sender == oldSchema, drop, receiver == oldSchema, decoded, want)
+				}
+			}
+		}
+	}
+}
+
+// protoUnknownMacaddr returns the wire format of the macaddr field.
+func protoUnknownMacaddr(macaddr []byte) []byte {
+	raw := protowire.AppendTag(nil, 4, protowire.BytesType)
+	return protowire.AppendBytes(raw, macaddr)
+}
@@ -0,0 +1,111 @@
+package pbmodel
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/egonelbre/exp-protobuf-compression/internal/randproto"
+)
+
+// dynamicVariant is a way to compress messages that FuzzDynamicRoundtrip
+// checks.
+type dynamicVariant struct {
+	name       string
+	compress   func(proto.Message, io.Writer) error
+	decompress func(io.Reader, proto.Message) error
+}
+
+// withOptions returns the variant of Compress and Decompress with opts.
+func withOptions(name string, opts ...Option) dynamicVariant {
+	return dynamicVariant{
+		name:       name,
+		compress:   func(msg proto.Message, w io.Writer) error { return Compress(msg, w, opts...) },
+		decompress: func(r io.Reader, msg proto.Message) error { return Decompress(r, msg, opts...) },
+	}
+}
+
+func dynamicVariants() []dynamicVariant {
+	codec := NewCodec()
+	return []dynamicVariant{
+		withOptions("default"),
+		withOptions("order-1", WithStringOrder(1)),
+		withOptions("order-2", WithStringOrder(2)),
+		withOptions("varint-models", WithVarintByteModels()),
+		withOptions("huffman", WithBackend(Huffman)),
+		withOptions("list-transforms", WithListTransforms()),
+		withOptions("back-references", WithBackReferences()),
+		withOptions("presence-bitmaps", WithPresenceBitmaps()),
+		withOptions("presence-bitmaps-huffman", WithPresenceBitmaps(), WithBackend(Huffman)),
+		withOptions("sparse-fields", WithSparseFields()),
+		withOptions("unknown-fields", WithUnknownFields()),
+		withOptions("checksum", WithChecksum()),
+		withOptions("schema-fingerprint", WithSchemaFingerprint()),
+		{
+			name:       "adaptive",
+			compress:   func(msg proto.Message, w io.Writer) error { return AdaptiveCompress(msg, w) },
+			decompress: func(r io.Reader, msg proto.Message) error { return AdaptiveDecompress(r, msg) },
+		},
+		{"codec", codec.Compress, codec.Decompress},
+	}
+}
+
+// addDynamicSeeds adds inputs of random bytes for every fixed schema and
+// for random schemas.
+func addDynamicSeeds(f *testing.F) {
+	rng := rand.New(rand.NewSource(1))
+	for schema := 0; schema <= len(randproto.Schemas()); schema++ {
+		f.Add(uint8(schema), []byte(nil))
+		for range 8 {
+			data := make([]byte, 64+rng.Intn(512))
+			rng.Read(data)
+			f.Add(uint8(schema), data)
+		}
+	}
+}
+
+// dynamicMessage returns the message described by the fuzz input: a message
+// of a fixed schema, or of a random schema when schema is past them.
+func dynamicMessage(t *testing.T, schema uint8, data []byte) protoreflect.Message {
+	src := randproto.NewSource(data)
+	schemas := randproto.Schemas()
+	md := schemas[int(schema)%len(schemas)]
+	if int(schema)%(len(schemas)+1) == len(schemas) {
+		var err error
+		if md, err = randproto.RandomSchema(src); err != nil {
+			t.Skipf("invalid random schema: %v", err)
+		}
+	}
+	return randproto.NewMessage(md, src, 3)
+}
+
+// FuzzDynamicRoundtrip checks that every variant decompresses the messages
+// it compresses to equal messages. Variants may refuse to compress a
+// message they cannot code, such as the adaptive variant an open enum with
+// an undeclared value.
+func FuzzDynamicRoundtrip(f *testing.F) {
+	addDynamicSeeds(f)
+	variants := dynamicVariants()
+
+	f.Fuzz(func(t *testing.T, schema uint8, data []byte) {
+		original := dynamicMessage(t, schema, data).Interface()
+		for _, variant := range variants {
+			var buf bytes.Buffer
+			if err := variant.compress(original, &buf); err != nil {
+				continue
+			}
+			decoded := dynamicpb.NewMessage(original.ProtoReflect().Descriptor())
+			if err := variant.decompress(&buf, decoded); err != nil {
+				t.Fatalf("%s: Decompress failed: %v\noriginal: %v", variant.name, err, original)
+			}
+			if !proto.Equal(original, decoded) {
+				t.Fatalf("%s: roundtrip mismatch\noriginal: %v\ndecoded:  %v", variant.name, original, decoded)
+			}
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package pbmodel
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// Enum values are coded by their index in the enum descriptor. Enums of
+// proto3 are open, so a field may hold a number its enum does not declare,
+// such as a value added by newer firmware. The models of the indexes have
+// one more symbol, an escape, after which the number is coded as a zigzag
+// varint. The escape takes 1/enumEscapeShare of the probability, which
+// costs a declared value less than a hundredth of a bit with arithmetic
+// coding.
+//
+// AdaptiveCompress, whose models the Meshtastic codecs up to V13 share, and
+// WithVarintByteModels keep enum models without the escape and refuse
+// undeclared numbers.
+
+// enumEscapeShare is the inverse of the probability of the escape.
+const enumEscapeShare = 256
+
+// openEnumModels holds the model of EnumModel by the number of values.
+var openEnumModels sync.Map
+
+// EnumModel returns the model of the value indexes of an enum with n values,
+// including aliases, followed by the escape of undeclared numbers.
+func EnumModel(n int) coder.Model {
+	if model, ok := openEnumModels.Load(n); ok {
+		return model.(coder.Model)
+	}
+	model, _ := openEnumModels.LoadOrStore(n, withEnumEscape(models.SharedUniformModel(n)))
+	return model.(coder.Model)
+}
+
+// withEnumEscape returns model with the escape appended as its last symbol.
+func withEnumEscape(model coder.Model) coder.Model {
+	n := model.SymbolCount()
+	total := model.TotalFreq()
+	scale := uint64(1)
+	if total < enumEscapeShare {
+		scale = enumEscapeShare/total + 1
+	}
+
+	frequencies := make([]uint64, n+1)
+	for i := range n {
+		lo, hi := model.Freq(i)
+		frequencies[i] = max((hi-lo)*scale, 1)
+	}
+	frequencies[n] = max(total*scale/enumEscapeShare, 1)
+	return models.NewFrequencyTable(frequencies)
+}
+
+// encodeEnum encodes the value number of enum ed with model, a model from
+// EnumModel or one learned from it.
+func (mb *ModelBuilder) encodeEnum(enc coder.SymbolEncoder, ed protoreflect.EnumDescriptor, number protoreflect.EnumNumber, model coder.Model) error {
+	values := ed.Values()
+	if value := values.ByNumber(number); value != nil {
+		return encodeLearned(enc, value.Index(), model)
+	}
+	if mb.varintBytes != nil {
+		return fmt.Errorf("unknown enum value: %d", number)
+	}
+	if err := encodeLearned(enc, values.Len(), model); err != nil {
+		return err
+	}
+	return mb.encodeEnumNumber(enc, number)
+}
+
+// decodeEnum decodes a value of enum ed written by encodeEnum.
+func (mb *ModelBuilder) decodeEnum(dec coder.SymbolDecoder, ed protoreflect.EnumDescriptor, model coder.Model) (protoreflect.EnumNumber, error) {
+	values := ed.Values()
+	index, err := decodeLearned(dec, model)
+	if err != nil {
+		return 0, err
+	}
+	if index < values.Len() {
+		return values.Get(index).Number(), nil
+	}
+	return mb.decodeEnumNumber(dec)
+}
+
+// encodeEnumNumber encodes the number of an undeclared enum value following
+// the escape.
+func (mb *ModelBuilder) encodeEnumNumber(enc coder.SymbolEncoder, number protoreflect.EnumNumber) error {
+	return mb.encodeVarint(enc, ZigzagEncode(int64(number)))
+}
+
+// decodeEnumNumber decodes a number written by encodeEnumNumber.
+func (mb *ModelBuilder) decodeEnumNumber(dec coder.SymbolDecoder) (protoreflect.EnumNumber, error) {
+	zigzag, err := mb.decodeVarint(dec)
+	if err != nil {
+		return 0, err
+	}
+	number := ZigzagDecode(zigzag)
+	if number < math.MinInt32 || number > math.MaxInt32 {
+		if err := mb.anomaly(fmt.Errorf("enum value %d: %w", number, ErrOutOfRange)); err != nil {
+			return 0, err
+		}
+	}
+	return protoreflect.EnumNumber(number), nil
+}
@@ -0,0 +1,73 @@
+package pbmodel
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestUnknownEnumValues(t *testing.T) {
+	var variants []dynamicVariant
+	for _, variant := range dynamicVariants() {
+		// The adaptive and varint-models variants code enums in a frozen
+		// format without the escape
+		if variant.name != "adaptive" && variant.name != "varint-models" {
+			variants = append(variants, variant)
+		}
+	}
+	variants = append(variants, dynamicVariant{"stream", compressStream, decompressStream})
+	for _, number := range []int32{5, 1000, -1, math.MaxInt32, math.MinInt32} {
+		msg := &testdata.MessageWithEnum{Status: testdata.Status(number), Description: "from newer firmware"}
+		for _, variant := range variants {
+			var buf bytes.Buffer
+			if err := variant.compress(msg, &buf); err != nil {
+				t.Fatalf("%s, %d: compress failed: %v", variant.name, number, err)
+			}
+			decoded := &testdata.MessageWithEnum{}
+			if err := variant.decompress(&buf, decoded); err != nil {
+				t.Fatalf("%s, %d: decompress failed: %v", variant.name, number, err)
+			}
+			if !proto.Equal(msg, decoded) {
+				t.Errorf("%s, %d: got %v, want %v", variant.name, number, decoded, msg)
+			}
+		}
+	}
+}
+
+func TestEnumEscapeCost(t *testing.T) {
+	model := EnumModel(5)
+	if got := model.SymbolCount(); got != 6 {
+		t.Fatalf("got %d symbols, want 6", got)
+	}
+	for i := range 5 {
+		if cost := model.Cost(i); cost > math.Log2(5)+0.01 {
+			t.Errorf("value %d costs %.3f bits", i, cost)
+		}
+	}
+
+	// Learned models keep the escape
+	set, err := TrainModels([]proto.Message{
+		&testdata.MessageWithEnum{Status: testdata.Status_ACTIVE},
+		&testdata.MessageWithEnum{Status: testdata.Status_ACTIVE},
+		&testdata.MessageWithEnum{Status: testdata.Status_PENDING},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &testdata.MessageWithEnum{Status: 42}
+	var buf bytes.Buffer
+	if err := Compress(msg, &buf, WithTrainedModels(set)); err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	decoded := &testdata.MessageWithEnum{}
+	if err := Decompress(&buf, decoded, WithTrainedModels(set)); err != nil {
+		t.Fatalf("Decompress failed: %v", err)
+	}
+	if !proto.Equal(msg, decoded) {
+		t.Errorf("trained: got %v, want %v", decoded, msg)
+	}
+}
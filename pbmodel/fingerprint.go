@@ -0,0 +1,122 @@
+package pbmodel
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// The compressed data holds no field numbers or types, so data decompressed
+// with a different version of the schema than it was compressed with decodes
+// into wrong fields instead of failing. WithSchemaFingerprint codes a
+// fingerprint of the schema before the message, which decompression compares
+// with the fingerprint of its own schema.
+//
+// The fingerprint covers what the coding depends on: the order of the
+// fields, their numbers, kinds and cardinalities, the values of enums and
+// the range hints, recursively for the message fields. Names are left out,
+// so renaming a field or a message keeps the fingerprint.
+
+// ErrSchemaMismatch is returned when the data was compressed with a schema
+// that differs from the one of the message decompressed into.
+var ErrSchemaMismatch = errors.New("pbmodel: schema mismatch")
+
+// fingerprintSize is the size of a schema fingerprint in bytes.
+const fingerprintSize = 4
+
+// WithSchemaFingerprint codes a fingerprint of the schema of the message
+// before it, at a cost of 4 bytes, and fails decompression with
+// ErrSchemaMismatch when the schemas differ, see SchemaFingerprint.
+func WithSchemaFingerprint() Option {
+	return func(o *options) { o.fingerprint = true }
+}
+
+// schemaFingerprints caches the fingerprints of message descriptors.
+var schemaFingerprints sync.Map
+
+// SchemaFingerprint returns the fingerprint of md and the messages it
+// refers to, the CRC-32C of a description of their fields.
+func SchemaFingerprint(md protoreflect.MessageDescriptor) uint32 {
+	if cached, ok := schemaFingerprints.Load(md); ok {
+		return cached.(uint32)
+	}
+	var desc []byte
+	appendSchema(&desc, md, map[protoreflect.FullName]int{})
+	sum := crc32.Checksum(desc, checksumTable)
+	schemaFingerprints.Store(md, sum)
+	return sum
+}
+
+// appendSchema appends the description of md to desc. Messages described
+// before are referred to by their position in seen, which ends cycles.
+func appendSchema(desc *[]byte, md protoreflect.MessageDescriptor, seen map[protoreflect.FullName]int) {
+	if index, ok := seen[md.FullName()]; ok {
+		*desc = binary.AppendUvarint(append(*desc, 'r'), uint64(index))
+		return
+	}
+	seen[md.FullName()] = len(seen)
+
+	fields := md.Fields()
+	*desc = binary.AppendUvarint(append(*desc, 'm'), uint64(fields.Len()))
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		*desc = binary.AppendUvarint(*desc, uint64(fd.Number()))
+		*desc = append(*desc, byte(fd.Kind()), byte(fd.Cardinality()))
+		if fd.IsMap() {
+			*desc = append(*desc, 'M')
+		}
+		if hint := lookupHint(fd); hint != nil {
+			*desc = binary.AppendUvarint(append(*desc, 'h'), uint64(hint.kind))
+			if hint.rangeModel != nil {
+				*desc = binary.AppendVarint(*desc, hint.min)
+				*desc = binary.AppendUvarint(*desc, uint64(hint.escape))
+			}
+		}
+
+		switch fd.Kind() {
+		case protoreflect.EnumKind:
+			values := fd.Enum().Values()
+			*desc = binary.AppendUvarint(append(*desc, 'e'), uint64(values.Len()))
+			for j := 0; j < values.Len(); j++ {
+				*desc = binary.AppendVarint(*desc, int64(values.Get(j).Number()))
+			}
+		case protoreflect.MessageKind, protoreflect.GroupKind:
+			appendSchema(desc, fd.Message(), seen)
+		}
+	}
+}
+
+// encodeFingerprint encodes the fingerprint of the schema of msg.
+func encodeFingerprint(enc coder.SymbolEncoder, md protoreflect.MessageDescriptor) error {
+	sum := SchemaFingerprint(md)
+	for i := fingerprintSize - 1; i >= 0; i-- {
+		if err := enc.Encode(int(sum>>(8*i))&0xff, models.SharedUniformModel(256)); err != nil {
+			return fmt.Errorf("schema fingerprint: %w", err)
+		}
+	}
+	return nil
+}
+
+// decodeFingerprint decodes a fingerprint and verifies it against the
+// schema of md.
+func decodeFingerprint(dec coder.SymbolDecoder, md protoreflect.MessageDescriptor) error {
+	var want uint32
+	for i := 0; i < fingerprintSize; i++ {
+		b, err := dec.Decode(models.SharedUniformModel(256))
+		if err != nil {
+			return fmt.Errorf("schema fingerprint: %w", err)
+		}
+		want = want<<8 | uint32(b)
+	}
+	if got := SchemaFingerprint(md); got != want {
+		return fmt.Errorf("schema fingerprint %08x, want %08x: %w", got, want, ErrSchemaMismatch)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package pbmodel
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestSchemaFingerprint(t *testing.T) {
+	msg := &testdata.SimpleMessage{Id: 12345, Name: "Alice", Active: true}
+
+	for _, backend := range []Backend{Arithmetic, Huffman} {
+		var plain, fingerprinted bytes.Buffer
+		if err := Compress(msg, &plain, WithBackend(backend)); err != nil {
+			t.Fatal(err)
+		}
+		if err := Compress(msg, &fingerprinted, WithBackend(backend), WithSchemaFingerprint()); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("backend %d: %d bytes, with fingerprint %d bytes", backend, plain.Len(), fingerprinted.Len())
+		data := fingerprinted.Bytes()
+
+		decoded := &testdata.SimpleMessage{}
+		if err := Decompress(bytes.NewReader(data), decoded, WithBackend(backend), WithSchemaFingerprint()); err != nil {
+			t.Fatalf("backend %d: Decompress failed: %v", backend, err)
+		}
+		if !proto.Equal(msg, decoded) {
+			t.Errorf("backend %d: got %v, want %v", backend, decoded, msg)
+		}
+
+		other := &testdata.NumericMessage{}
+		err := Decompress(bytes.NewReader(data), other, WithBackend(backend), WithSchemaFingerprint())
+		if !errors.Is(err, ErrSchemaMismatch) {
+			t.Errorf("backend %d: decompressing into another message: got %v, want a schema mismatch", backend, err)
+		}
+	}
+}
+
+func TestSchemaFingerprintStable(t *testing.T) {
+	// The fingerprints are part of the compressed data, so they must not
+	// change between releases
+	tests := []struct {
+		msg  proto.Message
+		want uint32
+	}{
+		{&testdata.SimpleMessage{}, 0xddeafece},
+		{&testdata.UserProfile{}, 0xdbb8cfd8},
+	}
+	for _, test := range tests {
+		md := test.msg.ProtoReflect().Descriptor()
+		if got := SchemaFingerprint(md); got != test.want {
+			t.Errorf("%s: fingerprint %08x, want %08x", md.FullName(), got, test.want)
+		}
+	}
+}
+
+func TestSchemaFingerprintChanges(t *testing.T) {
+	// schema returns a message with an int32, a string and an enum field
+	// after applying edit to the description
+	schema := func(edit func(file *descriptorpb.FileDescriptorProto)) protoreflect.MessageDescriptor {
+		t.Helper()
+		field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+			return &descriptorpb.FieldDescriptorProto{
+				Name:   proto.String(name),
+				Number: proto.Int32(number),
+				Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:   typ.Enum(),
+			}
+		}
+		level := field("level", 3, descriptorpb.FieldDescriptorProto_TYPE_ENUM)
+		level.TypeName = proto.String(".fingerprint.Level")
+		file := &descriptorpb.FileDescriptorProto{
+			Name:    proto.String("fingerprint.proto"),
+			Package: proto.String("fingerprint"),
+			Syntax:  proto.String("proto3"),
+			EnumType: []*descriptorpb.EnumDescriptorProto{{
+				Name: proto.String("Level"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("LOW"), Number: proto.Int32(0)},
+					{Name: proto.String("HIGH"), Number: proto.Int32(1)},
+				},
+			}},
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Reading"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("value", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+					field("label", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+					level,
+				},
+			}},
+		}
+		if edit != nil {
+			edit(file)
+		}
+		fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fd.Messages().Get(0)
+	}
+
+	base := SchemaFingerprint(schema(nil))
+	tests := []struct {
+		name    string
+		edit    func(file *descriptorpb.FileDescriptorProto)
+		changes bool
+	}{
+		{"rename field", func(file *descriptorpb.FileDescriptorProto) {
+			file.MessageType[0].Field[0].Name = proto.String("reading")
+		}, false},
+		{"rename message", func(file *descriptorpb.FileDescriptorProto) {
+			file.MessageType[0].Name = proto.String("Sample")
+		}, false},
+		{"renumber field", func(file *descriptorpb.FileDescriptorProto) {
+			file.MessageType[0].Field[1].Number = proto.Int32(4)
+		}, true},
+		{"change kind", func(file *descriptorpb.FileDescriptorProto) {
+			file.MessageType[0].Field[0].Type = descriptorpb.FieldDescriptorProto_TYPE_SINT32.Enum()
+		}, true},
+		{"repeat field", func(file *descriptorpb.FileDescriptorProto) {
+			file.MessageType[0].Field[0].Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		}, true},
+		{"reorder fields", func(file *descriptorpb.FileDescriptorProto) {
+			fields := file.MessageType[0].Field
+			fields[0], fields[1] = fields[1], fields[0]
+		}, true},
+		{"add enum value", func(file *descriptorpb.FileDescriptorProto) {
+			file.EnumType[0].Value = append(file.EnumType[0].Value,
+				&descriptorpb.EnumValueDescriptorProto{Name: proto.String("MAX"), Number: proto.Int32(2)})
+		}, true},
+	}
+	for _, test := range tests {
+		got := SchemaFingerprint(schema(test.edit))
+		if (got != base) != test.changes {
+			t.Errorf("%s: fingerprint %08x, base %08x, want change %v", test.name, got, base, test.changes)
+		}
+	}
+}
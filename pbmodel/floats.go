@@ -0,0 +1,43 @@
+package pbmodel
+
+import (
+	"math"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Floats are coded by their IEEE 754 bits, so negative zero, infinities and
+// the payloads of NaNs survive compression. The exception is signaling NaNs
+// in float fields: protoreflect hands out float values as float64, and the
+// conversion sets the quiet bit, keeping the rest of the payload. Such
+// messages fail the checksum of WithChecksum.
+//
+// Few programs care about NaN payloads, while a NaN with an arbitrary
+// payload is as costly to code as any other value and defeats the models
+// that learn the values of a field. WithCanonicalNaNs codes every NaN as the
+// quiet NaN without a payload instead.
+
+// Bits of the NaNs coded with WithCanonicalNaNs.
+const (
+	canonicalNaN32 = 0x7fc00000
+	canonicalNaN64 = 0x7ff8000000000000
+)
+
+// WithCanonicalNaNs codes every NaN of a float or double field as the
+// positive quiet NaN without a payload, so NaNs decompress to a single bit
+// pattern. The checksum of WithChecksum needs NaNs intact, so the options
+// cannot be combined.
+func WithCanonicalNaNs() Option {
+	return func(o *options) { o.canonicalNaNs = true }
+}
+
+// codedBits returns the IEEE 754 bits that code the value v of fd.
+func (mb *ModelBuilder) codedBits(fd protoreflect.FieldDescriptor, v float64) uint64 {
+	if mb.canonicalNaNs && math.IsNaN(v) {
+		if fd.Kind() == protoreflect.FloatKind {
+			return canonicalNaN32
+		}
+		return canonicalNaN64
+	}
+	return toBits(fd, v)
+}
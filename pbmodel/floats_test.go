@@ -0,0 +1,123 @@
+package pbmodel
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+// specialFloats are the float and double bits that arithmetic on the values
+// would not preserve.
+var specialFloats = []struct {
+	name    string
+	float   uint32
+	double  uint64
+	decoded uint32 // Float bits after decompression
+}{
+	{"negative zero", 0x80000000, 0x8000000000000000, 0x80000000},
+	{"infinity", 0x7f800000, 0x7ff0000000000000, 0x7f800000},
+	{"negative infinity", 0xff800000, 0xfff0000000000000, 0xff800000},
+	{"quiet NaN", 0x7fc00000, 0x7ff8000000000000, 0x7fc00000},
+	{"NaN with payload", 0x7fc0beef, 0x7ff800000000beef, 0x7fc0beef},
+	{"negative NaN", 0xffc00001, 0xfff8000000000001, 0xffc00001},
+	// Reflection sets the quiet bit of float NaNs
+	{"signaling NaN", 0x7f800001, 0x7ff0000000000001, 0x7fc00001},
+}
+
+func TestSpecialFloats(t *testing.T) {
+	variants := append(dynamicVariants(), dynamicVariant{"stream", compressStream, decompressStream})
+	for _, special := range specialFloats {
+		msg := &testdata.NumericMessage{
+			FloatField:  math.Float32frombits(special.float),
+			DoubleField: math.Float64frombits(special.double),
+		}
+		for _, variant := range variants {
+			var buf bytes.Buffer
+			if err := variant.compress(msg, &buf); err != nil {
+				t.Fatalf("%s, %s: compress failed: %v", variant.name, special.name, err)
+			}
+			decoded := &testdata.NumericMessage{}
+			err := variant.decompress(&buf, decoded)
+			if variant.name == "checksum" && special.decoded != special.float {
+				if !errors.Is(err, ErrChecksum) {
+					t.Errorf("%s, %s: got %v, want a checksum mismatch", variant.name, special.name, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s, %s: decompress failed: %v", variant.name, special.name, err)
+			}
+			if got := math.Float32bits(decoded.FloatField); got != special.decoded {
+				t.Errorf("%s, %s: float bits %#x, want %#x", variant.name, special.name, got, special.decoded)
+			}
+			if got := math.Float64bits(decoded.DoubleField); got != special.double {
+				t.Errorf("%s, %s: double bits %#x, want %#x", variant.name, special.name, got, special.double)
+			}
+		}
+	}
+}
+
+// compressStream compresses msg as the only message of a stream.
+func compressStream(msg proto.Message, w io.Writer) error {
+	return NewStreamCompressor(w).Compress(msg)
+}
+
+// decompressStream decompresses a message written by compressStream.
+func decompressStream(r io.Reader, msg proto.Message) error {
+	return NewStreamDecompressor(r).Decompress(msg)
+}
+
+func TestCanonicalNaNs(t *testing.T) {
+	for _, special := range specialFloats {
+		msg := &testdata.NumericMessage{
+			FloatField:  math.Float32frombits(special.float),
+			DoubleField: math.Float64frombits(special.double),
+		}
+		for _, backend := range []Backend{Arithmetic, Huffman} {
+			var buf bytes.Buffer
+			if err := Compress(msg, &buf, WithBackend(backend), WithCanonicalNaNs()); err != nil {
+				t.Fatalf("backend %v, %s: Compress failed: %v", backend, special.name, err)
+			}
+			decoded := &testdata.NumericMessage{}
+			if err := Decompress(&buf, decoded, WithBackend(backend), WithCanonicalNaNs()); err != nil {
+				t.Fatalf("backend %v, %s: Decompress failed: %v", backend, special.name, err)
+			}
+
+			wantFloat, wantDouble := special.float, special.double
+			if math.IsNaN(msg.DoubleField) {
+				wantFloat, wantDouble = canonicalNaN32, canonicalNaN64
+			}
+			if got := math.Float32bits(decoded.FloatField); got != wantFloat {
+				t.Errorf("backend %v, %s: float bits %#x, want %#x", backend, special.name, got, wantFloat)
+			}
+			if got := math.Float64bits(decoded.DoubleField); got != wantDouble {
+				t.Errorf("backend %v, %s: double bits %#x, want %#x", backend, special.name, got, wantDouble)
+			}
+		}
+	}
+
+	// Tolerances code NaNs exactly
+	msg := &testdata.NumericMessage{DoubleField: math.Float64frombits(0x7ff800000000beef)}
+	opts := []Option{WithCanonicalNaNs(), WithFloatTolerance("testdata.NumericMessage.double_field", Tolerance{Absolute: 0.5})}
+	var buf bytes.Buffer
+	if err := Compress(msg, &buf, opts...); err != nil {
+		t.Fatal(err)
+	}
+	decoded := &testdata.NumericMessage{}
+	if err := Decompress(&buf, decoded, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if got := math.Float64bits(decoded.DoubleField); got != canonicalNaN64 {
+		t.Errorf("quantized double bits %#x, want %#x", got, uint64(canonicalNaN64))
+	}
+
+	if err := Compress(msg, &bytes.Buffer{}, WithCanonicalNaNs(), WithChecksum()); err == nil {
+		t.Error("Compress with canonical NaNs and a checksum succeeded")
+	}
+}
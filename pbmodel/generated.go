@@ -0,0 +1,354 @@
+package pbmodel
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// Compressors generated by protoc-gen-pbzip code messages in the format of
+// Compress and Decompress without options. The generated code walks the
+// fields of a message with unrolled loops over the Go struct and codes the
+// values with a GenEncoder or GenDecoder, which hold the models; protoreflect
+// is only used for messages that the generator does not unroll, such as
+// messages of other Go packages.
+
+// GenEncoder codes the values of a message for generated compressors.
+type GenEncoder struct {
+	mb  *ModelBuilder
+	enc *coder.Encoder
+}
+
+// GenDecoder decodes the values written by a GenEncoder for generated
+// decompressors.
+type GenDecoder struct {
+	mb  *ModelBuilder
+	dec *coder.Decoder
+}
+
+// CompressGenerated compresses a message to w with encode, the generated
+// compressor of the message.
+func CompressGenerated(w io.Writer, encode func(*GenEncoder) error) error {
+	codec := codecPool.Get().(*Codec)
+	defer codecPool.Put(codec)
+
+	if err := codec.startEncoding(w); err != nil {
+		return err
+	}
+	codec.genEnc = GenEncoder{mb: codec.mb, enc: codec.enc}
+	if err := encode(&codec.genEnc); err != nil {
+		return err
+	}
+	return codec.enc.Close()
+}
+
+// DecompressGenerated decompresses a message from r with decode, the
+// generated decompressor of the message.
+func DecompressGenerated(r io.Reader, decode func(*GenDecoder) error) error {
+	codec := codecPool.Get().(*Codec)
+	defer codecPool.Put(codec)
+
+	if err := codec.startDecoding(r); err != nil {
+		return err
+	}
+	codec.genDec = GenDecoder{mb: codec.mb, dec: codec.dec}
+	if err := decode(&codec.genDec); err != nil {
+		return err
+	}
+	return codec.mb.finish(codec.dec)
+}
+
+// SortedKeys returns the keys of a map field in the order Compress codes
+// them, see RangeMapSorted. Maps with bool keys are ranged over false and
+// true instead.
+func SortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Enter starts coding a message, failing when it is nested too deeply.
+// Every successful Enter must be followed by Leave.
+func (e *GenEncoder) Enter() error {
+	if e.mb.depth >= e.mb.limits.MaxDepth {
+		return depthError(e.mb.limits.MaxDepth)
+	}
+	e.mb.depth++
+	return nil
+}
+
+// Leave finishes coding a message started with Enter.
+func (e *GenEncoder) Leave() { e.mb.depth-- }
+
+// Message encodes a message without a generated compressor.
+func (e *GenEncoder) Message(msg protoreflect.Message) error {
+	return compressMessage(msg, e.enc, e.mb)
+}
+
+// Presence encodes whether a field is present.
+func (e *GenEncoder) Presence(present bool) error {
+	return e.enc.Encode(boolSymbol(present), e.mb.boolModel)
+}
+
+// Length encodes the length of a list or a map.
+func (e *GenEncoder) Length(n int) error {
+	return e.mb.encodeVarint(e.enc, uint64(n))
+}
+
+// Bool encodes a bool value.
+func (e *GenEncoder) Bool(v bool) error {
+	return e.enc.Encode(boolSymbol(v), e.mb.boolModel)
+}
+
+// Enum encodes the index of an enum value with the model from EnumModel.
+func (e *GenEncoder) Enum(index int, model coder.Model) error {
+	return e.enc.Encode(index, model)
+}
+
+// UnknownEnum encodes an enum value that its enum does not declare as the
+// escape of model, which has n+1 symbols for an enum with n values, followed
+// by the number.
+func (e *GenEncoder) UnknownEnum(number protoreflect.EnumNumber, model coder.Model) error {
+	if err := e.enc.Encode(model.SymbolCount()-1, model); err != nil {
+		return err
+	}
+	return e.mb.encodeEnumNumber(e.enc, number)
+}
+
+// EnumOf encodes a value of an enum without generated coders.
+func (e *GenEncoder) EnumOf(v protoreflect.Enum) error {
+	ed := v.Descriptor()
+	return e.mb.encodeEnum(e.enc, ed, v.Number(), EnumModel(ed.Values().Len()))
+}
+
+// Int32 encodes an int32 value.
+func (e *GenEncoder) Int32(v int32) error { return e.mb.encodeVarint(e.enc, uint64(v)) }
+
+// Int64 encodes an int64 value.
+func (e *GenEncoder) Int64(v int64) error { return e.mb.encodeVarint(e.enc, uint64(v)) }
+
+// Uint32 encodes a uint32 value.
+func (e *GenEncoder) Uint32(v uint32) error { return e.mb.encodeVarint(e.enc, uint64(v)) }
+
+// Uint64 encodes a uint64 value.
+func (e *GenEncoder) Uint64(v uint64) error { return e.mb.encodeVarint(e.enc, v) }
+
+// Sint32 encodes a sint32 value.
+func (e *GenEncoder) Sint32(v int32) error { return e.mb.encodeVarint(e.enc, ZigzagEncode(int64(v))) }
+
+// Sint64 encodes a sint64 value.
+func (e *GenEncoder) Sint64(v int64) error { return e.mb.encodeVarint(e.enc, ZigzagEncode(v)) }
+
+// Fixed32 encodes a fixed32 value.
+func (e *GenEncoder) Fixed32(v uint32) error {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return encodeBytes(e.enc, buf[:], e.mb.byteModel)
+}
+
+// Fixed64 encodes a fixed64 value.
+func (e *GenEncoder) Fixed64(v uint64) error {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return encodeBytes(e.enc, buf[:], e.mb.byteModel)
+}
+
+// Sfixed32 encodes an sfixed32 value.
+func (e *GenEncoder) Sfixed32(v int32) error { return e.Fixed32(uint32(v)) }
+
+// Sfixed64 encodes an sfixed64 value.
+func (e *GenEncoder) Sfixed64(v int64) error { return e.Fixed64(uint64(v)) }
+
+// Float encodes a float value.
+func (e *GenEncoder) Float(v float32) error { return e.Fixed32(math.Float32bits(v)) }
+
+// Double encodes a double value.
+func (e *GenEncoder) Double(v float64) error { return e.Fixed64(math.Float64bits(v)) }
+
+// String encodes a string value.
+func (e *GenEncoder) String(v string) error { return e.mb.encodeString(e.enc, v) }
+
+// Bytes encodes a bytes value.
+func (e *GenEncoder) Bytes(v []byte) error { return e.mb.encodeBytesValue(e.enc, v) }
+
+// Enter starts decoding a message, failing when it is nested too deeply.
+// Every successful Enter must be followed by Leave.
+func (d *GenDecoder) Enter() error {
+	if d.mb.depth >= d.mb.limits.MaxDepth {
+		return depthError(d.mb.limits.MaxDepth)
+	}
+	d.mb.depth++
+	return nil
+}
+
+// Leave finishes decoding a message started with Enter.
+func (d *GenDecoder) Leave() { d.mb.depth-- }
+
+// Message decodes a message without a generated decompressor into msg.
+func (d *GenDecoder) Message(msg protoreflect.Message) error {
+	return decompressMessage(msg, d.dec, d.mb)
+}
+
+// Presence decodes whether a field is present.
+func (d *GenDecoder) Presence() (bool, error) {
+	present, err := d.dec.Decode(d.mb.boolModel)
+	return present != 0, err
+}
+
+// ListLength decodes the length of a list.
+func (d *GenDecoder) ListLength() (int, error) {
+	return d.length("list length")
+}
+
+// MapLength decodes the length of a map.
+func (d *GenDecoder) MapLength() (int, error) {
+	return d.length("map length")
+}
+
+// length decodes a length, verifying it against the limits.
+func (d *GenDecoder) length(what string) (int, error) {
+	length, err := d.mb.decodeVarint(d.dec)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkLimit(what, length, d.mb.limits.MaxListLen); err != nil {
+		return 0, err
+	}
+	return int(length), nil
+}
+
+// Bool decodes a bool value.
+func (d *GenDecoder) Bool() (bool, error) {
+	v, err := d.dec.Decode(d.mb.boolModel)
+	return v != 0, err
+}
+
+// Enum decodes the index of an enum value with the model from EnumModel.
+func (d *GenDecoder) Enum(model coder.Model) (int, error) {
+	return d.dec.Decode(model)
+}
+
+// UnknownEnum decodes the number of an enum value written by
+// GenEncoder.UnknownEnum, after Enum decoded the escape.
+func (d *GenDecoder) UnknownEnum() (protoreflect.EnumNumber, error) {
+	return d.mb.decodeEnumNumber(d.dec)
+}
+
+// EnumOf decodes a value of enum ed written by GenEncoder.EnumOf.
+func (d *GenDecoder) EnumOf(ed protoreflect.EnumDescriptor) (protoreflect.EnumNumber, error) {
+	return d.mb.decodeEnum(d.dec, ed, EnumModel(ed.Values().Len()))
+}
+
+// Int32 decodes an int32 value.
+func (d *GenDecoder) Int32() (int32, error) {
+	v, err := d.mb.decodeVarint(d.dec)
+	if err != nil {
+		return 0, err
+	}
+	if int64(v) < math.MinInt32 || int64(v) > math.MaxInt32 {
+		if err := d.mb.anomaly(fmt.Errorf("value %d: %w", int64(v), ErrOutOfRange)); err != nil {
+			return 0, err
+		}
+	}
+	return int32(v), nil
+}
+
+// Int64 decodes an int64 value.
+func (d *GenDecoder) Int64() (int64, error) {
+	v, err := d.mb.decodeVarint(d.dec)
+	return int64(v), err
+}
+
+// Uint32 decodes a uint32 value.
+func (d *GenDecoder) Uint32() (uint32, error) {
+	v, err := d.mb.decodeVarint(d.dec)
+	if err != nil {
+		return 0, err
+	}
+	if v > math.MaxUint32 {
+		if err := d.mb.anomaly(fmt.Errorf("value %d: %w", v, ErrOutOfRange)); err != nil {
+			return 0, err
+		}
+	}
+	return uint32(v), nil
+}
+
+// Uint64 decodes a uint64 value.
+func (d *GenDecoder) Uint64() (uint64, error) {
+	return d.mb.decodeVarint(d.dec)
+}
+
+// Sint32 decodes a sint32 value.
+func (d *GenDecoder) Sint32() (int32, error) {
+	zigzag, err := d.mb.decodeVarint(d.dec)
+	if err != nil {
+		return 0, err
+	}
+	v := ZigzagDecode(zigzag)
+	if v < math.MinInt32 || v > math.MaxInt32 {
+		if err := d.mb.anomaly(fmt.Errorf("value %d: %w", v, ErrOutOfRange)); err != nil {
+			return 0, err
+		}
+	}
+	return int32(v), nil
+}
+
+// Sint64 decodes a sint64 value.
+func (d *GenDecoder) Sint64() (int64, error) {
+	zigzag, err := d.mb.decodeVarint(d.dec)
+	return ZigzagDecode(zigzag), err
+}
+
+// Fixed32 decodes a fixed32 value.
+func (d *GenDecoder) Fixed32() (uint32, error) {
+	var buf [4]byte
+	err := decodeBytes(d.dec, buf[:], d.mb.byteModel)
+	return binary.LittleEndian.Uint32(buf[:]), err
+}
+
+// Fixed64 decodes a fixed64 value.
+func (d *GenDecoder) Fixed64() (uint64, error) {
+	var buf [8]byte
+	err := decodeBytes(d.dec, buf[:], d.mb.byteModel)
+	return binary.LittleEndian.Uint64(buf[:]), err
+}
+
+// Sfixed32 decodes an sfixed32 value.
+func (d *GenDecoder) Sfixed32() (int32, error) {
+	v, err := d.Fixed32()
+	return int32(v), err
+}
+
+// Sfixed64 decodes an sfixed64 value.
+func (d *GenDecoder) Sfixed64() (int64, error) {
+	v, err := d.Fixed64()
+	return int64(v), err
+}
+
+// Float decodes a float value.
+func (d *GenDecoder) Float() (float32, error) {
+	v, err := d.Fixed32()
+	return math.Float32frombits(v), err
+}
+
+// Double decodes a double value.
+func (d *GenDecoder) Double() (float64, error) {
+	v, err := d.Fixed64()
+	return math.Float64frombits(v), err
+}
+
+// String decodes a string value.
+func (d *GenDecoder) String() (string, error) { return d.mb.decodeString(d.dec) }
+
+// Bytes decodes a bytes value.
+func (d *GenDecoder) Bytes() ([]byte, error) { return d.mb.decodeBytesValue(d.dec) }
@@ -0,0 +1,164 @@
+package pbmodel
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The compressed data holds no marks of its own, so data decompressed with
+// other options than it was compressed with, or data that was not
+// compressed by pbmodel at all, decodes into a wrong message instead of
+// failing. Compressed data therefore starts with a format header: the two
+// bytes of headerMagic, the kind of the coding and two bytes of feature
+// bits, one for each option that changes the layout of the data.
+//
+// The header records whether the trained models, deprecated fields, float
+// tolerances and Any resolver are used, but not their contents, so those
+// must still match. WithSchemaFingerprint catches a mismatch of the schema.
+// Links where every byte counts can leave the header out with
+// WithoutHeader, and must then agree on the options by other means.
+
+var (
+	// ErrNoHeader is returned for data that does not start with a format
+	// header.
+	ErrNoHeader = errors.New("pbmodel: missing format header")
+
+	// ErrFormatMismatch is returned for data that was compressed with other
+	// options, or by another function, than the one decompressing it.
+	ErrFormatMismatch = errors.New("pbmodel: data compressed in another format")
+)
+
+// headerMagic starts format headers.
+var headerMagic = [2]byte{0xb7, 0x50}
+
+// HeaderSize is the size of a format header in bytes.
+const HeaderSize = len(headerMagic) + 3
+
+// Kinds of the coding in a format header.
+const (
+	formatMessage  = 1 // Compress, Codec and the generated compressors
+	formatDelta    = 2 // CompressDelta
+	formatAdaptive = 3 // AdaptiveCompress
+	formatStream   = 4 // StreamCompressor, once per stream
+	formatMasked   = 5 // CompressMasked
+)
+
+// Feature bits of a format header. The lowest two bits hold the string
+// order.
+const (
+	featureVarintModels   = 1 << 2
+	featureHuffman        = 1 << 3
+	featureUnknownFields  = 1 << 4
+	featureListTransforms = 1 << 5
+	featureWellKnown      = 1 << 6
+	featureTrained        = 1 << 7
+	featureDeprecated     = 1 << 8
+	featureTolerances     = 1 << 9
+	featureAnyResolver    = 1 << 10
+	featureChecksum       = 1 << 11
+	featureBackReferences = 1 << 12
+	featureBitmaps        = 1 << 13
+	featureSparseFields   = 1 << 14
+	featureFingerprint    = 1 << 15
+)
+
+// WithoutHeader leaves out the format header, saving HeaderSize bytes. The
+// data must be decompressed with WithoutHeader too, and data compressed
+// with other options then decodes into a wrong message instead of failing.
+func WithoutHeader() Option {
+	return func(o *options) { o.noHeader = true }
+}
+
+// features returns the feature bits of the options.
+func (o options) features() uint16 {
+	features := uint16(o.stringOrder)
+	for _, feature := range []struct {
+		bit uint16
+		set bool
+	}{
+		{featureVarintModels, o.varintModels},
+		{featureHuffman, o.backend == Huffman},
+		{featureChecksum, o.checksum},
+		{featureFingerprint, o.fingerprint},
+		{featureUnknownFields, o.unknownFields},
+		{featureListTransforms, o.listTransforms},
+		{featureBackReferences, o.backReferences},
+		{featureBitmaps, o.bitmaps},
+		{featureSparseFields, o.sparseFields},
+		{featureWellKnown, o.wellKnown},
+		{featureTrained, o.trained != nil},
+		{featureDeprecated, o.deprecated != nil},
+		{featureTolerances, len(o.tolerances) > 0},
+		{featureAnyResolver, o.anyResolver != nil},
+	} {
+		if feature.set {
+			features |= feature.bit
+		}
+	}
+	return features
+}
+
+// putHeader puts the format header of kind with features into header.
+func putHeader(header *[HeaderSize]byte, kind byte, features uint16) {
+	header[0], header[1], header[2] = headerMagic[0], headerMagic[1], kind
+	binary.BigEndian.PutUint16(header[3:], features)
+}
+
+// checkHeader verifies that header is a format header of kind with
+// features.
+func checkHeader(header *[HeaderSize]byte, kind byte, features uint16) error {
+	if header[0] != headerMagic[0] || header[1] != headerMagic[1] {
+		return ErrNoHeader
+	}
+	gotKind, gotFeatures := header[2], binary.BigEndian.Uint16(header[3:])
+	if gotKind != kind || gotFeatures != features {
+		return fmt.Errorf("kind %d with features %#04x, want kind %d with %#04x: %w", gotKind, gotFeatures, kind, features, ErrFormatMismatch)
+	}
+	return nil
+}
+
+// readHeader reads a format header from r into header and verifies it.
+// Data shorter than a header is reported as ErrTruncated.
+func readHeader(r io.Reader, header *[HeaderSize]byte, kind byte, features uint16) error {
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrTruncated
+		}
+		return err
+	}
+	return checkHeader(header, kind, features)
+}
+
+// headerOptions returns the options of a coding that takes no options but
+// WithoutHeader, such as AdaptiveCompress: the header has no feature bits.
+func headerOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return options{noHeader: o.noHeader}
+}
+
+// writeHeader writes the format header of kind for the options, unless
+// they leave it out.
+func (o options) writeHeader(w io.Writer, kind byte) error {
+	if o.noHeader {
+		return nil
+	}
+	var header [HeaderSize]byte
+	putHeader(&header, kind, o.features())
+	_, err := w.Write(header[:])
+	return err
+}
+
+// readHeader reads and verifies the format header of kind for the options,
+// unless they leave it out.
+func (o options) readHeader(r io.Reader, kind byte) error {
+	if o.noHeader {
+		return nil
+	}
+	var header [HeaderSize]byte
+	return readHeader(r, &header, kind, o.features())
+}
@@ -0,0 +1,139 @@
+package pbmodel
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestHeader(t *testing.T) {
+	msg := &testdata.SimpleMessage{Id: 42, Name: "header", Active: true}
+
+	var withHeader, without bytes.Buffer
+	if err := Compress(msg, &withHeader); err != nil {
+		t.Fatal(err)
+	}
+	if err := Compress(msg, &without, WithoutHeader()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := withHeader.Len(), without.Len()+HeaderSize; got != want {
+		t.Errorf("%d bytes with the header, want %d", got, want)
+	}
+
+	decoded := &testdata.SimpleMessage{}
+	if err := Decompress(bytes.NewReader(without.Bytes()), decoded, WithoutHeader()); err != nil {
+		t.Fatalf("Decompress without header failed: %v", err)
+	}
+	if !proto.Equal(msg, decoded) {
+		t.Errorf("got %v, want %v", decoded, msg)
+	}
+
+	// Data of other options or of another coding fails before it is decoded
+	tests := []struct {
+		name       string
+		decompress func(data []byte) error
+		want       error
+	}{
+		{"string order", func(data []byte) error {
+			return Decompress(bytes.NewReader(data), &testdata.SimpleMessage{}, WithStringOrder(2))
+		}, ErrFormatMismatch},
+		{"checksum", func(data []byte) error {
+			return Decompress(bytes.NewReader(data), &testdata.SimpleMessage{}, WithChecksum())
+		}, ErrFormatMismatch},
+		{"delta", func(data []byte) error {
+			return DecompressDelta(bytes.NewReader(data), msg, &testdata.SimpleMessage{})
+		}, ErrFormatMismatch},
+		{"adaptive", func(data []byte) error {
+			return AdaptiveDecompress(bytes.NewReader(data), &testdata.SimpleMessage{})
+		}, ErrFormatMismatch},
+		{"stream", func(data []byte) error {
+			return NewStreamDecompressor(bytes.NewReader(data)).Decompress(&testdata.SimpleMessage{})
+		}, ErrFormatMismatch},
+		{"truncated", func(data []byte) error {
+			return Decompress(bytes.NewReader(data[:HeaderSize-1]), &testdata.SimpleMessage{})
+		}, ErrTruncated},
+		{"no header", func(data []byte) error {
+			return Decompress(bytes.NewReader([]byte("protobuf")), &testdata.SimpleMessage{})
+		}, ErrNoHeader},
+	}
+	for _, test := range tests {
+		if err := test.decompress(withHeader.Bytes()); !errors.Is(err, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	// The codec and Marshal write the header of Compress without options
+	data, err := Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, withHeader.Bytes()) {
+		t.Errorf("Marshal wrote %x, want %x", data, withHeader.Bytes())
+	}
+}
+
+func TestHeaderAdaptiveAndStream(t *testing.T) {
+	msgs := []proto.Message{
+		&testdata.SimpleMessage{Id: 42, Name: "header", Active: true},
+		&testdata.SimpleMessage{Id: 43, Name: "header"},
+	}
+
+	var withHeader, without bytes.Buffer
+	if err := AdaptiveCompress(msgs[0], &withHeader); err != nil {
+		t.Fatal(err)
+	}
+	if err := AdaptiveCompress(msgs[0], &without, WithoutHeader()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(withHeader.Bytes()[HeaderSize:], without.Bytes()) {
+		t.Error("adaptive: data without header differs")
+	}
+	if err := AdaptiveDecompress(bytes.NewReader(withHeader.Bytes()), &testdata.SimpleMessage{}, WithoutHeader()); err == nil {
+		t.Error("adaptive: data with header decoded without it")
+	}
+	if err := Decompress(bytes.NewReader(withHeader.Bytes()), &testdata.SimpleMessage{}); !errors.Is(err, ErrFormatMismatch) {
+		t.Errorf("adaptive data decoded by Decompress: got %v, want ErrFormatMismatch", err)
+	}
+
+	// A stream has one header before its first message
+	withHeader.Reset()
+	without.Reset()
+	compressor := NewStreamCompressor(&withHeader)
+	raw := NewStreamCompressor(&without, WithoutHeader())
+	for _, msg := range msgs {
+		if err := compressor.Compress(msg); err != nil {
+			t.Fatal(err)
+		}
+		if err := raw.Compress(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(withHeader.Bytes()[HeaderSize:], without.Bytes()) {
+		t.Error("stream: data without header differs")
+	}
+	for _, test := range []struct {
+		name         string
+		decompressor *StreamDecompressor
+	}{
+		{"header", NewStreamDecompressor(&withHeader)},
+		{"without header", NewStreamDecompressor(&without, WithoutHeader())},
+	} {
+		for i, msg := range msgs {
+			decoded := &testdata.SimpleMessage{}
+			if err := test.decompressor.Decompress(decoded); err != nil {
+				t.Fatalf("stream with %s, message %d: %v", test.name, i, err)
+			}
+			if !proto.Equal(msg, decoded) {
+				t.Errorf("stream with %s, message %d: got %v, want %v", test.name, i, decoded, msg)
+			}
+		}
+	}
+	if err := NewStreamDecompressor(bytes.NewReader(nil)).Decompress(&testdata.SimpleMessage{}); err != io.EOF {
+		t.Errorf("empty stream: got %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,210 @@
+package pbmodel
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/pbz"
+)
+
+// Schemas can describe their integer fields with the options in package pbz,
+// so that fields get suitable models without code that knows the schema:
+//
+//	import "pbz.proto";
+//
+//	message Telemetry {
+//	  uint32 battery_level = 1 [(pbz.kind) = PERCENT];
+//	  int32 latitude_i = 2 [(pbz.kind) = COORDINATE];
+//	  uint32 time = 3 [(pbz.kind) = TIMESTAMP];
+//	  int32 temperature = 4 [(pbz.range) = {min: -40, max: 85}];
+//	}
+//
+// A value within the range of a field is coded uniformly within the range,
+// values outside of it are escaped and coded like fields without hints. The
+// kind selects how the other values are coded. Fields without a range take
+// the one of their protovalidate constraints, see validateRange. Hints of
+// fields other than varint-coded integers are ignored, as are ranges with
+// min above max or with more than maxHintRange values.
+
+// maxHintRange is the largest number of values of a range hint.
+const maxHintRange = 1 << 20
+
+// timestampEpoch is subtracted from timestamps, so that current times are
+// small.
+const timestampEpoch = 1_700_000_000
+
+// fieldHint holds the models selected by the options of a field.
+type fieldHint struct {
+	kind pbz.Kind
+
+	// Range of the values, rangeModel is nil without a range
+	min        int64
+	escape     int // Symbol of values outside of the range
+	rangeModel coder.Model
+}
+
+// fieldHints caches the hints of field descriptors, *fieldHint or nil.
+var fieldHints sync.Map
+
+// lookupHint returns the hints of fd, or nil when it has none.
+func lookupHint(fd protoreflect.FieldDescriptor) *fieldHint {
+	if cached, ok := fieldHints.Load(fd); ok {
+		return cached.(*fieldHint)
+	}
+	hint := newFieldHint(fd)
+	fieldHints.Store(fd, hint)
+	return hint
+}
+
+// newFieldHint reads the hints of fd from its options.
+func newFieldHint(fd protoreflect.FieldDescriptor) *fieldHint {
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Int64Kind,
+		protoreflect.Uint32Kind, protoreflect.Uint64Kind,
+		protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+	default:
+		return nil
+	}
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	if !ok || opts == nil {
+		return nil
+	}
+
+	hint := &fieldHint{kind: proto.GetExtension(opts, pbz.E_Kind).(pbz.Kind)}
+
+	var lo, hi int64
+	hasRange := true
+	switch {
+	case proto.HasExtension(opts, pbz.E_Range):
+		r := proto.GetExtension(opts, pbz.E_Range).(*pbz.Range)
+		lo, hi = r.GetMin(), r.GetMax()
+	case hint.kind == pbz.Kind_PERCENT:
+		lo, hi = 0, 100
+	default:
+		lo, hi, hasRange = validateRange(fd, opts)
+	}
+	if hasRange && lo <= hi && uint64(hi)-uint64(lo) < maxHintRange {
+		size := int(hi-lo) + 1
+		hint.min = lo
+		hint.escape = size
+		hint.rangeModel = models.NewUniformModel(size + 1)
+	}
+
+	if hint.rangeModel == nil && hint.kind == pbz.Kind_NONE {
+		return nil
+	}
+	return hint
+}
+
+// encodeHinted encodes an integer value of a field with hints.
+func (mb *ModelBuilder) encodeHinted(enc coder.SymbolEncoder, fd protoreflect.FieldDescriptor, hint *fieldHint, value protoreflect.Value) error {
+	// Unsigned values are handled as int64 with wraparound, which keeps
+	// every value representable
+	var v int64
+	if isUnsignedKind(fd.Kind()) {
+		v = int64(value.Uint())
+	} else {
+		v = value.Int()
+	}
+
+	if hint.rangeModel != nil {
+		symbol := hint.escape
+		if offset := uint64(v) - uint64(hint.min); offset < uint64(hint.escape) {
+			symbol = int(offset)
+		}
+		if err := enc.Encode(symbol, hint.rangeModel); err != nil {
+			return err
+		}
+		if symbol != hint.escape {
+			return nil
+		}
+	}
+	return mb.encodeFieldVarint(enc, fd, hint.bits(fd, v))
+}
+
+// decodeHinted decodes a value written by encodeHinted.
+func (mb *ModelBuilder) decodeHinted(dec coder.SymbolDecoder, fd protoreflect.FieldDescriptor, hint *fieldHint) (protoreflect.Value, error) {
+	if hint.rangeModel != nil {
+		symbol, err := dec.Decode(hint.rangeModel)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		if symbol != hint.escape {
+			return mb.integerValue(fd, int64(uint64(hint.min)+uint64(symbol)))
+		}
+	}
+
+	bits, err := mb.decodeFieldVarint(dec, fd)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	return mb.integerValue(fd, hint.value(fd, bits))
+}
+
+// bits returns the varint coded for an integer value v of fd.
+func (hint *fieldHint) bits(fd protoreflect.FieldDescriptor, v int64) uint64 {
+	switch {
+	case hint.kind == pbz.Kind_TIMESTAMP:
+		return ZigzagEncode(v - timestampEpoch)
+	case hint.kind == pbz.Kind_COORDINATE,
+		fd.Kind() == protoreflect.Sint32Kind, fd.Kind() == protoreflect.Sint64Kind:
+		return ZigzagEncode(v)
+	default:
+		return uint64(v)
+	}
+}
+
+// value returns the integer value coded as bits, see fieldHint.bits.
+func (hint *fieldHint) value(fd protoreflect.FieldDescriptor, bits uint64) int64 {
+	switch {
+	case hint.kind == pbz.Kind_TIMESTAMP:
+		return ZigzagDecode(bits) + timestampEpoch
+	case hint.kind == pbz.Kind_COORDINATE,
+		fd.Kind() == protoreflect.Sint32Kind, fd.Kind() == protoreflect.Sint64Kind:
+		return ZigzagDecode(bits)
+	default:
+		return int64(bits)
+	}
+}
+
+// integerValue converts a decoded integer to a value of fd, reporting values
+// that do not fit the field.
+func (mb *ModelBuilder) integerValue(fd protoreflect.FieldDescriptor, v int64) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			if err := mb.anomaly(fmt.Errorf("field %s value %d: %w", fd.FullName(), v, ErrOutOfRange)); err != nil {
+				return protoreflect.Value{}, err
+			}
+		}
+		return protoreflect.ValueOfInt32(int32(v)), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if uint64(v) > math.MaxUint32 {
+			if err := mb.anomaly(fmt.Errorf("field %s value %d: %w", fd.FullName(), uint64(v), ErrOutOfRange)); err != nil {
+				return protoreflect.Value{}, err
+			}
+		}
+		return protoreflect.ValueOfUint32(uint32(v)), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(uint64(v)), nil
+	default:
+		return protoreflect.ValueOfInt64(v), nil
+	}
+}
+
+// isUnsignedKind reports whether values of kind are read with Value.Uint.
+func isUnsignedKind(kind protoreflect.Kind) bool {
+	switch kind {
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind,
+		protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+		return true
+	}
+	return false
+}
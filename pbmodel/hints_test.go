@@ -0,0 +1,118 @@
+package pbmodel
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/pbz"
+)
+
+// hintedMessage returns the descriptor of a message with hinted fields and,
+// when hints is false, the same message without the options.
+func hintedMessage(t *testing.T, hints bool) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, opts *descriptorpb.FieldOptions) *descriptorpb.FieldDescriptorProto {
+		if !hints {
+			opts = nil
+		}
+		return &descriptorpb.FieldDescriptorProto{
+			Name:    proto.String(name),
+			Number:  proto.Int32(number),
+			Label:   descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:    typ.Enum(),
+			Options: opts,
+		}
+	}
+	withKind := func(kind pbz.Kind) *descriptorpb.FieldOptions {
+		opts := &descriptorpb.FieldOptions{}
+		proto.SetExtension(opts, pbz.E_Kind, kind)
+		return opts
+	}
+	withRange := func(lo, hi int64) *descriptorpb.FieldOptions {
+		opts := &descriptorpb.FieldOptions{}
+		proto.SetExtension(opts, pbz.E_Range, &pbz.Range{Min: lo, Max: hi})
+		return opts
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("hinted.proto"),
+		Package: proto.String("hinted"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Telemetry"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("battery_level", 1, descriptorpb.FieldDescriptorProto_TYPE_UINT32, withKind(pbz.Kind_PERCENT)),
+				field("latitude_i", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, withKind(pbz.Kind_COORDINATE)),
+				field("time", 3, descriptorpb.FieldDescriptorProto_TYPE_UINT32, withKind(pbz.Kind_TIMESTAMP)),
+				field("temperature", 4, descriptorpb.FieldDescriptorProto_TYPE_INT32, withRange(-40, 85)),
+				field("counter", 5, descriptorpb.FieldDescriptorProto_TYPE_UINT64, withRange(-5, 5)),
+				field("invalid", 6, descriptorpb.FieldDescriptorProto_TYPE_SINT64, withRange(10, -10)),
+			},
+		}},
+	}
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd.Messages().Get(0)
+}
+
+func TestFieldHints(t *testing.T) {
+	hinted, plain := hintedMessage(t, true), hintedMessage(t, false)
+
+	values := []map[string]protoreflect.Value{
+		{
+			"battery_level": protoreflect.ValueOfUint32(87),
+			"latitude_i":    protoreflect.ValueOfInt32(-337234567),
+			"time":          protoreflect.ValueOfUint32(1760000000),
+			"temperature":   protoreflect.ValueOfInt32(-12),
+			"counter":       protoreflect.ValueOfUint64(3),
+		},
+		{
+			// Values outside of the ranges
+			"battery_level": protoreflect.ValueOfUint32(101),
+			"latitude_i":    protoreflect.ValueOfInt32(math.MinInt32),
+			"time":          protoreflect.ValueOfUint32(math.MaxUint32),
+			"temperature":   protoreflect.ValueOfInt32(-41),
+			"counter":       protoreflect.ValueOfUint64(math.MaxUint64 - 4),
+			"invalid":       protoreflect.ValueOfInt64(math.MinInt64),
+		},
+	}
+
+	for i, fields := range values {
+		var sizes [2]int
+		for j, md := range []protoreflect.MessageDescriptor{hinted, plain} {
+			msg := dynamicpb.NewMessage(md)
+			for name, value := range fields {
+				msg.Set(md.Fields().ByName(protoreflect.Name(name)), value)
+			}
+
+			var buf bytes.Buffer
+			if err := Compress(msg, &buf); err != nil {
+				t.Fatalf("message %d: Compress failed: %v", i, err)
+			}
+			sizes[j] = buf.Len()
+
+			decoded := dynamicpb.NewMessage(md)
+			if err := Decompress(&buf, decoded); err != nil {
+				t.Fatalf("message %d: Decompress failed: %v", i, err)
+			}
+			if !proto.Equal(msg, decoded) {
+				t.Errorf("message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", i, msg, decoded)
+			}
+		}
+		t.Logf("message %d: %d bytes with hints, %d bytes without", i, sizes[0], sizes[1])
+		if i == 0 && sizes[0] >= sizes[1] {
+			t.Errorf("message %d: %d bytes with hints, %d bytes without", i, sizes[0], sizes[1])
+		}
+	}
+}
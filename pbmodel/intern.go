@@ -0,0 +1,116 @@
+package pbmodel
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"slices"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// Streams repeat strings in other places than the field that had them last:
+// node names appear in many entries of a node list, channel names in every
+// channel message. A stream therefore keeps the streamStrings most recently
+// coded strings, most recent first, and starts every string with its
+// position in that list, or with streamStringMiss for a string that is
+// coded in full. Either way the string then moves to the front.
+
+// streamStrings is the number of strings a stream remembers.
+const streamStrings = 32
+
+// streamStringMiss is the position coded for strings that are not
+// remembered.
+const streamStringMiss = streamStrings
+
+// stringDictionary holds the recently coded strings of a stream.
+type stringDictionary struct {
+	recent   []string               // Most recently coded first
+	position *models.FrequencyTable // Positions in recent, and streamStringMiss
+}
+
+func newStringDictionary() *stringDictionary {
+	// Strings are new more often than not, and a repeated string is most
+	// likely one of the last few
+	freqs := make([]uint64, streamStrings+1)
+	for i := range streamStrings {
+		freqs[i] = 1
+	}
+	freqs[0], freqs[1] = 4, 2
+	freqs[streamStringMiss] = 8
+	position := models.NewFrequencyTable(freqs)
+	position.SetMaxTotal(streamMaxTotal)
+	return &stringDictionary{
+		recent:   make([]string, 0, streamStrings),
+		position: position,
+	}
+}
+
+// lookup returns the position of s, or streamStringMiss.
+func (d *stringDictionary) lookup(s string) int {
+	if i := slices.Index(d.recent, s); i >= 0 {
+		return i
+	}
+	return streamStringMiss
+}
+
+// use moves the string at position to the front, or adds s at the front
+// for streamStringMiss.
+func (d *stringDictionary) use(position int, s string) {
+	if position == streamStringMiss {
+		if len(d.recent) < streamStrings {
+			d.recent = append(d.recent, "")
+		}
+		position = len(d.recent) - 1
+	}
+	copy(d.recent[1:position+1], d.recent[:position])
+	d.recent[0] = s
+}
+
+// encodeString encodes s as a reference to a remembered string or in full.
+func (d *stringDictionary) encodeString(enc coder.SymbolEncoder, mb *ModelBuilder, s string) error {
+	position := d.lookup(s)
+	if err := enc.Encode(position, d.position); err != nil {
+		return err
+	}
+	d.position.Add(position, streamIncrement)
+	if position == streamStringMiss {
+		if err := mb.encodeString(enc, s); err != nil {
+			return err
+		}
+	}
+	d.use(position, s)
+	return nil
+}
+
+// decodeString decodes a string written by encodeString.
+func (d *stringDictionary) decodeString(dec coder.SymbolDecoder, mb *ModelBuilder) (string, error) {
+	position, err := dec.Decode(d.position)
+	if err != nil {
+		return "", err
+	}
+	d.position.Add(position, streamIncrement)
+
+	var s string
+	switch {
+	case position == streamStringMiss:
+		if s, err = mb.decodeString(dec); err != nil {
+			return "", err
+		}
+	case position < len(d.recent):
+		s = d.recent[position]
+	default:
+		return "", errors.New("string refers to an unknown earlier string")
+	}
+	d.use(position, s)
+	return s, nil
+}
+
+// hashState writes the remembered strings and the position model to h.
+func (d *stringDictionary) hashState(h hash.Hash) {
+	for _, s := range d.recent {
+		fmt.Fprintf(h, "%q\x00", s)
+	}
+	d.position.HashState(h)
+}
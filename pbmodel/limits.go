@@ -0,0 +1,112 @@
+package pbmodel
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// Limits of the compressed format. Implementations in other languages must
+// support messages up to these limits to decode everything Compress writes.
+const (
+	// MaxMessageSize is the largest message, in bytes of its protobuf
+	// encoding, that the compressors support. It is the protobuf limit.
+	MaxMessageSize = math.MaxInt32
+
+	// MaxDepth is the deepest nesting of messages that the compressors
+	// support. The top-level message has depth 1.
+	MaxDepth = 100
+
+	// MaxStringLength is the longest string field, in bytes of UTF-8.
+	MaxStringLength = models.MaxLength
+
+	// MaxBytesLength is the longest bytes field.
+	MaxBytesLength = models.MaxLength
+)
+
+// Decompression trusts the lengths in its input, and a few bytes of hostile
+// input can declare a field of gigabytes, a list of billions of elements or
+// strings that never end. Limits bound what decompression allocates and how
+// deeply it recurses; data over the limits fails with ErrLimit. Compression
+// is not limited, so data must be decompressed with limits at least as
+// large as the messages.
+
+var (
+	// ErrLimit is returned when decompressed data exceeds the Limits.
+	ErrLimit = errors.New("pbmodel: decompression limit exceeded")
+
+	// ErrMaxDepth is returned for messages nested deeper than
+	// Limits.MaxDepth, such as the values of recursive types or corrupted
+	// data. It wraps ErrLimit.
+	ErrMaxDepth = fmt.Errorf("%w: message nesting too deep", ErrLimit)
+)
+
+// Limits bound the messages that decompression accepts, see WithLimits.
+type Limits struct {
+	MaxBytes     int // Longest bytes field, and longest coded string or unknown fields
+	MaxStringLen int // Longest string field, in bytes of UTF-8
+	MaxListLen   int // Most elements of a list or entries of a map
+	MaxDepth     int // Deepest nesting of messages; the top-level message has depth 1, compression is limited too
+}
+
+// DefaultLimits are the limits of decompression without WithLimits.
+var DefaultLimits = Limits{
+	MaxBytes:     1 << 24,
+	MaxStringLen: 1 << 24,
+	MaxListLen:   1 << 20,
+	MaxDepth:     MaxDepth,
+}
+
+// WithLimits sets the limits of decompression. Zero fields keep the
+// DefaultLimits; the limits cannot exceed the limits of the format, such
+// as MaxBytesLength.
+func WithLimits(limits Limits) Option {
+	return func(o *options) { o.limits = limits }
+}
+
+// withDefaults returns the limits with the zero fields set to the defaults.
+func (l Limits) withDefaults() Limits {
+	if l.MaxBytes == 0 {
+		l.MaxBytes = DefaultLimits.MaxBytes
+	}
+	if l.MaxStringLen == 0 {
+		l.MaxStringLen = DefaultLimits.MaxStringLen
+	}
+	if l.MaxListLen == 0 {
+		l.MaxListLen = DefaultLimits.MaxListLen
+	}
+	if l.MaxDepth == 0 {
+		l.MaxDepth = DefaultLimits.MaxDepth
+	}
+	return l
+}
+
+// check verifies that the limits are within the limits of the format.
+func (l Limits) check() error {
+	switch {
+	case l.MaxBytes < 0 || l.MaxBytes > MaxBytesLength:
+		return fmt.Errorf("pbmodel: MaxBytes %d outside 1..%d", l.MaxBytes, MaxBytesLength)
+	case l.MaxStringLen < 0 || l.MaxStringLen > MaxStringLength:
+		return fmt.Errorf("pbmodel: MaxStringLen %d outside 1..%d", l.MaxStringLen, MaxStringLength)
+	case l.MaxListLen < 0 || l.MaxListLen > math.MaxInt32:
+		return fmt.Errorf("pbmodel: MaxListLen %d outside 1..%d", l.MaxListLen, math.MaxInt32)
+	case l.MaxDepth < 0 || l.MaxDepth > MaxDepth:
+		return fmt.Errorf("pbmodel: MaxDepth %d outside 1..%d", l.MaxDepth, MaxDepth)
+	}
+	return nil
+}
+
+// depthError reports nesting deeper than limit.
+func depthError(limit int) error {
+	return fmt.Errorf("message nesting exceeds %d: %w", limit, ErrMaxDepth)
+}
+
+// checkLimit verifies a decoded length against its limit.
+func checkLimit(what string, length uint64, limit int) error {
+	if length > uint64(limit) {
+		return fmt.Errorf("%s %d exceeds limit %d: %w", what, length, limit, ErrLimit)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package pbmodel
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+// nestedValue returns a structpb.Value nested in lists n times. Every list
+// adds two levels of messages: the ListValue and the Value in it.
+func nestedValue(n int) *structpb.Value {
+	value := structpb.NewStringValue("leaf")
+	for range n {
+		value = structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{value}})
+	}
+	return value
+}
+
+func TestMaxDepth(t *testing.T) {
+	shallow := nestedValue((MaxDepth - 1) / 2)
+	var buf bytes.Buffer
+	if err := Compress(shallow, &buf); err != nil {
+		t.Fatalf("Compress at MaxDepth failed: %v", err)
+	}
+	decoded := &structpb.Value{}
+	if err := Decompress(&buf, decoded); err != nil {
+		t.Fatalf("Decompress at MaxDepth failed: %v", err)
+	}
+	if !proto.Equal(shallow, decoded) {
+		t.Error("roundtrip mismatch at MaxDepth")
+	}
+
+	deep := nestedValue(MaxDepth / 2)
+	if err := Compress(deep, &bytes.Buffer{}); !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("nesting deeper than MaxDepth: got %v, want ErrMaxDepth", err)
+	}
+	if err := AdaptiveCompress(deep, &bytes.Buffer{}); !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("adaptive nesting deeper than MaxDepth: got %v, want ErrMaxDepth", err)
+	}
+	if err := CompressDelta(deep, nestedValue(1), &bytes.Buffer{}); !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("delta nesting deeper than MaxDepth: got %v, want ErrMaxDepth", err)
+	}
+
+	// A configured limit applies to both directions
+	buf.Reset()
+	if err := Compress(nestedValue(3), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := Decompress(&buf, &structpb.Value{}, WithLimits(Limits{MaxDepth: 6})); !errors.Is(err, ErrMaxDepth) || !errors.Is(err, ErrLimit) {
+		t.Errorf("decompress deeper than the limit: got %v, want ErrMaxDepth", err)
+	}
+	if err := Compress(nestedValue(3), &bytes.Buffer{}, WithLimits(Limits{MaxDepth: 6})); !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("compress deeper than the limit: got %v, want ErrMaxDepth", err)
+	}
+}
+
+// hostileStream returns a stream for msg whose first field is present and
+// declares length, as a list, map or bytes field.
+func hostileStream(t *testing.T, msg proto.Message, length uint64) []byte {
+	t.Helper()
+
+	mb := NewModelBuilder()
+	fd := msg.ProtoReflect().Descriptor().Fields().Get(0)
+	var header [HeaderSize]byte
+	putHeader(&header, formatMessage, 0)
+	buf := bytes.NewBuffer(header[:])
+	enc := coder.NewEncoder(buf)
+	if err := mb.encodePresence(enc, fd, 1); err != nil {
+		t.Fatal(err)
+	}
+	if fd.Kind() == protoreflect.BytesKind {
+		if err := enc.Encode(0, mb.boolModel); err != nil { // Not text
+			t.Fatal(err)
+		}
+	}
+	if err := mb.encodeVarint(enc, length); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLimitsHostile(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    proto.Message
+		length uint64
+	}{
+		{"list", &testdata.RepeatedMessage{}, 1 << 30},
+		{"bytes", &testdata.MessageWithBytes{}, 1 << 30},
+		{"huge bytes", &testdata.MessageWithBytes{}, 1 << 62},
+	}
+	for _, test := range tests {
+		data := hostileStream(t, test.msg, test.length)
+		err := Decompress(bytes.NewReader(data), test.msg)
+		if !errors.Is(err, ErrLimit) {
+			t.Errorf("%s: %d-byte input declaring %d: got %v, want ErrLimit", test.name, len(data), test.length, err)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	msg := &testdata.RepeatedMessage{
+		Numbers: []int32{1, 2, 3, 4, 5},
+		Words:   []string{"a rather long string"},
+	}
+	var buf bytes.Buffer
+	if err := Compress(msg, &buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	tests := []struct {
+		limits Limits
+		ok     bool
+	}{
+		{Limits{}, true},
+		{Limits{MaxListLen: 5, MaxStringLen: 20}, true},
+		{Limits{MaxListLen: 4}, false},
+		{Limits{MaxStringLen: 19}, false},
+		{Limits{MaxDepth: 1}, true},
+	}
+	for _, test := range tests {
+		decoded := &testdata.RepeatedMessage{}
+		err := Decompress(bytes.NewReader(data), decoded, WithLimits(test.limits))
+		switch {
+		case test.ok && err != nil:
+			t.Errorf("%+v: Decompress failed: %v", test.limits, err)
+		case test.ok && !proto.Equal(msg, decoded):
+			t.Errorf("%+v: roundtrip mismatch", test.limits)
+		case !test.ok && !errors.Is(err, ErrLimit):
+			t.Errorf("%+v: got %v, want ErrLimit", test.limits, err)
+		}
+	}
+
+	// Nesting deeper than the limit
+	nested := nestedValue(2)
+	buf.Reset()
+	if err := Compress(nested, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := Decompress(&buf, &structpb.Value{}, WithLimits(Limits{MaxDepth: 4})); !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("nesting: got %v, want ErrMaxDepth", err)
+	}
+
+	if err := Decompress(bytes.NewReader(data), &testdata.RepeatedMessage{}, WithLimits(Limits{MaxBytes: MaxBytesLength + 1})); err == nil {
+		t.Error("limits above the format limits accepted")
+	}
+}
+
+func TestLimitsStringOrder(t *testing.T) {
+	// 7 bytes declaring a string of hundreds of millions of characters
+	data, err := hex.DecodeString("43c88f7d2fa240")
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits := WithLimits(Limits{MaxStringLen: 100, MaxBytes: 100})
+	for _, msg := range []proto.Message{&testdata.SimpleMessage{}, &testdata.UserProfile{}, &testdata.MessageWithBytes{}} {
+		start := time.Now()
+		err := Decompress(bytes.NewReader(data), msg, WithStringOrder(1), limits, WithoutHeader())
+		if !errors.Is(err, ErrLimit) {
+			t.Errorf("%T: got %v, want ErrLimit", msg, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%T: took %v", msg, elapsed)
+		}
+	}
+}
+
+func TestLimitsTextBytes(t *testing.T) {
+	msg := &testdata.MessageWithBytes{Data: []byte("a rather long text in bytes")}
+	var buf bytes.Buffer
+	if err := Compress(msg, &buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	decoded := &testdata.MessageWithBytes{}
+	if err := Decompress(bytes.NewReader(data), decoded, WithLimits(Limits{MaxBytes: len(msg.Data)})); err != nil || !proto.Equal(msg, decoded) {
+		t.Errorf("within the limit: got %v, %v", decoded, err)
+	}
+	if err := Decompress(bytes.NewReader(data), decoded, WithLimits(Limits{MaxBytes: len(msg.Data) - 1})); !errors.Is(err, ErrLimit) {
+		t.Errorf("got %v, want ErrLimit", err)
+	}
+}
+
+func TestLimitsAdaptive(t *testing.T) {
+	var buf bytes.Buffer
+	enc := coder.NewEncoder(&buf)
+	amb := NewAdaptiveModelBuilder()
+	if err := enc.Encode(1, amb.boolModel); err != nil { // data present
+		t.Fatal(err)
+	}
+	for _, b := range EncodeVarint(1 << 40) {
+		if err := enc.Encode(int(b), amb.byteModel); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := AdaptiveDecompress(&buf, &testdata.MessageWithBytes{}, WithoutHeader()); !errors.Is(err, ErrLimit) {
+		t.Errorf("got %v, want ErrLimit", err)
+	}
+}
@@ -0,0 +1,145 @@
+package pbmodel
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// Repeated integers often hold related values, such as the node numbers of a
+// route or the readings of a sensor, where the difference to the previous
+// element is much smaller than the element itself. With list transforms
+// every list of two or more integers starts with a flag selecting how its
+// elements are coded: as they are, or the first element as it is and every
+// following one as the zigzag encoded difference to the previous element.
+// The order of the elements is kept, so lists are not sorted.
+
+// Symbols of the list transform flag.
+const (
+	listPlain = 0
+	listDelta = 1
+)
+
+// WithListTransforms codes each list of integers either directly or as the
+// differences of consecutive elements, whichever is smaller.
+func WithListTransforms() Option {
+	return func(o *options) { o.listTransforms = true }
+}
+
+// transformsList reports whether lists of fd are coded with a transform.
+func (mb *ModelBuilder) transformsList(fd protoreflect.FieldDescriptor, length int) bool {
+	if !mb.listTransforms || length < 2 || lookupHint(fd) != nil {
+		return false
+	}
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Int64Kind,
+		protoreflect.Uint32Kind, protoreflect.Uint64Kind,
+		protoreflect.Sint32Kind, protoreflect.Sint64Kind,
+		protoreflect.Fixed32Kind, protoreflect.Fixed64Kind,
+		protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind:
+		return true
+	}
+	return false
+}
+
+// listInteger returns an integer element of fd, unsigned values with
+// wraparound.
+func listInteger(fd protoreflect.FieldDescriptor, value protoreflect.Value) int64 {
+	if isUnsignedKind(fd.Kind()) {
+		return int64(value.Uint())
+	}
+	return value.Int()
+}
+
+// chooseListTransform returns the transform with the shortest varints for
+// the elements of list, counting fixed-width elements by their width.
+func chooseListTransform(fd protoreflect.FieldDescriptor, list protoreflect.List) int {
+	first := plainSize(fd, listInteger(fd, list.Get(0)))
+	plain, delta := first, first
+	prev := listInteger(fd, list.Get(0))
+	for i := 1; i < list.Len(); i++ {
+		v := listInteger(fd, list.Get(i))
+		plain += plainSize(fd, v)
+		delta += protowire.SizeVarint(ZigzagEncode(v - prev))
+		prev = v
+	}
+	if delta < plain {
+		return listDelta
+	}
+	return listPlain
+}
+
+// plainSize returns the size of an integer element v of fd coded as it is.
+func plainSize(fd protoreflect.FieldDescriptor, v int64) int {
+	switch fd.Kind() {
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind:
+		return 4
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind:
+		return 8
+	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		return protowire.SizeVarint(ZigzagEncode(v))
+	default:
+		return protowire.SizeVarint(uint64(v))
+	}
+}
+
+// encodeListTransform encodes the transform flag and, for delta coded lists,
+// the elements of list.
+func (mb *ModelBuilder) encodeListTransform(enc coder.SymbolEncoder, fd protoreflect.FieldDescriptor, list protoreflect.List) (done bool, err error) {
+	transform := chooseListTransform(fd, list)
+	if err := enc.Encode(transform, mb.boolModel); err != nil {
+		return false, fmt.Errorf("list transform: %w", err)
+	}
+	if transform == listPlain {
+		return false, nil
+	}
+
+	if err := compressFieldValue(fd, list.Get(0), enc, mb); err != nil {
+		return false, fmt.Errorf("list element 0: %w", err)
+	}
+	prev := listInteger(fd, list.Get(0))
+	for i := 1; i < list.Len(); i++ {
+		v := listInteger(fd, list.Get(i))
+		if err := mb.encodeVarint(enc, ZigzagEncode(v-prev)); err != nil {
+			return false, fmt.Errorf("list element %d: %w", i, err)
+		}
+		prev = v
+	}
+	return true, nil
+}
+
+// decodeListTransform decodes the transform flag and, for delta coded lists,
+// the length elements into list.
+func (mb *ModelBuilder) decodeListTransform(dec coder.SymbolDecoder, fd protoreflect.FieldDescriptor, list protoreflect.List, length int) (done bool, err error) {
+	transform, err := dec.Decode(mb.boolModel)
+	if err != nil {
+		return false, fmt.Errorf("list transform: %w", err)
+	}
+	if transform == listPlain {
+		return false, nil
+	}
+
+	first, err := decompressFieldValue(fd, dec, mb)
+	if err != nil {
+		return false, fmt.Errorf("list element 0: %w", err)
+	}
+	list.Append(first)
+	prev := listInteger(fd, first)
+	for i := 1; i < length; i++ {
+		delta, err := mb.decodeVarint(dec)
+		if err != nil {
+			return false, fmt.Errorf("list element %d: %w", i, err)
+		}
+		v := prev + ZigzagDecode(delta)
+		value, err := mb.integerValue(fd, v)
+		if err != nil {
+			return false, fmt.Errorf("list element %d: %w", i, err)
+		}
+		list.Append(value)
+		prev = v
+	}
+	return true, nil
+}
@@ -0,0 +1,55 @@
+package pbmodel
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestListTransforms(t *testing.T) {
+	// Node numbers of a route through a mesh of consecutively numbered nodes
+	route := make([]int32, 40)
+	for i := range route {
+		route[i] = 0x7a3c1000 + int32(i*3%7)
+	}
+
+	messages := []*testdata.RepeatedMessage{
+		{Numbers: route},
+		{Numbers: []int32{math.MaxInt32, math.MinInt32, math.MaxInt32, 0, -1}},
+		{Numbers: []int32{5, 3000000, -7, 12}},
+		{Numbers: []int32{42}},
+		{Numbers: route, Words: []string{"a", "b"}},
+	}
+	for _, backend := range []Backend{Arithmetic, Huffman} {
+		for _, msg := range messages {
+			var buf bytes.Buffer
+			if err := Compress(msg, &buf, WithBackend(backend), WithListTransforms()); err != nil {
+				t.Fatalf("backend %v: Compress failed: %v", backend, err)
+			}
+			decoded := &testdata.RepeatedMessage{}
+			if err := Decompress(&buf, decoded, WithBackend(backend), WithListTransforms()); err != nil {
+				t.Fatalf("backend %v: Decompress failed: %v", backend, err)
+			}
+			if !proto.Equal(msg, decoded) {
+				t.Errorf("backend %v: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", backend, msg, decoded)
+			}
+		}
+	}
+
+	var plain, transformed bytes.Buffer
+	msg := &testdata.RepeatedMessage{Numbers: route}
+	if err := Compress(msg, &plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := Compress(msg, &transformed, WithListTransforms()); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("route: %d bytes, %d bytes with list transforms", plain.Len(), transformed.Len())
+	if transformed.Len()*3 > plain.Len() {
+		t.Errorf("route with list transforms %d bytes, want at most a third of %d bytes", transformed.Len(), plain.Len())
+	}
+}
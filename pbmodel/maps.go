@@ -0,0 +1,44 @@
+package pbmodel
+
+import (
+	"cmp"
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Go ranges over maps in a random order. The compressors code the entries
+// of map fields in the order of their keys instead, so that compressing a
+// message always produces the same bytes.
+
+// RangeMapSorted calls f for the entries of m in ascending key order: false
+// before true, integers numerically and strings by their bytes. It stops
+// when f returns false.
+func RangeMapSorted(m protoreflect.Map, f func(protoreflect.MapKey, protoreflect.Value) bool) {
+	keys := make([]protoreflect.MapKey, 0, m.Len())
+	m.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	slices.SortFunc(keys, compareMapKeys)
+
+	for _, k := range keys {
+		if !f(k, m.Get(k)) {
+			return
+		}
+	}
+}
+
+// compareMapKeys compares two keys of the same map.
+func compareMapKeys(a, b protoreflect.MapKey) int {
+	switch av := a.Interface().(type) {
+	case bool:
+		return cmp.Compare(boolSymbol(av), boolSymbol(b.Bool()))
+	case int32, int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case uint32, uint64:
+		return cmp.Compare(a.Uint(), b.Uint())
+	default:
+		return cmp.Compare(a.String(), b.String())
+	}
+}
@@ -0,0 +1,49 @@
+package pbmodel
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestMapDeterministic(t *testing.T) {
+	newMessage := func() *testdata.MessageWithMap {
+		msg := &testdata.MessageWithMap{Counts: map[string]int32{}, Lookup: map[int32]string{}}
+		for i := range 50 {
+			msg.Counts[fmt.Sprintf("key-%d", i*7%50)] = int32(i)
+			msg.Lookup[int32(i*13%50-25)] = fmt.Sprint(i)
+		}
+		return msg
+	}
+
+	compressors := []struct {
+		name     string
+		compress func(proto.Message, io.Writer) error
+	}{
+		{"default", func(msg proto.Message, w io.Writer) error { return Compress(msg, w) }},
+		{"huffman", func(msg proto.Message, w io.Writer) error { return Compress(msg, w, WithBackend(Huffman)) }},
+		{"adaptive", func(msg proto.Message, w io.Writer) error { return AdaptiveCompress(msg, w) }},
+	}
+	for _, c := range compressors {
+		var first []byte
+		for range 10 {
+			// A new map each time, so that the map layout differs as well
+			var buf bytes.Buffer
+			if err := c.compress(newMessage(), &buf); err != nil {
+				t.Fatalf("%s: compress failed: %v", c.name, err)
+			}
+			if first == nil {
+				first = buf.Bytes()
+				continue
+			}
+			if !bytes.Equal(first, buf.Bytes()) {
+				t.Fatalf("%s: output differs between runs", c.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package pbmodel
+
+import (
+	"bytes"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Pools for Marshal and Unmarshal, and for Compress and Decompress without
+// options. A Codec keeps its models and coder between messages, so pooled
+// codecs make repeated calls cheap.
+var (
+	codecPool  = sync.Pool{New: func() any { return NewCodec() }}
+	bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+	readerPool = sync.Pool{New: func() any { return new(bytes.Reader) }}
+)
+
+// Marshal compresses msg in the format of Compress and returns the
+// compressed data. It uses pooled buffers and models, so it suits callers
+// that compress many small messages.
+func Marshal(msg proto.Message) ([]byte, error) {
+	codec := codecPool.Get().(*Codec)
+	defer codecPool.Put(codec)
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer bufferPool.Put(buf)
+
+	buf.Reset()
+	if err := codec.Compress(msg, buf); err != nil {
+		return nil, err
+	}
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+// Unmarshal decompresses data written by Marshal or Compress into msg.
+func Unmarshal(data []byte, msg proto.Message) error {
+	codec := codecPool.Get().(*Codec)
+	defer codecPool.Put(codec)
+	r := readerPool.Get().(*bytes.Reader)
+	defer readerPool.Put(r)
+
+	r.Reset(data)
+	err := codec.Decompress(r, msg)
+	r.Reset(nil)
+	return err
+}
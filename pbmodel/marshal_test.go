@@ -0,0 +1,95 @@
+package pbmodel
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestMarshalMatchesCompress(t *testing.T) {
+	messages := []proto.Message{
+		&testdata.SimpleMessage{Id: 12345, Name: "Alice", Active: true},
+		&testdata.MessageWithBytes{Data: []byte{0, 0, 1, 2, 3, 0xFF}, Label: "binary data"},
+		&testdata.MessageWithEnum{Status: testdata.Status_ACTIVE, Description: "active"},
+		&testdata.SimpleMessage{},
+	}
+
+	var previous []byte
+	for i, msg := range messages {
+		var want bytes.Buffer
+		if err := Compress(msg, &want); err != nil {
+			t.Fatalf("message %d: Compress failed: %v", i, err)
+		}
+		data, err := Marshal(msg)
+		if err != nil {
+			t.Fatalf("message %d: Marshal failed: %v", i, err)
+		}
+		if !bytes.Equal(want.Bytes(), data) {
+			t.Fatalf("message %d: Marshal output differs from Compress", i)
+		}
+
+		decoded := msg.ProtoReflect().New().Interface()
+		if err := Unmarshal(data, decoded); err != nil {
+			t.Fatalf("message %d: Unmarshal failed: %v", i, err)
+		}
+		if !proto.Equal(msg, decoded) {
+			t.Errorf("message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", i, msg, decoded)
+		}
+
+		// The returned data must not share the pooled buffer
+		if previous != nil {
+			if err := Unmarshal(previous, messages[i-1].ProtoReflect().New().Interface()); err != nil {
+				t.Errorf("message %d: data of the previous Marshal was overwritten: %v", i, err)
+			}
+		}
+		previous = data
+	}
+
+	if err := Unmarshal(nil, &testdata.SimpleMessage{}); err != ErrTruncated {
+		t.Errorf("Unmarshal of empty input: got %v, want ErrTruncated", err)
+	}
+}
+
+func TestMarshalConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			for k := range 50 {
+				msg := &testdata.SimpleMessage{Id: int32(i*100 + k), Name: "concurrent", Active: k%2 == 0}
+				data, err := Marshal(msg)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				decoded := &testdata.SimpleMessage{}
+				if err := Unmarshal(data, decoded); err != nil {
+					t.Error(err)
+					return
+				}
+				if !proto.Equal(msg, decoded) {
+					t.Errorf("roundtrip mismatch: %v != %v", msg, decoded)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestMarshalAllocations(t *testing.T) {
+	msg := &testdata.MessageWithBytes{Data: []byte{0, 0, 1, 2, 3, 0xFF}, Label: "binary data"}
+	marshal := func() {
+		if _, err := Marshal(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	marshal()
+	// Only the returned slice is allocated
+	if allocs := testing.AllocsPerRun(100, marshal); allocs > 1 {
+		t.Errorf("Marshal allocates %v times per message, want 1", allocs)
+	}
+}
@@ -0,0 +1,256 @@
+package pbmodel
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/huffman"
+)
+
+// A partial update, such as a change of a few settings of a configuration,
+// touches a handful of fields of a large message. Masked compression codes
+// only the fields selected by a field mask known to both sides, in the
+// order of their declaration: the fields at the end of a path are coded as
+// usual, the messages along a path by their presence and their masked
+// fields. Fields outside of the mask cost nothing, not even their presence.
+// Unknown and deprecated fields are not coded.
+
+// fieldMask is a field mask as a tree of field names. A field mapped to nil
+// is selected as a whole.
+type fieldMask map[protoreflect.Name]fieldMask
+
+// newFieldMask returns the tree of the paths of mask within md. Every name
+// of a path but the last must be a singular message field.
+func newFieldMask(md protoreflect.MessageDescriptor, mask *fieldmaskpb.FieldMask) (fieldMask, error) {
+	root := fieldMask{}
+	for _, path := range mask.GetPaths() {
+		node, nodeDesc := root, md
+		names := strings.Split(path, ".")
+		for i, name := range names {
+			fd := nodeDesc.Fields().ByName(protoreflect.Name(name))
+			if fd == nil {
+				return nil, fmt.Errorf("pbmodel: mask path %q: %s has no field %q", path, nodeDesc.FullName(), name)
+			}
+			if i == len(names)-1 {
+				node[fd.Name()] = nil
+				break
+			}
+			if fd.IsList() || fd.IsMap() || fd.Message() == nil {
+				return nil, fmt.Errorf("pbmodel: mask path %q: %s is not a singular message field", path, fd.FullName())
+			}
+			child, ok := node[fd.Name()]
+			if ok && child == nil {
+				// An earlier path selects the whole field
+				break
+			}
+			if !ok {
+				child = fieldMask{}
+				node[fd.Name()] = child
+			}
+			node, nodeDesc = child, fd.Message()
+		}
+	}
+	return root, nil
+}
+
+// CompressMasked compresses the fields of msg selected by mask. The data
+// must be decompressed with DecompressMasked, the same mask and the same
+// options. The checksum of WithChecksum covers the selected fields.
+func CompressMasked(msg proto.Message, mask *fieldmaskpb.FieldMask, w io.Writer, opts ...Option) error {
+	o, err := collectOptions(opts)
+	if err != nil {
+		return err
+	}
+	m := msg.ProtoReflect()
+	fm, err := newFieldMask(m.Descriptor(), mask)
+	if err != nil {
+		return err
+	}
+	if o.checksum {
+		// Only the selected fields are coded and checksummed
+		selected := m.New()
+		copyMasked(selected, m, fm)
+		msg = selected.Interface()
+	}
+	if err := o.writeHeader(w, formatMasked); err != nil {
+		return err
+	}
+	mb := newModelBuilderWithOptions(o)
+	compress := func(m protoreflect.Message, enc coder.SymbolEncoder, mb *ModelBuilder) error {
+		return compressMaskedMessage(m, fm, enc, mb)
+	}
+
+	if o.backend == Huffman {
+		enc := huffman.NewEncoder(w)
+		if err := compressRoot(msg, enc, mb, o, compress); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	enc := coder.NewEncoder(w)
+	if o.stats != nil {
+		mb.stats = newStatsRecorder(o.stats, enc)
+	}
+	if err := compressRoot(msg, enc, mb, o, compress); err != nil {
+		return err
+	}
+	if o.stats != nil {
+		o.stats.Total = enc.Bits()
+	}
+	return enc.Close()
+}
+
+// DecompressMasked decompresses data written by CompressMasked into msg.
+// The fields of msg selected by mask are replaced by the decompressed ones,
+// the rest of msg is left as it is. The mask and the options must match the
+// ones the data was compressed with.
+func DecompressMasked(r io.Reader, mask *fieldmaskpb.FieldMask, msg proto.Message, opts ...Option) error {
+	o, err := collectOptions(opts)
+	if err != nil {
+		return err
+	}
+	m := msg.ProtoReflect()
+	fm, err := newFieldMask(m.Descriptor(), mask)
+	if err != nil {
+		return err
+	}
+	if err := o.readHeader(r, formatMasked); err != nil {
+		return err
+	}
+	mb := newModelBuilderWithOptions(o)
+	decompress := func(m protoreflect.Message, dec coder.SymbolDecoder, mb *ModelBuilder) error {
+		return decompressMaskedMessage(m, fm, dec, mb)
+	}
+
+	dec, err := newBackendDecoder(r, o.backend)
+	if err != nil {
+		return err
+	}
+	// The selected fields are decompressed on their own, as they were
+	// checksummed, and replace the ones of msg once they are verified
+	selected := m.New()
+	if err := decompressRoot(selected.Interface(), dec, mb, o, decompress); err != nil {
+		return err
+	}
+	if err := mb.finish(dec); err != nil {
+		return err
+	}
+	copyMasked(m, selected, fm)
+	return nil
+}
+
+// compressMaskedMessage compresses the fields of msg selected by mask.
+func compressMaskedMessage(msg protoreflect.Message, mask fieldMask, enc coder.SymbolEncoder, mb *ModelBuilder) error {
+	if mb.depth >= mb.limits.MaxDepth {
+		return depthError(mb.limits.MaxDepth)
+	}
+	mb.depth++
+	defer func() { mb.depth-- }()
+
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		child, ok := mask[fd.Name()]
+		if !ok {
+			continue
+		}
+		if child == nil {
+			if err := compressField(msg, fd, enc, mb); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !msg.Has(fd) {
+			if err := mb.encodePresence(enc, fd, 0); err != nil {
+				return fmt.Errorf("field %s presence: %w", fd.Name(), err)
+			}
+			continue
+		}
+		if err := mb.encodePresence(enc, fd, 1); err != nil {
+			return fmt.Errorf("field %s presence: %w", fd.Name(), err)
+		}
+		if err := compressMaskedMessage(msg.Get(fd).Message(), child, enc, mb); err != nil {
+			return fmt.Errorf("field %s: %w", fd.Name(), err)
+		}
+	}
+	return nil
+}
+
+// decompressMaskedMessage decompresses the fields written by
+// compressMaskedMessage into msg, an empty message.
+func decompressMaskedMessage(msg protoreflect.Message, mask fieldMask, dec coder.SymbolDecoder, mb *ModelBuilder) error {
+	if mb.depth >= mb.limits.MaxDepth {
+		return depthError(mb.limits.MaxDepth)
+	}
+	mb.depth++
+	defer func() { mb.depth-- }()
+
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		child, ok := mask[fd.Name()]
+		if !ok {
+			continue
+		}
+		if child == nil {
+			if err := decompressField(msg, fd, dec, mb); err != nil {
+				return err
+			}
+			continue
+		}
+
+		present, err := mb.decodePresence(dec, fd)
+		if err != nil {
+			return fmt.Errorf("field %s presence: %w", fd.Name(), err)
+		}
+		if present == 0 {
+			continue
+		}
+		if err := decompressMaskedMessage(msg.Mutable(fd).Message(), child, dec, mb); err != nil {
+			return fmt.Errorf("field %s: %w", fd.Name(), err)
+		}
+	}
+	return nil
+}
+
+// copyMasked replaces the fields of dst selected by mask with the ones of
+// src. A message along a path that is not set in src clears the selected
+// fields of dst.
+func copyMasked(dst, src protoreflect.Message, mask fieldMask) {
+	fields := dst.Descriptor().Fields()
+	for name, child := range mask {
+		fd := fields.ByName(name)
+		switch {
+		case child == nil && src.Has(fd):
+			dst.Set(fd, src.Get(fd))
+		case child == nil:
+			dst.Clear(fd)
+		case src.Has(fd):
+			copyMasked(dst.Mutable(fd).Message(), src.Get(fd).Message(), child)
+		case dst.Has(fd):
+			clearMasked(dst.Mutable(fd).Message(), child)
+		}
+	}
+}
+
+// clearMasked clears the fields of msg selected by mask.
+func clearMasked(msg protoreflect.Message, mask fieldMask) {
+	fields := msg.Descriptor().Fields()
+	for name, child := range mask {
+		fd := fields.ByName(name)
+		switch {
+		case child == nil:
+			msg.Clear(fd)
+		case msg.Has(fd):
+			clearMasked(msg.Mutable(fd).Message(), child)
+		}
+	}
+}
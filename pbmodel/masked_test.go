@@ -0,0 +1,189 @@
+package pbmodel
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func maskedProfile() *testdata.UserProfile {
+	return &testdata.UserProfile{
+		UserId:        42,
+		Username:      "alice",
+		Email:         "alice@example.com",
+		FullName:      "Alice Example",
+		Bio:           "Likes long walks on the mesh.",
+		Tags:          []string{"admin", "early"},
+		AccountStatus: testdata.Status_ACTIVE,
+		Address: &testdata.UserProfile_Address{
+			Street:  "1 Main St",
+			City:    "Springfield",
+			Country: "US",
+		},
+		CreatedAt: 1700000000,
+		UpdatedAt: 1700000100,
+		Metadata:  map[string]string{"theme": "dark"},
+	}
+}
+
+func TestMasked(t *testing.T) {
+	update := &testdata.UserProfile{
+		Email: "alice@example.org",
+		Tags:  []string{"moderator"},
+		Address: &testdata.UserProfile_Address{
+			City:   "Shelbyville",
+			Street: "ignored",
+		},
+		UpdatedAt: 1700000200,
+	}
+	mask := &fieldmaskpb.FieldMask{Paths: []string{"email", "tags", "address.city", "address.zip", "updated_at"}}
+
+	for _, backend := range []Backend{Arithmetic, Huffman} {
+		var buf bytes.Buffer
+		if err := CompressMasked(update, mask, &buf, WithBackend(backend)); err != nil {
+			t.Fatalf("backend %v: CompressMasked failed: %v", backend, err)
+		}
+		var full bytes.Buffer
+		if err := Compress(update, &full, WithBackend(backend)); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("backend %v: masked %d bytes, full %d bytes", backend, buf.Len(), full.Len())
+
+		decoded := maskedProfile()
+		if err := DecompressMasked(&buf, mask, decoded, WithBackend(backend)); err != nil {
+			t.Fatalf("backend %v: DecompressMasked failed: %v", backend, err)
+		}
+		want := maskedProfile()
+		want.Email = update.Email
+		want.Tags = update.Tags
+		want.Address.City = update.Address.City
+		want.UpdatedAt = update.UpdatedAt
+		if !proto.Equal(want, decoded) {
+			t.Errorf("backend %v: got %v, want %v", backend, decoded, want)
+		}
+	}
+
+	// A message along a path that is not set clears the masked fields
+	var buf bytes.Buffer
+	cleared := &fieldmaskpb.FieldMask{Paths: []string{"address.city", "address.street"}}
+	if err := CompressMasked(&testdata.UserProfile{}, cleared, &buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded := maskedProfile()
+	if err := DecompressMasked(&buf, cleared, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.Address; got.City != "" || got.Street != "" || got.Country != "US" {
+		t.Errorf("got address %v, want only the country", got)
+	}
+}
+
+func TestMaskedOptions(t *testing.T) {
+	update := maskedProfile()
+	update.Email = "alice@example.org"
+	mask := &fieldmaskpb.FieldMask{Paths: []string{"email", "address.city"}}
+
+	for _, backend := range []Backend{Arithmetic, Huffman} {
+		opts := []Option{WithBackend(backend), WithChecksum(), WithSchemaFingerprint()}
+		var plain, buf bytes.Buffer
+		if err := CompressMasked(update, mask, &plain, WithBackend(backend)); err != nil {
+			t.Fatal(err)
+		}
+		if err := CompressMasked(update, mask, &buf, opts...); err != nil {
+			t.Fatalf("backend %v: CompressMasked failed: %v", backend, err)
+		}
+		if got, want := buf.Len(), plain.Len()+checksumSize+fingerprintSize; got < want-1 || got > want+1 {
+			t.Errorf("backend %v: %d bytes, want about %d", backend, got, want)
+		}
+
+		// The fields outside of the mask are not part of the checksum
+		decoded := &testdata.UserProfile{Bio: "kept"}
+		if err := DecompressMasked(bytes.NewReader(buf.Bytes()), mask, decoded, opts...); err != nil {
+			t.Fatalf("backend %v: DecompressMasked failed: %v", backend, err)
+		}
+		want := &testdata.UserProfile{
+			Email:   update.Email,
+			Bio:     "kept",
+			Address: &testdata.UserProfile_Address{City: update.Address.City},
+		}
+		if !proto.Equal(want, decoded) {
+			t.Errorf("backend %v: got %v, want %v", backend, decoded, want)
+		}
+
+		// Flipped bits fail the checksum without touching the message
+		data := buf.Bytes()
+		detected := 0
+		for bit := 0; bit < 8*len(data); bit++ {
+			corrupted := bytes.Clone(data)
+			corrupted[bit/8] ^= 1 << (bit % 8)
+			decoded := &testdata.UserProfile{Bio: "kept"}
+			err := DecompressMasked(bytes.NewReader(corrupted), mask, decoded, opts...)
+			if err == nil && !proto.Equal(want, decoded) {
+				t.Errorf("backend %v: bit %d flipped: decoded %v", backend, bit, decoded)
+			}
+			if err != nil && !proto.Equal(&testdata.UserProfile{Bio: "kept"}, decoded) {
+				t.Errorf("backend %v: bit %d flipped: failed with %v but changed the message", backend, bit, err)
+			}
+			if errors.Is(err, ErrChecksum) {
+				detected++
+			}
+		}
+		if detected == 0 {
+			t.Errorf("backend %v: no flipped bit failed the checksum", backend)
+		}
+	}
+
+	var stats Stats
+	if err := CompressMasked(update, mask, &bytes.Buffer{}, WithStats(&stats)); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total == 0 || len(stats.Fields) == 0 {
+		t.Errorf("no stats recorded: %v", &stats)
+	}
+}
+
+func TestMaskedPaths(t *testing.T) {
+	tests := []struct {
+		paths []string
+		valid bool
+	}{
+		{nil, true},
+		{[]string{"address"}, true},
+		{[]string{"address", "address.city"}, true},
+		{[]string{"address.city", "address"}, true},
+		{[]string{"missing"}, false},
+		{[]string{"address.missing"}, false},
+		{[]string{"tags.value"}, false},
+		{[]string{"metadata.theme"}, false},
+		{[]string{"user_id.value"}, false},
+	}
+	for _, test := range tests {
+		mask := &fieldmaskpb.FieldMask{Paths: test.paths}
+		var buf bytes.Buffer
+		err := CompressMasked(maskedProfile(), mask, &buf)
+		if (err == nil) != test.valid {
+			t.Errorf("paths %q: got error %v, want valid %v", test.paths, err, test.valid)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		decoded := &testdata.UserProfile{}
+		if err := DecompressMasked(&buf, mask, decoded); err != nil {
+			t.Fatalf("paths %q: DecompressMasked failed: %v", test.paths, err)
+		}
+		want := &testdata.UserProfile{}
+		if len(test.paths) > 0 {
+			want.Address = maskedProfile().Address
+		}
+		if !proto.Equal(want, decoded) {
+			t.Errorf("paths %q: got %v, want %v", test.paths, decoded, want)
+		}
+	}
+}
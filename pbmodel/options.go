@@ -0,0 +1,116 @@
+package pbmodel
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Option configures Compress and Decompress. Options select how fields are
+// modelled, so data must be decompressed with the same options it was
+// compressed with.
+type Option func(*options)
+
+// options holds the settings of the compressors.
+type options struct {
+	backend        Backend
+	stringOrder    int
+	varintModels   bool
+	trained        *TrainedModelSet
+	unknownFields  bool
+	listTransforms bool
+	backReferences bool
+	bitmaps        bool
+	sparseFields   bool
+	deprecated     *deprecatedFields
+	tolerances     map[protoreflect.FullName]Tolerance
+	canonicalNaNs  bool
+	wellKnown      bool
+	anyResolver    protoregistry.MessageTypeResolver
+	checksum       bool
+	fingerprint    bool
+	limits         Limits
+	stats          *Stats
+	noHeader       bool
+}
+
+// WithBackend selects the entropy coder, see Backend.
+func WithBackend(backend Backend) Option {
+	return func(o *options) { o.backend = backend }
+}
+
+// WithStringOrder selects the English model for strings. Order 0, the
+// default, codes strings directly with an end-of-string symbol. Orders 1
+// and 2 predict each character from the previous ones; a string is coded as
+// the output of the order-1 or order-2 string coder, prefixed with its
+// length.
+func WithStringOrder(order int) Option {
+	return func(o *options) { o.stringOrder = order }
+}
+
+// WithVarintByteModels codes varints, and the lengths of lists, maps and
+// bytes fields, with separate models for the first byte and the
+// continuation bytes instead of a single byte model. It also keeps the
+// string and bytes coding of CompressVarintModels and its string order
+// variants: strings are always coded as the length-prefixed output of the
+// string coder, order 2 with the bigram tables, and bytes with the byte
+// model after their length. Enum models have no escape, so undeclared
+// enum numbers are refused.
+func WithVarintByteModels() Option {
+	return func(o *options) { o.varintModels = true }
+}
+
+// collectOptions applies opts to the default options.
+func collectOptions(opts []Option) (options, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.stringOrder < 0 || o.stringOrder > 2 {
+		return o, fmt.Errorf("pbmodel: unsupported string order %d", o.stringOrder)
+	}
+	for name, tolerance := range o.tolerances {
+		if err := tolerance.check(); err != nil {
+			return o, fmt.Errorf("pbmodel: field %s: %w", name, err)
+		}
+	}
+	o.limits = o.limits.withDefaults()
+	if err := o.limits.check(); err != nil {
+		return o, err
+	}
+	if err := o.checkChecksum(); err != nil {
+		return o, err
+	}
+	if o.bitmaps && o.sparseFields {
+		return o, fmt.Errorf("pbmodel: presence bitmaps with sparse fields")
+	}
+	if o.stats != nil && o.backend == Huffman {
+		return o, fmt.Errorf("pbmodel: stats with the Huffman backend")
+	}
+	return o, nil
+}
+
+// newModelBuilderWithOptions creates a model builder for the options.
+func newModelBuilderWithOptions(o options) *ModelBuilder {
+	mb := NewModelBuilder()
+	mb.stringOrder = o.stringOrder
+	if o.varintModels {
+		mb.varintBytes = newVarintByteModels()
+	}
+	mb.trained = o.trained
+	mb.unknownFields = o.unknownFields
+	mb.listTransforms = o.listTransforms
+	mb.backReferences = o.backReferences
+	if o.bitmaps {
+		mb.bitmaps = newPresenceBitmaps(o.backend != Huffman)
+	}
+	mb.sparseFields = o.sparseFields
+	mb.deprecated = o.deprecated
+	mb.tolerances = o.tolerances
+	mb.canonicalNaNs = o.canonicalNaNs
+	mb.wellKnown = o.wellKnown
+	mb.anyResolver = o.anyResolver
+	mb.limits = o.limits
+	return mb
+}
@@ -0,0 +1,97 @@
+package pbmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestOptionCombinations(t *testing.T) {
+	binaryData := make([]byte, 200)
+	for i := range binaryData {
+		binaryData[i] = byte(i * i % 7)
+	}
+	messages := []proto.Message{
+		&testdata.SimpleMessage{Id: 12345, Name: "Alice", Active: true},
+		&testdata.MessageWithBytes{Data: binaryData, Label: "binary data"},
+		&testdata.MessageWithMap{
+			Counts: map[string]int32{"apples": 5, "pears": 7},
+			Lookup: map[int32]string{1: "one", 2: "two"},
+		},
+		createLargeUserProfile(),
+		&testdata.RepeatedMessage{Numbers: []int32{100, 101, 103, 102, -5}},
+		&testdata.SimpleMessage{},
+	}
+
+	combinations := []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"order-2 strings", []Option{WithStringOrder(2)}},
+		{"varint models", []Option{WithVarintByteModels()}},
+		{"varint models, order-1 strings", []Option{WithVarintByteModels(), WithStringOrder(1)}},
+		{"huffman, order-2 strings", []Option{WithBackend(Huffman), WithStringOrder(2)}},
+		{"huffman, varint models", []Option{WithBackend(Huffman), WithVarintByteModels()}},
+		{"list transforms, varint models", []Option{WithListTransforms(), WithVarintByteModels()}},
+	}
+
+	for _, combination := range combinations {
+		t.Run(combination.name, func(t *testing.T) {
+			for i, msg := range messages {
+				var buf bytes.Buffer
+				if err := Compress(msg, &buf, combination.opts...); err != nil {
+					t.Fatalf("message %d: Compress failed: %v", i, err)
+				}
+				decoded := msg.ProtoReflect().New().Interface()
+				if err := Decompress(&buf, decoded, combination.opts...); err != nil {
+					t.Fatalf("message %d: Decompress failed: %v", i, err)
+				}
+				if !proto.Equal(msg, decoded) {
+					t.Errorf("message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", i, msg, decoded)
+				}
+			}
+		})
+	}
+}
+
+func TestOptionWrappers(t *testing.T) {
+	msg := &testdata.SimpleMessage{Id: 12345, Name: "Alice", Active: true}
+
+	wrappers := []struct {
+		name     string
+		compress func(proto.Message, *bytes.Buffer) error
+		opts     []Option
+	}{
+		{"CompressOrder2", func(m proto.Message, w *bytes.Buffer) error { return CompressOrder2(m, w) }, []Option{WithStringOrder(2)}},
+		{"CompressVarintModelsOrder1", func(m proto.Message, w *bytes.Buffer) error { return CompressVarintModelsOrder1(m, w) }, []Option{WithStringOrder(1), WithVarintByteModels()}},
+		{"CompressWithOptions", func(m proto.Message, w *bytes.Buffer) error {
+			return CompressWithOptions(m, w, CompressOptions{Backend: Huffman})
+		}, []Option{WithBackend(Huffman)}},
+	}
+	for _, wrapper := range wrappers {
+		var want, got bytes.Buffer
+		if err := Compress(msg, &want, wrapper.opts...); err != nil {
+			t.Fatalf("%s: Compress failed: %v", wrapper.name, err)
+		}
+		if err := wrapper.compress(msg, &got); err != nil {
+			t.Fatalf("%s failed: %v", wrapper.name, err)
+		}
+		if !bytes.Equal(want.Bytes(), got.Bytes()) {
+			t.Errorf("%s output differs from Compress with options", wrapper.name)
+		}
+	}
+}
+
+func TestInvalidStringOrder(t *testing.T) {
+	msg := &testdata.SimpleMessage{Name: "Alice"}
+	if err := Compress(msg, &bytes.Buffer{}, WithStringOrder(3)); err == nil {
+		t.Error("expected an error for string order 3")
+	}
+	if err := Decompress(bytes.NewReader([]byte{0}), msg, WithStringOrder(-1)); err == nil {
+		t.Error("expected an error for string order -1")
+	}
+}
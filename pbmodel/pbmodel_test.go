@@ -2,6 +2,7 @@ package pbmodel
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 
 	"google.golang.org/protobuf/proto"
@@ -113,6 +114,34 @@ func TestNestedMessageRoundtrip(t *testing.T) {
 	}
 }
 
+func TestDecompressTruncated(t *testing.T) {
+	original := &testdata.NestedMessage{
+		Inner: &testdata.NestedMessage_Inner{
+			Value: "inner value",
+			Count: 42,
+		},
+		InnerList: []*testdata.NestedMessage_Inner{
+			{Value: "first", Count: 1},
+			{Value: "second", Count: 2},
+		},
+		OuterField: "outer value",
+	}
+
+	var buf bytes.Buffer
+	if err := Compress(original, &buf); err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	data := buf.Bytes()
+
+	for n := 0; n < len(data); n++ {
+		decoded := &testdata.NestedMessage{}
+		err := Decompress(bytes.NewReader(data[:n]), decoded)
+		if !errors.Is(err, ErrTruncated) {
+			t.Fatalf("data truncated to %d of %d bytes: expected ErrTruncated, got %v", n, len(data), err)
+		}
+	}
+}
+
 func TestDeepNestingRoundtrip(t *testing.T) {
 	original := &testdata.DeepNesting{
 		Level1: &testdata.DeepNesting_Level1{
@@ -233,6 +262,53 @@ func TestMessageWithBytesRoundtrip(t *testing.T) {
 	}
 }
 
+func TestMessageWithBytesKinds(t *testing.T) {
+	binaryData := make([]byte, 200)
+	for i := range binaryData {
+		binaryData[i] = byte(i * i % 7)
+	}
+
+	testCases := []struct {
+		name   string
+		data   []byte
+		isText bool
+	}{
+		{"empty", []byte{}, false},
+		{"text", []byte("hello world, this is text"), true},
+		{"text with newline", []byte("line one\nline two"), true},
+		{"control bytes", []byte("abc\x00def"), false},
+		{"invalid utf8", []byte{0xC3, 0x28}, false},
+		{"binary", binaryData, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsText(tc.data); got != tc.isText {
+				t.Errorf("IsText = %v, want %v", got, tc.isText)
+			}
+
+			original := &testdata.MessageWithBytes{Data: tc.data}
+
+			var buf bytes.Buffer
+			if err := Compress(original, &buf); err != nil {
+				t.Fatalf("Compress failed: %v", err)
+			}
+			if tc.name == "binary" && buf.Len() >= len(tc.data) {
+				t.Errorf("binary data not compressed: %d >= %d", buf.Len(), len(tc.data))
+			}
+
+			decoded := &testdata.MessageWithBytes{}
+			if err := Decompress(&buf, decoded); err != nil {
+				t.Fatalf("Decompress failed: %v", err)
+			}
+
+			if !proto.Equal(original, decoded) {
+				t.Errorf("Messages don't match.\nOriginal: %v\nDecoded: %v", original, decoded)
+			}
+		})
+	}
+}
+
 func TestMessageWithMapRoundtrip(t *testing.T) {
 	original := &testdata.MessageWithMap{
 		Counts: map[string]int32{
@@ -400,3 +476,69 @@ func TestEmptyRepeatedFields(t *testing.T) {
 		t.Errorf("Messages don't match.\nOriginal: %v\nDecoded: %v", original, decoded)
 	}
 }
+
+func TestDecompressReusedMessage(t *testing.T) {
+	original := &testdata.RepeatedMessage{
+		Numbers: []int32{1, 2},
+		Words:   []string{"hello"},
+	}
+	var buf bytes.Buffer
+	if err := Compress(original, &buf); err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	data := buf.Bytes()
+
+	stale := func() *testdata.RepeatedMessage {
+		return &testdata.RepeatedMessage{
+			Numbers: []int32{7, 8, 9},
+			Flags:   []bool{true},
+		}
+	}
+
+	decoders := []struct {
+		name       string
+		decompress func(data []byte, msg proto.Message) error
+	}{
+		{"Decompress", func(data []byte, msg proto.Message) error {
+			return Decompress(bytes.NewReader(data), msg)
+		}},
+		{"Decompress with options", func(data []byte, msg proto.Message) error {
+			return Decompress(bytes.NewReader(data), msg, WithBackend(Arithmetic))
+		}},
+		{"DecompressWithOptions", func(data []byte, msg proto.Message) error {
+			_, err := DecompressWithOptions(bytes.NewReader(data), msg, DecodeOptions{})
+			return err
+		}},
+		{"Codec", func(data []byte, msg proto.Message) error {
+			return NewCodec().Decompress(bytes.NewReader(data), msg)
+		}},
+	}
+	for _, decoder := range decoders {
+		decoded := stale()
+		if err := decoder.decompress(data, decoded); err != nil {
+			t.Fatalf("%s failed: %v", decoder.name, err)
+		}
+		if !proto.Equal(original, decoded) {
+			t.Errorf("%s: got %v, want %v", decoder.name, decoded, original)
+		}
+	}
+
+	merged := stale()
+	if err := DecompressMerge(bytes.NewReader(data), merged); err != nil {
+		t.Fatalf("DecompressMerge failed: %v", err)
+	}
+	want := stale()
+	proto.Merge(want, original)
+	if !proto.Equal(want, merged) {
+		t.Errorf("DecompressMerge: got %v, want %v", merged, want)
+	}
+
+	// A failed merge leaves the message as it was
+	merged = stale()
+	if err := DecompressMerge(bytes.NewReader(data[:len(data)/2]), merged); err == nil {
+		t.Error("DecompressMerge of truncated data succeeded")
+	}
+	if !proto.Equal(stale(), merged) {
+		t.Errorf("failed DecompressMerge changed the message to %v", merged)
+	}
+}
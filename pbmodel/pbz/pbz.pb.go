@@ -0,0 +1,242 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: pbz.proto
+
+package pbz
+
+import (
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Kind describes what the values of an integer field represent, so that the
+// compressor can pick a model for them.
+type Kind int32
+
+const (
+	// No specific kind
+	Kind_NONE Kind = 0
+	// Latitude or longitude in units of 1e-7 degrees
+	Kind_COORDINATE Kind = 1
+	// Unix time in seconds
+	Kind_TIMESTAMP Kind = 2
+	// Percentage from 0 to 100
+	Kind_PERCENT Kind = 3
+)
+
+// Enum value maps for Kind.
+var (
+	Kind_name = map[int32]string{
+		0: "NONE",
+		1: "COORDINATE",
+		2: "TIMESTAMP",
+		3: "PERCENT",
+	}
+	Kind_value = map[string]int32{
+		"NONE":       0,
+		"COORDINATE": 1,
+		"TIMESTAMP":  2,
+		"PERCENT":    3,
+	}
+)
+
+func (x Kind) Enum() *Kind {
+	p := new(Kind)
+	*p = x
+	return p
+}
+
+func (x Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_pbz_proto_enumTypes[0].Descriptor()
+}
+
+func (Kind) Type() protoreflect.EnumType {
+	return &file_pbz_proto_enumTypes[0]
+}
+
+func (x Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Kind.Descriptor instead.
+func (Kind) EnumDescriptor() ([]byte, []int) {
+	return file_pbz_proto_rawDescGZIP(), []int{0}
+}
+
+// Range bounds the values of an integer field. Values outside of the range
+// remain valid, but cost more than they would without the hint.
+type Range struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Min           int64                  `protobuf:"varint,1,opt,name=min,proto3" json:"min,omitempty"`
+	Max           int64                  `protobuf:"varint,2,opt,name=max,proto3" json:"max,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Range) Reset() {
+	*x = Range{}
+	mi := &file_pbz_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Range) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Range) ProtoMessage() {}
+
+func (x *Range) ProtoReflect() protoreflect.Message {
+	mi := &file_pbz_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Range.ProtoReflect.Descriptor instead.
+func (*Range) Descriptor() ([]byte, []int) {
+	return file_pbz_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Range) GetMin() int64 {
+	if x != nil {
+		return x.Min
+	}
+	return 0
+}
+
+func (x *Range) GetMax() int64 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+var file_pbz_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*Range)(nil),
+		Field:         51234,
+		Name:          "pbz.range",
+		Tag:           "bytes,51234,opt,name=range",
+		Filename:      "pbz.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*Kind)(nil),
+		Field:         51235,
+		Name:          "pbz.kind",
+		Tag:           "varint,51235,opt,name=kind,enum=pbz.Kind",
+		Filename:      "pbz.proto",
+	},
+}
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// Range of the values of the field
+	//
+	// optional pbz.Range range = 51234;
+	E_Range = &file_pbz_proto_extTypes[0]
+	// Kind of the values of the field
+	//
+	// optional pbz.Kind kind = 51235;
+	E_Kind = &file_pbz_proto_extTypes[1]
+)
+
+var File_pbz_proto protoreflect.FileDescriptor
+
+const file_pbz_proto_rawDesc = "" +
+	"\n" +
+	"\tpbz.proto\x12\x03pbz\x1a google/protobuf/descriptor.proto\"+\n" +
+	"\x05Range\x12\x10\n" +
+	"\x03min\x18\x01 \x01(\x03R\x03min\x12\x10\n" +
+	"\x03max\x18\x02 \x01(\x03R\x03max*<\n" +
+	"\x04Kind\x12\b\n" +
+	"\x04NONE\x10\x00\x12\x0e\n" +
+	"\n" +
+	"COORDINATE\x10\x01\x12\r\n" +
+	"\tTIMESTAMP\x10\x02\x12\v\n" +
+	"\aPERCENT\x10\x03:A\n" +
+	"\x05range\x12\x1d.google.protobuf.FieldOptions\x18\xa2\x90\x03 \x01(\v2\n" +
+	".pbz.RangeR\x05range:>\n" +
+	"\x04kind\x12\x1d.google.protobuf.FieldOptions\x18\xa3\x90\x03 \x01(\x0e2\t.pbz.KindR\x04kindB;Z9github.com/egonelbre/exp-protobuf-compression/pbmodel/pbzb\x06proto3"
+
+var (
+	file_pbz_proto_rawDescOnce sync.Once
+	file_pbz_proto_rawDescData []byte
+)
+
+func file_pbz_proto_rawDescGZIP() []byte {
+	file_pbz_proto_rawDescOnce.Do(func() {
+		file_pbz_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pbz_proto_rawDesc), len(file_pbz_proto_rawDesc)))
+	})
+	return file_pbz_proto_rawDescData
+}
+
+var file_pbz_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_pbz_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_pbz_proto_goTypes = []any{
+	(Kind)(0),                         // 0: pbz.Kind
+	(*Range)(nil),                     // 1: pbz.Range
+	(*descriptorpb.FieldOptions)(nil), // 2: google.protobuf.FieldOptions
+}
+var file_pbz_proto_depIdxs = []int32{
+	2, // 0: pbz.range:extendee -> google.protobuf.FieldOptions
+	2, // 1: pbz.kind:extendee -> google.protobuf.FieldOptions
+	1, // 2: pbz.range:type_name -> pbz.Range
+	0, // 3: pbz.kind:type_name -> pbz.Kind
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	2, // [2:4] is the sub-list for extension type_name
+	0, // [0:2] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_pbz_proto_init() }
+func file_pbz_proto_init() {
+	if File_pbz_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pbz_proto_rawDesc), len(file_pbz_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   1,
+			NumExtensions: 2,
+			NumServices:   0,
+		},
+		GoTypes:           file_pbz_proto_goTypes,
+		DependencyIndexes: file_pbz_proto_depIdxs,
+		EnumInfos:         file_pbz_proto_enumTypes,
+		MessageInfos:      file_pbz_proto_msgTypes,
+		ExtensionInfos:    file_pbz_proto_extTypes,
+	}.Build()
+	File_pbz_proto = out.File
+	file_pbz_proto_goTypes = nil
+	file_pbz_proto_depIdxs = nil
+}
@@ -0,0 +1,219 @@
+package pbmodel
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// Sensor readings are floats with far more precision than the sensor has,
+// so their low mantissa bits are noise that costs nearly a byte each.
+// Quantization codes a float field as an integer instead: the nearest
+// multiple of twice the absolute tolerance, or the float with as many
+// mantissa bits as the relative tolerance needs. Values that cannot be
+// quantized within the tolerance, such as NaN, infinities and values too
+// large for the integers, are coded exactly behind an escape flag.
+
+// Tolerance is the error allowed in the values of a float field, see
+// WithFloatTolerance. Exactly one of the tolerances must be set.
+type Tolerance struct {
+	Absolute float64 // Largest difference between the decoded and the original value
+	Relative float64 // Largest difference relative to the original value, below 1
+}
+
+// WithFloatTolerance makes compression of the float or double field name
+// lossy: decompressed values differ from the original ones by at most the
+// tolerance. For example a voltage with Tolerance{Absolute: 0.01} decodes
+// within ±0.01 V.
+func WithFloatTolerance(name protoreflect.FullName, tolerance Tolerance) Option {
+	return func(o *options) {
+		if o.tolerances == nil {
+			o.tolerances = make(map[protoreflect.FullName]Tolerance)
+		}
+		o.tolerances[name] = tolerance
+	}
+}
+
+// check verifies that exactly one tolerance is set and valid.
+func (t Tolerance) check() error {
+	switch {
+	case t.Absolute != 0 && t.Relative != 0:
+		return fmt.Errorf("both absolute and relative tolerance")
+	case t.Absolute > 0 && !math.IsInf(t.Absolute, 1):
+		return nil
+	case t.Relative > 0 && t.Relative < 1:
+		return nil
+	default:
+		return fmt.Errorf("invalid tolerance %+v", t)
+	}
+}
+
+// Symbols of the quantization escape flag.
+const (
+	floatQuantized = 0
+	floatExact     = 1
+)
+
+// maxQuantized limits quantized absolute values, so that they convert to
+// floats exactly.
+const maxQuantized = 1 << 53
+
+// tolerance returns the tolerance of fd, nil for exact coding.
+func (mb *ModelBuilder) tolerance(fd protoreflect.FieldDescriptor) *Tolerance {
+	if mb.tolerances == nil {
+		return nil
+	}
+	if fd.Kind() != protoreflect.FloatKind && fd.Kind() != protoreflect.DoubleKind {
+		return nil
+	}
+	tolerance, ok := mb.tolerances[fd.FullName()]
+	if !ok {
+		return nil
+	}
+	return &tolerance
+}
+
+// floatBits returns the width of the values of fd and the number of their
+// mantissa bits.
+func floatBits(fd protoreflect.FieldDescriptor) (width, mantissa int) {
+	if fd.Kind() == protoreflect.FloatKind {
+		return 32, 23
+	}
+	return 64, 52
+}
+
+// fromBits returns the value of fd with the IEEE 754 bits.
+func fromBits(fd protoreflect.FieldDescriptor, bits uint64) float64 {
+	if fd.Kind() == protoreflect.FloatKind {
+		return float64(math.Float32frombits(uint32(bits)))
+	}
+	return math.Float64frombits(bits)
+}
+
+// toBits returns the IEEE 754 bits of a value of fd.
+func toBits(fd protoreflect.FieldDescriptor, v float64) uint64 {
+	if fd.Kind() == protoreflect.FloatKind {
+		return uint64(math.Float32bits(float32(v)))
+	}
+	return math.Float64bits(v)
+}
+
+// relativeShift returns the number of low mantissa bits dropped for a
+// relative tolerance: rounding to the remaining bits errs by at most half of
+// their last place.
+func relativeShift(fd protoreflect.FieldDescriptor, relative float64) int {
+	_, mantissa := floatBits(fd)
+	kept := int(math.Ceil(-math.Log2(relative))) - 1
+	return mantissa - min(max(kept, 0), mantissa)
+}
+
+// quantize returns the integer coding v within tolerance and the value it
+// decodes to.
+func quantize(fd protoreflect.FieldDescriptor, tolerance *Tolerance, v float64) (q uint64, decoded float64, ok bool) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, 0, false
+	}
+	if tolerance.Absolute > 0 {
+		steps := math.Round(v / (2 * tolerance.Absolute))
+		if math.Abs(steps) > maxQuantized {
+			return 0, 0, false
+		}
+		q = ZigzagEncode(int64(steps))
+	} else {
+		shift := relativeShift(fd, tolerance.Relative)
+		bits := toBits(fd, v)
+		if shift > 0 {
+			bits += 1 << (shift - 1)
+		}
+		q = bits >> shift
+	}
+
+	decoded = dequantize(fd, tolerance, q)
+	limit := tolerance.Absolute
+	if tolerance.Relative > 0 {
+		limit = tolerance.Relative * math.Abs(v)
+	}
+	if math.IsInf(decoded, 0) || math.Abs(decoded-v) > limit {
+		return 0, 0, false
+	}
+	return q, decoded, true
+}
+
+// dequantize returns the value of fd coded by the integer q.
+func dequantize(fd protoreflect.FieldDescriptor, tolerance *Tolerance, q uint64) float64 {
+	if tolerance.Absolute > 0 {
+		return fromBits(fd, toBits(fd, float64(ZigzagDecode(q))*2*tolerance.Absolute))
+	}
+	return fromBits(fd, q<<relativeShift(fd, tolerance.Relative))
+}
+
+// encodeQuantized encodes a float value of fd within tolerance.
+func (mb *ModelBuilder) encodeQuantized(enc coder.SymbolEncoder, fd protoreflect.FieldDescriptor, tolerance *Tolerance, value protoreflect.Value) error {
+	q, _, ok := quantize(fd, tolerance, value.Float())
+	if !ok {
+		if err := enc.Encode(floatExact, mb.boolModel); err != nil {
+			return err
+		}
+		return mb.encodeFloatBits(enc, fd, mb.codedBits(fd, value.Float()))
+	}
+	if err := enc.Encode(floatQuantized, mb.boolModel); err != nil {
+		return err
+	}
+	return mb.encodeFieldVarint(enc, fd, q)
+}
+
+// decodeQuantized decodes a value written by encodeQuantized.
+func (mb *ModelBuilder) decodeQuantized(dec coder.SymbolDecoder, fd protoreflect.FieldDescriptor, tolerance *Tolerance) (protoreflect.Value, error) {
+	exact, err := dec.Decode(mb.boolModel)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	var v float64
+	if exact == floatExact {
+		bits, err := mb.decodeFloatBits(dec, fd)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		v = fromBits(fd, bits)
+	} else {
+		q, err := mb.decodeFieldVarint(dec, fd)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		v = dequantize(fd, tolerance, q)
+	}
+
+	if fd.Kind() == protoreflect.FloatKind {
+		return protoreflect.ValueOfFloat32(float32(v)), nil
+	}
+	return protoreflect.ValueOfFloat64(v), nil
+}
+
+// encodeFloatBits encodes the IEEE 754 bits of a value of fd like the exact
+// coding of floats.
+func (mb *ModelBuilder) encodeFloatBits(enc coder.SymbolEncoder, fd protoreflect.FieldDescriptor, bits uint64) error {
+	width, _ := floatBits(fd)
+	for i := 0; i < width/8; i++ {
+		if err := encodeLearned(enc, int(byte(bits>>(8*i))), mb.fixedByteModel(fd, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeFloatBits decodes bits written by encodeFloatBits.
+func (mb *ModelBuilder) decodeFloatBits(dec coder.SymbolDecoder, fd protoreflect.FieldDescriptor) (uint64, error) {
+	width, _ := floatBits(fd)
+	var bits uint64
+	for i := 0; i < width/8; i++ {
+		b, err := decodeLearned(dec, mb.fixedByteModel(fd, i))
+		if err != nil {
+			return 0, err
+		}
+		bits |= uint64(b) << (8 * i)
+	}
+	return bits, nil
+}
@@ -0,0 +1,102 @@
+package pbmodel
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestFloatTolerance(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	values := []float64{0, 1, -1, 0.005, 3.3, -40.25, 1e-30, 1e30, 3e38, -math.MaxFloat32,
+		math.Inf(1), math.Inf(-1), math.NaN(), math.SmallestNonzeroFloat32}
+	for range 200 {
+		values = append(values, (rng.Float64()-0.5)*math.Pow(10, float64(rng.Intn(12)-4)))
+	}
+
+	tolerances := []Tolerance{
+		{Absolute: 0.01},
+		{Absolute: 0.5},
+		{Absolute: 1e-9},
+		{Relative: 0.001},
+		{Relative: 0.25},
+		{Relative: 1e-12},
+	}
+	for _, tolerance := range tolerances {
+		opts := []Option{
+			WithFloatTolerance("testdata.NumericMessage.float_field", tolerance),
+			WithFloatTolerance("testdata.NumericMessage.double_field", tolerance),
+		}
+		for _, v := range values {
+			msg := &testdata.NumericMessage{FloatField: float32(v), DoubleField: v}
+			var buf bytes.Buffer
+			if err := Compress(msg, &buf, opts...); err != nil {
+				t.Fatalf("%+v: Compress(%v) failed: %v", tolerance, v, err)
+			}
+			decoded := &testdata.NumericMessage{}
+			if err := Decompress(&buf, decoded, opts...); err != nil {
+				t.Fatalf("%+v: Decompress(%v) failed: %v", tolerance, v, err)
+			}
+
+			check := func(name string, got, want float64) {
+				limit := tolerance.Absolute + tolerance.Relative*math.Abs(want)
+				switch {
+				case math.IsNaN(want):
+					if !math.IsNaN(got) {
+						t.Errorf("%+v: %s %v decoded as %v", tolerance, name, want, got)
+					}
+				case math.IsInf(want, 0):
+					if got != want {
+						t.Errorf("%+v: %s %v decoded as %v", tolerance, name, want, got)
+					}
+				case math.Abs(got-want) > limit:
+					t.Errorf("%+v: %s %v decoded as %v, error %v exceeds %v", tolerance, name, want, got, math.Abs(got-want), limit)
+				}
+			}
+			check("float", float64(decoded.FloatField), float64(msg.FloatField))
+			check("double", decoded.DoubleField, msg.DoubleField)
+		}
+	}
+}
+
+func TestFloatToleranceSize(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	opts := []Option{
+		WithoutHeader(),
+		WithFloatTolerance("testdata.NumericMessage.float_field", Tolerance{Absolute: 0.05}),
+		WithFloatTolerance("testdata.NumericMessage.double_field", Tolerance{Relative: 0.001}),
+	}
+
+	var exact, lossy int
+	for range 100 {
+		// A temperature and a voltage
+		msg := &testdata.NumericMessage{FloatField: float32(15 + rng.Float64()*10), DoubleField: 3.7 + rng.Float64()*0.5}
+		var buf bytes.Buffer
+		if err := Compress(msg, &buf, WithoutHeader()); err != nil {
+			t.Fatal(err)
+		}
+		exact += buf.Len()
+		buf.Reset()
+		if err := Compress(msg, &buf, opts...); err != nil {
+			t.Fatal(err)
+		}
+		lossy += buf.Len()
+	}
+
+	t.Logf("exact %d bytes, with tolerances %d bytes", exact, lossy)
+	if lossy*5 > exact*3 {
+		t.Errorf("with tolerances %d bytes, want at most 60%% of %d bytes", lossy, exact)
+	}
+}
+
+func TestFloatToleranceInvalid(t *testing.T) {
+	for _, tolerance := range []Tolerance{{}, {Absolute: -1}, {Relative: 1}, {Absolute: 1, Relative: 0.1}, {Absolute: math.NaN()}} {
+		err := Compress(&testdata.NumericMessage{}, &bytes.Buffer{}, WithFloatTolerance("testdata.NumericMessage.float_field", tolerance))
+		if err == nil {
+			t.Errorf("%+v: Compress succeeded", tolerance)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package pbmodel
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// Setting a scalar through protoreflect boxes the value into an interface
+// and converts it with reflect, which allocates for most values. Generated
+// messages have a faster path: their unmarshaler stores numbers into the
+// struct without allocating. The decompressors therefore append the protobuf
+// wire encoding of a decoded number to a scratch buffer owned by the
+// ModelBuilder and merge it into the message, so that a Codec decodes
+// numeric fields without allocating. Strings and bytes are still set
+// through protoreflect, since they own memory anyway.
+
+// wireMerge merges the wire encoding of a single field into a message.
+var wireMerge = proto.UnmarshalOptions{Merge: true, AllowPartial: true}
+
+// setScalar sets the singular field fd of msg to value.
+func (mb *ModelBuilder) setScalar(msg protoreflect.Message, fd protoreflect.FieldDescriptor, value protoreflect.Value) error {
+	if fd.IsExtension() || !isNumericKind(fd.Kind()) {
+		msg.Set(fd, value)
+		return nil
+	}
+	mb.scratch = appendScalar(mb.scratch[:0], fd, value)
+	if err := wireMerge.Unmarshal(mb.scratch, msg.Interface()); err != nil {
+		return fmt.Errorf("set field %s: %w", fd.FullName(), err)
+	}
+	return nil
+}
+
+// isNumericKind reports whether values of kind are coded as a varint or as
+// fixed-size integers on the wire.
+func isNumericKind(kind protoreflect.Kind) bool {
+	switch kind {
+	case protoreflect.StringKind, protoreflect.BytesKind,
+		protoreflect.MessageKind, protoreflect.GroupKind:
+		return false
+	}
+	return true
+}
+
+// appendScalar appends the wire encoding of the numeric value of fd to b.
+func appendScalar(b []byte, fd protoreflect.FieldDescriptor, value protoreflect.Value) []byte {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b = protowire.AppendTag(b, fd.Number(), protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeBool(value.Bool()))
+	case protoreflect.EnumKind:
+		b = protowire.AppendTag(b, fd.Number(), protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(value.Enum()))
+	case protoreflect.Int32Kind, protoreflect.Int64Kind:
+		b = protowire.AppendTag(b, fd.Number(), protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(value.Int()))
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind:
+		b = protowire.AppendTag(b, fd.Number(), protowire.VarintType)
+		return protowire.AppendVarint(b, value.Uint())
+	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		b = protowire.AppendTag(b, fd.Number(), protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeZigZag(value.Int()))
+	case protoreflect.Fixed32Kind:
+		b = protowire.AppendTag(b, fd.Number(), protowire.Fixed32Type)
+		return protowire.AppendFixed32(b, uint32(value.Uint()))
+	case protoreflect.Sfixed32Kind:
+		b = protowire.AppendTag(b, fd.Number(), protowire.Fixed32Type)
+		return protowire.AppendFixed32(b, uint32(value.Int()))
+	case protoreflect.FloatKind:
+		b = protowire.AppendTag(b, fd.Number(), protowire.Fixed32Type)
+		return protowire.AppendFixed32(b, math.Float32bits(float32(value.Float())))
+	case protoreflect.Fixed64Kind:
+		b = protowire.AppendTag(b, fd.Number(), protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, value.Uint())
+	case protoreflect.Sfixed64Kind:
+		b = protowire.AppendTag(b, fd.Number(), protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, uint64(value.Int()))
+	case protoreflect.DoubleKind:
+		b = protowire.AppendTag(b, fd.Number(), protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(value.Float()))
+	}
+	panic(fmt.Sprintf("pbmodel: %v is not a numeric kind", fd.Kind()))
+}
+
+// decodeFixed decodes the n little-endian bytes of a fixed-size value of
+// fd.
+func (mb *ModelBuilder) decodeFixed(dec coder.SymbolDecoder, fd protoreflect.FieldDescriptor, n int) (uint64, error) {
+	var v uint64
+	for i := 0; i < n; i++ {
+		b, err := decodeLearned(dec, mb.fixedByteModel(fd, i))
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(b) << (8 * i)
+	}
+	return v, nil
+}
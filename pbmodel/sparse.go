@@ -0,0 +1,147 @@
+package pbmodel
+
+import (
+	"fmt"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// With sparse fields a message starts with the number of its present
+// fields, and every present field with the number of absent fields skipped
+// since the previous present one, so absent fields cost nothing on their
+// own. This is the field coding of meshtasticmodel V2 made to work with any
+// schema: the gaps count positions among the coded fields in declaration
+// order rather than field numbers, so that reserved ranges, large numbers
+// and fields declared out of numeric order cost nothing extra.
+
+// sparseDirect is the number of small counts and gaps that are coded as
+// a symbol of their own. Larger ones are coded as sparseDirect followed by
+// the rest as a varint.
+const sparseDirect = 16
+
+// sparseModel codes the counts and gaps, which are mostly small.
+var sparseModel = sync.OnceValue(func() coder.Model {
+	freqs := make([]uint64, sparseDirect+1)
+	for i := range sparseDirect {
+		freqs[i] = 2 * sparseDirect / uint64(i+1)
+	}
+	freqs[sparseDirect] = 1
+	return models.NewFrequencyTable(freqs)
+})
+
+// WithSparseFields codes only the present fields of a message, each with
+// the gap to the previous present field, instead of a presence marker for
+// every field. It suits large messages of which few fields are set. It
+// cannot be combined with WithPresenceBitmaps.
+func WithSparseFields() Option {
+	return func(o *options) { o.sparseFields = true }
+}
+
+// encodeSparse encodes a field count or gap of a sparse message.
+func (mb *ModelBuilder) encodeSparse(enc coder.SymbolEncoder, value int) error {
+	if mb.stats != nil {
+		defer mb.stats.presence(mb.stats.enc.Bits())
+	}
+	if err := enc.Encode(min(value, sparseDirect), sparseModel()); err != nil {
+		return err
+	}
+	if value < sparseDirect {
+		return nil
+	}
+	return mb.encodeVarint(enc, uint64(value-sparseDirect))
+}
+
+// decodeSparse decodes a value written by encodeSparse.
+func (mb *ModelBuilder) decodeSparse(dec coder.SymbolDecoder) (uint64, error) {
+	symbol, err := dec.Decode(sparseModel())
+	if err != nil || symbol < sparseDirect {
+		return uint64(symbol), err
+	}
+	rest, err := mb.decodeVarint(dec)
+	if err != nil {
+		return 0, err
+	}
+	return sparseDirect + rest, nil
+}
+
+// compressSparseFields compresses the present fields of msg after their
+// count.
+func compressSparseFields(msg protoreflect.Message, enc coder.SymbolEncoder, mb *ModelBuilder) error {
+	fields := msg.Descriptor().Fields()
+	present := 0
+	for i := 0; i < fields.Len(); i++ {
+		if fd := fields.Get(i); !mb.skipsField(fd) && msg.Has(fd) {
+			present++
+		}
+	}
+	if err := mb.encodeSparse(enc, present); err != nil {
+		return fmt.Errorf("present fields: %w", err)
+	}
+
+	gap := 0
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if mb.skipsField(fd) {
+			continue
+		}
+		if !msg.Has(fd) {
+			gap++
+			continue
+		}
+		if err := mb.encodeSparse(enc, gap); err != nil {
+			return fmt.Errorf("field %s gap: %w", fd.Name(), err)
+		}
+		gap = 0
+		if err := compressUnmarkedField(msg, fd, enc, mb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decompressSparseFields decompresses fields written by
+// compressSparseFields into msg.
+func decompressSparseFields(msg protoreflect.Message, dec coder.SymbolDecoder, mb *ModelBuilder) error {
+	fields := msg.Descriptor().Fields()
+	present, err := mb.decodeSparse(dec)
+	if err != nil {
+		return fmt.Errorf("present fields: %w", err)
+	}
+	if present > uint64(fields.Len()) {
+		return fmt.Errorf("%d present fields in a message of %d fields", present, fields.Len())
+	}
+
+	i := 0
+	for range present {
+		gap, err := mb.decodeSparse(dec)
+		if err != nil {
+			return fmt.Errorf("field gap: %w", err)
+		}
+
+		// Skip gap coded fields to the present one
+		var fd protoreflect.FieldDescriptor
+		for ; i < fields.Len(); i++ {
+			if mb.skipsField(fields.Get(i)) {
+				continue
+			}
+			if gap == 0 {
+				fd = fields.Get(i)
+				i++
+				break
+			}
+			gap--
+		}
+		if fd == nil {
+			return fmt.Errorf("field gap past the last field of %s", msg.Descriptor().Name())
+		}
+
+		if err := decompressPresentField(msg, fd, dec, mb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package pbmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestSparseFields(t *testing.T) {
+	messages := []proto.Message{
+		&testdata.UserProfile{UserId: 42, Metadata: map[string]string{"k": "v"}},
+		&testdata.UserProfile{UpdatedAt: 1700000000, Address: &testdata.UserProfile_Address{Country: "Estonia"}},
+		&testdata.NumericMessage{Int32Field: 21, DoubleField: 1013.25},
+		&testdata.NestedMessage{InnerList: []*testdata.NestedMessage_Inner{{Value: "a"}, {}, {Count: 3}}},
+		&testdata.MessageWithOneof{Value: &testdata.MessageWithOneof_IntValue{IntValue: 7}},
+		&testdata.EmptyMessage{},
+	}
+	for _, backend := range []Backend{Arithmetic, Huffman} {
+		for i, msg := range messages {
+			var buf bytes.Buffer
+			if err := Compress(msg, &buf, WithBackend(backend), WithSparseFields()); err != nil {
+				t.Fatalf("backend %v, message %d: Compress failed: %v", backend, i, err)
+			}
+			decoded := msg.ProtoReflect().New().Interface()
+			if err := Decompress(&buf, decoded, WithBackend(backend), WithSparseFields()); err != nil {
+				t.Fatalf("backend %v, message %d: Decompress failed: %v", backend, i, err)
+			}
+			if !proto.Equal(msg, decoded) {
+				t.Errorf("backend %v, message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", backend, i, msg, decoded)
+			}
+		}
+	}
+
+	// A single field late in a large message
+	msg := &testdata.UserProfile{UpdatedAt: 1700000000}
+	var fields, sparse Stats
+	if err := Compress(msg, &bytes.Buffer{}, WithStats(&fields)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Compress(msg, &bytes.Buffer{}, WithStats(&sparse), WithSparseFields()); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("presence %.1f bits, %.1f bits with sparse fields", fields.Presence, sparse.Presence)
+	if sparse.Presence >= fields.Presence {
+		t.Errorf("presence %.1f bits with sparse fields, want less than %.1f bits", sparse.Presence, fields.Presence)
+	}
+}
+
+func TestSparseFieldsWithBitmaps(t *testing.T) {
+	if err := Compress(&testdata.SimpleMessage{}, &bytes.Buffer{}, WithSparseFields(), WithPresenceBitmaps()); err == nil {
+		t.Error("Compress with sparse fields and presence bitmaps succeeded")
+	}
+}
@@ -0,0 +1,69 @@
+package pbmodel
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// HashState writes the state of the field models to h, see
+// models.StateHasher.
+func (amb *AdaptiveModelBuilder) HashState(h hash.Hash) {
+	paths := make([]string, 0, len(amb.fieldModels))
+	for path := range amb.fieldModels {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		fmt.Fprintf(h, "%s\x00", path)
+		models.HashState(h, amb.fieldModels[path])
+	}
+}
+
+// hashState writes the state of the adaptive models to h.
+func (mb *ModelBuilder) hashState(h hash.Hash) {
+	mb.bytesModel.HashState(h)
+	if mb.stream == nil {
+		return
+	}
+
+	names := make([]protoreflect.FullName, 0, len(mb.stream.fields))
+	for name := range mb.stream.fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		f := mb.stream.fields[name]
+		fmt.Fprintf(h, "%s\x00%t\x00", name, f.hasLast)
+		if f.hasLast {
+			fmt.Fprintf(h, "%v\x00", f.last.Interface())
+		}
+		f.presence.HashState(h)
+		f.repeat.HashState(h)
+		f.values.hashState(h)
+	}
+	mb.stream.strings.hashState(h)
+}
+
+// StateHash returns a hash of the state of the stream models. After every
+// message it equals the StateHash of the StreamDecompressor that decoded
+// the message; the first message after which they differ is where the
+// decompressor went out of sync.
+func (s *StreamCompressor) StateHash() uint64 {
+	h := fnv.New64a()
+	s.mb.hashState(h)
+	return h.Sum64()
+}
+
+// StateHash returns a hash of the state of the stream models, see
+// StreamCompressor.StateHash.
+func (s *StreamDecompressor) StateHash() uint64 {
+	h := fnv.New64a()
+	s.mb.hashState(h)
+	return h.Sum64()
+}
@@ -0,0 +1,134 @@
+package pbmodel
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// Stats is the cost of a compressed message by field, recorded by
+// WithStats. Costs are in bits of information, measured from the state of
+// the arithmetic coder, so they add up to the compressed size without the
+// up to 2 bits of termination and the padding to a whole byte.
+type Stats struct {
+	// Fields are the fields coded, in the order they were first coded.
+	// Fields of nested messages follow their parent.
+	Fields []FieldStats
+	// Presence is the cost of all presence markers.
+	Presence float64
+	// Total is the cost of the message, without its format header.
+	Total float64
+
+	// Trace, when not nil, is called after every coded field with the
+	// cost of that occurrence.
+	Trace func(FieldStats)
+
+	index map[string]int // Index of a path in Fields
+}
+
+// FieldStats is the cost of a field.
+type FieldStats struct {
+	// Path names the field from the root message, such as
+	// "decoded.payload". Elements of lists and maps share the path of the
+	// list or the map.
+	Path string
+	// Count is the number of times the field was present.
+	Count int
+	// Bits is the cost of the values, with the lengths of lists, maps and
+	// bytes, and the nested fields of messages.
+	Bits float64
+	// Presence is the cost of the presence markers of the field.
+	Presence float64
+}
+
+// WithStats records the cost of every field in stats while compressing,
+// replacing the previous contents of stats. It does not change the
+// compressed data and has no effect on decompression. Stats need the
+// arithmetic coder and cannot be combined with the Huffman backend.
+func WithStats(stats *Stats) Option {
+	return func(o *options) { o.stats = stats }
+}
+
+// String formats the fields that were present and the presence markers,
+// such as "payload: 132 bits, latitude_i: 29 bits, presence overhead: 14
+// bits".
+func (stats *Stats) String() string {
+	var b strings.Builder
+	for _, field := range stats.Fields {
+		if field.Count == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %.0f bits, ", field.Path, field.Bits)
+	}
+	fmt.Fprintf(&b, "presence overhead: %.0f bits", stats.Presence)
+	return b.String()
+}
+
+// statsRecorder records Stats while a message is compressed.
+type statsRecorder struct {
+	stats *Stats
+	enc   *coder.Encoder
+	path  []string // Names of the fields being coded
+	top   int      // Index in stats.Fields of the innermost field, or -1
+}
+
+func newStatsRecorder(stats *Stats, enc *coder.Encoder) *statsRecorder {
+	stats.Fields = stats.Fields[:0]
+	stats.Presence, stats.Total = 0, 0
+	stats.index = make(map[string]int)
+	return &statsRecorder{stats: stats, enc: enc, top: -1}
+}
+
+// enter starts recording the cost of fd, which is present when present is
+// set. The returned function ends the recording.
+func (r *statsRecorder) enter(fd protoreflect.FieldDescriptor, present bool) func() {
+	name := string(fd.Name())
+	if fd.IsExtension() {
+		name = "[" + string(fd.FullName()) + "]"
+	}
+	r.path = append(r.path, name)
+	path := strings.Join(r.path, ".")
+
+	index, ok := r.stats.index[path]
+	if !ok {
+		index = len(r.stats.Fields)
+		r.stats.index[path] = index
+		r.stats.Fields = append(r.stats.Fields, FieldStats{Path: path})
+	}
+	if present {
+		r.stats.Fields[index].Count++
+	}
+
+	parent := r.top
+	r.top = index
+	start := r.enc.Bits()
+	presence := r.stats.Fields[index].Presence
+	return func() {
+		field := &r.stats.Fields[index]
+		ownPresence := field.Presence - presence
+		bits := r.enc.Bits() - start - ownPresence
+		field.Bits += bits
+		if r.stats.Trace != nil {
+			occurrence := FieldStats{Path: path, Bits: bits, Presence: ownPresence}
+			if present {
+				occurrence.Count = 1
+			}
+			r.stats.Trace(occurrence)
+		}
+		r.path = r.path[:len(r.path)-1]
+		r.top = parent
+	}
+}
+
+// presence records the cost of a presence marker coded since start, a
+// value of Bits of the encoder.
+func (r *statsRecorder) presence(start float64) {
+	bits := r.enc.Bits() - start
+	r.stats.Presence += bits
+	if r.top >= 0 {
+		r.stats.Fields[r.top].Presence += bits
+	}
+}
@@ -0,0 +1,86 @@
+package pbmodel
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestStats(t *testing.T) {
+	msg := &testdata.UserProfile{
+		UserId:   42,
+		Username: "alice",
+		Bio:      "Writes compressors for protocol buffers.",
+		Tags:     []string{"go", "protobuf"},
+		Address:  &testdata.UserProfile_Address{City: "Tallinn", Country: "Estonia"},
+	}
+
+	var plain, measured bytes.Buffer
+	if err := Compress(msg, &plain); err != nil {
+		t.Fatal(err)
+	}
+	var traced []FieldStats
+	stats := &Stats{Trace: func(field FieldStats) { traced = append(traced, field) }}
+	if err := Compress(msg, &measured, WithStats(stats)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain.Bytes(), measured.Bytes()) {
+		t.Fatal("WithStats changed the compressed data")
+	}
+	t.Log(stats)
+
+	// The termination adds up to 2 bits and the padding up to 7 bits
+	if size := float64(8 * (measured.Len() - HeaderSize)); stats.Total > size || stats.Total < size-9 {
+		t.Errorf("total %.2f bits for %d bytes", stats.Total, measured.Len())
+	}
+
+	fields := map[string]FieldStats{}
+	var sum, presence float64
+	for _, field := range stats.Fields {
+		fields[field.Path] = field
+		presence += field.Presence
+		if !strings.Contains(field.Path, ".") {
+			sum += field.Bits + field.Presence
+		}
+	}
+	if math.Abs(sum-stats.Total) > 1e-6 {
+		t.Errorf("top-level fields cost %.2f bits, total %.2f bits", sum, stats.Total)
+	}
+	if math.Abs(presence-stats.Presence) > 1e-6 {
+		t.Errorf("presence of the fields %.2f bits, total presence %.2f bits", presence, stats.Presence)
+	}
+
+	for _, path := range []string{"bio", "tags", "address", "address.city"} {
+		if field := fields[path]; field.Count != 1 || field.Bits <= 0 {
+			t.Errorf("%s: got %+v, want it present once with a cost", path, field)
+		}
+	}
+	if field, ok := fields["email"]; !ok || field.Count != 0 || field.Bits != 0 || field.Presence <= 0 {
+		t.Errorf("email: got %+v, want only the cost of its absence", field)
+	}
+	if city, address := fields["address.city"], fields["address"]; city.Bits >= address.Bits {
+		t.Errorf("address.city costs %.2f bits, more than address with %.2f bits", city.Bits, address.Bits)
+	}
+	if len(traced) != len(stats.Fields) {
+		t.Errorf("traced %d fields, want %d", len(traced), len(stats.Fields))
+	}
+
+	// Recording again replaces the previous stats
+	previous := len(stats.Fields)
+	if err := Compress(msg, &bytes.Buffer{}, WithStats(stats)); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Fields) != previous {
+		t.Errorf("got %d fields after recording again, want %d", len(stats.Fields), previous)
+	}
+}
+
+func TestStatsHuffman(t *testing.T) {
+	err := Compress(&testdata.SimpleMessage{}, &bytes.Buffer{}, WithStats(&Stats{}), WithBackend(Huffman))
+	if err == nil {
+		t.Error("stats with the Huffman backend succeeded")
+	}
+}
@@ -0,0 +1,305 @@
+package pbmodel
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// Streams of messages on one connection, such as periodic telemetry, repeat
+// the same structure and often the same values. A stream codes every message
+// as a segment of one arithmetic coded stream and keeps its models between
+// messages: the presence of each field is coded with an adaptive model of
+// that field, every singular scalar field starts with a flag telling
+// whether it repeats the value the field had in the previous message,
+// strings can refer to recently coded strings, see stringDictionary, and
+// other values are coded with models that learn the values of the field,
+// see streamValues.
+
+// Adaptive models of the streams.
+const (
+	streamMaxTotal  = 1 << 10 // Total at which the frequencies are halved
+	streamIncrement = 24      // Frequency added to a coded symbol
+)
+
+// streamModels holds the per-field state of a stream.
+type streamModels struct {
+	fields  map[protoreflect.FullName]*streamField
+	strings *stringDictionary
+}
+
+// streamField holds the models and the last value of a field.
+type streamField struct {
+	presence *models.FrequencyTable
+	repeat   *models.FrequencyTable
+	values   streamValues
+
+	last    protoreflect.Value
+	hasLast bool
+}
+
+func newStreamModels() *streamModels {
+	return &streamModels{
+		fields:  make(map[protoreflect.FullName]*streamField),
+		strings: newStringDictionary(),
+	}
+}
+
+// field returns the state of fd, creating it on first use.
+func (s *streamModels) field(fd protoreflect.FieldDescriptor) *streamField {
+	f, ok := s.fields[fd.FullName()]
+	if !ok {
+		f = &streamField{
+			presence: newStreamFlagModel(),
+			repeat:   newStreamFlagModel(),
+		}
+		s.fields[fd.FullName()] = f
+	}
+	return f
+}
+
+// newStreamFlagModel creates an adaptive model for a flag.
+func newStreamFlagModel() *models.FrequencyTable {
+	ft := models.NewFrequencyTable([]uint64{1, 1})
+	ft.SetMaxTotal(streamMaxTotal)
+	return ft
+}
+
+// streamsScalar reports whether fd is coded with a repeat flag in streams.
+func streamsScalar(fd protoreflect.FieldDescriptor) bool {
+	return !fd.IsList() && !fd.IsMap() &&
+		fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind
+}
+
+// encodePresence encodes whether fd is present.
+func (mb *ModelBuilder) encodePresence(enc coder.SymbolEncoder, fd protoreflect.FieldDescriptor, present int) error {
+	if mb.stats != nil {
+		defer mb.stats.presence(mb.stats.enc.Bits())
+	}
+	if mb.stream == nil {
+		return enc.Encode(present, mb.presenceModel(fd))
+	}
+	model := mb.stream.field(fd).presence
+	if err := enc.Encode(present, model); err != nil {
+		return err
+	}
+	model.Add(present, streamIncrement)
+	return nil
+}
+
+// decodePresence decodes a marker written by encodePresence.
+func (mb *ModelBuilder) decodePresence(dec coder.SymbolDecoder, fd protoreflect.FieldDescriptor) (int, error) {
+	if mb.stream == nil {
+		return dec.Decode(mb.presenceModel(fd))
+	}
+	model := mb.stream.field(fd).presence
+	present, err := dec.Decode(model)
+	if err != nil {
+		return 0, err
+	}
+	model.Add(present, streamIncrement)
+	return present, nil
+}
+
+// compressStreamScalar compresses a singular scalar field of a stream,
+// referring to the previous value of the field when it repeats.
+func compressStreamScalar(fd protoreflect.FieldDescriptor, value protoreflect.Value, enc coder.SymbolEncoder, mb *ModelBuilder) error {
+	f := mb.stream.field(fd)
+
+	repeat := 0
+	if f.hasLast && sameScalar(fd, f.last, value) {
+		repeat = 1
+	}
+	if err := enc.Encode(repeat, f.repeat); err != nil {
+		return err
+	}
+	f.repeat.Add(repeat, streamIncrement)
+	if repeat == 1 {
+		return nil
+	}
+
+	if err := compressFieldValue(fd, value, enc, mb); err != nil {
+		return err
+	}
+	f.last, f.hasLast = cloneScalar(fd, value), true
+	return nil
+}
+
+// decompressStreamScalar decompresses a field written by
+// compressStreamScalar.
+func decompressStreamScalar(fd protoreflect.FieldDescriptor, dec coder.SymbolDecoder, mb *ModelBuilder) (protoreflect.Value, error) {
+	f := mb.stream.field(fd)
+
+	repeat, err := dec.Decode(f.repeat)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	f.repeat.Add(repeat, streamIncrement)
+	if repeat == 1 {
+		if !f.hasLast {
+			return protoreflect.Value{}, errors.New("repeats a value before the first one")
+		}
+		return cloneScalar(fd, f.last), nil
+	}
+
+	value, err := decompressFieldValue(fd, dec, mb)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	f.last, f.hasLast = cloneScalar(fd, value), true
+	return value, nil
+}
+
+// sameScalar reports whether x and y code identically. Floats are compared
+// by their bits, so that negative zero and NaN payloads are preserved.
+func sameScalar(fd protoreflect.FieldDescriptor, x, y protoreflect.Value) bool {
+	switch fd.Kind() {
+	case protoreflect.FloatKind:
+		return math.Float32bits(float32(x.Float())) == math.Float32bits(float32(y.Float()))
+	case protoreflect.DoubleKind:
+		return math.Float64bits(x.Float()) == math.Float64bits(y.Float())
+	case protoreflect.BytesKind:
+		return bytes.Equal(x.Bytes(), y.Bytes())
+	default:
+		return x.Interface() == y.Interface()
+	}
+}
+
+// cloneScalar returns a copy of value that does not share memory with it.
+func cloneScalar(fd protoreflect.FieldDescriptor, value protoreflect.Value) protoreflect.Value {
+	if fd.Kind() == protoreflect.BytesKind {
+		return protoreflect.ValueOfBytes(bytes.Clone(value.Bytes()))
+	}
+	return value
+}
+
+// StreamCompressor compresses a sequence of messages to one stream, keeping
+// its models between messages. Later messages cost less the more they
+// resemble the earlier ones.
+//
+// Every message is flushed to the writer once compressed, so that it can be
+// sent on its own. The first one follows a format header for the stream;
+// of the options only WithoutHeader applies. The messages must be
+// decompressed in the same order by a StreamDecompressor with the same
+// options. After an error the stream can not be continued.
+//
+// A StreamCompressor must not be used concurrently.
+type StreamCompressor struct {
+	w       io.Writer
+	o       options
+	started bool // Whether the header has been written
+	mb      *ModelBuilder
+	enc     *coder.Encoder
+	err     error
+}
+
+// NewStreamCompressor creates a stream compressor writing to w.
+func NewStreamCompressor(w io.Writer, opts ...Option) *StreamCompressor {
+	mb := NewModelBuilder()
+	mb.stream = newStreamModels()
+	return &StreamCompressor{w: w, o: headerOptions(opts), mb: mb, enc: coder.NewEncoder(w)}
+}
+
+// Compress compresses msg as the next message of the stream.
+func (s *StreamCompressor) Compress(msg proto.Message) error {
+	if s.err != nil {
+		return s.err
+	}
+	if !s.started {
+		if err := s.o.writeHeader(s.w, formatStream); err != nil {
+			s.err = err
+			return err
+		}
+		s.started = true
+	}
+	if err := compressMessage(msg.ProtoReflect(), s.enc, s.mb); err != nil {
+		s.err = err
+		return err
+	}
+	s.err = s.enc.Flush()
+	return s.err
+}
+
+// StreamDecompressor decompresses messages written by a StreamCompressor.
+//
+// A StreamDecompressor must not be used concurrently.
+type StreamDecompressor struct {
+	r       io.Reader
+	o       options
+	started bool // Whether the header has been read
+	mb      *ModelBuilder
+	dec     *coder.Decoder
+	err     error
+}
+
+// NewStreamDecompressor creates a stream decompressor reading from r.
+// Nothing is read before the first call to Decompress.
+func NewStreamDecompressor(r io.Reader, opts ...Option) *StreamDecompressor {
+	mb := NewModelBuilder()
+	mb.stream = newStreamModels()
+	return &StreamDecompressor{r: r, o: headerOptions(opts), mb: mb}
+}
+
+// Decompress decompresses the next message of the stream into msg, which is
+// reset first. It returns io.EOF when the stream has no further messages.
+func (s *StreamDecompressor) Decompress(msg proto.Message) error {
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.next(); err != nil {
+		if !errors.Is(err, io.EOF) {
+			s.err = err
+		}
+		return err
+	}
+	proto.Reset(msg)
+	if err := decompressMessage(msg.ProtoReflect(), s.dec, s.mb); err != nil {
+		s.err = err
+		return err
+	}
+	return nil
+}
+
+// next moves the decoder to the segment of the next message.
+func (s *StreamDecompressor) next() error {
+	if s.dec == nil {
+		if err := s.readHeader(); err != nil {
+			return err
+		}
+		dec, err := coder.NewDecoder(s.r)
+		if err != nil {
+			return err
+		}
+		s.dec = dec
+		return nil
+	}
+	return s.dec.NextSegment()
+}
+
+// readHeader reads and verifies the format header of the stream, unless the
+// options leave it out. A stream without messages ends with io.EOF.
+func (s *StreamDecompressor) readHeader() error {
+	if s.o.noHeader || s.started {
+		return nil
+	}
+	var header [HeaderSize]byte
+	n, err := io.ReadFull(s.r, header[:])
+	if n == 0 && errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrTruncated
+		}
+		return err
+	}
+	s.started = true
+	return checkHeader(&header, formatStream, 0)
+}
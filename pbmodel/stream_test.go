@@ -0,0 +1,198 @@
+package pbmodel
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+func TestStreamRoundtrip(t *testing.T) {
+	messages := []proto.Message{
+		&testdata.SimpleMessage{Id: 12345, Name: "Alice", Active: true},
+		&testdata.SimpleMessage{Id: 12345, Name: "Alice", Active: true},
+		&testdata.MessageWithBytes{Data: []byte{0, 1, 2, 0xFF}, Label: "binary data"},
+		&testdata.MessageWithBytes{Data: []byte{0, 1, 2, 0xFF}, Label: "binary data"},
+		&testdata.NumericMessage{FloatField: float32(math.Copysign(0, -1)), DoubleField: math.NaN()},
+		&testdata.NumericMessage{FloatField: 0, DoubleField: math.NaN()},
+		&testdata.NestedMessage{OuterField: "outer", InnerList: []*testdata.NestedMessage_Inner{{}, {}}},
+		&testdata.MessageWithMap{Counts: map[string]int32{"a": 1}},
+		&testdata.SimpleMessage{Id: 12346, Name: "Alice"},
+		&testdata.EmptyMessage{},
+	}
+
+	var buf bytes.Buffer
+	compressor := NewStreamCompressor(&buf)
+	for i, msg := range messages {
+		if err := compressor.Compress(msg); err != nil {
+			t.Fatalf("message %d: Compress failed: %v", i, err)
+		}
+	}
+
+	decompressor := NewStreamDecompressor(&buf)
+	for i, msg := range messages {
+		decoded := msg.ProtoReflect().New().Interface()
+		if err := decompressor.Decompress(decoded); err != nil {
+			t.Fatalf("message %d: Decompress failed: %v", i, err)
+		}
+		// proto.Equal treats all NaNs and both zeros as equal, compare the
+		// wire format instead
+		want, _ := proto.Marshal(msg)
+		got, _ := proto.Marshal(decoded)
+		if !bytes.Equal(want, got) {
+			t.Errorf("message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", i, msg, decoded)
+		}
+	}
+	if err := decompressor.Decompress(&testdata.SimpleMessage{}); err != io.EOF {
+		t.Errorf("Decompress past the end: got %v, want io.EOF", err)
+	}
+}
+
+func TestStreamRepeatedStructure(t *testing.T) {
+	var stateless, stream bytes.Buffer
+	compressor := NewStreamCompressor(&stream)
+
+	var first, last int
+	for i := range 20 {
+		msg := &testdata.UserProfile{
+			UserId:    1234567,
+			Username:  "gateway",
+			Email:     "gateway@example.com",
+			CreatedAt: 1700000000,
+			UpdatedAt: 1700000000 + int64(i)*30,
+		}
+		if err := Compress(msg, &stateless); err != nil {
+			t.Fatal(err)
+		}
+
+		before := stream.Len()
+		if err := compressor.Compress(msg); err != nil {
+			t.Fatal(err)
+		}
+		size := stream.Len() - before
+		if i == 0 {
+			first = size
+		}
+		last = size
+	}
+
+	t.Logf("stateless %d bytes, stream %d bytes, first %d, last %d", stateless.Len(), stream.Len(), first, last)
+	if last*2 > first {
+		t.Errorf("repeated message costs %d bytes, first one %d", last, first)
+	}
+	if stream.Len()*2 > stateless.Len() {
+		t.Errorf("stream is %d bytes, stateless compression %d", stream.Len(), stateless.Len())
+	}
+}
+
+func TestStreamDecompressIncremental(t *testing.T) {
+	// A decompressor reading from a connection sees io.EOF until the next
+	// message arrives
+	var conn bytes.Buffer
+	compressor := NewStreamCompressor(&conn)
+	decompressor := NewStreamDecompressor(&conn)
+
+	for i := range 5 {
+		msg := &testdata.SimpleMessage{Id: int32(i), Name: "node", Active: i%2 == 0}
+		if err := compressor.Compress(msg); err != nil {
+			t.Fatal(err)
+		}
+		decoded := &testdata.SimpleMessage{}
+		if err := decompressor.Decompress(decoded); err != nil {
+			t.Fatalf("message %d: Decompress failed: %v", i, err)
+		}
+		if !proto.Equal(msg, decoded) {
+			t.Errorf("message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", i, msg, decoded)
+		}
+		if compressor.StateHash() != decompressor.StateHash() {
+			t.Errorf("message %d: model state differs", i)
+		}
+	}
+}
+
+func TestStreamRepeatedStrings(t *testing.T) {
+	names := []string{"Tallinn gateway node", "Tartu relay on the roof", "Pärnu base station"}
+	messages := []proto.Message{
+		&testdata.RepeatedMessage{Words: names},
+		&testdata.RepeatedMessage{Words: []string{names[2], names[0], names[1]}},
+		&testdata.UserProfile{Username: names[1], FullName: names[0], Tags: names[2:]},
+	}
+
+	var buf bytes.Buffer
+	compressor := NewStreamCompressor(&buf)
+	var sizes []int
+	for i, msg := range messages {
+		before := buf.Len()
+		if err := compressor.Compress(msg); err != nil {
+			t.Fatalf("message %d: Compress failed: %v", i, err)
+		}
+		sizes = append(sizes, buf.Len()-before)
+	}
+	t.Logf("sizes: %v", sizes)
+	// Strings coded before cost a few bits each, in any field
+	for i, size := range sizes[1:] {
+		if size > 6 {
+			t.Errorf("message %d: %d bytes for remembered strings", i+1, size)
+		}
+	}
+
+	decompressor := NewStreamDecompressor(&buf)
+	for i, msg := range messages {
+		decoded := msg.ProtoReflect().New().Interface()
+		if err := decompressor.Decompress(decoded); err != nil {
+			t.Fatalf("message %d: Decompress failed: %v", i, err)
+		}
+		if !proto.Equal(msg, decoded) {
+			t.Errorf("message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", i, msg, decoded)
+		}
+	}
+}
+
+func TestStreamLearnsValues(t *testing.T) {
+	// Packets between a few nodes, so that the addresses rarely repeat the
+	// previous packet, with a slowly draining battery
+	nodes := []uint32{0x9e7a1c24, 0x433ad1f0, 0xdeadbeef}
+	var messages []proto.Message
+	for i := range 100 {
+		messages = append(messages, &testdata.NumericMessage{
+			Uint32Field:  nodes[i%3],
+			Fixed32Field: nodes[(i*7+1)%3],
+			Int32Field:   int32(100 - i/10),
+			Uint64Field:  uint64(1700000000 + 60*i),
+		})
+	}
+
+	var buf bytes.Buffer
+	compressor := NewStreamCompressor(&buf)
+	var sizes []int
+	for i, msg := range messages {
+		before := buf.Len()
+		if err := compressor.Compress(msg); err != nil {
+			t.Fatalf("message %d: Compress failed: %v", i, err)
+		}
+		sizes = append(sizes, buf.Len()-before)
+	}
+	late := 0
+	for _, size := range sizes[50:] {
+		late += size
+	}
+	t.Logf("first message %d bytes, later messages %.1f bytes on average", sizes[0], float64(late)/50)
+	if late > 50*sizes[0]/2 {
+		t.Errorf("later messages %d bytes in total, want at most half of %d bytes each", late, sizes[0])
+	}
+
+	decompressor := NewStreamDecompressor(&buf)
+	for i, msg := range messages {
+		decoded := msg.ProtoReflect().New().Interface()
+		if err := decompressor.Decompress(decoded); err != nil {
+			t.Fatalf("message %d: Decompress failed: %v", i, err)
+		}
+		if !proto.Equal(msg, decoded) {
+			t.Errorf("message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", i, msg, decoded)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package pbmodel
+
+import (
+	"fmt"
+	"hash"
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// The values of a field in a stream come from a small set: the node IDs of
+// a mesh, the hours of the timestamps, a battery level that drops by a
+// percent now and then. Every field of a stream therefore codes its values
+// with models of its own that start as the static models and learn from the
+// values coded, see models.EscapeModel. Booleans and enum values have one
+// model per field, varints and fixed-width values one per byte position.
+
+// Learned value models of the streams.
+const (
+	streamEscapeLimit   = 2       // Mispredictions after which the learned model is used
+	streamValueMaxTotal = 1 << 12 // Total at which the frequencies are halved
+)
+
+// streamValues holds the learned value models of a field.
+type streamValues struct {
+	symbols *models.EscapeModel   // Booleans and enum value indexes
+	varint  []*models.EscapeModel // Bytes of varints by position
+	fixed   []*models.EscapeModel // Bytes of fixed-width values by position
+}
+
+// newLearnedModel creates a model that learns the values coded with static.
+func newLearnedModel(static coder.Model) *models.EscapeModel {
+	return models.NewEscapeModel(static, streamEscapeLimit).WithDecay(streamValueMaxTotal)
+}
+
+// learnedSymbols returns the model of the booleans or enum value indexes of
+// the field, creating it from static on first use.
+func (f *streamField) learnedSymbols(static coder.Model) coder.Model {
+	if f.values.symbols == nil {
+		f.values.symbols = newLearnedModel(static)
+	}
+	return f.values.symbols
+}
+
+// learnedByte returns the model of byte i of byPosition, creating it from
+// static on first use.
+func learnedByte(byPosition *[]*models.EscapeModel, i int, static coder.Model) coder.Model {
+	for len(*byPosition) <= i {
+		*byPosition = append(*byPosition, nil)
+	}
+	if (*byPosition)[i] == nil {
+		(*byPosition)[i] = newLearnedModel(static)
+	}
+	return (*byPosition)[i]
+}
+
+// hashState writes the learned models to h.
+func (v *streamValues) hashState(h hash.Hash) {
+	for i, model := range slices.Concat([]*models.EscapeModel{v.symbols}, v.varint, v.fixed) {
+		if model != nil {
+			fmt.Fprintf(h, "%d\x00", i)
+			model.HashState(h)
+		}
+	}
+}
+
+// encodeLearned encodes symbol with model, which learns the symbol when it
+// is a model of a stream.
+func encodeLearned(enc coder.SymbolEncoder, symbol int, model coder.Model) error {
+	if err := enc.Encode(symbol, model); err != nil {
+		return err
+	}
+	if learned, ok := model.(*models.EscapeModel); ok {
+		learned.Update(symbol)
+	}
+	return nil
+}
+
+// decodeLearned decodes a symbol written by encodeLearned.
+func decodeLearned(dec coder.SymbolDecoder, model coder.Model) (int, error) {
+	symbol, err := dec.Decode(model)
+	if err != nil {
+		return 0, err
+	}
+	if learned, ok := model.(*models.EscapeModel); ok {
+		learned.Update(symbol)
+	}
+	return symbol, nil
+}
+
+// encodeStreamVarint encodes an integer value of fd in a stream.
+func (mb *ModelBuilder) encodeStreamVarint(enc coder.SymbolEncoder, fd protoreflect.FieldDescriptor, value uint64) error {
+	f := mb.stream.field(fd)
+	for i, b := range EncodeVarint(value) {
+		if err := encodeLearned(enc, int(b), learnedByte(&f.values.varint, i, mb.varintModel)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeStreamVarint decodes an integer written by encodeStreamVarint.
+func (mb *ModelBuilder) decodeStreamVarint(dec coder.SymbolDecoder, fd protoreflect.FieldDescriptor) (uint64, error) {
+	f := mb.stream.field(fd)
+	var result uint64
+	for i := 0; ; i++ {
+		b, err := decodeLearned(dec, learnedByte(&f.values.varint, i, mb.varintModel))
+		if err != nil {
+			return 0, err
+		}
+		result |= uint64(b&0x7F) << (7 * i)
+		if b < 128 {
+			return result, nil
+		}
+		if 7*(i+1) >= 64 {
+			return 0, fmt.Errorf("varint too long")
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package pbmodel
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// trainedSetVersion is the version of the format written by
+// TrainedModelSet.Save. Version 2 added blend weights; version 1 files are
+// read as having none.
+const trainedSetVersion = 2
+
+// TrainedModelSet holds field models trained on sample messages of a schema,
+// see TrainModels. The models replace the generic ones for the presence of
+// every field, for booleans and enums, for integers and for fixed-width
+// values. Strings, bytes and the lengths of lists keep the generic models.
+//
+// A TrainedModelSet is not modified while coding, so it can be shared by
+// concurrent compressors. Data must be decompressed with the same set it was
+// compressed with.
+//
+// The trained models can be blended with the generic ones, see SetBlend, to
+// stay robust when the data drifts away from the samples.
+type TrainedModelSet struct {
+	fields map[protoreflect.FullName]*trainedField
+	blend  *float64 // Default blend weight of the fields, nil for 1
+}
+
+// trainedField holds the counts of a field and the models built from them.
+// The counts are smoothed by FitModel when the models are built, so that
+// values that did not occur in the samples stay codable.
+type trainedField struct {
+	Presence []uint64   `json:"presence,omitempty"` // Absent and present
+	Symbols  []uint64   `json:"symbols,omitempty"`  // Booleans and enum value indexes
+	Varint   [][]uint64 `json:"varint,omitempty"`   // First and continuation varint bytes
+	Fixed    [][]uint64 `json:"fixed,omitempty"`    // Bytes of fixed-width values by position
+	Blend    *float64   `json:"blend,omitempty"`    // Blend weight, nil for the default of the set
+
+	presence    coder.Model
+	symbols     coder.Model
+	enumSymbols coder.Model // symbols with the escape of undeclared enum values
+	varint      *varintByteModels
+	fixed       []coder.Model
+}
+
+// trainedSetFile is the format written by TrainedModelSet.Save.
+type trainedSetFile struct {
+	Version int                                     `json:"version"`
+	Blend   *float64                                `json:"blend,omitempty"`
+	Fields  map[protoreflect.FullName]*trainedField `json:"fields"`
+}
+
+// TrainModels gathers the distributions of the field values in msgs and
+// returns models trained on them. The messages may be of different types.
+func TrainModels(msgs []proto.Message) (*TrainedModelSet, error) {
+	if len(msgs) == 0 {
+		return nil, errors.New("pbmodel: no messages to train on")
+	}
+
+	set := &TrainedModelSet{fields: make(map[protoreflect.FullName]*trainedField)}
+	for i, msg := range msgs {
+		if err := set.observeMessage(msg.ProtoReflect(), 0); err != nil {
+			return nil, fmt.Errorf("pbmodel: message %d: %w", i, err)
+		}
+	}
+	set.build()
+	return set, nil
+}
+
+// observeMessage adds the fields of msg to the counts.
+func (set *TrainedModelSet) observeMessage(msg protoreflect.Message, depth int) error {
+	if depth >= MaxDepth {
+		return depthError(MaxDepth)
+	}
+
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		field := set.field(fd)
+
+		if field.Presence == nil {
+			field.Presence = make([]uint64, 2)
+		}
+		if !msg.Has(fd) {
+			field.Presence[0]++
+			continue
+		}
+		field.Presence[1]++
+
+		value := msg.Get(fd)
+		switch {
+		case fd.IsList():
+			list := value.List()
+			for j := 0; j < list.Len(); j++ {
+				if err := set.observeValue(fd, list.Get(j), depth); err != nil {
+					return fmt.Errorf("field %s: %w", fd.Name(), err)
+				}
+			}
+		case fd.IsMap():
+			var err error
+			value.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				err = set.observeValue(fd.MapKey(), k.Value(), depth)
+				if err == nil {
+					err = set.observeValue(fd.MapValue(), v, depth)
+				}
+				return err == nil
+			})
+			if err != nil {
+				return fmt.Errorf("field %s: %w", fd.Name(), err)
+			}
+		default:
+			if err := set.observeValue(fd, value, depth); err != nil {
+				return fmt.Errorf("field %s: %w", fd.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// observeValue adds a single value of fd to the counts.
+func (set *TrainedModelSet) observeValue(fd protoreflect.FieldDescriptor, value protoreflect.Value, depth int) error {
+	field := set.field(fd)
+
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		field.observeSymbol(boolSymbol(value.Bool()), 2)
+
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(value.Enum()); ev != nil {
+			field.observeSymbol(ev.Index(), fd.Enum().Values().Len())
+		}
+
+	case protoreflect.Int32Kind, protoreflect.Int64Kind:
+		field.observeVarint(uint64(value.Int()))
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind:
+		field.observeVarint(value.Uint())
+	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		field.observeVarint(ZigzagEncode(value.Int()))
+
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind:
+		field.observeFixed(binary.LittleEndian.AppendUint32(nil, uint32(value.Uint())))
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind:
+		field.observeFixed(binary.LittleEndian.AppendUint64(nil, value.Uint()))
+	case protoreflect.FloatKind:
+		field.observeFixed(binary.LittleEndian.AppendUint32(nil, math.Float32bits(float32(value.Float()))))
+	case protoreflect.DoubleKind:
+		field.observeFixed(binary.LittleEndian.AppendUint64(nil, math.Float64bits(value.Float())))
+
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return set.observeMessage(value.Message(), depth+1)
+	}
+	return nil
+}
+
+// field returns the counts of fd, creating them on first use.
+func (set *TrainedModelSet) field(fd protoreflect.FieldDescriptor) *trainedField {
+	field, ok := set.fields[fd.FullName()]
+	if !ok {
+		field = &trainedField{}
+		set.fields[fd.FullName()] = field
+	}
+	return field
+}
+
+func (field *trainedField) observeSymbol(symbol, count int) {
+	if len(field.Symbols) != count {
+		field.Symbols = make([]uint64, count)
+	}
+	field.Symbols[symbol]++
+}
+
+func (field *trainedField) observeVarint(value uint64) {
+	if field.Varint == nil {
+		field.Varint = [][]uint64{make([]uint64, 256), make([]uint64, 256)}
+	}
+	for i, b := range EncodeVarint(value) {
+		field.Varint[min(i, 1)][b]++
+	}
+}
+
+func (field *trainedField) observeFixed(bytes []byte) {
+	if len(field.Fixed) != len(bytes) {
+		field.Fixed = make([][]uint64, len(bytes))
+		for i := range field.Fixed {
+			field.Fixed[i] = make([]uint64, 256)
+		}
+	}
+	for i, b := range bytes {
+		field.Fixed[i][b]++
+	}
+}
+
+// build creates the models of every field from its counts.
+func (set *TrainedModelSet) build() {
+	for _, field := range set.fields {
+		weight := set.weight(field)
+		fit := func(counts []uint64, smoothing models.Smoothing, static coder.Model) coder.Model {
+			model := models.FitModel(counts, smoothing)
+			if weight == 1 {
+				return model
+			}
+			return models.BlendModels(model, static, weight)
+		}
+
+		field.presence = nil
+		if len(field.Presence) == 2 {
+			field.presence = fit(field.Presence, models.Laplace, models.SharedUniformModel(2))
+		}
+		field.symbols, field.enumSymbols = nil, nil
+		if len(field.Symbols) > 0 && uint64(len(field.Symbols)) < coder.MaxTotalFreq/2 {
+			field.symbols = fit(field.Symbols, models.Laplace, models.SharedUniformModel(len(field.Symbols)))
+			field.enumSymbols = withEnumEscape(field.symbols)
+		}
+		field.varint = nil
+		if len(field.Varint) == 2 && len(field.Varint[0]) == 256 && len(field.Varint[1]) == 256 {
+			field.varint = &varintByteModels{
+				firstByteModel: fit(field.Varint[0], models.KneserNey, sharedVarintFirstByteModel()),
+				contByteModel:  fit(field.Varint[1], models.KneserNey, sharedVarintContinuationByteModel()),
+			}
+		}
+		field.fixed = nil
+		if (len(field.Fixed) == 4 || len(field.Fixed) == 8) && validFixedCounts(field.Fixed) {
+			for _, counts := range field.Fixed {
+				field.fixed = append(field.fixed, fit(counts, models.KneserNey, models.SharedUniformModel(256)))
+			}
+		}
+	}
+}
+
+// weight returns the blend weight of field.
+func (set *TrainedModelSet) weight(field *trainedField) float64 {
+	switch {
+	case field.Blend != nil:
+		return *field.Blend
+	case set.blend != nil:
+		return *set.blend
+	default:
+		return 1
+	}
+}
+
+// SetBlend sets the weight of the trained models of every field without a
+// weight of its own. The models of a field code with the weighted mean of the
+// trained and the generic probabilities: weight 1, the default, uses only the
+// trained ones and weight 0 only the generic ones. A lower weight gives up
+// some compression of data like the samples for less expansion of data unlike
+// them, which allows rolling out trained models gradually.
+//
+// The weights are saved with the counts, so they must be set before
+// compressing.
+func (set *TrainedModelSet) SetBlend(weight float64) error {
+	if err := checkBlend(weight); err != nil {
+		return err
+	}
+	set.blend = &weight
+	set.build()
+	return nil
+}
+
+// SetFieldBlend sets the weight of the trained models of the named field, see
+// SetBlend.
+func (set *TrainedModelSet) SetFieldBlend(name protoreflect.FullName, weight float64) error {
+	if err := checkBlend(weight); err != nil {
+		return err
+	}
+	field, ok := set.fields[name]
+	if !ok {
+		return fmt.Errorf("pbmodel: no trained models for field %s", name)
+	}
+	field.Blend = &weight
+	set.build()
+	return nil
+}
+
+// checkBlend reports whether weight is a valid blend weight.
+func checkBlend(weight float64) error {
+	if !(weight >= 0 && weight <= 1) {
+		return fmt.Errorf("pbmodel: blend weight %v not in [0, 1]", weight)
+	}
+	return nil
+}
+
+func validFixedCounts(counts [][]uint64) bool {
+	for _, c := range counts {
+		if len(c) != 256 {
+			return false
+		}
+	}
+	return true
+}
+
+// Save writes the trained counts as JSON.
+func (set *TrainedModelSet) Save(w io.Writer) error {
+	data, err := json.MarshalIndent(trainedSetFile{Version: trainedSetVersion, Blend: set.blend, Fields: set.fields}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// LoadTrainedModels reads models written by TrainedModelSet.Save.
+func LoadTrainedModels(r io.Reader) (*TrainedModelSet, error) {
+	var file trainedSetFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("pbmodel: reading trained models: %w", err)
+	}
+	if file.Version < 1 || file.Version > trainedSetVersion {
+		return nil, fmt.Errorf("pbmodel: unsupported trained models version %d", file.Version)
+	}
+	if file.Blend != nil {
+		if err := checkBlend(*file.Blend); err != nil {
+			return nil, err
+		}
+	}
+
+	set := &TrainedModelSet{fields: file.Fields, blend: file.Blend}
+	if set.fields == nil {
+		set.fields = make(map[protoreflect.FullName]*trainedField)
+	}
+	for name, field := range set.fields {
+		if field == nil {
+			delete(set.fields, name)
+			continue
+		}
+		if field.Blend != nil {
+			if err := checkBlend(*field.Blend); err != nil {
+				return nil, fmt.Errorf("pbmodel: field %s: %w", name, err)
+			}
+		}
+	}
+	set.build()
+	return set, nil
+}
+
+// ModelBuilder returns a model builder that codes with the trained models.
+func (set *TrainedModelSet) ModelBuilder() *ModelBuilder {
+	mb := NewModelBuilder()
+	mb.trained = set
+	return mb
+}
+
+// WithTrainedModels codes the fields with models trained by TrainModels.
+func WithTrainedModels(set *TrainedModelSet) Option {
+	return func(o *options) { o.trained = set }
+}
+
+// trainedField returns the trained models of fd, or nil.
+func (mb *ModelBuilder) trainedField(fd protoreflect.FieldDescriptor) *trainedField {
+	if mb.trained == nil {
+		return nil
+	}
+	return mb.trained.fields[fd.FullName()]
+}
+
+// presenceModel returns the model of the presence marker of fd.
+func (mb *ModelBuilder) presenceModel(fd protoreflect.FieldDescriptor) coder.Model {
+	if field := mb.trainedField(fd); field != nil && field.presence != nil {
+		return field.presence
+	}
+	return mb.boolModel
+}
+
+// boolValueModel returns the model of the values of a bool field.
+func (mb *ModelBuilder) boolValueModel(fd protoreflect.FieldDescriptor) coder.Model {
+	if mb.stream != nil {
+		return mb.stream.field(fd).learnedSymbols(mb.boolModel)
+	}
+	if field := mb.trainedField(fd); field != nil && field.symbols != nil && len(field.Symbols) == 2 {
+		return field.symbols
+	}
+	return mb.boolModel
+}
+
+// enumModel returns the model of the value indexes of an enum field. Trained
+// models are ignored when the enum has changed since training.
+func (mb *ModelBuilder) enumModel(fd protoreflect.FieldDescriptor) coder.Model {
+	if mb.stream != nil {
+		return mb.stream.field(fd).learnedSymbols(mb.GetEnumModel(fd.Enum()))
+	}
+	if field := mb.trainedField(fd); field != nil && field.enumSymbols != nil && len(field.Symbols) == fd.Enum().Values().Len() {
+		return field.enumSymbols
+	}
+	return mb.GetEnumModel(fd.Enum())
+}
+
+// fixedByteModel returns the model of byte i of a fixed-width field.
+func (mb *ModelBuilder) fixedByteModel(fd protoreflect.FieldDescriptor, i int) coder.Model {
+	if mb.stream != nil {
+		return learnedByte(&mb.stream.field(fd).values.fixed, i, mb.byteModel)
+	}
+	if field := mb.trainedField(fd); field != nil && i < len(field.fixed) {
+		return field.fixed[i]
+	}
+	return mb.byteModel
+}
+
+// encodeFieldVarint encodes an integer value of fd.
+func (mb *ModelBuilder) encodeFieldVarint(enc coder.SymbolEncoder, fd protoreflect.FieldDescriptor, value uint64) error {
+	if mb.stream != nil {
+		return mb.encodeStreamVarint(enc, fd, value)
+	}
+	if field := mb.trainedField(fd); field != nil && field.varint != nil {
+		return encodeVarintWithModels(value, enc, field.varint)
+	}
+	return mb.encodeVarint(enc, value)
+}
+
+// decodeFieldVarint decodes an integer written by encodeFieldVarint.
+func (mb *ModelBuilder) decodeFieldVarint(dec coder.SymbolDecoder, fd protoreflect.FieldDescriptor) (uint64, error) {
+	if mb.stream != nil {
+		return mb.decodeStreamVarint(dec, fd)
+	}
+	if field := mb.trainedField(fd); field != nil && field.varint != nil {
+		return decodeVarintWithModels(dec, field.varint)
+	}
+	return mb.decodeVarint(dec)
+}
+
+// boolSymbol returns the symbol of a boolean value.
+func boolSymbol(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,187 @@
+package pbmodel
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+// trainingSamples returns sensor-like messages with skewed distributions.
+func trainingSamples(n int) []proto.Message {
+	var msgs []proto.Message
+	for i := range n {
+		msgs = append(msgs, &testdata.NumericMessage{
+			Int32Field:   int32(20 + i%5),
+			Uint32Field:  uint32(1000 + i%3),
+			Sint32Field:  int32(-(i % 4)),
+			FloatField:   float32(21.5 + float64(i%4)*0.25),
+			DoubleField:  1013.25,
+			Fixed32Field: 0xCAFE0000 + uint32(i%2),
+		}, &testdata.MessageWithEnum{Status: testdata.Status_ACTIVE})
+	}
+	return msgs
+}
+
+func TestTrainedModels(t *testing.T) {
+	set, err := TrainModels(trainingSamples(200))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var saved bytes.Buffer
+	if err := set.Save(&saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadTrainedModels(&saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := append(trainingSamples(3),
+		// Values and fields that did not occur in training
+		&testdata.NumericMessage{Int32Field: -5, Int64Field: 1 << 40, FloatField: -1, Fixed64Field: 7},
+		&testdata.MessageWithEnum{Status: testdata.Status_FAILED, Description: "gone"},
+	)
+
+	var plainSize, trainedSize int
+	for i, msg := range messages {
+		var plain, trained, reloaded bytes.Buffer
+		if err := Compress(msg, &plain); err != nil {
+			t.Fatal(err)
+		}
+		if err := Compress(msg, &trained, WithTrainedModels(set)); err != nil {
+			t.Fatalf("message %d: Compress failed: %v", i, err)
+		}
+		if err := Compress(msg, &reloaded, WithTrainedModels(loaded)); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(trained.Bytes(), reloaded.Bytes()) {
+			t.Errorf("message %d: loaded models compress differently", i)
+		}
+		plainSize += plain.Len()
+		trainedSize += trained.Len()
+
+		decoded := msg.ProtoReflect().New().Interface()
+		if err := Decompress(&trained, decoded, WithTrainedModels(loaded)); err != nil {
+			t.Fatalf("message %d: Decompress failed: %v", i, err)
+		}
+		if !proto.Equal(msg, decoded) {
+			t.Errorf("message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", i, msg, decoded)
+		}
+	}
+
+	t.Logf("generic models %d bytes, trained models %d bytes", plainSize, trainedSize)
+	if trainedSize*3 > plainSize*2 {
+		t.Errorf("trained models compress to %d bytes, generic ones to %d", trainedSize, plainSize)
+	}
+}
+
+func TestLoadTrainedModelsInvalid(t *testing.T) {
+	for _, input := range []string{
+		``,
+		`{"version": 2, "fields": {}}`,
+		`{"version": 1, "fields": {"x": {"presence": [1, 2, 3]}}}`,
+	} {
+		set, err := LoadTrainedModels(bytes.NewBufferString(input))
+		if err != nil {
+			continue
+		}
+		// Malformed counts are ignored
+		var buf bytes.Buffer
+		if err := Compress(&testdata.SimpleMessage{Id: 1}, &buf, WithTrainedModels(set)); err != nil {
+			t.Errorf("%q: Compress failed: %v", input, err)
+		}
+	}
+}
+
+func TestTrainedModelsBlend(t *testing.T) {
+	// Messages unlike the training samples
+	shifted := []proto.Message{
+		&testdata.NumericMessage{Int32Field: 70000, Uint32Field: 5, Sint32Field: 900, FloatField: -3.75, DoubleField: 0.5, Fixed32Field: 17},
+		&testdata.MessageWithEnum{Status: testdata.Status_FAILED},
+	}
+
+	size := func(set *TrainedModelSet, msgs []proto.Message) int {
+		t.Helper()
+		var total int
+		for i, msg := range msgs {
+			var buf bytes.Buffer
+			if err := Compress(msg, &buf, WithTrainedModels(set)); err != nil {
+				t.Fatalf("message %d: Compress failed: %v", i, err)
+			}
+			total += buf.Len()
+
+			decoded := msg.ProtoReflect().New().Interface()
+			if err := Decompress(&buf, decoded, WithTrainedModels(set)); err != nil {
+				t.Fatalf("message %d: Decompress failed: %v", i, err)
+			}
+			if !proto.Equal(msg, decoded) {
+				t.Errorf("message %d: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", i, msg, decoded)
+			}
+		}
+		return total
+	}
+
+	trained, err := TrainModels(trainingSamples(200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blended, err := TrainModels(trainingSamples(200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blended.SetBlend(0.5); err != nil {
+		t.Fatal(err)
+	}
+
+	similar := trainingSamples(3)
+	t.Logf("similar messages: trained %d bytes, blended %d bytes", size(trained, similar), size(blended, similar))
+	t.Logf("shifted messages: trained %d bytes, blended %d bytes", size(trained, shifted), size(blended, shifted))
+	if size(blended, shifted) >= size(trained, shifted) {
+		t.Errorf("blending does not help shifted messages")
+	}
+	if size(blended, similar) < size(trained, similar) {
+		t.Errorf("blending helps messages like the samples")
+	}
+
+	// Field weights override the default and are saved
+	if err := blended.SetFieldBlend("testdata.NumericMessage.int32_field", 1); err != nil {
+		t.Fatal(err)
+	}
+	var saved bytes.Buffer
+	if err := blended.Save(&saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadTrainedModels(&saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, msg := range append(similar, shifted...) {
+		var want, got bytes.Buffer
+		if err := Compress(msg, &want, WithTrainedModels(blended)); err != nil {
+			t.Fatal(err)
+		}
+		if err := Compress(msg, &got, WithTrainedModels(loaded)); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(want.Bytes(), got.Bytes()) {
+			t.Errorf("message %d: loaded models compress differently", i)
+		}
+	}
+
+	for _, weight := range []float64{-0.1, 1.5, math.NaN()} {
+		if err := trained.SetBlend(weight); err == nil {
+			t.Errorf("SetBlend(%v) succeeded", weight)
+		}
+	}
+	if err := trained.SetFieldBlend("no.such.field", 0.5); err == nil {
+		t.Errorf("SetFieldBlend of an unknown field succeeded")
+	}
+	if _, err := LoadTrainedModels(bytes.NewBufferString(`{"version": 2, "blend": 2, "fields": {}}`)); err == nil {
+		t.Errorf("loading an invalid blend weight succeeded")
+	}
+}
@@ -0,0 +1,94 @@
+package pbmodel
+
+import (
+	"bytes"
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+)
+
+// WithUnknownFields keeps the unknown fields of messages, the fields that
+// are not in the local descriptors, such as the fields added by a newer
+// schema. Without the option they are dropped. The unknown fields of every
+// message are coded in their protobuf wire format after its known fields,
+// which costs a bit for every message without them.
+func WithUnknownFields() Option {
+	return func(o *options) { o.unknownFields = true }
+}
+
+// encodeUnknown encodes the unknown fields of msg.
+func (mb *ModelBuilder) encodeUnknown(enc coder.SymbolEncoder, msg protoreflect.Message) error {
+	raw := msg.GetUnknown()
+	if len(raw) > MaxBytesLength {
+		return fmt.Errorf("unknown fields length %d exceeds MaxBytesLength", len(raw))
+	}
+	if err := enc.Encode(boolSymbol(len(raw) > 0), mb.boolModel); err != nil {
+		return fmt.Errorf("unknown fields presence: %w", err)
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := mb.encodeVarint(enc, uint64(len(raw))); err != nil {
+		return fmt.Errorf("unknown fields: %w", err)
+	}
+	if err := mb.encodeRawBytes(enc, raw); err != nil {
+		return fmt.Errorf("unknown fields: %w", err)
+	}
+	return nil
+}
+
+// decodeUnknown decodes the unknown fields written by encodeUnknown into msg.
+func (mb *ModelBuilder) decodeUnknown(dec coder.SymbolDecoder, msg protoreflect.Message) error {
+	present, err := dec.Decode(mb.boolModel)
+	if err != nil {
+		return fmt.Errorf("unknown fields presence: %w", err)
+	}
+	if present == 0 {
+		msg.SetUnknown(nil)
+		return nil
+	}
+	length, err := mb.decodeVarint(dec)
+	if err != nil {
+		return fmt.Errorf("unknown fields: %w", err)
+	}
+	if err := checkLimit("unknown fields length", length, mb.limits.MaxBytes); err != nil {
+		return err
+	}
+	raw, err := mb.decodeRawBytes(dec, int(length))
+	if err != nil {
+		return fmt.Errorf("unknown fields: %w", err)
+	}
+	msg.SetUnknown(raw)
+	return nil
+}
+
+// encodeUnknownDelta encodes the unknown fields of msg, as unchanged when
+// they equal the ones of ref.
+func (mb *ModelBuilder) encodeUnknownDelta(enc coder.SymbolEncoder, msg, ref protoreflect.Message) error {
+	if bytes.Equal(msg.GetUnknown(), ref.GetUnknown()) {
+		if err := enc.Encode(deltaSame, deltaModel()); err != nil {
+			return fmt.Errorf("unknown fields delta: %w", err)
+		}
+		return nil
+	}
+	if err := enc.Encode(deltaChanged, deltaModel()); err != nil {
+		return fmt.Errorf("unknown fields delta: %w", err)
+	}
+	return mb.encodeUnknown(enc, msg)
+}
+
+// decodeUnknownDelta decodes the unknown fields written by
+// encodeUnknownDelta into msg.
+func (mb *ModelBuilder) decodeUnknownDelta(dec coder.SymbolDecoder, msg, ref protoreflect.Message) error {
+	delta, err := dec.Decode(deltaModel())
+	if err != nil {
+		return fmt.Errorf("unknown fields delta: %w", err)
+	}
+	if delta == deltaSame {
+		msg.SetUnknown(bytes.Clone(ref.GetUnknown()))
+		return nil
+	}
+	return mb.decodeUnknown(dec, msg)
+}
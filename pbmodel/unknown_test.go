@@ -0,0 +1,95 @@
+package pbmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/egonelbre/exp-protobuf-compression/pbmodel/testdata"
+)
+
+// withNewerFields returns a message as decoded from a sender with a newer
+// schema: every message has fields unknown to the local descriptors.
+func withNewerFields() *testdata.NestedMessage {
+	unknown := func(n int) []byte {
+		var raw []byte
+		raw = protowire.AppendTag(raw, protowire.Number(10+n), protowire.VarintType)
+		raw = protowire.AppendVarint(raw, uint64(42*n))
+		raw = protowire.AppendTag(raw, 20, protowire.BytesType)
+		raw = protowire.AppendString(raw, "added in firmware 2.7")
+		return raw
+	}
+
+	msg := &testdata.NestedMessage{
+		Inner:      &testdata.NestedMessage_Inner{Value: "inner", Count: 1},
+		InnerList:  []*testdata.NestedMessage_Inner{{Value: "first"}, {Count: 2}},
+		OuterField: "outer",
+	}
+	msg.ProtoReflect().SetUnknown(unknown(1))
+	msg.Inner.ProtoReflect().SetUnknown(unknown(2))
+	msg.InnerList[1].ProtoReflect().SetUnknown(unknown(3))
+	return msg
+}
+
+func TestUnknownFields(t *testing.T) {
+	msg := withNewerFields()
+
+	for _, backend := range []Backend{Arithmetic, Huffman} {
+		var buf bytes.Buffer
+		if err := Compress(msg, &buf, WithBackend(backend), WithUnknownFields()); err != nil {
+			t.Fatalf("backend %v: Compress failed: %v", backend, err)
+		}
+		decoded := &testdata.NestedMessage{}
+		if err := Decompress(&buf, decoded, WithBackend(backend), WithUnknownFields()); err != nil {
+			t.Fatalf("backend %v: Decompress failed: %v", backend, err)
+		}
+		if !proto.Equal(msg, decoded) {
+			t.Errorf("backend %v: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", backend, msg, decoded)
+		}
+
+		// The message reencodes to the bytes the sender produced
+		want, _ := proto.Marshal(msg)
+		got, _ := proto.Marshal(decoded)
+		if !bytes.Equal(want, got) {
+			t.Errorf("backend %v: wire format differs after roundtrip", backend)
+		}
+	}
+
+	// Without the option the unknown fields are dropped
+	var buf bytes.Buffer
+	if err := Compress(msg, &buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded := &testdata.NestedMessage{}
+	if err := Decompress(&buf, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.ProtoReflect().GetUnknown()) != 0 || proto.Equal(msg, decoded) {
+		t.Errorf("unknown fields kept without WithUnknownFields")
+	}
+}
+
+func TestUnknownFieldsDelta(t *testing.T) {
+	reference := withNewerFields()
+
+	updated := withNewerFields()
+	updated.OuterField = "changed"
+	raw := protowire.AppendTag(nil, 30, protowire.Fixed32Type)
+	updated.Inner.ProtoReflect().SetUnknown(protowire.AppendFixed32(raw, 7))
+
+	for _, msg := range []*testdata.NestedMessage{reference, updated} {
+		var buf bytes.Buffer
+		if err := CompressDelta(msg, reference, &buf, WithUnknownFields()); err != nil {
+			t.Fatalf("CompressDelta failed: %v", err)
+		}
+		decoded := &testdata.NestedMessage{}
+		if err := DecompressDelta(&buf, reference, decoded, WithUnknownFields()); err != nil {
+			t.Fatalf("DecompressDelta failed: %v", err)
+		}
+		if !proto.Equal(msg, decoded) {
+			t.Errorf("roundtrip mismatch.\nOriginal: %v\nDecoded: %v", msg, decoded)
+		}
+	}
+}
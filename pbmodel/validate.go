@@ -0,0 +1,219 @@
+package pbmodel
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Many schemas already bound their integer fields with protovalidate
+// constraints:
+//
+//	import "buf/validate/validate.proto";
+//
+//	message Telemetry {
+//	  uint32 battery_level = 1 [(buf.validate.field).uint32 = {gte: 0, lte: 100}];
+//	  int32 channel = 2 [(buf.validate.field).int32 = {in: [0, 1, 2, 3, 4, 5, 6, 7]}];
+//	}
+//
+// A field with a lower and an upper bound, from const, lt, lte, gt, gte or
+// in, gets the range hint of those bounds, see newFieldHint. The constraints
+// are read from the wire encoding of the field options, so the protovalidate
+// package is not needed, neither here nor in the program that registers the
+// schema.
+
+// validateFieldNumber is the number of the buf.validate.field extension of
+// google.protobuf.FieldOptions.
+const validateFieldNumber = 1159
+
+// Field numbers of the rules in buf.validate.FieldRules by the kind of the
+// constrained field.
+var validateRulesNumber = map[protoreflect.Kind]protowire.Number{
+	protoreflect.Int32Kind:  3,
+	protoreflect.Int64Kind:  4,
+	protoreflect.Uint32Kind: 5,
+	protoreflect.Uint64Kind: 6,
+	protoreflect.Sint32Kind: 7,
+	protoreflect.Sint64Kind: 8,
+}
+
+// Field numbers of the bounds in the integer rules, such as
+// buf.validate.Int32Rules.
+const (
+	validateConst = 1
+	validateLT    = 2
+	validateLTE   = 3
+	validateGT    = 4
+	validateGTE   = 5
+	validateIn    = 6
+)
+
+// validateRange returns the range of the values of fd allowed by its
+// protovalidate constraints. It reports false unless the constraints bound
+// the values from both sides.
+func validateRange(fd protoreflect.FieldDescriptor, opts *descriptorpb.FieldOptions) (lo, hi int64, ok bool) {
+	rulesNumber, ok := validateRulesNumber[fd.Kind()]
+	if !ok {
+		return 0, 0, false
+	}
+	// Known and unknown extensions marshal alike
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(opts)
+	if err != nil {
+		return 0, 0, false
+	}
+	rules := embedded(embedded(data, validateFieldNumber), rulesNumber)
+	if rules == nil {
+		return 0, 0, false
+	}
+
+	var hasLo, hasHi bool
+	var in []int64
+	for len(rules) > 0 {
+		num, typ, n := protowire.ConsumeTag(rules)
+		if n < 0 {
+			return 0, 0, false
+		}
+		rules = rules[n:]
+
+		var values []int64
+		switch {
+		case typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(rules)
+			if n < 0 {
+				return 0, 0, false
+			}
+			rules = rules[n:]
+			values = append(values, validateValue(fd, v))
+		case typ == protowire.BytesType && num == validateIn:
+			// Packed values of in
+			packed, n := protowire.ConsumeBytes(rules)
+			if n < 0 {
+				return 0, 0, false
+			}
+			rules = rules[n:]
+			for len(packed) > 0 {
+				v, n := protowire.ConsumeVarint(packed)
+				if n < 0 {
+					return 0, 0, false
+				}
+				packed = packed[n:]
+				values = append(values, validateValue(fd, v))
+			}
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, rules)
+			if n < 0 {
+				return 0, 0, false
+			}
+			rules = rules[n:]
+			continue
+		}
+
+		// Exclusive bounds at the limits of the type allow no values, and
+		// are left to the validator to report
+		for _, v := range values {
+			switch num {
+			case validateConst:
+				lo, hi, hasLo, hasHi = v, v, true, true
+			case validateLT:
+				if v != minOfKind(fd.Kind()) {
+					hi, hasHi = v-1, true
+				}
+			case validateLTE:
+				hi, hasHi = v, true
+			case validateGT:
+				if v != maxOfKind(fd.Kind()) {
+					lo, hasLo = v+1, true
+				}
+			case validateGTE:
+				lo, hasLo = v, true
+			case validateIn:
+				in = append(in, v)
+			}
+		}
+	}
+
+	if len(in) > 0 && !(hasLo && hasHi) {
+		lo, hi = in[0], in[0]
+		for _, v := range in[1:] {
+			lo, hi = widen(fd, lo, hi, v)
+		}
+		hasLo, hasHi = true, true
+	}
+	if isUnsignedKind(fd.Kind()) {
+		return lo, hi, hasLo && hasHi && uint64(lo) <= uint64(hi)
+	}
+	return lo, hi, hasLo && hasHi && lo <= hi
+}
+
+// validateValue returns the integer value of a bound of fd coded as the
+// varint v.
+func validateValue(fd protoreflect.FieldDescriptor, v uint64) int64 {
+	switch fd.Kind() {
+	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		return protowire.DecodeZigZag(v)
+	case protoreflect.Int32Kind:
+		return int64(int32(v))
+	default:
+		return int64(v)
+	}
+}
+
+// widen widens the range from lo to hi of fd to include v.
+func widen(fd protoreflect.FieldDescriptor, lo, hi, v int64) (int64, int64) {
+	if isUnsignedKind(fd.Kind()) {
+		return int64(min(uint64(v), uint64(lo))), int64(max(uint64(v), uint64(hi)))
+	}
+	return min(v, lo), max(v, hi)
+}
+
+// minOfKind returns the smallest value of an integer kind, as coded by
+// encodeHinted.
+func minOfKind(kind protoreflect.Kind) int64 {
+	switch kind {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind:
+		return -1 << 31
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind:
+		return -1 << 63
+	default:
+		return 0
+	}
+}
+
+// maxOfKind returns the largest value of an integer kind, as coded by
+// encodeHinted.
+func maxOfKind(kind protoreflect.Kind) int64 {
+	switch kind {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind:
+		return 1<<31 - 1
+	case protoreflect.Uint32Kind:
+		return 1<<32 - 1
+	case protoreflect.Uint64Kind:
+		return -1 // All bits set
+	default:
+		return 1<<63 - 1
+	}
+}
+
+// embedded returns the merged contents of the length-delimited fields num
+// of the message data, or nil when it has none.
+func embedded(data []byte, num protowire.Number) []byte {
+	var merged []byte
+	for len(data) > 0 {
+		n, typ, tagLen := protowire.ConsumeTag(data)
+		if tagLen < 0 {
+			return nil
+		}
+		data = data[tagLen:]
+		valueLen := protowire.ConsumeFieldValue(n, typ, data)
+		if valueLen < 0 {
+			return nil
+		}
+		if n == num && typ == protowire.BytesType {
+			value, _ := protowire.ConsumeBytes(data)
+			merged = append(merged, value...)
+		}
+		data = data[valueLen:]
+	}
+	return merged
+}
@@ -0,0 +1,151 @@
+package pbmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// validateRules returns field options with the buf.validate.field extension
+// holding integer rules, which are appended by build, for the rules with
+// number rulesNumber in buf.validate.FieldRules.
+func validateRules(rulesNumber protowire.Number, build func(b []byte) []byte) *descriptorpb.FieldOptions {
+	rules := protowire.AppendTag(nil, rulesNumber, protowire.BytesType)
+	rules = protowire.AppendBytes(rules, build(nil))
+	raw := protowire.AppendTag(nil, validateFieldNumber, protowire.BytesType)
+	raw = protowire.AppendBytes(raw, rules)
+
+	opts := &descriptorpb.FieldOptions{}
+	opts.ProtoReflect().SetUnknown(raw)
+	return opts
+}
+
+// appendRule appends the bound num with the varint v.
+func appendRule(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// validatedSchema returns a message with fields constrained by protovalidate.
+func validatedSchema(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, opts *descriptorpb.FieldOptions) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:    proto.String(name),
+			Number:  proto.Int32(number),
+			Label:   descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:    typ.Enum(),
+			Options: opts,
+		}
+	}
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("validated.proto"),
+		Package: proto.String("validated"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Reading"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("battery", 1, descriptorpb.FieldDescriptorProto_TYPE_UINT32, validateRules(5, func(b []byte) []byte {
+					b = appendRule(b, validateGTE, 0)
+					return appendRule(b, validateLTE, 100)
+				})),
+				field("temperature", 2, descriptorpb.FieldDescriptorProto_TYPE_SINT32, validateRules(7, func(b []byte) []byte {
+					b = appendRule(b, validateGT, protowire.EncodeZigZag(-41))
+					return appendRule(b, validateLT, protowire.EncodeZigZag(86))
+				})),
+				field("channel", 3, descriptorpb.FieldDescriptorProto_TYPE_INT32, validateRules(3, func(b []byte) []byte {
+					var packed []byte
+					for _, v := range []uint64{2, 0, 7} {
+						packed = protowire.AppendVarint(packed, v)
+					}
+					b = protowire.AppendTag(b, validateIn, protowire.BytesType)
+					return protowire.AppendBytes(b, packed)
+				})),
+				field("offset", 4, descriptorpb.FieldDescriptorProto_TYPE_INT64, validateRules(4, func(b []byte) []byte {
+					b = appendRule(b, validateIn, uint64(0xffffffffffffffff)) // -1
+					return appendRule(b, validateIn, 3)
+				})),
+				field("count", 5, descriptorpb.FieldDescriptorProto_TYPE_UINT64, validateRules(6, func(b []byte) []byte {
+					return appendRule(b, validateGTE, 1)
+				})),
+				field("plain", 6, descriptorpb.FieldDescriptorProto_TYPE_INT32, nil),
+			},
+		}},
+	}
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd.Messages().Get(0)
+}
+
+func TestValidateRange(t *testing.T) {
+	md := validatedSchema(t)
+	tests := []struct {
+		field  protoreflect.Name
+		lo, hi int64
+		ok     bool
+	}{
+		{"battery", 0, 100, true},
+		{"temperature", -40, 85, true},
+		{"channel", 0, 7, true},
+		{"offset", -1, 3, true},
+		{"count", 0, 0, false},
+		{"plain", 0, 0, false},
+	}
+	for _, test := range tests {
+		fd := md.Fields().ByName(test.field)
+		opts, _ := fd.Options().(*descriptorpb.FieldOptions)
+		lo, hi, ok := validateRange(fd, opts)
+		if ok != test.ok || ok && (lo != test.lo || hi != test.hi) {
+			t.Errorf("%s: got [%d, %d] %v, want [%d, %d] %v", test.field, lo, hi, ok, test.lo, test.hi, test.ok)
+		}
+	}
+}
+
+func TestValidateRangeCompression(t *testing.T) {
+	md := validatedSchema(t)
+	newReading := func(battery uint32, temperature, channel int32, offset int64) *dynamicpb.Message {
+		msg := dynamicpb.NewMessage(md)
+		msg.Set(md.Fields().ByName("battery"), protoreflect.ValueOfUint32(battery))
+		msg.Set(md.Fields().ByName("temperature"), protoreflect.ValueOfInt32(temperature))
+		msg.Set(md.Fields().ByName("channel"), protoreflect.ValueOfInt32(channel))
+		msg.Set(md.Fields().ByName("offset"), protoreflect.ValueOfInt64(offset))
+		return msg
+	}
+
+	// Values that break the constraints are escaped
+	for _, msg := range []*dynamicpb.Message{newReading(87, -12, 7, -1), newReading(250, -300, 9, 1<<40)} {
+		var buf bytes.Buffer
+		if err := Compress(msg, &buf); err != nil {
+			t.Fatalf("Compress failed: %v", err)
+		}
+		decoded := dynamicpb.NewMessage(md)
+		if err := Decompress(&buf, decoded); err != nil {
+			t.Fatalf("Decompress failed: %v", err)
+		}
+		if !proto.Equal(msg, decoded) {
+			t.Errorf("roundtrip mismatch.\nOriginal: %v\nDecoded: %v", msg, decoded)
+		}
+	}
+
+	// The constrained values cost the bits of their ranges
+	var stats Stats
+	if err := Compress(newReading(87, -12, 7, -1), &bytes.Buffer{}, WithStats(&stats)); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(&stats)
+	for _, field := range stats.Fields {
+		if field.Bits > 8 {
+			t.Errorf("%s: %.1f bits", field.Path, field.Bits)
+		}
+	}
+}
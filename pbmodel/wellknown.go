@@ -0,0 +1,237 @@
+package pbmodel
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/coder"
+	"github.com/egonelbre/exp-protobuf-compression/arithcode/models"
+)
+
+// The well-known types have fixed meanings, so WithWellKnownTypes codes them
+// with models for those meanings instead of as generic messages:
+//
+//   - A Timestamp codes its seconds as the difference to the previous
+//     Timestamp of the data, the first one to timestampEpoch, so that
+//     series of times cost a byte or two.
+//   - A Duration codes its seconds directly; zigzag varints favor short
+//     durations.
+//   - The nanoseconds of both are coded by their precision: none, whole
+//     milliseconds, whole microseconds or nanoseconds.
+//   - A wrapper, such as Int32Value, codes its value without a presence
+//     flag, as the presence of the wrapper tells whether there is a value.
+//   - An Any codes its type URL as an index into the URLs seen so far, see
+//     any.go, and its value as a message when the type resolves.
+
+// WithWellKnownTypes codes google.protobuf.Timestamp, Duration, Any and the
+// wrapper types, such as Int32Value, with dedicated models.
+func WithWellKnownTypes() Option {
+	return func(o *options) { o.wellKnown = true }
+}
+
+// wellKnownType identifies the well-known types with dedicated coding.
+type wellKnownType int
+
+const (
+	notWellKnown wellKnownType = iota
+	wellKnownTimestamp
+	wellKnownDuration
+	wellKnownWrapper
+	wellKnownAny
+)
+
+// wellKnownOf returns the well-known type of md, notWellKnown when md is not
+// one or the types are coded as generic messages.
+func (mb *ModelBuilder) wellKnownOf(md protoreflect.MessageDescriptor) wellKnownType {
+	if !mb.wellKnown || md.ParentFile().Package() != "google.protobuf" {
+		return notWellKnown
+	}
+	switch md.Name() {
+	case "Timestamp":
+		return wellKnownTimestamp
+	case "Duration":
+		return wellKnownDuration
+	case "Any":
+		return wellKnownAny
+	case "DoubleValue", "FloatValue", "Int64Value", "UInt64Value",
+		"Int32Value", "UInt32Value", "BoolValue", "StringValue", "BytesValue":
+		return wellKnownWrapper
+	}
+	return notWellKnown
+}
+
+// Symbols of nanosPrecisionModel, the precision of nanoseconds.
+const (
+	nanosZero    = 0 // No fraction of a second
+	nanosMillis  = 1 // Whole milliseconds
+	nanosMicros  = 2 // Whole microseconds
+	nanosNanos   = 3 // Any nanoseconds
+	nanosInvalid = 4 // Outside the valid range, coded as a zigzag varint
+)
+
+// nanosPrecisionModel codes the precision of nanoseconds.
+var nanosPrecisionModel = sync.OnceValue(func() coder.Model {
+	return models.NewFrequencyTable([]uint64{16, 8, 4, 4, 1})
+})
+
+// nanosScale are the units of the precisions of nanoseconds.
+var nanosScale = [...]int64{nanosMillis: 1e6, nanosMicros: 1e3, nanosNanos: 1}
+
+// encodeWellKnown encodes msg of a well-known type.
+func (mb *ModelBuilder) encodeWellKnown(enc coder.SymbolEncoder, msg protoreflect.Message, typ wellKnownType) error {
+	fields := msg.Descriptor().Fields()
+	switch typ {
+	case wellKnownWrapper:
+		fd := fields.ByName("value")
+		return compressFieldValue(fd, msg.Get(fd), enc, mb)
+	case wellKnownAny:
+		return mb.encodeAny(enc, msg)
+	}
+
+	seconds := msg.Get(fields.ByName("seconds")).Int()
+	nanos := msg.Get(fields.ByName("nanos")).Int()
+	var err error
+	if typ == wellKnownTimestamp {
+		err = mb.encodeVarint(enc, ZigzagEncode(seconds-mb.lastSeconds()))
+		mb.timestampSeconds, mb.hasTimestamp = seconds, true
+	} else {
+		err = mb.encodeVarint(enc, ZigzagEncode(seconds))
+	}
+	if err != nil {
+		return fmt.Errorf("seconds: %w", err)
+	}
+	if err := mb.encodeNanos(enc, typ, seconds, nanos); err != nil {
+		return fmt.Errorf("nanos: %w", err)
+	}
+	return nil
+}
+
+// decodeWellKnown decodes a message written by encodeWellKnown into msg.
+func (mb *ModelBuilder) decodeWellKnown(dec coder.SymbolDecoder, msg protoreflect.Message, typ wellKnownType) error {
+	fields := msg.Descriptor().Fields()
+	switch typ {
+	case wellKnownWrapper:
+		fd := fields.ByName("value")
+		value, err := decompressFieldValue(fd, dec, mb)
+		if err != nil {
+			return err
+		}
+		msg.Set(fd, value)
+		return nil
+	case wellKnownAny:
+		return mb.decodeAny(dec, msg)
+	}
+
+	bits, err := mb.decodeVarint(dec)
+	if err != nil {
+		return fmt.Errorf("seconds: %w", err)
+	}
+	seconds := ZigzagDecode(bits)
+	if typ == wellKnownTimestamp {
+		seconds += mb.lastSeconds()
+		mb.timestampSeconds, mb.hasTimestamp = seconds, true
+	}
+	nanos, err := mb.decodeNanos(dec, typ, seconds)
+	if err != nil {
+		return fmt.Errorf("nanos: %w", err)
+	}
+
+	msg.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(seconds))
+	msg.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(nanos)))
+	return nil
+}
+
+// lastSeconds returns the seconds the next Timestamp is coded against.
+func (mb *ModelBuilder) lastSeconds() int64 {
+	if mb.hasTimestamp {
+		return mb.timestampSeconds
+	}
+	return timestampEpoch
+}
+
+// encodeNanos encodes the nanoseconds of a Timestamp or a Duration. The
+// nanoseconds of durations are coded by magnitude; their sign follows the
+// seconds, and only needs a flag when there are none.
+func (mb *ModelBuilder) encodeNanos(enc coder.SymbolEncoder, typ wellKnownType, seconds, nanos int64) error {
+	magnitude := nanos
+	negative := typ == wellKnownDuration && nanos < 0
+	if negative {
+		magnitude = -nanos
+	}
+
+	precision := nanosInvalid
+	switch {
+	case magnitude < 0 || magnitude >= 1e9:
+	case typ == wellKnownDuration && nanos != 0 && seconds != 0 && negative != (seconds < 0):
+	case magnitude == 0:
+		precision = nanosZero
+	case magnitude%1e6 == 0:
+		precision = nanosMillis
+	case magnitude%1e3 == 0:
+		precision = nanosMicros
+	default:
+		precision = nanosNanos
+	}
+
+	if err := enc.Encode(precision, nanosPrecisionModel()); err != nil {
+		return err
+	}
+	switch precision {
+	case nanosZero:
+		return nil
+	case nanosInvalid:
+		return mb.encodeVarint(enc, ZigzagEncode(nanos))
+	}
+	if typ == wellKnownDuration && seconds == 0 {
+		if err := enc.Encode(boolSymbol(negative), mb.boolModel); err != nil {
+			return err
+		}
+	}
+	return mb.encodeVarint(enc, uint64(magnitude/nanosScale[precision]))
+}
+
+// decodeNanos decodes nanoseconds written by encodeNanos.
+func (mb *ModelBuilder) decodeNanos(dec coder.SymbolDecoder, typ wellKnownType, seconds int64) (int64, error) {
+	precision, err := dec.Decode(nanosPrecisionModel())
+	if err != nil {
+		return 0, err
+	}
+	switch precision {
+	case nanosZero:
+		return 0, nil
+	case nanosInvalid:
+		bits, err := mb.decodeVarint(dec)
+		if err != nil {
+			return 0, err
+		}
+		nanos := ZigzagDecode(bits)
+		if nanos < math.MinInt32 || nanos > math.MaxInt32 {
+			return 0, fmt.Errorf("nanos %d: %w", nanos, ErrOutOfRange)
+		}
+		return nanos, nil
+	}
+
+	negative := typ == wellKnownDuration && seconds < 0
+	if typ == wellKnownDuration && seconds == 0 {
+		sign, err := dec.Decode(mb.boolModel)
+		if err != nil {
+			return 0, err
+		}
+		negative = sign == 1
+	}
+	units, err := mb.decodeVarint(dec)
+	if err != nil {
+		return 0, err
+	}
+	magnitude := int64(units) * nanosScale[precision]
+	if units >= 1e9 || magnitude >= 1e9 {
+		return 0, fmt.Errorf("nanos %d: %w", units, ErrOutOfRange)
+	}
+	if negative {
+		return -magnitude, nil
+	}
+	return magnitude, nil
+}
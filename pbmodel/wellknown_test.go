@@ -0,0 +1,135 @@
+package pbmodel
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// eventSchema returns the descriptor of a message with well-known types.
+func eventSchema(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	field := func(name string, number int32, label descriptorpb.FieldDescriptorProto_Label, typ string) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    label.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(typ),
+		}
+	}
+	optional, repeated := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("events.proto"),
+		Package: proto.String("events"),
+		Syntax:  proto.String("proto3"),
+		Dependency: []string{
+			"google/protobuf/timestamp.proto",
+			"google/protobuf/duration.proto",
+			"google/protobuf/wrappers.proto",
+		},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Event"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("times", 1, repeated, ".google.protobuf.Timestamp"),
+				field("elapsed", 2, optional, ".google.protobuf.Duration"),
+				field("count", 3, optional, ".google.protobuf.Int32Value"),
+				field("name", 4, optional, ".google.protobuf.StringValue"),
+				field("valid", 5, optional, ".google.protobuf.BoolValue"),
+			},
+		}},
+	}
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd.Messages().Get(0)
+}
+
+func TestWellKnownTypes(t *testing.T) {
+	messages := []proto.Message{
+		&timestamppb.Timestamp{},
+		&timestamppb.Timestamp{Seconds: 1_712_345_678, Nanos: 250_000_000},
+		&timestamppb.Timestamp{Seconds: -62_135_596_800, Nanos: 999_999_999},
+		&timestamppb.Timestamp{Seconds: 1_712_345_678, Nanos: 1_500_000_000},
+		&timestamppb.Timestamp{Seconds: 1_712_345_678, Nanos: -1},
+		&durationpb.Duration{Seconds: 90, Nanos: 125_000},
+		&durationpb.Duration{Seconds: -3, Nanos: -7},
+		&durationpb.Duration{Nanos: -500_000_000},
+		&durationpb.Duration{Seconds: 5, Nanos: -1},
+		wrapperspb.Int32(-12),
+		wrapperspb.UInt64(1 << 60),
+		wrapperspb.Double(3.25),
+		wrapperspb.Bool(true),
+		wrapperspb.String("sensor"),
+		wrapperspb.Bytes([]byte{1, 2, 3}),
+	}
+	for _, msg := range messages {
+		var buf bytes.Buffer
+		if err := Compress(msg, &buf, WithWellKnownTypes()); err != nil {
+			t.Fatalf("Compress(%v) failed: %v", msg, err)
+		}
+		decoded := msg.ProtoReflect().New().Interface()
+		if err := Decompress(&buf, decoded, WithWellKnownTypes()); err != nil {
+			t.Fatalf("Decompress(%v) failed: %v", msg, err)
+		}
+		if !proto.Equal(msg, decoded) {
+			t.Errorf("roundtrip mismatch.\nOriginal: %v\nDecoded: %v", msg, decoded)
+		}
+	}
+}
+
+func TestWellKnownTypesInMessages(t *testing.T) {
+	md := eventSchema(t)
+	fields := md.Fields()
+
+	// Readings of a sensor a few seconds apart
+	event := dynamicpb.NewMessage(md)
+	times := event.Mutable(fields.ByName("times")).List()
+	for i := range 20 {
+		ts := &timestamppb.Timestamp{Seconds: 1_712_345_678 + int64(i*i%7) + int64(5*i), Nanos: int32(i%4) * 250_000_000}
+		times.Append(protoreflect.ValueOfMessage(ts.ProtoReflect()))
+	}
+	event.Set(fields.ByName("elapsed"), protoreflect.ValueOfMessage((&durationpb.Duration{Seconds: 95, Nanos: 750_000_000}).ProtoReflect()))
+	event.Set(fields.ByName("count"), protoreflect.ValueOfMessage(wrapperspb.Int32(0).ProtoReflect()))
+	event.Set(fields.ByName("name"), protoreflect.ValueOfMessage(wrapperspb.String("attic").ProtoReflect()))
+
+	sizes := map[bool]int{}
+	for _, wellKnown := range []bool{false, true} {
+		var opts []Option
+		if wellKnown {
+			opts = append(opts, WithWellKnownTypes())
+		}
+		for _, backend := range []Backend{Arithmetic, Huffman} {
+			var buf bytes.Buffer
+			if err := Compress(event, &buf, append(opts, WithBackend(backend))...); err != nil {
+				t.Fatalf("Compress failed: %v", err)
+			}
+			if backend == Arithmetic {
+				sizes[wellKnown] = buf.Len()
+			}
+			decoded := dynamicpb.NewMessage(md)
+			if err := Decompress(&buf, decoded, append(opts, WithBackend(backend))...); err != nil {
+				t.Fatalf("Decompress failed: %v", err)
+			}
+			if !proto.Equal(event, decoded) {
+				t.Errorf("well-known %v, backend %v: roundtrip mismatch.\nOriginal: %v\nDecoded: %v", wellKnown, backend, event, decoded)
+			}
+		}
+	}
+
+	t.Logf("event: %d bytes, %d bytes with well-known types", sizes[false], sizes[true])
+	if sizes[true]*2 > sizes[false] {
+		t.Errorf("event with well-known types %d bytes, want at most half of %d bytes", sizes[true], sizes[false])
+	}
+}